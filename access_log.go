@@ -0,0 +1,33 @@
+package fiber
+
+import (
+	"context"
+	"time"
+
+	"github.com/gojek/fiber/protocol"
+)
+
+// AccessLogEntry is the summary of a single dispatch of the router, that is written, once the response
+// of the router is chosen, e.g. for the log-based analytics
+type AccessLogEntry struct {
+	CorrelationID string
+	// RouterID is the id of the router, that has dispatched the request
+	RouterID string
+	// RouteID is the id of the route, that has responded, or empty, if the error response is not attributed
+	// to any route, e.g. the request has timed out, or all the routes have failed
+	RouteID  string
+	Protocol protocol.Protocol
+	// Attempts is the number of the routes, that have dispatched the request. EagerRouter dispatches
+	// the request by all of its routes
+	Attempts int
+	// Status is the status code of the response, including the error responses
+	Status int
+	// Latency is the time since the request was dispatched by the router
+	Latency time.Duration
+}
+
+// AccessLogger writes the access log record of each dispatch of the router. A router calls LogAccess
+// concurrently for every incoming request, so the implementations must be safe for concurrent use
+type AccessLogger interface {
+	LogAccess(ctx context.Context, entry AccessLogEntry)
+}
@@ -0,0 +1,98 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAccessLogger records the access log entries
+type recordingAccessLogger struct {
+	lock    sync.Mutex
+	entries []fiber.AccessLogEntry
+}
+
+func (l *recordingAccessLogger) LogAccess(_ context.Context, entry fiber.AccessLogEntry) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+func (l *recordingAccessLogger) Entries() []fiber.AccessLogEntry {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]fiber.AccessLogEntry(nil), l.entries...)
+}
+
+type accessLoggedRouter interface {
+	fiber.Router
+	SetAccessLogger(logger fiber.AccessLogger)
+}
+
+func TestRouter_AccessLogger(t *testing.T) {
+	for name, newRouter := range map[string]func() accessLoggedRouter{
+		"lazy router":  func() accessLoggedRouter { return fiber.NewLazyRouter("router") },
+		"eager router": func() accessLoggedRouter { return fiber.NewEagerRouter("router") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			routes, dispatcherA, _ := newLoggedRoutes(t)
+			router := newRouter()
+			router.SetRoutes(routes)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+			logger := &recordingAccessLogger{}
+			router.SetAccessLogger(logger)
+
+			// the primary route fails, so the response of the fallback route is sent back
+			resp, ok := <-router.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+			require.True(t, ok)
+			assert.Equal(t, "B-OK", string(resp.Payload()))
+
+			entries := logger.Entries()
+			require.Len(t, entries, 1)
+			assert.NotEmpty(t, entries[0].CorrelationID)
+			assert.Positive(t, entries[0].Latency)
+			entries[0].Latency = 0
+			assert.Equal(t, fiber.AccessLogEntry{
+				CorrelationID: entries[0].CorrelationID,
+				RouterID:      "router",
+				RouteID:       "route-b",
+				Protocol:      protocol.HTTP,
+				Attempts:      2,
+				Status:        http.StatusOK,
+			}, entries[0])
+
+			// the correlation id of the record is sent to the routes
+			dispatcherA.lock.Lock()
+			defer dispatcherA.lock.Unlock()
+			assert.Equal(t, entries[0].CorrelationID, http.Header(dispatcherA.header).Get(fiber.CorrelationIDHeader))
+		})
+	}
+}
+
+func TestRouter_AccessLoggerErrorResponse(t *testing.T) {
+	routes, _, _ := newLoggedRoutes(t)
+	router := fiber.NewLazyRouter("router")
+	router.SetRoutes(routes)
+	router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a"}, 0, nil))
+	logger := &recordingAccessLogger{}
+	router.SetAccessLogger(logger)
+
+	resp, ok := <-router.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+	require.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+
+	// the error response of the router is not attributed to any route
+	entries := logger.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "", entries[0].RouteID)
+	assert.Equal(t, 1, entries[0].Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, entries[0].Status)
+}
@@ -2,8 +2,14 @@ package fiber
 
 import (
 	"fmt"
+	"path"
+	"strings"
 )
 
+// UnixSocketScheme is the prefix of the endpoints of the backends, that listen on the unix domain sockets,
+// e.g. unix:///var/run/backend.sock
+const UnixSocketScheme = "unix://"
+
 // Backend is an abstraction to be used for defining different backend endpoints for routers or combiners
 type Backend interface {
 	URL(requestURI string) string
@@ -28,3 +34,17 @@ func NewBackend(name string, endpoint string) Backend {
 func (backend *backend) URL(requestURI string) string {
 	return fmt.Sprintf("%s%s", backend.Endpoint, requestURI)
 }
+
+// UnixSocketPath returns the path of the unix domain socket of the endpoint and true, if the endpoint has
+// the UnixSocketScheme. The path of the socket must be absolute and clean, i.e. unix:///path/to.sock
+func UnixSocketPath(endpoint string) (string, bool, error) {
+	if !strings.HasPrefix(endpoint, UnixSocketScheme) {
+		return "", false, nil
+	}
+	socket := strings.TrimPrefix(endpoint, UnixSocketScheme)
+	if !strings.HasPrefix(socket, "/") || path.Clean(socket) != socket || socket == "/" ||
+		strings.ContainsAny(socket, "?#") {
+		return "", true, fmt.Errorf("malformed unix socket endpoint [%s], expected unix:///path/to.sock", endpoint)
+	}
+	return socket, true, nil
+}
@@ -0,0 +1,57 @@
+package fiber_test
+
+import (
+	"testing"
+
+	"github.com/gojek/fiber"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnixSocketPath(t *testing.T) {
+	suite := map[string]struct {
+		endpoint    string
+		expected    string
+		isSocket    bool
+		expectedErr string
+	}{
+		"tcp endpoint": {
+			endpoint: "http://localhost:8080",
+		},
+		"grpc target": {
+			endpoint: "dns:///localhost:50050",
+		},
+		"unix socket": {
+			endpoint: "unix:///var/run/backend.sock",
+			expected: "/var/run/backend.sock",
+			isSocket: true,
+		},
+		"relative path": {
+			endpoint:    "unix://backend.sock",
+			isSocket:    true,
+			expectedErr: "malformed unix socket endpoint [unix://backend.sock], expected unix:///path/to.sock",
+		},
+		"unclean path": {
+			endpoint:    "unix:///var/run/../backend.sock",
+			isSocket:    true,
+			expectedErr: "malformed unix socket endpoint [unix:///var/run/../backend.sock], expected unix:///path/to.sock",
+		},
+		"no path": {
+			endpoint:    "unix:///",
+			isSocket:    true,
+			expectedErr: "malformed unix socket endpoint [unix:///], expected unix:///path/to.sock",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			socket, isSocket, err := fiber.UnixSocketPath(tt.endpoint)
+			assert.Equal(t, tt.expected, socket)
+			assert.Equal(t, tt.isSocket, isSocket)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
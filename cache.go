@@ -0,0 +1,270 @@
+package fiber
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gojek/fiber/protocol"
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultCacheCapacity is the number of the responses, that are kept by the LRUCacheStore, if it's not configured
+const DefaultCacheCapacity = 1000
+
+// StaleResponseHeader is the header (or the grpc metadata key) of the expired response, that the CachingDispatcher
+// serves in place of the failed one (see CachePolicy.StaleIfError). It carries the number of seconds
+// the response has been expired for
+const StaleResponseHeader = "X-Fiber-Stale"
+
+// CacheKeyFunc returns the key of the request in the cache. The request is dispatched without
+// the cache, if ok is false (e.g. if the request is not idempotent)
+type CacheKeyFunc func(req Request) (key string, ok bool)
+
+// CacheStore is the storage of the responses of the CachingDispatcher. The implementations
+// have to be safe for concurrent use
+type CacheStore interface {
+	// Get returns the response, stored with the key, if it hasn't expired yet
+	Get(key string) (Response, bool)
+	// Set stores the response with the key for the ttl
+	Set(key string, resp Response, ttl time.Duration)
+}
+
+// CachePolicy defines which requests are cached by the CachingDispatcher, and for how long
+type CachePolicy struct {
+	// TTL is the time the response is served from the cache, before the request is dispatched again
+	TTL time.Duration
+	// Key returns the key of the request in the cache
+	Key CacheKeyFunc
+	// Store is the storage of the responses. By default, the LRUCacheStore with DefaultCacheCapacity is used
+	Store CacheStore
+	// Cacheable, if set, returns false for the successful responses, that can't be cached, e.g. the http responses,
+	// that vary by the request headers, the key doesn't include (see http.CacheableResponse)
+	Cacheable func(resp Response) bool
+	// StaleIfError, if set, is the time the expired response is kept for after the TTL. If the request fails,
+	// because the backend is unhealthy (e.g. http 503 or grpc Unavailable, once the retries are exhausted),
+	// the expired response is served instead, marked with StaleResponseHeader
+	StaleIfError time.Duration
+}
+
+// CachingDispatcher is a Dispatcher, that serves the responses of the identical requests from the cache,
+// within the TTL, without dispatching them. Only successful responses are cached, and the streaming
+// responses are never cached. Responses, that implement the `Clone() Response` method, are cloned
+// on each cache hit, so they can be modified (e.g. by the interceptors) without affecting the cache.
+// With CachePolicy.StaleIfError, the last successful response of the request is served, when the backend fails
+type CachingDispatcher struct {
+	dispatcher Dispatcher
+	policy     CachePolicy
+}
+
+// NewCachingDispatcher is a factory method, that creates a CachingDispatcher, that caches the responses
+// of the requests, dispatched by the given Dispatcher, according to the policy
+func NewCachingDispatcher(dispatcher Dispatcher, policy CachePolicy) (*CachingDispatcher, error) {
+	if dispatcher == nil {
+		return nil, errors.New("cache policy: dispatcher can not be nil")
+	}
+	if policy.Key == nil {
+		return nil, errors.New("cache policy: key function can not be nil")
+	}
+	if policy.TTL <= 0 {
+		return nil, fmt.Errorf("cache policy: ttl must be positive: [%s]", policy.TTL)
+	}
+	if policy.StaleIfError < 0 {
+		return nil, fmt.Errorf("cache policy: stale_if_error can not be negative: [%s]", policy.StaleIfError)
+	}
+	if policy.Store == nil {
+		policy.Store = NewLRUCacheStore(DefaultCacheCapacity)
+	}
+	return &CachingDispatcher{
+		dispatcher: dispatcher,
+		policy:     policy,
+	}, nil
+}
+
+// staleableResponse is the cached response, that is kept after it expires, so it can be served, if the backend
+// fails (see CachePolicy.StaleIfError)
+type staleableResponse struct {
+	Response
+	expiresAt time.Time
+}
+
+// Do returns the cached response of the request, if there is one, or dispatches the request
+// and caches its response, if it's successful. If the request fails, while its expired response
+// is still kept, the expired response is served instead
+func (d *CachingDispatcher) Do(ctx context.Context, req Request) Response {
+	key, ok := d.policy.Key(req)
+	if !ok {
+		return d.dispatcher.Do(ctx, req)
+	}
+	var stale *staleableResponse
+	if cached, ok := d.policy.Store.Get(key); ok {
+		entry, staleable := cached.(*staleableResponse)
+		if !staleable {
+			return cloneResponse(cached)
+		}
+		if time.Now().Before(entry.expiresAt) {
+			return cloneResponse(entry.Response)
+		}
+		stale = entry
+	}
+
+	resp := d.dispatcher.Do(ctx, req)
+	_, streaming := resp.(StreamingResponse)
+	if resp.IsSuccess() && !streaming && (d.policy.Cacheable == nil || d.policy.Cacheable(resp)) {
+		d.store(key, resp)
+		return cloneResponse(resp)
+	}
+	if stale != nil && !resp.IsSuccess() && ctx.Err() == nil && isBackendFailure(req.Protocol(), resp.StatusCode()) {
+		return markStale(cloneResponse(stale.Response), time.Since(stale.expiresAt))
+	}
+	return resp
+}
+
+// Saturated returns true, if the underlying dispatcher is saturated, e.g. it's rate-limited, so the route
+// is skipped, even though the cache hits would be served (see SaturationReporter)
+func (d *CachingDispatcher) Saturated() bool {
+	return saturatedIfReporter(d.dispatcher)
+}
+
+// Describe adds the details of the dispatcher, that the cache misses are dispatched with, to the info
+func (d *CachingDispatcher) Describe(info *ComponentInfo) {
+	describeIfDescriber(d.dispatcher, info)
+}
+
+// Close closes the dispatcher of the cache misses (see Closer). The store is left as it is, since it can be
+// shared with the other dispatchers
+func (d *CachingDispatcher) Close(ctx context.Context) error {
+	return closeIfCloser(ctx, d.dispatcher)
+}
+
+// WarmUp establishes the connection of the underlying dispatcher to its backend, if it's a Warmer
+func (d *CachingDispatcher) WarmUp(ctx context.Context) error {
+	return warmUpIfWarmer(ctx, d.dispatcher)
+}
+
+// store caches the response for the TTL, and keeps it for StaleIfError after that, if it's set
+func (d *CachingDispatcher) store(key string, resp Response) {
+	if d.policy.StaleIfError <= 0 {
+		d.policy.Store.Set(key, resp, d.policy.TTL)
+		return
+	}
+	entry := &staleableResponse{Response: resp, expiresAt: time.Now().Add(d.policy.TTL)}
+	d.policy.Store.Set(key, entry, d.policy.TTL+d.policy.StaleIfError)
+}
+
+// markStale sets StaleResponseHeader of the expired response, if its headers can be set. The response, that
+// can't be cloned, is marked in the cache too, which is harmless, since it's only served as the stale one from now on
+func markStale(resp Response, staleness time.Duration) Response {
+	if setter, ok := resp.(headerSetter); ok {
+		setter.SetHeader(StaleResponseHeader, strconv.Itoa(int(staleness/time.Second)))
+	}
+	return resp
+}
+
+// isBackendFailure checks, if the status code of the failed response indicates, that the backend
+// is unhealthy, rather than the request is invalid
+func isBackendFailure(proto protocol.Protocol, statusCode int) bool {
+	if proto == protocol.GRPC {
+		switch codes.Code(statusCode) {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+			return true
+		default:
+			return false
+		}
+	}
+	return statusCode >= http.StatusInternalServerError ||
+		statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests
+}
+
+// cloneResponse clones the response, if it can be cloned
+func cloneResponse(resp Response) Response {
+	if cloneable, ok := resp.(interface{ Clone() Response }); ok {
+		return cloneable.Clone()
+	}
+	return resp
+}
+
+// LRUCacheStore is the in-memory CacheStore, that keeps up to capacity responses,
+// and evicts the least recently used ones
+type LRUCacheStore struct {
+	lock     sync.Mutex
+	capacity int
+	// entries are ordered from the most to the least recently used one
+	entries *list.List
+	index   map[string]*list.Element
+}
+
+type lruCacheEntry struct {
+	key       string
+	resp      Response
+	expiresAt time.Time
+}
+
+// NewLRUCacheStore is a creator factory for the LRUCacheStore. DefaultCacheCapacity is used,
+// if the capacity is not positive
+func NewLRUCacheStore(capacity int) *LRUCacheStore {
+	if capacity <= 0 {
+		capacity = DefaultCacheCapacity
+	}
+	return &LRUCacheStore{
+		capacity: capacity,
+		entries:  list.New(),
+		index:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the response, stored with the key, and marks it as the most recently used one.
+// The expired response is removed from the store
+func (s *LRUCacheStore) Get(key string) (Response, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	elem, ok := s.index[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruCacheEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		s.remove(elem)
+		return nil, false
+	}
+	s.entries.MoveToFront(elem)
+	return entry.resp, true
+}
+
+// Set stores the response with the key, and evicts the least recently used response,
+// if the capacity is exceeded
+func (s *LRUCacheStore) Set(key string, resp Response, ttl time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry := &lruCacheEntry{key: key, resp: resp, expiresAt: time.Now().Add(ttl)}
+	if elem, ok := s.index[key]; ok {
+		elem.Value = entry
+		s.entries.MoveToFront(elem)
+		return
+	}
+	s.index[key] = s.entries.PushFront(entry)
+	if s.entries.Len() > s.capacity {
+		s.remove(s.entries.Back())
+	}
+}
+
+// Len returns the number of the responses in the store, including the expired ones,
+// that haven't been removed yet
+func (s *LRUCacheStore) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.entries.Len()
+}
+
+func (s *LRUCacheStore) remove(elem *list.Element) {
+	s.entries.Remove(elem)
+	delete(s.index, elem.Value.(*lruCacheEntry).key)
+}
@@ -0,0 +1,302 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberHTTP "github.com/gojek/fiber/http"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingDispatcher responds with the given response and counts the dispatched requests
+type countingDispatcher struct {
+	lock     sync.Mutex
+	response fiber.Response
+	count    int
+}
+
+func (d *countingDispatcher) Do(context.Context, fiber.Request) fiber.Response {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.count++
+	return d.response
+}
+
+func (d *countingDispatcher) respond(resp fiber.Response) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.response = resp
+}
+
+func (d *countingDispatcher) dispatched() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.count
+}
+
+func TestNewCachingDispatcher(t *testing.T) {
+	suite := map[string]struct {
+		dispatcher  fiber.Dispatcher
+		policy      fiber.CachePolicy
+		expectedErr string
+	}{
+		"ok": {
+			dispatcher: &countingDispatcher{},
+			policy:     fiber.CachePolicy{TTL: time.Second, Key: fiberHTTP.CacheKey()},
+		},
+		"error: nil dispatcher": {
+			policy:      fiber.CachePolicy{TTL: time.Second, Key: fiberHTTP.CacheKey()},
+			expectedErr: "cache policy: dispatcher can not be nil",
+		},
+		"error: nil key function": {
+			dispatcher:  &countingDispatcher{},
+			policy:      fiber.CachePolicy{TTL: time.Second},
+			expectedErr: "cache policy: key function can not be nil",
+		},
+		"error: non-positive ttl": {
+			dispatcher:  &countingDispatcher{},
+			policy:      fiber.CachePolicy{Key: fiberHTTP.CacheKey()},
+			expectedErr: "cache policy: ttl must be positive: [0s]",
+		},
+		"error: negative stale_if_error": {
+			dispatcher:  &countingDispatcher{},
+			policy:      fiber.CachePolicy{TTL: time.Second, Key: fiberHTTP.CacheKey(), StaleIfError: -time.Second},
+			expectedErr: "cache policy: stale_if_error can not be negative: [-1s]",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			dispatcher, err := fiber.NewCachingDispatcher(tt.dispatcher, tt.policy)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+				assert.NotNil(t, dispatcher)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestCachingDispatcher_Do(t *testing.T) {
+	suite := map[string]struct {
+		response           fiber.Response
+		requests           []*fiberHTTP.Request
+		expectedDispatched int
+	}{
+		"identical requests": {
+			response: testUtilsHttp.MockResp(http.StatusOK, "cached", nil, nil),
+			requests: []*fiberHTTP.Request{
+				testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", ""),
+				testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", ""),
+				testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", ""),
+			},
+			expectedDispatched: 1,
+		},
+		"different requests": {
+			response: testUtilsHttp.MockResp(http.StatusOK, "cached", nil, nil),
+			requests: []*fiberHTTP.Request{
+				testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", ""),
+				testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=2", ""),
+				testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features/1", ""),
+			},
+			expectedDispatched: 3,
+		},
+		"failed responses are not cached": {
+			response: testUtilsHttp.MockResp(http.StatusServiceUnavailable, "", nil, nil),
+			requests: []*fiberHTTP.Request{
+				testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", ""),
+				testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", ""),
+			},
+			expectedDispatched: 2,
+		},
+		"non-idempotent requests are not cached": {
+			response: testUtilsHttp.MockResp(http.StatusOK, "cached", nil, nil),
+			requests: []*fiberHTTP.Request{
+				testUtilsHttp.MockReq(http.MethodPost, "http://localhost/features", `{"id":1}`),
+				testUtilsHttp.MockReq(http.MethodPost, "http://localhost/features", `{"id":1}`),
+			},
+			expectedDispatched: 2,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			backend := &countingDispatcher{response: tt.response}
+			dispatcher, err := fiber.NewCachingDispatcher(backend, fiber.CachePolicy{
+				TTL: time.Minute,
+				Key: fiberHTTP.CacheKey(),
+			})
+			require.NoError(t, err)
+
+			for _, req := range tt.requests {
+				resp := dispatcher.Do(context.Background(), req)
+				assert.Equal(t, tt.response.StatusCode(), resp.StatusCode())
+				assert.Equal(t, tt.response.Payload(), resp.Payload())
+			}
+			assert.Equal(t, tt.expectedDispatched, backend.dispatched())
+		})
+	}
+}
+
+func TestCachingDispatcher_TTL(t *testing.T) {
+	backend := &countingDispatcher{response: testUtilsHttp.MockResp(http.StatusOK, "cached", nil, nil)}
+	dispatcher, err := fiber.NewCachingDispatcher(backend, fiber.CachePolicy{
+		TTL: 50 * time.Millisecond,
+		Key: fiberHTTP.CacheKey(),
+	})
+	require.NoError(t, err)
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", "")
+
+	dispatcher.Do(context.Background(), req)
+	dispatcher.Do(context.Background(), req)
+	assert.Equal(t, 1, backend.dispatched())
+
+	// the expired response is dispatched again, and cached for another ttl
+	time.Sleep(60 * time.Millisecond)
+	dispatcher.Do(context.Background(), req)
+	dispatcher.Do(context.Background(), req)
+	assert.Equal(t, 2, backend.dispatched())
+}
+
+func TestCachingDispatcher_StaleIfError(t *testing.T) {
+	backend := &countingDispatcher{response: testUtilsHttp.MockResp(http.StatusOK, "fresh", nil, nil)}
+	dispatcher, err := fiber.NewCachingDispatcher(backend, fiber.CachePolicy{
+		TTL:          50 * time.Millisecond,
+		Key:          fiberHTTP.CacheKey(),
+		StaleIfError: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", "")
+
+	resp := dispatcher.Do(context.Background(), req)
+	assert.Equal(t, "fresh", string(resp.Payload()))
+	assert.Empty(t, resp.(*fiberHTTP.Response).Header().Get(fiber.StaleResponseHeader))
+
+	// the fresh response is served, while the backend fails
+	backend.respond(testUtilsHttp.MockResp(http.StatusServiceUnavailable, "", nil, nil))
+	resp = dispatcher.Do(context.Background(), req)
+	assert.Equal(t, "fresh", string(resp.Payload()))
+	assert.Equal(t, 1, backend.dispatched())
+
+	// the expired response is served in place of the failed one, marked as stale
+	time.Sleep(60 * time.Millisecond)
+	resp = dispatcher.Do(context.Background(), req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "fresh", string(resp.Payload()))
+	assert.Equal(t, "0", resp.(*fiberHTTP.Response).Header().Get(fiber.StaleResponseHeader))
+	assert.Equal(t, 2, backend.dispatched())
+
+	// the failures of the request itself are not replaced
+	backend.respond(testUtilsHttp.MockResp(http.StatusNotFound, "", nil, nil))
+	assert.Equal(t, http.StatusNotFound, dispatcher.Do(context.Background(), req).StatusCode())
+
+	// the successful response replaces the stale one
+	backend.respond(testUtilsHttp.MockResp(http.StatusOK, "renewed", nil, nil))
+	resp = dispatcher.Do(context.Background(), req)
+	assert.Equal(t, "renewed", string(resp.Payload()))
+	assert.Empty(t, resp.(*fiberHTTP.Response).Header().Get(fiber.StaleResponseHeader))
+
+	// the response isn't served, once it's been expired for longer than stale_if_error
+	backend.respond(testUtilsHttp.MockResp(http.StatusServiceUnavailable, "", nil, nil))
+	time.Sleep(160 * time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, dispatcher.Do(context.Background(), req).StatusCode())
+	assert.Equal(t, 5, backend.dispatched())
+}
+
+func TestCachingDispatcher_ClonesResponses(t *testing.T) {
+	backend := &countingDispatcher{response: testUtilsHttp.MockResp(http.StatusOK, "cached", nil, nil)}
+	dispatcher, err := fiber.NewCachingDispatcher(backend, fiber.CachePolicy{
+		TTL: time.Minute,
+		Key: fiberHTTP.CacheKey(),
+	})
+	require.NoError(t, err)
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", "")
+
+	// the response, modified by the caller, doesn't affect the cached one
+	resp := dispatcher.Do(context.Background(), req)
+	resp.(*fiberHTTP.Response).Header().Set("Set-Cookie", "route=a")
+	resp.WithBackendName("route-a")
+
+	resp = dispatcher.Do(context.Background(), req)
+	assert.Empty(t, resp.(*fiberHTTP.Response).Header().Get("Set-Cookie"))
+	assert.Empty(t, resp.BackendName())
+	assert.Equal(t, 1, backend.dispatched())
+}
+
+func TestCachingDispatcher_VaryHeaders(t *testing.T) {
+	withLanguage := func(req *fiberHTTP.Request, language string) *fiberHTTP.Request {
+		req.Request.Header.Set("Accept-Language", language)
+		return req
+	}
+	suite := map[string]struct {
+		varyHeaders        []string
+		vary               string
+		expectedDispatched int
+	}{
+		"requests, that differ by the vary header, are cached separately": {
+			varyHeaders:        []string{"Accept-Language"},
+			vary:               "Accept-Language",
+			expectedDispatched: 2,
+		},
+		"responses, that vary by the other headers, are not cached": {
+			varyHeaders:        []string{"Accept-Language"},
+			vary:               "Accept-Encoding",
+			expectedDispatched: 3,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			header := http.Header{"Vary": []string{tt.vary}}
+			backend := &countingDispatcher{response: testUtilsHttp.MockResp(http.StatusOK, "cached", header, nil)}
+			dispatcher, err := fiber.NewCachingDispatcher(backend, fiber.CachePolicy{
+				TTL:       time.Minute,
+				Key:       fiberHTTP.CacheKey(tt.varyHeaders...),
+				Cacheable: fiberHTTP.CacheableResponse(tt.varyHeaders...),
+			})
+			require.NoError(t, err)
+
+			for _, language := range []string{"en", "fr", "en"} {
+				dispatcher.Do(context.Background(), withLanguage(testUtilsHttp.MockReq(http.MethodGet, "http://localhost/greeting", ""), language))
+			}
+			assert.Equal(t, tt.expectedDispatched, backend.dispatched())
+		})
+	}
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	store := fiber.NewLRUCacheStore(2)
+	respA := testUtilsHttp.MockResp(http.StatusOK, "a", nil, nil)
+	respB := testUtilsHttp.MockResp(http.StatusOK, "b", nil, nil)
+	respC := testUtilsHttp.MockResp(http.StatusOK, "c", nil, nil)
+
+	store.Set("a", respA, time.Minute)
+	store.Set("b", respB, time.Minute)
+	// "a" becomes the most recently used one, so "b" is evicted
+	_, ok := store.Get("a")
+	require.True(t, ok)
+	store.Set("c", respC, time.Minute)
+
+	assert.Equal(t, 2, store.Len())
+	_, ok = store.Get("b")
+	assert.False(t, ok)
+	resp, ok := store.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, respA, resp)
+	resp, ok = store.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, respC, resp)
+
+	// the expired response is removed
+	store.Set("a", respA, -time.Second)
+	_, ok = store.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, store.Len())
+}
@@ -33,9 +33,14 @@ func NewCaller(id string, dispatcher Dispatcher) (*Caller, error) {
 }
 
 // Dispatch uses Dispatcher to process incoming request and asynchronously sends
-// received response into the output channel. The output channel will be closed
+// received response into the output channel. If the response is a StreamingResponse, its frames
+// are sent into the output channel as they arrive. The output channel will be closed
 // after Dispatcher has processed request and response was sent back
 func (c *Caller) Dispatch(ctx context.Context, req Request) ResponseQueue {
+	return c.intercept(ctx, req, c.dispatch)
+}
+
+func (c *Caller) dispatch(ctx context.Context, req Request) ResponseQueue {
 	ctx = c.beforeDispatch(ctx, req)
 	out := make(chan Response, 1)
 	queue := NewResponseQueue(out, 1)
@@ -43,8 +48,58 @@ func (c *Caller) Dispatch(ctx context.Context, req Request) ResponseQueue {
 
 	go func() {
 		defer c.afterCompletion(ctx, req, queue)
-		out <- c.dispatcher.Do(req)
-		close(out)
+		defer close(out)
+		resp := c.do(ctx, req)
+		if stream, ok := resp.(StreamingResponse); ok {
+			for frame := range stream.Frames() {
+				select {
+				case out <- frame:
+				case <-ctx.Done():
+					// the stream is abandoned, its frames are discarded
+					// until the dispatcher finishes it
+				}
+			}
+			return
+		}
+		out <- resp
 	}()
 	return queue
 }
+
+// do dispatches the request by the dispatcher. If the dispatcher panics, the panic is logged
+// and the error response is returned instead
+func (c *Caller) do(ctx context.Context, req Request) (resp Response) {
+	defer func() {
+		if value := recover(); value != nil {
+			err := newPanicError(value)
+			ctxDispatchLogger(ctx).logPanic(ctx, c.ID(), err)
+			resp = panicResponse(req.Protocol(), err)
+		}
+	}()
+	return c.dispatcher.Do(ctx, req)
+}
+
+// Saturated returns true, if the dispatcher of the caller is saturated (see SaturationReporter)
+func (c *Caller) Saturated() bool {
+	return saturatedIfReporter(c.dispatcher)
+}
+
+// Describe adds the details of the dispatcher, that the caller sends its requests with, to the info,
+// e.g. the protocol and the timeout of the requests
+func (c *Caller) Describe(info *ComponentInfo) {
+	describeIfDescriber(c.dispatcher, info)
+}
+
+// Close closes the dispatcher of the caller (see Closer), e.g. the connections of its http or grpc client
+func (c *Caller) Close(ctx context.Context) error {
+	return closeIfCloser(ctx, c.dispatcher)
+}
+
+// WarmUp establishes the connection of the dispatcher of the caller to its backend, if it's a Warmer.
+// The failure is returned as WarmUpErrors with the ID of the caller
+func (c *Caller) WarmUp(ctx context.Context) error {
+	if err := warmUpIfWarmer(ctx, c.dispatcher); err != nil {
+		return WarmUpErrors{{RouteID: c.ID(), Err: err}}
+	}
+	return nil
+}
@@ -15,7 +15,7 @@ type MockDispatcher struct {
 	mock.Mock
 }
 
-func (h *MockDispatcher) Do(req fiber.Request) fiber.Response {
+func (h *MockDispatcher) Do(_ context.Context, req fiber.Request) fiber.Response {
 	args := h.Called(req)
 	if resp := args.Get(0); resp != nil {
 		return resp.(fiber.Response)
@@ -42,3 +42,22 @@ func TestCaller_Dispatch(t *testing.T) {
 
 	dispatcher.AssertExpectations(t)
 }
+
+// contextlessDispatcher is the dispatcher of the earlier versions of fiber, which doesn't receive the context
+type contextlessDispatcher struct {
+	response fiber.Response
+}
+
+func (d *contextlessDispatcher) Do(fiber.Request) fiber.Response {
+	return d.response
+}
+
+func TestCaller_DispatchContextless(t *testing.T) {
+	expectedResponse := testutils.MockResp(http.StatusOK, "**BODY**", nil, nil)
+	caller, err := fiber.NewCaller("", fiber.FromContextlessDispatcher(&contextlessDispatcher{expectedResponse}))
+	assert.NoError(t, err)
+
+	resp := <-caller.Dispatch(context.Background(), testutils.MockReq("GET", "http://:8080/test", "")).Iter()
+	assert.True(t, resp.IsSuccess())
+	assert.Equal(t, []byte("**BODY**"), resp.Payload())
+}
@@ -0,0 +1,229 @@
+package fiber
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	fiberErrors "github.com/gojek/fiber/errors"
+)
+
+// Defaults of the CircuitBreakerPolicy
+const (
+	DefaultCircuitFailureThreshold = 5
+	DefaultCircuitCooldown         = 10 * time.Second
+)
+
+// CircuitState is the state of the CircuitBreakingDispatcher
+type CircuitState int
+
+// States of the circuit breaker
+const (
+	// CircuitClosed lets all the requests through to the backend
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen lets a single probe request through to check, if the backend has recovered
+	CircuitHalfOpen
+	// CircuitOpen short-circuits all the requests
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitStateObserver is notified about the transitions of the circuit breakers. The dispatch metrics, that implement
+// this interface, get the states of the circuit breakers of the routes, initialized from the config
+type CircuitStateObserver interface {
+	RecordCircuitState(routeID string, state CircuitState)
+}
+
+// CircuitBreakerPolicy defines when the circuit to a backend is opened and for how long. The zero values
+// are replaced with the defaults
+type CircuitBreakerPolicy struct {
+	// FailureThreshold is the number of consecutive failures, after which the circuit is opened
+	FailureThreshold int
+	// Cooldown is the time the circuit stays open, before a probe request is let through
+	Cooldown time.Duration
+}
+
+// CircuitBreakingDispatcher is a Dispatcher, that stops sending the requests to the backend, after it has
+// failed FailureThreshold times in a row. While the circuit is open, the requests are immediately responded
+// with ErrCircuitOpen. After the Cooldown, the circuit becomes half-open and a single probe request
+// is dispatched: the circuit is closed, if it succeeds, or opened again otherwise.
+// Only the failures, that indicate the backend is unhealthy (http 5xx, 408 and 429, or grpc Unavailable,
+// DeadlineExceeded, ResourceExhausted, Internal and Unknown codes) are counted
+type CircuitBreakingDispatcher struct {
+	routeID    string
+	dispatcher Dispatcher
+	policy     CircuitBreakerPolicy
+	observer   CircuitStateObserver
+
+	lock     sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	// generation is incremented on each transition, so the outcomes of the requests, dispatched
+	// before the transition, are ignored
+	generation uint64
+}
+
+// NewCircuitBreakingDispatcher is a factory method, that creates a CircuitBreakingDispatcher around the
+// Dispatcher of the given route
+func NewCircuitBreakingDispatcher(
+	routeID string,
+	dispatcher Dispatcher,
+	policy CircuitBreakerPolicy,
+) (*CircuitBreakingDispatcher, error) {
+	if dispatcher == nil {
+		return nil, errors.New("circuit breaker: dispatcher can not be nil")
+	}
+	if policy.FailureThreshold < 0 || policy.Cooldown < 0 {
+		return nil, errors.New("circuit breaker: failure_threshold and cooldown can not be negative")
+	}
+
+	if policy.FailureThreshold == 0 {
+		policy.FailureThreshold = DefaultCircuitFailureThreshold
+	}
+	if policy.Cooldown == 0 {
+		policy.Cooldown = DefaultCircuitCooldown
+	}
+
+	return &CircuitBreakingDispatcher{
+		routeID:    routeID,
+		dispatcher: dispatcher,
+		policy:     policy,
+	}, nil
+}
+
+// WithObserver sets the observer, that is notified on each transition of the circuit
+func (d *CircuitBreakingDispatcher) WithObserver(observer CircuitStateObserver) *CircuitBreakingDispatcher {
+	d.observer = observer
+	if observer != nil {
+		observer.RecordCircuitState(d.routeID, d.State())
+	}
+	return d
+}
+
+// State returns the current state of the circuit
+func (d *CircuitBreakingDispatcher) State() CircuitState {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.state
+}
+
+// Do dispatches the request, unless the circuit is open, and updates the state of the circuit
+// according to the response. The requests, cancelled by the caller (e.g. the stragglers of a Combiner),
+// and the ones, that haven't reached the backend, since they have exceeded its rate limit
+// (see RateLimitedDispatcher), are not counted, since they don't indicate the failure of the backend
+func (d *CircuitBreakingDispatcher) Do(ctx context.Context, req Request) Response {
+	generation, ok := d.allow()
+	if !ok {
+		return NewErrorResponse(fiberErrors.ErrCircuitOpen(req.Protocol()))
+	}
+
+	resp := d.dispatcher.Do(ctx, req)
+	if ctx.Err() == context.Canceled || isRateLimited(req.Protocol(), resp) {
+		d.abandon(generation)
+		return resp
+	}
+	d.record(generation, !resp.IsSuccess() && isBackendFailure(req.Protocol(), resp.StatusCode()))
+	return resp
+}
+
+// allow checks, if the request can be dispatched, and lets the first request after the cooldown
+// through as the probe. The concurrent requests are short-circuited, until the probe completes
+func (d *CircuitBreakingDispatcher) allow() (uint64, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	switch d.state {
+	case CircuitClosed:
+		return d.generation, true
+	case CircuitOpen:
+		if time.Since(d.openedAt) >= d.policy.Cooldown {
+			d.transition(CircuitHalfOpen)
+			return d.generation, true
+		}
+	}
+	return d.generation, false
+}
+
+// record updates the state of the circuit with the outcome of the dispatched request
+func (d *CircuitBreakingDispatcher) record(generation uint64, failed bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if generation != d.generation {
+		return
+	}
+	switch {
+	case !failed:
+		d.failures = 0
+		if d.state != CircuitClosed {
+			d.transition(CircuitClosed)
+		}
+	case d.state == CircuitHalfOpen:
+		d.transition(CircuitOpen)
+	case d.state == CircuitClosed:
+		d.failures++
+		if d.failures >= d.policy.FailureThreshold {
+			d.transition(CircuitOpen)
+		}
+	}
+}
+
+// abandon gives up the probe of the half-open circuit, which request hasn't reached the backend or has been
+// cancelled, so the circuit is opened again. The cooldown is not restarted, since the backend hasn't failed,
+// so the next request is let through as the probe
+func (d *CircuitBreakingDispatcher) abandon(generation uint64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if generation == d.generation && d.state == CircuitHalfOpen {
+		openedAt := d.openedAt
+		d.transition(CircuitOpen)
+		d.openedAt = openedAt
+	}
+}
+
+// transition changes the state of the circuit and notifies the observer. It must be called with the lock held
+func (d *CircuitBreakingDispatcher) transition(state CircuitState) {
+	d.state = state
+	d.generation++
+	if state == CircuitOpen {
+		d.failures = 0
+		d.openedAt = time.Now()
+	}
+	if d.observer != nil {
+		d.observer.RecordCircuitState(d.routeID, state)
+	}
+}
+
+// Saturated returns true, if the underlying dispatcher is saturated, e.g. it's rate-limited
+// (see SaturationReporter)
+func (d *CircuitBreakingDispatcher) Saturated() bool {
+	return saturatedIfReporter(d.dispatcher)
+}
+
+// Describe adds the state of the circuit and the details of the underlying dispatcher to the info
+func (d *CircuitBreakingDispatcher) Describe(info *ComponentInfo) {
+	info.CircuitState = d.State().String()
+	describeIfDescriber(d.dispatcher, info)
+}
+
+// Close closes the dispatcher behind the circuit breaker (see Closer). The state of the circuit is left as it is
+func (d *CircuitBreakingDispatcher) Close(ctx context.Context) error {
+	return closeIfCloser(ctx, d.dispatcher)
+}
+
+// WarmUp establishes the connection of the underlying dispatcher to its backend, if it's a Warmer
+func (d *CircuitBreakingDispatcher) WarmUp(ctx context.Context) error {
+	return warmUpIfWarmer(ctx, d.dispatcher)
+}
@@ -0,0 +1,271 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// circuitStatesRecorder records the transitions of the circuit breakers
+type circuitStatesRecorder struct {
+	lock   sync.Mutex
+	states []fiber.CircuitState
+}
+
+func (r *circuitStatesRecorder) RecordCircuitState(_ string, state fiber.CircuitState) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.states = append(r.states, state)
+}
+
+// blockingDispatcher responds with the given response, once it's released
+type blockingDispatcher struct {
+	response fiber.Response
+	release  chan struct{}
+	attempts int64
+}
+
+func (d *blockingDispatcher) Do(context.Context, fiber.Request) fiber.Response {
+	atomic.AddInt64(&d.attempts, 1)
+	<-d.release
+	return d.response
+}
+
+func TestNewCircuitBreakingDispatcher(t *testing.T) {
+	suite := map[string]struct {
+		dispatcher  fiber.Dispatcher
+		policy      fiber.CircuitBreakerPolicy
+		expectedErr string
+	}{
+		"ok: defaults": {
+			dispatcher: &sequenceDispatcher{},
+		},
+		"error: nil dispatcher": {
+			expectedErr: "circuit breaker: dispatcher can not be nil",
+		},
+		"error: negative threshold": {
+			dispatcher:  &sequenceDispatcher{},
+			policy:      fiber.CircuitBreakerPolicy{FailureThreshold: -1},
+			expectedErr: "circuit breaker: failure_threshold and cooldown can not be negative",
+		},
+		"error: negative cooldown": {
+			dispatcher:  &sequenceDispatcher{},
+			policy:      fiber.CircuitBreakerPolicy{Cooldown: -time.Second},
+			expectedErr: "circuit breaker: failure_threshold and cooldown can not be negative",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			breaker, err := fiber.NewCircuitBreakingDispatcher("route-a", tt.dispatcher, tt.policy)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+				assert.Equal(t, fiber.CircuitClosed, breaker.State())
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakingDispatcher_Do(t *testing.T) {
+	unavailable := testUtilsHttp.MockResp(
+		http.StatusServiceUnavailable, "", nil, fiberErrors.ErrServiceUnavailable(protocol.HTTP))
+	notFound := testUtilsHttp.MockResp(http.StatusNotFound, "", nil, nil)
+	ok := testUtilsHttp.MockResp(http.StatusOK, "OK", nil, nil)
+	grpcUnavailable := fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.GRPC))
+	shortCircuited := fiber.NewErrorResponse(fiberErrors.ErrCircuitOpen(protocol.HTTP))
+
+	httpReq := testUtilsHttp.MockReq("GET", "http://localhost:8080/circuit", "")
+
+	suite := map[string]struct {
+		request          fiber.Request
+		responses        []fiber.Response
+		requests         int
+		expected         fiber.Response
+		expectedAttempts int
+		expectedState    fiber.CircuitState
+	}{
+		"opens after consecutive failures": {
+			request:          httpReq,
+			responses:        []fiber.Response{unavailable},
+			requests:         5,
+			expected:         shortCircuited,
+			expectedAttempts: 3,
+			expectedState:    fiber.CircuitOpen,
+		},
+		"success resets the failures": {
+			request:          httpReq,
+			responses:        []fiber.Response{unavailable, unavailable, ok, unavailable, unavailable},
+			requests:         5,
+			expected:         unavailable,
+			expectedAttempts: 5,
+			expectedState:    fiber.CircuitClosed,
+		},
+		"client errors are not counted": {
+			request:          httpReq,
+			responses:        []fiber.Response{notFound},
+			requests:         5,
+			expected:         notFound,
+			expectedAttempts: 5,
+			expectedState:    fiber.CircuitClosed,
+		},
+		"grpc unavailable is counted": {
+			request:          &grpcRequest{Request: httpReq},
+			responses:        []fiber.Response{grpcUnavailable},
+			requests:         4,
+			expected:         fiber.NewErrorResponse(fiberErrors.ErrCircuitOpen(protocol.GRPC)),
+			expectedAttempts: 3,
+			expectedState:    fiber.CircuitOpen,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			dispatcher := &sequenceDispatcher{responses: tt.responses}
+			breaker, err := fiber.NewCircuitBreakingDispatcher("route-a", dispatcher, fiber.CircuitBreakerPolicy{
+				FailureThreshold: 3,
+				Cooldown:         time.Minute,
+			})
+			require.NoError(t, err)
+
+			var resp fiber.Response
+			for idx := 0; idx < tt.requests; idx++ {
+				resp = breaker.Do(context.Background(), tt.request)
+			}
+			assert.Equal(t, tt.expected, resp)
+			assert.Equal(t, tt.expectedAttempts, dispatcher.attempts)
+			assert.Equal(t, tt.expectedState, breaker.State())
+		})
+	}
+}
+
+func TestCircuitBreakingDispatcher_HalfOpen(t *testing.T) {
+	unavailable := fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP))
+	ok := testUtilsHttp.MockResp(http.StatusOK, "OK", nil, nil)
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/circuit", "")
+
+	dispatcher := &sequenceDispatcher{responses: []fiber.Response{unavailable, unavailable, ok}}
+	recorder := &circuitStatesRecorder{}
+	breaker, err := fiber.NewCircuitBreakingDispatcher("route-a", dispatcher, fiber.CircuitBreakerPolicy{
+		FailureThreshold: 1,
+		Cooldown:         20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	breaker.WithObserver(recorder)
+
+	breaker.Do(context.Background(), req)
+	assert.Equal(t, fiber.CircuitOpen, breaker.State())
+
+	// the failed probe opens the circuit again
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, unavailable, breaker.Do(context.Background(), req))
+	assert.Equal(t, fiber.CircuitOpen, breaker.State())
+	assert.Equal(t, 2, dispatcher.attempts)
+
+	// the successful probe closes the circuit
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, ok, breaker.Do(context.Background(), req))
+	assert.Equal(t, fiber.CircuitClosed, breaker.State())
+	assert.Equal(t, 3, dispatcher.attempts)
+
+	assert.Equal(t, []fiber.CircuitState{
+		fiber.CircuitClosed,
+		fiber.CircuitOpen,
+		fiber.CircuitHalfOpen,
+		fiber.CircuitOpen,
+		fiber.CircuitHalfOpen,
+		fiber.CircuitClosed,
+	}, recorder.states)
+}
+
+func TestCircuitBreakingDispatcher_SingleProbe(t *testing.T) {
+	ok := testUtilsHttp.MockResp(http.StatusOK, "OK", nil, nil)
+	shortCircuited := fiber.NewErrorResponse(fiberErrors.ErrCircuitOpen(protocol.HTTP))
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/circuit", "")
+
+	dispatcher := &blockingDispatcher{
+		response: fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+		release:  make(chan struct{}, 1),
+	}
+	breaker, err := fiber.NewCircuitBreakingDispatcher("route-a", dispatcher, fiber.CircuitBreakerPolicy{
+		FailureThreshold: 1,
+		Cooldown:         10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	dispatcher.release <- struct{}{}
+	breaker.Do(context.Background(), req)
+	require.Equal(t, fiber.CircuitOpen, breaker.State())
+	time.Sleep(20 * time.Millisecond)
+
+	dispatcher.response = ok
+	probe := make(chan fiber.Response)
+	go func() {
+		probe <- breaker.Do(context.Background(), req)
+	}()
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&dispatcher.attempts) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, fiber.CircuitHalfOpen, breaker.State())
+
+	// the requests are short-circuited, while the probe is in flight
+	var wg sync.WaitGroup
+	for idx := 0; idx < 10; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, shortCircuited, breaker.Do(context.Background(), req))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(2), atomic.LoadInt64(&dispatcher.attempts))
+
+	close(dispatcher.release)
+	assert.Equal(t, ok, <-probe)
+	assert.Equal(t, fiber.CircuitClosed, breaker.State())
+}
+
+func TestCircuitBreakingDispatcher_Cancelled(t *testing.T) {
+	unavailable := fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP))
+	ok := testUtilsHttp.MockResp(http.StatusOK, "OK", nil, nil)
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/circuit", "")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dispatcher := &sequenceDispatcher{responses: []fiber.Response{unavailable, unavailable, unavailable, unavailable, ok}}
+	breaker, err := fiber.NewCircuitBreakingDispatcher("route-a", dispatcher, fiber.CircuitBreakerPolicy{
+		FailureThreshold: 1,
+		Cooldown:         20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	// the cancelled requests are not counted as failures
+	breaker.Do(cancelled, req)
+	breaker.Do(cancelled, req)
+	assert.Equal(t, fiber.CircuitClosed, breaker.State())
+
+	// the cancelled probe doesn't leave the circuit half-open
+	breaker.Do(context.Background(), req)
+	require.Equal(t, fiber.CircuitOpen, breaker.State())
+	time.Sleep(30 * time.Millisecond)
+	breaker.Do(cancelled, req)
+	assert.Equal(t, fiber.CircuitOpen, breaker.State())
+	assert.Equal(t, 4, dispatcher.attempts)
+
+	// the cooldown is not restarted, so the next request is the probe
+	resp := breaker.Do(context.Background(), req)
+	assert.True(t, resp.IsSuccess())
+	assert.Equal(t, fiber.CircuitClosed, breaker.State())
+	assert.Equal(t, 5, dispatcher.attempts)
+}
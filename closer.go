@@ -0,0 +1,76 @@
+package fiber
+
+import (
+	"context"
+	"sync"
+)
+
+// Closer is implemented by the components and the dispatchers, that hold the resources (i.e. grpc
+// connections or http transports), which have to be released, when they are not used anymore.
+// Routers stop accepting new requests on Close, and wait for the in-flight dispatches to complete,
+// before their routes are closed
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// closeIfCloser closes the component or the dispatcher, if it's a Closer
+func closeIfCloser(ctx context.Context, closable interface{}) error {
+	if closer, ok := closable.(Closer); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+// dispatchGate tracks the in-flight dispatches of a component, and rejects the new ones,
+// once the component is closed
+type dispatchGate struct {
+	lock     sync.Mutex
+	closed   bool
+	inFlight sync.WaitGroup
+	release  sync.Once
+}
+
+// enter registers the new dispatch, unless the component is closed. If it returns true,
+// leave has to be called, when the dispatch is complete
+func (g *dispatchGate) enter() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.closed {
+		return false
+	}
+	g.inFlight.Add(1)
+	return true
+}
+
+// leave marks the dispatch, registered by enter, as complete
+func (g *dispatchGate) leave() {
+	g.inFlight.Done()
+}
+
+// close stops accepting new dispatches, waits for the in-flight ones to complete, until the context
+// is done, and then releases the resources of the component once. The error of the context is returned,
+// if it's done before the in-flight dispatches are complete
+func (g *dispatchGate) close(ctx context.Context, release func(ctx context.Context) error) error {
+	g.lock.Lock()
+	g.closed = true
+	g.lock.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		g.inFlight.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	g.release.Do(func() {
+		if releaseErr := release(ctx); err == nil {
+			err = releaseErr
+		}
+	})
+	return err
+}
@@ -0,0 +1,127 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowComponent responds to each request with a new successful response after the latency,
+// and records, if it has been closed
+type slowComponent struct {
+	*fiber.BaseComponent
+	latency time.Duration
+
+	completed int32
+	closed    int32
+}
+
+func newSlowComponent(id string, latency time.Duration) *slowComponent {
+	return &slowComponent{BaseComponent: fiber.NewBaseComponent(id, fiber.CallerKind), latency: latency}
+}
+
+func (c *slowComponent) Dispatch(context.Context, fiber.Request) fiber.ResponseQueue {
+	out := make(chan fiber.Response, 1)
+	go func() {
+		time.Sleep(c.latency)
+		atomic.AddInt32(&c.completed, 1)
+		out <- testUtilsHttp.MockResp(http.StatusOK, c.ID(), nil, nil)
+		close(out)
+	}()
+	return fiber.NewResponseQueue(out, 1)
+}
+
+func (c *slowComponent) Close(context.Context) error {
+	atomic.AddInt32(&c.closed, 1)
+	return nil
+}
+
+type closableRouter interface {
+	fiber.Router
+	fiber.Closer
+}
+
+var closableRouters = map[string]func(id string) closableRouter{
+	"lazy router":  func(id string) closableRouter { return fiber.NewLazyRouter(id) },
+	"eager router": func(id string) closableRouter { return fiber.NewEagerRouter(id) },
+}
+
+func newClosableRouter(newRouter func(id string) closableRouter, route fiber.Component) closableRouter {
+	routes := map[string]fiber.Component{route.ID(): route}
+	router := newRouter("router")
+	router.SetRoutes(routes)
+	router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{route.ID()}, 0, nil))
+	return router
+}
+
+func TestRouter_Close(t *testing.T) {
+	for name, newRouter := range closableRouters {
+		t.Run(name, func(t *testing.T) {
+			route := newSlowComponent("route-a", 100*time.Millisecond)
+			router := newClosableRouter(newRouter, route)
+
+			var wg sync.WaitGroup
+			responses := make([]fiber.Response, 5)
+			for i := range responses {
+				queue := router.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", ""))
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					responses[i] = <-queue.Iter()
+				}(i)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			require.NoError(t, router.Close(ctx))
+
+			// the in-flight dispatches are complete, before the routes are closed
+			assert.Equal(t, int32(len(responses)), atomic.LoadInt32(&route.completed))
+			assert.Equal(t, int32(1), atomic.LoadInt32(&route.closed))
+			wg.Wait()
+			for _, resp := range responses {
+				require.NotNil(t, resp)
+				assert.True(t, resp.IsSuccess())
+				assert.Equal(t, "route-a", string(resp.Payload()))
+			}
+
+			// the new dispatches are rejected
+			resp := <-router.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+			assert.Equal(t, fiber.NewErrorResponse(fiberErrors.ErrComponentClosed(protocol.HTTP)), resp)
+
+			// the routes are closed only once
+			require.NoError(t, router.Close(ctx))
+			assert.Equal(t, int32(1), atomic.LoadInt32(&route.closed))
+		})
+	}
+}
+
+func TestRouter_CloseDeadlineExceeded(t *testing.T) {
+	for name, newRouter := range closableRouters {
+		t.Run(name, func(t *testing.T) {
+			route := newSlowComponent("route-a", 500*time.Millisecond)
+			router := newClosableRouter(newRouter, route)
+			queue := router.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", ""))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			assert.Equal(t, context.DeadlineExceeded, router.Close(ctx))
+
+			// the routes are closed, even though the dispatch is still in-flight
+			assert.Equal(t, int32(0), atomic.LoadInt32(&route.completed))
+			assert.Equal(t, int32(1), atomic.LoadInt32(&route.closed))
+			<-queue.Iter()
+		})
+	}
+}
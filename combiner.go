@@ -2,18 +2,23 @@ package fiber
 
 import (
 	"context"
+	"sort"
 
+	"github.com/gojek/fiber/errors"
 	"github.com/gojek/fiber/util"
 )
 
 // Combiner is a network component, that uses BaseFanOut to dispatch incoming request
 // by all of its sub-routes and then merge all the responseQueue from them into a single
-// response, using provided FanIn
+// response, using provided FanIn. Each response, that the FanIn receives, carries the ID
+// of the route, that it came from (see RouteID)
 type Combiner struct {
 	BaseComponent
 	FanOut
 
-	fanIn FanIn
+	fanIn      FanIn
+	routeOrder map[string]int
+	gate       dispatchGate
 }
 
 // NewCombiner is a factory for the Combiner type.
@@ -44,10 +49,38 @@ func (c *Combiner) WithFanIn(fanIn FanIn) *Combiner {
 	return c
 }
 
+// WithRouteOrder makes the Combiner deliver the responses of its routes to the FanIn in the given order
+// of the route IDs, rather than in the order they arrive, e.g. for the FanIn-s, that merge the responses
+// in a stable order. The responses are delivered, once all the routes have responded (or the request is done),
+// so the latency-sensitive FanIn-s should keep the arrival order, which is the default. The responses of
+// the routes, that are not in the order, follow the rest, sorted by their route IDs. No route IDs restore
+// the arrival order
+func (c *Combiner) WithRouteOrder(routeIDs ...string) *Combiner {
+	c.routeOrder = nil
+	if len(routeIDs) > 0 {
+		c.routeOrder = make(map[string]int, len(routeIDs))
+		for idx, id := range routeIDs {
+			if _, ok := c.routeOrder[id]; !ok {
+				c.routeOrder[id] = idx
+			}
+		}
+	}
+	return c
+}
+
 // Dispatch method on the Combiner will ask its embedded dispatcher to simultaneously
 // dispatch the incoming request by all of its nested components. After that, Combiner's FanIn
-// listens to responseQueue and aggregate them into a single response, that is being sent to output
+// listens to responseQueue and aggregate them into a single response, that is being sent to output.
+// The dispatches, that are still in-flight, when the response is aggregated, are cancelled
 func (c *Combiner) Dispatch(ctx context.Context, req Request) ResponseQueue {
+	return c.intercept(ctx, req, c.dispatch)
+}
+
+func (c *Combiner) dispatch(ctx context.Context, req Request) ResponseQueue {
+	if !c.gate.enter() {
+		return NewResponseQueueFromResponses(NewErrorResponse(errors.ErrComponentClosed(req.Protocol())))
+	}
+
 	ctx = c.beforeDispatch(ctx, req)
 	out := make(chan Response, 1)
 
@@ -55,15 +88,72 @@ func (c *Combiner) Dispatch(ctx context.Context, req Request) ResponseQueue {
 	defer c.afterDispatch(ctx, req, queue)
 
 	go func() {
+		defer c.gate.leave()
 		defer c.afterCompletion(ctx, req, queue)
 
-		out <- c.fanIn.Aggregate(ctx, req, c.FanOut.Dispatch(ctx, req))
+		// the fan-out is cancelled, once the fan-in doesn't need the responses of the routes anymore
+		fanOutCtx, cancel := context.WithCancel(ctx)
+		responses := c.FanOut.Dispatch(fanOutCtx, req)
+		if c.routeOrder != nil {
+			responses = sortByRoute(responses, c.routeOrder)
+		}
+		out <- safeAggregate(fanOutCtx, c.ID(), c.fanIn, req, responses)
+		cancel()
 		close(out)
 	}()
 
 	return queue
 }
 
+// sortByRoute returns the queue of all the responses of the given queue, sorted by the order of their routes.
+// The responses of the same route keep their order
+func sortByRoute(queue ResponseQueue, order map[string]int) ResponseQueue {
+	out := make(chan Response, 1)
+	go func() {
+		defer close(out)
+
+		var responses []Response
+		for resp := range queue.Iter() {
+			responses = append(responses, resp)
+		}
+		rank := func(routeID string) int {
+			if idx, ok := order[routeID]; ok {
+				return idx
+			}
+			return len(order)
+		}
+		sort.SliceStable(responses, func(i, j int) bool {
+			left, right := RouteID(responses[i]), RouteID(responses[j])
+			if rank(left) != rank(right) {
+				return rank(left) < rank(right)
+			}
+			return left < right
+		})
+		for _, resp := range responses {
+			out <- resp
+		}
+	}()
+	return NewResponseQueue(out, 1)
+}
+
+// Close stops accepting new requests, that are responded with ErrComponentClosed, waits for the in-flight
+// dispatches to complete, until the context is done, and then closes the routes of the combiner
+func (c *Combiner) Close(ctx context.Context) error {
+	return c.gate.close(ctx, func(ctx context.Context) error {
+		return closeIfCloser(ctx, c.FanOut)
+	})
+}
+
+// Describe adds the descriptions of the routes of the combiner to the info
+func (c *Combiner) Describe(info *ComponentInfo) {
+	info.Routes = describeRoutes(c.FanOut.GetRoutes())
+}
+
+// WarmUp warms up all the routes of the combiner concurrently (see Warmer)
+func (c *Combiner) WarmUp(ctx context.Context) error {
+	return warmUpRoutes(ctx, c.FanOut.GetRoutes())
+}
+
 // AddInterceptor can be used to add the given interceptor to the Combiner and optionally,
 // to all its nested components.
 func (c *Combiner) AddInterceptor(recursive bool, interceptor ...Interceptor) {
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/internal/testutils"
 	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
 	"github.com/gojek/fiber/util"
 	"github.com/stretchr/testify/assert"
@@ -105,3 +106,60 @@ func TestCombiner_Id(t *testing.T) {
 	combiner := fiber.NewCombiner(id)
 	assert.Equal(t, id, combiner.ID())
 }
+
+// routeOrderFanIn records the route IDs of the responses in the order it receives them
+type routeOrderFanIn struct {
+	fiber.BaseFanIn
+	routes []string
+}
+
+func (fanIn *routeOrderFanIn) Aggregate(_ context.Context, _ fiber.Request, queue fiber.ResponseQueue) fiber.Response {
+	var last fiber.Response
+	for resp := range queue.Iter() {
+		fanIn.routes = append(fanIn.routes, fiber.RouteID(resp))
+		last = resp
+	}
+	return last
+}
+
+func TestCombiner_RouteOrder(t *testing.T) {
+	suite := map[string]struct {
+		order    []string
+		expected []string
+	}{
+		"arrival order": {
+			expected: []string{"route-c", "route-b", "route-a"},
+		},
+		"route order": {
+			order:    []string{"route-a", "route-b", "route-c"},
+			expected: []string{"route-a", "route-b", "route-c"},
+		},
+		"routes out of order follow the rest": {
+			order:    []string{"route-b"},
+			expected: []string{"route-b", "route-a", "route-c"},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			fanIn := &routeOrderFanIn{}
+			combiner := fiber.NewCombiner("combiner").WithFanIn(fanIn).WithRouteOrder(tt.order...)
+			combiner.SetRoutes(map[string]fiber.Component{
+				"route-a": testutils.NewMockComponent("route-a", testUtilsHttp.DelayedResponse{
+					Latency:  60 * time.Millisecond,
+					Response: testUtilsHttp.MockResp(200, "A-OK", nil, nil),
+				}),
+				"route-b": testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+					Latency:  30 * time.Millisecond,
+					Response: testUtilsHttp.MockResp(200, "B-OK", nil, nil),
+				}),
+				"route-c": testutils.NewMockComponent("route-c", testUtilsHttp.DelayedResponse{
+					Response: testUtilsHttp.MockResp(200, "C-OK", nil, nil),
+				}),
+			})
+
+			testutils.Dispatch(context.Background(), combiner, testUtilsHttp.MockReq("POST", "http://combiner:8080", ""))
+			assert.Equal(t, tt.expected, fanIn.routes)
+		})
+	}
+}
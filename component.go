@@ -1,6 +1,10 @@
 package fiber
 
-import "context"
+import (
+	"context"
+
+	"github.com/gojek/fiber/errors"
+)
 
 // ComponentKind can be used to define the types of Fiber components
 // that support the Component interface
@@ -61,24 +65,83 @@ func (c *BaseComponent) beforeDispatch(ctx context.Context, req Request) context
 	ctx = context.WithValue(ctx, CtxComponentIDKey, c.ID())
 	ctx = context.WithValue(ctx, CtxComponentKindKey, c.Kind())
 	for _, i := range c.interceptors {
-		ctx = i.BeforeDispatch(ctx, req)
+		ctx = c.safeBeforeDispatch(ctx, i, req)
 	}
 	return ctx
 }
 
+// safeBeforeDispatch calls BeforeDispatch of the interceptor. If the interceptor panics,
+// the panic is logged and the request context is left as it is
+func (c *BaseComponent) safeBeforeDispatch(ctx context.Context, i Interceptor, req Request) (next context.Context) {
+	defer func() {
+		if value := recover(); value != nil {
+			next = ctx
+			ctxDispatchLogger(ctx).logPanic(ctx, c.ID(), newPanicError(value))
+		}
+	}()
+	return i.BeforeDispatch(ctx, req)
+}
+
 func (c *BaseComponent) afterDispatch(ctx context.Context, req Request, queue ResponseQueue) {
 	for _, i := range c.interceptors {
-		go i.AfterDispatch(ctx, req, queue)
+		i := i
+		safeGo(ctx, c.ID(), func() { i.AfterDispatch(ctx, req, queue) })
 	}
 }
 
 func (c *BaseComponent) afterCompletion(ctx context.Context, req Request, queue ResponseQueue) {
 	for _, i := range c.interceptors {
-		go i.AfterCompletion(ctx, req, queue)
+		i := i
+		safeGo(ctx, c.ID(), func() { i.AfterCompletion(ctx, req, queue) })
 	}
 }
 
-// AddInterceptor can be used to add one or more interceptors to the BaseComponent
+// intercept passes the request through the interceptors, that are the RequestInterceptor-s, dispatches it
+// with the given function, and passes each of its responses through the interceptors, that are
+// the ResponseInterceptor-s, in the order the interceptors were added. If a RequestInterceptor fails,
+// the request is not dispatched, and its error is sent back as the response
+func (c *BaseComponent) intercept(
+	ctx context.Context,
+	req Request,
+	dispatch func(ctx context.Context, req Request) ResponseQueue,
+) ResponseQueue {
+	var responseInterceptors []ResponseInterceptor
+	for _, i := range c.interceptors {
+		if interceptor, ok := i.(RequestInterceptor); ok {
+			intercepted, err := interceptor.InterceptRequest(ctx, req)
+			if err != nil {
+				return NewResponseQueueFromResponses(NewErrorResponse(errors.NewFiberError(req.Protocol(), err)))
+			}
+			req = intercepted
+		}
+		if interceptor, ok := i.(ResponseInterceptor); ok {
+			responseInterceptors = append(responseInterceptors, interceptor)
+		}
+	}
+
+	queue := dispatch(ctx, req)
+	if len(responseInterceptors) == 0 {
+		return queue
+	}
+
+	// the channel is buffered and drained by the returned queue, so the responses are forwarded,
+	// even if the queue is never iterated
+	out := make(chan Response, 1)
+	go func() {
+		defer close(out)
+		for resp := range queue.Iter() {
+			for _, interceptor := range responseInterceptors {
+				resp = interceptor.InterceptResponse(ctx, req, resp)
+			}
+			out <- resp
+		}
+	}()
+	return NewResponseQueue(out, 1)
+}
+
+// AddInterceptor can be used to add one or more interceptors to the BaseComponent. The interceptors,
+// that are also the RequestInterceptor-s or the ResponseInterceptor-s, transform the requests
+// and the responses of the component
 func (c *BaseComponent) AddInterceptor(recursive bool, interceptors ...Interceptor) {
 	c.interceptors = append(c.interceptors, interceptors...)
 }
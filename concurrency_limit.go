@@ -0,0 +1,138 @@
+package fiber
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	fiberErrors "github.com/gojek/fiber/errors"
+)
+
+// SaturationReporter is implemented by the routes, that limit the number of their requests in flight
+// (see ConcurrencyLimitedComponent) or the rate of their requests (see RateLimitedDispatcher). LazyRouter
+// doesn't select the saturated routes, and sheds the request with ErrComponentSaturated, if all of its routes
+// are saturated. EagerRouter dispatches the request by all of its routes, so the saturated ones fail it,
+// and the router falls back to the next route
+type SaturationReporter interface {
+	Saturated() bool
+}
+
+// saturatedIfReporter returns true, if the component or the dispatcher is a saturated SaturationReporter
+func saturatedIfReporter(value interface{}) bool {
+	reporter, ok := value.(SaturationReporter)
+	return ok && reporter.Saturated()
+}
+
+// ConcurrencyLimitedComponent is a Component, that dispatches at most maxInFlight requests at the same time,
+// so the backend is protected from the spikes of the load. The excess requests are immediately responded
+// with ErrComponentSaturated, so the router falls back to the next route. A request is in flight, until
+// all of its responses are received, or the component has panicked, while dispatching it
+type ConcurrencyLimitedComponent struct {
+	Component
+	maxInFlight int64
+	inFlight    int64
+}
+
+// NewConcurrencyLimitedComponent is a factory method, that creates a ConcurrencyLimitedComponent, which
+// dispatches at most maxInFlight requests by the given component at the same time
+func NewConcurrencyLimitedComponent(component Component, maxInFlight int) (*ConcurrencyLimitedComponent, error) {
+	if component == nil {
+		return nil, errors.New("concurrency limit: component can not be nil")
+	}
+	if maxInFlight <= 0 {
+		return nil, errors.New("concurrency limit: max_in_flight must be positive")
+	}
+	return &ConcurrencyLimitedComponent{
+		Component:   component,
+		maxInFlight: int64(maxInFlight),
+	}, nil
+}
+
+// Dispatch dispatches the request by the component, unless maxInFlight requests are already in flight,
+// in which case ErrComponentSaturated is sent back. If the request is abandoned (i.e. its context is done),
+// its remaining responses are discarded, and it's released, once the component has completed it
+func (c *ConcurrencyLimitedComponent) Dispatch(ctx context.Context, req Request) ResponseQueue {
+	if atomic.AddInt64(&c.inFlight, 1) > c.maxInFlight {
+		c.release()
+		return NewResponseQueueFromResponses(NewErrorResponse(fiberErrors.ErrComponentSaturated(req.Protocol())))
+	}
+
+	in := c.dispatch(ctx, req)
+	out := make(chan Response, 1)
+	go func() {
+		defer c.release()
+		defer close(out)
+		for resp := range in.Iter() {
+			select {
+			case out <- resp:
+			case <-ctx.Done():
+				// the response is discarded, but the queue is drained, so the request
+				// is released, once the component has completed it
+			}
+		}
+	}()
+	return NewResponseQueue(out, 1)
+}
+
+// dispatch dispatches the request by the component. The request is released, if the component panics
+func (c *ConcurrencyLimitedComponent) dispatch(ctx context.Context, req Request) ResponseQueue {
+	defer func() {
+		if value := recover(); value != nil {
+			c.release()
+			panic(value)
+		}
+	}()
+	return c.Component.Dispatch(ctx, req)
+}
+
+// release marks the request as completed
+func (c *ConcurrencyLimitedComponent) release() {
+	atomic.AddInt64(&c.inFlight, -1)
+}
+
+// InFlight returns the number of the requests, that are in flight
+func (c *ConcurrencyLimitedComponent) InFlight() int {
+	return int(atomic.LoadInt64(&c.inFlight))
+}
+
+// Saturated returns true, if maxInFlight requests are already in flight, or the component is saturated itself
+func (c *ConcurrencyLimitedComponent) Saturated() bool {
+	return atomic.LoadInt64(&c.inFlight) >= c.maxInFlight || saturatedIfReporter(c.Component)
+}
+
+// Healthy returns the health of the component, if it's health-checked (see HealthReporter), or true otherwise
+func (c *ConcurrencyLimitedComponent) Healthy() bool {
+	reporter, ok := c.Component.(HealthReporter)
+	return !ok || reporter.Healthy()
+}
+
+// Describe adds the number of the requests in flight and the details of the component to the info
+func (c *ConcurrencyLimitedComponent) Describe(info *ComponentInfo) {
+	inFlight := c.InFlight()
+	info.InFlight = &inFlight
+	describeIfDescriber(c.Component, info)
+}
+
+// Close closes the component, which requests in flight are limited (see Closer)
+func (c *ConcurrencyLimitedComponent) Close(ctx context.Context) error {
+	return closeIfCloser(ctx, c.Component)
+}
+
+// WarmUp warms up the component, if it's a Warmer
+func (c *ConcurrencyLimitedComponent) WarmUp(ctx context.Context) error {
+	return warmUpIfWarmer(ctx, c.Component)
+}
+
+// unsaturatedRoutes returns the routes, that are not saturated
+func unsaturatedRoutes(routes map[string]Component) map[string]Component {
+	unsaturated := make(map[string]Component, len(routes))
+	for id, route := range routes {
+		if reporter, ok := route.(SaturationReporter); !ok || !reporter.Saturated() {
+			unsaturated[id] = route
+		}
+	}
+	if len(unsaturated) == len(routes) {
+		return routes
+	}
+	return unsaturated
+}
@@ -0,0 +1,141 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConcurrencyLimitedComponent(t *testing.T) {
+	_, err := fiber.NewConcurrencyLimitedComponent(nil, 1)
+	assert.EqualError(t, err, "concurrency limit: component can not be nil")
+
+	_, err = fiber.NewConcurrencyLimitedComponent(newSlowComponent("route-a", 0), 0)
+	assert.EqualError(t, err, "concurrency limit: max_in_flight must be positive")
+}
+
+func TestConcurrencyLimitedComponent_Dispatch(t *testing.T) {
+	component, err := fiber.NewConcurrencyLimitedComponent(newSlowComponent("route-a", 50*time.Millisecond), 1)
+	require.NoError(t, err)
+	req := testUtilsHttp.MockReq("GET", "http://localhost", "")
+
+	first := component.Dispatch(context.Background(), req)
+	assert.Equal(t, 1, component.InFlight())
+	assert.True(t, component.Saturated())
+
+	// the overflow request is responded immediately
+	resp := <-component.Dispatch(context.Background(), req).Iter()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	assert.Equal(t, 1, component.InFlight())
+
+	resp = <-first.Iter()
+	assert.Equal(t, "route-a", string(resp.Payload()))
+	require.Eventually(t, func() bool { return component.InFlight() == 0 }, time.Second, time.Millisecond)
+	assert.False(t, component.Saturated())
+}
+
+func TestConcurrencyLimitedComponent_DispatchPanic(t *testing.T) {
+	broken, err := fiber.NewCaller("route-a", panickingDispatcher{})
+	require.NoError(t, err)
+	component, err := fiber.NewConcurrencyLimitedComponent(broken, 1)
+	require.NoError(t, err)
+
+	// the panic of the dispatcher is recovered by the caller, and the request is released
+	resp := <-component.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode())
+	require.Eventually(t, func() bool { return component.InFlight() == 0 }, time.Second, time.Millisecond)
+
+	// the panic of the component itself is released too
+	component, err = fiber.NewConcurrencyLimitedComponent(&panickingComponent{
+		BaseComponent: fiber.NewBaseComponent("route-a", fiber.CallerKind),
+	}, 1)
+	require.NoError(t, err)
+	assert.Panics(t, func() {
+		component.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", ""))
+	})
+	assert.Equal(t, 0, component.InFlight())
+}
+
+func TestConcurrencyLimitedComponent_DispatchAbandoned(t *testing.T) {
+	slow := newSlowComponent("route-a", 20*time.Millisecond)
+	component, err := fiber.NewConcurrencyLimitedComponent(slow, 1)
+	require.NoError(t, err)
+
+	// the request is released, once the component has completed it, even if its responses are never received
+	ctx, cancel := context.WithCancel(context.Background())
+	component.Dispatch(ctx, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+	cancel()
+	assert.Equal(t, 1, component.InFlight())
+	require.Eventually(t, func() bool { return component.InFlight() == 0 }, time.Second, time.Millisecond)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&slow.completed))
+}
+
+// panickingComponent panics, when the request is dispatched by it
+type panickingComponent struct {
+	*fiber.BaseComponent
+}
+
+func (c *panickingComponent) Dispatch(context.Context, fiber.Request) fiber.ResponseQueue {
+	panic("component is broken")
+}
+
+func TestRouter_SkipsSaturatedRoutes(t *testing.T) {
+	for name, newRouter := range map[string]func() fiber.Router{
+		"lazy router":  func() fiber.Router { return fiber.NewLazyRouter("lazy-router") },
+		"eager router": func() fiber.Router { return fiber.NewEagerRouter("eager-router") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			routes := make(map[string]fiber.Component)
+			for _, id := range []string{"route-a", "route-b"} {
+				route, err := fiber.NewConcurrencyLimitedComponent(newSlowComponent(id, 100*time.Millisecond), 1)
+				require.NoError(t, err)
+				routes[id] = route
+			}
+			router := newRouter()
+			router.SetRoutes(routes)
+			router.SetStrategy(&orderedRoutingStrategy{order: []string{"route-a", "route-b"}})
+			dispatch := func() fiber.ResponseQueue {
+				return router.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", ""))
+			}
+			saturated := func(id string) func() bool {
+				return func() bool { return routes[id].(fiber.SaturationReporter).Saturated() }
+			}
+
+			first := dispatch()
+			require.Eventually(t, saturated("route-a"), time.Second, time.Millisecond)
+			var second fiber.ResponseQueue
+			if name == "lazy router" {
+				// the primary route is saturated by the first request, so the second one is dispatched by the fallback
+				second = dispatch()
+				require.Eventually(t, saturated("route-b"), time.Second, time.Millisecond)
+			} else {
+				// the eager router has dispatched the first request by all of its routes
+				require.Eventually(t, saturated("route-b"), time.Second, time.Millisecond)
+			}
+
+			// all the routes are saturated, so the overflow request is shed
+			resp := <-dispatch().Iter()
+			assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+			assert.Equal(t, "route-a", string((<-first.Iter()).Payload()))
+			if second != nil {
+				assert.Equal(t, fiber.NewErrorResponse(fiberErrors.ErrComponentSaturated(protocol.HTTP)).Payload(),
+					resp.Payload())
+				assert.Equal(t, "route-b", string((<-second.Iter()).Payload()))
+			}
+
+			// the routes are released, once they have responded
+			require.Eventually(t, func() bool { return !saturated("route-a")() && !saturated("route-b")() },
+				time.Second, time.Millisecond)
+			assert.Equal(t, "route-a", string((<-dispatch().Iter()).Payload()))
+		})
+	}
+}
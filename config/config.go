@@ -1,34 +1,52 @@
 package config
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras/interceptor"
 	"github.com/gojek/fiber/grpc"
 	fiberHTTP "github.com/gojek/fiber/http"
 	"github.com/gojek/fiber/protocol"
 	"github.com/gojek/fiber/types"
+	grpcLib "google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
-// DefaultClientTimeout defines the default http client timeout to use,
-// if it is not supplied in the config
+// DefaultClientTimeout defines the default http client timeout to use, if it is not supplied
+// in the config of the proxy, that is the root component. The routes of the routers don't have
+// a default timeout, unless the router sets its `default_timeout`
 const DefaultClientTimeout = time.Second
 
 // Config is the base interface to initialise a network from a config file
 type Config interface {
 	initComponent() (fiber.Component, error)
+	// validate adds the problems of the config at the given path to the errors
+	validate(path string, errs *ValidationErrors)
 }
 
 // ComponentConfig is used to parse the base properties for a component
 type ComponentConfig struct {
 	ID   string `json:"id" required:"true"`
 	Type string `json:"type" required:"true"`
+	// Tags, if set, are the tags of the component as a route of the lazy router, e.g. `{"region": "us"}`,
+	// that the router can filter its routes by (see RouteTagsConfig)
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
 // Routes represent a collection of configurations.
@@ -66,6 +84,11 @@ func (d Duration) MarshalJSON() ([]byte, error) {
 	return json.Marshal(time.Duration(d).String())
 }
 
+// String returns the human-readable format of the Duration
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
 // Routes takes in an object of type Routes and returns a map of each route's ID and the route
 func (r Routes) Routes() (map[string]fiber.Component, error) {
 	routes := make(map[string]fiber.Component)
@@ -83,12 +106,200 @@ func (r Routes) Routes() (map[string]fiber.Component, error) {
 type MultiRouteConfig struct {
 	ComponentConfig
 	Routes Routes `json:"routes" required:"true"`
+	// DefaultTimeout, if set, is the timeout of the routes, that don't set their own one. The nested
+	// components inherit it, unless they set their own default
+	DefaultTimeout Duration `json:"default_timeout,omitempty"`
+}
+
+// multiRouteConfig is implemented by the configs of the components with the routes
+type multiRouteConfig interface {
+	multiRouteConfig() *MultiRouteConfig
+}
+
+func (c *MultiRouteConfig) multiRouteConfig() *MultiRouteConfig {
+	return c
+}
+
+// applyDefaultTimeout sets the default timeout of the component, unless it has its own one, as the timeout
+// of its proxies, that don't set their own one, and as the default timeout of its nested components
+func (c *MultiRouteConfig) applyDefaultTimeout(timeout Duration) {
+	if c.DefaultTimeout == 0 {
+		c.DefaultTimeout = timeout
+	}
+	if c.DefaultTimeout == 0 {
+		return
+	}
+	for _, route := range c.Routes {
+		switch typed := route.(type) {
+		case *ProxyConfig:
+			if typed.Timeout == 0 {
+				typed.Timeout = c.DefaultTimeout
+			}
+		case multiRouteConfig:
+			typed.multiRouteConfig().applyDefaultTimeout(c.DefaultTimeout)
+		}
+	}
 }
 
 // RouterConfig is used to parse the configuration for a Router
 type RouterConfig struct {
 	MultiRouteConfig
 	Strategy StrategyConfig `json:"strategy" required:"true"`
+	// Metrics, if set, records the outcomes of the dispatches of the router's routes
+	Metrics *MetricsConfig `json:"metrics,omitempty"`
+	// FailureStatusCodes, if set, are the status codes of the responses, on which the router falls back to
+	// the next route, in addition to the non-successful responses, that it falls back on by default
+	FailureStatusCodes []int `json:"failure_status_codes,omitempty"`
+	// RouteOverride, if set, lets the requests pin the route of the router with the header
+	RouteOverride *RouteOverrideConfig `json:"route_override,omitempty"`
+	// Acceptance, if set, is the predicate of the responses, that the eager router selects
+	Acceptance *AcceptanceConfig `json:"acceptance,omitempty"`
+	// Hedging, if set, lets the lazy router send the hedged requests to the fallback routes, if the route
+	// is slow to respond
+	Hedging *HedgingConfig `json:"hedging,omitempty"`
+	// MaxAttempts, if set, is the number of the routes, that the lazy router tries at most, in the order
+	// of its strategy, before it fails the request
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// Validation, if set, rejects the malformed requests, before the strategy selects the routes
+	Validation *ValidationConfig `json:"validation,omitempty"`
+	// RouteTags, if set, lets the lazy router restrict the routes to the ones, that match the tags of the request
+	RouteTags *RouteTagsConfig `json:"route_tags,omitempty"`
+	// TimeoutResponse, if set, is sent back, when the request times out, while the router is waiting for its routes
+	TimeoutResponse *TimeoutResponseConfig `json:"timeout_response,omitempty"`
+	// Baggage, if set, propagates the W3C baggage of the requests to the routes within the limits, and makes
+	// its members available to the strategy (see interceptor.BaggageInterceptor)
+	Baggage *BaggageConfig `json:"baggage,omitempty"`
+}
+
+// BaggageConfig is used to parse the limits of the baggage, that a Router propagates to its routes
+type BaggageConfig struct {
+	// MaxMembers, if set, is the number of the members of the baggage, that are propagated at most,
+	// instead of interceptor.DefaultBaggageMaxMembers
+	MaxMembers int `json:"max_members,omitempty"`
+	// MaxBytes, if set, is the size of the propagated baggage at most, instead of interceptor.DefaultBaggageMaxBytes
+	MaxBytes int `json:"max_bytes,omitempty"`
+}
+
+// BaggageOptions converts the configuration into the interceptor.BaggageOptions
+func (c *BaggageConfig) BaggageOptions() interceptor.BaggageOptions {
+	return interceptor.BaggageOptions{MaxMembers: c.MaxMembers, MaxBytes: c.MaxBytes}
+}
+
+// propagateBaggage lets the proxies of the routes, and of their nested routes, send the baggage to their
+// backends, even if it's not one of their propagated headers (or metadata keys)
+func propagateBaggage(routes Routes) {
+	for _, route := range routes {
+		switch typed := route.(type) {
+		case *ProxyConfig:
+			typed.propagateBaggage = true
+		case multiRouteConfig:
+			propagateBaggage(typed.multiRouteConfig().Routes)
+		}
+	}
+}
+
+// RouteTagsConfig is used to parse the configuration of the fiber.RouteTagFilter of a Router
+type RouteTagsConfig struct {
+	// Headers are the headers (or the grpc metadata keys) with the tags of the request, keyed by the tag,
+	// e.g. `{"region": "X-Region"}`
+	Headers map[string]string `json:"headers" required:"true"`
+}
+
+// RouteTagFilter converts the configuration into the fiber.RouteTagFilter, with the tags of the given routes
+func (c *RouteTagsConfig) RouteTagFilter(routes Routes) *fiber.RouteTagFilter {
+	if c == nil {
+		return nil
+	}
+	tags := make(map[string]map[string]string, len(routes))
+	for _, route := range routes {
+		tags[routeID(route)] = routeTags(route)
+	}
+	return &fiber.RouteTagFilter{Headers: c.Headers, Tags: tags}
+}
+
+// ValidationConfig is used to parse the validation of the requests of a Router, that rejects the malformed
+// requests with errors.ErrInvalidInput (see fiber.PreRoutingHook)
+type ValidationConfig struct {
+	// JSONSchema is the path to the JSON schema, that the bodies of the http requests have to match
+	// (see fiberHTTP.JSONSchema)
+	JSONSchema string `json:"json_schema,omitempty"`
+	// Message is the full name of the message, that the grpc requests have to be decoded into,
+	// e.g. `testproto.PredictValuesRequest`
+	Message string `json:"message,omitempty"`
+	// DescriptorSet, if set, is the path to the binary FileDescriptorSet with the Message.
+	// Otherwise, the generated code of the message has to be linked into the binary, so it's registered
+	DescriptorSet string `json:"descriptor_set,omitempty"`
+}
+
+// PreRoutingHook converts the configuration into the fiber.PreRoutingHook, that validates the requests
+func (c *ValidationConfig) PreRoutingHook() (fiber.PreRoutingHook, error) {
+	if c.JSONSchema != "" {
+		schema, err := fiberHTTP.LoadJSONSchema(c.JSONSchema)
+		if err != nil {
+			return nil, err
+		}
+		return fiberHTTP.ValidateJSON(schema), nil
+	}
+
+	descriptors := grpc.RegisteredDescriptorSet()
+	if c.DescriptorSet != "" {
+		var err error
+		if descriptors, err = grpc.LoadDescriptorSet(c.DescriptorSet); err != nil {
+			return nil, err
+		}
+	}
+	messageType, err := descriptors.FindMessageType(c.Message)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.ValidateMessage(messageType), nil
+}
+
+// HedgingConfig is used to parse the configuration of the fiber.HedgingPolicy of a LazyRouter
+type HedgingConfig struct {
+	Delay      Duration `json:"delay" required:"true"`
+	Percentile float64  `json:"percentile,omitempty"`
+	MaxHedges  int      `json:"max_hedges,omitempty"`
+}
+
+// HedgingPolicy converts the configuration into the fiber.HedgingPolicy
+func (c *HedgingConfig) HedgingPolicy() *fiber.HedgingPolicy {
+	if c == nil {
+		return nil
+	}
+	return &fiber.HedgingPolicy{
+		Delay:      time.Duration(c.Delay),
+		Percentile: c.Percentile,
+		MaxHedges:  c.MaxHedges,
+	}
+}
+
+// AcceptanceConfig is used to parse the configuration of the acceptance predicate of an EagerRouter
+type AcceptanceConfig struct {
+	// Predicate is the name of the predicate, registered with types.RegisterAcceptancePredicate
+	Predicate string `json:"predicate" required:"true"`
+	// Strict, if set, fails the request, that none of the routes has responded acceptably to.
+	// Otherwise, the last response, that is not accepted, is sent back
+	Strict bool `json:"strict,omitempty"`
+}
+
+// RouteOverrideConfig is used to parse the configuration of the fiber.RouteOverride of a Router
+type RouteOverrideConfig struct {
+	Header        string `json:"header,omitempty"`
+	Fallback      bool   `json:"fallback,omitempty"`
+	RejectUnknown bool   `json:"reject_unknown,omitempty"`
+}
+
+// RouteOverride converts the configuration into the fiber.RouteOverride
+func (c *RouteOverrideConfig) RouteOverride() *fiber.RouteOverride {
+	if c == nil {
+		return nil
+	}
+	return &fiber.RouteOverride{
+		Header:        c.Header,
+		Fallback:      c.Fallback,
+		RejectUnknown: c.RejectUnknown,
+	}
 }
 
 // StrategyConfig is used to parse the configuration for a RoutingStrategy
@@ -97,22 +308,85 @@ type StrategyConfig struct {
 	Properties json.RawMessage `json:"properties" yaml:"properties,omitempty"`
 }
 
-// Strategy takes a reference to a StrategyConfig and creates a RoutingStrategy
+// MetricsConfig is used to parse the configuration for a DispatchMetrics
+type MetricsConfig struct {
+	Type       string          `json:"type" required:"true"`
+	Properties json.RawMessage `json:"properties" yaml:"properties,omitempty"`
+}
+
+// Metrics takes a reference to a MetricsConfig and creates an initialized DispatchMetrics
+func (c *MetricsConfig) Metrics() (interceptor.DispatchMetrics, error) {
+	metrics, err := types.MetricsByName(c.Type)
+	if err != nil {
+		return nil, err
+	}
+	if err = metrics.Initialize(c.Properties); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// Strategy takes a reference to a StrategyConfig and creates a RoutingStrategy, initialized with the properties
 func (c *StrategyConfig) Strategy() (fiber.RoutingStrategy, error) {
-	strategy, err := types.StrategyByName(c.Type)
-	return strategy, err
+	return types.NewRoutingStrategy(c.Type, c.Properties)
 }
 
 func (c *RouterConfig) initComponent() (fiber.Component, error) {
 	var router fiber.Router
 	switch c.Type {
 	case "LAZY_ROUTER":
-		router = fiber.NewLazyRouter(c.ID)
+		lazyRouter := fiber.NewLazyRouter(c.ID)
+		lazyRouter.SetFailureStatusCodes(c.FailureStatusCodes...)
+		lazyRouter.SetRouteOverride(c.RouteOverride.RouteOverride())
+		lazyRouter.SetRouteTagFilter(c.RouteTags.RouteTagFilter(c.Routes))
+		lazyRouter.SetTimeoutResponse(c.TimeoutResponse.TimeoutResponse())
+		router = lazyRouter
 	case "EAGER_ROUTER":
-		router = fiber.NewEagerRouter(c.ID)
+		eagerRouter := fiber.NewEagerRouter(c.ID)
+		eagerRouter.SetFailureStatusCodes(c.FailureStatusCodes...)
+		eagerRouter.SetRouteOverride(c.RouteOverride.RouteOverride())
+		eagerRouter.SetTimeoutResponse(c.TimeoutResponse.TimeoutResponse())
+		router = eagerRouter
 	default:
 		return nil, fmt.Errorf("unknown router type: [%s]", c.Type)
 	}
+
+	var metrics interceptor.DispatchMetrics
+	if c.Metrics != nil {
+		var err error
+		if metrics, err = c.Metrics.Metrics(); err != nil {
+			return nil, err
+		}
+		// Let the metrics observe the circuit breakers of the proxies, if they record their states
+		if observer, ok := metrics.(fiber.CircuitStateObserver); ok {
+			for _, routeConfig := range c.Routes {
+				if proxyConfig, ok := routeConfig.(*ProxyConfig); ok {
+					proxyConfig.circuitObserver = observer
+				}
+			}
+		}
+		// and the health of the proxies, if they record it
+		if observer, ok := metrics.(fiber.HealthStateObserver); ok {
+			for _, routeConfig := range c.Routes {
+				if proxyConfig, ok := routeConfig.(*ProxyConfig); ok {
+					proxyConfig.healthObserver = observer
+				}
+			}
+		}
+		// and the dispatches of the tenants of the proxies, if they record them
+		if observer, ok := metrics.(fiber.TenantDispatchObserver); ok {
+			for _, routeConfig := range c.Routes {
+				if proxyConfig, ok := routeConfig.(*ProxyConfig); ok {
+					proxyConfig.tenantObserver = observer
+				}
+			}
+		}
+	}
+
+	if c.Baggage != nil {
+		propagateBaggage(c.Routes)
+	}
+
 	routes, err := c.Routes.Routes()
 	if err != nil {
 		return nil, err
@@ -123,21 +397,188 @@ func (c *RouterConfig) initComponent() (fiber.Component, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Set the strategy on the router
+	router.SetStrategy(strategy)
+	// and precompute its decisions, if it's static
+	if compiler, ok := strategy.(routingTableCompiler); ok {
+		compiler.Compile(routes)
+	}
 
-	// Initialize strategy
-	err = strategy.Initialize(c.Strategy.Properties)
+	if c.Acceptance != nil {
+		eagerRouter, ok := router.(*fiber.EagerRouter)
+		if !ok {
+			return nil, fmt.Errorf("router [%s]: acceptance is only supported by the eager router", c.ID)
+		}
+		predicate, err := types.AcceptancePredicateByName(c.Acceptance.Predicate)
+		if err != nil {
+			return nil, err
+		}
+		eagerRouter.SetAcceptancePredicate(predicate, c.Acceptance.Strict)
+	}
+
+	if c.Hedging != nil {
+		lazyRouter, ok := router.(*fiber.LazyRouter)
+		if !ok {
+			return nil, fmt.Errorf("router [%s]: hedging is only supported by the lazy router", c.ID)
+		}
+		if err := lazyRouter.SetHedgingPolicy(c.Hedging.HedgingPolicy()); err != nil {
+			return nil, fmt.Errorf("router [%s]: %v", c.ID, err)
+		}
+	}
+
+	if c.Validation != nil {
+		hook, err := c.Validation.PreRoutingHook()
+		if err != nil {
+			return nil, fmt.Errorf("router [%s]: %v", c.ID, err)
+		}
+		router.(preRoutingRouter).SetPreRoutingHook(hook)
+	}
+
+	if c.MaxAttempts != 0 {
+		lazyRouter, ok := router.(*fiber.LazyRouter)
+		if !ok {
+			return nil, fmt.Errorf("router [%s]: max_attempts is only supported by the lazy router", c.ID)
+		}
+		if c.MaxAttempts < 0 {
+			return nil, fmt.Errorf("router [%s]: invalid max_attempts: [%d]", c.ID, c.MaxAttempts)
+		}
+		lazyRouter.SetMaxAttempts(c.MaxAttempts)
+	}
+
+	// Let the strategy observe the responses of the routes, if it needs them
+	if observer, ok := strategy.(routesObserver); ok {
+		for _, route := range routes {
+			route.AddInterceptor(false, observer.Interceptor())
+		}
+	}
+
+	// Record the outcomes of the dispatches of the routes, if the metrics are configured
+	if metrics != nil {
+		for _, route := range routes {
+			route.AddInterceptor(false, interceptor.NewDispatchMetricsInterceptor(metrics))
+		}
+	}
+
+	// Let the strategy update the responses of the router, if it needs to
+	if interceptor, ok := strategy.(responseInterceptor); ok {
+		router.AddInterceptor(false, interceptor)
+	}
+
+	// Trim the baggage, before the strategy selects the routes by its members
+	if c.Baggage != nil {
+		router.AddInterceptor(false, interceptor.NewBaggageInterceptor(c.Baggage.BaggageOptions()))
+	}
+	return router, nil
+}
+
+// routingTableCompiler is implemented by the static routing strategies, which decisions are compiled into
+// a routing table of the routes, i.e. extras.HeaderRoutingStrategy
+type routingTableCompiler interface {
+	Compile(routes map[string]fiber.Component)
+}
+
+// preRoutingRouter is implemented by the routers, that run the fiber.PreRoutingHook, before their strategy
+// selects the routes, i.e. the lazy and the eager routers
+type preRoutingRouter interface {
+	SetPreRoutingHook(hook fiber.PreRoutingHook)
+}
+
+// responseInterceptor is implemented by the routing strategies, that update the responses of the router,
+// i.e. extras.StickyRoutingStrategy, that sets the cookies on them
+type responseInterceptor interface {
+	fiber.Interceptor
+	fiber.ResponseInterceptor
+}
+
+// routesObserver is implemented by the routing strategies, that select the routes based on
+// their previous responses, i.e. extras.LatencyAwareRoutingStrategy
+type routesObserver interface {
+	Interceptor() fiber.Interceptor
+}
+
+// RacingRouterConfig is used to parse the configuration for a RacingRouter
+type RacingRouterConfig struct {
+	MultiRouteConfig
+	// MaxConcurrency, if set, is the number of the routes, that dispatch the request at the same time
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+}
+
+func (c *RacingRouterConfig) initComponent() (fiber.Component, error) {
+	if c.MaxConcurrency < 0 {
+		return nil, fmt.Errorf("invalid max_concurrency: [%d]", c.MaxConcurrency)
+	}
+	router := fiber.NewRacingRouter(c.ID).WithMaxConcurrency(c.MaxConcurrency)
+
+	routes, err := c.Routes.Routes()
 	if err != nil {
 		return nil, err
 	}
-	// Set the strategy on the router
-	router.SetStrategy(strategy)
+	router.SetRoutes(routes)
 	return router, nil
 }
 
 // CombinerConfig is used to parse the configuration for a Combiner
 type CombinerConfig struct {
 	MultiRouteConfig
-	FanIn FanInConfig `json:"fan_in" required:"true"`
+	FanIn  FanInConfig  `json:"fan_in" required:"true"`
+	FanOut FanOutConfig `json:"fan_out,omitempty"`
+	// ResponseOrder is the order, in which the responses of the routes are delivered to the fan-in: either
+	// `arrival` (default), as they arrive, or `route`, in the order of the routes, once all of them have responded
+	ResponseOrder string `json:"response_order,omitempty"`
+}
+
+// FanOutConfig is used to parse the configuration of the FanOut of a Combiner
+type FanOutConfig struct {
+	// SubsetSize, if set, is the number of routes, randomly selected for each request
+	SubsetSize int `json:"subset_size,omitempty"`
+	// Weights are the weights of the routes in the random selection, keyed by the route ID
+	Weights map[string]float64 `json:"weights,omitempty"`
+	// MaxConcurrency, if set, is the number of the routes, that dispatch the request at the same time
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+	// QueueCapacity, if set, is the number of the responses of the routes, that the queue of the fan out holds at most
+	QueueCapacity int `json:"queue_capacity,omitempty"`
+	// QueueOverflow is what happens to the responses, when the queue is full: either `block` (default), so the routes
+	// wait for the fan-in to receive the queued responses, or `drop`, so the responses are dropped
+	QueueOverflow string `json:"queue_overflow,omitempty"`
+}
+
+// queueOverflowPolicies are the QueueOverflowPolicies by their names in the configuration
+var queueOverflowPolicies = map[string]fiber.QueueOverflowPolicy{
+	"":      fiber.BlockOnOverflow,
+	"block": fiber.BlockOnOverflow,
+	"drop":  fiber.DropOnOverflow,
+}
+
+// FanOut validates the configuration and creates a FanOut for the given routes
+func (c *FanOutConfig) FanOut(routes map[string]fiber.Component) (fiber.FanOut, error) {
+	if c.SubsetSize < 0 {
+		return nil, fmt.Errorf("invalid fan_out subset_size: [%d]", c.SubsetSize)
+	}
+	if c.MaxConcurrency < 0 {
+		return nil, fmt.Errorf("invalid fan_out max_concurrency: [%d]", c.MaxConcurrency)
+	}
+	if c.QueueCapacity < 0 {
+		return nil, fmt.Errorf("invalid fan_out queue_capacity: [%d]", c.QueueCapacity)
+	}
+	overflow, ok := queueOverflowPolicies[c.QueueOverflow]
+	if !ok {
+		return nil, fmt.Errorf("unknown fan_out queue_overflow: [%s]", c.QueueOverflow)
+	}
+	for routeID, weight := range c.Weights {
+		if _, ok := routes[routeID]; !ok {
+			return nil, fmt.Errorf("fan_out weight of unknown route: [%s]", routeID)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("negative fan_out weight of route: [%s]", routeID)
+		}
+	}
+
+	fanOut := fiber.NewFanOut("fan_out").
+		WithSubset(c.SubsetSize, c.Weights).
+		WithMaxConcurrency(c.MaxConcurrency).
+		WithQueueCapacity(c.QueueCapacity, overflow)
+	fanOut.SetRoutes(routes)
+	return fanOut, nil
 }
 
 // FanInConfig is used to parse the configuration for a FanIn
@@ -159,7 +600,9 @@ func (c *CombinerConfig) initComponent() (fiber.Component, error) {
 	if err != nil {
 		return nil, err
 	}
-	combiner.SetRoutes(routes)
+	if combiner.FanOut, err = c.FanOut.FanOut(routes); err != nil {
+		return nil, err
+	}
 
 	fanIn, err := c.FanIn.FanIn()
 	if err != nil {
@@ -171,6 +614,18 @@ func (c *CombinerConfig) initComponent() (fiber.Component, error) {
 	if err != nil {
 		return nil, err
 	}
+	switch c.ResponseOrder {
+	case "", "arrival":
+	case "route":
+		routeIDs := make([]string, 0, len(c.Routes))
+		for _, route := range c.Routes {
+			routeIDs = append(routeIDs, routeID(route))
+		}
+		combiner.WithRouteOrder(routeIDs...)
+	default:
+		return nil, fmt.Errorf("combiner [%s]: unknown response_order [%s]", c.ID, c.ResponseOrder)
+	}
+
 	// Set the fanIn on the combiner
 	return combiner.WithFanIn(fanIn), nil
 }
@@ -181,74 +636,1107 @@ type ProxyConfig struct {
 	Endpoint string            `json:"endpoint" required:"true"`
 	Timeout  Duration          `json:"timeout"`
 	Protocol protocol.Protocol `json:"protocol"`
+	// Endpoints, if set instead of Endpoint, are the equivalent endpoints of the backend (i.e. its replicas).
+	// The requests are spread across them by the LoadBalancing policy, and each attempt (see Retry) is
+	// dispatched to the endpoint, that the policy selects
+	Endpoints []string `json:"endpoints,omitempty"`
+	// LoadBalancing is the policy of the selection of the Endpoints, fiber.RoundRobinLoadBalancing by default
+	LoadBalancing fiber.LoadBalancingPolicy `json:"load_balancing,omitempty"`
+	// MaxTimeout, if set, is the ceiling of the timeout of the request, set with fiber.WithRequestTimeout,
+	// so it can be longer than Timeout. Otherwise, the request timeout can only make Timeout shorter
+	MaxTimeout Duration `json:"max_timeout,omitempty"`
+	// ConnectTimeout, if set, is the timeout of establishing the connection to the backend, separate from
+	// Timeout of the request, so the unreachable backend fails fast instead of timing out as a slow one
+	ConnectTimeout Duration `json:"connect_timeout,omitempty"`
+	// RateLimit, if set, caps the rate of the requests to the backend
+	RateLimit *RateLimitConfig `json:"rate_limit,omitempty"`
+	// Cache, if set, serves the successful responses of the identical requests from the cache
+	Cache *CacheConfig `json:"cache,omitempty"`
+	// Tenants, if set, isolates the tenants of the requests: each of them has its own connections to the backend,
+	// rate limit and circuit breaker (see fiber.TenantIsolatedDispatcher), while the cache is shared
+	Tenants *TenantsConfig `json:"tenants,omitempty"`
+	// TimeoutResponse overrides the response sent back, when the backend fails to respond within timeout
+	TimeoutResponse *TimeoutResponseConfig `json:"timeout_response,omitempty"`
+	// TLS, if set, configures the TLS connections to the backend
+	TLS *TLSConfig `json:"tls,omitempty"`
+	// Retry, if set, configures the retries of the failed requests to the backend
+	Retry *RetryConfig `json:"retry,omitempty"`
+	// CircuitBreaker, if set, stops dispatching the requests to the backend, while it keeps failing
+	CircuitBreaker *CircuitBreakerConfig `json:"circuit_breaker,omitempty"`
+	// MaxRequestSize and MaxResponseSize, if set, are the limits of the request and response bodies
+	// (or messages, for grpc) in bytes, so a misbehaving backend can't exhaust the memory
+	MaxRequestSize  int64 `json:"max_request_size,omitempty"`
+	MaxResponseSize int64 `json:"max_response_size,omitempty"`
 	GrpcConfig
+	HTTPConfig
+
+	// HealthCheck, if set, probes the backend in the background, so the router skips it, while it's unhealthy
+	HealthCheck *HealthCheckConfig `json:"health_check,omitempty"`
+	// MaxInFlight, if set, is the limit of the requests in flight to the backend. The router skips the route,
+	// while it's saturated, and sheds the request, if all of its routes are saturated
+	MaxInFlight int `json:"max_in_flight,omitempty"`
+	// Metrics, if set, records the outcome of each dispatch of the proxy, that is the root component,
+	// i.e. the passthrough to a single backend without a router. The routes record the metrics of their router
+	Metrics *MetricsConfig `json:"metrics,omitempty"`
+
+	// circuitObserver is set by the parent router, if its metrics record the states of the circuit breakers
+	circuitObserver fiber.CircuitStateObserver
+	// healthObserver is set by the parent router, if its metrics record the health of the routes
+	healthObserver fiber.HealthStateObserver
+	// tenantObserver is set by the parent router, if its metrics record the dispatches of the tenants
+	tenantObserver fiber.TenantDispatchObserver
+	// dispatcher, if set, is used instead of the dispatcher of the protocol (see LoadOptions.Dispatchers)
+	dispatcher fiber.Dispatcher
+	// propagateBaggage is set by the parent router, if it propagates the baggage of the requests
+	propagateBaggage bool
+}
+
+// TimeoutResponseConfig is used to parse the configuration of the response, that is sent back
+// when the request to the backend times out. For http, the body is sent as is with the given
+// status code, for grpc the code and the body are used as the status code and the status message
+type TimeoutResponseConfig struct {
+	Code int    `json:"code" required:"true"`
+	Body string `json:"body"`
+}
+
+// TimeoutResponse converts the configuration into the fiber.TimeoutResponse, or nil, if it's not set
+func (c *TimeoutResponseConfig) TimeoutResponse() *fiber.TimeoutResponse {
+	if c == nil {
+		return nil
+	}
+	return &fiber.TimeoutResponse{StatusCode: c.Code, Body: []byte(c.Body)}
+}
+
+// RetryConfig is used to parse the retry policy of a Proxy
+type RetryConfig struct {
+	MaxAttempts          int      `json:"max_attempts,omitempty"`
+	InitialBackoff       Duration `json:"initial_backoff,omitempty"`
+	Multiplier           float64  `json:"multiplier,omitempty"`
+	MaxBackoff           Duration `json:"max_backoff,omitempty"`
+	Jitter               float64  `json:"jitter,omitempty"`
+	RetryableStatusCodes []int    `json:"retryable_status_codes,omitempty"`
+}
+
+// RetryPolicy converts the configuration into the fiber.RetryPolicy
+func (c *RetryConfig) RetryPolicy() fiber.RetryPolicy {
+	return fiber.RetryPolicy{
+		MaxAttempts:          c.MaxAttempts,
+		InitialBackoff:       time.Duration(c.InitialBackoff),
+		Multiplier:           c.Multiplier,
+		MaxBackoff:           time.Duration(c.MaxBackoff),
+		Jitter:               c.Jitter,
+		RetryableStatusCodes: c.RetryableStatusCodes,
+	}
+}
+
+// CircuitBreakerConfig is used to parse the circuit breaker policy of a Proxy
+type CircuitBreakerConfig struct {
+	FailureThreshold int      `json:"failure_threshold,omitempty"`
+	Cooldown         Duration `json:"cooldown,omitempty"`
+}
+
+// CircuitBreakerPolicy converts the configuration into the fiber.CircuitBreakerPolicy
+func (c *CircuitBreakerConfig) CircuitBreakerPolicy() fiber.CircuitBreakerPolicy {
+	return fiber.CircuitBreakerPolicy{
+		FailureThreshold: c.FailureThreshold,
+		Cooldown:         time.Duration(c.Cooldown),
+	}
+}
+
+// HealthCheckConfig is used to parse the configuration of the health checks of the backend of a Proxy
+type HealthCheckConfig struct {
+	// Path is the path of the health endpoint of the http backend, resolved against its endpoint
+	Path string `json:"path,omitempty"`
+	// Service is the name of the service, which health is checked by the grpc health-check RPC
+	Service            string   `json:"service,omitempty"`
+	Interval           Duration `json:"interval,omitempty"`
+	Timeout            Duration `json:"timeout,omitempty"`
+	HealthyThreshold   int      `json:"healthy_threshold,omitempty"`
+	UnhealthyThreshold int      `json:"unhealthy_threshold,omitempty"`
+}
+
+// HealthCheckPolicy converts the configuration into the fiber.HealthCheckPolicy
+func (c *HealthCheckConfig) HealthCheckPolicy() fiber.HealthCheckPolicy {
+	return fiber.HealthCheckPolicy{
+		Interval:           time.Duration(c.Interval),
+		Timeout:            time.Duration(c.Timeout),
+		HealthyThreshold:   c.HealthyThreshold,
+		UnhealthyThreshold: c.UnhealthyThreshold,
+	}
 }
 
 type GrpcConfig struct {
 	ServiceMethod string `json:"service_method,omitempty"`
+	// DeadlineBuffer is subtracted from the deadline of the incoming request, propagated to the backend
+	DeadlineBuffer Duration `json:"deadline_buffer,omitempty"`
+	// Streaming should be set, if the service method is a server-streaming one, unless it's described
+	// by the DescriptorSet
+	Streaming bool `json:"streaming,omitempty"`
+	// DescriptorSet, if set, is the path to the binary FileDescriptorSet with the service of the backend and its
+	// imports, so the messages of the service method are used without their generated code (see grpc.DescriptorSet)
+	DescriptorSet string `json:"descriptor_set,omitempty"`
+	// PropagatedMetadata, if set, are the keys of the metadata of the incoming request, that are sent
+	// to the backend. The other keys are dropped
+	PropagatedMetadata []string `json:"propagated_metadata,omitempty"`
+	// Keepalive, if set, configures the keepalive pings of the connection to the backend
+	Keepalive *GrpcKeepaliveConfig `json:"keepalive,omitempty"`
+	// Backoff, if set, configures the backoff of the reconnects to the backend
+	Backoff *GrpcBackoffConfig `json:"backoff,omitempty"`
+	// Transcoding, if set, lets the http clients call the unary method of the backend with JSON
+	Transcoding *GrpcTranscodingConfig `json:"transcoding,omitempty"`
+	// Authority, if set, is sent as the :authority header of the calls instead of the endpoint
+	Authority string `json:"authority,omitempty"`
+	// Compressor, if set, is the name of the compressor of the request messages, e.g. gzip
+	Compressor string `json:"compressor,omitempty"`
+	// IdleTimeout, if set, evicts the connection to the backend, that hasn't been used for that long, and the next
+	// call dials the new one. The calls of the evicted connection are given IdleGracePeriod to complete
+	IdleTimeout     Duration `json:"idle_timeout,omitempty"`
+	IdleGracePeriod Duration `json:"idle_grace_period,omitempty"`
+}
+
+// GrpcTranscodingConfig is used to parse the messages of the grpc method, that the JSON of the http
+// requests and responses are transcoded from and to (see grpc.TranscodingDispatcher)
+type GrpcTranscodingConfig struct {
+	// RequestMessage and ResponseMessage are the full names of the messages, e.g. `testproto.PredictValuesRequest`.
+	// The generated code of the messages has to be linked into the binary, so they are registered, unless
+	// the proxy has the descriptor_set, that they are found in. They default to the messages of the service method
+	// of the descriptor_set
+	RequestMessage  string `json:"request_message,omitempty"`
+	ResponseMessage string `json:"response_message,omitempty"`
+	// DecodeError is the fiber.DecodeErrorPolicy of the response messages, that can't be decoded:
+	// `fail` (default), `pass_through` or `error`
+	DecodeError string `json:"decode_error,omitempty"`
+}
+
+// GrpcKeepaliveConfig is used to parse the keepalive parameters of the grpc connection to a backend
+type GrpcKeepaliveConfig struct {
+	// Time is the period of inactivity, after which the connection is pinged
+	Time Duration `json:"time,omitempty"`
+	// Timeout is the time to wait for the ping to be acknowledged, before the connection is closed
+	Timeout Duration `json:"timeout,omitempty"`
+	// PermitWithoutStream, if set, pings the connection even if there are no active calls
+	PermitWithoutStream bool `json:"permit_without_stream,omitempty"`
+}
+
+// ClientParameters converts the configuration into the keepalive.ClientParameters. The defaults
+// of grpc are used for the durations, that are not set
+func (c *GrpcKeepaliveConfig) ClientParameters() keepalive.ClientParameters {
+	return keepalive.ClientParameters{
+		Time:                time.Duration(c.Time),
+		Timeout:             time.Duration(c.Timeout),
+		PermitWithoutStream: c.PermitWithoutStream,
+	}
+}
+
+// GrpcBackoffConfig is used to parse the backoff of the reconnects of the grpc connection to a backend
+type GrpcBackoffConfig struct {
+	BaseDelay  Duration `json:"base_delay,omitempty"`
+	Multiplier float64  `json:"multiplier,omitempty"`
+	Jitter     float64  `json:"jitter,omitempty"`
+	MaxDelay   Duration `json:"max_delay,omitempty"`
+	// MinConnectTimeout is the minimum time given to each connection attempt
+	MinConnectTimeout Duration `json:"min_connect_timeout,omitempty"`
+}
+
+// ConnectParams converts the configuration into the grpc.ConnectParams. The parameters, that are not set,
+// are taken from backoff.DefaultConfig
+func (c *GrpcBackoffConfig) ConnectParams() grpcLib.ConnectParams {
+	params := grpcLib.ConnectParams{
+		Backoff:           backoff.DefaultConfig,
+		MinConnectTimeout: time.Duration(c.MinConnectTimeout),
+	}
+	if c.BaseDelay > 0 {
+		params.Backoff.BaseDelay = time.Duration(c.BaseDelay)
+	}
+	if c.Multiplier > 0 {
+		params.Backoff.Multiplier = c.Multiplier
+	}
+	if c.Jitter > 0 {
+		params.Backoff.Jitter = c.Jitter
+	}
+	if c.MaxDelay > 0 {
+		params.Backoff.MaxDelay = time.Duration(c.MaxDelay)
+	}
+	return params
+}
+
+// HTTPConfig is used to parse the http-specific configuration of a Proxy
+type HTTPConfig struct {
+	UserAgent string `json:"user_agent,omitempty"`
+	// Transport, if set, configures the pool of the connections to the backend
+	Transport *HTTPTransportConfig `json:"transport,omitempty"`
+	// PropagatedHeaders, if set, are the headers of the incoming request, that are sent to the backend.
+	// The other headers are dropped
+	PropagatedHeaders []string `json:"propagated_headers,omitempty"`
+	// Host, if set, is sent as the Host header of the requests instead of the host of the endpoint
+	Host string `json:"host,omitempty"`
+	// Decompress, if set, decodes the gzip and deflate-encoded responses of the backend
+	Decompress bool `json:"decompress,omitempty"`
+	// BackendNameHeader, if set, is the header of the responses, that carries the breadcrumb of their backends,
+	// instead of fiberHTTP.DefaultBackendNameHeader
+	BackendNameHeader string `json:"backend_name_header,omitempty"`
+	// HTTP2, if set, sends the requests over HTTP/2: with prior knowledge (h2c) to the plaintext endpoints,
+	// or negotiated with ALPN, if the endpoint is https or TLS is configured
+	HTTP2 bool `json:"http2,omitempty"`
+	// SharedTransport, if set, shares the pool of the connections with the other proxies to the same hosts with
+	// the same TLS, transport, connect timeout and HTTP/2 settings (see fiberHTTP.TransportPool), e.g. to the
+	// different paths of a backend
+	SharedTransport bool `json:"shared_transport,omitempty"`
+}
+
+// HTTPTransportConfig is used to parse the configuration of the pool of the http connections to a backend
+type HTTPTransportConfig struct {
+	MaxIdleConns        int      `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int      `json:"max_idle_conns_per_host,omitempty"`
+	MaxConnsPerHost     int      `json:"max_conns_per_host,omitempty"`
+	IdleConnTimeout     Duration `json:"idle_conn_timeout,omitempty"`
+	KeepAlive           Duration `json:"keep_alive,omitempty"`
+}
+
+// RateLimitConfig is used to parse the rate limit policy of a Proxy
+type RateLimitConfig struct {
+	// Rate is the number of the requests per second
+	Rate    float64  `json:"rate" required:"true"`
+	Burst   int      `json:"burst,omitempty"`
+	MaxWait Duration `json:"max_wait,omitempty"`
+	// Key, if set, limits the rate of the requests of each key separately
+	Key *RequestKeyConfig `json:"key,omitempty"`
+	// Limits are the limits of the keys, matching their patterns. Rate and Burst limit the other keys
+	Limits  []KeyRateLimitConfig `json:"limits,omitempty"`
+	MaxKeys int                  `json:"max_keys,omitempty"`
+}
+
+// RequestKeyConfig is used to parse the attributes of the requests, that they are keyed by (see fiber.RequestKey)
+type RequestKeyConfig struct {
+	// Headers are the http headers / grpc metadata keys, which values are a part of the key
+	Headers []string `json:"headers,omitempty"`
+	// Operation, if set, adds the operation of the request, i.e. the method and the path of the http request
+	Operation bool `json:"operation,omitempty"`
+}
+
+// RequestKey converts the configuration into the fiber.RequestKeyFunc
+func (c *RequestKeyConfig) RequestKey() fiber.RequestKeyFunc {
+	return fiber.RequestKey(c.Operation, c.Headers...)
+}
+
+// KeyRateLimitConfig is used to parse the rate limit of the keys, that match the pattern
+type KeyRateLimitConfig struct {
+	Pattern string  `json:"pattern" required:"true"`
+	Rate    float64 `json:"rate" required:"true"`
+	Burst   int     `json:"burst,omitempty"`
+}
+
+// RateLimitPolicy converts the configuration into the fiber.RateLimitPolicy
+func (c *RateLimitConfig) RateLimitPolicy() fiber.RateLimitPolicy {
+	policy := fiber.RateLimitPolicy{
+		Rate:    c.Rate,
+		Burst:   c.Burst,
+		MaxWait: time.Duration(c.MaxWait),
+		MaxKeys: c.MaxKeys,
+	}
+	if c.Key != nil {
+		policy.Key = c.Key.RequestKey()
+	}
+	for _, limit := range c.Limits {
+		policy.KeyLimits = append(policy.KeyLimits, fiber.KeyRateLimit{
+			Pattern: limit.Pattern,
+			Rate:    limit.Rate,
+			Burst:   limit.Burst,
+		})
+	}
+	return policy
+}
+
+// TenantsConfig is used to parse the tenants of the requests, that a Proxy isolates
+type TenantsConfig struct {
+	// Key is the attributes of the requests, that their tenant is keyed by
+	Key        RequestKeyConfig `json:"key"`
+	MaxTenants int              `json:"max_tenants,omitempty"`
+}
+
+// TenantPolicy converts the configuration into the fiber.TenantPolicy
+func (c *TenantsConfig) TenantPolicy() fiber.TenantPolicy {
+	return fiber.TenantPolicy{
+		Key:        c.Key.RequestKey(),
+		MaxTenants: c.MaxTenants,
+	}
+}
+
+// CacheConfig is used to parse the configuration of the response cache of a Proxy
+type CacheConfig struct {
+	TTL      Duration `json:"ttl" required:"true"`
+	Capacity int      `json:"capacity,omitempty"`
+	// VaryHeaders are the http headers / grpc metadata keys, which values are a part of the cache key, so the requests,
+	// that differ by them (e.g. by Accept-Language), are cached separately. The http responses, that Vary by the other
+	// headers, are not cached
+	VaryHeaders []string `json:"vary_headers,omitempty"`
+	// Canonical, if set, keys the grpc requests on the hash of the deterministic encoding of their message
+	// (see grpc.CanonicalCacheKey), that is the input of the service method, found in the descriptor_set
+	// of the proxy or among the registered messages
+	Canonical bool `json:"canonical,omitempty"`
+	// StaleIfError, if set, is the time the expired responses are kept for, and served, marked with
+	// the fiber.StaleResponseHeader, if the backend fails (see fiber.CachePolicy)
+	StaleIfError Duration `json:"stale_if_error,omitempty"`
+}
+
+// CachePolicy converts the configuration into the fiber.CachePolicy of the requests of the protocol
+func (c *CacheConfig) CachePolicy(proto protocol.Protocol) fiber.CachePolicy {
+	if proto == protocol.GRPC {
+		return fiber.CachePolicy{
+			TTL:          time.Duration(c.TTL),
+			Key:          grpc.CacheKey(c.VaryHeaders...),
+			Store:        fiber.NewLRUCacheStore(c.Capacity),
+			StaleIfError: time.Duration(c.StaleIfError),
+		}
+	}
+	return fiber.CachePolicy{
+		TTL:          time.Duration(c.TTL),
+		Key:          fiberHTTP.CacheKey(c.VaryHeaders...),
+		Store:        fiber.NewLRUCacheStore(c.Capacity),
+		Cacheable:    fiberHTTP.CacheableResponse(c.VaryHeaders...),
+		StaleIfError: time.Duration(c.StaleIfError),
+	}
 }
 
 func (c *ProxyConfig) initComponent() (fiber.Component, error) {
+	if c.Timeout <= 0 {
+		return nil, fmt.Errorf("proxy [%s]: timeout is required, unless the router sets default_timeout", c.ID)
+	}
+
+	var metrics interceptor.DispatchMetrics
+	if c.Metrics != nil {
+		var err error
+		if metrics, err = c.Metrics.Metrics(); err != nil {
+			return nil, err
+		}
+		// the metrics observe the circuit breaker and the health of the proxy, if they record them
+		if observer, ok := metrics.(fiber.CircuitStateObserver); ok && c.circuitObserver == nil {
+			c.circuitObserver = observer
+		}
+		if observer, ok := metrics.(fiber.HealthStateObserver); ok && c.healthObserver == nil {
+			c.healthObserver = observer
+		}
+		if observer, ok := metrics.(fiber.TenantDispatchObserver); ok && c.tenantObserver == nil {
+			c.tenantObserver = observer
+		}
+	}
 
+	proto := protocol.HTTP
+	if strings.EqualFold(string(c.Protocol), string(protocol.GRPC)) {
+		proto = protocol.GRPC
+	}
+	for _, endpoint := range c.endpoints() {
+		if _, _, err := fiber.UnixSocketPath(endpoint); err != nil {
+			return nil, fmt.Errorf("proxy [%s]: %v", c.ID, err)
+		}
+	}
+	var descriptors *grpc.DescriptorSet
+	if proto == protocol.GRPC && c.DescriptorSet != "" {
+		var err error
+		if descriptors, err = c.descriptors(); err != nil {
+			return nil, fmt.Errorf("proxy [%s]: %v", c.ID, err)
+		}
+	}
+	var backend fiber.Backend
+	if proto == protocol.HTTP && len(c.Endpoints) == 0 {
+		backend = fiber.NewBackend(c.ID, httpEndpoint(c.Endpoint))
+	}
 	var dispatcher fiber.Dispatcher
+	var probe fiber.HealthProbe
 	var err error
-	var backend fiber.Backend
-	if strings.EqualFold(string(c.Protocol), string(protocol.GRPC)) {
-		dispatcher, err = grpc.NewDispatcher(grpc.DispatcherConfig{
-			ServiceMethod: c.ServiceMethod,
-			Endpoint:      c.Endpoint,
-			Timeout:       time.Duration(c.Timeout),
-		})
+	if c.Tenants != nil {
+		// each tenant has its own dispatcher of the backend, with its own connections, rate limit and circuit breaker,
+		// while the probe checks the backend of the default one
+		var tenants *fiber.TenantIsolatedDispatcher
+		tenants, err = fiber.NewTenantIsolatedDispatcher(c.ID, c.Tenants.TenantPolicy(),
+			func(tenant string) (fiber.Dispatcher, error) {
+				tenantDispatcher, tenantProbe, err := c.backendDispatcher(proto, tenant)
+				if tenant == "" {
+					probe = tenantProbe
+				}
+				return tenantDispatcher, err
+			})
+		if err == nil {
+			dispatcher = tenants.WithObserver(c.tenantObserver)
+		}
 	} else {
-		httpClient := &http.Client{Timeout: time.Duration(c.Timeout)}
-		dispatcher, err = fiberHTTP.NewDispatcher(httpClient)
-		backend = fiber.NewBackend(c.ID, c.Endpoint)
+		dispatcher, probe, err = c.backendDispatcher(proto, "")
 	}
 	if err != nil {
 		return nil, err
 	}
+	if c.Cache != nil {
+		// the cache is shared by the tenants, since the cache hits don't reach the backend,
+		// and they don't affect the circuit breaker
+		policy := c.Cache.CachePolicy(proto)
+		if proto == protocol.GRPC && c.Cache.Canonical {
+			if policy.Key, err = c.canonicalCacheKey(descriptors); err != nil {
+				return nil, fmt.Errorf("proxy [%s]: %v", c.ID, err)
+			}
+		}
+		if dispatcher, err = fiber.NewCachingDispatcher(dispatcher, policy); err != nil {
+			return nil, err
+		}
+	}
+	if proto == protocol.GRPC && c.Transcoding != nil {
+		// the http requests are transcoded before they are cached, retried or counted by the circuit breaker
+		if dispatcher, err = c.transcodingDispatcher(dispatcher, descriptors); err != nil {
+			return nil, err
+		}
+	}
 	caller, err := fiber.NewCaller(c.ID, dispatcher)
 	if err != nil {
 		return nil, err
 	}
 
-	return fiber.NewProxy(backend, caller), nil
+	var proxy fiber.Component = fiber.NewProxy(backend, caller)
+	if probe != nil {
+		checker, err := fiber.NewHealthChecker(c.ID, probe, c.HealthCheck.HealthCheckPolicy())
+		if err != nil {
+			return nil, err
+		}
+		proxy = fiber.NewHealthCheckedComponent(proxy, checker.WithObserver(c.healthObserver).Start())
+	}
+	if c.MaxInFlight > 0 {
+		// the requests, that are shed, are not counted by the circuit breaker
+		limited, err := fiber.NewConcurrencyLimitedComponent(proxy, c.MaxInFlight)
+		if err != nil {
+			return nil, err
+		}
+		proxy = limited
+	}
+	if metrics != nil {
+		proxy.AddInterceptor(false, interceptor.NewDispatchMetricsInterceptor(metrics))
+	}
+	return proxy, nil
+}
+
+// backendDispatcher creates the dispatcher of the backend of the tenant with the rate limit, the retries and
+// the circuit breaker of the proxy, and returns it with the probe of the backend, if the health checks are
+// configured. Only the circuit breaker of the requests without the tenant is observed, so the states
+// of the breakers of the tenants don't overwrite the state of the route
+func (c *ProxyConfig) backendDispatcher(
+	proto protocol.Protocol,
+	tenant string,
+) (fiber.Dispatcher, fiber.HealthProbe, error) {
+	var probe fiber.HealthProbe
+	var err error
+	dispatcher := c.dispatcher
+	if dispatcher == nil {
+		dispatcher, probe, err = c.endpointsDispatcher(proto)
+	} else {
+		probe, err = c.healthProbe(dispatcher, c.Endpoint)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.RateLimit != nil {
+		// each retry takes its own turn, while the rate-limited requests are not counted by the circuit breaker,
+		// and the cache hits are served without waiting for it
+		if dispatcher, err = fiber.NewRateLimitedDispatcher(dispatcher, c.RateLimit.RateLimitPolicy()); err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.Retry != nil {
+		if dispatcher, err = fiber.NewRetryingDispatcher(dispatcher, c.Retry.RetryPolicy()); err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.CircuitBreaker != nil {
+		breaker, err := fiber.NewCircuitBreakingDispatcher(c.ID, dispatcher, c.CircuitBreaker.CircuitBreakerPolicy())
+		if err != nil {
+			return nil, nil, err
+		}
+		if tenant == "" {
+			breaker.WithObserver(c.circuitObserver)
+		}
+		dispatcher = breaker
+	}
+	return dispatcher, probe, nil
+}
+
+// descriptors loads the DescriptorSet of the grpc backend, and checks, that it describes the service method.
+// The server-streaming method is dispatched as the streaming one, even if the proxy doesn't set streaming
+func (c *ProxyConfig) descriptors() (*grpc.DescriptorSet, error) {
+	descriptors, err := grpc.LoadDescriptorSet(c.DescriptorSet)
+	if err != nil {
+		return nil, err
+	}
+	method, err := descriptors.FindMethod(c.ServiceMethod)
+	if err != nil {
+		return nil, err
+	}
+	if method.IsStreamingServer() {
+		c.Streaming = true
+	}
+	return descriptors, nil
+}
+
+// canonicalCacheKey returns the grpc.CanonicalCacheKey of the input message of the service method, that is found
+// in the descriptors, if they are set, or among the registered messages otherwise
+func (c *ProxyConfig) canonicalCacheKey(descriptors *grpc.DescriptorSet) (fiber.CacheKeyFunc, error) {
+	if descriptors == nil {
+		descriptors = grpc.RegisteredDescriptorSet()
+	}
+	method, err := descriptors.FindMethod(c.ServiceMethod)
+	if err != nil {
+		return nil, err
+	}
+	requestType, err := descriptors.FindMessageType(string(method.Input().FullName()))
+	if err != nil {
+		return nil, err
+	}
+	return grpc.CanonicalCacheKey(requestType, c.Cache.VaryHeaders...), nil
+}
+
+// transcodingDispatcher returns the grpc.TranscodingDispatcher of the transcoding with its decode error policy
+func (c *ProxyConfig) transcodingDispatcher(
+	dispatcher fiber.Dispatcher,
+	descriptors *grpc.DescriptorSet,
+) (fiber.Dispatcher, error) {
+	decodeError, err := fiber.ParseDecodeErrorPolicy(c.Transcoding.DecodeError)
+	if err != nil {
+		return nil, err
+	}
+	transcoding, err := c.newTranscodingDispatcher(dispatcher, descriptors)
+	if err != nil {
+		return nil, err
+	}
+	return transcoding.WithDecodeErrorPolicy(decodeError), nil
+}
+
+// newTranscodingDispatcher creates the grpc.TranscodingDispatcher of the messages of the transcoding, that are found
+// in the descriptors, if they are set, or among the registered messages otherwise
+func (c *ProxyConfig) newTranscodingDispatcher(
+	dispatcher fiber.Dispatcher,
+	descriptors *grpc.DescriptorSet,
+) (*grpc.TranscodingDispatcher, error) {
+	if descriptors == nil {
+		return grpc.NewTranscodingDispatcherByName(
+			dispatcher, c.Transcoding.RequestMessage, c.Transcoding.ResponseMessage)
+	}
+	if c.Transcoding.RequestMessage == "" && c.Transcoding.ResponseMessage == "" {
+		return grpc.NewTranscodingDispatcherForMethod(dispatcher, descriptors, c.ServiceMethod)
+	}
+	requestType, err := descriptors.FindMessageType(c.Transcoding.RequestMessage)
+	if err != nil {
+		return nil, err
+	}
+	responseType, err := descriptors.FindMessageType(c.Transcoding.ResponseMessage)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.NewTranscodingDispatcher(dispatcher, requestType, responseType)
+}
+
+// httpTransport creates the transport of the http backend, or returns nil, if the requests are sent
+// by http.DefaultTransport
+func (c *ProxyConfig) httpTransport() (http.RoundTripper, error) {
+	sockets := c.unixSockets()
+	// the transport is created once per proxy, so its connections are reused by all the requests
+	transportConfig := fiberHTTP.TransportConfig{DialTimeout: time.Duration(c.ConnectTimeout)}
+	if c.Transport != nil {
+		transportConfig = fiberHTTP.TransportConfig{
+			MaxIdleConns:        c.Transport.MaxIdleConns,
+			MaxIdleConnsPerHost: c.Transport.MaxIdleConnsPerHost,
+			MaxConnsPerHost:     c.Transport.MaxConnsPerHost,
+			IdleConnTimeout:     time.Duration(c.Transport.IdleConnTimeout),
+			KeepAlive:           time.Duration(c.Transport.KeepAlive),
+			DialTimeout:         time.Duration(c.ConnectTimeout),
+		}
+	}
+	transportConfig.UnixSockets = sockets
+	if c.HTTP2 {
+		var tlsConfig *tls.Config
+		if c.TLS != nil {
+			var err error
+			if tlsConfig, err = c.TLS.TLSClientConfig(); err != nil {
+				return nil, err
+			}
+		} else {
+			for _, endpoint := range c.endpoints() {
+				if strings.HasPrefix(endpoint, "https://") {
+					tlsConfig = &tls.Config{}
+				}
+			}
+		}
+		return fiberHTTP.NewHTTP2Transport(transportConfig, tlsConfig)
+	}
+	// the isolated tenants don't share the pool of http.DefaultTransport, and neither do the shared transports,
+	// so the routes, that release them, don't close its connections
+	if c.TLS == nil && c.Transport == nil && c.ConnectTimeout <= 0 && len(sockets) == 0 &&
+		c.Tenants == nil && !c.SharedTransport {
+		return nil, nil
+	}
+	transport, err := fiberHTTP.NewTransport(transportConfig)
+	if err != nil {
+		return nil, err
+	}
+	if c.TLS != nil {
+		if transport.TLSClientConfig, err = c.TLS.TLSClientConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return transport, nil
+}
+
+// transportKey returns the key of the transport of the http backend in the fiberHTTP.DefaultTransportPool,
+// so only the routes to the same hosts with the same settings of the transport (e.g. TLS) share it
+func (c *ProxyConfig) transportKey() (string, error) {
+	hosts := make([]string, 0, len(c.endpoints()))
+	for _, endpoint := range c.endpoints() {
+		endpointURL, err := url.Parse(httpEndpoint(endpoint))
+		if err != nil {
+			return "", fmt.Errorf("invalid endpoint [%s]: %v", endpoint, err)
+		}
+		hosts = append(hosts, endpointURL.Scheme+"://"+endpointURL.Host)
+	}
+	sort.Strings(hosts)
+
+	key, err := json.Marshal(struct {
+		Hosts          []string             `json:"hosts"`
+		Sockets        map[string]string    `json:"sockets,omitempty"`
+		TLS            *TLSConfig           `json:"tls,omitempty"`
+		Transport      *HTTPTransportConfig `json:"transport,omitempty"`
+		ConnectTimeout Duration             `json:"connect_timeout,omitempty"`
+		HTTP2          bool                 `json:"http2,omitempty"`
+	}{hosts, c.unixSockets(), c.TLS, c.Transport, c.ConnectTimeout, c.HTTP2})
+	return string(key), err
+}
+
+// endpointsDispatcher returns the dispatcher of the endpoint of the backend, or the LoadBalancingDispatcher
+// across its Endpoints, if they are set, and the probe of the backend, if the health checks are configured.
+// The backend with the Endpoints is healthy, while any of them is healthy
+func (c *ProxyConfig) endpointsDispatcher(proto protocol.Protocol) (fiber.Dispatcher, fiber.HealthProbe, error) {
+	var httpDispatcher fiber.Dispatcher
+	if proto == protocol.HTTP {
+		// the http client is shared by the endpoints, since the request is sent to the endpoint by its url
+		var err error
+		if httpDispatcher, err = c.httpDispatcher(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(c.Endpoints) == 0 {
+		dispatcher := httpDispatcher
+		if proto == protocol.GRPC {
+			var err error
+			if dispatcher, err = c.grpcDispatcher(c.Endpoint); err != nil {
+				return nil, nil, err
+			}
+		}
+		// the backend is probed over the connections of its dispatcher, without the retries and the circuit breaker
+		probe, err := c.healthProbe(dispatcher, c.Endpoint)
+		return dispatcher, probe, err
+	}
+
+	dispatchers := make([]fiber.Dispatcher, len(c.Endpoints))
+	probes := make([]fiber.HealthProbe, 0, len(c.Endpoints))
+	for idx, endpoint := range c.Endpoints {
+		dispatcher := httpDispatcher
+		if proto == protocol.GRPC {
+			var err error
+			if dispatcher, err = c.grpcDispatcher(endpoint); err != nil {
+				return nil, nil, err
+			}
+		}
+		probe, err := c.healthProbe(dispatcher, endpoint)
+		if err != nil {
+			return nil, nil, err
+		}
+		if probe != nil {
+			probes = append(probes, probe)
+		}
+		if proto == protocol.HTTP {
+			dispatcher = fiber.NewBackendDispatcher(fiber.NewBackend(c.ID, httpEndpoint(endpoint)), httpDispatcher)
+		}
+		dispatchers[idx] = dispatcher
+	}
+	dispatcher, err := fiber.NewLoadBalancingDispatcher(c.LoadBalancing, dispatchers...)
+	if err != nil || len(probes) == 0 {
+		return dispatcher, nil, err
+	}
+	return dispatcher, anyHealthy(probes), nil
+}
+
+// endpoints returns the Endpoint of the backend, or its Endpoints, if they are set
+func (c *ProxyConfig) endpoints() []string {
+	if len(c.Endpoints) > 0 {
+		return c.Endpoints
+	}
+	return []string{c.Endpoint}
+}
+
+// httpEndpoint returns the endpoint, that the http requests to the backend are sent to: the endpoint itself, or
+// the endpoint with the host of the unix domain socket, that the transport connects to (see fiberHTTP.UnixSocketHost)
+func httpEndpoint(endpoint string) string {
+	if socket, ok, err := fiber.UnixSocketPath(endpoint); ok && err == nil {
+		return "http://" + fiberHTTP.UnixSocketHost(socket)
+	}
+	return endpoint
+}
+
+// unixSockets returns the paths of the unix domain sockets of the backend, keyed by the hosts of their http endpoints
+func (c *ProxyConfig) unixSockets() map[string]string {
+	var sockets map[string]string
+	for _, endpoint := range c.endpoints() {
+		if socket, ok, err := fiber.UnixSocketPath(endpoint); ok && err == nil {
+			if sockets == nil {
+				sockets = make(map[string]string)
+			}
+			sockets[fiberHTTP.UnixSocketHost(socket)] = socket
+		}
+	}
+	return sockets
+}
+
+// anyHealthy returns the probe, that succeeds, if any of the given probes succeeds
+func anyHealthy(probes []fiber.HealthProbe) fiber.HealthProbe {
+	return fiber.HealthProbeFunc(func(ctx context.Context) error {
+		var err error
+		for _, probe := range probes {
+			if err = probe.Probe(ctx); err == nil {
+				return nil
+			}
+		}
+		return err
+	})
+}
+
+// healthProbe returns the probe of the endpoint of the backend, if the health checks are configured
+func (c *ProxyConfig) healthProbe(dispatcher fiber.Dispatcher, endpoint string) (fiber.HealthProbe, error) {
+	if c.HealthCheck == nil {
+		return nil, nil
+	}
+	switch d := dispatcher.(type) {
+	case *grpc.Dispatcher:
+		return d.HealthProbe(c.HealthCheck.Service), nil
+	case *fiberHTTP.Dispatcher:
+		endpointURL, err := url.Parse(httpEndpoint(endpoint))
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint [%s]: %v", endpoint, err)
+		}
+		path, err := url.Parse(c.HealthCheck.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid health_check path [%s]: %v", c.HealthCheck.Path, err)
+		}
+		return d.HealthProbe(endpointURL.ResolveReference(path).String()), nil
+	}
+	return nil, nil
+}
+
+// propagatedHeaders returns the headers (or the metadata keys) of the request, that are sent to the backend,
+// with the baggage, if the parent router propagates it. All of them are sent, if none are set
+func (c *ProxyConfig) propagatedHeaders(headers []string) []string {
+	if len(headers) == 0 || !c.propagateBaggage {
+		return headers
+	}
+	return append(headers[:len(headers):len(headers)], interceptor.BaggageHeader)
+}
+
+func (c *ProxyConfig) grpcDispatcher(endpoint string) (fiber.Dispatcher, error) {
+	var timeoutStatus *status.Status
+	if c.TimeoutResponse != nil {
+		timeoutStatus = status.New(codes.Code(c.TimeoutResponse.Code), c.TimeoutResponse.Body)
+	}
+
+	var tlsConfig *tls.Config
+	if c.TLS != nil {
+		var err error
+		if tlsConfig, err = c.TLS.TLSClientConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	var keepaliveParams *keepalive.ClientParameters
+	if c.Keepalive != nil {
+		params := c.Keepalive.ClientParameters()
+		keepaliveParams = &params
+	}
+	var connectParams *grpcLib.ConnectParams
+	if c.Backoff != nil {
+		params := c.Backoff.ConnectParams()
+		connectParams = &params
+	}
+
+	return grpc.NewDispatcher(grpc.DispatcherConfig{
+		ServiceMethod:      c.ServiceMethod,
+		Endpoint:           endpoint,
+		Timeout:            time.Duration(c.Timeout),
+		MaxTimeout:         time.Duration(c.MaxTimeout),
+		TimeoutStatus:      timeoutStatus,
+		TLSConfig:          tlsConfig,
+		DeadlineBuffer:     time.Duration(c.DeadlineBuffer),
+		Streaming:          c.Streaming,
+		PropagatedMetadata: c.propagatedHeaders(c.PropagatedMetadata),
+		MaxRequestSize:     int(c.MaxRequestSize),
+		MaxResponseSize:    int(c.MaxResponseSize),
+		Keepalive:          keepaliveParams,
+		ConnectParams:      connectParams,
+		ConnectTimeout:     time.Duration(c.ConnectTimeout),
+		Authority:          c.Authority,
+		Compressor:         c.Compressor,
+		IdleTimeout:        time.Duration(c.IdleTimeout),
+		IdleGracePeriod:    time.Duration(c.IdleGracePeriod),
+	})
+}
+
+func (c *ProxyConfig) httpDispatcher() (fiber.Dispatcher, error) {
+	httpClient := &http.Client{Timeout: time.Duration(c.Timeout)}
+	if c.MaxTimeout > 0 {
+		// the dispatcher applies the timeout of each request, so the client only enforces the ceiling
+		httpClient.Timeout = time.Duration(c.MaxTimeout)
+	}
+	if c.SharedTransport {
+		key, err := c.transportKey()
+		if err != nil {
+			return nil, err
+		}
+		transport, err := fiberHTTP.DefaultTransportPool.Get(key, c.httpTransport)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = transport
+	} else {
+		transport, err := c.httpTransport()
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = transport
+	}
+
+	httpDispatcher, err := fiberHTTP.NewDispatcher(httpClient)
+	if err != nil {
+		return nil, err
+	}
+	if c.MaxTimeout > 0 {
+		httpDispatcher.WithTimeout(time.Duration(c.Timeout), time.Duration(c.MaxTimeout))
+	}
+	if c.UserAgent != "" {
+		httpDispatcher.WithUserAgent(c.UserAgent)
+	}
+	if c.Host != "" {
+		httpDispatcher.WithHost(c.Host)
+	}
+	if c.BackendNameHeader != "" {
+		httpDispatcher.WithBackendNameHeader(c.BackendNameHeader)
+	}
+	if len(c.PropagatedHeaders) > 0 {
+		httpDispatcher.WithPropagatedHeaders(c.propagatedHeaders(c.PropagatedHeaders)...)
+	}
+	if c.MaxRequestSize > 0 || c.MaxResponseSize > 0 {
+		httpDispatcher.WithMaxBodySizes(c.MaxRequestSize, c.MaxResponseSize)
+	}
+	if c.Decompress {
+		httpDispatcher.WithDecompression()
+	}
+	if c.TimeoutResponse != nil {
+		httpDispatcher.WithTimeoutResponse(c.TimeoutResponse.TimeoutResponse())
+	}
+	return httpDispatcher, nil
+}
+
+// LoadOptions are the options of loading the config
+type LoadOptions struct {
+	// FailFast, if set, skips the validation of the whole config, so only the first problem
+	// of the config is returned, when the component is initialized
+	FailFast bool
+	// Dispatchers, if set, are the dispatchers of the proxies by their ids, that are used instead of the
+	// ones of their protocols, e.g. the in-process backends or the fakes of the tests. The retries, the circuit
+	// breaker, etc. of the proxy are applied to the dispatcher, and the http requests are sent to its endpoint.
+	// The proxies with the tenants can't have the injected dispatchers
+	Dispatchers map[string]fiber.Dispatcher
+	// WarmUp, if set, establishes the connections to the backends of the proxies, once the component
+	// is initialized, so the first requests don't wait for them (see fiber.WarmUp)
+	WarmUp *WarmUpOptions
+}
+
+// DefaultWarmUpTimeout is the time, the backends are given to warm up, if it's not configured
+const DefaultWarmUpTimeout = 5 * time.Second
+
+// WarmUpOptions are the options of the warm-up of the component, initialized from the config
+type WarmUpOptions struct {
+	// Timeout limits the time of the warm-up of all the backends. Defaults to DefaultWarmUpTimeout
+	Timeout time.Duration
+	// Fatal, if set, fails the initialization with fiber.WarmUpErrors, if any of the backends has failed
+	// to warm up. The component is closed in this case
+	Fatal bool
+	// OnError, if set, is called for each backend, that has failed to warm up, unless the warm-up is Fatal.
+	// The failures are logged with the standard logger otherwise
+	OnError func(err fiber.WarmUpError)
+}
+
+// warmUp warms up the component according to the options, and returns the error of the warm-up, if it's fatal
+func (o *WarmUpOptions) warmUp(component fiber.Component) error {
+	timeout := o.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWarmUpTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := fiber.WarmUp(ctx, component)
+	if err == nil {
+		return nil
+	}
+	if o.Fatal {
+		if closer, ok := component.(fiber.Closer); ok {
+			_ = closer.Close(context.Background())
+		}
+		return err
+	}
+	errs, ok := err.(fiber.WarmUpErrors)
+	if !ok {
+		errs = fiber.WarmUpErrors{{RouteID: component.ID(), Err: err}}
+	}
+	for _, warmUpErr := range errs {
+		if o.OnError != nil {
+			o.OnError(warmUpErr)
+		} else {
+			log.Printf("fiber: %v", warmUpErr)
+		}
+	}
+	return nil
 }
 
 // InitComponentFromConfig takes in the path to a config file, parses the contents
-// and if successful, constructs a fiber Component
+// and if successful, constructs a fiber Component. The format of the file is detected
+// by its extension: `.json`, or `.yaml`/`.yml`. The references to the environment variables
+// (`${ENV_VAR}` or `${ENV_VAR:-default}`) in the string values are resolved at load time.
+// The config is validated before the component is constructed, and all the problems found
+// are returned as ValidationErrors
 func InitComponentFromConfig(configPath string) (fiber.Component, error) {
-	if yamlFile, err := ioutil.ReadFile(configPath); err != nil {
+	return InitComponentFromConfigWithOptions(configPath, LoadOptions{})
+}
+
+// InitComponentFromConfigWithOptions constructs a fiber Component from the config file, same as
+// InitComponentFromConfig, according to the given options
+func InitComponentFromConfigWithOptions(configPath string, options LoadOptions) (fiber.Component, error) {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if !options.FailFast {
+		var errs ValidationErrors
+		if cfg.validate("", &errs); len(errs) > 0 {
+			return nil, errs
+		}
+	}
+	if err = injectDispatchers(cfg, options.Dispatchers); err != nil {
+		return nil, err
+	}
+	component, err := cfg.initComponent()
+	if err != nil || options.WarmUp == nil {
+		return component, err
+	}
+	if err = options.WarmUp.warmUp(component); err != nil {
+		return nil, err
+	}
+	return component, nil
+}
+
+// injectDispatchers sets the dispatchers of the proxies of the config by their ids. The dispatcher
+// can't be injected into the proxy with the tenants, since each tenant has to have its own one
+func injectDispatchers(cfg Config, dispatchers map[string]fiber.Dispatcher) error {
+	injected := make(map[string]bool, len(dispatchers))
+	var isolated []string
+	var inject func(cfg Config)
+	inject = func(cfg Config) {
+		switch typed := cfg.(type) {
+		case *ProxyConfig:
+			if dispatcher, ok := dispatchers[typed.ID]; ok {
+				if typed.Tenants != nil {
+					isolated = append(isolated, typed.ID)
+				}
+				typed.dispatcher = dispatcher
+				injected[typed.ID] = true
+			}
+		case multiRouteConfig:
+			for _, route := range typed.multiRouteConfig().Routes {
+				inject(route)
+			}
+		}
+	}
+	inject(cfg)
+
+	if len(isolated) > 0 {
+		return fmt.Errorf("dispatcher of proxy [%s] can not be injected, since its tenants are isolated", isolated[0])
+	}
+	for id := range dispatchers {
+		if !injected[id] {
+			return fmt.Errorf("dispatcher of unknown proxy [%s]", id)
+		}
+	}
+	return nil
+}
+
+// ValidateConfig parses the config file and returns all the problems of the config as ValidationErrors,
+// without constructing the component
+func ValidateConfig(configPath string) error {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	var errs ValidationErrors
+	if cfg.validate("", &errs); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// loadConfig reads and parses the config file in the format, detected by its extension
+func loadConfig(configPath string) (Config, error) {
+	var toJSON func([]byte) ([]byte, error)
+	switch ext := strings.ToLower(filepath.Ext(configPath)); ext {
+	case ".json":
+		toJSON = func(data []byte) ([]byte, error) { return data, nil }
+	case ".yaml", ".yml":
+		toJSON = yaml.YAMLToJSON
+	default:
+		return nil, fmt.Errorf("unsupported config file extension: [%s], expected .json, .yaml or .yml", ext)
+	}
+
+	if data, err := ioutil.ReadFile(configPath); err != nil {
 		return nil, err
-	} else if cfg, err := parseConfig(yamlFile); err != nil {
+	} else if jsonData, err := toJSON(data); err != nil {
+		return nil, err
+	} else if jsonData, err = interpolateEnv(jsonData); err != nil {
+		return nil, err
+	} else if cfg, err := parseConfig(jsonData); err != nil {
 		return nil, err
 	} else {
-		return cfg.initComponent()
+		// the proxy, that is the root component, has the default timeout
+		if proxy, ok := cfg.(*ProxyConfig); ok && proxy.Timeout == 0 {
+			proxy.Timeout = Duration(DefaultClientTimeout)
+		}
+		return cfg, nil
 	}
 }
 
+// parseConfig parses the JSON representation of the config. Both the JSON and YAML configs
+// are parsed by it, so they share the same config structs
 func parseConfig(data []byte) (Config, error) {
 	typez := struct {
 		Type   string            `json:"type" required:"true"`
 		Routes []json.RawMessage `json:"routes"`
 	}{}
 
-	if err := yaml.Unmarshal(data, &typez); err != nil {
+	if err := json.Unmarshal(data, &typez); err != nil {
 		return nil, err
 	}
 
 	var dst Config
 	switch typez.Type {
 	case "PROXY":
-		dst = &ProxyConfig{
-			// Set the default value here, can't find an easier way to supply defaults
-			// Ref: https://github.com/go-yaml/yaml/issues/165
-			Timeout: Duration(DefaultClientTimeout),
-		}
+		// the timeout of the proxy defaults to the default_timeout of its router (see applyDefaultTimeout)
+		dst = &ProxyConfig{}
 	case "EAGER_ROUTER", "LAZY_ROUTER":
 		dst = &RouterConfig{
 			MultiRouteConfig: MultiRouteConfig{Routes: make(Routes, len(typez.Routes))},
 		}
+	case "RACING_ROUTER":
+		dst = &RacingRouterConfig{
+			MultiRouteConfig: MultiRouteConfig{Routes: make(Routes, len(typez.Routes))},
+		}
 	case "COMBINER":
 		dst = &CombinerConfig{
 			MultiRouteConfig: MultiRouteConfig{Routes: make(Routes, len(typez.Routes))},
@@ -257,9 +1745,12 @@ func parseConfig(data []byte) (Config, error) {
 		return nil, fmt.Errorf("unknown component type: %s", typez.Type)
 	}
 
-	if err := yaml.Unmarshal(data, dst); err != nil {
+	if err := json.Unmarshal(data, dst); err != nil {
 		return nil, err
 	}
+	if multiRoute, ok := dst.(multiRouteConfig); ok {
+		multiRoute.multiRouteConfig().applyDefaultTimeout(0)
+	}
 
 	return dst, nil
 }
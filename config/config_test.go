@@ -1,9 +1,20 @@
 package config_test
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,12 +22,20 @@ import (
 	"github.com/gojek/fiber/config"
 	fibergrpc "github.com/gojek/fiber/grpc"
 	fiberhttp "github.com/gojek/fiber/http"
+	"github.com/gojek/fiber/internal/mocks"
 	testutils "github.com/gojek/fiber/internal/testutils/grpc"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/gojek/fiber/types"
+	"github.com/gojek/fiber/util"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/protobuf/types/dynamicpb"
 )
 
@@ -79,6 +98,10 @@ func TestFromConfig(t *testing.T) {
 	httpDispatcher, _ := fiberhttp.NewDispatcher(&http.Client{Timeout: timeout})
 	httpCaller, _ := fiber.NewCaller("proxy_name", httpDispatcher)
 	httpProxy := fiber.NewProxy(backend, httpCaller)
+
+	uaDispatcher, _ := fiberhttp.NewDispatcher(&http.Client{Timeout: timeout})
+	uaCaller, _ := fiber.NewCaller("proxy_name", uaDispatcher.WithUserAgent("proxy_name/1.0"))
+	uaProxy := fiber.NewProxy(backend, uaCaller)
 	testutils.RunTestUPIServer(testutils.GrpcTestServer{
 		Port: port,
 	})
@@ -92,6 +115,28 @@ func TestFromConfig(t *testing.T) {
 	grpcCaller, _ := fiber.NewCaller("proxy_name", grpcDispatcher)
 	grpcProxy := fiber.NewProxy(nil, grpcCaller)
 
+	// the backoff parameters, that are not configured, are the defaults of grpc
+	keepaliveBackoff := backoff.DefaultConfig
+	keepaliveBackoff.BaseDelay = 100 * time.Millisecond
+	keepaliveBackoff.MaxDelay = 5 * time.Second
+	keepaliveDispatcher, _ := fibergrpc.NewDispatcher(
+		fibergrpc.DispatcherConfig{
+			ServiceMethod: "testproto.UniversalPredictionService/PredictValues",
+			Endpoint:      fmt.Sprintf("localhost:%d", port),
+			Timeout:       timeout,
+			Keepalive: &keepalive.ClientParameters{
+				Time:                time.Minute,
+				Timeout:             10 * time.Second,
+				PermitWithoutStream: true,
+			},
+			ConnectParams: &grpc.ConnectParams{
+				Backoff:           keepaliveBackoff,
+				MinConnectTimeout: 2 * time.Second,
+			},
+		})
+	keepaliveCaller, _ := fiber.NewCaller("proxy_name", keepaliveDispatcher)
+	keepaliveProxy := fiber.NewProxy(nil, keepaliveCaller)
+
 	tests := []struct {
 		name              string
 		configPath        string
@@ -103,16 +148,78 @@ func TestFromConfig(t *testing.T) {
 			configPath:        "../internal/testdata/config/http_proxy.yaml",
 			expectedComponent: httpProxy,
 		},
+		{
+			name:              "http proxy from json",
+			configPath:        "../internal/testdata/config/http_proxy.json",
+			expectedComponent: httpProxy,
+		},
+		{
+			name:              "http proxy with user agent",
+			configPath:        "../internal/testdata/config/http_proxy_user_agent.yaml",
+			expectedComponent: uaProxy,
+		},
 		{
 			name:              "grpc proxy",
 			configPath:        "../internal/testdata/config/grpc_proxy.yaml",
 			expectedComponent: grpcProxy,
 		},
+		{
+			name:              "grpc proxy with keepalive and backoff",
+			configPath:        "../internal/testdata/config/grpc_proxy_keepalive.yaml",
+			expectedComponent: keepaliveProxy,
+		},
 		{
 			name:           "grpc proxy",
 			configPath:     "../internal/testdata/config/invalid_grpc_proxy.yaml",
 			expectedErrMsg: "fiber: grpc dispatcher: missing config (endpoint/serviceMethod)",
 		},
+		{
+			name:       "grpc proxy with invalid timeout response",
+			configPath: "../internal/testdata/config/invalid_grpc_timeout_response.yaml",
+			expectedErrMsg: "invalid config, 1 problem(s) found:\n" +
+				"  - timeout_response.code: code must be a grpc status code in [0, 16] range: [17]",
+		},
+		{
+			name:       "http proxy with invalid timeout response",
+			configPath: "../internal/testdata/config/invalid_http_timeout_response.yaml",
+			expectedErrMsg: "invalid config, 1 problem(s) found:\n" +
+				"  - timeout_response.code: code must be an http status code in [100, 599] range: [1000]",
+		},
+		{
+			name:           "router with negative strategy weight",
+			configPath:     "../internal/testdata/config/invalid_weighted_router.yaml",
+			expectedErrMsg: "weighted random routing strategy: negative weight of route: [route_b]",
+		},
+		{
+			name:           "combiner with negative fan out weight",
+			configPath:     "../internal/testdata/config/invalid_combiner_fan_out.yaml",
+			expectedErrMsg: "negative fan_out weight of route: [route_b]",
+		},
+		{
+			name:           "router with unknown metrics type",
+			configPath:     "../internal/testdata/config/invalid_router_metrics.yaml",
+			expectedErrMsg: "unknown METRICS type: fiber.UnknownMetrics",
+		},
+		{
+			name:           "http proxy with negative transport limit",
+			configPath:     "../internal/testdata/config/invalid_http_transport.yaml",
+			expectedErrMsg: "http transport: number of connections can not be negative",
+		},
+		{
+			name:           "proxy with invalid retry jitter",
+			configPath:     "../internal/testdata/config/invalid_retry_proxy.yaml",
+			expectedErrMsg: "retry policy: jitter must be in [0, 1] range: [2]",
+		},
+		{
+			name:           "proxy with invalid circuit breaker threshold",
+			configPath:     "../internal/testdata/config/invalid_circuit_breaker_proxy.yaml",
+			expectedErrMsg: "circuit breaker: failure_threshold and cooldown can not be negative",
+		},
+		{
+			name:           "unsupported config file extension",
+			configPath:     "../internal/testdata/config/http_proxy.toml",
+			expectedErrMsg: "unsupported config file extension: [.toml], expected .json, .yaml or .yml",
+		},
 	}
 
 	for _, tt := range tests {
@@ -123,6 +230,7 @@ func TestFromConfig(t *testing.T) {
 				assert.True(t,
 					cmp.Equal(tt.expectedComponent, got,
 						cmpopts.IgnoreUnexported(grpc.ClientConn{}, dynamicpb.Message{}),
+						cmpopts.IgnoreFields(fibergrpc.Dispatcher{}, "conn"),
 						cmp.AllowUnexported(
 							fiber.BaseComponent{},
 							fiber.Proxy{},
@@ -137,3 +245,1748 @@ func TestFromConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestFromConfig_HeaderRoutingStrategy(t *testing.T) {
+	backend := func(body string) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(body))
+		}))
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+
+	configPath := filepath.Join(t.TempDir(), "header_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: header_router
+strategy:
+  type: fiber.HeaderRoutingStrategy
+  properties:
+    header: X-Tenant
+    rules:
+      - values: [gold]
+        routes: [route_b, route_a]
+    defaults: [route_a]
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+  - id: route_b
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+`, backend("A"), backend("B"))), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+	for tenant, expected := range map[string]string{"gold": "B", "silver": "A", "": "A"} {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, expected, recorder.Body.String(), "tenant: %s", tenant)
+	}
+}
+
+func TestFromConfig_ExternalRoutingStrategyFallback(t *testing.T) {
+	backend := func(body string) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(body))
+		}))
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+
+	suite := map[string]struct {
+		fallback    string
+		expectedErr string
+	}{
+		"configured fallback": {
+			fallback: `
+    fallback:
+      type: fiber.HeaderRoutingStrategy
+      properties:
+        header: X-Tier
+        defaults: [route_b, route_a]`,
+		},
+		"unknown fallback": {
+			fallback: `
+    fallback:
+      type: fiber.UnknownRoutingStrategy`,
+			expectedErr: "external routing strategy: fallback: unknown ROUTING_STRATEGY type: fiber.UnknownRoutingStrategy",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "external_router.yaml")
+			require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: external_router
+strategy:
+  type: fiber.ExternalRoutingStrategy
+  properties:
+    endpoint: http://localhost:0/decision
+    key_header: X-Tenant%s
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+  - id: route_b
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+`, tt.fallback, backend("A"), backend("B"))), 0600))
+
+			component, err := config.InitComponentFromConfig(configPath)
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			// the requests without the key are routed by the fallback strategy
+			handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+			for i := 0; i < 10; i++ {
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+				assert.Equal(t, "B", recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestFromConfig_RateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "rate_limited_proxy.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: PROXY
+id: rate_limited_proxy
+endpoint: %q
+timeout: 1s
+rate_limit:
+  rate: 0.001
+  key:
+    headers: [X-Tenant]
+  limits:
+    - pattern: "gold"
+      rate: 0.001
+      burst: 2
+`, server.URL)), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+	for _, tt := range []struct {
+		tenant   string
+		expected int
+	}{
+		{tenant: "gold", expected: http.StatusOK},
+		{tenant: "gold", expected: http.StatusOK},
+		{tenant: "gold", expected: http.StatusTooManyRequests},
+		{tenant: "silver", expected: http.StatusOK},
+		{tenant: "silver", expected: http.StatusTooManyRequests},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		req.Header.Set("X-Tenant", tt.tenant)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, tt.expected, recorder.Code, "tenant: %s", tt.tenant)
+	}
+}
+
+func TestFromConfig_RouterTimeoutResponse(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	for _, routerType := range []string{"LAZY_ROUTER", "EAGER_ROUTER"} {
+		t.Run(routerType, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "router.yaml")
+			require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: %s
+id: router
+timeout_response:
+  code: 504
+  body: '{"error":"timeout"}'
+strategy:
+  type: fiber.RandomRoutingStrategy
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+    timeout: 2s
+`, routerType, slow.URL)), 0600))
+
+			component, err := config.InitComponentFromConfig(configPath)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			resp, ok := <-component.Dispatch(ctx, testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+			require.True(t, ok)
+			assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode())
+			assert.Equal(t, `{"error":"timeout"}`, string(resp.Payload()))
+		})
+	}
+}
+
+func TestFromConfig_Baggage(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Baggage") + "|" + r.Header.Get("X-Tenant")))
+	}))
+	defer backend.Close()
+
+	configPath := filepath.Join(t.TempDir(), "baggage_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: baggage_router
+strategy:
+  type: fiber.RandomRoutingStrategy
+baggage:
+  max_members: 1
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+    propagated_headers: [X-Tenant]
+`, backend.URL)), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+
+	req := testUtilsHttp.MockReq("GET", "http://localhost", "")
+	req.Request.Header = http.Header{"Baggage": []string{"tenant=gold,experiment=exp1"}, "X-Tenant": []string{"gold"}}
+	resp, ok := <-component.Dispatch(context.Background(), req).Iter()
+	require.True(t, ok)
+
+	// the trimmed baggage is sent, even though it's not one of the propagated headers,
+	// while the incoming request is not modified
+	assert.Equal(t, "tenant=gold|gold", string(resp.Payload()))
+	assert.Equal(t, "tenant=gold,experiment=exp1", req.Request.Header.Get("Baggage"))
+}
+
+func TestFromConfig_TenantIsolation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "proxy.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+id: proxy
+type: PROXY
+endpoint: %q
+timeout: 1s
+rate_limit:
+  rate: 0.001
+tenants:
+  key:
+    headers: [X-Tenant]
+  max_tenants: 10
+`, server.URL)), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+	dispatch := func(tenant string) int {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// each tenant, and the requests without one, take the turns of their own rate limit
+	assert.Equal(t, http.StatusOK, dispatch("gold"))
+	assert.Equal(t, http.StatusTooManyRequests, dispatch("gold"))
+	assert.Equal(t, http.StatusOK, dispatch("silver"))
+	assert.Equal(t, http.StatusOK, dispatch(""))
+	assert.Equal(t, http.StatusTooManyRequests, dispatch(""))
+
+	// the injected dispatcher would be shared by all the tenants
+	_, err = config.InitComponentFromConfigWithOptions(configPath, config.LoadOptions{
+		Dispatchers: map[string]fiber.Dispatcher{"proxy": &mocks.Dispatcher{}},
+	})
+	assert.EqualError(t, err, "dispatcher of proxy [proxy] can not be injected, since its tenants are isolated")
+}
+
+func TestFromConfig_SharedTransport(t *testing.T) {
+	var connections int32
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	backend.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	backend.Start()
+	defer backend.Close()
+
+	proxy := func(path string) http.Handler {
+		configPath := filepath.Join(t.TempDir(), "proxy.yaml")
+		require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+id: proxy
+type: PROXY
+endpoint: "%s%s"
+timeout: 1s
+shared_transport: true
+`, backend.URL, path)), 0600))
+		component, err := config.InitComponentFromConfig(configPath)
+		require.NoError(t, err)
+		return fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+	}
+	proxyA, proxyB := proxy("/a"), proxy("/b")
+	assert.Equal(t, 1, fiberhttp.DefaultTransportPool.Len())
+
+	// the proxies to the same host reuse the connection of the shared transport
+	for _, tt := range []struct {
+		handler  http.Handler
+		expected string
+	}{{proxyA, "/a/"}, {proxyB, "/b/"}, {proxyA, "/a/"}} {
+		recorder := httptest.NewRecorder()
+		tt.handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.Equal(t, tt.expected, recorder.Body.String())
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+}
+
+func TestFromConfig_CacheVaryHeaders(t *testing.T) {
+	var dispatched int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&dispatched, 1)
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	defer server.Close()
+
+	suite := map[string]struct {
+		varyHeaders        string
+		expectedDispatched int32
+	}{
+		"requests are cached by the vary headers": {
+			varyHeaders:        "vary_headers: [Accept-Language]",
+			expectedDispatched: 2,
+		},
+		"responses, that vary by the other headers, are not cached": {
+			expectedDispatched: 3,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&dispatched, 0)
+			configPath := filepath.Join(t.TempDir(), "cached_proxy.yaml")
+			require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: PROXY
+id: cached_proxy
+endpoint: %q
+timeout: 1s
+cache:
+  ttl: 1m
+  %s
+`, server.URL, tt.varyHeaders)), 0600))
+
+			component, err := config.InitComponentFromConfig(configPath)
+			require.NoError(t, err)
+
+			// the requests, that differ only by the vary header, don't share the cached response
+			handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+			for _, language := range []string{"en", "fr", "en"} {
+				req := httptest.NewRequest(http.MethodGet, "http://localhost/greeting", nil)
+				req.Header.Set("Accept-Language", language)
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Equal(t, language, recorder.Body.String())
+			}
+			assert.Equal(t, tt.expectedDispatched, atomic.LoadInt32(&dispatched))
+		})
+	}
+}
+
+func TestFromConfig_JSONAndYAML(t *testing.T) {
+	fromYAML, err := config.InitComponentFromConfig("../internal/testdata/config/lazy_router.yaml")
+	require.NoError(t, err)
+	fromJSON, err := config.InitComponentFromConfig("../internal/testdata/config/lazy_router.json")
+	require.NoError(t, err)
+
+	router, ok := fromJSON.(*fiber.LazyRouter)
+	require.True(t, ok)
+	assert.Equal(t, "lazy_router", router.ID())
+	assert.Len(t, router.GetRoutes(), 2)
+
+	assert.True(t,
+		cmp.Equal(fromYAML, fromJSON,
+			// the random sources are seeded at the creation time
+			cmpopts.IgnoreTypes(&util.ShardedRand{}),
+			cmp.Exporter(func(reflect.Type) bool { return true }),
+		),
+		"components from json and yaml configs are not equal")
+}
+
+func TestFromConfig_EnvInterpolation(t *testing.T) {
+	httpDispatcher, _ := fiberhttp.NewDispatcher(&http.Client{Timeout: 20 * time.Second})
+	httpCaller, _ := fiber.NewCaller("proxy_name", httpDispatcher)
+	httpProxy := fiber.NewProxy(fiber.NewBackend("proxy_name", "localhost:1234"), httpCaller)
+
+	tests := []struct {
+		name              string
+		configPath        string
+		env               map[string]string
+		expectedComponent fiber.Component
+		expectedErrMsg    string
+	}{
+		{
+			name:              "variables and defaults are resolved",
+			configPath:        "../internal/testdata/config/env_http_proxy.yaml",
+			env:               map[string]string{"FIBER_TEST_HOST": "localhost", "FIBER_TEST_PORT": "1234"},
+			expectedComponent: httpProxy,
+		},
+		{
+			name:       "empty variable falls back to the default",
+			configPath: "../internal/testdata/config/env_http_proxy.yaml",
+			env: map[string]string{
+				"FIBER_TEST_HOST":    "localhost",
+				"FIBER_TEST_PORT":    "1234",
+				"FIBER_TEST_TIMEOUT": "",
+			},
+			expectedComponent: httpProxy,
+		},
+		{
+			name:           "missing variable",
+			configPath:     "../internal/testdata/config/env_http_proxy.yaml",
+			env:            map[string]string{"FIBER_TEST_HOST": "localhost"},
+			expectedErrMsg: "config: environment variable [FIBER_TEST_PORT] of field [endpoint] is not set",
+		},
+		{
+			name:           "missing variable of a nested route",
+			configPath:     "../internal/testdata/config/env_router.yaml",
+			expectedErrMsg: "config: environment variable [FIBER_TEST_ROUTE_B_ENDPOINT] of field [routes[1].endpoint] is not set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				require.NoError(t, os.Setenv(name, value))
+			}
+			defer func() {
+				for name := range tt.env {
+					_ = os.Unsetenv(name)
+				}
+			}()
+
+			got, err := config.InitComponentFromConfig(tt.configPath)
+			if tt.expectedErrMsg == "" {
+				require.NoError(t, err)
+				assert.True(t,
+					cmp.Equal(tt.expectedComponent, got,
+						cmp.AllowUnexported(
+							fiber.BaseComponent{},
+							fiber.Proxy{},
+							fiber.Caller{},
+							fiberhttp.Dispatcher{}),
+					),
+					"config not equal to expected")
+			} else {
+				assert.EqualError(t, err, tt.expectedErrMsg)
+			}
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		configPath     string
+		expectedErrors config.ValidationErrors
+	}{
+		{
+			name:       "valid config",
+			configPath: "../internal/testdata/config/lazy_router.yaml",
+		},
+		{
+			name:       "router with multiple problems",
+			configPath: "../internal/testdata/config/invalid_router_problems.yaml",
+			expectedErrors: config.ValidationErrors{
+				{Field: "routes[0].endpoint",
+					Message: "malformed unix socket endpoint [unix://var/run/route-a.sock], expected unix:///path/to.sock"},
+				{Field: "routes[0].timeout", Message: "timeout is required, unless the router sets default_timeout"},
+				{Field: "routes[1].timeout", Message: "timeout is required, unless the router sets default_timeout"},
+				{Field: "routes[1].shared_transport",
+					Message: "shared_transport is only supported by the http backends"},
+				{Field: "routes[1].host", Message: "host is only supported by the http backends, use authority for grpc"},
+				{Field: "routes[1].http2", Message: "http2 is only supported by the http backends"},
+				{Field: "routes[1].backend_name_header",
+					Message: "backend_name_header is only supported by the http backends"},
+				{Field: "routes[1].id", Message: "duplicate route id [route_a], also used by routes[0]"},
+				{
+					Field:   "routes[1].protocol",
+					Message: "protocol [GRPC] doesn't match protocol [HTTP] of route [route_a] of [router_name]",
+				},
+				{Field: "routes[2].endpoint", Message: "endpoint of the backend is required"},
+				{Field: "routes[2].max_timeout", Message: "max_timeout can not be shorter than timeout: [500ms]"},
+				{Field: "routes[2].connect_timeout", Message: "connect_timeout can not be negative: [-1s]"},
+				{Field: "routes[2].rate_limit.rate", Message: "rate must be positive: [0]"},
+				{Field: "routes[2].rate_limit", Message: "burst and max_wait can not be negative"},
+				{Field: "routes[2].rate_limit.limits", Message: "limits of the keys require the key"},
+				{Field: "routes[2].rate_limit.limits[0]",
+					Message: "pattern and positive rate are required, and burst can not be negative"},
+				{Field: "routes[2].tenants.key", Message: "key of the tenants requires the headers or the operation"},
+				{Field: "routes[2].tenants.max_tenants", Message: "max_tenants can not be negative: [-1]"},
+				{Field: "routes[2].cache.stale_if_error", Message: "stale_if_error can not be negative: [-1m0s]"},
+				{Field: "routes[2].shared_transport", Message: "shared_transport can not be set together with tenants, " +
+					"since the tenants have their own connections"},
+				{Field: "routes[2].idle_timeout", Message: "idle_timeout is only supported by the grpc backends"},
+				{Field: "routes[2].idle_timeout", Message: "idle_timeout and idle_grace_period can not be negative"},
+				{Field: "routes[2].descriptor_set", Message: "descriptor_set is only supported by the grpc backends"},
+				{Field: "routes[2].cache.canonical", Message: "canonical is only supported by the grpc backends"},
+				{Field: "strategy.type", Message: unknownStrategyMessage("fiber.UnknownRoutingStrategy")},
+				{Field: "acceptance.predicate", Message: "unknown acceptance predicate: unknown_predicate"},
+				{Field: "hedging", Message: "hedging is only supported by the lazy router"},
+				{Field: "max_attempts", Message: "max_attempts is only supported by the lazy router"},
+				{Field: "validation", Message: "json_schema and message can not be set together"},
+				{Field: "baggage", Message: "max_members and max_bytes can not be negative"},
+				{Field: "route_tags", Message: "route_tags is only supported by the lazy router"},
+			},
+		},
+		{
+			name:       "router with invalid timeout responses",
+			configPath: "../internal/testdata/config/invalid_timeout_responses.yaml",
+			expectedErrors: config.ValidationErrors{
+				{Field: "routes[0].timeout_response.code",
+					Message: "code must be an http status code in [100, 599] range: [1000]"},
+				{Field: "timeout_response.code", Message: "code must be an http status code in [100, 599] range: [0]"},
+			},
+		},
+		{
+			name:       "grpc proxy with invalid timeout response",
+			configPath: "../internal/testdata/config/invalid_grpc_timeout_response.yaml",
+			expectedErrors: config.ValidationErrors{
+				{Field: "timeout_response.code", Message: "code must be a grpc status code in [0, 16] range: [17]"},
+			},
+		},
+		{
+			name:       "combiner with multiple problems",
+			configPath: "../internal/testdata/config/invalid_combiner_problems.yaml",
+			expectedErrors: config.ValidationErrors{
+				{Field: "routes[0].load_balancing",
+					Message: "unknown load_balancing policy [random], expected round_robin or least_connections"},
+				{Field: "routes[0].max_response_size", Message: "max_response_size can not be negative: [-1]"},
+				{Field: "routes[0].metrics", Message: "metrics of the proxy are only supported, " +
+					"when it's the root component, otherwise the metrics of its router are recorded"},
+				{Field: "routes[0].max_in_flight", Message: "max_in_flight can not be negative: [-1]"},
+				{Field: "routes[0].keepalive", Message: "time and timeout can not be negative"},
+				{Field: "routes[0].backoff.jitter", Message: "jitter must be in [0, 1] range: [2]"},
+				{Field: "routes[0].protocol", Message: "unsupported protocol [websocket], expected http or grpc"},
+				{Field: "routes[0].transcoding", Message: "transcoding is only supported by the grpc backends"},
+				{Field: "routes[0].transcoding", Message: "request_message and response_message are required"},
+				{Field: "routes[0].transcoding.decode_error",
+					Message: "unknown decode error policy [ignore], expected fail, pass_through or error"},
+				{Field: "routes[0].authority",
+					Message: "authority is only supported by the grpc backends, use host for http"},
+				{Field: "routes[0].compressor", Message: "compressor is only supported by the grpc backends"},
+				{Field: "routes[0].compressor", Message: "unknown compressor [snappy]"},
+				{Field: "fan_in.type", Message: "unknown FAN_IN type: fiber.UnknownFanIn"},
+				{Field: "fan_out.weights", Message: "weight of unknown route [route_x]"},
+				{Field: "fan_out.max_concurrency", Message: "max_concurrency can not be negative: [-1]"},
+				{Field: "fan_out.queue_capacity", Message: "queue_capacity can not be negative: [-1]"},
+				{Field: "fan_out.queue_overflow", Message: "unknown queue_overflow [wait], expected block or drop"},
+				{Field: "response_order", Message: "unknown response_order [random], expected arrival or route"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.ValidateConfig(tt.configPath)
+			if tt.expectedErrors == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.expectedErrors, err)
+
+			// the component is not initialized from the invalid config
+			_, err = config.InitComponentFromConfig(tt.configPath)
+			assert.Equal(t, tt.expectedErrors, err)
+		})
+	}
+}
+
+func TestInitComponentFromConfigWithOptions_FailFast(t *testing.T) {
+	_, err := config.InitComponentFromConfigWithOptions(
+		"../internal/testdata/config/invalid_router_problems.yaml",
+		config.LoadOptions{FailFast: true},
+	)
+	assert.EqualError(t, err, "proxy [route_a]: timeout is required, unless the router sets default_timeout")
+}
+
+func TestInitComponentFromConfigWithOptions_Dispatchers(t *testing.T) {
+	failing := &mocks.Dispatcher{}
+	failing.On("Do", mock.Anything, mock.Anything).
+		Return(testUtilsHttp.MockResp(http.StatusServiceUnavailable, "A-NOK", nil, nil)).Maybe()
+
+	var requestURL string
+	inMemory := fiber.DispatcherFunc(func(_ context.Context, req fiber.Request) fiber.Response {
+		requestURL = req.(*fiberhttp.Request).URL.String()
+		return testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil)
+	})
+
+	component, err := config.InitComponentFromConfigWithOptions(
+		"../internal/testdata/config/lazy_router.yaml",
+		config.LoadOptions{Dispatchers: map[string]fiber.Dispatcher{"route_a": failing, "route_b": inMemory}},
+	)
+	require.NoError(t, err)
+
+	// the requests are served by the injected dispatchers, rather than sent to the endpoints over http
+	for i := 0; i < 4; i++ {
+		resp, ok := <-component.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+		require.True(t, ok)
+		assert.Equal(t, "B-OK", string(resp.Payload()))
+	}
+	assert.Equal(t, "http://localhost:8080/routes/route-b/", requestURL)
+	failing.AssertExpectations(t)
+
+	_, err = config.InitComponentFromConfigWithOptions(
+		"../internal/testdata/config/lazy_router.yaml",
+		config.LoadOptions{Dispatchers: map[string]fiber.Dispatcher{"route_x": inMemory}},
+	)
+	assert.EqualError(t, err, "dispatcher of unknown proxy [route_x]")
+}
+
+// countingListener counts the connections, accepted by the listener
+type countingListener struct {
+	net.Listener
+	accepted int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		atomic.AddInt32(&l.accepted, 1)
+	}
+	return conn, err
+}
+
+func TestInitComponentFromConfigWithOptions_WarmUp(t *testing.T) {
+	newListener := func() *countingListener {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		return &countingListener{Listener: listener}
+	}
+
+	listeners := make([]*countingListener, 3)
+	endpoints := make([]string, len(listeners))
+	for idx := range listeners {
+		listeners[idx] = newListener()
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		server.Listener = listeners[idx]
+		server.Start()
+		defer server.Close()
+		endpoints[idx] = server.URL
+	}
+	grpcListener := newListener()
+	grpcServer := grpc.NewServer()
+	go func() { _ = grpcServer.Serve(grpcListener) }()
+	defer grpcServer.Stop()
+
+	// the backend, that is not listening, fails to warm up
+	unreachable := newListener()
+	require.NoError(t, unreachable.Close())
+
+	writeConfig := func(routes string) string {
+		configPath := filepath.Join(t.TempDir(), "warm_router.yaml")
+		require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: EAGER_ROUTER
+id: warm_router
+strategy:
+  type: fiber.RandomRoutingStrategy
+default_timeout: 1s
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+  - id: route_b
+    type: PROXY
+    endpoints: [%q, %q]
+%s`, endpoints[0], endpoints[1], endpoints[2], routes)), 0600))
+		return configPath
+	}
+	grpcConfigPath := filepath.Join(t.TempDir(), "warm_grpc_proxy.yaml")
+	require.NoError(t, ioutil.WriteFile(grpcConfigPath, []byte(fmt.Sprintf(`
+type: PROXY
+id: route_c
+protocol: grpc
+endpoint: %q
+service_method: testproto.UniversalPredictionService/PredictValues
+`, grpcListener.Addr().String())), 0600))
+
+	for _, configPath := range []string{writeConfig(""), grpcConfigPath} {
+		component, err := config.InitComponentFromConfigWithOptions(configPath,
+			config.LoadOptions{WarmUp: &config.WarmUpOptions{Fatal: true}})
+		require.NoError(t, err)
+		defer func() { _ = component.(fiber.Closer).Close(context.Background()) }()
+	}
+
+	// every backend is dialed, before the component is returned
+	for idx, listener := range append(listeners, grpcListener) {
+		assert.Equal(t, int32(1), atomic.LoadInt32(&listener.accepted), "backend %d", idx)
+	}
+
+	withUnreachable := writeConfig(fmt.Sprintf(`  - id: route_d
+    type: PROXY
+    endpoint: "http://%s"
+`, unreachable.Addr().String()))
+
+	t.Run("failures are reported", func(t *testing.T) {
+		var failed []string
+		component, err := config.InitComponentFromConfigWithOptions(withUnreachable,
+			config.LoadOptions{WarmUp: &config.WarmUpOptions{OnError: func(err fiber.WarmUpError) {
+				failed = append(failed, err.RouteID)
+			}}})
+		require.NoError(t, err)
+		defer func() { _ = component.(fiber.Closer).Close(context.Background()) }()
+		assert.Equal(t, []string{"route_d"}, failed)
+	})
+
+	t.Run("failures are fatal", func(t *testing.T) {
+		_, err := config.InitComponentFromConfigWithOptions(withUnreachable,
+			config.LoadOptions{WarmUp: &config.WarmUpOptions{Fatal: true}})
+		var errs fiber.WarmUpErrors
+		require.True(t, errors.As(err, &errs))
+		require.Len(t, errs, 1)
+		assert.Equal(t, "route_d", errs[0].RouteID)
+	})
+}
+
+func TestValidationErrors_Error(t *testing.T) {
+	err := config.ValidationErrors{
+		{Field: "routes[0].timeout", Message: "timeout must be positive: [0s]"},
+		{Field: "strategy.type", Message: "unknown ROUTING_STRATEGY type: fiber.Unknown"},
+	}
+	assert.EqualError(t, err, "invalid config, 2 problem(s) found:\n"+
+		"  - routes[0].timeout: timeout must be positive: [0s]\n"+
+		"  - strategy.type: unknown ROUTING_STRATEGY type: fiber.Unknown")
+}
+
+// unknownStrategyMessage is the error message of the unknown routing strategy type
+func unknownStrategyMessage(name string) string {
+	return fmt.Sprintf("unknown ROUTING_STRATEGY type: %s (registered types: %s)",
+		name, strings.Join(types.RoutingStrategyTypes(), ", "))
+}
+
+// fixedRoutingStrategy is the custom routing strategy, that always selects the route from its properties
+type fixedRoutingStrategy struct {
+	fiber.BaseFiberType
+	route string
+}
+
+func newFixedRoutingStrategy(properties json.RawMessage) (fiber.RoutingStrategy, error) {
+	var props struct {
+		Route string `json:"route"`
+	}
+	if err := json.Unmarshal(properties, &props); err != nil {
+		return nil, err
+	}
+	if props.Route == "" {
+		return nil, errors.New("fixed routing strategy: route is required")
+	}
+	return &fixedRoutingStrategy{route: props.Route}, nil
+}
+
+func (s *fixedRoutingStrategy) SelectRoute(
+	_ context.Context,
+	_ fiber.Request,
+	routes map[string]fiber.Component,
+) (fiber.Component, []fiber.Component, error) {
+	return routes[s.route], nil, nil
+}
+
+func init() {
+	if err := types.RegisterRoutingStrategy("config_test.FixedRoutingStrategy", newFixedRoutingStrategy); err != nil {
+		panic(err)
+	}
+}
+
+func TestFromConfig_CustomRoutingStrategy(t *testing.T) {
+	require.NoError(t, os.Setenv("FIBER_TEST_ROUTE_A_ENDPOINT", newBackend(t, "A")))
+	require.NoError(t, os.Setenv("FIBER_TEST_ROUTE_B_ENDPOINT", newBackend(t, "B")))
+	defer func() {
+		_ = os.Unsetenv("FIBER_TEST_ROUTE_A_ENDPOINT")
+		_ = os.Unsetenv("FIBER_TEST_ROUTE_B_ENDPOINT")
+	}()
+
+	component, err := config.InitComponentFromConfig("../internal/testdata/config/custom_strategy_router.yaml")
+	require.NoError(t, err)
+
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+	for i := 0; i < 5; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "B", recorder.Body.String())
+	}
+}
+
+func TestRegisterRoutingStrategy(t *testing.T) {
+	suite := map[string]struct {
+		name        string
+		factory     types.RoutingStrategyFactory
+		expectedErr string
+	}{
+		"error: empty name": {
+			factory:     newFixedRoutingStrategy,
+			expectedErr: "routing strategy type name can not be empty",
+		},
+		"error: nil factory": {
+			name:        "config_test.NilRoutingStrategy",
+			expectedErr: "factory of routing strategy type config_test.NilRoutingStrategy can not be nil",
+		},
+		"error: already registered": {
+			name:        "config_test.FixedRoutingStrategy",
+			factory:     newFixedRoutingStrategy,
+			expectedErr: "routing strategy type config_test.FixedRoutingStrategy is already registered",
+		},
+		"error: built-in type": {
+			name:        "fiber.RandomRoutingStrategy",
+			factory:     newFixedRoutingStrategy,
+			expectedErr: "routing strategy type fiber.RandomRoutingStrategy is already registered",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			assert.EqualError(t, types.RegisterRoutingStrategy(tt.name, tt.factory), tt.expectedErr)
+		})
+	}
+
+	// the registered strategy can't be replaced by the installed type
+	assert.EqualError(t, types.InstallType("config_test.FixedRoutingStrategy", &fixedRoutingStrategy{}),
+		"routing strategy type config_test.FixedRoutingStrategy is already registered")
+	_, err := types.NewRoutingStrategy("config_test.FixedRoutingStrategy", json.RawMessage(`{"route": "route_a"}`))
+	assert.NoError(t, err)
+
+	// the registered strategy is listed along with the built-in ones
+	assert.Contains(t, types.RoutingStrategyTypes(), "config_test.FixedRoutingStrategy")
+	assert.Contains(t, types.RoutingStrategyTypes(), "fiber.RandomRoutingStrategy")
+	assert.Contains(t, unknownStrategyMessage("fiber.Unknown"), "config_test.FixedRoutingStrategy")
+}
+
+func TestFromConfig_CustomRoutingStrategyError(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: custom_router
+strategy:
+  type: config_test.FixedRoutingStrategy
+  properties: {}
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+`, newBackend(t, "A"))), 0600))
+
+	_, err := config.InitComponentFromConfig(configPath)
+	assert.EqualError(t, err, "fixed routing strategy: route is required")
+}
+
+func TestFromConfig_StickyRoutingStrategy(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "sticky_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: sticky_router
+default_timeout: 1s
+strategy:
+  type: fiber.StickyRoutingStrategy
+  properties:
+    cookie_name: session_route
+    secret: s3cr3t
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+  - id: route_b
+    type: PROXY
+    endpoint: %q
+`, newBackend(t, "A"), newBackend(t, "B"))), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+	router, ok := component.(fiber.Router)
+	require.True(t, ok, "the component should be a router")
+	assert.Equal(t, "sticky_router", router.ID())
+
+	handler := fiberhttp.NewHandler(router, fiberhttp.Options{Timeout: time.Second})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "session_route", cookies[0].Name)
+
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		req.AddCookie(cookies[0])
+		next := httptest.NewRecorder()
+		handler.ServeHTTP(next, req)
+		assert.Equal(t, recorder.Body.String(), next.Body.String())
+	}
+
+	// the router, initialized from the config, explains the routing of the sticky requests as it dispatches them
+	lazyRouter, ok := component.(*fiber.LazyRouter)
+	require.True(t, ok, "the component should be a lazy router")
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.AddCookie(cookies[0])
+	fiberReq, err := fiberhttp.NewHTTPRequest(req)
+	require.NoError(t, err)
+	decision, err := lazyRouter.Explain(context.Background(), fiberReq)
+	require.NoError(t, err)
+	assert.Equal(t, "sticky_router", decision.RouterID)
+	require.NotEmpty(t, decision.Routes)
+	assert.Equal(t, "route_"+strings.ToLower(recorder.Body.String()), decision.Routes[0])
+}
+
+func TestFromConfig_ProxyCache(t *testing.T) {
+	var dispatched int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&dispatched, 1)
+		_, _ = w.Write([]byte(r.URL.Query().Get("id")))
+	}))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "cached_proxy.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: PROXY
+id: cached_proxy
+endpoint: %q
+timeout: 1s
+cache:
+  ttl: 1m
+  capacity: 10
+`, server.URL)), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+	for _, id := range []string{"1", "1", "2", "1"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost/features?id="+id, nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, id, recorder.Body.String())
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&dispatched))
+}
+
+func TestFromConfig_HealthCheck(t *testing.T) {
+	var healthy int32 = 1
+	unhealthyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" && atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("A"))
+	}))
+	defer unhealthyServer.Close()
+
+	configPath := filepath.Join(t.TempDir(), "health_checked_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: health_checked_router
+strategy:
+  type: fiber.RandomRoutingStrategy
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+    health_check:
+      path: /healthz
+      interval: 5ms
+      healthy_threshold: 1
+      unhealthy_threshold: 1
+  - id: route_b
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+`, unhealthyServer.URL, newBackend(t, "B"))), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+	router := component.(fiber.Router)
+	defer router.(fiber.Closer).Close(context.Background())
+
+	route, ok := router.GetRoutes()["route_a"].(fiber.HealthReporter)
+	require.True(t, ok, "the route should report its health")
+	assert.True(t, route.Healthy())
+
+	atomic.StoreInt32(&healthy, 0)
+	require.Eventually(t, func() bool { return !route.Healthy() }, time.Second, time.Millisecond)
+
+	handler := fiberhttp.NewHandler(router, fiberhttp.Options{Timeout: time.Second})
+	for i := 0; i < 10; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.Equal(t, "B", recorder.Body.String())
+	}
+}
+
+func TestFromConfig_MaxInFlight(t *testing.T) {
+	release := make(chan struct{})
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_, _ = w.Write([]byte("A"))
+	}))
+	defer slowServer.Close()
+
+	configPath := filepath.Join(t.TempDir(), "limited_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: limited_router
+strategy:
+  type: fiber.RandomRoutingStrategy
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+    max_in_flight: 1
+`, slowServer.URL)), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+	router := component.(fiber.Router)
+	route, ok := router.GetRoutes()["route_a"].(fiber.SaturationReporter)
+	require.True(t, ok, "the route should report its saturation")
+
+	handler := fiberhttp.NewHandler(router, fiberhttp.Options{Timeout: time.Second})
+	done := make(chan string)
+	go func() {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		done <- recorder.Body.String()
+	}()
+	require.Eventually(t, route.Saturated, time.Second, time.Millisecond)
+
+	// the only route is saturated, so the request is shed
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	close(release)
+	assert.Equal(t, "A", <-done)
+	require.Eventually(t, func() bool { return !route.Saturated() }, time.Second, time.Millisecond)
+}
+
+func TestFromConfig_Endpoints(t *testing.T) {
+	calls := make([]int32, 3)
+	endpoints := make([]string, len(calls))
+	for idx := range calls {
+		idx := idx
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls[idx], 1)
+			_, _ = w.Write([]byte(r.URL.Path))
+		}))
+		defer server.Close()
+		endpoints[idx] = server.URL + "/replica"
+	}
+
+	configPath := filepath.Join(t.TempDir(), "pooled_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: pooled_router
+strategy:
+  type: fiber.RandomRoutingStrategy
+routes:
+  - id: route_a
+    type: PROXY
+    endpoints: [%q, %q, %q]
+    load_balancing: round_robin
+    timeout: 1s
+`, endpoints[0], endpoints[1], endpoints[2])), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+	router := component.(fiber.Router)
+	assert.Equal(t, endpoints, fiber.DescribeComponent(router).Routes[0].Endpoints)
+
+	handler := fiberhttp.NewHandler(router, fiberhttp.Options{Timeout: time.Second})
+	for i := 0; i < 9; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost/predict", nil))
+		assert.Equal(t, "/replica/predict", recorder.Body.String())
+	}
+	// the requests are spread across the endpoints of the route
+	for idx := range calls {
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls[idx]), "endpoint %d", idx)
+	}
+}
+
+func TestFromConfig_Decompress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		_, _ = writer.Write([]byte("decoded"))
+		_ = writer.Close()
+	}))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "decompressing_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: decompressing_router
+strategy:
+  type: fiber.RandomRoutingStrategy
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+    decompress: true
+`, server.URL)), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+
+	// the encoding accepted by the client is sent to the backend, but the decoded payload is sent back
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/predict", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "decoded", recorder.Body.String())
+	assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+}
+
+func TestFromConfig_RouteOverride(t *testing.T) {
+	endpoints := make(map[string]string)
+	for _, id := range []string{"route_a", "route_b"} {
+		id := id
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(id))
+		}))
+		defer server.Close()
+		endpoints[id] = server.URL
+	}
+
+	configPath := filepath.Join(t.TempDir(), "overridable_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: overridable_router
+strategy:
+  type: fiber.RandomRoutingStrategy
+route_override:
+  header: X-Canary
+  reject_unknown: true
+default_timeout: 1s
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+  - id: route_b
+    type: PROXY
+    endpoint: %q
+`, endpoints["route_a"], endpoints["route_b"])), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+	dispatch := func(route string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/predict", nil)
+		req.Header.Set("X-Canary", route)
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// the random strategy is bypassed by the pinned route
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, "route_b", dispatch("route_b").Body.String())
+	}
+	assert.Equal(t, http.StatusBadRequest, dispatch("route_x").Code)
+}
+
+func TestFromConfig_RouteTags(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "tagged_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: tagged_router
+strategy:
+  type: fiber.RandomRoutingStrategy
+route_tags:
+  headers:
+    region: X-Region
+default_timeout: 1s
+routes:
+  - id: route_us_a
+    type: PROXY
+    endpoint: %q
+    tags:
+      region: us
+  - id: route_us_b
+    type: PROXY
+    endpoint: %q
+    tags:
+      region: us
+  - id: route_eu
+    type: PROXY
+    endpoint: %q
+    tags:
+      region: eu
+`, newBackend(t, "route_us_a"), newBackend(t, "route_us_b"), newBackend(t, "route_eu"))), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+	dispatch := func(region string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/predict", nil)
+		if region != "" {
+			req.Header.Set("X-Region", region)
+		}
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	selected := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		selected[dispatch("us").Body.String()]++
+	}
+	assert.Zero(t, selected["route_eu"])
+	assert.Equal(t, 20, selected["route_us_a"]+selected["route_us_b"])
+
+	assert.Equal(t, "route_eu", dispatch("eu").Body.String())
+	assert.Equal(t, http.StatusOK, dispatch("").Code)
+	assert.Equal(t, http.StatusBadRequest, dispatch("ap").Code)
+}
+
+func TestFromConfig_Hedging(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		_, _ = w.Write([]byte("route_a"))
+	}))
+	defer slow.Close()
+
+	configPath := filepath.Join(t.TempDir(), "hedged_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: hedged_router
+strategy:
+  type: fiber.RandomRoutingStrategy
+route_override:
+  fallback: true
+hedging:
+  delay: 20ms
+  max_hedges: 1
+default_timeout: 2s
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+  - id: route_b
+    type: PROXY
+    endpoint: %q
+`, slow.URL, newBackend(t, "route_b"))), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: 2 * time.Second})
+
+	// the slow route is pinned as the primary one, and the request is hedged to the fallback
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/predict", nil)
+	req.Header.Set(fiber.RouteOverrideHeader, "route_a")
+	start := time.Now()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "route_b", recorder.Body.String())
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+}
+
+func TestFromConfig_Validation(t *testing.T) {
+	dir := t.TempDir()
+	schemaPath := filepath.Join(dir, "request.json")
+	require.NoError(t, ioutil.WriteFile(schemaPath, []byte(`{
+	"type": "object",
+	"required": ["id"],
+	"properties": {"id": {"type": "string"}}
+}`), 0600))
+
+	configPath := filepath.Join(dir, "validated_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: validated_router
+strategy:
+  type: fiber.RandomRoutingStrategy
+validation:
+  json_schema: %q
+default_timeout: 1s
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+`, schemaPath, newBackend(t, "route_a"))), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+	dispatch := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "http://localhost/predict", strings.NewReader(body)))
+		return recorder
+	}
+
+	assert.Equal(t, "route_a", dispatch(`{"id": "req-1"}`).Body.String())
+
+	recorder := dispatch(`{"id": 1}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "request doesn't match the schema: $.id: expected type string, got integer")
+
+	// the schema, that can't be loaded, fails the initialization of the router
+	require.NoError(t, ioutil.WriteFile(schemaPath, []byte(`{"$ref": "#/definitions/request"}`), 0600))
+	_, err = config.InitComponentFromConfig(configPath)
+	assert.EqualError(t, err, "router [validated_router]: json schema: $: $ref is not supported")
+}
+
+func TestFromConfig_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "backend.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("route_a " + r.URL.Path))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "unix_socket_proxy.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: PROXY
+id: route_a
+endpoint: %q
+timeout: 1s
+`, "unix://"+socket)), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second}).
+		ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost/predict", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "route_a /predict", recorder.Body.String())
+}
+
+func TestFromConfig_Acceptance(t *testing.T) {
+	require.NoError(t, types.RegisterAcceptancePredicate("config_test.non_empty",
+		func(_ fiber.Request, resp fiber.Response) bool { return len(resp.Payload()) > 0 }))
+
+	endpoints := make(map[string]string)
+	for id, body := range map[string]string{"route_a": "", "route_b": "B-OK"} {
+		body := body
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(body))
+		}))
+		defer server.Close()
+		endpoints[id] = server.URL
+	}
+
+	writeConfig := func(routerType string) string {
+		configPath := filepath.Join(t.TempDir(), "accepting_router.yaml")
+		require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: %s
+id: accepting_router
+strategy:
+  type: fiber.RoundRobinRoutingStrategy
+acceptance:
+  predicate: config_test.non_empty
+default_timeout: 1s
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+  - id: route_b
+    type: PROXY
+    endpoint: %q
+`, routerType, endpoints["route_a"], endpoints["route_b"])), 0600))
+		return configPath
+	}
+
+	component, err := config.InitComponentFromConfig(writeConfig("EAGER_ROUTER"))
+	require.NoError(t, err)
+
+	// the empty response of route_a is never selected, even if it's the primary route
+	for i := 0; i < 4; i++ {
+		resp, ok := <-component.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+		require.True(t, ok)
+		assert.Equal(t, "B-OK", string(resp.Payload()))
+	}
+
+	// the lazy router doesn't support the acceptance predicate
+	_, err = config.InitComponentFromConfig(writeConfig("LAZY_ROUTER"))
+	assert.Equal(t, config.ValidationErrors{
+		{Field: "acceptance", Message: "acceptance is only supported by the eager router"},
+	}, err)
+}
+
+func TestFromConfig_FailureStatusCodes(t *testing.T) {
+	newStatusBackend := func(status int) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+
+	suite := map[string]struct {
+		endpoint       string
+		expectedStatus int
+	}{
+		"successful response in the failure set falls back": {
+			endpoint:       newStatusBackend(http.StatusAccepted),
+			expectedStatus: http.StatusOK,
+		},
+		"non-successful response in the failure set falls back": {
+			endpoint:       newStatusBackend(http.StatusTooManyRequests),
+			expectedStatus: http.StatusOK,
+		},
+		"non-successful response not in the failure set falls back": {
+			endpoint:       newStatusBackend(http.StatusNotFound),
+			expectedStatus: http.StatusOK,
+		},
+		"successful response not in the failure set is sent back": {
+			endpoint:       newStatusBackend(http.StatusCreated),
+			expectedStatus: http.StatusCreated,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "router.yaml")
+			require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: LAZY_ROUTER
+id: lazy_router
+failure_status_codes: [202, 429]
+strategy:
+  type: fiber.WeightedRandomRoutingStrategy
+  properties:
+    weights:
+      route_a: 1
+      route_b: 0
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+  - id: route_b
+    type: PROXY
+    endpoint: %q
+    timeout: 1s
+`, tt.endpoint, newBackend(t, "B"))), 0600))
+
+			component, err := config.InitComponentFromConfig(configPath)
+			require.NoError(t, err)
+
+			handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+		})
+	}
+}
+
+func TestFromConfig_RateLimitedRetries(t *testing.T) {
+	var hits int32
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	configPath := filepath.Join(t.TempDir(), "proxy.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+id: proxy
+type: PROXY
+endpoint: %q
+timeout: 1s
+retry:
+  max_attempts: 5
+  initial_backoff: 1ms
+rate_limit:
+  rate: 1
+  burst: 2
+`, unavailable.URL)), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+
+	// each retry takes its own turn, so the request is rate-limited after the burst
+	resp, ok := <-component.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+	require.True(t, ok)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+	assert.True(t, component.(fiber.SaturationReporter).Saturated())
+}
+
+func TestFromConfig_RacingRouter(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+			_, _ = w.Write([]byte("A"))
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	configPath := filepath.Join(t.TempDir(), "racing_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: RACING_ROUTER
+id: racing_router
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+    timeout: 2s
+  - id: route_b
+    type: PROXY
+    endpoint: %q
+    timeout: 2s
+`, slow.URL, newBackend(t, "B"))), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+	assert.IsType(t, &fiber.RacingRouter{}, component)
+
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: 2 * time.Second})
+	recorder := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "B", recorder.Body.String())
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestFromConfig_RacingRouterMaxConcurrency(t *testing.T) {
+	var inFlight, maxFlight, calls int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			highest := atomic.LoadInt32(&maxFlight)
+			if current <= highest || atomic.CompareAndSwapInt32(&maxFlight, highest, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	configPath := filepath.Join(t.TempDir(), "racing_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: RACING_ROUTER
+id: racing_router
+max_concurrency: 1
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %[1]q
+    timeout: 1s
+  - id: route_b
+    type: PROXY
+    endpoint: %[1]q
+    timeout: 1s
+  - id: route_c
+    type: PROXY
+    endpoint: %[1]q
+    timeout: 1s
+`, failing.URL)), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+
+	handler := fiberhttp.NewHandler(component, fiberhttp.Options{Timeout: time.Second})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	// all the routes fail, so each of them is called, one at a time
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxFlight))
+}
+
+func TestFromConfig_Describe(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "described_router.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: EAGER_ROUTER
+id: eager_router
+default_timeout: 1s
+strategy:
+  type: fiber.RandomRoutingStrategy
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %q
+    timeout: 2s
+    circuit_breaker: {}
+  - id: route_b
+    type: PROXY
+    endpoint: %q
+    max_timeout: 3s
+    health_check:
+      path: /
+      interval: 1m
+`, newBackend(t, "A"), newBackend(t, "B"))), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+	defer component.(fiber.Closer).Close(context.Background())
+
+	info := fiber.DescribeComponent(component)
+	assert.Equal(t, "eager_router", info.ID)
+	require.Len(t, info.Routes, 2)
+
+	routeA, routeB := info.Routes[0], info.Routes[1]
+	assert.Equal(t, "route_a", routeA.ID)
+	assert.Equal(t, protocol.HTTP, routeA.Protocol)
+	assert.Equal(t, "2s", routeA.Timeout)
+	assert.Equal(t, "closed", routeA.CircuitState)
+	assert.Nil(t, routeA.Healthy)
+
+	assert.Equal(t, "route_b", routeB.ID)
+	assert.Equal(t, "1s", routeB.Timeout)
+	assert.Empty(t, routeB.CircuitState)
+	require.NotNil(t, routeB.Healthy)
+	assert.True(t, *routeB.Healthy)
+}
+
+func TestFromConfig_DefaultTimeout(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           string
+		expectedTimeouts map[string]string
+		expectedErr      error
+	}{
+		{
+			name: "route timeout overrides router default",
+			config: `
+type: EAGER_ROUTER
+id: eager_router
+default_timeout: 3s
+strategy:
+  type: fiber.RandomRoutingStrategy
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %[1]q
+  - id: route_b
+    type: PROXY
+    endpoint: %[1]q
+    timeout: 500ms
+`,
+			expectedTimeouts: map[string]string{"route_a": "3s", "route_b": "500ms"},
+		},
+		{
+			name: "nested router inherits default, unless it sets its own",
+			config: `
+type: EAGER_ROUTER
+id: eager_router
+default_timeout: 3s
+strategy:
+  type: fiber.RandomRoutingStrategy
+routes:
+  - id: nested_a
+    type: LAZY_ROUTER
+    strategy:
+      type: fiber.RandomRoutingStrategy
+    routes:
+      - id: route_a
+        type: PROXY
+        endpoint: %[1]q
+  - id: nested_b
+    type: LAZY_ROUTER
+    default_timeout: 2s
+    strategy:
+      type: fiber.RandomRoutingStrategy
+    routes:
+      - id: route_b
+        type: PROXY
+        endpoint: %[1]q
+`,
+			expectedTimeouts: map[string]string{"route_a": "3s", "route_b": "2s"},
+		},
+		{
+			name: "neither route timeout nor router default",
+			config: `
+type: EAGER_ROUTER
+id: eager_router
+strategy:
+  type: fiber.RandomRoutingStrategy
+routes:
+  - id: route_a
+    type: PROXY
+    endpoint: %[1]q
+  - id: route_b
+    type: PROXY
+    endpoint: %[1]q
+    timeout: 500ms
+`,
+			expectedErr: config.ValidationErrors{
+				{Field: "routes[0].timeout", Message: "timeout is required, unless the router sets default_timeout"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "router.yaml")
+			require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(tt.config, newBackend(t, "A"))), 0600))
+
+			component, err := config.InitComponentFromConfig(configPath)
+			if tt.expectedErr != nil {
+				assert.Equal(t, tt.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			timeouts := make(map[string]string)
+			var collect func(info fiber.ComponentInfo)
+			collect = func(info fiber.ComponentInfo) {
+				if len(info.Routes) == 0 {
+					timeouts[info.ID] = info.Timeout
+				}
+				for _, route := range info.Routes {
+					collect(route)
+				}
+			}
+			collect(fiber.DescribeComponent(component))
+			assert.Equal(t, tt.expectedTimeouts, timeouts)
+		})
+	}
+}
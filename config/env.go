@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// envVarPattern matches the `${ENV_VAR}` and `${ENV_VAR:-default}` references
+var envVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolateEnv replaces the references to the environment variables in the string values
+// of the JSON config with their values. The value of `${ENV_VAR:-default}` falls back to the default,
+// if the variable is not set or empty, and `${ENV_VAR}` fails, if the variable is not set
+func interpolateEnv(data []byte) ([]byte, error) {
+	if !envVarPattern.Match(data) {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// keep the numbers as is, so they are not converted into floats
+	decoder.UseNumber()
+
+	var cfg interface{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	cfg, err := interpolateValue(cfg, "")
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(cfg)
+}
+
+// interpolateValue recursively interpolates the string values. The path of the value is used
+// to name the field in the error message
+func interpolateValue(value interface{}, path string) (interface{}, error) {
+	switch typed := value.(type) {
+	case string:
+		return interpolateString(typed, path)
+	case map[string]interface{}:
+		// the fields are visited in a stable order, so the same missing variable is reported every time
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			interpolated, err := interpolateValue(typed[key], field)
+			if err != nil {
+				return nil, err
+			}
+			typed[key] = interpolated
+		}
+	case []interface{}:
+		for idx, item := range typed {
+			interpolated, err := interpolateValue(item, path+"["+strconv.Itoa(idx)+"]")
+			if err != nil {
+				return nil, err
+			}
+			typed[idx] = interpolated
+		}
+	}
+	return value, nil
+}
+
+func interpolateString(value string, path string) (string, error) {
+	var err error
+	interpolated := envVarPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		match := envVarPattern.FindStringSubmatch(ref)
+		name, hasDefault, defaultValue := match[1], match[2] != "", match[3]
+
+		envValue, ok := os.LookupEnv(name)
+		switch {
+		case hasDefault && envValue == "":
+			return defaultValue
+		case !ok && err == nil:
+			err = fmt.Errorf("config: environment variable [%s] of field [%s] is not set", name, path)
+		}
+		return envValue
+	})
+	return interpolated, err
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gojek/fiber"
+)
+
+// ReloadEvent describes the outcome of the reload of the config of a ReloadableRouter
+type ReloadEvent struct {
+	// ConfigPath is the path to the config, that was loaded
+	ConfigPath string
+	// RouterID is the ID of the router, that dispatches the requests after the reload
+	RouterID string
+	// Err is the error of the reload, if it has failed. In this case, the previous router is kept
+	Err error
+}
+
+// ReloadableRouter is a Component, that dispatches the requests by the router, initialized from
+// the config file, and re-creates it from the config on Reload, e.g. to change the weights of the routes
+// or add new routes without a restart. The new router replaces the previous one atomically: the in-flight
+// dispatches are completed by the previous router, while the new ones are dispatched by the new router.
+// The state of the previous router's components, e.g. the circuit breakers, is not carried over, and
+// the previous router is closed (see fiber.Closer), once its in-flight dispatches are complete
+type ReloadableRouter struct {
+	fiber.BaseFiberType
+
+	// router holds the fiber.Router, that dispatches the requests
+	router atomic.Value
+	// swap is held by the dispatches, while they enter the current router, so the previous router
+	// is not closed, before the dispatches, that have already picked it, are started
+	swap sync.RWMutex
+
+	// lock serializes the reloads with the changes of the interceptors and the subscribers
+	lock         sync.Mutex
+	configPath   string
+	interceptors []reloadableInterceptors
+	subscribers  []func(ReloadEvent)
+}
+
+// reloadableInterceptors are the interceptors, added to the ReloadableRouter, that are re-added
+// to each new router after the reload
+type reloadableInterceptors struct {
+	recursive    bool
+	interceptors []fiber.Interceptor
+}
+
+// NewReloadableRouter creates the ReloadableRouter from the router config at the given path
+func NewReloadableRouter(configPath string) (*ReloadableRouter, error) {
+	router, err := initRouterFromConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	reloadable := &ReloadableRouter{configPath: configPath}
+	reloadable.router.Store(router)
+	return reloadable, nil
+}
+
+// ID returns the ID of the current router
+func (r *ReloadableRouter) ID() string {
+	return r.current().ID()
+}
+
+// Kind returns the kind of the current router
+func (r *ReloadableRouter) Kind() fiber.ComponentKind {
+	return r.current().Kind()
+}
+
+// Dispatch dispatches the request by the current router
+func (r *ReloadableRouter) Dispatch(ctx context.Context, req fiber.Request) fiber.ResponseQueue {
+	r.swap.RLock()
+	defer r.swap.RUnlock()
+	return r.current().Dispatch(ctx, req)
+}
+
+// AddInterceptor adds the interceptors to the current router, and to all the routers, created on the reloads
+func (r *ReloadableRouter) AddInterceptor(recursive bool, interceptors ...fiber.Interceptor) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.interceptors = append(r.interceptors, reloadableInterceptors{recursive: recursive, interceptors: interceptors})
+	r.current().AddInterceptor(recursive, interceptors...)
+}
+
+// Describe describes the routes of the current router (see fiber.Describer)
+func (r *ReloadableRouter) Describe(info *fiber.ComponentInfo) {
+	if describer, ok := r.current().(fiber.Describer); ok {
+		describer.Describe(info)
+	}
+}
+
+// Close closes the current router (see fiber.Closer), so it stops accepting new requests, and waits
+// for its in-flight dispatches to complete, until the context is done
+func (r *ReloadableRouter) Close(ctx context.Context) error {
+	if closer, ok := r.current().(fiber.Closer); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+// WarmUp warms up the routes of the current router (see fiber.Warmer). The routers, created on the reloads,
+// are not warmed up
+func (r *ReloadableRouter) WarmUp(ctx context.Context) error {
+	return fiber.WarmUp(ctx, r.current())
+}
+
+// Subscribe registers the subscriber, that is called after each reload, whether it has succeeded or not,
+// e.g. to log the reloads. The subscribers are called synchronously, in the order they were registered
+func (r *ReloadableRouter) Subscribe(subscriber func(ReloadEvent)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.subscribers = append(r.subscribers, subscriber)
+}
+
+// Reload creates the new router from the config at the given path, or the path, the router was last
+// loaded from, if it's empty, and replaces the current router with it. If the config is invalid,
+// the current router is kept and the error is returned
+func (r *ReloadableRouter) Reload(configPath string) error {
+	r.lock.Lock()
+	if configPath == "" {
+		configPath = r.configPath
+	}
+	event := ReloadEvent{ConfigPath: configPath}
+
+	router, err := initRouterFromConfig(configPath)
+	var previous fiber.Router
+	if err == nil {
+		for _, added := range r.interceptors {
+			router.AddInterceptor(added.recursive, added.interceptors...)
+		}
+		previous = r.current()
+		r.swap.Lock()
+		r.router.Store(router)
+		r.swap.Unlock()
+		r.configPath = configPath
+	}
+	event.RouterID, event.Err = r.current().ID(), err
+	subscribers := r.subscribers
+	r.lock.Unlock()
+
+	// the previous router is closed, once its in-flight dispatches are complete, to release its resources,
+	// e.g. to stop the health checks of its routes
+	if closer, ok := previous.(fiber.Closer); ok {
+		go func() { _ = closer.Close(context.Background()) }()
+	}
+
+	for _, subscriber := range subscribers {
+		subscriber(event)
+	}
+	return err
+}
+
+func (r *ReloadableRouter) current() fiber.Router {
+	return r.router.Load().(fiber.Router)
+}
+
+// initRouterFromConfig initializes the component from the config, and checks it's a router
+func initRouterFromConfig(configPath string) (fiber.Router, error) {
+	component, err := InitComponentFromConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	router, ok := component.(fiber.Router)
+	if !ok {
+		return nil, fmt.Errorf("reloadable router: component [%s] of config [%s] is not a router", component.ID(), configPath)
+	}
+	return router, nil
+}
@@ -0,0 +1,180 @@
+package config_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/config"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reloadableRouterConfig is the config of the lazy router, that dispatches the requests by the given routes
+const reloadableRouterConfig = `
+type: LAZY_ROUTER
+id: %s
+default_timeout: 1s
+strategy:
+  type: fiber.RandomRoutingStrategy
+routes:
+%s`
+
+// countingInterceptor counts the dispatches of the component it's added to
+type countingInterceptor struct {
+	fiber.NoopAfterDispatchInterceptor
+	fiber.NoopAfterCompletionInterceptor
+	dispatches int64
+}
+
+func (i *countingInterceptor) BeforeDispatch(ctx context.Context, _ fiber.Request) context.Context {
+	atomic.AddInt64(&i.dispatches, 1)
+	return ctx
+}
+
+// newBackend starts the backend, that responds with the given body
+func newBackend(t *testing.T, body string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func writeRouterConfig(t *testing.T, path string, routerID string, endpoints ...string) {
+	var routes string
+	for idx, endpoint := range endpoints {
+		routes += fmt.Sprintf("  - id: route_%d\n    type: PROXY\n    endpoint: %q\n", idx, endpoint)
+	}
+	require.NoError(t, ioutil.WriteFile(path, []byte(fmt.Sprintf(reloadableRouterConfig, routerID, routes)), 0600))
+}
+
+func dispatch(t *testing.T, component fiber.Component) string {
+	resp, ok := <-component.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+	require.True(t, ok)
+	return string(resp.Payload())
+}
+
+func TestReloadableRouter_Reload(t *testing.T) {
+	backendA, backendB := newBackend(t, "A"), newBackend(t, "B")
+	configPath := filepath.Join(t.TempDir(), "router.yaml")
+	invalidPath := filepath.Join(t.TempDir(), "invalid.yaml")
+	require.NoError(t, ioutil.WriteFile(invalidPath, []byte("type: LAZY_ROUTER\nstrategy: [}"), 0600))
+
+	writeRouterConfig(t, configPath, "router_v1", backendA)
+	router, err := config.NewReloadableRouter(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "router_v1", router.ID())
+	assert.Equal(t, fiber.MultiRouteComponentKind, router.Kind())
+
+	var events []config.ReloadEvent
+	router.Subscribe(func(event config.ReloadEvent) {
+		events = append(events, event)
+	})
+	interceptor := &countingInterceptor{}
+	router.AddInterceptor(false, interceptor)
+	assert.Equal(t, "A", dispatch(t, router))
+
+	// the routes are replaced
+	writeRouterConfig(t, configPath, "router_v2", backendB)
+	require.NoError(t, router.Reload(""))
+	assert.Equal(t, "router_v2", router.ID())
+	assert.Equal(t, "B", dispatch(t, router))
+
+	// the malformed config keeps the previous router
+	err = router.Reload(invalidPath)
+	require.Error(t, err)
+	assert.Equal(t, "router_v2", router.ID())
+	assert.Equal(t, "B", dispatch(t, router))
+
+	// the previous config path is kept as well
+	writeRouterConfig(t, configPath, "router_v3", backendA)
+	require.NoError(t, router.Reload(""))
+	assert.Equal(t, "A", dispatch(t, router))
+
+	// the interceptors are added to the new routers
+	assert.Equal(t, int64(4), atomic.LoadInt64(&interceptor.dispatches))
+	assert.Equal(t, []config.ReloadEvent{
+		{ConfigPath: configPath, RouterID: "router_v2"},
+		{ConfigPath: invalidPath, RouterID: "router_v2", Err: err},
+		{ConfigPath: configPath, RouterID: "router_v3"},
+	}, events)
+}
+
+func TestNewReloadableRouter(t *testing.T) {
+	tests := []struct {
+		name           string
+		configPath     string
+		expectedErrMsg string
+	}{
+		{
+			name:       "router",
+			configPath: "../internal/testdata/config/lazy_router.yaml",
+		},
+		{
+			name:       "proxy",
+			configPath: "../internal/testdata/config/http_proxy.yaml",
+			expectedErrMsg: "reloadable router: component [proxy_name] of config " +
+				"[../internal/testdata/config/http_proxy.yaml] is not a router",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.NewReloadableRouter(tt.configPath)
+			if tt.expectedErrMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErrMsg)
+			}
+		})
+	}
+}
+
+func TestReloadableRouter_ConcurrentDispatch(t *testing.T) {
+	backendA, backendB := newBackend(t, "A"), newBackend(t, "B")
+	configPathA := filepath.Join(t.TempDir(), "router_a.yaml")
+	configPathB := filepath.Join(t.TempDir(), "router_b.yaml")
+	writeRouterConfig(t, configPathA, "router_a", backendA)
+	writeRouterConfig(t, configPathB, "router_b", backendB)
+
+	router, err := config.NewReloadableRouter(configPathA)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for routine := 0; routine < 4; routine++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					payload := dispatch(t, router)
+					assert.Contains(t, []string{"A", "B"}, payload)
+				}
+			}
+		}()
+	}
+
+	for idx := 0; idx < 20; idx++ {
+		configPath := configPathA
+		if idx%2 == 0 {
+			configPath = configPathB
+		}
+		require.NoError(t, router.Reload(configPath))
+	}
+	close(done)
+	wg.Wait()
+	assert.Equal(t, "A", dispatch(t, router))
+}
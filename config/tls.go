@@ -0,0 +1,106 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// DefaultTLSMinVersion is the minimum TLS version of the connections to the backend, that is used,
+// if `min_version` is not set in the TLS config of the proxy. The older versions are not supported
+const DefaultTLSMinVersion = "1.2"
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig is used to parse the `tls` configuration of the proxy, that is applied to the connections
+// to its backend. The zero value uses DefaultTLSMinVersion, the default cipher suites of Go and
+// the system's CA certificates. See TLSClientConfig for the validation of the settings
+type TLSConfig struct {
+	// MinVersion is the minimum TLS version, that is acceptable. Supported values: "1.2", "1.3"
+	MinVersion string `json:"min_version,omitempty"`
+	// CipherSuites is the list of enabled TLS 1.2 cipher suites (i.e. `TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256`).
+	// Only the cipher suites, that are considered secure, are accepted. TLS 1.3 cipher suites are not configurable
+	CipherSuites []string `json:"cipher_suites,omitempty"`
+	// CACert is the path to the PEM file with the CA certificates, that the certificate of the backend
+	// is verified with. By default, the system's CA certificates are used
+	CACert string `json:"ca_cert,omitempty"`
+	// ClientCert is the path to the PEM file with the client certificate, that is presented to the backend
+	// for the mutual TLS. It has to be set together with ClientKey
+	ClientCert string `json:"client_cert,omitempty"`
+	// ClientKey is the path to the PEM file with the private key of ClientCert
+	ClientKey string `json:"client_key,omitempty"`
+	// ServerName, if set, overrides the host name, that the certificate of the backend is verified against
+	ServerName string `json:"server_name,omitempty"`
+	// InsecureSkipVerify disables the verification of the certificate of the backend.
+	// It should only be used for development
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// TLSClientConfig validates the TLS configuration and creates a tls.Config from it
+func (c *TLSConfig) TLSClientConfig() (*tls.Config, error) {
+	minVersion := c.MinVersion
+	if minVersion == "" {
+		minVersion = DefaultTLSMinVersion
+	}
+	version, ok := tlsVersions[strings.TrimPrefix(strings.ToUpper(minVersion), "TLS")]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TLS min_version: [%s]", c.MinVersion)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         version,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if len(c.CipherSuites) > 0 {
+		secure := make(map[string]uint16)
+		for _, suite := range tls.CipherSuites() {
+			secure[suite.Name] = suite.ID
+		}
+		insecure := make(map[string]bool)
+		for _, suite := range tls.InsecureCipherSuites() {
+			insecure[suite.Name] = true
+		}
+
+		for _, name := range c.CipherSuites {
+			if id, ok := secure[name]; ok {
+				tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+			} else if insecure[name] {
+				return nil, fmt.Errorf("insecure TLS cipher suite: [%s]", name)
+			} else {
+				return nil, fmt.Errorf("unknown TLS cipher suite: [%s]", name)
+			}
+		}
+	}
+
+	if c.CACert != "" {
+		pem, err := ioutil.ReadFile(c.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS ca_cert [%s]: %v", c.CACert, err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to parse TLS ca_cert [%s]: no PEM certificates found", c.CACert)
+		}
+	}
+
+	if c.ClientCert != "" || c.ClientKey != "" {
+		if c.ClientCert == "" || c.ClientKey == "" {
+			return nil, errors.New("TLS client_cert and client_key have to be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client_cert [%s] and client_key [%s]: %v",
+				c.ClientCert, c.ClientKey, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
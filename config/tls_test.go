@@ -0,0 +1,400 @@
+package config_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber/config"
+	fibergrpc "github.com/gojek/fiber/grpc"
+	fiberhttp "github.com/gojek/fiber/http"
+	testproto "github.com/gojek/fiber/internal/testdata/gen/testdata/proto"
+	testutils "github.com/gojek/fiber/internal/testutils/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTLSConfig_TLSClientConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.TLSConfig
+		expected *tls.Config
+		errMsg   string
+	}{
+		{
+			name:     "default",
+			cfg:      config.TLSConfig{},
+			expected: &tls.Config{MinVersion: tls.VersionTLS12},
+		},
+		{
+			name: "min version and cipher suites",
+			cfg: config.TLSConfig{
+				MinVersion:   "TLS1.3",
+				CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			},
+			expected: &tls.Config{
+				MinVersion:   tls.VersionTLS13,
+				CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			},
+		},
+		{
+			name:   "unsupported min version",
+			cfg:    config.TLSConfig{MinVersion: "1.0"},
+			errMsg: "unsupported TLS min_version: [1.0]",
+		},
+		{
+			name:   "insecure cipher suite",
+			cfg:    config.TLSConfig{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+			errMsg: "insecure TLS cipher suite: [TLS_RSA_WITH_RC4_128_SHA]",
+		},
+		{
+			name:   "unknown cipher suite",
+			cfg:    config.TLSConfig{CipherSuites: []string{"TLS_UNKNOWN"}},
+			errMsg: "unknown TLS cipher suite: [TLS_UNKNOWN]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.TLSClientConfig()
+			if tt.errMsg == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, got)
+			} else {
+				assert.EqualError(t, err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestFromConfig_TLSMinVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "fiber_tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "tls_proxy.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: PROXY
+id: proxy_name
+endpoint: "%s"
+tls:
+  min_version: "1.3"
+`, server.URL)), 0600))
+
+	component, err := config.InitComponentFromConfig(configPath)
+	require.NoError(t, err)
+
+	httpReq, _ := http.NewRequest(http.MethodGet, "", nil)
+	req, _ := fiberhttp.NewHTTPRequest(httpReq)
+
+	resp, ok := <-component.Dispatch(httpReq.Context(), req).Iter()
+	require.True(t, ok)
+	assert.False(t, resp.IsSuccess())
+	assert.Contains(t, string(resp.Payload()), "protocol version")
+}
+
+func TestFromConfig_InvalidTLS(t *testing.T) {
+	_, err := config.InitComponentFromConfig("../internal/testdata/config/invalid_tls_proxy.yaml")
+	assert.EqualError(t, err, "unsupported TLS min_version: [1.1]")
+}
+
+// testCertificate is the PEM-encoded certificate and key, written into the temporary files
+type testCertificate struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certPath string
+	keyPath  string
+}
+
+// newTestCertificate issues the certificate for the given DNS names, signed by the parent certificate,
+// or the self-signed CA certificate, if there is no parent
+func newTestCertificate(t *testing.T, name string, parent *testCertificate, dnsNames ...string) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certificate := &testCertificate{
+		cert:     cert,
+		key:      key,
+		certPath: filepath.Join(dir, name+".crt"),
+		keyPath:  filepath.Join(dir, name+".key"),
+	}
+	require.NoError(t, ioutil.WriteFile(certificate.certPath,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(certificate.keyPath,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certificate
+}
+
+func TestFromConfig_GrpcMutualTLS(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	serverCert := newTestCertificate(t, "server", ca, "backend.test")
+	clientCert := newTestCertificate(t, "client", ca)
+
+	// the server only accepts the connections with the client certificates, signed by the CA
+	serverKeyPair, err := tls.LoadX509KeyPair(serverCert.certPath, serverCert.keyPath)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverKeyPair},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})))
+	testproto.RegisterUniversalPredictionServiceServer(server, &testutils.GrpcTestServer{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	payload, err := proto.Marshal(&testproto.PredictValuesRequest{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		tls        string
+		expectedOK bool
+	}{
+		{
+			name: "mutual tls",
+			tls: fmt.Sprintf("  ca_cert: %q\n  client_cert: %q\n  client_key: %q\n  server_name: backend.test\n",
+				ca.certPath, clientCert.certPath, clientCert.keyPath),
+			expectedOK: true,
+		},
+		{
+			name: "insecure skip verify",
+			tls: fmt.Sprintf("  client_cert: %q\n  client_key: %q\n  insecure_skip_verify: true\n",
+				clientCert.certPath, clientCert.keyPath),
+			expectedOK: true,
+		},
+		{
+			name: "server name mismatch",
+			tls: fmt.Sprintf("  ca_cert: %q\n  client_cert: %q\n  client_key: %q\n",
+				ca.certPath, clientCert.certPath, clientCert.keyPath),
+		},
+		{
+			name: "no client certificate",
+			tls:  fmt.Sprintf("  ca_cert: %q\n  server_name: backend.test\n", ca.certPath),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "grpc_tls_proxy.yaml")
+			require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: PROXY
+id: proxy_name
+timeout: "2s"
+endpoint: "%s"
+protocol: grpc
+service_method: "testproto.UniversalPredictionService/PredictValues"
+tls:
+%s`, listener.Addr().String(), tt.tls)), 0600))
+
+			component, err := config.InitComponentFromConfig(configPath)
+			require.NoError(t, err)
+
+			resp, ok := <-component.Dispatch(context.Background(), &fibergrpc.Request{Message: payload}).Iter()
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedOK, resp.IsSuccess(), string(resp.Payload()))
+		})
+	}
+}
+
+func TestFromConfig_HTTPTLS(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	serverCert := newTestCertificate(t, "server", ca, "backend.test")
+	clientCert := newTestCertificate(t, "client", ca)
+	missingPath := filepath.Join(t.TempDir(), "missing.pem")
+
+	// the server verifies the client certificate, if any, and responds with its common name
+	serverKeyPair, err := tls.LoadX509KeyPair(serverCert.certPath, serverCert.keyPath)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverKeyPair},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS12,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		tls             string
+		expectedOK      bool
+		expectedPayload string
+		errMsg          string
+	}{
+		{
+			name:       "custom ca",
+			tls:        fmt.Sprintf("  ca_cert: %q\n  server_name: backend.test\n", ca.certPath),
+			expectedOK: true,
+		},
+		{
+			name: "mutual tls",
+			tls: fmt.Sprintf("  ca_cert: %q\n  client_cert: %q\n  client_key: %q\n  server_name: backend.test\n",
+				ca.certPath, clientCert.certPath, clientCert.keyPath),
+			expectedOK:      true,
+			expectedPayload: "client",
+		},
+		{
+			name:       "insecure skip verify",
+			tls:        "  insecure_skip_verify: true\n",
+			expectedOK: true,
+		},
+		{
+			name: "server name mismatch",
+			tls:  fmt.Sprintf("  ca_cert: %q\n", ca.certPath),
+		},
+		{
+			name: "system ca",
+			tls:  "  server_name: backend.test\n",
+		},
+		{
+			name: "missing ca_cert",
+			tls:  fmt.Sprintf("  ca_cert: %q\n", missingPath),
+			errMsg: fmt.Sprintf("failed to read TLS ca_cert [%s]: open %s: no such file or directory",
+				missingPath, missingPath),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "http_tls_proxy.yaml")
+			require.NoError(t, ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+type: PROXY
+id: proxy_name
+timeout: "2s"
+endpoint: "%s"
+tls:
+%s`, server.URL, tt.tls)), 0600))
+
+			component, err := config.InitComponentFromConfig(configPath)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			httpReq, _ := http.NewRequest(http.MethodGet, "", nil)
+			req, _ := fiberhttp.NewHTTPRequest(httpReq)
+			resp, ok := <-component.Dispatch(context.Background(), req).Iter()
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedOK, resp.IsSuccess(), string(resp.Payload()))
+			if tt.expectedOK {
+				assert.Equal(t, tt.expectedPayload, string(resp.Payload()))
+			}
+		})
+	}
+}
+
+func TestTLSConfig_TLSClientConfig_Certificates(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	clientCert := newTestCertificate(t, "client", ca)
+	missingPath := filepath.Join(t.TempDir(), "missing.pem")
+
+	tests := []struct {
+		name   string
+		cfg    config.TLSConfig
+		errMsg string
+	}{
+		{
+			name: "ok",
+			cfg:  config.TLSConfig{CACert: ca.certPath, ClientCert: clientCert.certPath, ClientKey: clientCert.keyPath},
+		},
+		{
+			name: "missing ca_cert",
+			cfg:  config.TLSConfig{CACert: missingPath},
+			errMsg: fmt.Sprintf("failed to read TLS ca_cert [%s]: open %s: no such file or directory",
+				missingPath, missingPath),
+		},
+		{
+			name:   "malformed ca_cert",
+			cfg:    config.TLSConfig{CACert: clientCert.keyPath},
+			errMsg: fmt.Sprintf("failed to parse TLS ca_cert [%s]: no PEM certificates found", clientCert.keyPath),
+		},
+		{
+			name:   "client_cert without client_key",
+			cfg:    config.TLSConfig{ClientCert: clientCert.certPath},
+			errMsg: "TLS client_cert and client_key have to be set together",
+		},
+		{
+			name: "missing client_key",
+			cfg:  config.TLSConfig{ClientCert: clientCert.certPath, ClientKey: missingPath},
+			errMsg: fmt.Sprintf("failed to load TLS client_cert [%s] and client_key [%s]: open %s: no such file or directory",
+				clientCert.certPath, missingPath, missingPath),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.TLSClientConfig()
+			if tt.errMsg == "" {
+				require.NoError(t, err)
+				assert.NotNil(t, got.RootCAs)
+				assert.Len(t, got.Certificates, 1)
+			} else {
+				assert.EqualError(t, err, tt.errMsg)
+			}
+		})
+	}
+}
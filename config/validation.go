@@ -0,0 +1,444 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/protocol"
+	"github.com/gojek/fiber/types"
+	"google.golang.org/grpc/encoding"
+)
+
+// ValidationError is a problem of the config, found by the validation, with the path of the field,
+// e.g. `routes[1].timeout`, where it was found
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors are all the problems of the config, found by the validation
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	problems := make([]string, len(e))
+	for idx, err := range e {
+		problems[idx] = "\n  - " + err.Error()
+	}
+	return fmt.Sprintf("invalid config, %d problem(s) found:%s", len(e), strings.Join(problems, ""))
+}
+
+func (e *ValidationErrors) add(path string, field string, format string, args ...interface{}) {
+	*e = append(*e, ValidationError{Field: fieldPath(path, field), Message: fmt.Sprintf(format, args...)})
+}
+
+// fieldPath joins the path of the component's config with the name of its field
+func fieldPath(path string, field string) string {
+	if path == "" || field == "" {
+		return path + field
+	}
+	return path + "." + field
+}
+
+func (c *ComponentConfig) componentID() string {
+	return c.ID
+}
+
+func (c *ComponentConfig) componentTags() map[string]string {
+	return c.Tags
+}
+
+func (c *ComponentConfig) validate(path string, errs *ValidationErrors) {
+	if c.ID == "" {
+		errs.add(path, "id", "id is required")
+	}
+}
+
+func (c *MultiRouteConfig) validate(path string, errs *ValidationErrors) {
+	c.ComponentConfig.validate(path, errs)
+	if len(c.Routes) == 0 {
+		errs.add(path, "routes", "at least one route is required")
+	}
+	if c.DefaultTimeout < 0 {
+		errs.add(path, "default_timeout", "default_timeout must be positive: [%s]", c.DefaultTimeout)
+	}
+
+	routeIDs := make(map[string]int)
+	var expectedProtocol protocol.Protocol
+	var expectedRouteID string
+	for idx, route := range c.Routes {
+		routePath := fieldPath(path, "routes["+strconv.Itoa(idx)+"]")
+		route.validate(routePath, errs)
+
+		id := routeID(route)
+		if first, exists := routeIDs[id]; exists && id != "" {
+			errs.add(routePath, "id", "duplicate route id [%s], also used by routes[%d]", id, first)
+		} else {
+			routeIDs[id] = idx
+		}
+
+		// all the routes have to serve the same protocol, since they dispatch the same requests
+		if proto, ok := routeProtocol(route); ok {
+			if expectedProtocol == "" {
+				expectedProtocol, expectedRouteID = proto, id
+			} else if proto != expectedProtocol {
+				errs.add(routePath, "protocol", "protocol [%s] doesn't match protocol [%s] of route [%s] of [%s]",
+					proto, expectedProtocol, expectedRouteID, c.ID)
+			}
+		}
+	}
+}
+
+func (c *RouterConfig) validate(path string, errs *ValidationErrors) {
+	c.MultiRouteConfig.validate(path, errs)
+	if err := types.ValidateRoutingStrategy(c.Strategy.Type); err != nil {
+		errs.add(path, "strategy.type", err.Error())
+	}
+	for _, code := range c.FailureStatusCodes {
+		if code < 0 {
+			errs.add(path, "failure_status_codes", "status code can not be negative: [%d]", code)
+		}
+	}
+	if c.Acceptance != nil {
+		if c.Type != "EAGER_ROUTER" {
+			errs.add(path, "acceptance", "acceptance is only supported by the eager router")
+		} else if _, err := types.AcceptancePredicateByName(c.Acceptance.Predicate); err != nil {
+			errs.add(path, "acceptance.predicate", err.Error())
+		}
+	}
+	if c.Hedging != nil {
+		c.Hedging.validate(path, c.Type, errs)
+	}
+	if c.MaxAttempts != 0 {
+		if c.Type != "LAZY_ROUTER" {
+			errs.add(path, "max_attempts", "max_attempts is only supported by the lazy router")
+		} else if c.MaxAttempts < 0 {
+			errs.add(path, "max_attempts", "max_attempts can not be negative: [%d]", c.MaxAttempts)
+		}
+	}
+	if c.Validation != nil {
+		c.Validation.validate(path, c, errs)
+	}
+	if proto, ok := routeProtocol(c); ok && c.TimeoutResponse != nil {
+		c.TimeoutResponse.validate(path, proto, errs)
+	}
+	if c.Baggage != nil && (c.Baggage.MaxMembers < 0 || c.Baggage.MaxBytes < 0) {
+		errs.add(path, "baggage", "max_members and max_bytes can not be negative")
+	}
+	if c.RouteTags != nil {
+		if c.Type != "LAZY_ROUTER" {
+			errs.add(path, "route_tags", "route_tags is only supported by the lazy router")
+		} else if len(c.RouteTags.Headers) == 0 {
+			errs.add(path, "route_tags.headers", "at least one header is required")
+		}
+	}
+}
+
+func (c *ValidationConfig) validate(path string, router *RouterConfig, errs *ValidationErrors) {
+	switch {
+	case c.JSONSchema == "" && c.Message == "":
+		errs.add(path, "validation", "json_schema or message is required")
+	case c.JSONSchema != "" && c.Message != "":
+		errs.add(path, "validation", "json_schema and message can not be set together")
+	}
+
+	if proto, ok := routeProtocol(router); ok {
+		if c.JSONSchema != "" && proto != protocol.HTTP {
+			errs.add(path, "validation.json_schema", "json_schema is only supported by the http routes")
+		}
+		if c.Message != "" && proto != protocol.GRPC {
+			errs.add(path, "validation.message", "message is only supported by the grpc routes")
+		}
+	}
+}
+
+// validate checks, that the code of the timeout response is the status code of the protocol, since the http
+// status codes outside of the range can't be written to the response
+func (c *TimeoutResponseConfig) validate(path string, proto protocol.Protocol, errs *ValidationErrors) {
+	if proto == protocol.GRPC && (c.Code < 0 || c.Code > 16) {
+		errs.add(path, "timeout_response.code", "code must be a grpc status code in [0, 16] range: [%d]", c.Code)
+	}
+	if proto == protocol.HTTP && (c.Code < 100 || c.Code > 599) {
+		errs.add(path, "timeout_response.code", "code must be an http status code in [100, 599] range: [%d]", c.Code)
+	}
+}
+
+func (c *HedgingConfig) validate(path string, routerType string, errs *ValidationErrors) {
+	if routerType != "LAZY_ROUTER" {
+		errs.add(path, "hedging", "hedging is only supported by the lazy router")
+		return
+	}
+	if c.Delay <= 0 {
+		errs.add(path, "hedging.delay", "delay must be positive: [%s]", c.Delay)
+	}
+	if c.Percentile < 0 || c.Percentile >= 100 {
+		errs.add(path, "hedging.percentile", "percentile must be in (0, 100) range: [%v]", c.Percentile)
+	}
+	if c.MaxHedges < 0 {
+		errs.add(path, "hedging.max_hedges", "max_hedges can not be negative: [%d]", c.MaxHedges)
+	}
+}
+
+func (c *CombinerConfig) validate(path string, errs *ValidationErrors) {
+	c.MultiRouteConfig.validate(path, errs)
+	if _, err := types.FanInByName(c.FanIn.Type); err != nil {
+		errs.add(path, "fan_in.type", err.Error())
+	}
+
+	routeIDs := make(map[string]bool, len(c.Routes))
+	for _, route := range c.Routes {
+		routeIDs[routeID(route)] = true
+	}
+	weighted := make([]string, 0, len(c.FanOut.Weights))
+	for id := range c.FanOut.Weights {
+		weighted = append(weighted, id)
+	}
+	sort.Strings(weighted)
+	for _, id := range weighted {
+		if !routeIDs[id] {
+			errs.add(path, "fan_out.weights", "weight of unknown route [%s]", id)
+		}
+	}
+	if c.FanOut.MaxConcurrency < 0 {
+		errs.add(path, "fan_out.max_concurrency", "max_concurrency can not be negative: [%d]", c.FanOut.MaxConcurrency)
+	}
+	if c.FanOut.QueueCapacity < 0 {
+		errs.add(path, "fan_out.queue_capacity", "queue_capacity can not be negative: [%d]", c.FanOut.QueueCapacity)
+	}
+	if _, ok := queueOverflowPolicies[c.FanOut.QueueOverflow]; !ok {
+		errs.add(path, "fan_out.queue_overflow",
+			"unknown queue_overflow [%s], expected block or drop", c.FanOut.QueueOverflow)
+	}
+	switch c.ResponseOrder {
+	case "", "arrival", "route":
+	default:
+		errs.add(path, "response_order", "unknown response_order [%s], expected arrival or route", c.ResponseOrder)
+	}
+}
+
+func (c *RacingRouterConfig) validate(path string, errs *ValidationErrors) {
+	c.MultiRouteConfig.validate(path, errs)
+	if c.MaxConcurrency < 0 {
+		errs.add(path, "max_concurrency", "max_concurrency can not be negative: [%d]", c.MaxConcurrency)
+	}
+}
+
+func (c *ProxyConfig) validate(path string, errs *ValidationErrors) {
+	c.ComponentConfig.validate(path, errs)
+	if c.Endpoint == "" && len(c.Endpoints) == 0 {
+		errs.add(path, "endpoint", "endpoint of the backend is required")
+	} else if c.Endpoint != "" && len(c.Endpoints) > 0 {
+		errs.add(path, "endpoints", "endpoint and endpoints can not be set together")
+	}
+	field := "endpoint"
+	if len(c.Endpoints) > 0 {
+		field = "endpoints"
+	}
+	for _, endpoint := range c.endpoints() {
+		if _, _, err := fiber.UnixSocketPath(endpoint); err != nil {
+			errs.add(path, field, err.Error())
+		}
+	}
+	switch c.LoadBalancing {
+	case "", fiber.RoundRobinLoadBalancing, fiber.LeastConnectionsLoadBalancing:
+	default:
+		errs.add(path, "load_balancing", "unknown load_balancing policy [%s], expected %s or %s",
+			c.LoadBalancing, fiber.RoundRobinLoadBalancing, fiber.LeastConnectionsLoadBalancing)
+	}
+	if c.Timeout == 0 {
+		errs.add(path, "timeout", "timeout is required, unless the router sets default_timeout")
+	} else if c.Timeout < 0 {
+		errs.add(path, "timeout", "timeout must be positive: [%s]", c.Timeout)
+	}
+	if c.MaxTimeout != 0 && c.MaxTimeout < c.Timeout {
+		errs.add(path, "max_timeout", "max_timeout can not be shorter than timeout: [%s]", c.MaxTimeout)
+	}
+	if c.ConnectTimeout < 0 {
+		errs.add(path, "connect_timeout", "connect_timeout can not be negative: [%s]", c.ConnectTimeout)
+	}
+	if c.RateLimit != nil {
+		if c.RateLimit.Rate <= 0 {
+			errs.add(path, "rate_limit.rate", "rate must be positive: [%v]", c.RateLimit.Rate)
+		}
+		if c.RateLimit.Burst < 0 || c.RateLimit.MaxWait < 0 {
+			errs.add(path, "rate_limit", "burst and max_wait can not be negative")
+		}
+		if len(c.RateLimit.Limits) > 0 && c.RateLimit.Key == nil {
+			errs.add(path, "rate_limit.limits", "limits of the keys require the key")
+		}
+		for idx, limit := range c.RateLimit.Limits {
+			if limit.Pattern == "" || limit.Rate <= 0 || limit.Burst < 0 {
+				errs.add(path, fmt.Sprintf("rate_limit.limits[%d]", idx),
+					"pattern and positive rate are required, and burst can not be negative")
+			}
+		}
+		if c.RateLimit.MaxKeys < 0 {
+			errs.add(path, "rate_limit.max_keys", "max_keys can not be negative: [%d]", c.RateLimit.MaxKeys)
+		}
+	}
+	if c.Tenants != nil {
+		if len(c.Tenants.Key.Headers) == 0 && !c.Tenants.Key.Operation {
+			errs.add(path, "tenants.key", "key of the tenants requires the headers or the operation")
+		}
+		if c.Tenants.MaxTenants < 0 {
+			errs.add(path, "tenants.max_tenants", "max_tenants can not be negative: [%d]", c.Tenants.MaxTenants)
+		}
+	}
+	if c.HealthCheck != nil && (c.HealthCheck.Interval < 0 || c.HealthCheck.Timeout < 0 ||
+		c.HealthCheck.HealthyThreshold < 0 || c.HealthCheck.UnhealthyThreshold < 0) {
+		errs.add(path, "health_check", "interval, timeout and thresholds can not be negative")
+	}
+	if c.MaxRequestSize < 0 {
+		errs.add(path, "max_request_size", "max_request_size can not be negative: [%d]", c.MaxRequestSize)
+	}
+	if c.MaxResponseSize < 0 {
+		errs.add(path, "max_response_size", "max_response_size can not be negative: [%d]", c.MaxResponseSize)
+	}
+	if c.Metrics != nil && path != "" {
+		errs.add(path, "metrics", "metrics of the proxy are only supported, when it's the root component, "+
+			"otherwise the metrics of its router are recorded")
+	}
+	if c.MaxInFlight < 0 {
+		errs.add(path, "max_in_flight", "max_in_flight can not be negative: [%d]", c.MaxInFlight)
+	}
+	if c.Keepalive != nil && (c.Keepalive.Time < 0 || c.Keepalive.Timeout < 0) {
+		errs.add(path, "keepalive", "time and timeout can not be negative")
+	}
+	if c.Backoff != nil && (c.Backoff.BaseDelay < 0 || c.Backoff.MaxDelay < 0 || c.Backoff.MinConnectTimeout < 0) {
+		errs.add(path, "backoff", "base_delay, max_delay and min_connect_timeout can not be negative")
+	}
+	if c.Backoff != nil && (c.Backoff.Jitter < 0 || c.Backoff.Jitter > 1) {
+		errs.add(path, "backoff.jitter", "jitter must be in [0, 1] range: [%v]", c.Backoff.Jitter)
+	}
+	if c.Cache != nil && c.Cache.TTL <= 0 {
+		errs.add(path, "cache.ttl", "ttl must be positive: [%s]", c.Cache.TTL)
+	}
+	if c.Cache != nil && c.Cache.StaleIfError < 0 {
+		errs.add(path, "cache.stale_if_error", "stale_if_error can not be negative: [%s]", c.Cache.StaleIfError)
+	}
+	proto, ok := proxyProtocol(c)
+	if !ok {
+		errs.add(path, "protocol", "unsupported protocol [%s], expected http or grpc", c.Protocol)
+	} else if c.TimeoutResponse != nil {
+		c.TimeoutResponse.validate(path, proto, errs)
+	}
+	if c.SharedTransport && proto != protocol.HTTP {
+		errs.add(path, "shared_transport", "shared_transport is only supported by the http backends")
+	}
+	if c.SharedTransport && c.Tenants != nil {
+		errs.add(path, "shared_transport", "shared_transport can not be set together with tenants, "+
+			"since the tenants have their own connections")
+	}
+	if (c.IdleTimeout != 0 || c.IdleGracePeriod != 0) && proto != protocol.GRPC {
+		errs.add(path, "idle_timeout", "idle_timeout is only supported by the grpc backends")
+	}
+	if c.IdleTimeout < 0 || c.IdleGracePeriod < 0 {
+		errs.add(path, "idle_timeout", "idle_timeout and idle_grace_period can not be negative")
+	}
+	if c.Transcoding != nil {
+		if proto != protocol.GRPC {
+			errs.add(path, "transcoding", "transcoding is only supported by the grpc backends")
+		}
+		if c.DescriptorSet == "" && (c.Transcoding.RequestMessage == "" || c.Transcoding.ResponseMessage == "") {
+			errs.add(path, "transcoding", "request_message and response_message are required")
+		} else if (c.Transcoding.RequestMessage == "") != (c.Transcoding.ResponseMessage == "") {
+			errs.add(path, "transcoding", "request_message and response_message have to be set together")
+		}
+		if _, err := fiber.ParseDecodeErrorPolicy(c.Transcoding.DecodeError); err != nil {
+			errs.add(path, "transcoding.decode_error", err.Error())
+		}
+		if c.Streaming {
+			errs.add(path, "transcoding", "transcoding of the streaming methods is not supported")
+		}
+	}
+	if c.DescriptorSet != "" && proto != protocol.GRPC {
+		errs.add(path, "descriptor_set", "descriptor_set is only supported by the grpc backends")
+	}
+	if c.Cache != nil && c.Cache.Canonical && proto != protocol.GRPC {
+		errs.add(path, "cache.canonical", "canonical is only supported by the grpc backends")
+	}
+	if c.Authority != "" && proto != protocol.GRPC {
+		errs.add(path, "authority", "authority is only supported by the grpc backends, use host for http")
+	}
+	if c.Host != "" && proto != protocol.HTTP {
+		errs.add(path, "host", "host is only supported by the http backends, use authority for grpc")
+	}
+	if c.HTTP2 && proto != protocol.HTTP {
+		errs.add(path, "http2", "http2 is only supported by the http backends")
+	}
+	if c.BackendNameHeader != "" && proto != protocol.HTTP {
+		errs.add(path, "backend_name_header", "backend_name_header is only supported by the http backends")
+	}
+	if c.Compressor != "" {
+		if proto != protocol.GRPC {
+			errs.add(path, "compressor", "compressor is only supported by the grpc backends")
+		}
+		if encoding.GetCompressor(c.Compressor) == nil {
+			errs.add(path, "compressor", "unknown compressor [%s]", c.Compressor)
+		}
+	}
+}
+
+func routeID(route Config) string {
+	if component, ok := route.(interface{ componentID() string }); ok {
+		return component.componentID()
+	}
+	return ""
+}
+
+func routeTags(route Config) map[string]string {
+	if component, ok := route.(interface{ componentTags() map[string]string }); ok {
+		return component.componentTags()
+	}
+	return nil
+}
+
+// routeProtocol returns the protocol, served by the route. The protocol of the multi-route component
+// is the protocol of its routes, if they all serve the same one
+func routeProtocol(route Config) (protocol.Protocol, bool) {
+	var routes Routes
+	switch typed := route.(type) {
+	case *ProxyConfig:
+		if typed.Transcoding != nil {
+			// the grpc backend with transcoding serves the http requests
+			return protocol.HTTP, true
+		}
+		return proxyProtocol(typed)
+	case *RouterConfig:
+		routes = typed.Routes
+	case *RacingRouterConfig:
+		routes = typed.Routes
+	case *CombinerConfig:
+		routes = typed.Routes
+	}
+
+	var common protocol.Protocol
+	for _, nested := range routes {
+		proto, ok := routeProtocol(nested)
+		if !ok || (common != "" && proto != common) {
+			return "", false
+		}
+		common = proto
+	}
+	return common, common != ""
+}
+
+func proxyProtocol(c *ProxyConfig) (protocol.Protocol, bool) {
+	switch {
+	case c.Protocol == "" || strings.EqualFold(string(c.Protocol), string(protocol.HTTP)):
+		return protocol.HTTP, true
+	case strings.EqualFold(string(c.Protocol), string(protocol.GRPC)):
+		return protocol.GRPC, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,33 @@
+package fiber
+
+import "fmt"
+
+// DecodeErrorPolicy defines how the components, that decode the responses of the backends (e.g. to transcode
+// or to merge them), handle the responses, that can't be decoded. The decode errors are logged with the route
+// and the size of the response (see LogDecodeError)
+type DecodeErrorPolicy string
+
+const (
+	// DecodeErrorFail treats the response, that can't be decoded, as the failure of its route, i.e. it's replaced
+	// with ErrDecodeFailed, so the router falls back to the next route, or it's skipped, like the failed responses,
+	// when the responses are merged. It's the default policy
+	DecodeErrorFail DecodeErrorPolicy = "fail"
+	// DecodeErrorPassThrough sends back the raw response, that can't be decoded, as it's been received
+	DecodeErrorPassThrough DecodeErrorPolicy = "pass_through"
+	// DecodeErrorRespond sends back ErrDecodeFailed as the response of the component, e.g. instead of the merged
+	// response, if any of the responses can't be decoded
+	DecodeErrorRespond DecodeErrorPolicy = "error"
+)
+
+// ParseDecodeErrorPolicy returns the DecodeErrorPolicy with the given name, or DecodeErrorFail, if it's empty
+func ParseDecodeErrorPolicy(name string) (DecodeErrorPolicy, error) {
+	switch policy := DecodeErrorPolicy(name); policy {
+	case "":
+		return DecodeErrorFail, nil
+	case DecodeErrorFail, DecodeErrorPassThrough, DecodeErrorRespond:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown decode error policy [%s], expected %s, %s or %s",
+			name, DecodeErrorFail, DecodeErrorPassThrough, DecodeErrorRespond)
+	}
+}
@@ -0,0 +1,48 @@
+package fiber_test
+
+import (
+	"testing"
+
+	"github.com/gojek/fiber"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDecodeErrorPolicy(t *testing.T) {
+	suite := map[string]struct {
+		name        string
+		expected    fiber.DecodeErrorPolicy
+		expectedErr string
+	}{
+		"default": {
+			expected: fiber.DecodeErrorFail,
+		},
+		"fail": {
+			name:     "fail",
+			expected: fiber.DecodeErrorFail,
+		},
+		"pass through": {
+			name:     "pass_through",
+			expected: fiber.DecodeErrorPassThrough,
+		},
+		"error": {
+			name:     "error",
+			expected: fiber.DecodeErrorRespond,
+		},
+		"unknown": {
+			name:        "ignore",
+			expectedErr: "unknown decode error policy [ignore], expected fail, pass_through or error",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			policy, err := fiber.ParseDecodeErrorPolicy(tt.name)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, policy)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
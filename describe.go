@@ -0,0 +1,60 @@
+package fiber
+
+import (
+	"sort"
+
+	"github.com/gojek/fiber/protocol"
+)
+
+// ComponentInfo describes a component of the fiber network and its routes, at the time it's described.
+// It's marshaled into JSON, i.e. to be exposed by an admin endpoint
+type ComponentInfo struct {
+	ID   string        `json:"id"`
+	Kind ComponentKind `json:"kind,omitempty"`
+	// Endpoint is the endpoint of the backend of the proxy
+	Endpoint string            `json:"endpoint,omitempty"`
+	Protocol protocol.Protocol `json:"protocol,omitempty"`
+	// Endpoints are the endpoints of the backend of the proxy, if the requests are spread across them
+	// (see LoadBalancingDispatcher)
+	Endpoints []string `json:"endpoints,omitempty"`
+	// Timeout is the timeout of the requests to the backend, e.g. "1s"
+	Timeout string `json:"timeout,omitempty"`
+	// Healthy is the health of the backend, if it's health-checked (see HealthCheckedComponent)
+	Healthy *bool `json:"healthy,omitempty"`
+	// InFlight is the number of the requests in flight, if the route limits it (see ConcurrencyLimitedComponent)
+	InFlight *int `json:"in_flight,omitempty"`
+	// CircuitState is the state of the circuit breaker of the backend, if it has one
+	CircuitState string `json:"circuit_state,omitempty"`
+	// Routes are the routes of the multi-route component, sorted by their IDs
+	Routes []ComponentInfo `json:"routes,omitempty"`
+}
+
+// Describer is implemented by the components and the dispatchers, that add their details
+// to the ComponentInfo. Describe is safe to call concurrently with Dispatch
+type Describer interface {
+	Describe(info *ComponentInfo)
+}
+
+// DescribeComponent describes the component, and, recursively, its routes
+func DescribeComponent(component Component) ComponentInfo {
+	info := ComponentInfo{ID: component.ID(), Kind: component.Kind()}
+	describeIfDescriber(component, &info)
+	return info
+}
+
+// describeIfDescriber adds the details of the component or the dispatcher to the info, if it's a Describer
+func describeIfDescriber(describable interface{}, info *ComponentInfo) {
+	if describer, ok := describable.(Describer); ok {
+		describer.Describe(info)
+	}
+}
+
+// describeRoutes describes the routes of a multi-route component
+func describeRoutes(routes map[string]Component) []ComponentInfo {
+	infos := make([]ComponentInfo, 0, len(routes))
+	for _, route := range routes {
+		infos = append(infos, DescribeComponent(route))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
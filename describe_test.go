@@ -0,0 +1,89 @@
+package fiber_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberHTTP "github.com/gojek/fiber/http"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDescribedProxy(t *testing.T, id string, endpoint string) fiber.Component {
+	dispatcher, err := fiberHTTP.NewDispatcher(&http.Client{Timeout: time.Second})
+	require.NoError(t, err)
+	breaker, err := fiber.NewCircuitBreakingDispatcher(id, dispatcher, fiber.CircuitBreakerPolicy{})
+	require.NoError(t, err)
+	caller, err := fiber.NewCaller(id, breaker)
+	require.NoError(t, err)
+	return fiber.NewProxy(fiber.NewBackend(id, endpoint), caller)
+}
+
+func TestDescribeComponent(t *testing.T) {
+	checker, err := fiber.NewHealthChecker("route-b", &switchableProbe{}, fiber.HealthCheckPolicy{})
+	require.NoError(t, err)
+
+	router := fiber.NewLazyRouter("lazy-router")
+	router.SetRoutes(map[string]fiber.Component{
+		"route-a":  newDescribedProxy(t, "route-a", "http://route-a"),
+		"route-b":  fiber.NewHealthCheckedComponent(newDescribedProxy(t, "route-b", "http://route-b"), checker),
+		"combiner": fiber.NewRacingRouter("combiner"),
+	})
+
+	data, err := json.Marshal(fiber.DescribeComponent(router))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "lazy-router",
+		"kind": "MultiRouteComponent",
+		"routes": [
+			{"id": "combiner", "kind": "Combiner"},
+			{
+				"id": "route-a",
+				"kind": "Caller",
+				"endpoint": "http://route-a",
+				"protocol": "HTTP",
+				"timeout": "1s",
+				"circuit_state": "closed"
+			},
+			{
+				"id": "route-b",
+				"kind": "Caller",
+				"endpoint": "http://route-b",
+				"protocol": "HTTP",
+				"timeout": "1s",
+				"healthy": true,
+				"circuit_state": "closed"
+			}
+		]
+	}`, string(data))
+}
+
+func TestDescribeComponent_ConcurrentDispatch(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": newSlowComponent("route-a", time.Millisecond),
+		"route-b": newSlowComponent("route-b", time.Millisecond),
+	}
+	router := fiber.NewEagerRouter("eager-router")
+	router.SetRoutes(routes)
+	router.SetStrategy(&orderedRoutingStrategy{order: []string{"route-a", "route-b"}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-router.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Len(t, fiber.DescribeComponent(router).Routes, len(routes))
+		}()
+	}
+	wg.Wait()
+}
@@ -1,6 +1,34 @@
 package fiber
 
-//
+import "context"
+
+// Dispatcher is a transport-specific implementation of the request to a backend.
+// The context carries the deadline and the cancellation of the incoming request.
+// It's implemented by http.Dispatcher and grpc.Dispatcher, and any other transport
+// (e.g. the in-process backend, or the fake of the tests) can be used by the Caller
+// of the route instead (see also config.LoadOptions)
 type Dispatcher interface {
+	Do(ctx context.Context, request Request) Response
+}
+
+// DispatcherFunc is an adapter to use the function as a Dispatcher
+type DispatcherFunc func(ctx context.Context, request Request) Response
+
+// Do calls the function
+func (f DispatcherFunc) Do(ctx context.Context, request Request) Response {
+	return f(ctx, request)
+}
+
+// ContextlessDispatcher is the Dispatcher of the earlier versions of fiber, which Do doesn't receive
+// the context of the request. It can still be used by the Caller, once it's adapted with FromContextlessDispatcher
+type ContextlessDispatcher interface {
 	Do(request Request) Response
 }
+
+// FromContextlessDispatcher adapts the ContextlessDispatcher to the Dispatcher. The context of the request
+// is not passed to it, so its requests are neither cancelled, nor limited by the deadline of the incoming request
+func FromContextlessDispatcher(dispatcher ContextlessDispatcher) Dispatcher {
+	return DispatcherFunc(func(_ context.Context, request Request) Response {
+		return dispatcher.Do(request)
+	})
+}
@@ -2,6 +2,8 @@ package fiber
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/gojek/fiber/errors"
 	"github.com/gojek/fiber/util"
@@ -14,9 +16,37 @@ import (
 // (defined by the routing strategy) or switches back to one of fallback options.
 //
 // In a sense, EagerRouter is a Combiner, that aggregates responses from its all routes
-// into a single response by selecting this response based on a provided RoutingStrategy
+// into a single response by selecting this response based on a provided RoutingStrategy.
+// Once the response is selected, the dispatches of the routes, that are still in flight, are cancelled.
+// The streaming routes are not supported: since their frames can't be selected as a single response,
+// they fail on their first frame
 type EagerRouter struct {
 	*Combiner
+
+	logger     Logger
+	access     AccessLogger
+	failures   failureStatuses
+	preRouting PreRoutingHook
+	override   *RouteOverride
+	acceptance *acceptance
+	timeout    *TimeoutResponse
+}
+
+// AcceptancePredicate returns true, if the response of the route, that hasn't failed, is good enough to be
+// selected by the router (e.g. its predictions are not empty). It's called concurrently for every incoming request,
+// so the implementations must be safe for concurrent use
+type AcceptancePredicate func(req Request, resp Response) bool
+
+// acceptance is the AcceptancePredicate of the EagerRouter and its handling of the requests, that none of
+// the routes has responded acceptably
+type acceptance struct {
+	predicate AcceptancePredicate
+	strict    bool
+}
+
+// accepts returns true, if the response satisfies the predicate, or there is no predicate
+func (a *acceptance) accepts(req Request, resp Response) bool {
+	return a == nil || a.predicate(req, resp)
 }
 
 // NewEagerRouter initializes new EagerRouter
@@ -33,10 +63,111 @@ func NewEagerRouter(id string) *EagerRouter {
 func (router *EagerRouter) SetStrategy(strategy RoutingStrategy) {
 	router.WithFanIn(&eagerRouterFanIn{
 		BaseFanIn{},
-		&baseRoutingStrategy{RoutingStrategy: strategy},
+		newBaseRoutingStrategy(strategy),
 		router})
 }
 
+// SetPreRoutingHook sets the hook, that enriches each request, before it's dispatched by the routes
+// and the routing strategy selects the response
+func (router *EagerRouter) SetPreRoutingHook(hook PreRoutingHook) {
+	router.preRouting = hook
+}
+
+// SetLogger sets the logger of the routing decisions of this router. The router doesn't log
+// anything, and doesn't set the correlation id on the requests, unless the logger or the access logger is set
+func (router *EagerRouter) SetLogger(logger Logger) {
+	router.logger = logger
+}
+
+// SetAccessLogger sets the sink of the access log records of this router, one per dispatch, that are
+// written, once the response of the router is chosen, including the error responses. The router sets
+// the correlation id on the requests, if either of the loggers is set
+func (router *EagerRouter) SetAccessLogger(logger AccessLogger) {
+	router.access = logger
+}
+
+// SetFailureStatusCodes sets the status codes of the responses, on which the router falls back
+// to the next route, even if they are successful. The router always falls back on any non-successful
+// response, except the grpc responses with DefaultGRPCTerminalStatusCodes, which are selected as they are,
+// unless their codes are set too
+func (router *EagerRouter) SetFailureStatusCodes(codes ...int) {
+	router.failures = newFailureStatuses(codes)
+}
+
+// SetAcceptancePredicate sets the predicate of the responses, that the router selects. The responses, that
+// haven't failed (see SetFailureStatusCodes), but don't satisfy it, are treated as the soft failures: the router falls back to the next route,
+// but if none of the routes has responded acceptably, the last response, that is not accepted, is selected.
+// If strict is set, ErrServiceUnavailable is sent back instead. The predicate is removed, if it's nil
+func (router *EagerRouter) SetAcceptancePredicate(predicate AcceptancePredicate, strict bool) {
+	router.acceptance = nil
+	if predicate != nil {
+		router.acceptance = &acceptance{predicate: predicate, strict: strict}
+	}
+}
+
+// SetTimeoutResponse sets the response, that is sent back instead of ErrRequestTimeout, when the request
+// times out, before any of the routes has responded. ErrRequestTimeout is sent back, if it's nil (default)
+func (router *EagerRouter) SetTimeoutResponse(timeout *TimeoutResponse) {
+	router.timeout = timeout
+}
+
+// SetRouteOverride lets the requests pin the route, whose response is selected by this router, with
+// the header, bypassing the routing strategy. The request is still dispatched by all the routes.
+// The override is disabled, if it's nil (default)
+func (router *EagerRouter) SetRouteOverride(override *RouteOverride) {
+	router.override = override
+}
+
+// Dispatch dispatches the request by all the routes of the router and selects the response
+// according to the routing strategy (see eagerRouterFanIn). The request is enriched by
+// the PreRoutingHook first, if it's set
+func (router *EagerRouter) Dispatch(ctx context.Context, req Request) ResponseQueue {
+	return router.intercept(ctx, req, router.dispatch)
+}
+
+func (router *EagerRouter) dispatch(ctx context.Context, req Request) ResponseQueue {
+	if fanIn, ok := router.fanIn.(*eagerRouterFanIn); ok {
+		ctx = fanIn.strategy.withName(ctx)
+	}
+
+	log, ctx := newDispatchLogger(ctx, router.logger, router.access, router.ID(), req)
+	if log != nil {
+		// the request (and the metadata of the grpc request) is copied, so the correlation id
+		// is not set on the caller's request
+		if copyReq, err := req.Clone(); err == nil {
+			req = copyReq
+		}
+		log.tag(req)
+		for id := range router.GetRoutes() {
+			log.log(ctx, RouteAttemptStartedEvent, id, nil, 0)
+		}
+	}
+	ctx = withDispatchLogger(ctx, log)
+
+	ctx, hookResp := preRoute(ctx, router.preRouting, req)
+	if hookResp != nil {
+		log.logResponse(ctx, hookResp)
+		return NewResponseQueueFromResponses(hookResp)
+	}
+	// the request is already intercepted, since the router shares the interceptors with its combiner
+	return router.Combiner.dispatch(ctx, req)
+}
+
+// Explain runs the routing strategy for the request, after it's enriched by the PreRoutingHook, and returns
+// the routes in the order of the priority of their responses, without dispatching the request (see LazyRouter.Explain)
+func (router *EagerRouter) Explain(ctx context.Context, req Request) (RoutingDecision, error) {
+	ctx, err := runPreRoutingHook(ctx, router.preRouting, req)
+	if err != nil {
+		return RoutingDecision{RouterID: router.ID()}, err
+	}
+
+	var strategy *baseRoutingStrategy
+	if fanIn, ok := router.fanIn.(*eagerRouterFanIn); ok {
+		strategy = fanIn.strategy
+	}
+	return strategy.explain(ctx, router.ID(), req, router.GetRoutes(), router.override, RoutingDecision{})
+}
+
 // EagerRouter's specific FanIn implementation
 // It receives the channel with responses from all possible router routes and asynchronously
 // retrieves information about primary route and the order of fallbacks to be used.
@@ -49,7 +180,9 @@ func (router *EagerRouter) SetStrategy(strategy RoutingStrategy) {
 // response from fallback routes will be sent back.
 
 // If primary route AND all fallback routes responded with not non-successful responses, the error
-// response will be created and sent back.
+// response will be created and sent back. It carries the failures of all the routes (see ErrorResponse.RouteErrors).
+// It's ErrRequestRejected, if all the routes have rejected the request, or ErrRequestTimeout (or the timeout
+// response of the router, see SetTimeoutResponse), if none of them has responded in time.
 type eagerRouterFanIn struct {
 	BaseFanIn
 	strategy *baseRoutingStrategy
@@ -63,7 +196,9 @@ func (fanIn *eagerRouterFanIn) Aggregate(
 ) Response {
 	// use routing strategy to fetch primary route and fallbacks
 	// publish the ordered routes into a channel
-	routesOrderCh, errCh := fanIn.strategy.getRoutesOrder(ctx, req, fanIn.router.GetRoutes())
+	routesOrderCh, errCh := fanIn.strategy.getRoutesOrder(ctx, req, fanIn.router.GetRoutes(), fanIn.router.override)
+	log := ctxDispatchLogger(ctx)
+	start := time.Now()
 
 	out := make(chan Response, 1)
 	go func() {
@@ -83,24 +218,43 @@ func (fanIn *eagerRouterFanIn) Aggregate(
 			responseCh = queue.Iter()
 
 			masterResponse Response
+
+			// the last successful response, that is not accepted (see SetAcceptancePredicate)
+			unaccepted Response
 		)
 
 		for masterResponse == nil {
 			select {
 			case resp, ok := <-responseCh:
 				if ok {
-					responses[resp.BackendName()] = resp
+					routeID := RouteID(resp)
+					if isStreamFrame(resp) {
+						// the frames of a stream can't be selected as the single response, so the streaming
+						// route fails on its first frame, and the rest of its frames are ignored
+						if _, rejected := responses[routeID]; rejected {
+							break
+						}
+						resp = NewErrorResponse(errors.ErrRequestFailed(req.Protocol(),
+							fmt.Errorf("eager router doesn't support the streaming route: %s", routeID)))
+						resp = resp.WithBackendName(routeID)
+					}
+					responses[routeID] = resp
+					log.log(ctx, RouteAttemptFinishedEvent, routeID, resp, time.Since(start))
 				} else {
 					responseCh = nil
 				}
 			case orderedRoutes, ok := <-routesOrderCh:
 				if ok {
 					routes = orderedRoutes
+					if len(routes) > 0 {
+						log.log(ctx, RouteSelectedEvent, routes[0].ID(), nil, time.Since(start))
+					}
 				} else {
 					routesOrderCh = nil
 				}
 			case err, ok := <-errCh:
 				if ok {
+					logStrategyPanic(ctx, log, err)
 					masterResponse = NewErrorResponse(errors.NewFiberError(req.Protocol(), err))
 				} else {
 					errCh = nil
@@ -118,27 +272,51 @@ func (fanIn *eagerRouterFanIn) Aggregate(
 			if routes != nil {
 				for ; currentRouteIdx < len(routes); currentRouteIdx++ {
 					if currMasterResponse, exist := responses[routes[currentRouteIdx].ID()]; exist {
-						if currMasterResponse.IsSuccess() {
-							// preferred response found
-							masterResponse = currMasterResponse
-							break
+						if !fanIn.router.failures.isFailure(req.Protocol(), currMasterResponse) {
+							if fanIn.router.acceptance.accepts(req, currMasterResponse) {
+								// preferred response found
+								masterResponse = currMasterResponse
+								break
+							}
+							unaccepted = currMasterResponse
 						}
 					} else if responseCh != nil {
 						// response from preferred route is not ready; continue listening for new responseQueue
 						break
 					}
+					if currentRouteIdx+1 < len(routes) {
+						log.log(ctx, FallbackTriggeredEvent, routes[currentRouteIdx+1].ID(), nil, 0)
+					}
 				}
 
 				// all expected routes tried, no OK response received from either of them
 				if currentRouteIdx >= len(routes) {
 					if len(routes) == 0 {
 						masterResponse = NewErrorResponse(errors.ErrRouterStrategyReturnedEmptyRoutes(req.Protocol()))
+					} else if unaccepted != nil && !fanIn.router.acceptance.strict {
+						masterResponse = unaccepted
 					} else {
-						masterResponse = NewErrorResponse(errors.ErrServiceUnavailable(req.Protocol()))
+						routeErrors := make([]errors.RouteError, len(routes))
+						for idx, route := range routes {
+							resp := responses[route.ID()]
+							routeErrors[idx] = newRouteError(req.Protocol(), route.ID(), resp)
+							if resp != nil && !fanIn.router.failures.isFailure(req.Protocol(), resp) {
+								// the response hasn't failed, but it's not accepted by the predicate
+								routeErrors[idx].Message = "fiber: response is not accepted"
+							}
+						}
+						masterResponse = errAllRoutesFailed(req.Protocol(), routeErrors)
+						if len(responses) == 0 && ctx.Err() == context.DeadlineExceeded {
+							// none of the routes has responded in time
+							timeout := fanIn.router.timeout.response(req.Protocol())
+							timeout.routeErrors = routeErrors
+							masterResponse = timeout
+						}
 					}
 				}
 			}
 		}
+		log.logResponse(ctx, masterResponse)
 		out <- masterResponse
 	}()
 
@@ -2,8 +2,10 @@ package fiber_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
 	"github.com/gojek/fiber/protocol"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type eagerRouterTestCase struct {
@@ -270,3 +273,72 @@ func TestEagerRouter_Dispatch(t *testing.T) {
 		})
 	}
 }
+
+// hasPredictions accepts the responses with the non-empty predictions
+func hasPredictions(_ fiber.Request, resp fiber.Response) bool {
+	var payload struct {
+		Predictions []float64 `json:"predictions"`
+	}
+	return json.Unmarshal(resp.Payload(), &payload) == nil && len(payload.Predictions) > 0
+}
+
+func TestEagerRouter_AcceptancePredicate(t *testing.T) {
+	failedRoute2 := func() fiber.Response {
+		return testUtilsHttp.MockResp(http.StatusInternalServerError, "route2 failed", nil, nil)
+	}
+
+	suite := map[string]struct {
+		route2         func() fiber.Response
+		strict         bool
+		expectedStatus int
+		expectedBody   string
+		expectedRoute  string
+	}{
+		"fallback response is accepted": {
+			route2: func() fiber.Response {
+				return testUtilsHttp.MockResp(http.StatusOK, `{"predictions":[0.5]}`, nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"predictions":[0.5]}`,
+			expectedRoute:  "route2",
+		},
+		"none of the responses is accepted": {
+			route2:         failedRoute2,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"predictions":[]}`,
+			expectedRoute:  "route1",
+		},
+		"none of the responses is accepted by the strict router": {
+			route2:         failedRoute2,
+			strict:         true,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   fiberErrors.ErrServiceUnavailable(protocol.HTTP).Message,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			router := testutils.NewRouterBuilder("eager-router").
+				// the route1 responds with the empty predictions
+				WithRoute("route1", testUtilsHttp.MockResp(http.StatusOK, `{"predictions":[]}`, nil, nil)).
+				WithRoute("route2", tt.route2()).
+				WithOrder([]string{"route1", "route2"}, 0, nil).
+				Build()
+			router.SetAcceptancePredicate(hasPredictions, tt.strict)
+
+			responses := testutils.Dispatch(context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+			require.Len(t, responses, 1)
+			assert.Equal(t, tt.expectedStatus, responses[0].StatusCode())
+			assert.Contains(t, string(responses[0].Payload()), tt.expectedBody)
+			if tt.expectedRoute != "" {
+				assert.Equal(t, tt.expectedRoute, responses[0].BackendName())
+			}
+			if tt.strict {
+				assert.Equal(t, []fiberErrors.RouteError{
+					{RouteID: "route1", Code: http.StatusOK, Message: "fiber: response is not accepted"},
+					{RouteID: "route2", Code: http.StatusInternalServerError, Message: "route2 failed"},
+				}, responses[0].(*fiber.ErrorResponse).RouteErrors())
+			}
+		})
+	}
+}
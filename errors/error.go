@@ -2,6 +2,7 @@ package errors
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -13,6 +14,27 @@ import (
 type FiberError struct {
 	Code    int    `json:"code"`
 	Message string `json:"error"`
+	// Kind is the class of the failure, and Protocol is the protocol of the failed request. They are
+	// not a part of the payload of the error response, but are known to the callers of the components
+	// (see fiber.ErrorResponse.Err)
+	Kind     Kind              `json:"-"`
+	Protocol protocol.Protocol `json:"-"`
+}
+
+// RouteError captures the failure of a single route, that has been tried by a router,
+// before none of its routes has responded successfully
+type RouteError struct {
+	RouteID string `json:"route_id"`
+	Code    int    `json:"code"`
+	Message string `json:"error"`
+	// Cause is the original error of the backend of the route, e.g. the grpc status with its details,
+	// if it's known (see fiber.ErrorResponse.Cause)
+	Cause error `json:"-"`
+}
+
+// Error returns the description of the failure of the route
+func (err RouteError) Error() string {
+	return fmt.Sprintf("route %s failed with status %d: %s", err.RouteID, err.Code, err.Message)
 }
 
 // Error is a getter for the error message in a FiberError object
@@ -20,16 +42,23 @@ func (err FiberError) Error() string {
 	return err.Message
 }
 
+// Is reports, if the error is of the given Kind, so the FiberError can be matched with errors.Is
+func (err FiberError) Is(target error) bool {
+	kind, ok := target.(Kind)
+	return ok && kind != "" && err.Kind == kind
+}
+
 // ToJSON returns the FiberError object as a Json encoded byte array
 func (err *FiberError) ToJSON() ([]byte, error) {
 	return json.MarshalIndent(err, "", "  ")
 }
 
 // NewFiberError returns an error of type FiberError from the input error object.
-// If the input error is already of the required type, it is returned as is.
+// If the input error is already of the required type (or wraps one), it is returned as is.
 // If not, a generic request failed error is created from the given error.
 func NewFiberError(protocol protocol.Protocol, err error) *FiberError {
-	if fiberError, ok := err.(FiberError); ok {
+	var fiberError FiberError
+	if errors.As(err, &fiberError) {
 		return &fiberError
 	}
 	return ErrRequestFailed(protocol, err)
@@ -44,8 +73,10 @@ var (
 			statusCode = int(codes.DeadlineExceeded)
 		}
 		return &FiberError{
-			Code:    statusCode,
-			Message: "fiber: routing strategy failed to respond within given timeout",
+			Code:     statusCode,
+			Message:  "fiber: routing strategy failed to respond within given timeout",
+			Kind:     ErrTimeout,
+			Protocol: protocol,
 		}
 	}
 
@@ -57,8 +88,10 @@ var (
 			statusCode = int(codes.NotFound)
 		}
 		return &FiberError{
-			Code:    statusCode,
-			Message: "fiber: routing strategy returned empty routes list",
+			Code:     statusCode,
+			Message:  "fiber: routing strategy returned empty routes list",
+			Kind:     ErrNoRoutes,
+			Protocol: protocol,
 		}
 	}
 
@@ -70,8 +103,25 @@ var (
 			statusCode = int(codes.Unavailable)
 		}
 		return &FiberError{
-			Code:    statusCode,
-			Message: "fiber: no responses received",
+			Code:     statusCode,
+			Message:  "fiber: no responses received",
+			Kind:     ErrAllRoutesFailed,
+			Protocol: protocol,
+		}
+	}
+
+	// ErrComponentClosed is a FiberError that's returned when the request is dispatched
+	// by the component, that has been closed
+	ErrComponentClosed = func(protocol protocol.Protocol) *FiberError {
+		statusCode := http.StatusServiceUnavailable
+		if protocol == "GRPC" {
+			statusCode = int(codes.Unavailable)
+		}
+		return &FiberError{
+			Code:     statusCode,
+			Message:  "fiber: component is closed and doesn't accept new requests",
+			Kind:     ErrUnavailable,
+			Protocol: protocol,
 		}
 	}
 
@@ -83,8 +133,81 @@ var (
 			statusCode = int(codes.DeadlineExceeded)
 		}
 		return &FiberError{
-			Code:    statusCode,
-			Message: "fiber: failed to receive a response within configured timeout",
+			Code:     statusCode,
+			Message:  "fiber: failed to receive a response within configured timeout",
+			Kind:     ErrTimeout,
+			Protocol: protocol,
+		}
+	}
+
+	// ErrComponentSaturated is a FiberError that's returned, when the request is shed by the component,
+	// that has too many requests in flight already (or all of its routes have)
+	ErrComponentSaturated = func(protocol protocol.Protocol) *FiberError {
+		statusCode := http.StatusServiceUnavailable
+		if protocol == "GRPC" {
+			statusCode = int(codes.Unavailable)
+		}
+		return &FiberError{
+			Code:     statusCode,
+			Message:  "fiber: too many requests in flight",
+			Kind:     ErrUnavailable,
+			Protocol: protocol,
+		}
+	}
+
+	// ErrResponsesDropped is a FiberError that's sent instead of the responses, that are dropped by
+	// the bounded response queue, since it's full
+	ErrResponsesDropped = func(protocol protocol.Protocol) *FiberError {
+		statusCode := http.StatusServiceUnavailable
+		if protocol == "GRPC" {
+			statusCode = int(codes.ResourceExhausted)
+		}
+		return &FiberError{
+			Code:     statusCode,
+			Message:  "fiber: responses are dropped, since the response queue is full",
+			Kind:     ErrUnavailable,
+			Protocol: protocol,
+		}
+	}
+
+	// ErrCircuitOpen is a FiberError that's returned, when the request is not dispatched to the backend,
+	// since the circuit breaker of the backend is open
+	ErrCircuitOpen = func(protocol protocol.Protocol) *FiberError {
+		statusCode := http.StatusServiceUnavailable
+		if protocol == "GRPC" {
+			statusCode = int(codes.Unavailable)
+		}
+		return &FiberError{
+			Code:     statusCode,
+			Message:  "fiber: circuit breaker is open",
+			Kind:     ErrUnavailable,
+			Protocol: protocol,
+		}
+	}
+
+	// ErrRequestRejected is a FiberError that's returned, when all the routes have rejected the request
+	// as invalid. The status code is the one of the rejection, e.g. 400 or INVALID_ARGUMENT
+	ErrRequestRejected = func(protocol protocol.Protocol, statusCode int) *FiberError {
+		return &FiberError{
+			Code:     statusCode,
+			Message:  "fiber: request is rejected by all the routes",
+			Kind:     ErrBackendRejected,
+			Protocol: protocol,
+		}
+	}
+
+	// ErrConnectTimeout is a FiberError that's returned when the connection to the backend
+	// can't be established within the configured connect timeout
+	ErrConnectTimeout = func(protocol protocol.Protocol) *FiberError {
+		statusCode := http.StatusServiceUnavailable
+		if protocol == "GRPC" {
+			statusCode = int(codes.Unavailable)
+		}
+		return &FiberError{
+			Code:     statusCode,
+			Message:  "fiber: failed to connect to the backend within configured timeout",
+			Kind:     ErrUnavailable,
+			Protocol: protocol,
 		}
 	}
 
@@ -96,8 +219,55 @@ var (
 			statusCode = int(codes.Internal)
 		}
 		return &FiberError{
-			Code:    statusCode,
-			Message: fmt.Sprintf("fiber: failed to read incoming request: %s", err.Error()),
+			Code:     statusCode,
+			Message:  fmt.Sprintf("fiber: failed to read incoming request: %s", err.Error()),
+			Kind:     ErrInternal,
+			Protocol: protocol,
+		}
+	}
+
+	// ErrRequestTooLarge is a FiberError that's returned when the body of the incoming request
+	// exceeds the configured limit
+	ErrRequestTooLarge = func(protocol protocol.Protocol, limit int64) *FiberError {
+		statusCode := http.StatusRequestEntityTooLarge
+		if protocol == "GRPC" {
+			statusCode = int(codes.ResourceExhausted)
+		}
+		return &FiberError{
+			Code:     statusCode,
+			Message:  fmt.Sprintf("fiber: request body exceeds the limit of %d bytes", limit),
+			Kind:     ErrLimitExceeded,
+			Protocol: protocol,
+		}
+	}
+
+	// ErrResponseTooLarge is a FiberError that's returned when the body of the response of the backend
+	// exceeds the configured limit
+	ErrResponseTooLarge = func(protocol protocol.Protocol, limit int64) *FiberError {
+		statusCode := http.StatusBadGateway
+		if protocol == "GRPC" {
+			statusCode = int(codes.ResourceExhausted)
+		}
+		return &FiberError{
+			Code:     statusCode,
+			Message:  fmt.Sprintf("fiber: response body exceeds the limit of %d bytes", limit),
+			Kind:     ErrLimitExceeded,
+			Protocol: protocol,
+		}
+	}
+
+	// ErrDecodeFailed is a FiberError that's returned when the response of the backend can't be decoded,
+	// e.g. it's not a valid message of the expected type
+	ErrDecodeFailed = func(protocol protocol.Protocol, err error) *FiberError {
+		statusCode := http.StatusBadGateway
+		if protocol == "GRPC" {
+			statusCode = int(codes.Internal)
+		}
+		return &FiberError{
+			Code:     statusCode,
+			Message:  fmt.Sprintf("fiber: response can not be decoded: %s", err.Error()),
+			Kind:     ErrInvalidResponse,
+			Protocol: protocol,
 		}
 	}
 
@@ -109,8 +279,25 @@ var (
 			statusCode = int(codes.Internal)
 		}
 		return &FiberError{
-			Code:    statusCode,
-			Message: fmt.Sprintf("fiber: request cannot be completed: %s", err.Error()),
+			Code:     statusCode,
+			Message:  fmt.Sprintf("fiber: request cannot be completed: %s", err.Error()),
+			Kind:     ErrInternal,
+			Protocol: protocol,
+		}
+	}
+
+	// ErrRateLimited is a FiberError that's returned, when the request is not dispatched to the backend,
+	// since it exceeds the rate limit of the backend
+	ErrRateLimited = func(protocol protocol.Protocol) *FiberError {
+		statusCode := http.StatusTooManyRequests
+		if protocol == "GRPC" {
+			statusCode = int(codes.ResourceExhausted)
+		}
+		return &FiberError{
+			Code:     statusCode,
+			Message:  "fiber: rate limit of the backend is exceeded",
+			Kind:     ErrUnavailable,
+			Protocol: protocol,
 		}
 	}
 
@@ -120,8 +307,10 @@ var (
 			statusCode = int(codes.InvalidArgument)
 		}
 		return &FiberError{
-			Code:    statusCode,
-			Message: fmt.Sprintf("fiber: %s", err.Error()),
+			Code:     statusCode,
+			Message:  fmt.Sprintf("fiber: %s", err.Error()),
+			Kind:     ErrInvalidRequest,
+			Protocol: protocol,
 		}
 	}
 )
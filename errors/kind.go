@@ -0,0 +1,36 @@
+package errors
+
+// Kind is the stable code of the class of the failure, so the callers can handle the failures of the same
+// class alike, regardless of the protocol, the status code and the message of the FiberError. The FiberError
+// matches its kind with errors.Is, e.g. errors.Is(resp.Err(), ErrTimeout)
+type Kind string
+
+// Error returns the code of the kind
+func (kind Kind) Error() string {
+	return string(kind)
+}
+
+const (
+	// ErrTimeout is the kind of the failures of the routes or the routing strategy to respond within the timeout
+	ErrTimeout Kind = "TIMEOUT"
+	// ErrAllRoutesFailed is the kind of the failures of the routers, when none of the routes has responded
+	// successfully (see fiber.ErrorResponse.RouteErrors)
+	ErrAllRoutesFailed Kind = "ALL_ROUTES_FAILED"
+	// ErrNoRoutes is the kind of the failures of the routers, when the routing strategy selects no routes
+	ErrNoRoutes Kind = "NO_ROUTES"
+	// ErrBackendRejected is the kind of the failures of the routers, when all the routes have rejected
+	// the request as invalid (e.g. with 4xx status codes), so it would fail on any other route too
+	ErrBackendRejected Kind = "BACKEND_REJECTED"
+	// ErrUnavailable is the kind of the failures of the components, that don't accept the request at the moment,
+	// e.g. the closed or saturated component, the open circuit breaker or the unreachable backend
+	ErrUnavailable Kind = "UNAVAILABLE"
+	// ErrInvalidRequest is the kind of the failures of the invalid requests, e.g. the ones without the shard key
+	ErrInvalidRequest Kind = "INVALID_REQUEST"
+	// ErrLimitExceeded is the kind of the failures of the requests and the responses, that exceed the size limits
+	ErrLimitExceeded Kind = "LIMIT_EXCEEDED"
+	// ErrInvalidResponse is the kind of the failures of the responses of the backends, that can't be decoded,
+	// e.g. to be transcoded or merged (see fiber.DecodeErrorPolicy)
+	ErrInvalidResponse Kind = "INVALID_RESPONSE"
+	// ErrInternal is the kind of the other failures, e.g. the errors of the transport
+	ErrInternal Kind = "INTERNAL"
+)
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/grpc"
+	testproto "github.com/gojek/fiber/internal/testdata/gen/testdata/proto"
+	testutils "github.com/gojek/fiber/internal/testutils/grpc"
+	"github.com/gojek/fiber/protocol"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	port1         = 50555
+	port2         = 50556
+	endpoint1     = "localhost:50555"
+	endpoint2     = "localhost:50556"
+	serviceMethod = "testproto.UniversalPredictionService/PredictValues"
+)
+
+// mergePredictions concatenates the predictions of the shards into a single PredictValuesResponse
+func mergePredictions(_ context.Context, _ fiber.Request, responses []fiber.Response) fiber.Response {
+	merged := &testproto.PredictValuesResponse{}
+	for _, resp := range responses {
+		shard := &testproto.PredictValuesResponse{}
+		if err := proto.Unmarshal(resp.Payload(), shard); err != nil {
+			return fiber.NewErrorResponse(errors.ErrRequestFailed(protocol.GRPC, err))
+		}
+		merged.Predictions = append(merged.Predictions, shard.Predictions...)
+	}
+	payload, err := proto.Marshal(merged)
+	if err != nil {
+		return fiber.NewErrorResponse(errors.ErrRequestFailed(protocol.GRPC, err))
+	}
+	return &grpc.Response{
+		Metadata: metadata.MD{},
+		Message:  payload,
+		Status:   *status.New(codes.OK, ""),
+	}
+}
+
+func main() {
+
+	// each shard responds with the predictions of its own rows
+	testutils.RunTestUPIServer(testutils.GrpcTestServer{
+		Port: port1,
+		MockResponse: &testproto.PredictValuesResponse{
+			Predictions: []*testproto.PredictionResult{{RowId: "1"}},
+		},
+	})
+	testutils.RunTestUPIServer(testutils.GrpcTestServer{
+		Port: port2,
+		MockResponse: &testproto.PredictValuesResponse{
+			Predictions: []*testproto.PredictionResult{{RowId: "2"}},
+		},
+	})
+
+	// merge the predictions, once both shards have responded successfully
+	fanIn, _ := extras.NewMergingFanIn(mergePredictions, 2)
+	component := fiber.NewCombiner("combiner").WithFanIn(fanIn)
+
+	dispatcher1, _ := grpc.NewDispatcher(grpc.DispatcherConfig{
+		Endpoint:      endpoint1,
+		ServiceMethod: serviceMethod,
+	})
+	dispatcher2, _ := grpc.NewDispatcher(grpc.DispatcherConfig{
+		Endpoint:      endpoint2,
+		ServiceMethod: serviceMethod,
+	})
+	caller1, _ := fiber.NewCaller("shard-1", dispatcher1)
+	caller2, _ := fiber.NewCaller("shard-2", dispatcher2)
+
+	component.SetRoutes(map[string]fiber.Component{
+		"shard-1": fiber.NewProxy(nil, caller1),
+		"shard-2": fiber.NewProxy(nil, caller2),
+	})
+
+	bytePayload, _ := proto.Marshal(&testproto.PredictValuesRequest{
+		PredictionRows: []*testproto.PredictionRow{
+			{
+				RowId: "1",
+			},
+			{
+				RowId: "2",
+			},
+		},
+	})
+	var req = &grpc.Request{
+		Message: bytePayload,
+	}
+
+	resp, ok := <-component.Dispatch(context.Background(), req).Iter()
+	if !ok {
+		log.Fatalf("fail to receive response queue")
+	}
+	if resp.StatusCode() != int(codes.OK) {
+		log.Fatal(string(resp.Payload()))
+	}
+	responseProto := &testproto.PredictValuesResponse{}
+	if err := proto.Unmarshal(resp.Payload(), responseProto); err != nil {
+		log.Fatalf("fail to unmarshal to proto")
+	}
+	log.Print(responseProto.String())
+}
@@ -0,0 +1,107 @@
+package fiber
+
+import (
+	"context"
+	"fmt"
+)
+
+// Reasons of the routes, that are skipped by the router (see RoutingDecision)
+const (
+	// SkippedUnhealthy is the reason of the routes, that are skipped, while they are unhealthy (see HealthReporter)
+	SkippedUnhealthy = "unhealthy"
+	// SkippedSaturated is the reason of the routes, that are skipped, while they are saturated (see SaturationReporter)
+	SkippedSaturated = "saturated"
+	// SkippedTags is the reason of the routes, which tags don't match the tags of the request (see RouteTagFilter)
+	SkippedTags = "tags"
+	// SkippedMaxAttempts is the reason of the routes, that are not tried, because of the max attempts of the router
+	SkippedMaxAttempts = "max_attempts"
+)
+
+// RoutingDecision explains, how the router routes the request, without dispatching it. It's marshaled
+// into JSON, i.e. to be exposed by an admin endpoint
+type RoutingDecision struct {
+	RouterID string `json:"router_id"`
+	// Strategy is the type name of the routing strategy, i.e. "*extras.WeightedRandomRoutingStrategy"
+	Strategy string `json:"strategy"`
+	// Routes are the IDs of the routes in the order of their priority, i.e. the primary route and its fallbacks
+	Routes []string `json:"routes"`
+	// Pinned is the ID of the route, that the request has pinned with the override header (see RouteOverride)
+	Pinned string `json:"pinned,omitempty"`
+	// Skipped are the reasons, why the routes are not selected, keyed by the route ID
+	Skipped map[string]string `json:"skipped,omitempty"`
+	// Scores are the scores of the routes, that the strategy has selected them by, if it's a RouteScorer
+	Scores map[string]float64 `json:"scores,omitempty"`
+}
+
+// RouteScorer is implemented by the routing strategies, that select the routes by their scores, e.g. their
+// weights or latencies, so the scores are included into the RoutingDecision. The meaning of the scores is
+// specific to the strategy
+type RouteScorer interface {
+	RouteScores(routes map[string]Component) map[string]float64
+}
+
+// skip records the reason, why the route is not selected
+func (d *RoutingDecision) skip(routeID, reason string) {
+	if d.Skipped == nil {
+		d.Skipped = make(map[string]string)
+	}
+	d.Skipped[routeID] = reason
+}
+
+// explain runs the routing strategy, as the router would for the request, and returns its decision.
+// The strategy is run as it is, so the strategies with the state (e.g. the round-robin ones) advance it
+func (s *baseRoutingStrategy) explain(
+	ctx context.Context,
+	routerID string,
+	req Request,
+	routes map[string]Component,
+	override *RouteOverride,
+	decision RoutingDecision,
+) (RoutingDecision, error) {
+	if s == nil {
+		return decision, fmt.Errorf("router [%s]: no routing strategy", routerID)
+	}
+	decision.RouterID, decision.Strategy = routerID, s.name
+
+	healthy := healthyRoutes(routes)
+	if pinned, err := override.route(req, routes); err == nil && pinned != nil {
+		decision.Pinned = pinned.ID()
+	}
+	if scorer, ok := s.RoutingStrategy.(RouteScorer); ok {
+		decision.Scores = scorer.RouteScores(healthy)
+	}
+
+	routesOrderCh, errCh := s.getRoutesOrder(s.withName(ctx), req, routes, override)
+	for routesOrderCh != nil || errCh != nil {
+		select {
+		case orderedRoutes, ok := <-routesOrderCh:
+			if !ok {
+				routesOrderCh = nil
+				continue
+			}
+			decision.Routes = make([]string, 0, len(orderedRoutes))
+			for _, route := range orderedRoutes {
+				decision.Routes = append(decision.Routes, route.ID())
+			}
+		case err, ok := <-errCh:
+			if ok {
+				return decision, err
+			}
+			errCh = nil
+		case <-ctx.Done():
+			return decision, ctx.Err()
+		}
+	}
+
+	// the pinned route is selected, even if it's unhealthy
+	selected := make(map[string]bool, len(decision.Routes))
+	for _, id := range decision.Routes {
+		selected[id] = true
+	}
+	for id := range routes {
+		if _, ok := healthy[id]; !ok && !selected[id] {
+			decision.skip(id, SkippedUnhealthy)
+		}
+	}
+	return decision, nil
+}
@@ -0,0 +1,131 @@
+package fiber_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyRouter_Explain(t *testing.T) {
+	weights := map[string]float64{"route-a": 4, "route-b": 3, "route-c": 2, "route-d": 1}
+	strategy, err := extras.NewWeightedRandomRoutingStrategy(weights)
+	require.NoError(t, err)
+
+	// all the routes fail, so the order, in which they are tried, is the one of their route errors
+	builder := testutils.NewRouterBuilder("lazy-router").WithStrategy(strategy)
+	backends := make(map[string]*testutils.MockBackend)
+	for id := range weights {
+		backends[id] = testutils.NewMockBackend(
+			fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP)))
+		builder.WithComponent(backends[id].Component(id))
+	}
+	router := builder.BuildLazy()
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080", "")
+
+	for seed := int64(0); seed < 10; seed++ {
+		calls := make(map[string]int)
+		for id, backend := range backends {
+			calls[id] = backend.Calls()
+		}
+
+		strategy.WithSeed(seed)
+		decision, err := router.Explain(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "lazy-router", decision.RouterID)
+		assert.Equal(t, "*extras.WeightedRandomRoutingStrategy", decision.Strategy)
+		assert.Equal(t, weights, decision.Scores)
+		assert.Empty(t, decision.Skipped)
+		// the request is not dispatched
+		for id, backend := range backends {
+			require.Equal(t, calls[id], backend.Calls(), id)
+		}
+
+		strategy.WithSeed(seed)
+		responses := testutils.Dispatch(context.Background(), router, req)
+		require.Len(t, responses, 1)
+		errResp, ok := responses[0].(*fiber.ErrorResponse)
+		require.True(t, ok)
+		var tried []string
+		for _, routeErr := range errResp.RouteErrors() {
+			tried = append(tried, routeErr.RouteID)
+		}
+		assert.Equal(t, tried, decision.Routes, "seed %d", seed)
+	}
+}
+
+func TestLazyRouter_ExplainSkipped(t *testing.T) {
+	checker, err := fiber.NewHealthChecker("route-c", fiber.HealthProbeFunc(func(context.Context) error {
+		return errors.New("unavailable")
+	}), fiber.HealthCheckPolicy{Interval: 5 * time.Millisecond, UnhealthyThreshold: 1})
+	require.NoError(t, err)
+	checker.Start()
+	defer checker.Close(context.Background())
+	require.Eventually(t, func() bool { return !checker.Healthy() }, time.Second, time.Millisecond)
+
+	// the routes with zero weights are ordered last, as they are sorted
+	strategy, err := extras.NewWeightedRandomRoutingStrategy(map[string]float64{"route-b": 1, "route-a": 0, "route-d": 0})
+	require.NoError(t, err)
+	router := testutils.NewRouterBuilder("lazy-router").
+		WithRoute("route-a", testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil)).
+		WithRoute("route-b", testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil)).
+		WithComponent(fiber.NewHealthCheckedComponent(testutils.NewMockComponent("route-c"), checker)).
+		WithRoute("route-d", testUtilsHttp.MockResp(http.StatusOK, "D-OK", nil, nil)).
+		WithStrategy(strategy).
+		BuildLazy()
+	router.SetMaxAttempts(2)
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080", "")
+
+	decision, err := router.Explain(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"route-b", "route-a"}, decision.Routes)
+	assert.Equal(t, map[string]string{
+		"route-c": fiber.SkippedUnhealthy,
+		"route-d": fiber.SkippedMaxAttempts,
+	}, decision.Skipped)
+	assert.Equal(t, map[string]float64{"route-a": 0, "route-b": 1, "route-d": 0}, decision.Scores)
+
+	// the errors of the pre-routing hook and the strategy are returned
+	router.SetPreRoutingHook(func(ctx context.Context, req fiber.Request) (context.Context, error) {
+		return ctx, errors.New("unknown tenant")
+	})
+	_, err = router.Explain(context.Background(), req)
+	assert.EqualError(t, err, "unknown tenant")
+
+	router = testutils.NewRouterBuilder("lazy-router").
+		WithRoute("route-a", testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil)).
+		WithOrder([]string{"route-a"}, 0, errors.New("strategy failed")).
+		BuildLazy()
+	_, err = router.Explain(context.Background(), req)
+	assert.EqualError(t, err, "strategy failed")
+
+	_, err = fiber.NewLazyRouter("no-strategy").Explain(context.Background(), req)
+	assert.EqualError(t, err, "router [no-strategy]: no routing strategy")
+}
+
+func TestEagerRouter_Explain(t *testing.T) {
+	router := testutils.NewRouterBuilder("eager-router").
+		WithRoute("route-a", testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil)).
+		WithRoute("route-b", testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil)).
+		WithOrder([]string{"route-b", "route-a"}, 0, nil).
+		Build()
+	router.SetRouteOverride(&fiber.RouteOverride{Fallback: true})
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080", "")
+	req.Request.Header.Set(fiber.RouteOverrideHeader, "route-a")
+
+	decision, err := router.Explain(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "eager-router", decision.RouterID)
+	assert.Equal(t, "route-a", decision.Pinned)
+	assert.Equal(t, []string{"route-a", "route-b"}, decision.Routes)
+}
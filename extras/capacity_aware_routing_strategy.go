@@ -0,0 +1,347 @@
+package extras
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/util"
+)
+
+const (
+	// DefaultCapacityHeader is the default header of the probe response, that carries the capacity score
+	DefaultCapacityHeader = "X-Capacity"
+	// DefaultCapacityProbeInterval is the default time, after which the capacity score of a route is probed again
+	DefaultCapacityProbeInterval = 10 * time.Second
+	// DefaultCapacityHalfLife is the default time, in which a stale capacity score loses half of its bias
+	DefaultCapacityHalfLife = 30 * time.Second
+	// DefaultCapacityScore is the default weight of the routes, that haven't reported their capacity
+	DefaultCapacityScore = 1
+)
+
+// CapacityProbe reads the capacity score, that the backend of a route advertises, e.g. the number of
+// its ready replicas. The scores of all the routes of the strategy are expected to be of the same scale
+type CapacityProbe interface {
+	Capacity(ctx context.Context) (float64, error)
+}
+
+// CapacityProbeFunc is an adapter to use the function as a CapacityProbe
+type CapacityProbeFunc func(ctx context.Context) (float64, error)
+
+// Capacity calls the function
+func (f CapacityProbeFunc) Capacity(ctx context.Context) (float64, error) {
+	return f(ctx)
+}
+
+// HTTPCapacityProbe reads the capacity score with the http GET request to the endpoint of the backend,
+// e.g. its health endpoint. The score is taken from the response header, or, if the header is missing,
+// from the `capacity` field of the JSON response body
+type HTTPCapacityProbe struct {
+	endpoint string
+	header   string
+	client   *http.Client
+}
+
+// NewHTTPCapacityProbe is a factory method, that creates the HTTPCapacityProbe of the endpoint
+func NewHTTPCapacityProbe(endpoint string, header string, client *http.Client) *HTTPCapacityProbe {
+	if header == "" {
+		header = DefaultCapacityHeader
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPCapacityProbe{endpoint: endpoint, header: header, client: client}
+}
+
+// Capacity requests the endpoint and parses the capacity score from its response
+func (p *HTTPCapacityProbe) Capacity(ctx context.Context) (float64, error) {
+	httpReq, err := http.NewRequest(http.MethodGet, p.endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := p.client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("capacity probe responded with status %d", resp.StatusCode)
+	}
+	if value := resp.Header.Get(p.header); value != "" {
+		return strconv.ParseFloat(value, 64)
+	}
+
+	var body capacityResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	if body.Capacity == nil {
+		return 0, errors.New("capacity probe responded without the capacity")
+	}
+	return *body.Capacity, nil
+}
+
+type capacityResponse struct {
+	Capacity *float64 `json:"capacity"`
+}
+
+// CapacityAwareRoutingStrategy orders the routes by a weighted random draw, where the weight of each route
+// is the capacity score, that its backend advertises, so the routes, that have scaled out, take the bigger
+// share of the traffic. The scores are probed with the CapacityProbe of each route in the background, once
+// the previous score of the route is older than the probe interval. A score, that hasn't been refreshed
+// within the interval (i.e. the probe fails), decays towards the default score with the given half-life,
+// so the stale scores lose their bias. Routes without a probe or a score are weighted with the default score
+type CapacityAwareRoutingStrategy struct {
+	fiber.BaseFiberType
+
+	probes       map[string]CapacityProbe
+	interval     time.Duration
+	halfLife     time.Duration
+	defaultScore float64
+	scores       sync.Map // route ID -> *capacityScore
+	rand         *util.ShardedRand
+}
+
+type capacityScore struct {
+	lock     sync.Mutex
+	value    float64
+	reported time.Time
+	probed   time.Time
+}
+
+type capacityAwareRoutingStrategyProperties struct {
+	Probes       map[string]string `json:"probes"`
+	Header       string            `json:"header"`
+	Interval     string            `json:"interval"`
+	HalfLife     string            `json:"half_life"`
+	DefaultScore *float64          `json:"default_score"`
+}
+
+// NewCapacityAwareRoutingStrategy is a creator factory for the CapacityAwareRoutingStrategy. probes are
+// the capacity probes of the routes, keyed by the route ID. Each probe has to complete within the interval
+func NewCapacityAwareRoutingStrategy(
+	probes map[string]CapacityProbe,
+	interval time.Duration,
+	halfLife time.Duration,
+) (*CapacityAwareRoutingStrategy, error) {
+	if interval <= 0 || halfLife <= 0 {
+		return nil, errors.New("capacity aware routing strategy: interval and half_life must be positive")
+	}
+	for routeID, probe := range probes {
+		if probe == nil {
+			return nil, fmt.Errorf("capacity aware routing strategy: missing probe of route: [%s]", routeID)
+		}
+	}
+	return &CapacityAwareRoutingStrategy{
+		probes:       probes,
+		interval:     interval,
+		halfLife:     halfLife,
+		defaultScore: DefaultCapacityScore,
+		rand:         util.NewShardedRand(time.Now().UnixNano()),
+	}, nil
+}
+
+// Initialize parses the properties of the strategy:
+//   - probes – capacity endpoints of the routes, keyed by the route ID. Example `{"route-a": "http://a/health"}`
+//   - header – response header of the endpoints with the capacity score, `X-Capacity` by default.
+//     The responses without the header are parsed as the JSON `{"capacity": <score>}`
+//   - interval – time, after which the capacity of a route is probed again. Example `10s`
+//   - half_life – time, in which a stale score loses half of its bias. Example `30s`
+//   - default_score – weight of the routes, that haven't reported their capacity, 1 by default
+func (s *CapacityAwareRoutingStrategy) Initialize(properties json.RawMessage) error {
+	var props capacityAwareRoutingStrategyProperties
+	if len(properties) > 0 {
+		if err := json.Unmarshal(properties, &props); err != nil {
+			return fmt.Errorf("capacity aware routing strategy: failed to parse properties: %s", err)
+		}
+	}
+
+	interval, halfLife := DefaultCapacityProbeInterval, DefaultCapacityHalfLife
+	var err error
+	if props.Interval != "" {
+		if interval, err = time.ParseDuration(props.Interval); err != nil {
+			return fmt.Errorf("capacity aware routing strategy: invalid interval: %s", err)
+		}
+	}
+	if props.HalfLife != "" {
+		if halfLife, err = time.ParseDuration(props.HalfLife); err != nil {
+			return fmt.Errorf("capacity aware routing strategy: invalid half_life: %s", err)
+		}
+	}
+
+	client := &http.Client{Timeout: interval}
+	probes := make(map[string]CapacityProbe, len(props.Probes))
+	for routeID, endpoint := range props.Probes {
+		probes[routeID] = NewHTTPCapacityProbe(endpoint, props.Header, client)
+	}
+
+	strategy, err := NewCapacityAwareRoutingStrategy(probes, interval, halfLife)
+	if err != nil {
+		return err
+	}
+	if props.DefaultScore != nil {
+		if _, err = strategy.WithDefaultScore(*props.DefaultScore); err != nil {
+			return err
+		}
+	}
+	s.probes = strategy.probes
+	s.interval = strategy.interval
+	s.halfLife = strategy.halfLife
+	s.defaultScore = strategy.defaultScore
+	s.rand = strategy.rand
+	return nil
+}
+
+// WithDefaultScore sets the weight of the routes, that haven't reported their capacity
+func (s *CapacityAwareRoutingStrategy) WithDefaultScore(score float64) (*CapacityAwareRoutingStrategy, error) {
+	if score < 0 || math.IsInf(score, 0) || math.IsNaN(score) {
+		return nil, fmt.Errorf("capacity aware routing strategy: invalid default_score: [%v]", score)
+	}
+	s.defaultScore = score
+	return s, nil
+}
+
+// WithSeed seeds the random source of the strategy
+func (s *CapacityAwareRoutingStrategy) WithSeed(seed int64) *CapacityAwareRoutingStrategy {
+	s.rand = util.NewShardedRand(seed)
+	return s
+}
+
+// WithRandSource replaces the random source of the strategy with the given one
+func (s *CapacityAwareRoutingStrategy) WithRandSource(source rand.Source) *CapacityAwareRoutingStrategy {
+	s.rand = util.NewShardedRandFromSource(source)
+	return s
+}
+
+// Observe records the capacity score of the route with the given ID, e.g. the one reported by its response.
+// Negative scores are ignored
+func (s *CapacityAwareRoutingStrategy) Observe(routeID string, capacity float64) {
+	if capacity < 0 || math.IsInf(capacity, 0) || math.IsNaN(capacity) {
+		return
+	}
+	score := s.score(routeID)
+	score.lock.Lock()
+	defer score.lock.Unlock()
+	score.value = capacity
+	score.reported = time.Now()
+}
+
+// Scores returns the snapshot of the current weights of the routes, that have reported their capacity
+func (s *CapacityAwareRoutingStrategy) Scores() map[string]float64 {
+	now := time.Now()
+	scores := make(map[string]float64)
+	s.scores.Range(func(key, value interface{}) bool {
+		if weight, ok := s.weight(value.(*capacityScore), now); ok {
+			scores[key.(string)] = weight
+		}
+		return true
+	})
+	return scores
+}
+
+// SelectRoute selects the primary route and the order of fallbacks by a weighted random draw, where
+// the routes are weighted by their capacity scores. The stale scores of the routes are probed again
+// in the background, so they are used by the next requests
+func (s *CapacityAwareRoutingStrategy) SelectRoute(
+	_ context.Context,
+	_ fiber.Request,
+	routes map[string]fiber.Component,
+) (route fiber.Component, fallbacks []fiber.Component, err error) {
+	if len(routes) == 0 {
+		return nil, nil, nil
+	}
+
+	now := time.Now()
+	ids := make([]string, 0, len(routes))
+	weights := make(map[string]float64, len(routes))
+	for id := range routes {
+		ids = append(ids, id)
+		s.refresh(id, now)
+		weights[id] = s.routeWeight(id, now)
+	}
+	// sorted, so the order is reproducible with a seeded random source
+	sort.Strings(ids)
+	s.rand.WeightedShuffle(ids, func(id string) float64 {
+		return weights[id]
+	})
+
+	for _, id := range ids[1:] {
+		fallbacks = append(fallbacks, routes[id])
+	}
+	return routes[ids[0]], fallbacks, nil
+}
+
+// RouteScores returns the current weights of the routes (see fiber.RouteScorer)
+func (s *CapacityAwareRoutingStrategy) RouteScores(routes map[string]fiber.Component) map[string]float64 {
+	now := time.Now()
+	scores := make(map[string]float64, len(routes))
+	for id := range routes {
+		scores[id] = s.routeWeight(id, now)
+	}
+	return scores
+}
+
+// routeWeight returns the weight of the route, or the default score, if it hasn't reported its capacity
+func (s *CapacityAwareRoutingStrategy) routeWeight(routeID string, now time.Time) float64 {
+	if weight, ok := s.weight(s.score(routeID), now); ok {
+		return weight
+	}
+	return s.defaultScore
+}
+
+func (s *CapacityAwareRoutingStrategy) score(routeID string) *capacityScore {
+	value, _ := s.scores.LoadOrStore(routeID, &capacityScore{})
+	return value.(*capacityScore)
+}
+
+// weight returns the capacity score of the route, decayed towards the default score, if it's stale.
+// It returns false, if the route hasn't reported its capacity yet
+func (s *CapacityAwareRoutingStrategy) weight(score *capacityScore, now time.Time) (float64, bool) {
+	score.lock.Lock()
+	defer score.lock.Unlock()
+	if score.reported.IsZero() {
+		return 0, false
+	}
+
+	stale := now.Sub(score.reported) - s.interval
+	if stale <= 0 {
+		return score.value, true
+	}
+	decay := math.Exp2(-float64(stale) / float64(s.halfLife))
+	return s.defaultScore + (score.value-s.defaultScore)*decay, true
+}
+
+// refresh probes the capacity of the route in the background, if it hasn't been probed within the interval
+func (s *CapacityAwareRoutingStrategy) refresh(routeID string, now time.Time) {
+	probe, ok := s.probes[routeID]
+	if !ok {
+		return
+	}
+
+	score := s.score(routeID)
+	score.lock.Lock()
+	if !score.probed.IsZero() && now.Sub(score.probed) < s.interval {
+		score.lock.Unlock()
+		return
+	}
+	score.probed = now
+	score.lock.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+		defer cancel()
+		if capacity, err := probe.Capacity(ctx); err == nil {
+			s.Observe(routeID, capacity)
+		}
+	}()
+}
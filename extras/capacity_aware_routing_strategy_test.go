@@ -0,0 +1,185 @@
+package extras_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapacityAwareRoutingStrategy_Initialize(t *testing.T) {
+	suite := map[string]struct {
+		properties string
+		expected   string
+	}{
+		"ok": {
+			properties: `{"probes": {"route-a": "http://localhost:8080/health"}, "interval": "5s", "half_life": "1m"}`,
+		},
+		"no properties": {},
+		"invalid interval": {
+			properties: `{"interval": "5"}`,
+			expected:   `capacity aware routing strategy: invalid interval: time: missing unit in duration "5"`,
+		},
+		"negative half life": {
+			properties: `{"half_life": "-1s"}`,
+			expected:   "capacity aware routing strategy: interval and half_life must be positive",
+		},
+		"negative default score": {
+			properties: `{"default_score": -1}`,
+			expected:   "capacity aware routing strategy: invalid default_score: [-1]",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			strategy := new(extras.CapacityAwareRoutingStrategy)
+			err := strategy.Initialize(json.RawMessage(tt.properties))
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCapacityAwareRoutingStrategy_SelectRoute(t *testing.T) {
+	const iterations = 10000
+
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+	}
+	capacity := func(score float64) extras.CapacityProbe {
+		return extras.CapacityProbeFunc(func(context.Context) (float64, error) {
+			return score, nil
+		})
+	}
+	// route-c has no probe, so it's weighted with the default score
+	strategy, err := extras.NewCapacityAwareRoutingStrategy(map[string]extras.CapacityProbe{
+		"route-a": capacity(6),
+		"route-b": capacity(3),
+	}, time.Minute, time.Minute)
+	require.NoError(t, err)
+	strategy.WithSeed(42)
+
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/capacity", "")
+	_, _, err = strategy.SelectRoute(context.Background(), req, routes)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(strategy.Scores()) == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, map[string]float64{"route-a": 6, "route-b": 3}, strategy.Scores())
+
+	primary := make(map[string]int)
+	for i := 0; i < iterations; i++ {
+		route, fallbacks, err := strategy.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+		require.Len(t, fallbacks, len(routes)-1)
+		primary[route.ID()]++
+	}
+
+	assert.InDelta(t, 0.6, float64(primary["route-a"])/iterations, 0.03)
+	assert.InDelta(t, 0.3, float64(primary["route-b"])/iterations, 0.03)
+	assert.InDelta(t, 0.1, float64(primary["route-c"])/iterations, 0.03)
+}
+
+func TestCapacityAwareRoutingStrategy_Decay(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+	}
+	var probes int32
+	strategy, err := extras.NewCapacityAwareRoutingStrategy(map[string]extras.CapacityProbe{
+		"route-a": extras.CapacityProbeFunc(func(context.Context) (float64, error) {
+			// the capacity is only reported once, so the score becomes stale
+			if atomic.AddInt32(&probes, 1) > 1 {
+				return 0, errors.New("unavailable")
+			}
+			return 9, nil
+		}),
+	}, 20*time.Millisecond, 20*time.Millisecond)
+	require.NoError(t, err)
+	_, err = strategy.WithDefaultScore(3)
+	require.NoError(t, err)
+	strategy.Observe("route-b", 3)
+
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/capacity", "")
+	_, _, err = strategy.SelectRoute(context.Background(), req, routes)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return strategy.Scores()["route-a"] > 3
+	}, time.Second, 5*time.Millisecond)
+
+	// the stale score of route-a decays towards the default score
+	require.Eventually(t, func() bool {
+		_, _, err := strategy.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+		return strategy.Scores()["route-a"] < 3.1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Greater(t, atomic.LoadInt32(&probes), int32(1))
+}
+
+func TestHTTPCapacityProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/header":
+			w.Header().Set("X-Capacity", "2.5")
+		case "/body":
+			_, _ = w.Write([]byte(`{"status": "ok", "capacity": 4}`))
+		case "/empty":
+			_, _ = w.Write([]byte(`{"status": "ok"}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	suite := map[string]struct {
+		path     string
+		expected float64
+		err      string
+	}{
+		"header": {
+			path:     "/header",
+			expected: 2.5,
+		},
+		"body": {
+			path:     "/body",
+			expected: 4,
+		},
+		"no capacity": {
+			path: "/empty",
+			err:  "capacity probe responded without the capacity",
+		},
+		"unavailable": {
+			path: "/unavailable",
+			err:  "capacity probe responded with status 503",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			probe := extras.NewHTTPCapacityProbe(server.URL+tt.path, "", nil)
+			capacity, err := probe.Capacity(context.Background())
+			if tt.err == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, capacity)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package extras
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gojek/fiber"
+)
+
+// DefaultVirtualNodes is the default number of points on the hash ring per route
+const DefaultVirtualNodes = 100
+
+// maxHashRings is the number of the hash rings, built for the distinct sets of the routes (e.g. the routes,
+// filtered by the tags of the requests), that the strategy keeps. All of them are dropped, once it's reached
+const maxHashRings = 64
+
+// ConsistentHashRoutingStrategy places the routes on a consistent hash ring, and selects the route,
+// that owns the hash of the key from the configured request header (or grpc metadata), as the primary
+// route. The next distinct routes on the ring are the fallbacks, so when a route is removed, only its
+// keys are moved to other routes. Each route is placed on the ring several times (virtual nodes), so
+// the keys are distributed evenly even between a small number of routes.
+// If the key is missing in the request, the fallback strategy is used instead
+type ConsistentHashRoutingStrategy struct {
+	fiber.BaseFiberType
+
+	keyHeader    string
+	virtualNodes int
+	fallback     fiber.RoutingStrategy
+
+	lock sync.RWMutex
+	// rings are the hash rings, keyed by the sorted IDs of their routes
+	rings map[string]*hashRing
+}
+
+type hashRing struct {
+	hashes []uint32
+	owners []string
+}
+
+type consistentHashRoutingStrategyProperties struct {
+	KeyHeader    string `json:"key_header"`
+	VirtualNodes int    `json:"virtual_nodes"`
+}
+
+// NewConsistentHashRoutingStrategy is a creator factory for the ConsistentHashRoutingStrategy
+func NewConsistentHashRoutingStrategy(keyHeader string, virtualNodes int) *ConsistentHashRoutingStrategy {
+	if virtualNodes <= 0 {
+		virtualNodes = DefaultVirtualNodes
+	}
+	return &ConsistentHashRoutingStrategy{
+		keyHeader:    keyHeader,
+		virtualNodes: virtualNodes,
+		fallback:     &RandomRoutingStrategy{},
+	}
+}
+
+// Initialize parses the properties of the strategy:
+//   - key_header – name of the request header, which value is used as the hashing key (required)
+//   - virtual_nodes – number of points on the hash ring per route
+func (s *ConsistentHashRoutingStrategy) Initialize(properties json.RawMessage) error {
+	var props consistentHashRoutingStrategyProperties
+	if err := json.Unmarshal(properties, &props); err != nil {
+		return fmt.Errorf("consistent hash routing strategy: failed to parse properties: %s", err)
+	}
+	if props.KeyHeader == "" {
+		return errors.New("consistent hash routing strategy: missing config (key_header)")
+	}
+	if props.VirtualNodes < 0 {
+		return errors.New("consistent hash routing strategy: virtual_nodes can not be negative")
+	}
+
+	s.keyHeader = props.KeyHeader
+	s.virtualNodes = DefaultVirtualNodes
+	if props.VirtualNodes > 0 {
+		s.virtualNodes = props.VirtualNodes
+	}
+	if s.fallback == nil {
+		s.fallback = &RandomRoutingStrategy{}
+	}
+	return nil
+}
+
+// WithFallback sets the routing strategy, that is used when the request has no key.
+// By default, RandomRoutingStrategy is used
+func (s *ConsistentHashRoutingStrategy) WithFallback(fallback fiber.RoutingStrategy) *ConsistentHashRoutingStrategy {
+	s.fallback = fallback
+	return s
+}
+
+// SelectRoute selects the route, that owns the key of the request on the hash ring, as the primary
+// route, and the following distinct routes on the ring as the fallbacks
+func (s *ConsistentHashRoutingStrategy) SelectRoute(
+	ctx context.Context,
+	req fiber.Request,
+	routes map[string]fiber.Component,
+) (route fiber.Component, fallbacks []fiber.Component, err error) {
+	key := headerValue(req, s.keyHeader)
+	if key == "" || len(routes) == 0 {
+		return s.fallback.SelectRoute(ctx, req, routes)
+	}
+
+	ring := s.hashRing(routes)
+	hash := hashOf(key)
+	start := sort.Search(len(ring.hashes), func(i int) bool {
+		return ring.hashes[i] >= hash
+	})
+
+	ordered := make([]fiber.Component, 0, len(routes))
+	seen := make(map[string]bool, len(routes))
+	for i := 0; i < len(ring.hashes) && len(ordered) < len(routes); i++ {
+		owner := ring.owners[(start+i)%len(ring.hashes)]
+		if !seen[owner] {
+			seen[owner] = true
+			ordered = append(ordered, routes[owner])
+		}
+	}
+	return ordered[0], ordered[1:], nil
+}
+
+// hashRing returns the ring for the given routes, and builds it, if it's the new set of the routes
+func (s *ConsistentHashRoutingStrategy) hashRing(routes map[string]fiber.Component) *hashRing {
+	ids := make([]string, 0, len(routes))
+	for id := range routes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	key := strings.Join(ids, "\x00")
+
+	s.lock.RLock()
+	ring, ok := s.rings[key]
+	s.lock.RUnlock()
+	if ok {
+		return ring
+	}
+
+	ring = &hashRing{}
+	type point struct {
+		hash  uint32
+		owner string
+	}
+	points := make([]point, 0, len(ids)*s.virtualNodes)
+	for _, id := range ids {
+		for node := 0; node < s.virtualNodes; node++ {
+			points = append(points, point{hash: hashOf(id + "#" + strconv.Itoa(node)), owner: id})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].hash == points[j].hash {
+			return points[i].owner < points[j].owner
+		}
+		return points[i].hash < points[j].hash
+	})
+	for _, p := range points {
+		ring.hashes = append(ring.hashes, p.hash)
+		ring.owners = append(ring.owners, p.owner)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.rings == nil || len(s.rings) >= maxHashRings {
+		s.rings = make(map[string]*hashRing)
+	}
+	s.rings[key] = ring
+	return ring
+}
+
+// hashOf hashes the key with FNV-1a, which output is additionally mixed (the murmur3 finalizer),
+// because FNV alone spreads similar keys, such as the virtual nodes of a route, unevenly
+func hashOf(key string) uint32 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	h := hash.Sum64()
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return uint32(h)
+}
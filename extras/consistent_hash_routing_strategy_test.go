@@ -0,0 +1,163 @@
+package extras_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/grpc"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestConsistentHashRoutingStrategy_Initialize(t *testing.T) {
+	suite := map[string]struct {
+		properties string
+		expected   string
+	}{
+		"ok": {
+			properties: `{"key_header": "X-User-ID", "virtual_nodes": 50}`,
+		},
+		"missing key header": {
+			properties: `{"virtual_nodes": 50}`,
+			expected:   "consistent hash routing strategy: missing config (key_header)",
+		},
+		"negative virtual nodes": {
+			properties: `{"key_header": "X-User-ID", "virtual_nodes": -1}`,
+			expected:   "consistent hash routing strategy: virtual_nodes can not be negative",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			strategy := new(extras.ConsistentHashRoutingStrategy)
+			err := strategy.Initialize(json.RawMessage(tt.properties))
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func consistentHashRoutes(ids ...string) map[string]fiber.Component {
+	routes := make(map[string]fiber.Component)
+	for _, id := range ids {
+		routes[id] = testutils.NewMockComponent(id)
+	}
+	return routes
+}
+
+func userRequest(userID string) fiber.Request {
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/hash", "")
+	req.Request.Header = http.Header{"X-User-Id": []string{userID}}
+	return req
+}
+
+func TestConsistentHashRoutingStrategy_SelectRoute(t *testing.T) {
+	const users = 3000
+	strategy := extras.NewConsistentHashRoutingStrategy("X-User-ID", extras.DefaultVirtualNodes)
+
+	routes := consistentHashRoutes("route-a", "route-b", "route-c")
+	owners := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < users; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		route, fallbacks, err := strategy.SelectRoute(context.Background(), userRequest(userID), routes)
+		require.NoError(t, err)
+		assert.Len(t, fallbacks, 2)
+		assert.NotContains(t, fallbacks, route)
+
+		// the same key is always routed to the same route
+		again, _, _ := strategy.SelectRoute(context.Background(), userRequest(userID), routes)
+		assert.Equal(t, route.ID(), again.ID())
+
+		owners[userID] = route.ID()
+		counts[route.ID()]++
+	}
+	for id, count := range counts {
+		assert.InDelta(t, users/3, count, users/10, "keys should be evenly distributed: %s", id)
+	}
+
+	// when a route is removed, only its keys are moved
+	reduced := consistentHashRoutes("route-a", "route-c")
+	for userID, owner := range owners {
+		route, _, _ := strategy.SelectRoute(context.Background(), userRequest(userID), reduced)
+		if owner != "route-b" {
+			assert.Equal(t, owner, route.ID())
+		}
+	}
+
+	// when a route is added, the keys are only moved to the new route
+	extended := consistentHashRoutes("route-a", "route-b", "route-c", "route-d")
+	for userID, owner := range owners {
+		route, _, _ := strategy.SelectRoute(context.Background(), userRequest(userID), extended)
+		if route.ID() != "route-d" {
+			assert.Equal(t, owner, route.ID())
+		}
+	}
+}
+
+func TestConsistentHashRoutingStrategy_RouteSubsets(t *testing.T) {
+	strategy := extras.NewConsistentHashRoutingStrategy("X-User-ID", extras.DefaultVirtualNodes)
+	subsets := []map[string]fiber.Component{
+		consistentHashRoutes("route-a", "route-b", "route-c"),
+		consistentHashRoutes("route-a", "route-c"),
+		consistentHashRoutes("route-b", "route-c"),
+	}
+
+	// the routes are selected from the rings of the subsets, as they were first selected,
+	// when the requests alternate between them (e.g. with the routes filtered by the tags)
+	selected := make([]map[string]string, len(subsets))
+	for idx, routes := range subsets {
+		selected[idx] = make(map[string]string)
+		for i := 0; i < 100; i++ {
+			userID := fmt.Sprintf("user-%d", i)
+			route, _, err := strategy.SelectRoute(context.Background(), userRequest(userID), routes)
+			require.NoError(t, err)
+			selected[idx][userID] = route.ID()
+		}
+	}
+	for i := 0; i < 100; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		for idx, routes := range subsets {
+			route, _, err := strategy.SelectRoute(context.Background(), userRequest(userID), routes)
+			require.NoError(t, err)
+			assert.Equal(t, selected[idx][userID], route.ID())
+		}
+	}
+}
+
+func TestConsistentHashRoutingStrategy_SelectRouteGrpc(t *testing.T) {
+	strategy := extras.NewConsistentHashRoutingStrategy("X-User-ID", extras.DefaultVirtualNodes)
+	routes := consistentHashRoutes("route-a", "route-b", "route-c")
+
+	httpRoute, _, err := strategy.SelectRoute(context.Background(), userRequest("user-1"), routes)
+	require.NoError(t, err)
+
+	grpcReq := &grpc.Request{Metadata: metadata.New(map[string]string{"x-user-id": "user-1"})}
+	grpcRoute, _, err := strategy.SelectRoute(context.Background(), grpcReq, routes)
+	require.NoError(t, err)
+	assert.Equal(t, httpRoute.ID(), grpcRoute.ID())
+}
+
+func TestConsistentHashRoutingStrategy_MissingKey(t *testing.T) {
+	routes := consistentHashRoutes("route-a", "route-b")
+	fallback := testutils.NewMockRoutingStrategy(routes, []string{"route-b", "route-a"}, 0, nil)
+	strategy := extras.NewConsistentHashRoutingStrategy("X-User-ID", 0).WithFallback(fallback)
+
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/hash", "")
+	route, fallbacks, err := strategy.SelectRoute(context.Background(), req, routes)
+	require.NoError(t, err)
+	assert.Equal(t, "route-b", route.ID())
+	assert.Equal(t, []fiber.Component{routes["route-a"]}, fallbacks)
+	fallback.AssertExpectations(t)
+}
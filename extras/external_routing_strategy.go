@@ -0,0 +1,296 @@
+package extras
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gojek/fiber"
+)
+
+const (
+	// DefaultDecisionTimeout is the default timeout of the call to the decision service
+	DefaultDecisionTimeout = 50 * time.Millisecond
+	// DefaultDecisionCacheTTL is the default time for which the decisions are cached
+	DefaultDecisionCacheTTL = 30 * time.Second
+	// DefaultDecisionCacheSize is the default maximum number of cached decisions
+	DefaultDecisionCacheSize = 10000
+)
+
+// ExternalRoutingStrategy delegates the selection of the primary route to an external decision
+// service. The decision service is called with HTTP GET {endpoint}?key={value}, where
+// the value is taken from the configured request header (or grpc metadata) and it's expected
+// to respond with a JSON object {"route_id": "<id of the route>"}.
+// Decisions are cached by the key for the configured TTL. If the key is missing, the decision service
+// fails to respond within the timeout or returns an unknown route, the fallback strategy is used instead
+type ExternalRoutingStrategy struct {
+	fiber.BaseFiberType
+
+	endpoint  string
+	keyHeader string
+	cacheTTL  time.Duration
+	cacheSize int
+	client    *http.Client
+	fallback  fiber.RoutingStrategy
+	// fallbackType is the configuration of the fallback strategy, that is created from the registered
+	// types by types.NewRoutingStrategy
+	fallbackType *FallbackStrategyConfig
+
+	cache *decisionCache
+}
+
+// FallbackStrategyConfig is the type name and the properties of the fallback strategy of the
+// ExternalRoutingStrategy, e.g. `{"type": "fiber.RoundRobinRoutingStrategy"}`
+type FallbackStrategyConfig struct {
+	Type       string          `json:"type"`
+	Properties json.RawMessage `json:"properties"`
+}
+
+type routingDecision struct {
+	routeID string
+	expires time.Time
+}
+
+// decisionCacheShards is the number of independently locked shards of the decision cache,
+// so the concurrent requests with different keys don't contend on a single lock
+const decisionCacheShards = 16
+
+type decisionCache struct {
+	shards [decisionCacheShards]decisionCacheShard
+}
+
+type decisionCacheShard struct {
+	lock      sync.RWMutex
+	decisions map[string]routingDecision
+	capacity  int
+}
+
+func newDecisionCache(size int) *decisionCache {
+	capacity := size / decisionCacheShards
+	if capacity < 1 {
+		capacity = 1
+	}
+	cache := &decisionCache{}
+	for idx := range cache.shards {
+		cache.shards[idx].decisions = make(map[string]routingDecision)
+		cache.shards[idx].capacity = capacity
+	}
+	return cache
+}
+
+func (c *decisionCache) shard(key string) *decisionCacheShard {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return &c.shards[hash.Sum32()%decisionCacheShards]
+}
+
+func (c *decisionCache) get(key string, now time.Time) (string, bool) {
+	shard := c.shard(key)
+	shard.lock.RLock()
+	defer shard.lock.RUnlock()
+	if decision, ok := shard.decisions[key]; ok && now.Before(decision.expires) {
+		return decision.routeID, true
+	}
+	return "", false
+}
+
+// put caches the decision. If the shard is full, the expired decisions are evicted, or the one, that expires
+// first, if none of them has expired, so the new decisions are always cached
+func (c *decisionCache) put(key string, decision routingDecision, now time.Time) {
+	shard := c.shard(key)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+	if _, exists := shard.decisions[key]; !exists && len(shard.decisions) >= shard.capacity {
+		var oldest string
+		for k, cached := range shard.decisions {
+			if now.After(cached.expires) {
+				delete(shard.decisions, k)
+			} else if oldest == "" || cached.expires.Before(shard.decisions[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(shard.decisions) >= shard.capacity {
+			delete(shard.decisions, oldest)
+		}
+	}
+	shard.decisions[key] = decision
+}
+
+type externalRoutingStrategyProperties struct {
+	Endpoint  string                  `json:"endpoint"`
+	KeyHeader string                  `json:"key_header"`
+	CacheTTL  string                  `json:"cache_ttl"`
+	CacheSize int                     `json:"cache_size"`
+	Timeout   string                  `json:"timeout"`
+	Fallback  *FallbackStrategyConfig `json:"fallback"`
+}
+
+type decisionResponse struct {
+	RouteID string `json:"route_id"`
+}
+
+// NewExternalRoutingStrategy is a creator factory for the ExternalRoutingStrategy
+func NewExternalRoutingStrategy(
+	endpoint string,
+	keyHeader string,
+	cacheTTL time.Duration,
+	timeout time.Duration,
+) *ExternalRoutingStrategy {
+	return &ExternalRoutingStrategy{
+		endpoint:  endpoint,
+		keyHeader: keyHeader,
+		cacheTTL:  cacheTTL,
+		cacheSize: DefaultDecisionCacheSize,
+		client:    &http.Client{Timeout: timeout},
+		fallback:  &RandomRoutingStrategy{},
+		cache:     newDecisionCache(DefaultDecisionCacheSize),
+	}
+}
+
+// Initialize parses the properties of the strategy:
+//   - endpoint – url of the decision service (required)
+//   - key_header – name of the request header, which value is used as the decision key (required)
+//   - cache_ttl – time for which the decisions are cached. Example `30s`
+//   - cache_size – maximum number of the cached decisions
+//   - timeout – timeout of the call to the decision service. Example `50ms`
+//   - fallback – `type` and `properties` of the fallback strategy. Defaults to `fiber.RandomRoutingStrategy`
+func (s *ExternalRoutingStrategy) Initialize(properties json.RawMessage) error {
+	var props externalRoutingStrategyProperties
+	if err := json.Unmarshal(properties, &props); err != nil {
+		return fmt.Errorf("external routing strategy: failed to parse properties: %s", err)
+	}
+	if props.Endpoint == "" || props.KeyHeader == "" {
+		return errors.New("external routing strategy: missing config (endpoint/key_header)")
+	}
+	if props.Fallback != nil && props.Fallback.Type == "" {
+		return errors.New("external routing strategy: fallback type is required")
+	}
+
+	cacheTTL, timeout := DefaultDecisionCacheTTL, DefaultDecisionTimeout
+	var err error
+	if props.CacheTTL != "" {
+		if cacheTTL, err = time.ParseDuration(props.CacheTTL); err != nil {
+			return fmt.Errorf("external routing strategy: invalid cache_ttl: %s", err)
+		}
+	}
+	if props.Timeout != "" {
+		if timeout, err = time.ParseDuration(props.Timeout); err != nil {
+			return fmt.Errorf("external routing strategy: invalid timeout: %s", err)
+		}
+	}
+
+	s.endpoint = props.Endpoint
+	s.keyHeader = props.KeyHeader
+	s.cacheTTL = cacheTTL
+	s.cacheSize = DefaultDecisionCacheSize
+	if props.CacheSize > 0 {
+		s.cacheSize = props.CacheSize
+	}
+	s.client = &http.Client{Timeout: timeout}
+	s.fallbackType = props.Fallback
+	if s.fallback == nil {
+		s.fallback = &RandomRoutingStrategy{}
+	}
+	s.cache = newDecisionCache(s.cacheSize)
+	return nil
+}
+
+// FallbackType returns the configuration of the fallback strategy from the properties of the strategy,
+// or nil, if it's not configured, so the fallback is created from the registered types (see WithFallback)
+func (s *ExternalRoutingStrategy) FallbackType() *FallbackStrategyConfig {
+	return s.fallbackType
+}
+
+// WithFallback sets the local routing strategy, that is used when the decision can't be
+// received from the decision service. By default, RandomRoutingStrategy is used
+func (s *ExternalRoutingStrategy) WithFallback(fallback fiber.RoutingStrategy) *ExternalRoutingStrategy {
+	s.fallback = fallback
+	return s
+}
+
+// SelectRoute selects the primary route, as decided by the decision service, followed by the rest
+// of the routes, sorted by their IDs. The fallback strategy selects the routes instead, only if
+// the decision can't be received
+func (s *ExternalRoutingStrategy) SelectRoute(
+	ctx context.Context,
+	req fiber.Request,
+	routes map[string]fiber.Component,
+) (route fiber.Component, fallbacks []fiber.Component, err error) {
+	key := headerValue(req, s.keyHeader)
+	if key == "" {
+		return s.fallback.SelectRoute(ctx, req, routes)
+	}
+
+	routeID, err := s.decision(ctx, key)
+	if err != nil {
+		return s.fallback.SelectRoute(ctx, req, routes)
+	}
+	decided, ok := routes[routeID]
+	if !ok {
+		return s.fallback.SelectRoute(ctx, req, routes)
+	}
+
+	ids := make([]string, 0, len(routes))
+	for id := range routes {
+		if id != routeID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fallbacks = append(fallbacks, routes[id])
+	}
+	return decided, fallbacks, nil
+}
+
+func (s *ExternalRoutingStrategy) decision(ctx context.Context, key string) (string, error) {
+	if routeID, ok := s.cache.get(key, time.Now()); ok {
+		return routeID, nil
+	}
+
+	routeID, err := s.fetchDecision(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	s.cache.put(key, routingDecision{routeID: routeID, expires: now.Add(s.cacheTTL)}, now)
+	return routeID, nil
+}
+
+func (s *ExternalRoutingStrategy) fetchDecision(ctx context.Context, key string) (string, error) {
+	decisionURL, err := url.Parse(s.endpoint)
+	if err != nil {
+		return "", err
+	}
+	query := decisionURL.Query()
+	query.Set("key", key)
+	decisionURL.RawQuery = query.Encode()
+
+	httpReq, err := http.NewRequest(http.MethodGet, decisionURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("decision service responded with status %d", resp.StatusCode)
+	}
+
+	var decision decisionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return "", err
+	}
+	return decision.RouteID, nil
+}
@@ -0,0 +1,166 @@
+package extras_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDecisionServer(decisions map[string]string, latency time.Duration, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(latency)
+		routeID, ok := decisions[r.URL.Query().Get("key")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"route_id": "%s"}`, routeID)
+	}))
+}
+
+func TestExternalRoutingStrategy_Initialize(t *testing.T) {
+	suite := map[string]struct {
+		properties string
+		expected   string
+	}{
+		"ok": {
+			properties: `{"endpoint": "http://decision:8080", "key_header": "X-Tenant", "cache_ttl": "1m", "timeout": "20ms"}`,
+		},
+		"missing endpoint": {
+			properties: `{"key_header": "X-Tenant"}`,
+			expected:   "external routing strategy: missing config (endpoint/key_header)",
+		},
+		"fallback without type": {
+			properties: `{"endpoint": "http://decision:8080", "key_header": "X-Tenant", "fallback": {}}`,
+			expected:   "external routing strategy: fallback type is required",
+		},
+		"invalid ttl": {
+			properties: `{"endpoint": "http://decision:8080", "key_header": "X-Tenant", "cache_ttl": "forever"}`,
+			expected:   `external routing strategy: invalid cache_ttl: time: invalid duration "forever"`,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			strategy := new(extras.ExternalRoutingStrategy)
+			err := strategy.Initialize(json.RawMessage(tt.properties))
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExternalRoutingStrategy_SelectRoute(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+	}
+	fallbackOrder := []string{"route-a", "route-b", "route-c"}
+
+	suite := map[string]struct {
+		tenant        string
+		latency       time.Duration
+		expected      []string
+		calls         int32
+		fallbackCalls int
+	}{
+		"decision received": {
+			tenant:   "gold",
+			expected: []string{"route-c", "route-a", "route-b"},
+			calls:    1,
+		},
+		"missing key, fallback strategy used": {
+			expected:      fallbackOrder,
+			fallbackCalls: 2,
+		},
+		"decision service failed, fallback strategy used": {
+			tenant:        "unknown",
+			expected:      fallbackOrder,
+			calls:         2,
+			fallbackCalls: 2,
+		},
+		"decision service timed out, fallback strategy used": {
+			tenant:        "gold",
+			latency:       50 * time.Millisecond,
+			expected:      fallbackOrder,
+			calls:         2,
+			fallbackCalls: 2,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			server := newDecisionServer(map[string]string{"gold": "route-c"}, tt.latency, &calls)
+			defer server.Close()
+
+			fallback := testutils.NewMockRoutingStrategy(routes, fallbackOrder, 0, nil)
+			strategy := extras.NewExternalRoutingStrategy(server.URL, "X-Tenant", time.Minute, 20*time.Millisecond).
+				WithFallback(fallback)
+
+			// the second call is expected to be served from the cache, if the decision was received
+			for i := 0; i < 2; i++ {
+				req := testUtilsHttp.MockReq("GET", "http://localhost:8080/decision", "")
+				if tt.tenant != "" {
+					req.Request.Header.Set("X-Tenant", tt.tenant)
+				}
+
+				route, fallbacks, err := strategy.SelectRoute(context.Background(), req, routes)
+				require.NoError(t, err)
+
+				actual := []string{route.ID()}
+				for _, fallback := range fallbacks {
+					actual = append(actual, fallback.ID())
+				}
+				assert.Equal(t, tt.expected, actual)
+			}
+			assert.Equal(t, tt.calls, atomic.LoadInt32(&calls))
+			// the fallback strategy is only used, if the decision can't be received
+			fallback.AssertNumberOfCalls(t, "SelectRoute", tt.fallbackCalls)
+		})
+	}
+}
+
+func TestExternalRoutingStrategy_CacheEviction(t *testing.T) {
+	var calls int32
+	decisions := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		decisions[fmt.Sprintf("tenant-%d", i)] = "route-a"
+	}
+	server := newDecisionServer(decisions, 0, &calls)
+	defer server.Close()
+
+	strategy := new(extras.ExternalRoutingStrategy)
+	require.NoError(t, strategy.Initialize(json.RawMessage(
+		fmt.Sprintf(`{"endpoint": %q, "key_header": "X-Tenant", "cache_size": 16}`, server.URL))))
+	routes := map[string]fiber.Component{"route-a": testutils.NewMockComponent("route-a")}
+
+	// the cache is full long before the last tenants, but their decisions are still cached by evicting the older ones
+	for tenant := range decisions {
+		for i := 0; i < 2; i++ {
+			req := testUtilsHttp.MockReq("GET", "http://localhost:8080/decision", "")
+			req.Request.Header.Set("X-Tenant", tenant)
+			route, _, err := strategy.SelectRoute(context.Background(), req, routes)
+			require.NoError(t, err)
+			assert.Equal(t, "route-a", route.ID())
+		}
+	}
+	assert.Equal(t, int32(len(decisions)), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,182 @@
+package extras
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gojek/fiber"
+)
+
+// HeaderRoutingRule maps the values of the request header to the ordered routes: the first one is
+// the primary route, and the rest are the fallbacks
+type HeaderRoutingRule struct {
+	Values []string `json:"values"`
+	Routes []string `json:"routes"`
+}
+
+// HeaderRoutingStrategy routes the requests by the value of the configured request header (or grpc metadata)
+// to the routes of the first rule, that lists the value. The requests without the header, or with the value,
+// that no rule lists, are routed to the default routes. The routes, that the router doesn't have, are skipped.
+//
+// The strategy is static, i.e. its decisions depend only on the header of the request, so the rules are compiled
+// into an immutable routing table, when the strategy is created, and the routes of the request are selected with
+// a single lookup of the table by the value of the header. Compile prunes the table to the routes of the router,
+// once they are set. The routers, created from the config, compile the table of their routes, when the config
+// is loaded
+type HeaderRoutingStrategy struct {
+	fiber.BaseFiberType
+
+	header   string
+	rules    []HeaderRoutingRule
+	defaults []string
+
+	lock  sync.RWMutex
+	table *routingTable
+}
+
+// routingTable is the compiled decisions of the HeaderRoutingStrategy. It keeps the IDs of the routes, so the routes
+// of the request are taken from the routes of the router, i.e. the ones, that have replaced the compiled ones
+type routingTable struct {
+	// routes are the IDs of the ordered routes, keyed by the value of the header
+	routes   map[string][]string
+	defaults []string
+}
+
+type headerRoutingStrategyProperties struct {
+	Header   string              `json:"header"`
+	Rules    []HeaderRoutingRule `json:"rules"`
+	Defaults []string            `json:"defaults"`
+}
+
+// NewHeaderRoutingStrategy is a creator factory for the HeaderRoutingStrategy
+func NewHeaderRoutingStrategy(header string, rules []HeaderRoutingRule, defaults []string) *HeaderRoutingStrategy {
+	strategy := &HeaderRoutingStrategy{
+		header:   header,
+		rules:    rules,
+		defaults: defaults,
+	}
+	strategy.table = strategy.compile(nil)
+	return strategy
+}
+
+// Initialize parses the properties of the strategy:
+//   - header – name of the request header, which value selects the routes (required)
+//   - rules – the `values` of the header and the ordered `routes` of these values
+//   - defaults – the ordered routes of the requests, that no rule matches
+func (s *HeaderRoutingStrategy) Initialize(properties json.RawMessage) error {
+	var props headerRoutingStrategyProperties
+	if err := json.Unmarshal(properties, &props); err != nil {
+		return fmt.Errorf("header routing strategy: failed to parse properties: %s", err)
+	}
+	if props.Header == "" {
+		return errors.New("header routing strategy: missing config (header)")
+	}
+	for idx, rule := range props.Rules {
+		if len(rule.Values) == 0 || len(rule.Routes) == 0 {
+			return fmt.Errorf("header routing strategy: rule [%d] has to list the values and the routes", idx)
+		}
+	}
+
+	strategy := NewHeaderRoutingStrategy(props.Header, props.Rules, props.Defaults)
+	s.header = strategy.header
+	s.rules = strategy.rules
+	s.defaults = strategy.defaults
+	s.lock.Lock()
+	s.table = s.compile(nil)
+	s.lock.Unlock()
+	return nil
+}
+
+// Compile compiles the routing table of the routes of the router, that the routes, it doesn't have, are pruned
+// from. It has to be called again, once the routes of the router are set
+func (s *HeaderRoutingStrategy) Compile(routes map[string]fiber.Component) {
+	table := s.compile(routes)
+	s.lock.Lock()
+	s.table = table
+	s.lock.Unlock()
+}
+
+// SelectRoute looks up the ordered routes of the value of the request header in the routing table, and selects
+// the ones among the given routes, i.e. the routes, that the router hasn't skipped. No route is selected,
+// if none of the routes of the request is given
+func (s *HeaderRoutingStrategy) SelectRoute(
+	_ context.Context,
+	req fiber.Request,
+	routes map[string]fiber.Component,
+) (route fiber.Component, fallbacks []fiber.Component, err error) {
+	s.lock.RLock()
+	table := s.table
+	s.lock.RUnlock()
+	if table == nil {
+		return nil, nil, nil
+	}
+
+	ids, ok := table.routes[headerValue(req, s.header)]
+	if !ok {
+		ids = table.defaults
+	}
+	ordered := make([]fiber.Component, 0, len(ids))
+	for _, id := range ids {
+		if route, ok := routes[id]; ok {
+			ordered = append(ordered, route)
+		}
+	}
+	if len(ordered) == 0 {
+		return nil, nil, nil
+	}
+	return ordered[0], ordered[1:], nil
+}
+
+// compile builds the routing table of the rules, which routes are pruned to the given ones, if they are set
+func (s *HeaderRoutingStrategy) compile(routes map[string]fiber.Component) *routingTable {
+	table := &routingTable{
+		routes:   make(map[string][]string),
+		defaults: existingRoutes(s.defaults, routes),
+	}
+	for _, rule := range s.rules {
+		for _, value := range rule.Values {
+			// the first rule, that lists the value, takes precedence
+			if _, ok := table.routes[value]; !ok && value != "" {
+				table.routes[value] = existingRoutes(rule.Routes, routes)
+			}
+		}
+	}
+	return table
+}
+
+// existingRoutes returns the given IDs of the routes in their order without the repeated ones, and without
+// the routes, that don't exist, if the routes are set
+func existingRoutes(ids []string, routes map[string]fiber.Component) []string {
+	ordered := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := routes[id]; (ok || routes == nil) && !seen[id] {
+			seen[id] = true
+			ordered = append(ordered, id)
+		}
+	}
+	return ordered
+}
+
+// headerValue returns the first value of the request header (or grpc metadata) with the given name
+func headerValue(req fiber.Request, name string) string {
+	header := req.Header()
+	keys := []string{name}
+	// http headers are stored in the canonical form, grpc metadata keys are lower-cased
+	if canonical := http.CanonicalHeaderKey(name); canonical != name {
+		keys = append(keys, canonical)
+	}
+	keys = append(keys, strings.ToLower(name))
+
+	for _, key := range keys {
+		if values := header[key]; len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
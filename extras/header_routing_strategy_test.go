@@ -0,0 +1,208 @@
+package extras_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderRoutingStrategy_Initialize(t *testing.T) {
+	suite := map[string]struct {
+		properties string
+		expected   string
+	}{
+		"ok": {
+			properties: `{"header": "X-Tenant", "rules": [{"values": ["gold"], "routes": ["route-a"]}]}`,
+		},
+		"missing header": {
+			properties: `{"rules": [{"values": ["gold"], "routes": ["route-a"]}]}`,
+			expected:   "header routing strategy: missing config (header)",
+		},
+		"rule without routes": {
+			properties: `{"header": "X-Tenant", "rules": [{"values": ["gold"]}]}`,
+			expected:   "header routing strategy: rule [0] has to list the values and the routes",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			strategy := new(extras.HeaderRoutingStrategy)
+			err := strategy.Initialize(json.RawMessage(tt.properties))
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func headerRoutes(ids ...string) map[string]fiber.Component {
+	routes := make(map[string]fiber.Component)
+	for _, id := range ids {
+		routes[id] = testutils.NewMockComponent(id)
+	}
+	return routes
+}
+
+func tenantRequest(tenant string) fiber.Request {
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/header", "")
+	if tenant != "" {
+		req.Request.Header = http.Header{"X-Tenant": []string{tenant}}
+	}
+	return req
+}
+
+// headerRoutingRules are the rules of the header routing strategy under test
+var headerRoutingRules = []extras.HeaderRoutingRule{
+	{Values: []string{"gold", "platinum"}, Routes: []string{"route-a", "route-b"}},
+	{Values: []string{"silver"}, Routes: []string{"route-b", "route-missing", "route-b"}},
+	// the first rule, that lists the value, takes precedence
+	{Values: []string{"gold"}, Routes: []string{"route-c"}},
+}
+
+// headerRoutingDefaults are the default routes of the header routing strategy under test
+var headerRoutingDefaults = []string{"route-c", "route-a"}
+
+// selectedRoutes returns the IDs of the route and the fallbacks, selected by the strategy
+func selectedRoutes(
+	t *testing.T,
+	strategy fiber.RoutingStrategy,
+	req fiber.Request,
+	routes map[string]fiber.Component,
+) []string {
+	route, fallbacks, err := strategy.SelectRoute(context.Background(), req, routes)
+	require.NoError(t, err)
+
+	var selected []string
+	if route != nil {
+		selected = append(selected, route.ID())
+	}
+	for _, fallback := range fallbacks {
+		selected = append(selected, fallback.ID())
+	}
+	return selected
+}
+
+// scanRules selects the routes of the value by scanning the rules one by one, as the strategy would without
+// the routing table
+func scanRules(value string, routes map[string]fiber.Component) []string {
+	ids := headerRoutingDefaults
+scan:
+	for _, rule := range headerRoutingRules {
+		for _, v := range rule.Values {
+			if value != "" && v == value {
+				ids = rule.Routes
+				break scan
+			}
+		}
+	}
+
+	var selected []string
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if _, ok := routes[id]; ok && !seen[id] {
+			seen[id] = true
+			selected = append(selected, id)
+		}
+	}
+	return selected
+}
+
+func TestHeaderRoutingStrategy_SelectRoute(t *testing.T) {
+
+	suite := map[string]struct {
+		routes   map[string]fiber.Component
+		tenant   string
+		expected []string
+	}{
+		"first rule": {
+			routes:   headerRoutes("route-a", "route-b", "route-c"),
+			tenant:   "platinum",
+			expected: []string{"route-a", "route-b"},
+		},
+		"preceding rule": {
+			routes:   headerRoutes("route-a", "route-b", "route-c"),
+			tenant:   "gold",
+			expected: []string{"route-a", "route-b"},
+		},
+		"missing and repeated routes": {
+			routes:   headerRoutes("route-a", "route-b", "route-c"),
+			tenant:   "silver",
+			expected: []string{"route-b"},
+		},
+		"unknown value": {
+			routes:   headerRoutes("route-a", "route-b", "route-c"),
+			tenant:   "bronze",
+			expected: []string{"route-c", "route-a"},
+		},
+		"no header": {
+			routes:   headerRoutes("route-a", "route-b", "route-c"),
+			expected: []string{"route-c", "route-a"},
+		},
+		"subset of the routes": {
+			routes:   headerRoutes("route-b", "route-c"),
+			tenant:   "gold",
+			expected: []string{"route-b"},
+		},
+		"no matching routes": {
+			routes: headerRoutes("route-b"),
+			tenant: "bronze",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			// the routes are selected the same way, whether the table is pruned to the routes of the router or not
+			uncompiled := extras.NewHeaderRoutingStrategy("X-Tenant", headerRoutingRules, headerRoutingDefaults)
+			compiled := extras.NewHeaderRoutingStrategy("X-Tenant", headerRoutingRules, headerRoutingDefaults)
+			compiled.Compile(headerRoutes("route-a", "route-b", "route-c"))
+
+			for _, strategy := range []*extras.HeaderRoutingStrategy{uncompiled, compiled} {
+				assert.Equal(t, tt.expected, selectedRoutes(t, strategy, tenantRequest(tt.tenant), tt.routes))
+			}
+		})
+	}
+}
+
+func TestHeaderRoutingStrategy_ScanRules(t *testing.T) {
+	strategy := extras.NewHeaderRoutingStrategy("X-Tenant", headerRoutingRules, headerRoutingDefaults)
+	strategy.Compile(headerRoutes("route-a", "route-b", "route-c"))
+
+	// the routing table selects the same routes as the scan of the rules, for each subset of the routes,
+	// that the router may select from, e.g. while some of them are unhealthy
+	ids := []string{"route-a", "route-b", "route-c"}
+	for subset := 0; subset < 1<<len(ids); subset++ {
+		var selectable []string
+		for idx, id := range ids {
+			if subset&(1<<idx) != 0 {
+				selectable = append(selectable, id)
+			}
+		}
+		routes := headerRoutes(selectable...)
+		for _, tenant := range []string{"gold", "platinum", "silver", "bronze", ""} {
+			assert.Equal(t, scanRules(tenant, routes), selectedRoutes(t, strategy, tenantRequest(tenant), routes),
+				"tenant [%s], routes %v", tenant, selectable)
+		}
+	}
+}
+
+func TestHeaderRoutingStrategy_ReplacedRoutes(t *testing.T) {
+	strategy := extras.NewHeaderRoutingStrategy("X-Tenant", headerRoutingRules, headerRoutingDefaults)
+	strategy.Compile(headerRoutes("route-a", "route-b", "route-c"))
+
+	// the routes, that have replaced the compiled ones with the same IDs, are selected
+	routes := headerRoutes("route-a", "route-b", "route-c")
+	route, fallbacks, err := strategy.SelectRoute(context.Background(), tenantRequest("gold"), routes)
+	require.NoError(t, err)
+	assert.Same(t, routes["route-a"], route)
+	assert.Equal(t, []fiber.Component{routes["route-b"]}, fallbacks)
+}
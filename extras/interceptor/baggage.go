@@ -0,0 +1,146 @@
+package interceptor
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/protocol"
+)
+
+const (
+	// BaggageHeader is the name of the http header (and grpc metadata key), that carries the baggage
+	BaggageHeader = "baggage"
+
+	// DefaultBaggageMaxMembers is the maximum number of baggage members, as recommended by W3C
+	DefaultBaggageMaxMembers = 180
+	// DefaultBaggageMaxBytes is the maximum size of the baggage header, as recommended by W3C
+	DefaultBaggageMaxBytes = 8192
+)
+
+// CtxBaggageKey is used to store the baggage of the incoming request in the context
+var CtxBaggageKey fiber.CtxKey = "CTX_BAGGAGE"
+
+// BaggageFromContext returns the baggage members, that were extracted from the incoming request
+// by the BaggageInterceptor, or nil if there is no baggage in the context
+func BaggageFromContext(ctx context.Context) map[string]string {
+	if baggage, ok := ctx.Value(CtxBaggageKey).(map[string]string); ok {
+		return baggage
+	}
+	return nil
+}
+
+// BaggageOptions captures the limits applied to the propagated baggage
+type BaggageOptions struct {
+	// MaxMembers is the maximum number of baggage members to be propagated
+	MaxMembers int
+	// MaxBytes is the maximum size of the propagated baggage header
+	MaxBytes int
+}
+
+// NewBaggageInterceptor is a creator factory for a BaggageInterceptor. Zero limits in
+// the options are replaced with the defaults recommended by W3C
+func NewBaggageInterceptor(options BaggageOptions) fiber.Interceptor {
+	if options.MaxMembers <= 0 {
+		options.MaxMembers = DefaultBaggageMaxMembers
+	}
+	if options.MaxBytes <= 0 {
+		options.MaxBytes = DefaultBaggageMaxBytes
+	}
+	return &BaggageInterceptor{options: options}
+}
+
+// BaggageInterceptor extracts the W3C baggage from the incoming request (the `baggage` http header
+// or grpc metadata), makes its members available in the context for the routing strategies and other
+// interceptors, and trims the propagated baggage, so it doesn't exceed the configured limits. The baggage
+// is trimmed on the copy of the request, so the incoming one, that may be shared by the routes of the
+// fan-out, is not modified
+type BaggageInterceptor struct {
+	fiber.NoopAfterDispatchInterceptor
+	fiber.NoopAfterCompletionInterceptor
+	options BaggageOptions
+}
+
+// InterceptRequest returns the copy of the request with the trimmed baggage, or the request as it is,
+// if its baggage is within the limits
+func (i *BaggageInterceptor) InterceptRequest(_ context.Context, req fiber.Request) (fiber.Request, error) {
+	values, members, _ := i.parse(req)
+	if values == nil {
+		return req, nil
+	}
+	outgoing := strings.Join(members, ",")
+	if len(values) == 1 && values[0] == outgoing {
+		return req, nil
+	}
+
+	trimmed, err := req.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		fiber.DeleteRequestHeader(trimmed, BaggageHeader)
+	} else {
+		fiber.SetRequestHeader(trimmed, BaggageHeader, outgoing)
+	}
+	return trimmed, nil
+}
+
+// BeforeDispatch parses the baggage of the request and stores its members in the context
+func (i *BaggageInterceptor) BeforeDispatch(ctx context.Context, req fiber.Request) context.Context {
+	if _, _, baggage := i.parse(req); len(baggage) > 0 {
+		return context.WithValue(ctx, CtxBaggageKey, baggage)
+	}
+	return ctx
+}
+
+// parse returns the values of the baggage header of the request, or nil, if it has none, together with
+// its valid members within the limits, and their keys and values
+func (i *BaggageInterceptor) parse(req fiber.Request) ([]string, []string, map[string]string) {
+	header := req.Header()
+	if header == nil {
+		return nil, nil, nil
+	}
+
+	key := BaggageHeader
+	if req.Protocol() == protocol.HTTP {
+		key = http.CanonicalHeaderKey(BaggageHeader)
+	}
+	values, ok := header[key]
+	if !ok {
+		return nil, nil, nil
+	}
+
+	var (
+		members  []string
+		size     int
+		baggage  = make(map[string]string)
+		incoming = strings.Split(strings.Join(values, ","), ",")
+	)
+	for _, member := range incoming {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		// the separator between the members is also counted towards the limit
+		if len(members) >= i.options.MaxMembers || size+len(member)+len(members) > i.options.MaxBytes {
+			break
+		}
+
+		// member properties (after ';') are propagated, but not exposed in the context
+		keyValue := strings.SplitN(strings.SplitN(member, ";", 2)[0], "=", 2)
+		if len(keyValue) != 2 || strings.TrimSpace(keyValue[0]) == "" {
+			// invalid members are not propagated
+			continue
+		}
+		value := strings.TrimSpace(keyValue[1])
+		if decoded, err := url.PathUnescape(value); err == nil {
+			value = decoded
+		}
+		baggage[strings.TrimSpace(keyValue[0])] = value
+		members = append(members, member)
+		size += len(member)
+	}
+	return values, members, baggage
+}
@@ -0,0 +1,83 @@
+package interceptor_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gojek/fiber/extras/interceptor"
+	"github.com/gojek/fiber/grpc"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestBaggageInterceptor(t *testing.T) {
+	suite := map[string]struct {
+		options          interceptor.BaggageOptions
+		baggage          []string
+		expectedBaggage  map[string]string
+		expectedOutgoing []string
+	}{
+		"no baggage": {},
+		"baggage members": {
+			baggage:          []string{"tenant=gold,experiment=exp%201;ttl=10"},
+			expectedBaggage:  map[string]string{"tenant": "gold", "experiment": "exp 1"},
+			expectedOutgoing: []string{"tenant=gold,experiment=exp%201;ttl=10"},
+		},
+		"invalid members are dropped": {
+			baggage:          []string{"tenant=gold, invalid ,=empty", "treatment=a"},
+			expectedBaggage:  map[string]string{"tenant": "gold", "treatment": "a"},
+			expectedOutgoing: []string{"tenant=gold,treatment=a"},
+		},
+		"members limit": {
+			options:          interceptor.BaggageOptions{MaxMembers: 1},
+			baggage:          []string{"tenant=gold,experiment=exp1"},
+			expectedBaggage:  map[string]string{"tenant": "gold"},
+			expectedOutgoing: []string{"tenant=gold"},
+		},
+		"size limit": {
+			options:          interceptor.BaggageOptions{MaxBytes: 20},
+			baggage:          []string{"tenant=gold,experiment=exp1"},
+			expectedBaggage:  map[string]string{"tenant": "gold"},
+			expectedOutgoing: []string{"tenant=gold"},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name+" | http", func(t *testing.T) {
+			req := testUtilsHttp.MockReq("GET", "http://localhost:8080/baggage", "")
+			if tt.baggage != nil {
+				req.Request.Header = http.Header{"Baggage": tt.baggage}
+			}
+
+			baggage := interceptor.NewBaggageInterceptor(tt.options).(*interceptor.BaggageInterceptor)
+			intercepted, err := baggage.InterceptRequest(context.Background(), req)
+			require.NoError(t, err)
+			ctx := baggage.BeforeDispatch(context.Background(), intercepted)
+
+			assert.Equal(t, tt.expectedBaggage, interceptor.BaggageFromContext(ctx))
+			assert.Equal(t, tt.expectedOutgoing, http.Header(intercepted.Header()).Values("Baggage"))
+			// the incoming request is not modified
+			assert.Equal(t, tt.baggage, req.Request.Header.Values("Baggage"))
+		})
+
+		t.Run(name+" | grpc", func(t *testing.T) {
+			req := grpc.NewRequest(metadata.MD{}, nil, nil)
+			if tt.baggage != nil {
+				req.Metadata.Set(interceptor.BaggageHeader, tt.baggage...)
+			}
+
+			baggage := interceptor.NewBaggageInterceptor(tt.options).(*interceptor.BaggageInterceptor)
+			intercepted, err := baggage.InterceptRequest(context.Background(), req)
+			require.NoError(t, err)
+			ctx := baggage.BeforeDispatch(context.Background(), intercepted)
+
+			assert.Equal(t, tt.expectedBaggage, interceptor.BaggageFromContext(ctx))
+			assert.Equal(t, tt.expectedOutgoing, metadata.MD(intercepted.Header()).Get(interceptor.BaggageHeader))
+			// the incoming request is not modified
+			assert.Equal(t, tt.baggage, req.Metadata.Get(interceptor.BaggageHeader))
+		})
+	}
+}
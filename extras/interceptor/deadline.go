@@ -0,0 +1,78 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gojek/fiber"
+)
+
+// DefaultDeadlineWarningThreshold is the fraction of the deadline, after which the warning is emitted,
+// if the threshold supplied to the NewDeadlineWarningInterceptor is not in the (0, 1) range
+const DefaultDeadlineWarningThreshold = 0.8
+
+// DeadlineWarningHook is called when the request has consumed the threshold fraction of its deadline,
+// but hasn't completed yet. elapsed is the time since the component started dispatching the request,
+// and budget is the total time the component had, until the deadline of the request
+type DeadlineWarningHook func(ctx context.Context, req fiber.Request, elapsed time.Duration, budget time.Duration)
+
+// NewDeadlineWarningInterceptor is a creator factory for a DeadlineWarningInterceptor.
+// It returns an error, if the hook is nil
+func NewDeadlineWarningInterceptor(threshold float64, hook DeadlineWarningHook) (fiber.Interceptor, error) {
+	if hook == nil {
+		return nil, errors.New("deadline warning hook can not be nil")
+	}
+	if threshold <= 0 || threshold >= 1 {
+		threshold = DefaultDeadlineWarningThreshold
+	}
+	return &DeadlineWarningInterceptor{
+		threshold: threshold,
+		hook:      hook,
+	}, nil
+}
+
+// DeadlineWarningInterceptor watches the requests with a deadline in the context, and calls the
+// hook (i.e. to log a warning or to submit a metric) once a request has consumed the threshold
+// fraction of its deadline without completion. The request itself is not affected
+type DeadlineWarningInterceptor struct {
+	fiber.NoopAfterDispatchInterceptor
+	threshold float64
+	hook      DeadlineWarningHook
+}
+
+// BeforeDispatch starts the deadline watcher, if the request context has a deadline
+func (i *DeadlineWarningInterceptor) BeforeDispatch(ctx context.Context, req fiber.Request) context.Context {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx
+	}
+	start := time.Now()
+	budget := deadline.Sub(start)
+	if budget <= 0 {
+		return ctx
+	}
+
+	done := make(chan struct{})
+	go func() {
+		timer := time.NewTimer(time.Duration(float64(budget) * i.threshold))
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			i.hook(ctx, req, time.Since(start), budget)
+		case <-done:
+		case <-ctx.Done():
+		}
+	}()
+
+	// the interceptor itself is used as the key, so several watchers can share the context
+	return context.WithValue(ctx, i, done)
+}
+
+// AfterCompletion stops the deadline watcher of the completed request
+func (i *DeadlineWarningInterceptor) AfterCompletion(ctx context.Context, req fiber.Request, queue fiber.ResponseQueue) {
+	if done, ok := ctx.Value(i).(chan struct{}); ok {
+		close(done)
+	}
+}
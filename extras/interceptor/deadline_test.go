@@ -0,0 +1,71 @@
+package interceptor_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras/interceptor"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlineWarningInterceptor(t *testing.T) {
+	suite := map[string]struct {
+		timeout      time.Duration
+		completeIn   time.Duration
+		expectedWarn bool
+	}{
+		"no deadline": {
+			completeIn: 60 * time.Millisecond,
+		},
+		"completed before threshold": {
+			timeout:    100 * time.Millisecond,
+			completeIn: 10 * time.Millisecond,
+		},
+		"threshold exceeded": {
+			timeout:      100 * time.Millisecond,
+			completeIn:   80 * time.Millisecond,
+			expectedWarn: true,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			warnings := make(chan time.Duration, 1)
+			hook := func(ctx context.Context, req fiber.Request, elapsed time.Duration, budget time.Duration) {
+				warnings <- elapsed
+			}
+			deadlineInterceptor, err := interceptor.NewDeadlineWarningInterceptor(0.5, hook)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			req := testUtilsHttp.MockReq("GET", "http://localhost:8080/deadline", "")
+
+			ctx = deadlineInterceptor.BeforeDispatch(ctx, req)
+			time.Sleep(tt.completeIn)
+			deadlineInterceptor.AfterCompletion(ctx, req, nil)
+
+			select {
+			case elapsed := <-warnings:
+				assert.True(t, tt.expectedWarn, "unexpected deadline warning")
+				assert.GreaterOrEqual(t, int64(elapsed), int64(tt.timeout/2))
+			case <-time.After(tt.timeout):
+				assert.False(t, tt.expectedWarn, "expected deadline warning")
+			}
+		})
+	}
+}
+
+func TestNewDeadlineWarningInterceptor_NilHook(t *testing.T) {
+	deadlineInterceptor, err := interceptor.NewDeadlineWarningInterceptor(0.5, nil)
+	assert.Nil(t, deadlineInterceptor)
+	assert.EqualError(t, err, "deadline warning hook can not be nil")
+}
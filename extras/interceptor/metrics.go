@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/protocol"
 )
 
 // StatsdClient is an interface for a stats listener
@@ -15,6 +16,22 @@ type StatsdClient interface {
 	Timing(string, interface{})
 }
 
+// DispatchMetrics records the outcomes of the dispatches of the routes. RecordDispatch is called
+// concurrently, once per route attempt, so the implementations must be safe for concurrent use
+type DispatchMetrics interface {
+	fiber.Type
+	RecordDispatch(routeID string, protocol protocol.Protocol, statusCode int, latency time.Duration)
+}
+
+// NoopDispatchMetrics is the DispatchMetrics, that records nothing
+type NoopDispatchMetrics struct {
+	fiber.BaseFiberType
+}
+
+// RecordDispatch is an empty method
+func (m *NoopDispatchMetrics) RecordDispatch(string, protocol.Protocol, int, time.Duration) {
+}
+
 // MetricsKey is an alias for a string
 type MetricsKey string
 
@@ -30,10 +47,23 @@ func NewMetricsInterceptor(client StatsdClient) fiber.Interceptor {
 	}
 }
 
+// NewDispatchMetricsInterceptor creates a new MetricsInterceptor, that records the outcome of each
+// dispatch of the component it's added to into the given DispatchMetrics. Routers initialized
+// from the config add it to their routes, if the metrics are configured
+func NewDispatchMetricsInterceptor(metrics DispatchMetrics) fiber.Interceptor {
+	if metrics == nil {
+		metrics = &NoopDispatchMetrics{}
+	}
+	return &MetricsInterceptor{
+		metrics: metrics,
+	}
+}
+
 // MetricsInterceptor is an interceptor to log metrics
 type MetricsInterceptor struct {
 	fiber.NoopAfterDispatchInterceptor
-	statsd StatsdClient
+	statsd  StatsdClient
+	metrics DispatchMetrics
 }
 
 func (i *MetricsInterceptor) operationName(ctx context.Context, req fiber.Request, suffix string) string {
@@ -43,15 +73,53 @@ func (i *MetricsInterceptor) operationName(ctx context.Context, req fiber.Reques
 
 // BeforeDispatch records count of the requests through a component and the request start time
 func (i *MetricsInterceptor) BeforeDispatch(ctx context.Context, req fiber.Request) context.Context {
-	i.statsd.Increment(i.operationName(ctx, req, "count"))
+	if i.statsd != nil {
+		i.statsd.Increment(i.operationName(ctx, req, "count"))
+	} else if _, noop := i.metrics.(*NoopDispatchMetrics); noop {
+		return ctx
+	}
 
 	ctx = context.WithValue(ctx, CtxDispatchStartTimeKey, time.Now())
 	return ctx
 }
 
-// AfterCompletion computes and submits the request completion time
+// AfterCompletion computes and submits the request completion time. The DispatchMetrics also
+// record the status code of the response. If the component has responded with several responses
+// (e.g. a stream), the status code of the first failed one is recorded
 func (i *MetricsInterceptor) AfterCompletion(ctx context.Context, req fiber.Request, queue fiber.ResponseQueue) {
-	if startTime, ok := ctx.Value(CtxDispatchStartTimeKey).(time.Time); ok {
+	startTime, ok := ctx.Value(CtxDispatchStartTimeKey).(time.Time)
+	if !ok {
+		return
+	}
+	if i.statsd != nil {
 		i.statsd.Timing(i.operationName(ctx, req, "timing"), int(time.Since(startTime)/time.Millisecond))
 	}
+	if i.metrics != nil {
+		i.recordDispatch(ctx, req, queue, time.Since(startTime))
+	}
+}
+
+func (i *MetricsInterceptor) recordDispatch(
+	ctx context.Context,
+	req fiber.Request,
+	queue fiber.ResponseQueue,
+	latency time.Duration,
+) {
+	routeID, _ := ctx.Value(fiber.CtxComponentIDKey).(string)
+
+	var status fiber.Response
+	for resp := range queue.Iter() {
+		if status == nil || (status.IsSuccess() && !resp.IsSuccess()) {
+			status = resp
+		}
+	}
+	if status != nil {
+		i.metrics.RecordDispatch(routeID, req.Protocol(), status.StatusCode(), latency)
+	}
+}
+
+// RecordTenantDispatch counts the dispatches of the requests of the tenant by the route, by their status codes
+// (see fiber.TenantDispatchObserver)
+func (i *MetricsInterceptor) RecordTenantDispatch(routeID string, tenant string, _ protocol.Protocol, statusCode int) {
+	i.statsd.Increment(fmt.Sprintf("fiber.%s.tenant.%s.%d", routeID, tenant, statusCode))
 }
@@ -0,0 +1,106 @@
+package interceptor_test
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/extras/interceptor"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dispatchRecord struct {
+	routeID    string
+	protocol   protocol.Protocol
+	statusCode int
+}
+
+type mockMetrics struct {
+	fiber.BaseFiberType
+	records chan dispatchRecord
+}
+
+func (m *mockMetrics) RecordDispatch(routeID string, protocol protocol.Protocol, statusCode int, _ time.Duration) {
+	m.records <- dispatchRecord{routeID: routeID, protocol: protocol, statusCode: statusCode}
+}
+
+func TestDispatchMetricsInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		routes   map[string]fiber.Response
+		strategy []string
+		expected []dispatchRecord
+	}{
+		{
+			name: "primary route succeeded",
+			routes: map[string]fiber.Response{
+				"route-a": testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil),
+				"route-b": testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil),
+			},
+			strategy: []string{"route-a", "route-b"},
+			expected: []dispatchRecord{
+				{routeID: "route-a", protocol: protocol.HTTP, statusCode: http.StatusOK},
+			},
+		},
+		{
+			name: "primary route failed, fallback succeeded",
+			routes: map[string]fiber.Response{
+				"route-a": testUtilsHttp.MockResp(
+					http.StatusServiceUnavailable, "A-NOK", nil, fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+				"route-b": testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil),
+			},
+			strategy: []string{"route-a", "route-b"},
+			expected: []dispatchRecord{
+				{routeID: "route-a", protocol: protocol.HTTP, statusCode: http.StatusServiceUnavailable},
+				{routeID: "route-b", protocol: protocol.HTTP, statusCode: http.StatusOK},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := &mockMetrics{records: make(chan dispatchRecord, len(tt.routes))}
+			routes := make(map[string]fiber.Component)
+			for routeID, resp := range tt.routes {
+				resp := resp
+				caller, err := fiber.NewCaller(routeID, fiber.DispatcherFunc(
+					func(context.Context, fiber.Request) fiber.Response {
+						return resp
+					}))
+				require.NoError(t, err)
+				caller.AddInterceptor(false, interceptor.NewDispatchMetricsInterceptor(metrics))
+				routes[routeID] = caller
+			}
+
+			router := fiber.NewLazyRouter("lazy-router")
+			router.SetRoutes(routes)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(routes, tt.strategy, 0, nil))
+
+			req := testUtilsHttp.MockReq("GET", "http://localhost:8080/lazy-router", "")
+			for range router.Dispatch(context.Background(), req).Iter() {
+			}
+
+			var records []dispatchRecord
+			for range tt.expected {
+				select {
+				case record := <-metrics.records:
+					records = append(records, record)
+				case <-time.After(time.Second):
+					require.FailNow(t, "dispatch is not recorded")
+				}
+			}
+			sort.Slice(records, func(i, j int) bool {
+				return records[i].routeID < records[j].routeID
+			})
+			assert.Equal(t, tt.expected, records)
+		})
+	}
+}
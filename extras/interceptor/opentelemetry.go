@@ -0,0 +1,173 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/protocol"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// InstrumentationName is the name of the OpenTelemetry tracer, used by the OpenTelemetryInterceptor
+const InstrumentationName = "github.com/gojek/fiber"
+
+// Attributes of the spans, created by the OpenTelemetryInterceptor
+const (
+	AttributeComponentID     = attribute.Key("fiber.component.id")
+	AttributeComponentKind   = attribute.Key("fiber.component.kind")
+	AttributeProtocol        = attribute.Key("fiber.protocol")
+	AttributeRoutingStrategy = attribute.Key("fiber.routing_strategy")
+	AttributeStatusCode      = attribute.Key("fiber.status_code")
+)
+
+// OpenTelemetryOptions captures the OpenTelemetry setup of the OpenTelemetryInterceptor
+type OpenTelemetryOptions struct {
+	// TracerProvider provides the tracer of the spans. By default, the no-op tracer is used
+	TracerProvider trace.TracerProvider
+	// Propagator injects the trace context into the requests to the backends, and extracts
+	// the trace context of the incoming request. By default, the W3C trace context is used
+	Propagator propagation.TextMapPropagator
+}
+
+// NewOpenTelemetryInterceptor is a creator factory for an OpenTelemetryInterceptor
+func NewOpenTelemetryInterceptor(options OpenTelemetryOptions) fiber.Interceptor {
+	if options.TracerProvider == nil {
+		options.TracerProvider = trace.NewNoopTracerProvider()
+	}
+	if options.Propagator == nil {
+		options.Propagator = propagation.TraceContext{}
+	}
+	return &OpenTelemetryInterceptor{
+		tracer:     options.TracerProvider.Tracer(InstrumentationName),
+		propagator: options.Propagator,
+	}
+}
+
+// OpenTelemetryInterceptor creates an OpenTelemetry span for each dispatch of the component. When it's added
+// recursively to a router, the span of the router has the child spans of each route attempt. The callers
+// propagate the trace context to the backends in the http headers or grpc metadata. The spans of the dispatches,
+// that have failed (i.e. with ErrServiceUnavailable or a non-OK grpc status), are marked as errored
+type OpenTelemetryInterceptor struct {
+	fiber.NoopAfterDispatchInterceptor
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func (i *OpenTelemetryInterceptor) operationName(ctx context.Context, req fiber.Request) string {
+	componentID := ctx.Value(fiber.CtxComponentIDKey)
+	return fmt.Sprintf("[%s] %s", componentID, req.OperationName())
+}
+
+// BeforeDispatch starts the span of the dispatch. If there is no span in the context yet, the trace context
+// of the incoming request is used as the parent. Callers inject the trace context into the outgoing request
+func (i *OpenTelemetryInterceptor) BeforeDispatch(ctx context.Context, req fiber.Request) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() && req.Header() != nil {
+		ctx = i.propagator.Extract(ctx, headerCarrier(req))
+	}
+
+	kind, _ := ctx.Value(fiber.CtxComponentKindKey).(fiber.ComponentKind)
+	componentID, _ := ctx.Value(fiber.CtxComponentIDKey).(string)
+	attributes := []attribute.KeyValue{
+		AttributeComponentID.String(componentID),
+		AttributeComponentKind.String(string(kind)),
+		AttributeProtocol.String(string(req.Protocol())),
+	}
+	if strategy, ok := ctx.Value(fiber.CtxRoutingStrategyKey).(string); ok {
+		attributes = append(attributes, AttributeRoutingStrategy.String(strategy))
+	}
+
+	spanKind := trace.SpanKindInternal
+	if kind == fiber.CallerKind {
+		spanKind = trace.SpanKindClient
+	}
+	ctx, span := i.tracer.Start(ctx, i.operationName(ctx, req),
+		trace.WithSpanKind(spanKind),
+		trace.WithAttributes(attributes...))
+
+	if kind == fiber.CallerKind {
+		ctx = i.inject(ctx, req)
+	}
+	// the interceptor itself is used as the key, so it doesn't clash with other interceptors
+	return context.WithValue(ctx, i, span)
+}
+
+// AfterCompletion records the status code of the response and ends the span. If the component has
+// responded with several responses (e.g. a stream), the status code of the first failed one is recorded
+func (i *OpenTelemetryInterceptor) AfterCompletion(ctx context.Context, req fiber.Request, queue fiber.ResponseQueue) {
+	span, ok := ctx.Value(i).(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	var status fiber.Response
+	for resp := range queue.Iter() {
+		if status == nil || (status.IsSuccess() && !resp.IsSuccess()) {
+			status = resp
+		}
+	}
+	if status == nil {
+		return
+	}
+
+	span.SetAttributes(AttributeStatusCode.Int(status.StatusCode()))
+	if !status.IsSuccess() {
+		span.SetStatus(codes.Error, string(status.Payload()))
+	}
+}
+
+// inject propagates the trace context of the span to the backend: in the http headers of the request,
+// or in the grpc metadata of the outgoing context, since the grpc requests are shared between the routes
+func (i *OpenTelemetryInterceptor) inject(ctx context.Context, req fiber.Request) context.Context {
+	if req.Protocol() == protocol.HTTP {
+		if header := req.Header(); header != nil {
+			i.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+		}
+		return ctx
+	}
+
+	carrier := propagation.MapCarrier{}
+	i.propagator.Inject(ctx, carrier)
+	for key, value := range carrier {
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+	return ctx
+}
+
+// headerCarrier returns the carrier of the trace context of the incoming request
+func headerCarrier(req fiber.Request) propagation.TextMapCarrier {
+	if req.Protocol() == protocol.HTTP {
+		return propagation.HeaderCarrier(http.Header(req.Header()))
+	}
+	return metadataCarrier(req.Header())
+}
+
+// metadataCarrier reads the trace context from the grpc metadata, which keys are lower-cased
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set sets the metadata value. The interceptor injects the trace context into the outgoing context instead
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, strings.ToLower(key))
+	}
+	return keys
+}
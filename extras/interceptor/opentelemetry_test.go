@@ -0,0 +1,173 @@
+package interceptor_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/extras/interceptor"
+	fiberGRPC "github.com/gojek/fiber/grpc"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	grpcCodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// traceRecordingDispatcher responds with the given response and records the trace context,
+// propagated to the backend
+type traceRecordingDispatcher struct {
+	response fiber.Response
+
+	lock        sync.Mutex
+	traceparent string
+}
+
+func (d *traceRecordingDispatcher) Do(ctx context.Context, req fiber.Request) fiber.Response {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if req.Protocol() == protocol.HTTP {
+		d.traceparent = http.Header(req.Header()).Get("traceparent")
+	} else if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get("traceparent")) > 0 {
+		d.traceparent = md.Get("traceparent")[0]
+	}
+	return d.response
+}
+
+func (d *traceRecordingDispatcher) propagated() trace.SpanContext {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": d.traceparent})
+	return trace.SpanContextFromContext(ctx)
+}
+
+func TestOpenTelemetryInterceptor(t *testing.T) {
+	grpcUnavailable := status.New(grpcCodes.Unavailable, "unavailable")
+	suite := map[string]struct {
+		router    func() fiber.Router
+		request   fiber.Request
+		responses map[string]fiber.Response
+		strategy  string
+		errored   map[string]int
+	}{
+		"http lazy router": {
+			router:  func() fiber.Router { return fiber.NewLazyRouter("router") },
+			request: testUtilsHttp.MockReq("GET", "http://localhost:8080/tracing", ""),
+			responses: map[string]fiber.Response{
+				"route-a": testUtilsHttp.MockResp(
+					http.StatusServiceUnavailable, "", nil, fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+				"route-b": testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil),
+			},
+			strategy: "*testutils.MockRoutingStrategy",
+			errored:  map[string]int{"route-a": http.StatusServiceUnavailable},
+		},
+		"grpc eager router": {
+			router:  func() fiber.Router { return fiber.NewEagerRouter("router") },
+			request: &fiberGRPC.Request{Metadata: metadata.MD{}},
+			responses: map[string]fiber.Response{
+				"route-a": &fiberGRPC.Response{Metadata: metadata.MD{}, Status: *grpcUnavailable},
+				"route-b": &fiberGRPC.Response{Metadata: metadata.MD{}, Status: *status.New(grpcCodes.OK, "")},
+			},
+			strategy: "*testutils.MockRoutingStrategy",
+			errored:  map[string]int{"route-a": int(grpcCodes.Unavailable)},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			dispatchers := make(map[string]*traceRecordingDispatcher)
+			routes := make(map[string]fiber.Component)
+			for routeID, resp := range tt.responses {
+				dispatchers[routeID] = &traceRecordingDispatcher{response: resp}
+				caller, err := fiber.NewCaller(routeID, dispatchers[routeID])
+				require.NoError(t, err)
+				routes[routeID] = caller
+			}
+
+			router := tt.router()
+			router.SetRoutes(routes)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+			router.AddInterceptor(true, interceptor.NewOpenTelemetryInterceptor(interceptor.OpenTelemetryOptions{
+				TracerProvider: provider,
+			}))
+
+			for range router.Dispatch(context.Background(), tt.request).Iter() {
+			}
+			require.Eventually(t, func() bool {
+				return len(recorder.Ended()) >= len(routes)+1
+			}, time.Second, 10*time.Millisecond)
+
+			byComponent := make(map[string]sdktrace.ReadOnlySpan)
+			for _, span := range recorder.Ended() {
+				for _, attr := range span.Attributes() {
+					if attr.Key == interceptor.AttributeComponentID {
+						byComponent[attr.Value.AsString()] = span
+					}
+				}
+			}
+
+			routerSpan, ok := byComponent["router"]
+			require.True(t, ok, "router span is not recorded")
+			assert.Contains(t, routerSpan.Attributes(), interceptor.AttributeRoutingStrategy.String(tt.strategy))
+			assert.Contains(t, routerSpan.Attributes(), interceptor.AttributeProtocol.String(string(tt.request.Protocol())))
+			assert.Equal(t, codes.Unset, routerSpan.Status().Code)
+
+			for routeID, dispatcher := range dispatchers {
+				span, ok := byComponent[routeID]
+				require.True(t, ok, "route span is not recorded: %s", routeID)
+				assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+				assert.Equal(t, routerSpan.SpanContext().TraceID(), span.Parent().TraceID())
+
+				code, failed := tt.errored[routeID]
+				if failed {
+					assert.Equal(t, codes.Error, span.Status().Code)
+				} else {
+					code = tt.responses[routeID].StatusCode()
+					assert.Equal(t, codes.Unset, span.Status().Code)
+				}
+				assert.Contains(t, span.Attributes(), interceptor.AttributeStatusCode.Int(code))
+
+				// the backend joins the trace as the child of the route span
+				propagated := dispatcher.propagated()
+				assert.Equal(t, span.SpanContext().TraceID(), propagated.TraceID())
+				assert.Equal(t, span.SpanContext().SpanID(), propagated.SpanID())
+			}
+		})
+	}
+}
+
+func TestOpenTelemetryInterceptor_IncomingTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otelInterceptor := interceptor.NewOpenTelemetryInterceptor(interceptor.OpenTelemetryOptions{
+		TracerProvider: provider,
+	})
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/tracing", "")
+	req.Header()["Traceparent"] = []string{traceparent}
+
+	ctx := otelInterceptor.BeforeDispatch(context.Background(), req)
+	otelInterceptor.AfterCompletion(ctx, req, fiber.NewResponseQueueFromResponses())
+
+	require.Len(t, recorder.Ended(), 1)
+	parent := recorder.Ended()[0].Parent()
+	assert.True(t, parent.IsRemote())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", parent.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", parent.SpanID().String())
+}
@@ -0,0 +1,236 @@
+package extras
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gojek/fiber"
+)
+
+const (
+	// DefaultLatencyDecayFactor is the default weight of the latest latency sample in the moving average
+	DefaultLatencyDecayFactor = 0.2
+	// DefaultLatencyWarmUpSamples is the default number of samples to be observed from each route,
+	// before the routes are ordered by their latency
+	DefaultLatencyWarmUpSamples = 10
+
+	// latencyFailurePenalty is the factor of the moving average of the route's latency, that the failed
+	// dispatch is recorded with at least, so the route, that fails fast, doesn't look like the fastest one
+	latencyFailurePenalty = 2
+)
+
+// LatencyAwareRoutingStrategy orders the routes ascending by the exponentially-weighted moving
+// average (EWMA) of their observed latencies, so the fastest route is selected as the primary one.
+// Until every route has at least the warm-up number of samples, the routes are ordered round-robin.
+// The failed dispatches are penalized, so their samples are at least twice the current average of the route.
+//
+// The latencies are observed by the interceptor returned from the Interceptor method, that has to
+// be added to each route of the router. Routers initialized from the config do it automatically
+type LatencyAwareRoutingStrategy struct {
+	fiber.BaseFiberType
+
+	decay   float64
+	warmUp  int64
+	next    uint32
+	latency sync.Map // route ID -> *latencyStats
+}
+
+type latencyStats struct {
+	// ewma stores the bits of the float64 moving average in nanoseconds
+	ewma    uint64
+	samples int64
+}
+
+type latencyAwareRoutingStrategyProperties struct {
+	DecayFactor   *float64 `json:"decay_factor"`
+	WarmUpSamples *int     `json:"warm_up_samples"`
+}
+
+// NewLatencyAwareRoutingStrategy is a creator factory for the LatencyAwareRoutingStrategy.
+// decay is the weight of the latest sample in the moving average, in the (0, 1] range
+func NewLatencyAwareRoutingStrategy(decay float64, warmUpSamples int) (*LatencyAwareRoutingStrategy, error) {
+	if decay <= 0 || decay > 1 {
+		return nil, fmt.Errorf("latency aware routing strategy: decay_factor must be in (0, 1] range: [%v]", decay)
+	}
+	if warmUpSamples < 0 {
+		return nil, errors.New("latency aware routing strategy: warm_up_samples can not be negative")
+	}
+	return &LatencyAwareRoutingStrategy{
+		decay:  decay,
+		warmUp: int64(warmUpSamples),
+	}, nil
+}
+
+// Initialize parses the properties of the strategy:
+//   - decay_factor – weight of the latest sample in the moving average. Example `0.2`
+//   - warm_up_samples – number of samples from each route, during which the routes are ordered round-robin
+func (s *LatencyAwareRoutingStrategy) Initialize(properties json.RawMessage) error {
+	var props latencyAwareRoutingStrategyProperties
+	if len(properties) > 0 {
+		if err := json.Unmarshal(properties, &props); err != nil {
+			return fmt.Errorf("latency aware routing strategy: failed to parse properties: %s", err)
+		}
+	}
+
+	decay, warmUp := DefaultLatencyDecayFactor, DefaultLatencyWarmUpSamples
+	if props.DecayFactor != nil {
+		decay = *props.DecayFactor
+	}
+	if props.WarmUpSamples != nil {
+		warmUp = *props.WarmUpSamples
+	}
+
+	strategy, err := NewLatencyAwareRoutingStrategy(decay, warmUp)
+	if err != nil {
+		return err
+	}
+	s.decay = strategy.decay
+	s.warmUp = strategy.warmUp
+	return nil
+}
+
+// Observe records the latency of a successful response from the route with the given ID
+func (s *LatencyAwareRoutingStrategy) Observe(routeID string, latency time.Duration) {
+	s.observe(routeID, latency, false)
+}
+
+// observe records the latency of the dispatch by the route. The latency of the failed dispatch is
+// penalized with latencyFailurePenalty
+func (s *LatencyAwareRoutingStrategy) observe(routeID string, latency time.Duration, failed bool) {
+	value, _ := s.latency.LoadOrStore(routeID, &latencyStats{})
+	stats := value.(*latencyStats)
+
+	for {
+		old := atomic.LoadUint64(&stats.ewma)
+		sample := float64(latency)
+		ewma := sample
+		if atomic.LoadInt64(&stats.samples) > 0 {
+			average := math.Float64frombits(old)
+			if failed {
+				sample = math.Max(sample, latencyFailurePenalty*average)
+			}
+			ewma = s.decay*sample + (1-s.decay)*average
+		}
+		if atomic.CompareAndSwapUint64(&stats.ewma, old, math.Float64bits(ewma)) {
+			break
+		}
+	}
+	atomic.AddInt64(&stats.samples, 1)
+}
+
+// Latencies returns the snapshot of the current moving averages of the routes' latencies
+func (s *LatencyAwareRoutingStrategy) Latencies() map[string]time.Duration {
+	latencies := make(map[string]time.Duration)
+	s.latency.Range(func(key, value interface{}) bool {
+		stats := value.(*latencyStats)
+		if atomic.LoadInt64(&stats.samples) > 0 {
+			latencies[key.(string)] = time.Duration(math.Float64frombits(atomic.LoadUint64(&stats.ewma)))
+		}
+		return true
+	})
+	return latencies
+}
+
+// RouteScores returns the moving averages of the latencies of the routes in milliseconds, that have been
+// observed (see fiber.RouteScorer)
+func (s *LatencyAwareRoutingStrategy) RouteScores(routes map[string]fiber.Component) map[string]float64 {
+	latencies := s.Latencies()
+	scores := make(map[string]float64, len(routes))
+	for id := range routes {
+		if latency, ok := latencies[id]; ok {
+			scores[id] = float64(latency) / float64(time.Millisecond)
+		}
+	}
+	return scores
+}
+
+// Interceptor returns the interceptor, that observes the latencies of the routes for this strategy
+func (s *LatencyAwareRoutingStrategy) Interceptor() fiber.Interceptor {
+	return &latencyInterceptor{strategy: s}
+}
+
+// SelectRoute selects the route with the lowest latency as the primary route, and orders the
+// fallbacks ascending by their latency. During the warm-up, the routes are ordered round-robin
+func (s *LatencyAwareRoutingStrategy) SelectRoute(
+	_ context.Context,
+	_ fiber.Request,
+	routes map[string]fiber.Component,
+) (route fiber.Component, fallbacks []fiber.Component, err error) {
+	if len(routes) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]string, 0, len(routes))
+	for id := range routes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	latencies := make(map[string]float64, len(ids))
+	warm := true
+	for _, id := range ids {
+		value, ok := s.latency.Load(id)
+		if !ok {
+			warm = false
+			break
+		}
+		stats := value.(*latencyStats)
+		if samples := atomic.LoadInt64(&stats.samples); samples == 0 || samples < s.warmUp {
+			warm = false
+			break
+		}
+		latencies[id] = math.Float64frombits(atomic.LoadUint64(&stats.ewma))
+	}
+
+	if warm {
+		sort.SliceStable(ids, func(i, j int) bool {
+			return latencies[ids[i]] < latencies[ids[j]]
+		})
+	} else {
+		offset := int((atomic.AddUint32(&s.next, 1) - 1) % uint32(len(ids)))
+		rotated := make([]string, 0, len(ids))
+		ids = append(append(rotated, ids[offset:]...), ids[:offset]...)
+	}
+
+	for _, id := range ids[1:] {
+		fallbacks = append(fallbacks, routes[id])
+	}
+	return routes[ids[0]], fallbacks, nil
+}
+
+// latencyInterceptor measures the time between the start of the dispatch and its completion
+// by a route, and records it into the LatencyAwareRoutingStrategy
+type latencyInterceptor struct {
+	fiber.NoopAfterDispatchInterceptor
+	strategy *LatencyAwareRoutingStrategy
+}
+
+// BeforeDispatch records the start time of the dispatch
+func (i *latencyInterceptor) BeforeDispatch(ctx context.Context, req fiber.Request) context.Context {
+	// the interceptor itself is used as the key, so it doesn't clash with other interceptors
+	return context.WithValue(ctx, i, time.Now())
+}
+
+// AfterCompletion records the latency of the completed route, penalized, if any of its responses has failed
+func (i *latencyInterceptor) AfterCompletion(ctx context.Context, req fiber.Request, queue fiber.ResponseQueue) {
+	startTime, ok := ctx.Value(i).(time.Time)
+	if !ok {
+		return
+	}
+	latency := time.Since(startTime)
+
+	failed := false
+	for resp := range queue.Iter() {
+		failed = failed || !resp.IsSuccess()
+	}
+	if routeID, ok := ctx.Value(fiber.CtxComponentIDKey).(string); ok {
+		i.strategy.observe(routeID, latency, failed)
+	}
+}
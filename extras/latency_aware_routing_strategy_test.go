@@ -0,0 +1,172 @@
+package extras_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyAwareRoutingStrategy_Initialize(t *testing.T) {
+	suite := map[string]struct {
+		properties string
+		expected   string
+	}{
+		"ok": {
+			properties: `{"decay_factor": 0.5, "warm_up_samples": 0}`,
+		},
+		"defaults": {},
+		"invalid decay factor": {
+			properties: `{"decay_factor": 1.5}`,
+			expected:   "latency aware routing strategy: decay_factor must be in (0, 1] range: [1.5]",
+		},
+		"negative warm up": {
+			properties: `{"warm_up_samples": -1}`,
+			expected:   "latency aware routing strategy: warm_up_samples can not be negative",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			strategy := new(extras.LatencyAwareRoutingStrategy)
+			err := strategy.Initialize(json.RawMessage(tt.properties))
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func routeIDs(route fiber.Component, fallbacks []fiber.Component) []string {
+	ids := []string{route.ID()}
+	for _, fallback := range fallbacks {
+		ids = append(ids, fallback.ID())
+	}
+	return ids
+}
+
+func TestLatencyAwareRoutingStrategy_SelectRoute(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+	}
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/latency", "")
+
+	strategy, err := extras.NewLatencyAwareRoutingStrategy(0.5, 2)
+	require.NoError(t, err)
+
+	// round-robin during the warm-up
+	for _, expected := range [][]string{
+		{"route-a", "route-b", "route-c"},
+		{"route-b", "route-c", "route-a"},
+		{"route-c", "route-a", "route-b"},
+	} {
+		route, fallbacks, err := strategy.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+		assert.Equal(t, expected, routeIDs(route, fallbacks))
+	}
+
+	for _, latency := range []time.Duration{10, 30} {
+		strategy.Observe("route-a", latency*time.Millisecond)
+		strategy.Observe("route-b", 5*time.Millisecond)
+	}
+	strategy.Observe("route-c", 15*time.Millisecond)
+
+	// route-c has not enough samples yet
+	route, _, _ := strategy.SelectRoute(context.Background(), req, routes)
+	assert.Equal(t, "route-a", route.ID())
+
+	strategy.Observe("route-c", 25*time.Millisecond)
+	assert.Equal(t, map[string]time.Duration{
+		"route-a": 20 * time.Millisecond,
+		"route-b": 5 * time.Millisecond,
+		"route-c": 20 * time.Millisecond,
+	}, strategy.Latencies())
+
+	route, fallbacks, err := strategy.SelectRoute(context.Background(), req, routes)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"route-b", "route-a", "route-c"}, routeIDs(route, fallbacks))
+}
+
+func TestLatencyAwareRoutingStrategy_Interceptor(t *testing.T) {
+	strategy, err := extras.NewLatencyAwareRoutingStrategy(1, 1)
+	require.NoError(t, err)
+
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+	}
+	latencies := map[string]time.Duration{"route-a": 30 * time.Millisecond, "route-b": 10 * time.Millisecond}
+	interceptor := strategy.Interceptor()
+	var wg sync.WaitGroup
+	for id, latency := range latencies {
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(id string, latency time.Duration) {
+				defer wg.Done()
+				ctx := context.WithValue(context.Background(), fiber.CtxComponentIDKey, id)
+				ctx = interceptor.BeforeDispatch(ctx, nil)
+				time.Sleep(latency)
+				interceptor.AfterCompletion(ctx, nil,
+					fiber.NewResponseQueueFromResponses(testUtilsHttp.MockResp(http.StatusOK, id, nil, nil)))
+			}(id, latency)
+		}
+	}
+	wg.Wait()
+
+	observed := strategy.Latencies()
+	require.Len(t, observed, 2)
+	assert.GreaterOrEqual(t, int64(observed["route-a"]), int64(30*time.Millisecond))
+	assert.Less(t, int64(observed["route-b"]), int64(observed["route-a"]))
+
+	route, _, err := strategy.SelectRoute(context.Background(), nil, routes)
+	require.NoError(t, err)
+	assert.Equal(t, "route-b", route.ID())
+}
+
+func TestLatencyAwareRoutingStrategy_InterceptorFailures(t *testing.T) {
+	strategy, err := extras.NewLatencyAwareRoutingStrategy(0.5, 1)
+	require.NoError(t, err)
+
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+	}
+	// route-a fails fast, while route-b succeeds slower
+	outcomes := map[string]struct {
+		latency time.Duration
+		status  int
+	}{
+		"route-a": {latency: time.Millisecond, status: http.StatusServiceUnavailable},
+		"route-b": {latency: 10 * time.Millisecond, status: http.StatusOK},
+	}
+	interceptor := strategy.Interceptor()
+	for id, outcome := range outcomes {
+		for i := 0; i < 10; i++ {
+			ctx := context.WithValue(context.Background(), fiber.CtxComponentIDKey, id)
+			ctx = interceptor.BeforeDispatch(ctx, nil)
+			time.Sleep(outcome.latency)
+			interceptor.AfterCompletion(ctx, nil,
+				fiber.NewResponseQueueFromResponses(testUtilsHttp.MockResp(outcome.status, id, nil, nil)))
+		}
+	}
+
+	// the failures are penalized, so the failing route doesn't look like the fastest one
+	observed := strategy.Latencies()
+	assert.Greater(t, int64(observed["route-a"]), int64(observed["route-b"]))
+	route, _, err := strategy.SelectRoute(context.Background(), nil, routes)
+	require.NoError(t, err)
+	assert.Equal(t, "route-b", route.ID())
+}
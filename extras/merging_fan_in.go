@@ -0,0 +1,94 @@
+package extras
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+)
+
+// MergeFunc merges the successful responses of the routes, in the order they have arrived, into a single response
+type MergeFunc func(ctx context.Context, req fiber.Request, responses []fiber.Response) fiber.Response
+
+// MergingFanIn is a FanIn, that collects the successful responses of the routes and merges them
+// into a single response with the MergeFunc, e.g. to concatenate the results of the sharded backends.
+// The failed responses are skipped, so the merged response is produced from whatever has succeeded,
+// as long as at least minSuccess responses have. Otherwise, ErrServiceUnavailable is returned.
+// The responses are merged, as soon as minSuccess of them have succeeded, or after the grace period,
+// that lets the slower routes contribute (see WithGracePeriod), so the Combiner cancels the rest.
+// If the context of the request is done, the responses received so far are merged
+type MergingFanIn struct {
+	fiber.BaseFanIn
+
+	merge       MergeFunc
+	minSuccess  int
+	gracePeriod time.Duration
+}
+
+// NewMergingFanIn is a factory method, that creates a MergingFanIn, that merges the responses with the given
+// function, once minSuccess of them have succeeded. If minSuccess is zero, a single successful response is enough
+func NewMergingFanIn(merge MergeFunc, minSuccess int) (*MergingFanIn, error) {
+	if merge == nil {
+		return nil, errors.New("merging fan in: merge function can not be nil")
+	}
+	if minSuccess < 0 {
+		return nil, fmt.Errorf("merging fan in: min_success can not be negative: [%d]", minSuccess)
+	}
+	if minSuccess == 0 {
+		minSuccess = 1
+	}
+	return &MergingFanIn{merge: merge, minSuccess: minSuccess}, nil
+}
+
+// WithGracePeriod sets the time, that the fan in keeps waiting for the responses of the remaining routes,
+// once minSuccess of them have succeeded, so their responses are merged too. By default, the responses
+// are merged right away
+func (f *MergingFanIn) WithGracePeriod(gracePeriod time.Duration) *MergingFanIn {
+	f.gracePeriod = gracePeriod
+	return f
+}
+
+// Aggregate waits for the responses of the routes, until minSuccess of them have succeeded and the grace
+// period has elapsed, or all of them have responded, and merges the successful ones
+func (f *MergingFanIn) Aggregate(
+	ctx context.Context,
+	req fiber.Request,
+	queue fiber.ResponseQueue,
+) fiber.Response {
+	var succeeded []fiber.Response
+	var grace <-chan time.Time
+	responses := queue.Iter()
+	for done := false; !done; {
+		select {
+		case resp, ok := <-responses:
+			if !ok {
+				done = true
+			} else if resp.IsSuccess() {
+				succeeded = append(succeeded, resp)
+				if len(succeeded) == f.minSuccess {
+					if f.gracePeriod <= 0 {
+						done = true
+					} else {
+						timer := time.NewTimer(f.gracePeriod)
+						defer timer.Stop()
+						grace = timer.C
+					}
+				}
+			}
+		case <-grace:
+			done = true
+		case <-ctx.Done():
+			done = true
+		}
+	}
+
+	if len(succeeded) < f.minSuccess {
+		err := fiberErrors.ErrServiceUnavailable(req.Protocol())
+		err.Message = fmt.Sprintf("fiber: %d response(s) succeeded, %d required", len(succeeded), f.minSuccess)
+		return fiber.NewErrorResponse(err)
+	}
+	return f.merge(ctx, req, succeeded)
+}
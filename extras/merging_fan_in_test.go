@@ -0,0 +1,234 @@
+package extras_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/extras"
+	fiberGRPC "github.com/gojek/fiber/grpc"
+	testproto "github.com/gojek/fiber/internal/testdata/gen/testdata/proto"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// concatPayloads merges the http responses by joining their payloads
+func concatPayloads(_ context.Context, _ fiber.Request, responses []fiber.Response) fiber.Response {
+	payloads := make([]string, len(responses))
+	for idx, resp := range responses {
+		payloads[idx] = string(resp.Payload())
+	}
+	return testUtilsHttp.MockResp(http.StatusOK, strings.Join(payloads, ","), nil, nil)
+}
+
+// mergePredictions merges the grpc responses by concatenating their predictions
+func mergePredictions(_ context.Context, _ fiber.Request, responses []fiber.Response) fiber.Response {
+	merged := &testproto.PredictValuesResponse{}
+	for _, resp := range responses {
+		msg := &testproto.PredictValuesResponse{}
+		if err := proto.Unmarshal(resp.Payload(), msg); err != nil {
+			return fiber.NewErrorResponse(fiberErrors.ErrRequestFailed(protocol.GRPC, err))
+		}
+		merged.Predictions = append(merged.Predictions, msg.Predictions...)
+	}
+	payload, _ := proto.Marshal(merged)
+	return &fiberGRPC.Response{Metadata: metadata.MD{}, Message: payload, Status: *status.New(codes.OK, "")}
+}
+
+func predictionsResponse(t *testing.T, rowIDs ...string) fiber.Response {
+	msg := &testproto.PredictValuesResponse{}
+	for _, rowID := range rowIDs {
+		msg.Predictions = append(msg.Predictions, &testproto.PredictionResult{RowId: rowID})
+	}
+	payload, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	return &fiberGRPC.Response{Metadata: metadata.MD{}, Message: payload, Status: *status.New(codes.OK, "")}
+}
+
+func TestNewMergingFanIn(t *testing.T) {
+	suite := map[string]struct {
+		merge       extras.MergeFunc
+		minSuccess  int
+		expectedErr string
+	}{
+		"ok": {
+			merge:      concatPayloads,
+			minSuccess: 2,
+		},
+		"error: nil merge function": {
+			expectedErr: "merging fan in: merge function can not be nil",
+		},
+		"error: negative min success": {
+			merge:       concatPayloads,
+			minSuccess:  -1,
+			expectedErr: "merging fan in: min_success can not be negative: [-1]",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			_, err := extras.NewMergingFanIn(tt.merge, tt.minSuccess)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestMergingFanIn_Aggregate(t *testing.T) {
+	failed := testUtilsHttp.MockResp(
+		http.StatusInternalServerError, "", nil, fiberErrors.ErrRequestFailed(protocol.HTTP, errors.New("failed")))
+	httpReq := testUtilsHttp.MockReq("GET", "http://localhost:8080/merge", "")
+
+	suite := map[string]struct {
+		merge            extras.MergeFunc
+		minSuccess       int
+		request          fiber.Request
+		responses        []fiber.Response
+		expectedStatus   int
+		expectedPayload  string
+		expectedRowIDs   []string
+		expectedErrorMsg string
+	}{
+		"all succeeded": {
+			merge:      concatPayloads,
+			minSuccess: 2,
+			request:    httpReq,
+			responses: []fiber.Response{
+				testUtilsHttp.MockResp(http.StatusOK, "A", nil, nil),
+				testUtilsHttp.MockResp(http.StatusOK, "B", nil, nil),
+			},
+			expectedStatus:  http.StatusOK,
+			expectedPayload: "A,B",
+		},
+		"merged, once min success is reached": {
+			merge:   concatPayloads,
+			request: httpReq,
+			responses: []fiber.Response{
+				failed,
+				testUtilsHttp.MockResp(http.StatusOK, "B", nil, nil),
+				testUtilsHttp.MockResp(http.StatusOK, "C", nil, nil),
+			},
+			expectedStatus:  http.StatusOK,
+			expectedPayload: "B",
+		},
+		"partial failures": {
+			merge:      concatPayloads,
+			minSuccess: 2,
+			request:    httpReq,
+			responses: []fiber.Response{
+				testUtilsHttp.MockResp(http.StatusOK, "A", nil, nil),
+				failed,
+				testUtilsHttp.MockResp(http.StatusOK, "C", nil, nil),
+			},
+			expectedStatus:  http.StatusOK,
+			expectedPayload: "A,C",
+		},
+		"not enough succeeded": {
+			merge:      concatPayloads,
+			minSuccess: 2,
+			request:    httpReq,
+			responses: []fiber.Response{
+				testUtilsHttp.MockResp(http.StatusOK, "A", nil, nil),
+				failed,
+			},
+			expectedStatus:   http.StatusServiceUnavailable,
+			expectedErrorMsg: "fiber: 1 response(s) succeeded, 2 required",
+		},
+		"no responses": {
+			merge:            concatPayloads,
+			request:          httpReq,
+			expectedStatus:   http.StatusServiceUnavailable,
+			expectedErrorMsg: "fiber: 0 response(s) succeeded, 1 required",
+		},
+		"grpc predictions": {
+			merge:      mergePredictions,
+			minSuccess: 2,
+			request:    &fiberGRPC.Request{Metadata: metadata.MD{}},
+			responses: []fiber.Response{
+				predictionsResponse(t, "1", "2"),
+				fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.GRPC)),
+				predictionsResponse(t, "3"),
+			},
+			expectedStatus: int(codes.OK),
+			expectedRowIDs: []string{"1", "2", "3"},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			fanIn, err := extras.NewMergingFanIn(tt.merge, tt.minSuccess)
+			require.NoError(t, err)
+
+			resp := fanIn.Aggregate(context.Background(), tt.request, fiber.NewResponseQueueFromResponses(tt.responses...))
+			require.Equal(t, tt.expectedStatus, resp.StatusCode())
+
+			switch {
+			case tt.expectedErrorMsg != "":
+				assert.Contains(t, string(resp.Payload()), tt.expectedErrorMsg)
+			case tt.expectedRowIDs != nil:
+				merged := &testproto.PredictValuesResponse{}
+				require.NoError(t, proto.Unmarshal(resp.Payload(), merged))
+				var rowIDs []string
+				for _, prediction := range merged.Predictions {
+					rowIDs = append(rowIDs, prediction.RowId)
+				}
+				assert.Equal(t, tt.expectedRowIDs, rowIDs)
+			default:
+				assert.Equal(t, tt.expectedPayload, string(resp.Payload()))
+			}
+		})
+	}
+}
+
+func TestMergingFanIn_AggregateContextDone(t *testing.T) {
+	fanIn, err := extras.NewMergingFanIn(concatPayloads, 1)
+	require.NoError(t, err)
+
+	// the second route never responds
+	out := make(chan fiber.Response, 2)
+	out <- testUtilsHttp.MockResp(http.StatusOK, "A", nil, nil)
+	queue := fiber.NewResponseQueue(out, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	resp := fanIn.Aggregate(ctx, testUtilsHttp.MockReq("GET", "http://localhost:8080/merge", ""), queue)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "A", string(resp.Payload()))
+}
+
+func TestMergingFanIn_AggregateGracePeriod(t *testing.T) {
+	fanIn, err := extras.NewMergingFanIn(concatPayloads, 1)
+	require.NoError(t, err)
+	fanIn = fanIn.WithGracePeriod(100 * time.Millisecond)
+
+	// the second route responds within the grace period, while the third one never responds
+	out := make(chan fiber.Response, 3)
+	out <- testUtilsHttp.MockResp(http.StatusOK, "A", nil, nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		out <- testUtilsHttp.MockResp(http.StatusOK, "B", nil, nil)
+	}()
+	queue := fiber.NewResponseQueue(out, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	resp := fanIn.Aggregate(ctx, testUtilsHttp.MockReq("GET", "http://localhost:8080/merge", ""), queue)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "A,B", string(resp.Payload()))
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+}
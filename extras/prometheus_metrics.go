@@ -0,0 +1,237 @@
+package extras
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultMetricsNamespace is the default namespace of the metrics, registered by PrometheusMetrics
+const DefaultMetricsNamespace = "fiber"
+
+// Outcomes of the dispatches, recorded by PrometheusMetrics
+const (
+	OutcomeSuccess = "success"
+	OutcomeTimeout = "timeout"
+	OutcomeError   = "error"
+)
+
+// PrometheusMetrics is the interceptor.DispatchMetrics, that records the dispatches of the routes into
+// the prometheus counter `<namespace>_route_dispatch_total` and the latency histogram
+// `<namespace>_route_dispatch_duration_seconds`, both labeled with the route ID, the protocol,
+// the outcome (success, timeout or error) and the status.
+// To keep the cardinality of the metrics bounded, the http status codes are recorded as their
+// classes (e.g. "5xx"), and the grpc status codes by their names (e.g. "Unavailable").
+// The states of the circuit breakers of the routes are recorded into the gauge `<namespace>_route_circuit_state`,
+// labeled with the route ID, with the values of fiber.CircuitState: 0 (closed), 1 (half-open) and 2 (open).
+// The health of the routes is recorded into the gauge `<namespace>_route_healthy`, labeled with the route ID:
+// 1 (healthy) or 0 (unhealthy).
+// The dispatches of the tenants of the routes with the isolated tenants (see fiber.TenantIsolatedDispatcher)
+// are recorded into the counter `<namespace>_route_tenant_dispatch_total`, labeled with the route ID, the tenant
+// and the outcome. Its cardinality is bounded by the number of the tenants, which dispatchers the routes keep
+type PrometheusMetrics struct {
+	fiber.BaseFiberType
+
+	dispatches       *prometheus.CounterVec
+	latencies        *prometheus.HistogramVec
+	circuitStates    *prometheus.GaugeVec
+	health           *prometheus.GaugeVec
+	tenantDispatches *prometheus.CounterVec
+}
+
+type prometheusMetricsProperties struct {
+	Namespace string    `json:"namespace"`
+	Buckets   []float64 `json:"buckets"`
+}
+
+var prometheusMetricsLabels = []string{"route", "protocol", "outcome", "status"}
+
+// NewPrometheusMetrics is a creator factory for the PrometheusMetrics. The metrics are registered with
+// the given registerer, or reused, if the metrics with the same namespace are already registered there.
+// If the buckets are not set, prometheus.DefBuckets are used
+func NewPrometheusMetrics(
+	namespace string,
+	buckets []float64,
+	registerer prometheus.Registerer,
+) (*PrometheusMetrics, error) {
+	if namespace == "" {
+		namespace = DefaultMetricsNamespace
+	}
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	if registerer == nil {
+		return nil, errors.New("prometheus metrics: registerer can not be nil")
+	}
+
+	dispatches, err := registerCollector(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "route_dispatch_total",
+		Help:      "Number of the dispatches of the requests by the routes",
+	}, prometheusMetricsLabels))
+	if err != nil {
+		return nil, err
+	}
+	latencies, err := registerCollector(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "route_dispatch_duration_seconds",
+		Help:      "Latency of the dispatches of the requests by the routes",
+		Buckets:   buckets,
+	}, prometheusMetricsLabels))
+	if err != nil {
+		return nil, err
+	}
+	circuitStates, err := registerCollector(registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "route_circuit_state",
+		Help:      "State of the circuit breaker of the route: 0 (closed), 1 (half-open) or 2 (open)",
+	}, []string{"route"}))
+	if err != nil {
+		return nil, err
+	}
+	health, err := registerCollector(registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "route_healthy",
+		Help:      "Health of the route, checked by its health probes: 1 (healthy) or 0 (unhealthy)",
+	}, []string{"route"}))
+	if err != nil {
+		return nil, err
+	}
+	tenantDispatches, err := registerCollector(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "route_tenant_dispatch_total",
+		Help:      "Number of the dispatches of the requests of the tenants by the routes, that isolate them",
+	}, []string{"route", "tenant", "outcome"}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrometheusMetrics{
+		dispatches:       dispatches.(*prometheus.CounterVec),
+		latencies:        latencies.(*prometheus.HistogramVec),
+		circuitStates:    circuitStates.(*prometheus.GaugeVec),
+		health:           health.(*prometheus.GaugeVec),
+		tenantDispatches: tenantDispatches.(*prometheus.CounterVec),
+	}, nil
+}
+
+// Initialize parses the properties of the metrics and registers them with prometheus.DefaultRegisterer:
+//   - namespace – namespace of the metrics. Defaults to `fiber`
+//   - buckets – buckets of the latency histogram, in seconds. Example `[0.01, 0.05, 0.1, 0.5, 1]`
+func (m *PrometheusMetrics) Initialize(properties json.RawMessage) error {
+	var props prometheusMetricsProperties
+	if len(properties) > 0 {
+		if err := json.Unmarshal(properties, &props); err != nil {
+			return fmt.Errorf("prometheus metrics: failed to parse properties: %s", err)
+		}
+	}
+
+	metrics, err := NewPrometheusMetrics(props.Namespace, props.Buckets, prometheus.DefaultRegisterer)
+	if err != nil {
+		return err
+	}
+	m.dispatches = metrics.dispatches
+	m.latencies = metrics.latencies
+	m.circuitStates = metrics.circuitStates
+	m.health = metrics.health
+	m.tenantDispatches = metrics.tenantDispatches
+	return nil
+}
+
+// RecordDispatch records the dispatch of the request by the route
+func (m *PrometheusMetrics) RecordDispatch(
+	routeID string,
+	proto protocol.Protocol,
+	statusCode int,
+	latency time.Duration,
+) {
+	outcome, status := dispatchOutcome(proto, statusCode)
+	labels := prometheus.Labels{
+		"route":    routeID,
+		"protocol": strings.ToLower(string(proto)),
+		"outcome":  outcome,
+		"status":   status,
+	}
+	m.dispatches.With(labels).Inc()
+	m.latencies.With(labels).Observe(latency.Seconds())
+}
+
+// RecordCircuitState records the state of the circuit breaker of the route
+func (m *PrometheusMetrics) RecordCircuitState(routeID string, state fiber.CircuitState) {
+	m.circuitStates.With(prometheus.Labels{"route": routeID}).Set(float64(state))
+}
+
+// RecordRouteHealth records the health of the route
+func (m *PrometheusMetrics) RecordRouteHealth(routeID string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	m.health.With(prometheus.Labels{"route": routeID}).Set(value)
+}
+
+// RecordTenantDispatch records the dispatch of the request of the tenant by the route
+func (m *PrometheusMetrics) RecordTenantDispatch(
+	routeID string,
+	tenant string,
+	proto protocol.Protocol,
+	statusCode int,
+) {
+	outcome, _ := dispatchOutcome(proto, statusCode)
+	m.tenantDispatches.With(prometheus.Labels{
+		"route":   routeID,
+		"tenant":  tenant,
+		"outcome": outcome,
+	}).Inc()
+}
+
+// registerCollector registers the collector, or returns the existing one, if it's already registered
+func registerCollector(registerer prometheus.Registerer, collector prometheus.Collector) (prometheus.Collector, error) {
+	if err := registerer.Register(collector); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if errors.As(err, &registered) {
+			return registered.ExistingCollector, nil
+		}
+		return nil, fmt.Errorf("prometheus metrics: %s", err)
+	}
+	return collector, nil
+}
+
+// dispatchOutcome returns the outcome and the bounded status label of the status code
+func dispatchOutcome(proto protocol.Protocol, statusCode int) (outcome string, status string) {
+	if proto == protocol.GRPC {
+		code := codes.Code(statusCode)
+		if statusCode < 0 || code > codes.Unauthenticated {
+			return OutcomeError, "other"
+		}
+		switch code {
+		case codes.OK:
+			return OutcomeSuccess, code.String()
+		case codes.DeadlineExceeded:
+			return OutcomeTimeout, code.String()
+		default:
+			return OutcomeError, code.String()
+		}
+	}
+
+	if statusCode < 100 || statusCode > 599 {
+		return OutcomeError, "other"
+	}
+	status = fmt.Sprintf("%dxx", statusCode/100)
+	switch {
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return OutcomeTimeout, status
+	case statusCode < 400:
+		return OutcomeSuccess, status
+	default:
+		return OutcomeError, status
+	}
+}
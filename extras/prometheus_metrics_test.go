@@ -0,0 +1,188 @@
+package extras_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestPrometheusMetrics_RecordDispatch(t *testing.T) {
+	suite := map[string]struct {
+		protocol   protocol.Protocol
+		statusCode int
+		expected   map[string]string
+	}{
+		"http success": {
+			protocol:   protocol.HTTP,
+			statusCode: http.StatusOK,
+			expected:   map[string]string{"protocol": "http", "outcome": extras.OutcomeSuccess, "status": "2xx"},
+		},
+		"http error": {
+			protocol:   protocol.HTTP,
+			statusCode: http.StatusServiceUnavailable,
+			expected:   map[string]string{"protocol": "http", "outcome": extras.OutcomeError, "status": "5xx"},
+		},
+		"http timeout": {
+			protocol:   protocol.HTTP,
+			statusCode: http.StatusRequestTimeout,
+			expected:   map[string]string{"protocol": "http", "outcome": extras.OutcomeTimeout, "status": "4xx"},
+		},
+		"http unknown status": {
+			protocol:   protocol.HTTP,
+			statusCode: 1234,
+			expected:   map[string]string{"protocol": "http", "outcome": extras.OutcomeError, "status": "other"},
+		},
+		"grpc success": {
+			protocol:   protocol.GRPC,
+			statusCode: int(codes.OK),
+			expected:   map[string]string{"protocol": "grpc", "outcome": extras.OutcomeSuccess, "status": "OK"},
+		},
+		"grpc timeout": {
+			protocol:   protocol.GRPC,
+			statusCode: int(codes.DeadlineExceeded),
+			expected:   map[string]string{"protocol": "grpc", "outcome": extras.OutcomeTimeout, "status": "DeadlineExceeded"},
+		},
+		"grpc unknown status": {
+			protocol:   protocol.GRPC,
+			statusCode: 100,
+			expected:   map[string]string{"protocol": "grpc", "outcome": extras.OutcomeError, "status": "other"},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			metrics, err := extras.NewPrometheusMetrics("", []float64{0.1, 1}, registry)
+			require.NoError(t, err)
+
+			metrics.RecordDispatch("route-a", tt.protocol, tt.statusCode, 200*time.Millisecond)
+
+			families, err := registry.Gather()
+			require.NoError(t, err)
+			require.Len(t, families, 2)
+
+			tt.expected["route"] = "route-a"
+			for _, family := range families {
+				require.Len(t, family.GetMetric(), 1)
+				metric := family.GetMetric()[0]
+
+				labels := make(map[string]string)
+				for _, label := range metric.GetLabel() {
+					labels[label.GetName()] = label.GetValue()
+				}
+				assert.Equal(t, tt.expected, labels)
+
+				switch family.GetName() {
+				case "fiber_route_dispatch_total":
+					assert.Equal(t, float64(1), metric.GetCounter().GetValue())
+				case "fiber_route_dispatch_duration_seconds":
+					assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+					assert.InDelta(t, 0.2, metric.GetHistogram().GetSampleSum(), 1e-9)
+				default:
+					assert.Fail(t, "unexpected metric", family.GetName())
+				}
+			}
+		})
+	}
+}
+
+func TestNewPrometheusMetrics_AlreadyRegistered(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	first, err := extras.NewPrometheusMetrics("test", nil, registry)
+	require.NoError(t, err)
+	second, err := extras.NewPrometheusMetrics("test", nil, registry)
+	require.NoError(t, err)
+
+	first.RecordDispatch("route-a", protocol.HTTP, http.StatusOK, time.Millisecond)
+	second.RecordDispatch("route-a", protocol.HTTP, http.StatusOK, time.Millisecond)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == "test_route_dispatch_total" {
+			assert.Equal(t, float64(2), family.GetMetric()[0].GetCounter().GetValue())
+			return
+		}
+	}
+	assert.Fail(t, "metrics are not registered")
+}
+
+func TestPrometheusMetrics_RecordRouteHealth(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := extras.NewPrometheusMetrics("", nil, registry)
+	require.NoError(t, err)
+
+	metrics.RecordRouteHealth("route-a", true)
+	metrics.RecordRouteHealth("route-b", true)
+	metrics.RecordRouteHealth("route-b", false)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	assert.Equal(t, "fiber_route_healthy", families[0].GetName())
+
+	health := make(map[string]float64)
+	for _, metric := range families[0].GetMetric() {
+		health[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+	}
+	assert.Equal(t, map[string]float64{"route-a": 1, "route-b": 0}, health)
+}
+
+func TestPrometheusMetrics_RecordCircuitState(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := extras.NewPrometheusMetrics("", nil, registry)
+	require.NoError(t, err)
+
+	metrics.RecordCircuitState("route-a", fiber.CircuitOpen)
+	metrics.RecordCircuitState("route-b", fiber.CircuitOpen)
+	metrics.RecordCircuitState("route-b", fiber.CircuitHalfOpen)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	assert.Equal(t, "fiber_route_circuit_state", families[0].GetName())
+
+	states := make(map[string]float64)
+	for _, metric := range families[0].GetMetric() {
+		states[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+	}
+	assert.Equal(t, map[string]float64{
+		"route-a": float64(fiber.CircuitOpen),
+		"route-b": float64(fiber.CircuitHalfOpen),
+	}, states)
+}
+
+func TestPrometheusMetrics_RecordTenantDispatch(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := extras.NewPrometheusMetrics("", nil, registry)
+	require.NoError(t, err)
+
+	metrics.RecordTenantDispatch("route-a", "gold", protocol.HTTP, http.StatusOK)
+	metrics.RecordTenantDispatch("route-a", "gold", protocol.HTTP, http.StatusOK)
+	metrics.RecordTenantDispatch("route-a", "silver", protocol.GRPC, int(codes.Unavailable))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	assert.Equal(t, "fiber_route_tenant_dispatch_total", families[0].GetName())
+
+	// the labels are sorted by their names: outcome, route and tenant
+	dispatches := make(map[string]float64)
+	for _, metric := range families[0].GetMetric() {
+		labels := metric.GetLabel()
+		key := labels[1].GetValue() + "/" + labels[2].GetValue() + "/" + labels[0].GetValue()
+		dispatches[key] = metric.GetCounter().GetValue()
+	}
+	assert.Equal(t, map[string]float64{
+		"route-a/gold/success": 2,
+		"route-a/silver/error": 1,
+	}, dispatches)
+}
@@ -0,0 +1,104 @@
+package extras
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+)
+
+// QuorumKeyFunc returns the key of the successful response, so the responses, that agree with each other
+// (e.g. have the same prediction), have the same key
+type QuorumKeyFunc func(resp fiber.Response) string
+
+// QuorumFanIn is a FanIn, that groups the successful responses of the routes by the QuorumKeyFunc, and
+// returns the response of the group, that first gathers the quorum of votes, e.g. to return the majority-agreeing
+// response of the replicated backends. Each route has a single vote, unless it's weighted otherwise (see WithWeights).
+// Ties are broken by the earliest arrival: since the groups are voted for in the order the responses have arrived,
+// the first group to reach the quorum wins, and its first response is returned. Once the quorum is reached,
+// the requests to the remaining routes are cancelled by the Combiner. If the quorum can't be reached, i.e. all
+// the routes have responded or the context of the request is done, ErrServiceUnavailable is returned
+type QuorumFanIn struct {
+	fiber.BaseFanIn
+
+	key     QuorumKeyFunc
+	quorum  int
+	weights map[string]int
+}
+
+// NewQuorumFanIn is a factory method, that creates a QuorumFanIn, that returns the response, once quorum
+// votes agree with it according to the given key function
+func NewQuorumFanIn(key QuorumKeyFunc, quorum int) (*QuorumFanIn, error) {
+	if key == nil {
+		return nil, errors.New("quorum fan in: key function can not be nil")
+	}
+	if quorum <= 0 {
+		return nil, fmt.Errorf("quorum fan in: quorum must be positive: [%d]", quorum)
+	}
+	return &QuorumFanIn{key: key, quorum: quorum}, nil
+}
+
+// WithWeights sets the number of votes of the routes, keyed by the route ID. Routes without a weight
+// have a single vote
+func (f *QuorumFanIn) WithWeights(weights map[string]int) (*QuorumFanIn, error) {
+	for routeID, weight := range weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("quorum fan in: weight of the route [%s] can not be negative: [%d]", routeID, weight)
+		}
+	}
+	f.weights = weights
+	return f, nil
+}
+
+// Aggregate collects the votes of the successful responses, until any group of them reaches the quorum
+func (f *QuorumFanIn) Aggregate(
+	ctx context.Context,
+	req fiber.Request,
+	queue fiber.ResponseQueue,
+) fiber.Response {
+	votes := make(map[string]int)
+	first := make(map[string]fiber.Response)
+	responses := queue.Iter()
+	for {
+		select {
+		case resp, ok := <-responses:
+			if !ok {
+				return f.noQuorum(req, votes)
+			}
+			if !resp.IsSuccess() {
+				continue
+			}
+			key := f.key(resp)
+			if _, ok := first[key]; !ok {
+				first[key] = resp
+			}
+			votes[key] += f.votes(fiber.RouteID(resp))
+			if votes[key] >= f.quorum {
+				return first[key]
+			}
+		case <-ctx.Done():
+			return f.noQuorum(req, votes)
+		}
+	}
+}
+
+func (f *QuorumFanIn) votes(routeID string) int {
+	if weight, ok := f.weights[routeID]; ok {
+		return weight
+	}
+	return 1
+}
+
+func (f *QuorumFanIn) noQuorum(req fiber.Request, votes map[string]int) fiber.Response {
+	most := 0
+	for _, count := range votes {
+		if count > most {
+			most = count
+		}
+	}
+	err := fiberErrors.ErrServiceUnavailable(req.Protocol())
+	err.Message = fmt.Sprintf("fiber: quorum of %d vote(s) not reached, the largest group has %d", f.quorum, most)
+	return fiber.NewErrorResponse(err)
+}
@@ -0,0 +1,215 @@
+package extras_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/extras"
+	fiberGRPC "github.com/gojek/fiber/grpc"
+	testproto "github.com/gojek/fiber/internal/testdata/gen/testdata/proto"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// predictedRowIDs is the key of the grpc responses, so the responses with the same predictions agree
+func predictedRowIDs(resp fiber.Response) string {
+	msg := &testproto.PredictValuesResponse{}
+	if err := proto.Unmarshal(resp.Payload(), msg); err != nil {
+		return ""
+	}
+	rowIDs := make([]string, len(msg.Predictions))
+	for idx, prediction := range msg.Predictions {
+		rowIDs[idx] = prediction.RowId
+	}
+	return strings.Join(rowIDs, ",")
+}
+
+// blockingComponent never responds, until the context of the dispatch is cancelled
+type blockingComponent struct {
+	*fiber.BaseComponent
+	cancelled chan struct{}
+}
+
+func (c *blockingComponent) Dispatch(ctx context.Context, _ fiber.Request) fiber.ResponseQueue {
+	out := make(chan fiber.Response, 1)
+	go func() {
+		defer close(out)
+		<-ctx.Done()
+		close(c.cancelled)
+	}()
+	return fiber.NewResponseQueue(out, 1)
+}
+
+func TestNewQuorumFanIn(t *testing.T) {
+	suite := map[string]struct {
+		key         extras.QuorumKeyFunc
+		quorum      int
+		weights     map[string]int
+		expectedErr string
+	}{
+		"ok": {
+			key:     predictedRowIDs,
+			quorum:  2,
+			weights: map[string]int{"route-a": 2},
+		},
+		"error: nil key function": {
+			quorum:      2,
+			expectedErr: "quorum fan in: key function can not be nil",
+		},
+		"error: non-positive quorum": {
+			key:         predictedRowIDs,
+			expectedErr: "quorum fan in: quorum must be positive: [0]",
+		},
+		"error: negative weight": {
+			key:         predictedRowIDs,
+			quorum:      2,
+			weights:     map[string]int{"route-a": -1},
+			expectedErr: "quorum fan in: weight of the route [route-a] can not be negative: [-1]",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			fanIn, err := extras.NewQuorumFanIn(tt.key, tt.quorum)
+			if err == nil {
+				_, err = fanIn.WithWeights(tt.weights)
+			}
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestQuorumFanIn_Aggregate(t *testing.T) {
+	suite := map[string]struct {
+		quorum           int
+		weights          map[string]int
+		responses        map[string]testUtilsHttp.DelayedResponse
+		expectedStatus   int
+		expectedRowIDs   string
+		expectedBackend  string
+		expectedErrorMsg string
+	}{
+		"two of three backends agree": {
+			quorum: 2,
+			responses: map[string]testUtilsHttp.DelayedResponse{
+				"route-a": {Response: predictionsResponse(t, "1", "2")},
+				"route-b": {Latency: 20 * time.Millisecond, Response: predictionsResponse(t, "3")},
+				"route-c": {Latency: 40 * time.Millisecond, Response: predictionsResponse(t, "1", "2")},
+			},
+			expectedStatus:  int(codes.OK),
+			expectedRowIDs:  "1,2",
+			expectedBackend: "route-a",
+		},
+		"failed responses don't vote": {
+			quorum: 2,
+			responses: map[string]testUtilsHttp.DelayedResponse{
+				"route-a": {Response: fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.GRPC))},
+				"route-b": {Latency: 20 * time.Millisecond, Response: predictionsResponse(t, "3")},
+				"route-c": {Latency: 40 * time.Millisecond, Response: predictionsResponse(t, "3")},
+			},
+			expectedStatus:  int(codes.OK),
+			expectedRowIDs:  "3",
+			expectedBackend: "route-b",
+		},
+		"tie is broken by the earliest arrival": {
+			quorum:  2,
+			weights: map[string]int{"route-a": 2, "route-b": 2},
+			responses: map[string]testUtilsHttp.DelayedResponse{
+				"route-a": {Latency: 20 * time.Millisecond, Response: predictionsResponse(t, "1")},
+				"route-b": {Response: predictionsResponse(t, "2")},
+			},
+			expectedStatus:  int(codes.OK),
+			expectedRowIDs:  "2",
+			expectedBackend: "route-b",
+		},
+		"weighted backend outvotes the others": {
+			quorum:  3,
+			weights: map[string]int{"route-c": 3},
+			responses: map[string]testUtilsHttp.DelayedResponse{
+				"route-a": {Response: predictionsResponse(t, "1")},
+				"route-b": {Latency: 20 * time.Millisecond, Response: predictionsResponse(t, "1")},
+				"route-c": {Latency: 40 * time.Millisecond, Response: predictionsResponse(t, "2")},
+			},
+			expectedStatus:  int(codes.OK),
+			expectedRowIDs:  "2",
+			expectedBackend: "route-c",
+		},
+		"quorum not reached": {
+			quorum: 2,
+			responses: map[string]testUtilsHttp.DelayedResponse{
+				"route-a": {Response: predictionsResponse(t, "1")},
+				"route-b": {Response: predictionsResponse(t, "2")},
+				"route-c": {Response: predictionsResponse(t, "3")},
+			},
+			expectedStatus:   int(codes.Unavailable),
+			expectedErrorMsg: "fiber: quorum of 2 vote(s) not reached, the largest group has 1",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			fanIn, err := extras.NewQuorumFanIn(predictedRowIDs, tt.quorum)
+			require.NoError(t, err)
+			fanIn, err = fanIn.WithWeights(tt.weights)
+			require.NoError(t, err)
+
+			routes := make(map[string]fiber.Component)
+			for routeID, resp := range tt.responses {
+				routes[routeID] = testutils.NewMockComponent(routeID, resp)
+			}
+			combiner := fiber.NewCombiner("quorum").WithFanIn(fanIn)
+			combiner.SetRoutes(routes)
+
+			resp := <-combiner.Dispatch(context.Background(), &fiberGRPC.Request{Metadata: metadata.MD{}}).Iter()
+			require.Equal(t, tt.expectedStatus, resp.StatusCode())
+			if tt.expectedErrorMsg != "" {
+				assert.Contains(t, string(resp.Payload()), tt.expectedErrorMsg)
+				return
+			}
+			assert.Equal(t, tt.expectedRowIDs, predictedRowIDs(resp))
+			assert.Equal(t, tt.expectedBackend, resp.BackendName())
+		})
+	}
+}
+
+func TestQuorumFanIn_CancelsStragglers(t *testing.T) {
+	fanIn, err := extras.NewQuorumFanIn(predictedRowIDs, 2)
+	require.NoError(t, err)
+
+	straggler := &blockingComponent{
+		BaseComponent: fiber.NewBaseComponent("route-c", fiber.CallerKind),
+		cancelled:     make(chan struct{}),
+	}
+	combiner := fiber.NewCombiner("quorum").WithFanIn(fanIn)
+	combiner.SetRoutes(map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a",
+			testUtilsHttp.DelayedResponse{Response: predictionsResponse(t, "1")}),
+		"route-b": testutils.NewMockComponent("route-b",
+			testUtilsHttp.DelayedResponse{Response: predictionsResponse(t, "1")}),
+		"route-c": straggler,
+	})
+
+	resp := <-combiner.Dispatch(context.Background(), &fiberGRPC.Request{Metadata: metadata.MD{}}).Iter()
+	require.True(t, resp.IsSuccess())
+	assert.Equal(t, "1", predictedRowIDs(resp))
+
+	select {
+	case <-straggler.cancelled:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the straggler hasn't been cancelled after the quorum is reached")
+	}
+}
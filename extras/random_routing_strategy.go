@@ -3,31 +3,65 @@ package extras
 import (
 	"context"
 	"math/rand"
+	"sort"
+	"time"
 
 	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/util"
 )
 
-// RandomRoutingStrategy is just a reference implementation of a RoutingStrategy.
-// It randomly selects a primary route and all other routes are fallbacks (with no specific order)
+// defaultRand is the random source of the RandomRoutingStrategy-s, that are not seeded
+var defaultRand = util.NewShardedRand(time.Now().UnixNano())
+
+// RandomRoutingStrategy is the simplest implementation of a RoutingStrategy, that spreads the load
+// between the routes. It shuffles the routes on each call, so the first one is the primary route, and
+// the rest are the fallbacks, in random order. It needs no properties
 type RandomRoutingStrategy struct {
 	fiber.BaseFiberType
+
+	rand *util.ShardedRand
+}
+
+// WithSeed seeds the random source of the strategy
+func (s *RandomRoutingStrategy) WithSeed(seed int64) *RandomRoutingStrategy {
+	s.rand = util.NewShardedRand(seed)
+	return s
+}
+
+// WithRandSource replaces the random source of the strategy with the given one, e.g. to reproduce
+// the exact sequence of the selected routes in the tests
+func (s *RandomRoutingStrategy) WithRandSource(source rand.Source) *RandomRoutingStrategy {
+	s.rand = util.NewShardedRandFromSource(source)
+	return s
 }
 
 // SelectRoute on the RandomRoutingStrategy selects one of the given routes as the primary
-// route, at random, and sets the others as fallbacks
+// route, at random, and sets the others as fallbacks, in random order
 func (s *RandomRoutingStrategy) SelectRoute(
 	_ context.Context,
 	_ fiber.Request,
 	routes map[string]fiber.Component,
 ) (route fiber.Component, fallbacks []fiber.Component, err error) {
-	idx := rand.Intn(len(routes))
-	for _, child := range routes {
-		if idx == 0 {
-			route = child
-		} else {
-			fallbacks = append(fallbacks, child)
-		}
-		idx--
+	if len(routes) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]string, 0, len(routes))
+	for id := range routes {
+		ids = append(ids, id)
+	}
+	// sorted, so the order is reproducible with a seeded random source
+	sort.Strings(ids)
+	random := s.rand
+	if random == nil {
+		random = defaultRand
+	}
+	random.Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+
+	for _, id := range ids[1:] {
+		fallbacks = append(fallbacks, routes[id])
 	}
-	return route, fallbacks, nil
+	return routes[ids[0]], fallbacks, nil
 }
@@ -0,0 +1,96 @@
+package extras_test
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomRoutingStrategy_SelectRoute(t *testing.T) {
+	const iterations = 10000
+
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+		"route-d": testutils.NewMockComponent("route-d"),
+	}
+	strategy := new(extras.RandomRoutingStrategy).WithSeed(42)
+
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/random", "")
+	// positions[id][idx] is the number of times the route was at the idx position of the order
+	positions := make(map[string][]int)
+	for i := 0; i < iterations; i++ {
+		route, fallbacks, err := strategy.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+
+		ids := routeIDs(route, fallbacks)
+		assert.ElementsMatch(t, []string{"route-a", "route-b", "route-c", "route-d"}, ids)
+		for idx, id := range ids {
+			if positions[id] == nil {
+				positions[id] = make([]int, len(routes))
+			}
+			positions[id][idx]++
+		}
+	}
+
+	for id, counts := range positions {
+		for idx, count := range counts {
+			assert.InDelta(t, 0.25, float64(count)/iterations, 0.03, "route %s at position %d", id, idx)
+		}
+	}
+}
+
+func TestRandomRoutingStrategy_WithSeed(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+	}
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/random", "")
+
+	first, second := new(extras.RandomRoutingStrategy).WithSeed(7), new(extras.RandomRoutingStrategy).WithSeed(7)
+	for i := 0; i < 10; i++ {
+		route, fallbacks, err := first.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+		expected := routeIDs(route, fallbacks)
+
+		route, fallbacks, err = second.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+		assert.Equal(t, expected, routeIDs(route, fallbacks))
+	}
+}
+
+func TestRandomRoutingStrategy_WithRandSource(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+	}
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/random", "")
+
+	strategy := new(extras.RandomRoutingStrategy).WithRandSource(rand.NewSource(42))
+	primaries := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		route, fallbacks, err := strategy.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+		assert.Len(t, fallbacks, 2)
+		primaries = append(primaries, route.ID())
+	}
+	assert.Equal(t, []string{"route-c", "route-c", "route-b", "route-b", "route-a"}, primaries)
+}
+
+func TestRandomRoutingStrategy_NoRoutes(t *testing.T) {
+	route, fallbacks, err := new(extras.RandomRoutingStrategy).SelectRoute(
+		context.Background(), testUtilsHttp.MockReq("GET", "http://localhost:8080/random", ""), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, route)
+	assert.Empty(t, fallbacks)
+}
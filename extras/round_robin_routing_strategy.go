@@ -0,0 +1,118 @@
+package extras
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/gojek/fiber"
+)
+
+// RoundRobinRoutingStrategy spreads the load between the routes deterministically: each call selects
+// the next route as the primary one, so over a cycle of sum(weights) requests, each route is the primary
+// route exactly as many times, as its weight. The turns of the heavier routes are interleaved with the others,
+// e.g. the weights {"route-a": 2, "route-b": 1} give the cycle [route-a, route-b, route-a].
+// The rest of the routes are the fallbacks, in the round-robin order after the primary route.
+// Routes without a weight are weighted as 1, and the routes with the zero weight are only used as fallbacks
+type RoundRobinRoutingStrategy struct {
+	fiber.BaseFiberType
+
+	weights map[string]int
+	next    uint32
+}
+
+type roundRobinRoutingStrategyProperties struct {
+	Weights map[string]int `json:"weights"`
+}
+
+// NewRoundRobinRoutingStrategy is a creator factory for the RoundRobinRoutingStrategy
+func NewRoundRobinRoutingStrategy(weights map[string]int) (*RoundRobinRoutingStrategy, error) {
+	for routeID, weight := range weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("round robin routing strategy: negative weight of route: [%s]", routeID)
+		}
+	}
+	return &RoundRobinRoutingStrategy{weights: weights}, nil
+}
+
+// Initialize parses the properties of the strategy:
+//   - weights – optional weights of the routes, keyed by the route ID. Example `{"route-a": 2, "route-b": 1}`
+func (s *RoundRobinRoutingStrategy) Initialize(properties json.RawMessage) error {
+	var props roundRobinRoutingStrategyProperties
+	if len(properties) > 0 {
+		if err := json.Unmarshal(properties, &props); err != nil {
+			return fmt.Errorf("round robin routing strategy: failed to parse properties: %s", err)
+		}
+	}
+
+	strategy, err := NewRoundRobinRoutingStrategy(props.Weights)
+	if err != nil {
+		return err
+	}
+	s.weights = strategy.weights
+	return nil
+}
+
+// SelectRoute selects the route, which turn it is, as the primary route, and the others as fallbacks
+func (s *RoundRobinRoutingStrategy) SelectRoute(
+	_ context.Context,
+	_ fiber.Request,
+	routes map[string]fiber.Component,
+) (route fiber.Component, fallbacks []fiber.Component, err error) {
+	if len(routes) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]string, 0, len(routes))
+	for id := range routes {
+		ids = append(ids, id)
+	}
+	// sorted, so each call rotates the same order
+	sort.Strings(ids)
+	primary := s.primary(ids, atomic.AddUint32(&s.next, 1)-1)
+
+	for idx := 1; idx < len(ids); idx++ {
+		fallbacks = append(fallbacks, routes[ids[(primary+idx)%len(ids)]])
+	}
+	return routes[ids[primary]], fallbacks, nil
+}
+
+// primary returns the index of the route, which turn is the given one. The cycle consists of the rounds,
+// in which the routes with the weight larger than the number of the round take their turns in order
+func (s *RoundRobinRoutingStrategy) primary(ids []string, turn uint32) int {
+	weights := make([]int, len(ids))
+	total, rounds := 0, 0
+	for idx, id := range ids {
+		weights[idx] = s.weight(id)
+		total += weights[idx]
+		if weights[idx] > rounds {
+			rounds = weights[idx]
+		}
+	}
+	if total == 0 {
+		// all the routes have the zero weight, so they take equal turns
+		return int(turn % uint32(len(ids)))
+	}
+
+	slot := int(turn % uint32(total))
+	for round := 0; round < rounds; round++ {
+		for idx, weight := range weights {
+			if weight > round {
+				if slot == 0 {
+					return idx
+				}
+				slot--
+			}
+		}
+	}
+	return 0
+}
+
+func (s *RoundRobinRoutingStrategy) weight(routeID string) int {
+	if weight, ok := s.weights[routeID]; ok {
+		return weight
+	}
+	return 1
+}
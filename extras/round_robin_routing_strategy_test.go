@@ -0,0 +1,169 @@
+package extras_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundRobinRoutingStrategy_Initialize(t *testing.T) {
+	suite := map[string]struct {
+		properties string
+		expected   string
+	}{
+		"ok": {
+			properties: `{"weights": {"route-a": 2, "route-b": 1}}`,
+		},
+		"no properties": {},
+		"negative weight": {
+			properties: `{"weights": {"route-a": 2, "route-b": -1}}`,
+			expected:   "round robin routing strategy: negative weight of route: [route-b]",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			strategy := new(extras.RoundRobinRoutingStrategy)
+			err := strategy.Initialize(json.RawMessage(tt.properties))
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRoundRobinRoutingStrategy_SelectRoute(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+	}
+	strategy := new(extras.RoundRobinRoutingStrategy)
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/round-robin", "")
+
+	// the consecutive requests prefer the routes in sequence, and the rest are the fallbacks
+	for _, expected := range [][]string{
+		{"route-a", "route-b", "route-c"},
+		{"route-b", "route-c", "route-a"},
+		{"route-c", "route-a", "route-b"},
+		{"route-a", "route-b", "route-c"},
+	} {
+		route, fallbacks, err := strategy.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+		assert.Equal(t, expected, routeIDs(route, fallbacks))
+	}
+}
+
+func TestRoundRobinRoutingStrategy_Concurrent(t *testing.T) {
+	const requests, goroutines = 3000, 10
+
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+		"route-d": testutils.NewMockComponent("route-d"),
+	}
+
+	suite := map[string]struct {
+		weights  map[string]int
+		expected map[string]int
+	}{
+		"equal weights": {
+			expected: map[string]int{"route-a": 750, "route-b": 750, "route-c": 750, "route-d": 750},
+		},
+		// route-c has no weight, so it's weighted as 1
+		"weighted routes": {
+			weights:  map[string]int{"route-a": 3, "route-b": 1, "route-d": 0},
+			expected: map[string]int{"route-a": 1800, "route-b": 600, "route-c": 600},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			strategy, err := extras.NewRoundRobinRoutingStrategy(tt.weights)
+			require.NoError(t, err)
+			req := testUtilsHttp.MockReq("GET", "http://localhost:8080/round-robin", "")
+
+			var lock sync.Mutex
+			var wg sync.WaitGroup
+			primary := make(map[string]int)
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < requests/goroutines; i++ {
+						route, fallbacks, err := strategy.SelectRoute(context.Background(), req, routes)
+						require.NoError(t, err)
+						assert.Len(t, fallbacks, len(routes)-1)
+
+						lock.Lock()
+						primary[route.ID()]++
+						lock.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, tt.expected, primary)
+		})
+	}
+}
+
+func TestRoundRobinRoutingStrategy_Interleaved(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+	}
+	strategy, err := extras.NewRoundRobinRoutingStrategy(map[string]int{"route-a": 3})
+	require.NoError(t, err)
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/round-robin", "")
+
+	var order []string
+	for i := 0; i < 8; i++ {
+		route, _, err := strategy.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+		order = append(order, route.ID())
+	}
+	assert.Equal(t, []string{
+		"route-a", "route-b", "route-a", "route-a",
+		"route-a", "route-b", "route-a", "route-a",
+	}, order)
+}
+
+func TestRoundRobinRoutingStrategy_NoRoutes(t *testing.T) {
+	route, fallbacks, err := new(extras.RoundRobinRoutingStrategy).SelectRoute(
+		context.Background(), testUtilsHttp.MockReq("GET", "http://localhost:8080/round-robin", ""), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, route)
+	assert.Empty(t, fallbacks)
+}
+
+func TestRoundRobinRoutingStrategy_Dispatch(t *testing.T) {
+	strategy, err := extras.NewRoundRobinRoutingStrategy(map[string]int{"route-a": 2})
+	require.NoError(t, err)
+	router := testutils.NewRouterBuilder("round-robin-router").
+		WithRoute("route-a", testUtilsHttp.MockResp(200, "A-OK", nil, nil)).
+		WithRoute("route-b", testUtilsHttp.MockResp(200, "B-OK", nil, nil)).
+		WithStrategy(strategy).
+		Build()
+
+	counts := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		req := testUtilsHttp.MockReq("GET", "http://localhost:8080/round-robin", "")
+		responses := testutils.Dispatch(context.Background(), router, req)
+		require.Len(t, responses, 1)
+		counts[fiber.RouteID(responses[0])]++
+	}
+	// the weighted route takes two turns per cycle
+	assert.Equal(t, map[string]int{"route-a": 4, "route-b": 2}, counts)
+}
@@ -0,0 +1,159 @@
+package extras
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gojek/fiber"
+)
+
+// DefaultStickyCookieName is the name of the cookie with the route of the session, if it's not configured
+const DefaultStickyCookieName = "fiber_route"
+
+// StickyRoutingStrategy keeps the http client on the same route across its requests. The route of
+// the first request is selected by the delegate strategy, and is remembered in the cookie, that is
+// set on its response. The route from the cookie is then the primary route of the following requests,
+// and the routes of the delegate are the fallbacks. If the cookie is missing, malformed, has an invalid
+// signature or references a route, that doesn't exist anymore, the delegate strategy is used instead.
+//
+// The strategy can't access the responses, so it's also a fiber.ResponseInterceptor, that sets the cookie
+// on the response, and has to be added to the interceptors of the router. If the secret is
+// configured, the cookie is signed with HMAC-SHA256, so it can't be tampered with by the client
+type StickyRoutingStrategy struct {
+	fiber.BaseFiberType
+	fiber.NoopBeforeDispatchInterceptor
+	fiber.NoopAfterDispatchInterceptor
+	fiber.NoopAfterCompletionInterceptor
+
+	cookieName string
+	secret     []byte
+	delegate   fiber.RoutingStrategy
+}
+
+type stickyRoutingStrategyProperties struct {
+	CookieName string `json:"cookie_name"`
+	Secret     string `json:"secret"`
+}
+
+// NewStickyRoutingStrategy is a creator factory for the StickyRoutingStrategy. The cookie is not
+// signed, if the secret is empty
+func NewStickyRoutingStrategy(cookieName string, secret string) *StickyRoutingStrategy {
+	if cookieName == "" {
+		cookieName = DefaultStickyCookieName
+	}
+	return &StickyRoutingStrategy{
+		cookieName: cookieName,
+		secret:     []byte(secret),
+		delegate:   &RandomRoutingStrategy{},
+	}
+}
+
+// Initialize parses the properties of the strategy:
+//   - cookie_name – name of the cookie with the route of the session. Defaults to `fiber_route`
+//   - secret – the key to sign the cookie with. The cookie is not signed, if it's not set
+func (s *StickyRoutingStrategy) Initialize(properties json.RawMessage) error {
+	var props stickyRoutingStrategyProperties
+	if len(properties) > 0 {
+		if err := json.Unmarshal(properties, &props); err != nil {
+			return fmt.Errorf("sticky routing strategy: failed to parse properties: %s", err)
+		}
+	}
+
+	strategy := NewStickyRoutingStrategy(props.CookieName, props.Secret)
+	s.cookieName = strategy.cookieName
+	s.secret = strategy.secret
+	if s.delegate == nil {
+		s.delegate = strategy.delegate
+	}
+	return nil
+}
+
+// WithDelegate sets the routing strategy, that selects the route of the new sessions.
+// By default, RandomRoutingStrategy is used
+func (s *StickyRoutingStrategy) WithDelegate(delegate fiber.RoutingStrategy) *StickyRoutingStrategy {
+	s.delegate = delegate
+	return s
+}
+
+// SelectRoute selects the route from the cookie of the request as the primary route, and the routes,
+// selected by the delegate, as the fallbacks
+func (s *StickyRoutingStrategy) SelectRoute(
+	ctx context.Context,
+	req fiber.Request,
+	routes map[string]fiber.Component,
+) (route fiber.Component, fallbacks []fiber.Component, err error) {
+	route, fallbacks, err = s.delegate.SelectRoute(ctx, req, routes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sticky, ok := routes[s.routeID(req)]
+	if !ok || route == nil || route.ID() == sticky.ID() {
+		return route, fallbacks, nil
+	}
+
+	ordered := []fiber.Component{route}
+	for _, fallback := range fallbacks {
+		if fallback.ID() != sticky.ID() {
+			ordered = append(ordered, fallback)
+		}
+	}
+	return sticky, ordered, nil
+}
+
+// InterceptResponse sets the cookie with the route of the successful response, unless the request
+// already has the same one
+func (s *StickyRoutingStrategy) InterceptResponse(_ context.Context, req fiber.Request, resp fiber.Response) fiber.Response {
+	routeID := fiber.RouteID(resp)
+	if !resp.IsSuccess() || routeID == "" || s.routeID(req) == routeID {
+		return resp
+	}
+	if headers, ok := resp.(interface{ Header() http.Header }); ok {
+		cookie := &http.Cookie{Name: s.cookieName, Value: s.cookieValue(routeID), Path: "/", HttpOnly: true}
+		headers.Header().Add("Set-Cookie", cookie.String())
+	}
+	return resp
+}
+
+// routeID returns the route of the session from the cookie of the request, or an empty string,
+// if the request has no valid cookie
+func (s *StickyRoutingStrategy) routeID(req fiber.Request) string {
+	cookie, err := (&http.Request{Header: req.Header()}).Cookie(s.cookieName)
+	if err != nil {
+		return ""
+	}
+
+	encoded, signature := cookie.Value, ""
+	if idx := strings.IndexByte(cookie.Value, '.'); idx >= 0 {
+		encoded, signature = cookie.Value[:idx], cookie.Value[idx+1:]
+	}
+	if len(s.secret) > 0 && !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return ""
+	}
+	routeID, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ""
+	}
+	return string(routeID)
+}
+
+// cookieValue encodes the route ID (so it's a valid cookie value), and signs it, if the secret is configured
+func (s *StickyRoutingStrategy) cookieValue(routeID string) string {
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(routeID))
+	if len(s.secret) == 0 {
+		return encoded
+	}
+	return encoded + "." + s.sign(encoded)
+}
+
+func (s *StickyRoutingStrategy) sign(value string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
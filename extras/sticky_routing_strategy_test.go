@@ -0,0 +1,181 @@
+package extras_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	fiberHTTP "github.com/gojek/fiber/http"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stickyCookie returns the cookie, that the strategy sets on the response of the route
+func stickyCookie(t *testing.T, strategy *extras.StickyRoutingStrategy, routeID string) *http.Cookie {
+	resp := testUtilsHttp.MockResp(http.StatusOK, "", nil, nil).WithBackendName(routeID)
+	resp = strategy.InterceptResponse(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", ""), resp)
+	cookies := (&http.Response{Header: resp.(*fiberHTTP.Response).Header()}).Cookies()
+	require.Len(t, cookies, 1)
+	return cookies[0]
+}
+
+func TestStickyRoutingStrategy_Initialize(t *testing.T) {
+	suite := map[string]struct {
+		properties string
+		expected   string
+	}{
+		"ok": {
+			properties: `{"cookie_name": "session_route", "secret": "s3cr3t"}`,
+		},
+		"no properties": {},
+		"malformed properties": {
+			properties: `{"cookie_name": 1}`,
+			expected: "sticky routing strategy: failed to parse properties: " +
+				"json: cannot unmarshal number into Go struct field " +
+				"stickyRoutingStrategyProperties.cookie_name of type string",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			strategy := new(extras.StickyRoutingStrategy)
+			err := strategy.Initialize(json.RawMessage(tt.properties))
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStickyRoutingStrategy_SelectRoute(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+	}
+	signed := extras.NewStickyRoutingStrategy("session_route", "s3cr3t").
+		WithDelegate(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b", "route-c"}, 0, nil))
+	unsigned := extras.NewStickyRoutingStrategy("session_route", "").
+		WithDelegate(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b", "route-c"}, 0, nil))
+	otherSecret := extras.NewStickyRoutingStrategy("session_route", "other")
+
+	// the route of the signed cookie is replaced, but its signature is kept
+	tampered := stickyCookie(t, signed, "route-c")
+	tampered.Value = stickyCookie(t, unsigned, "route-b").Value + tampered.Value[strings.Index(tampered.Value, "."):]
+
+	suite := map[string]struct {
+		strategy *extras.StickyRoutingStrategy
+		cookie   *http.Cookie
+		expected []string
+	}{
+		"no cookie": {
+			strategy: signed,
+			expected: []string{"route-a", "route-b", "route-c"},
+		},
+		"signed cookie": {
+			strategy: signed,
+			cookie:   stickyCookie(t, signed, "route-c"),
+			expected: []string{"route-c", "route-a", "route-b"},
+		},
+		"cookie of the delegate's primary route": {
+			strategy: signed,
+			cookie:   stickyCookie(t, signed, "route-a"),
+			expected: []string{"route-a", "route-b", "route-c"},
+		},
+		"unsigned cookie": {
+			strategy: unsigned,
+			cookie:   stickyCookie(t, unsigned, "route-b"),
+			expected: []string{"route-b", "route-a", "route-c"},
+		},
+		"unsigned cookie, secret configured": {
+			strategy: signed,
+			cookie:   stickyCookie(t, unsigned, "route-b"),
+			expected: []string{"route-a", "route-b", "route-c"},
+		},
+		"cookie signed with another secret": {
+			strategy: signed,
+			cookie:   stickyCookie(t, otherSecret, "route-b"),
+			expected: []string{"route-a", "route-b", "route-c"},
+		},
+		"tampered cookie": {
+			strategy: signed,
+			cookie:   tampered,
+			expected: []string{"route-a", "route-b", "route-c"},
+		},
+		"malformed cookie": {
+			strategy: unsigned,
+			cookie:   &http.Cookie{Name: "session_route", Value: "***"},
+			expected: []string{"route-a", "route-b", "route-c"},
+		},
+		"stale route": {
+			strategy: signed,
+			cookie:   stickyCookie(t, signed, "route-d"),
+			expected: []string{"route-a", "route-b", "route-c"},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			req := testUtilsHttp.MockReq("GET", "http://localhost:8080/sticky", "")
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			route, fallbacks, err := tt.strategy.SelectRoute(context.Background(), req, routes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, routeIDs(route, fallbacks))
+		})
+	}
+}
+
+func TestStickyRoutingStrategy_Stickiness(t *testing.T) {
+	routes := make(map[string]fiber.Component)
+	for _, id := range []string{"route-a", "route-b", "route-c"} {
+		id := id
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(id))
+		}))
+		defer server.Close()
+
+		dispatcher, err := fiberHTTP.NewDispatcher(http.DefaultClient)
+		require.NoError(t, err)
+		caller, err := fiber.NewCaller(id, dispatcher)
+		require.NoError(t, err)
+		routes[id] = fiber.NewProxy(fiber.NewBackend(id, server.URL), caller)
+	}
+	strategy := extras.NewStickyRoutingStrategy("", "s3cr3t").WithDelegate(new(extras.RandomRoutingStrategy).WithSeed(42))
+	router := fiber.NewLazyRouter("router")
+	router.SetRoutes(routes)
+	router.SetStrategy(strategy)
+	router.AddInterceptor(false, strategy)
+	handler := fiberHTTP.NewHandler(router, fiberHTTP.Options{Timeout: time.Second})
+
+	// the first request is assigned to the route
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:8080/sticky", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assigned := recorder.Body.String()
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, extras.DefaultStickyCookieName, cookies[0].Name)
+
+	// the following requests stick to it, and the cookie is not set again
+	for i := 0; i < 20; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/sticky", nil)
+		req.AddCookie(cookies[0])
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, assigned, recorder.Body.String())
+		assert.Empty(t, recorder.Result().Cookies())
+	}
+}
@@ -0,0 +1,118 @@
+package extras
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/util"
+)
+
+// WeightedRandomRoutingStrategy orders the routes by a weighted random draw, so the probability of
+// a route to be selected as the primary route is proportional to its weight. The rest of the routes
+// are fallbacks, also ordered by the weighted draw. Routes without a weight are weighted as 1
+type WeightedRandomRoutingStrategy struct {
+	fiber.BaseFiberType
+
+	weights map[string]float64
+	rand    *util.ShardedRand
+}
+
+// WeightedShuffleRoutingStrategy is the WeightedRandomRoutingStrategy under the name, that tells, that it draws
+// the whole order of the routes by their weights, not just the primary route: each fallback is drawn by weight
+// from the routes, that haven't been drawn yet, so the retries spread the load by the weights too
+type WeightedShuffleRoutingStrategy = WeightedRandomRoutingStrategy
+
+type weightedRandomRoutingStrategyProperties struct {
+	Weights map[string]float64 `json:"weights"`
+}
+
+// NewWeightedRandomRoutingStrategy is a creator factory for the WeightedRandomRoutingStrategy
+func NewWeightedRandomRoutingStrategy(weights map[string]float64) (*WeightedRandomRoutingStrategy, error) {
+	for routeID, weight := range weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("weighted random routing strategy: negative weight of route: [%s]", routeID)
+		}
+	}
+	return &WeightedRandomRoutingStrategy{
+		weights: weights,
+		rand:    util.NewShardedRand(time.Now().UnixNano()),
+	}, nil
+}
+
+// Initialize parses the properties of the strategy:
+//   - weights – weights of the routes, keyed by the route ID. Example `{"route-a": 9, "route-b": 1}`
+func (s *WeightedRandomRoutingStrategy) Initialize(properties json.RawMessage) error {
+	var props weightedRandomRoutingStrategyProperties
+	if len(properties) > 0 {
+		if err := json.Unmarshal(properties, &props); err != nil {
+			return fmt.Errorf("weighted random routing strategy: failed to parse properties: %s", err)
+		}
+	}
+
+	strategy, err := NewWeightedRandomRoutingStrategy(props.Weights)
+	if err != nil {
+		return err
+	}
+	s.weights = strategy.weights
+	s.rand = strategy.rand
+	return nil
+}
+
+// WithSeed seeds the random source of the strategy
+func (s *WeightedRandomRoutingStrategy) WithSeed(seed int64) *WeightedRandomRoutingStrategy {
+	s.rand = util.NewShardedRand(seed)
+	return s
+}
+
+// WithRandSource replaces the random source of the strategy with the given one, e.g. to reproduce
+// the exact sequence of the selected routes in the tests
+func (s *WeightedRandomRoutingStrategy) WithRandSource(source rand.Source) *WeightedRandomRoutingStrategy {
+	s.rand = util.NewShardedRandFromSource(source)
+	return s
+}
+
+// SelectRoute selects the primary route and the order of fallbacks by a weighted random draw
+func (s *WeightedRandomRoutingStrategy) SelectRoute(
+	_ context.Context,
+	_ fiber.Request,
+	routes map[string]fiber.Component,
+) (route fiber.Component, fallbacks []fiber.Component, err error) {
+	if len(routes) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]string, 0, len(routes))
+	for id := range routes {
+		ids = append(ids, id)
+	}
+	// sorted, so the order is reproducible with a seeded random source
+	sort.Strings(ids)
+	s.rand.WeightedShuffle(ids, s.weight)
+
+	for _, id := range ids[1:] {
+		fallbacks = append(fallbacks, routes[id])
+	}
+	return routes[ids[0]], fallbacks, nil
+}
+
+// RouteScores returns the weights of the routes (see fiber.RouteScorer)
+func (s *WeightedRandomRoutingStrategy) RouteScores(routes map[string]fiber.Component) map[string]float64 {
+	scores := make(map[string]float64, len(routes))
+	for id := range routes {
+		scores[id] = s.weight(id)
+	}
+	return scores
+}
+
+// weight returns the weight of the route, or 1, if it has no weight
+func (s *WeightedRandomRoutingStrategy) weight(routeID string) float64 {
+	if weight, ok := s.weights[routeID]; ok {
+		return weight
+	}
+	return 1
+}
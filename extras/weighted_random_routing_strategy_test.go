@@ -0,0 +1,165 @@
+package extras_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeightedRandomRoutingStrategy_Initialize(t *testing.T) {
+	suite := map[string]struct {
+		properties string
+		expected   string
+	}{
+		"ok": {
+			properties: `{"weights": {"route-a": 9, "route-b": 1}}`,
+		},
+		"no properties": {},
+		"negative weight": {
+			properties: `{"weights": {"route-a": 9, "route-b": -1}}`,
+			expected:   "weighted random routing strategy: negative weight of route: [route-b]",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			strategy := new(extras.WeightedRandomRoutingStrategy)
+			err := strategy.Initialize(json.RawMessage(tt.properties))
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWeightedRandomRoutingStrategy_SelectRoute(t *testing.T) {
+	const iterations = 10000
+
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+		"route-d": testutils.NewMockComponent("route-d"),
+	}
+	// route-c has no weight, so it's weighted as 1
+	strategy, err := extras.NewWeightedRandomRoutingStrategy(
+		map[string]float64{"route-a": 6, "route-b": 3, "route-d": 0})
+	require.NoError(t, err)
+	strategy.WithSeed(42)
+
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/weighted", "")
+	primary := make(map[string]int)
+	for i := 0; i < iterations; i++ {
+		route, fallbacks, err := strategy.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+		require.Len(t, fallbacks, len(routes)-1)
+
+		unique := map[string]bool{route.ID(): true}
+		for _, fallback := range fallbacks {
+			unique[fallback.ID()] = true
+		}
+		assert.Len(t, unique, len(routes))
+		assert.Equal(t, "route-d", fallbacks[len(fallbacks)-1].ID(), "route with zero weight should be the last")
+
+		primary[route.ID()]++
+	}
+
+	assert.InDelta(t, 0.6, float64(primary["route-a"])/iterations, 0.03)
+	assert.InDelta(t, 0.3, float64(primary["route-b"])/iterations, 0.03)
+	assert.InDelta(t, 0.1, float64(primary["route-c"])/iterations, 0.03)
+	assert.Zero(t, primary["route-d"])
+}
+
+func TestWeightedRandomRoutingStrategy_WithRandSource(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+	}
+	strategy, err := extras.NewWeightedRandomRoutingStrategy(map[string]float64{"route-a": 6, "route-b": 3})
+	require.NoError(t, err)
+	strategy.WithRandSource(rand.NewSource(42))
+
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/weighted", "")
+	primaries := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		route, _, err := strategy.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+		primaries = append(primaries, route.ID())
+	}
+	assert.Equal(t, []string{"route-b", "route-b", "route-b", "route-a", "route-b"}, primaries)
+}
+
+func TestWeightedShuffleRoutingStrategy_Order(t *testing.T) {
+	const iterations = 20000
+
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a"),
+		"route-b": testutils.NewMockComponent("route-b"),
+		"route-c": testutils.NewMockComponent("route-c"),
+	}
+	strategy, err := extras.NewWeightedRandomRoutingStrategy(
+		map[string]float64{"route-a": 6, "route-b": 3, "route-c": 1})
+	require.NoError(t, err)
+	var shuffle *extras.WeightedShuffleRoutingStrategy = strategy.WithSeed(7)
+
+	// the probability of each order is the product of the weighted draws without replacement,
+	// e.g. P(a, b, c) = 6/10 * 3/4
+	expectedOrders := map[string]float64{
+		"route-a,route-b,route-c": 6.0 / 10 * 3 / 4,
+		"route-a,route-c,route-b": 6.0 / 10 * 1 / 4,
+		"route-b,route-a,route-c": 3.0 / 10 * 6 / 7,
+		"route-b,route-c,route-a": 3.0 / 10 * 1 / 7,
+		"route-c,route-a,route-b": 1.0 / 10 * 6 / 9,
+		"route-c,route-b,route-a": 1.0 / 10 * 3 / 9,
+	}
+	expectedPositions := []map[string]float64{
+		{"route-a": 0.6, "route-b": 0.3, "route-c": 0.1},
+		{},
+		{},
+	}
+	for order, p := range expectedOrders {
+		for position, id := range strings.Split(order, ",")[1:] {
+			expectedPositions[position+1][id] += p
+		}
+	}
+
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/weighted", "")
+	orders := make(map[string]int)
+	positions := []map[string]int{{}, {}, {}}
+	for i := 0; i < iterations; i++ {
+		route, fallbacks, err := shuffle.SelectRoute(context.Background(), req, routes)
+		require.NoError(t, err)
+		ids := []string{route.ID()}
+		for _, fallback := range fallbacks {
+			ids = append(ids, fallback.ID())
+		}
+		require.Len(t, ids, len(routes))
+
+		orders[strings.Join(ids, ",")]++
+		for position, id := range ids {
+			positions[position][id]++
+		}
+	}
+
+	for order, p := range expectedOrders {
+		assert.InDelta(t, p, float64(orders[order])/iterations, 0.015, "order [%s]", order)
+	}
+	for position, expected := range expectedPositions {
+		for id, p := range expected {
+			assert.InDelta(t, p, float64(positions[position][id])/iterations, 0.015,
+				"route [%s] at position %d", id, position)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package extras
+
+import (
+	"context"
+
+	"github.com/gojek/fiber"
+
+	"go.uber.org/zap"
+)
+
+// ZapLogger is a fiber.Logger, that writes the routing decisions of the routers as the structured
+// zap log entries. Triggered fallbacks are logged with the warning level, recovered panics with
+// the error level, the other decisions with the info level. It's a fiber.AccessLogger too, that writes
+// the access log records with the info level
+type ZapLogger struct {
+	logger *zap.Logger
+}
+
+// NewZapLogger is a factory method, that creates a ZapLogger, that writes to the given zap logger
+func NewZapLogger(logger *zap.Logger) *ZapLogger {
+	return &ZapLogger{logger: logger}
+}
+
+// Log writes the log entry with its fields
+func (l *ZapLogger) Log(_ context.Context, entry fiber.LogEntry) {
+	fields := []zap.Field{
+		zap.String("correlation_id", entry.CorrelationID),
+		zap.String("router", entry.RouterID),
+		zap.String("route", entry.RouteID),
+		zap.String("protocol", string(entry.Protocol)),
+	}
+	if entry.Status != 0 {
+		fields = append(fields, zap.Int("status", entry.Status))
+	}
+	if entry.Latency != 0 {
+		fields = append(fields, zap.Duration("latency", entry.Latency))
+	}
+
+	if entry.Error != "" {
+		fields = append(fields, zap.String("error", entry.Error))
+	}
+	if entry.Stack != "" {
+		fields = append(fields, zap.String("stack", entry.Stack))
+	}
+
+	switch entry.Event {
+	case fiber.FallbackTriggeredEvent:
+		l.logger.Warn(string(entry.Event), fields...)
+	case fiber.PanicRecoveredEvent:
+		l.logger.Error(string(entry.Event), fields...)
+	default:
+		l.logger.Info(string(entry.Event), fields...)
+	}
+}
+
+// LogAccess writes the access log record of the dispatch with its fields
+func (l *ZapLogger) LogAccess(_ context.Context, entry fiber.AccessLogEntry) {
+	l.logger.Info("access",
+		zap.String("correlation_id", entry.CorrelationID),
+		zap.String("router", entry.RouterID),
+		zap.String("route", entry.RouteID),
+		zap.String("protocol", string(entry.Protocol)),
+		zap.Int("attempts", entry.Attempts),
+		zap.Int("status", entry.Status),
+		zap.Duration("latency", entry.Latency),
+	)
+}
@@ -0,0 +1,125 @@
+package extras_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLogger_Log(t *testing.T) {
+	tests := []struct {
+		name           string
+		entry          fiber.LogEntry
+		expectedLevel  zapcore.Level
+		expectedFields map[string]interface{}
+	}{
+		{
+			name: "route attempt finished",
+			entry: fiber.LogEntry{
+				Event:         fiber.RouteAttemptFinishedEvent,
+				CorrelationID: "request-1",
+				RouterID:      "router",
+				RouteID:       "route-a",
+				Protocol:      protocol.HTTP,
+				Status:        200,
+				Latency:       15 * time.Millisecond,
+			},
+			expectedLevel: zapcore.InfoLevel,
+			expectedFields: map[string]interface{}{
+				"correlation_id": "request-1",
+				"router":         "router",
+				"route":          "route-a",
+				"protocol":       "HTTP",
+				"status":         int64(200),
+				"latency":        15 * time.Millisecond,
+			},
+		},
+		{
+			name: "fallback triggered",
+			entry: fiber.LogEntry{
+				Event:         fiber.FallbackTriggeredEvent,
+				CorrelationID: "request-2",
+				RouterID:      "router",
+				RouteID:       "route-b",
+				Protocol:      protocol.GRPC,
+			},
+			expectedLevel: zapcore.WarnLevel,
+			expectedFields: map[string]interface{}{
+				"correlation_id": "request-2",
+				"router":         "router",
+				"route":          "route-b",
+				"protocol":       "GRPC",
+			},
+		},
+		{
+			name: "panic recovered",
+			entry: fiber.LogEntry{
+				Event:         fiber.PanicRecoveredEvent,
+				CorrelationID: "request-3",
+				RouterID:      "router",
+				RouteID:       "route-a",
+				Protocol:      protocol.HTTP,
+				Error:         "recovered panic: boom",
+				Stack:         "goroutine 1 [running]:",
+			},
+			expectedLevel: zapcore.ErrorLevel,
+			expectedFields: map[string]interface{}{
+				"correlation_id": "request-3",
+				"router":         "router",
+				"route":          "route-a",
+				"protocol":       "HTTP",
+				"error":          "recovered panic: boom",
+				"stack":          "goroutine 1 [running]:",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			extras.NewZapLogger(zap.New(core)).Log(context.Background(), tt.entry)
+
+			entries := logs.AllUntimed()
+			require.Len(t, entries, 1)
+			assert.Equal(t, string(tt.entry.Event), entries[0].Message)
+			assert.Equal(t, tt.expectedLevel, entries[0].Level)
+			assert.Equal(t, tt.expectedFields, entries[0].ContextMap())
+		})
+	}
+}
+
+func TestZapLogger_LogAccess(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	extras.NewZapLogger(zap.New(core)).LogAccess(context.Background(), fiber.AccessLogEntry{
+		CorrelationID: "request-1",
+		RouterID:      "router",
+		RouteID:       "route-b",
+		Protocol:      protocol.HTTP,
+		Attempts:      2,
+		Status:        200,
+		Latency:       15 * time.Millisecond,
+	})
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "access", entries[0].Message)
+	assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	assert.Equal(t, map[string]interface{}{
+		"correlation_id": "request-1",
+		"router":         "router",
+		"route":          "route-b",
+		"protocol":       "HTTP",
+		"attempts":       int64(2),
+		"status":         int64(200),
+		"latency":        15 * time.Millisecond,
+	}, entries[0].ContextMap())
+}
@@ -0,0 +1,54 @@
+package fiber
+
+import (
+	"github.com/gojek/fiber/protocol"
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultGRPCTerminalStatusCodes are the grpc status codes, on which the routers don't fall back to the
+// next route by default, since the request would fail with the same status on any other route
+var DefaultGRPCTerminalStatusCodes = []int{
+	int(codes.InvalidArgument),
+	int(codes.NotFound),
+	int(codes.AlreadyExists),
+	int(codes.PermissionDenied),
+	int(codes.FailedPrecondition),
+	int(codes.OutOfRange),
+	int(codes.Unimplemented),
+	int(codes.Unauthenticated),
+}
+
+var defaultGRPCTerminalStatuses = newFailureStatuses(DefaultGRPCTerminalStatusCodes)
+
+// failureStatuses is the set of the status codes of the responses, that a router treats as failed,
+// and falls back to the next route, on top of all the non-successful responses, except the grpc
+// responses with DefaultGRPCTerminalStatusCodes, which are treated as failed by default
+type failureStatuses map[int]struct{}
+
+func newFailureStatuses(codes []int) failureStatuses {
+	if len(codes) == 0 {
+		return nil
+	}
+	statuses := make(failureStatuses, len(codes))
+	for _, code := range codes {
+		statuses[code] = struct{}{}
+	}
+	return statuses
+}
+
+// isFailure returns true, if the router should fall back to the next route on the response
+func (s failureStatuses) isFailure(proto protocol.Protocol, resp Response) bool {
+	if s.contains(resp.StatusCode()) {
+		return true
+	}
+	if resp.IsSuccess() {
+		return false
+	}
+	return proto != protocol.GRPC || !defaultGRPCTerminalStatuses.contains(resp.StatusCode())
+}
+
+// contains returns true, if the status code is in the set
+func (s failureStatuses) contains(code int) bool {
+	_, ok := s[code]
+	return ok
+}
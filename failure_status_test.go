@@ -0,0 +1,100 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failureStatusRouter interface {
+	fiber.Router
+	SetFailureStatusCodes(codes ...int)
+}
+
+func TestRouter_FailureStatusCodes(t *testing.T) {
+	suite := map[string]struct {
+		primary        int
+		fallback       int
+		failureCodes   []int
+		expectedStatus int
+		expectedRoute  string
+	}{
+		"default: non-successful response falls back": {
+			primary:        http.StatusServiceUnavailable,
+			expectedStatus: http.StatusOK,
+			expectedRoute:  "route-b",
+		},
+		"successful response in the failure set falls back": {
+			primary:        http.StatusOK,
+			fallback:       http.StatusAccepted,
+			failureCodes:   []int{http.StatusOK, http.StatusTooManyRequests},
+			expectedStatus: http.StatusAccepted,
+			expectedRoute:  "route-b",
+		},
+		"non-successful response in the failure set falls back": {
+			primary:        http.StatusTooManyRequests,
+			failureCodes:   []int{http.StatusTooManyRequests},
+			expectedStatus: http.StatusOK,
+			expectedRoute:  "route-b",
+		},
+		"non-successful response not in the failure set falls back": {
+			primary:        http.StatusNotFound,
+			failureCodes:   []int{http.StatusTooManyRequests},
+			expectedStatus: http.StatusOK,
+			expectedRoute:  "route-b",
+		},
+		"successful response not in the failure set is sent back": {
+			primary:        http.StatusOK,
+			failureCodes:   []int{http.StatusTooManyRequests},
+			expectedStatus: http.StatusOK,
+			expectedRoute:  "route-a",
+		},
+	}
+
+	newRouters := map[string]func() failureStatusRouter{
+		"lazy router":  func() failureStatusRouter { return fiber.NewLazyRouter("lazy-router") },
+		"eager router": func() failureStatusRouter { return fiber.NewEagerRouter("eager-router") },
+	}
+
+	for name, tt := range suite {
+		for routerName, newRouter := range newRouters {
+			t.Run(routerName+": "+name, func(t *testing.T) {
+				var primaryErr error
+				if tt.primary >= http.StatusBadRequest {
+					primaryErr = &fiberErrors.FiberError{Code: tt.primary, Message: "A-NOK"}
+				}
+				fallback := tt.fallback
+				if fallback == 0 {
+					fallback = http.StatusOK
+				}
+				routes := map[string]fiber.Component{
+					"route-a": testutils.NewMockComponent("route-a", testUtilsHttp.DelayedResponse{
+						Response: testUtilsHttp.MockResp(tt.primary, "A", nil, primaryErr),
+					}),
+					"route-b": testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+						Response: testUtilsHttp.MockResp(fallback, "B", nil, nil),
+					}),
+				}
+				router := newRouter()
+				router.SetRoutes(routes)
+				router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+				router.SetFailureStatusCodes(tt.failureCodes...)
+
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				resp, ok := <-router.Dispatch(ctx, testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+				require.True(t, ok)
+				assert.Equal(t, tt.expectedStatus, resp.StatusCode())
+				assert.Equal(t, tt.expectedRoute, resp.BackendName())
+			})
+		}
+	}
+}
@@ -2,7 +2,10 @@ package fiber
 
 import (
 	"context"
+	"math/rand"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/gojek/fiber/util"
 )
@@ -18,6 +21,13 @@ type FanOut interface {
 // BaseFanOut is a component, that dispatches incoming request by each of its nested sub-routes
 type BaseFanOut struct {
 	*BaseMultiRouteComponent
+
+	subsetSize     int
+	maxConcurrency int
+	queueCapacity  int
+	queueOverflow  QueueOverflowPolicy
+	weights        map[string]float64
+	rand           *util.ShardedRand
 }
 
 // NewFanOut initializes a new BaseFanOut component and assigns to it a generated unique ID
@@ -28,31 +38,133 @@ func NewFanOut(id string) *BaseFanOut {
 	}
 	return &BaseFanOut{
 		BaseMultiRouteComponent: NewMultiRouteComponent(id),
+		rand:                    util.NewShardedRand(time.Now().UnixNano()),
 	}
 }
 
+// WithSubset makes the BaseFanOut dispatch each incoming request by a weighted random subset
+// of its routes of the given size, instead of all of them. Routes, that have no weight in
+// the map, are weighted as 1, and routes with zero weight are only selected, when there are
+// not enough routes with positive weights. Zero size disables the subset selection
+func (fanOut *BaseFanOut) WithSubset(size int, weights map[string]float64) *BaseFanOut {
+	fanOut.subsetSize = size
+	fanOut.weights = weights
+	return fanOut
+}
+
+// WithMaxConcurrency limits the number of the routes, that dispatch the request at the same time. The rest
+// of the routes are queued, and each of them dispatches the request, once one of the in-flight routes has responded.
+// The routes are queued in the weighted random order (see WithSubset), so the routes with larger weights are more
+// likely to be dispatched first. Zero limit dispatches the request by all the routes at once
+func (fanOut *BaseFanOut) WithMaxConcurrency(limit int) *BaseFanOut {
+	fanOut.maxConcurrency = limit
+	return fanOut
+}
+
+// WithQueueCapacity bounds the response queue of the BaseFanOut, so it holds at most capacity responses
+// of its routes, that haven't been received by the fan-in yet (see NewBoundedResponseQueue). When the queue
+// is full, the routes either wait for the fan-in, or their responses are dropped, depending on the overflow
+// policy. Zero capacity makes the queue unbounded
+func (fanOut *BaseFanOut) WithQueueCapacity(capacity int, overflow QueueOverflowPolicy) *BaseFanOut {
+	fanOut.queueCapacity = capacity
+	fanOut.queueOverflow = overflow
+	return fanOut
+}
+
+// WithSeed seeds the random source used by the BaseFanOut to select the subsets of routes
+func (fanOut *BaseFanOut) WithSeed(seed int64) *BaseFanOut {
+	fanOut.rand = util.NewShardedRand(seed)
+	return fanOut
+}
+
+// WithRandSource replaces the random source used by the BaseFanOut to select the subsets of routes and
+// the order of the queued routes with the given one, e.g. to reproduce the exact selection in the tests
+func (fanOut *BaseFanOut) WithRandSource(source rand.Source) *BaseFanOut {
+	fanOut.rand = util.NewShardedRandFromSource(source)
+	return fanOut
+}
+
+// selectRoutes returns all the routes of the BaseFanOut, or their weighted random subset,
+// if it's configured. The routes are in the weighted random order, if the concurrency is limited
+func (fanOut *BaseFanOut) selectRoutes() []Component {
+	ids := make([]string, 0, len(fanOut.routes))
+	for id := range fanOut.routes {
+		ids = append(ids, id)
+	}
+
+	subset := fanOut.subsetSize > 0 && fanOut.subsetSize < len(ids)
+	if !subset && (fanOut.maxConcurrency <= 0 || fanOut.maxConcurrency >= len(ids)) {
+		routes := make([]Component, 0, len(ids))
+		for _, id := range ids {
+			routes = append(routes, fanOut.routes[id])
+		}
+		return routes
+	}
+
+	// sorted, so the selected subsets are reproducible with a seeded random source
+	sort.Strings(ids)
+	fanOut.rand.WeightedShuffle(ids, func(id string) float64 {
+		if weight, ok := fanOut.weights[id]; ok {
+			return weight
+		}
+		return 1
+	})
+
+	size := len(ids)
+	if subset {
+		size = fanOut.subsetSize
+	}
+	routes := make([]Component, size)
+	for idx := range routes {
+		routes[idx] = fanOut.routes[ids[idx]]
+	}
+	return routes
+}
+
 // Dispatch creates a copy of incoming request (one for each sub-route), asynchronously dispatches
 // these request by its children components and then merges response channels into a
-// single response channel with zero or more responseQueue in it
+// single response channel with zero or more responseQueue in it. If the concurrency is limited
+// (see WithMaxConcurrency), the queued routes are not dispatched, once the context is done
 func (fanOut *BaseFanOut) Dispatch(ctx context.Context, req Request) ResponseQueue {
+	return fanOut.intercept(ctx, req, fanOut.dispatch)
+}
+
+func (fanOut *BaseFanOut) dispatch(ctx context.Context, req Request) ResponseQueue {
+	// the id is set on the request, before it's copied for the routes, so all of them send the same one
+	RequestID(req)
 	ctx = fanOut.beforeDispatch(ctx, req)
-	out := make(chan Response, len(fanOut.routes))
+	routes := fanOut.selectRoutes()
+	out := make(chan Response, len(routes))
 
-	queue := NewResponseQueue(out, len(fanOut.routes))
+	queue := NewBoundedResponseQueue(out, len(routes), fanOut.queueCapacity, fanOut.queueOverflow, req.Protocol())
 	defer fanOut.afterDispatch(ctx, req, queue)
 
 	go func() {
 		defer fanOut.afterCompletion(ctx, req, queue)
 
 		var wg sync.WaitGroup
-		wg.Add(len(fanOut.routes))
+		// slots of the in-flight routes, if the concurrency is limited
+		var slots chan struct{}
+		if fanOut.maxConcurrency > 0 && fanOut.maxConcurrency < len(routes) {
+			slots = make(chan struct{}, fanOut.maxConcurrency)
+		}
 
-		for _, route := range fanOut.routes {
+	dispatch:
+		for _, route := range routes {
+			if slots != nil {
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					// the queued routes are not dispatched, since the request is already done
+					break dispatch
+				}
+			}
+			wg.Add(1)
 			go func(route Component) {
 				// Make a copy of incoming request for each sub-name
 				copyReq, _ := req.Clone()
 
-				in := route.Dispatch(ctx, copyReq).Iter()
+				in := safeDispatch(ctx, ctxDispatchLogger(ctx), route, copyReq).Iter()
 
 				for {
 					select {
@@ -65,6 +177,9 @@ func (fanOut *BaseFanOut) Dispatch(ctx context.Context, req Request) ResponseQue
 					}
 					break
 				}
+				if slots != nil {
+					<-slots
+				}
 				wg.Done()
 			}(route)
 		}
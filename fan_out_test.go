@@ -3,6 +3,7 @@ package fiber_test
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -154,3 +155,160 @@ func TestFanOut_Dispatch(t *testing.T) {
 		}
 	}
 }
+
+func TestFanOut_DispatchSubset(t *testing.T) {
+	const (
+		iterations = 1000
+		subsetSize = 3
+	)
+
+	routes := make(map[string]fiber.Component)
+	for idx := 0; idx < 10; idx++ {
+		id := fmt.Sprintf("route-%d", idx)
+		routes[id] = testutils.NewMockComponent(
+			id, testUtilsHttp.DelayedResponse{Response: testUtilsHttp.MockResp(200, "OK", nil, nil)})
+	}
+	weights := map[string]float64{"route-0": 10, "route-9": 0}
+
+	dispatchSubsets := func(fanOut *fiber.BaseFanOut) [][]string {
+		subsets := make([][]string, 0, iterations)
+		for i := 0; i < iterations; i++ {
+			subset := make([]string, 0, subsetSize)
+			for resp := range fanOut.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://test:8080", "")).Iter() {
+				subset = append(subset, fiber.RouteID(resp))
+			}
+			subsets = append(subsets, subset)
+		}
+		return subsets
+	}
+
+	fanOut := fiber.NewFanOut("").WithSubset(subsetSize, weights).WithSeed(42)
+	fanOut.SetRoutes(routes)
+	subsets := dispatchSubsets(fanOut)
+
+	counts := make(map[string]int)
+	for _, subset := range subsets {
+		assert.Len(t, subset, subsetSize)
+		unique := make(map[string]bool)
+		for _, id := range subset {
+			unique[id] = true
+			counts[id]++
+		}
+		assert.Len(t, unique, subsetSize, "routes in the subset should be unique")
+	}
+
+	assert.Zero(t, counts["route-9"], "route with zero weight should not be selected")
+	assert.Greater(t, counts["route-0"], iterations*8/10, "heavier route should be selected more often")
+	for idx := 1; idx < 9; idx++ {
+		count := counts[fmt.Sprintf("route-%d", idx)]
+		assert.Greater(t, count, 0)
+		assert.Less(t, count, counts["route-0"])
+	}
+
+	seeded := fiber.NewFanOut("").WithSubset(subsetSize, weights).WithSeed(42)
+	seeded.SetRoutes(routes)
+	for idx, subset := range dispatchSubsets(seeded) {
+		assert.ElementsMatch(t, subsets[idx], subset, "subsets should be reproducible with the same seed")
+	}
+}
+
+// concurrencyTracker tracks the number of the in-flight dispatches of its components
+type concurrencyTracker struct {
+	inFlight   int32
+	maxFlight  int32
+	dispatched int32
+}
+
+func (c *concurrencyTracker) component(id string, latency time.Duration) fiber.Component {
+	return &trackedComponent{BaseComponent: fiber.NewBaseComponent(id, fiber.CallerKind), tracker: c, latency: latency}
+}
+
+type trackedComponent struct {
+	*fiber.BaseComponent
+	tracker *concurrencyTracker
+	latency time.Duration
+}
+
+func (c *trackedComponent) Dispatch(context.Context, fiber.Request) fiber.ResponseQueue {
+	atomic.AddInt32(&c.tracker.dispatched, 1)
+	inFlight := atomic.AddInt32(&c.tracker.inFlight, 1)
+	for {
+		maxFlight := atomic.LoadInt32(&c.tracker.maxFlight)
+		if inFlight <= maxFlight || atomic.CompareAndSwapInt32(&c.tracker.maxFlight, maxFlight, inFlight) {
+			break
+		}
+	}
+
+	out := make(chan fiber.Response, 1)
+	go func() {
+		defer close(out)
+		time.Sleep(c.latency)
+		atomic.AddInt32(&c.tracker.inFlight, -1)
+		out <- testUtilsHttp.MockResp(200, c.ID(), nil, nil)
+	}()
+	return fiber.NewResponseQueue(out, 1)
+}
+
+func TestFanOut_DispatchMaxConcurrency(t *testing.T) {
+	const routesCount = 8
+
+	suite := map[string]struct {
+		limit       int
+		expectedMax int32
+	}{
+		"unlimited": {
+			expectedMax: routesCount,
+		},
+		"one route at a time": {
+			limit:       1,
+			expectedMax: 1,
+		},
+		"three routes at a time": {
+			limit:       3,
+			expectedMax: 3,
+		},
+		"limit above the number of routes": {
+			limit:       routesCount + 1,
+			expectedMax: routesCount,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			tracker := new(concurrencyTracker)
+			routes := make(map[string]fiber.Component)
+			for idx := 0; idx < routesCount; idx++ {
+				id := fmt.Sprintf("route-%d", idx)
+				routes[id] = tracker.component(id, 10*time.Millisecond)
+			}
+			fanOut := fiber.NewFanOut("").WithMaxConcurrency(tt.limit)
+			fanOut.SetRoutes(routes)
+
+			var received []string
+			for resp := range fanOut.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://test:8080", "")).Iter() {
+				received = append(received, resp.BackendName())
+			}
+
+			assert.Len(t, received, routesCount, "all the routes should respond")
+			assert.Equal(t, tt.expectedMax, atomic.LoadInt32(&tracker.maxFlight))
+		})
+	}
+
+	t.Run("queued routes are not dispatched after the context is done", func(t *testing.T) {
+		tracker := new(concurrencyTracker)
+		routes := make(map[string]fiber.Component)
+		for idx := 0; idx < routesCount; idx++ {
+			id := fmt.Sprintf("route-%d", idx)
+			routes[id] = tracker.component(id, 50*time.Millisecond)
+		}
+		fanOut := fiber.NewFanOut("").WithMaxConcurrency(2)
+		fanOut.SetRoutes(routes)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		for range fanOut.Dispatch(ctx, testUtilsHttp.MockReq("GET", "http://test:8080", "")).Iter() {
+		}
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(&tracker.dispatched))
+	})
+}
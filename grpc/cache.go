@@ -0,0 +1,38 @@
+package grpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gojek/fiber"
+)
+
+// CacheKey returns the fiber.CacheKeyFunc, that keys the grpc requests on the hash of their message
+// and the values of the given metadata keys. The service method is not a part of the key, since
+// each Dispatcher invokes a single method. The responses of the streaming methods are never cached
+func CacheKey(metadataKeys ...string) fiber.CacheKeyFunc {
+	return func(req fiber.Request) (string, bool) {
+		grpcReq, ok := req.(*Request)
+		if !ok {
+			return "", false
+		}
+
+		digest := sha256.Sum256(grpcReq.Payload())
+		return cacheKey(hex.EncodeToString(digest[:]), grpcReq, metadataKeys), true
+	}
+}
+
+// cacheKey joins the hash of the message of the request with the values of its metadata keys
+func cacheKey(hash string, req *Request, metadataKeys []string) string {
+	var key strings.Builder
+	key.WriteString(hash)
+	for _, metadataKey := range metadataKeys {
+		metadataKey = strings.ToLower(metadataKey)
+		key.WriteString("\n")
+		key.WriteString(metadataKey)
+		key.WriteString(": ")
+		key.WriteString(strings.Join(req.Metadata.Get(metadataKey), ","))
+	}
+	return key.String()
+}
@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestCacheKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []string
+		reqA     *Request
+		reqB     *Request
+		sameKeys bool
+	}{
+		{
+			name:     "same messages",
+			reqA:     &Request{Message: []byte("message")},
+			reqB:     &Request{Message: []byte("message")},
+			sameKeys: true,
+		},
+		{
+			name: "different messages",
+			reqA: &Request{Message: []byte("message-a")},
+			reqB: &Request{Message: []byte("message-b")},
+		},
+		{
+			name: "different selected metadata",
+			keys: []string{"Customer-ID"},
+			reqA: &Request{Message: []byte("message"), Metadata: metadata.Pairs("customer-id", "1")},
+			reqB: &Request{Message: []byte("message"), Metadata: metadata.Pairs("customer-id", "2")},
+		},
+		{
+			name:     "different other metadata",
+			keys:     []string{"customer-id"},
+			reqA:     &Request{Message: []byte("message"), Metadata: metadata.Pairs("request-id", "1")},
+			reqB:     &Request{Message: []byte("message"), Metadata: metadata.Pairs("request-id", "2")},
+			sameKeys: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := CacheKey(tt.keys...)
+			keyA, okA := key(tt.reqA)
+			keyB, okB := key(tt.reqB)
+			assert.True(t, okA)
+			assert.True(t, okB)
+			assert.Equal(t, tt.sameKeys, keyA == keyB)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"io"
+	"sync"
 
 	"google.golang.org/grpc/encoding"
 )
@@ -13,6 +14,8 @@ const codecName = "fiber"
 // when unnecessary, base on the inputs
 type FiberCodec struct {
 	defaultCodec encoding.Codec
+	// once guards the initialization of the default codec, since the codec is used concurrently
+	once sync.Once
 }
 
 // Marshal will attempt to pass the request directly if it is a byte slice,
@@ -41,8 +44,10 @@ func (*FiberCodec) Name() string {
 }
 
 func (fc *FiberCodec) getDefaultCodec() encoding.Codec {
-	if fc.defaultCodec == nil {
-		fc.defaultCodec = encoding.GetCodec("proto")
-	}
+	fc.once.Do(func() {
+		if fc.defaultCodec == nil {
+			fc.defaultCodec = encoding.GetCodec("proto")
+		}
+	})
 	return fc.defaultCodec
 }
@@ -0,0 +1,165 @@
+package grpc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultIdleGracePeriod is the default time the calls of the evicted connection are given to complete,
+// before it's closed (see DispatcherConfig.IdleTimeout)
+const DefaultIdleGracePeriod = 10 * time.Second
+
+// connection is the grpc connection of the Dispatcher to its backend. If it has the idle timeout, the connection,
+// that hasn't been used for longer than that, is evicted by the background reaper, and the next call dials
+// the new one
+type connection struct {
+	dial        func() (*grpc.ClientConn, error)
+	idleTimeout time.Duration
+	gracePeriod time.Duration
+	// now returns the current time, so the idle connections can be evicted by the fake clock in the tests
+	now func() time.Time
+
+	lock sync.Mutex
+	// current is nil, while the connection is evicted
+	current  *trackedConn
+	lastUsed time.Time
+	closed   bool
+	// stop is closed, when the connection is closed, so the reaper and the evicted connections don't wait anymore
+	stop chan struct{}
+	// evicted is the number of the evicted connections
+	evicted int64
+}
+
+// trackedConn is the grpc connection with the number of its calls in flight
+type trackedConn struct {
+	*grpc.ClientConn
+	// calls and drained are guarded by the lock of the connection
+	calls int
+	// drained, if set, is closed, once the evicted connection has no calls in flight
+	drained chan struct{}
+}
+
+// newConnection dials the connection, and starts the reaper of the connection, if it has the idle timeout
+func newConnection(dial func() (*grpc.ClientConn, error), idleTimeout, gracePeriod time.Duration) (*connection, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultIdleGracePeriod
+	}
+	c := &connection{
+		dial:        dial,
+		idleTimeout: idleTimeout,
+		gracePeriod: gracePeriod,
+		now:         time.Now,
+		current:     &trackedConn{ClientConn: conn},
+		stop:        make(chan struct{}),
+	}
+	c.lastUsed = c.now()
+	if idleTimeout > 0 {
+		go c.reap(idleTimeout / 2)
+	}
+	return c, nil
+}
+
+// acquire returns the connection for the call, and dials it, if it has been evicted. The call has to release it,
+// once it's complete. The closed connection is returned as it is, so the call fails as cancelled
+func (c *connection) acquire() (*trackedConn, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.current == nil {
+		if c.closed {
+			return nil, status.Error(codes.Canceled, "grpc: the client connection is closing")
+		}
+		conn, err := c.dial()
+		if err != nil {
+			return nil, err
+		}
+		c.current = &trackedConn{ClientConn: conn}
+	}
+	c.current.calls++
+	c.lastUsed = c.now()
+	return c.current, nil
+}
+
+// release marks the call of the connection, acquired by acquire, as complete
+func (c *connection) release(conn *trackedConn) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	conn.calls--
+	if conn.calls == 0 && conn.drained != nil {
+		close(conn.drained)
+	}
+}
+
+// reap evicts the idle connection every interval, until the connection is closed
+func (c *connection) reap(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.evictIdle()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// evictIdle evicts the connection, if it hasn't been used for longer than the idle timeout. The evicted connection
+// with the calls in flight (e.g. the long-lived streams) is closed, once they are complete, or after the grace period
+func (c *connection) evictIdle() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed || c.current == nil || c.now().Sub(c.lastUsed) < c.idleTimeout {
+		return
+	}
+	evicted := c.current
+	c.current = nil
+	atomic.AddInt64(&c.evicted, 1)
+	if evicted.calls == 0 {
+		_ = evicted.Close()
+		return
+	}
+
+	evicted.drained = make(chan struct{})
+	go func() {
+		timer := time.NewTimer(c.gracePeriod)
+		defer timer.Stop()
+		select {
+		case <-evicted.drained:
+		case <-timer.C:
+		case <-c.stop:
+		}
+		_ = evicted.Close()
+	}()
+}
+
+// evictedCount returns the number of the evicted connections
+func (c *connection) evictedCount() int64 {
+	return atomic.LoadInt64(&c.evicted)
+}
+
+// close closes the connection and stops the reaper. The calls in flight are cancelled, and the evicted connections,
+// that wait for their calls to complete, are closed too
+func (c *connection) close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.stop)
+	}
+	if c.current == nil {
+		return nil
+	}
+	return c.current.Close()
+}
@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// DescriptorSet holds the descriptors of the protobuf files of the grpc services, so their messages can be
+// used as the dynamic messages (see dynamicpb), without the generated code of the messages linked into the binary
+type DescriptorSet struct {
+	files *protoregistry.Files
+}
+
+// NewDescriptorSet creates the DescriptorSet from the FileDescriptorSet, that has to include the imports
+// of its files, e.g. the one produced by `protoc --include_imports --descriptor_set_out`
+func NewDescriptorSet(set *descriptorpb.FileDescriptorSet) (*DescriptorSet, error) {
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("grpc descriptors: %v", err)
+	}
+	return &DescriptorSet{files: files}, nil
+}
+
+// RegisteredDescriptorSet returns the DescriptorSet of the protobuf files, that are registered by the generated code
+// linked into the binary (see protoregistry.GlobalFiles)
+func RegisteredDescriptorSet() *DescriptorSet {
+	return &DescriptorSet{files: protoregistry.GlobalFiles}
+}
+
+// LoadDescriptorSet reads the binary FileDescriptorSet from the file and creates the DescriptorSet
+func LoadDescriptorSet(path string) (*DescriptorSet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("grpc descriptors: %v", err)
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err = proto.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("grpc descriptors: invalid descriptor set [%s]: %v", path, err)
+	}
+	return NewDescriptorSet(set)
+}
+
+// FindMessageType returns the dynamic type of the message by its full name, e.g. `testproto.PredictValuesRequest`
+func (s *DescriptorSet) FindMessageType(name string) (protoreflect.MessageType, error) {
+	desc, err := s.files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("grpc descriptors: message [%s]: %v", name, err)
+	}
+	message, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("grpc descriptors: [%s] is not a message", name)
+	}
+	return dynamicpb.NewMessageType(message), nil
+}
+
+// FindMethod returns the descriptor of the service method in the format of the DispatcherConfig,
+// i.e. `{grpc_service_name}/{method_name}`
+func (s *DescriptorSet) FindMethod(serviceMethod string) (protoreflect.MethodDescriptor, error) {
+	idx := strings.LastIndex(serviceMethod, "/")
+	if idx < 0 {
+		return nil, fmt.Errorf("grpc descriptors: malformed service method [%s]", serviceMethod)
+	}
+	serviceName, methodName := strings.TrimPrefix(serviceMethod[:idx], "/"), serviceMethod[idx+1:]
+
+	desc, err := s.files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("grpc descriptors: service [%s]: %v", serviceName, err)
+	}
+	service, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("grpc descriptors: [%s] is not a service", serviceName)
+	}
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf("grpc descriptors: service [%s] has no method [%s]", serviceName, methodName)
+	}
+	return method, nil
+}
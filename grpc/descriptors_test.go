@@ -0,0 +1,166 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/gojek/fiber"
+	fiberError "github.com/gojek/fiber/errors"
+	testutils "github.com/gojek/fiber/internal/testutils/grpc"
+	httpTestUtils "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// descriptorSetPath is the descriptor set of the test service, so the tests don't use its generated code
+const descriptorSetPath = "../internal/testdata/proto/upi.protoset"
+
+func TestDescriptorSet_FindMethod(t *testing.T) {
+	descriptors, err := LoadDescriptorSet(descriptorSetPath)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		serviceMethod string
+		expected      string
+	}{
+		"method": {
+			serviceMethod: "testproto.UniversalPredictionService/PredictValues",
+		},
+		"method with leading slash": {
+			serviceMethod: "/testproto.UniversalPredictionService/PredictValues",
+		},
+		"malformed": {
+			serviceMethod: "PredictValues",
+			expected:      "grpc descriptors: malformed service method [PredictValues]",
+		},
+		"unknown method": {
+			serviceMethod: "testproto.UniversalPredictionService/Unknown",
+			expected:      "grpc descriptors: service [testproto.UniversalPredictionService] has no method [Unknown]",
+		},
+		"not a service": {
+			serviceMethod: "testproto.PredictValuesRequest/PredictValues",
+			expected:      "grpc descriptors: [testproto.PredictValuesRequest] is not a service",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			method, err := descriptors.FindMethod(tt.serviceMethod)
+			if tt.expected != "" {
+				assert.EqualError(t, err, tt.expected)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "testproto.PredictValuesRequest", string(method.Input().FullName()))
+			assert.Equal(t, "testproto.PredictValuesResponse", string(method.Output().FullName()))
+		})
+	}
+
+	_, err = LoadDescriptorSet("upi.protoset")
+	assert.Error(t, err)
+}
+
+func TestDescriptorSet_Transcoding(t *testing.T) {
+	descriptors, err := LoadDescriptorSet(descriptorSetPath)
+	require.NoError(t, err)
+
+	socket := filepath.Join(t.TempDir(), "upi.sock")
+	testutils.RunTestUPIServer(testutils.GrpcTestServer{Socket: socket})
+	grpcDispatcher, err := NewDispatcher(DispatcherConfig{
+		Endpoint:      "unix://" + socket,
+		ServiceMethod: "testproto.UniversalPredictionService/PredictValues",
+	})
+	require.NoError(t, err)
+	defer grpcDispatcher.Close(context.Background())
+
+	// the messages of the method are the dynamic ones, found in the descriptor set
+	dispatcher, err := NewTranscodingDispatcherForMethod(
+		grpcDispatcher, descriptors, "testproto.UniversalPredictionService/PredictValues")
+	require.NoError(t, err)
+	_, dynamic := dispatcher.requestType.New().Interface().(*dynamicpb.Message)
+	assert.True(t, dynamic)
+
+	resp := dispatcher.Do(context.Background(),
+		httpTestUtils.MockReq(http.MethodPost, "http://localhost:8080/predict", `{"predictionRows": [{"rowId": "1"}]}`))
+	require.Equal(t, http.StatusOK, resp.StatusCode(), string(resp.Payload()))
+	assert.JSONEq(t, `{"metadata": {"predictionId": "123", "experimentId": "0"}}`, string(resp.Payload()))
+
+	_, err = NewTranscodingDispatcherForMethod(
+		grpcDispatcher, descriptors, "testproto.UniversalPredictionService/Unknown")
+	assert.EqualError(t, err, "grpc transcoding: grpc descriptors: "+
+		"service [testproto.UniversalPredictionService] has no method [Unknown]")
+}
+
+func TestMergeMessages(t *testing.T) {
+	descriptors, err := LoadDescriptorSet(descriptorSetPath)
+	require.NoError(t, err)
+	responseType, err := descriptors.FindMessageType("testproto.PredictValuesResponse")
+	require.NoError(t, err)
+
+	shardResponse := func(json string) fiber.Response {
+		message := responseType.New().Interface()
+		require.NoError(t, protojson.Unmarshal([]byte(json), message))
+		payload, err := proto.Marshal(message)
+		require.NoError(t, err)
+		return &Response{Message: payload}
+	}
+
+	merge := MergeMessages(responseType, fiber.DecodeErrorFail)
+	resp := merge(context.Background(), &Request{}, []fiber.Response{
+		shardResponse(`{"predictions": [{"rowId": "1"}], "metadata": {"predictionId": "a"}}`),
+		shardResponse(`{"predictions": [{"rowId": "2"}], "metadata": {"predictionId": "b"}}`),
+	})
+	require.True(t, resp.IsSuccess())
+
+	merged := responseType.New().Interface()
+	require.NoError(t, proto.Unmarshal(resp.Payload(), merged))
+	json, err := protojson.Marshal(merged)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"predictions": [{"rowId": "1"}, {"rowId": "2"}], "metadata": {"predictionId": "b"}}`, string(json))
+
+	invalid := &Response{Message: []byte("invalid")}
+	valid := shardResponse(`{"predictions": [{"rowId": "1"}]}`)
+	suite := map[string]struct {
+		policy    fiber.DecodeErrorPolicy
+		responses []fiber.Response
+		expected  fiber.Response
+	}{
+		"fail: invalid message is skipped": {
+			policy:    fiber.DecodeErrorFail,
+			responses: []fiber.Response{invalid, valid},
+			expected:  valid,
+		},
+		"fail: no valid messages": {
+			policy:    fiber.DecodeErrorFail,
+			responses: []fiber.Response{invalid},
+		},
+		"pass through": {
+			policy:    fiber.DecodeErrorPassThrough,
+			responses: []fiber.Response{valid, invalid},
+			expected:  invalid,
+		},
+		"error": {
+			policy:    fiber.DecodeErrorRespond,
+			responses: []fiber.Response{invalid, valid},
+		},
+	}
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			resp := MergeMessages(responseType, tt.policy)(context.Background(), &Request{}, tt.responses)
+			if tt.expected == nil {
+				require.False(t, resp.IsSuccess())
+				assert.True(t, errors.Is(resp.(*fiber.ErrorResponse).Err(), fiberError.ErrInvalidResponse))
+				assert.Equal(t, int(codes.Internal), resp.StatusCode())
+				return
+			}
+			require.True(t, resp.IsSuccess())
+			assert.Equal(t, tt.expected.Payload(), resp.Payload())
+		})
+	}
+}
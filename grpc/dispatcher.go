@@ -3,7 +3,11 @@ package grpc
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"strings"
 	"time"
 
@@ -12,8 +16,14 @@ import (
 	"github.com/gojek/fiber/protocol"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/encoding"
+	// the gzip compressor is registered, so it can be used by the dispatchers (see DispatcherConfig.Compressor)
+	_ "google.golang.org/grpc/encoding/gzip"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
@@ -26,23 +36,98 @@ const (
 	TimeoutDefault = time.Second
 )
 
+// Dispatcher is the fiber.Dispatcher, that invokes the service method of the backend over the grpc connection
 type Dispatcher struct {
 	timeout time.Duration
+	// maxTimeout is the ceiling of the timeout of the request, set with fiber.WithRequestTimeout
+	maxTimeout time.Duration
 	// serviceMethod is the service and method of server point in the format "{grpc_service_name}/{method_name}"
 	serviceMethod string
 	// endpoint is the host+port of the grpc server, eg "127.0.0.1:50050"
 	endpoint string
-	// conn is the grpc connection dialed upon creation of dispatcher
-	conn *grpc.ClientConn
+	// conn is the grpc connection dialed upon creation of dispatcher, and re-dialed by the first call
+	// after it has been evicted (see DispatcherConfig.IdleTimeout)
+	conn *connection
+	// timeoutStatus, if set, is returned instead of the original status when the call times out
+	timeoutStatus *status.Status
+	// deadlineBuffer is subtracted from the deadline of the incoming request, when it's propagated
+	deadlineBuffer time.Duration
+	// streaming is true, if the service method is a server-streaming one
+	streaming bool
+	// propagatedMetadata, if set, are the keys of the metadata of the request, that are sent to the backend
+	propagatedMetadata []string
+	// maxRequestSize, if set, is the limit of the request message in bytes
+	maxRequestSize int
+	// keepalive, if set, are the parameters of the keepalive pings of the connection
+	keepalive *keepalive.ClientParameters
+	// connectParams, if set, are the parameters of the reconnects of the connection
+	connectParams *grpc.ConnectParams
 }
 
+var _ fiber.Dispatcher = (*Dispatcher)(nil)
+
 type DispatcherConfig struct {
 	ServiceMethod string
-	Endpoint      string
-	Timeout       time.Duration
+	// Endpoint is the target of the backend, e.g. localhost:50050, or unix:///var/run/backend.sock,
+	// if the backend listens on the unix domain socket
+	Endpoint string
+	Timeout  time.Duration
+	// MaxTimeout is the ceiling of the timeout of the request, set with fiber.WithRequestTimeout.
+	// By default, the request timeout can't exceed Timeout
+	MaxTimeout time.Duration
+	// TimeoutStatus is the status to be returned to the client when the call to the backend
+	// exceeds the configured timeout. By default, the status returned by grpc is used
+	TimeoutStatus *status.Status
+	// IdleTimeout, if set, is the time after the last call, when the connection to the backend is evicted
+	// to free its resources, once the traffic drops. The next call dials the new connection. The connection
+	// with the calls in flight is closed, once they are complete, or after IdleGracePeriod
+	IdleTimeout time.Duration
+	// IdleGracePeriod is the time the calls of the evicted connection are given to complete,
+	// defaults to DefaultIdleGracePeriod
+	IdleGracePeriod time.Duration
+	// TLSConfig, if set, is used to establish a secure connection to the backend.
+	// Otherwise, the connection is insecure
+	TLSConfig *tls.Config
+	// DeadlineBuffer is subtracted from the deadline of the incoming request, when it's propagated
+	// to the backend, to leave time for sending the response back to the client
+	DeadlineBuffer time.Duration
+	// Streaming should be set, if the service method is a server-streaming one. The dispatcher
+	// then returns a StreamingResponse, which frames are the messages of the stream
+	Streaming bool
+	// PropagatedMetadata, if set, are the keys of the metadata of the incoming request, that are sent
+	// to the backend. The other keys are dropped, so the internal metadata doesn't leak to the backend.
+	// By default, all the metadata of the request is sent
+	PropagatedMetadata []string
+	// MaxRequestSize and MaxResponseSize, if set, are the limits of the request and response messages in bytes
+	// (see grpc.MaxCallSendMsgSize and grpc.MaxCallRecvMsgSize). The larger requests are not sent, and the larger
+	// responses are not received, so RESOURCE_EXHAUSTED is returned instead. By default, the limits of grpc apply
+	MaxRequestSize  int
+	MaxResponseSize int
+	// Keepalive, if set, configures the keepalive pings of the connection to the backend, so the idle connection
+	// is not dropped by the backend or the network in between (see keepalive.ClientParameters)
+	Keepalive *keepalive.ClientParameters
+	// ConnectParams, if set, configures the backoff of the reconnects to the backend, and the timeout
+	// of each connection attempt (see grpc.ConnectParams)
+	ConnectParams *grpc.ConnectParams
+	// ConnectTimeout, if set, is the timeout of establishing the tcp connection to the backend. It's separate
+	// from Timeout of the calls, so the calls to the backend, that is unreachable, fail with UNAVAILABLE, once
+	// the connection attempt times out, instead of waiting for the connection until their deadline
+	ConnectTimeout time.Duration
+	// Authority, if set, is sent as the :authority header of the calls instead of the Endpoint, e.g. to address
+	// the tenant of the multi-tenant backend behind the shared proxy. The certificate of the backend is still
+	// verified against the host of the Endpoint, unless TLSConfig sets its own ServerName
+	Authority string
+	// Compressor, if set, is the name of the compressor of the request messages, e.g. gzip (see grpc.UseCompressor).
+	// The gzip compressor is registered by this package, the other ones have to be registered with
+	// encoding.RegisterCompressor, before the dispatcher is created. By default, the messages are not compressed
+	Compressor string
 }
 
-func (d *Dispatcher) Do(request fiber.Request) fiber.Response {
+// Do invokes the service method of the backend. The timeout of the request, set with fiber.WithRequestTimeout,
+// overrides the timeout of the dispatcher. The deadline of the context, if it's shorter than the timeout,
+// is propagated to the backend, reduced by the deadline buffer.
+// If the dispatcher is streaming, the StreamingResponse is returned
+func (d *Dispatcher) Do(ctx context.Context, request fiber.Request) fiber.Response {
 	grpcRequest, ok := request.(*Request)
 	if !ok {
 		return fiber.NewErrorResponse(
@@ -52,9 +137,30 @@ func (d *Dispatcher) Do(request fiber.Request) fiber.Response {
 			})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	if d.maxRequestSize > 0 && len(grpcRequest.Payload()) > d.maxRequestSize {
+		return fiber.NewErrorResponse(fiberError.ErrRequestTooLarge(protocol.GRPC, int64(d.maxRequestSize)))
+	}
+
+	ctx, cancel := d.callContext(ctx)
+	// the metadata, added to the outgoing context by the interceptors (i.e. the trace context), is sent too
+	requestID := grpcRequest.RequestID()
+	md := d.requestMetadata(grpcRequest)
+	// the id of the request is sent, even if its metadata isn't propagated
+	md.Set(fiber.RequestIDHeader, requestID)
+	if outgoing, ok := metadata.FromOutgoingContext(ctx); ok {
+		md = metadata.Join(outgoing, md)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	conn, err := d.conn.acquire()
+	if err != nil {
+		defer cancel()
+		return d.errorResponse(ctx, err)
+	}
+	if d.streaming {
+		return d.doStream(ctx, cancel, conn, grpcRequest)
+	}
 	defer cancel()
-	ctx = metadata.NewOutgoingContext(ctx, grpcRequest.Metadata)
+	defer d.conn.release(conn)
 
 	response := new(bytes.Buffer)
 	var responseHeader metadata.MD
@@ -62,7 +168,7 @@ func (d *Dispatcher) Do(request fiber.Request) fiber.Response {
 	// Dispatcher will send both request and payload as bytes, with the use of codec
 	// to prevent marshaling. The codec content type will be sent with request and
 	// the server will attempt to unmarshal with the codec.
-	err := d.conn.Invoke(
+	err = conn.Invoke(
 		ctx,
 		d.serviceMethod,
 		grpcRequest.Payload(),
@@ -71,13 +177,7 @@ func (d *Dispatcher) Do(request fiber.Request) fiber.Response {
 		grpc.CallContentSubtype(codecName),
 	)
 	if err != nil {
-		// if ok is false, unknown codes.Unknown and Status msg is returned in Status
-		responseStatus, _ := status.FromError(err)
-		return fiber.NewErrorResponse(
-			fiberError.FiberError{
-				Code:    int(responseStatus.Code()),
-				Message: responseStatus.String(),
-			})
+		return d.errorResponse(ctx, err)
 	}
 
 	return &Response{
@@ -87,6 +187,245 @@ func (d *Dispatcher) Do(request fiber.Request) fiber.Response {
 	}
 }
 
+// doStream opens the stream to the server-streaming method of the backend, and returns the
+// StreamingResponse, which frames are received in background. The context is canceled,
+// and the connection is released, when the stream is finished
+func (d *Dispatcher) doStream(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	conn *trackedConn,
+	request *Request,
+) fiber.Response {
+	stream, err := conn.NewStream(
+		ctx,
+		&grpc.StreamDesc{ServerStreams: true},
+		d.serviceMethod,
+		grpc.CallContentSubtype(codecName),
+	)
+	if err == nil {
+		if err = stream.SendMsg(request.Payload()); err == nil {
+			err = stream.CloseSend()
+		}
+	}
+	var header metadata.MD
+	if err == nil {
+		header, err = stream.Header()
+	}
+	if err != nil {
+		defer cancel()
+		defer d.conn.release(conn)
+		return d.errorResponse(ctx, err)
+	}
+
+	frames := make(chan fiber.Response)
+	go func() {
+		defer cancel()
+		defer d.conn.release(conn)
+		defer close(frames)
+		for {
+			message := new(bytes.Buffer)
+			if err := stream.RecvMsg(message); err != nil {
+				if err != io.EOF {
+					frames <- d.errorResponse(ctx, err)
+				}
+				return
+			}
+			frames <- &Response{
+				// each frame has its own copy of the metadata, so it can be updated independently
+				Metadata: header.Copy(),
+				Message:  message.Bytes(),
+				Status:   *status.New(codes.OK, "Success"),
+				frame:    true,
+			}
+		}
+	}()
+
+	return &StreamingResponse{
+		Metadata: header.Copy(),
+		frames:   frames,
+	}
+}
+
+// WarmUp connects the connection of the dispatcher, that is otherwise established on the first call,
+// and waits until it's ready, or the context is done (see fiber.Warmer)
+func (d *Dispatcher) WarmUp(ctx context.Context) error {
+	conn, err := d.conn.acquire()
+	if err != nil {
+		return fmt.Errorf("grpc dispatcher: unable to connect to [%s]: %v", d.endpoint, err)
+	}
+	defer d.conn.release(conn)
+
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("grpc dispatcher: unable to connect to [%s]: connection is %s", d.endpoint, state)
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("grpc dispatcher: unable to connect to [%s]: %v", d.endpoint, ctx.Err())
+		}
+	}
+}
+
+// HealthProbe returns the fiber.HealthProbe, that calls the grpc health-check RPC of the service over
+// the connection of the dispatcher. The empty service checks the overall health of the server.
+// The backend is healthy, if the service is SERVING. The probes are the calls of the connection too,
+// so it's not evicted, while the backend is health-checked more often than its IdleTimeout
+func (d *Dispatcher) HealthProbe(service string) fiber.HealthProbe {
+	return fiber.HealthProbeFunc(func(ctx context.Context) error {
+		conn, err := d.conn.acquire()
+		if err != nil {
+			return err
+		}
+		defer d.conn.release(conn)
+
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("health probe: service [%s] is %s", service, resp.GetStatus())
+		}
+		return nil
+	})
+}
+
+// requestMetadata returns the copy of the metadata of the request, that is sent to the backend. The grpc
+// requests are not copied for the routes (see Request.Clone), so the metadata of the request is shared by
+// the routes, that dispatch it concurrently, and by the caller, and it's not modified by the dispatcher
+func (d *Dispatcher) requestMetadata(request *Request) metadata.MD {
+	if d.propagatedMetadata == nil {
+		return request.Metadata.Copy()
+	}
+	md := metadata.MD{}
+	for _, key := range d.propagatedMetadata {
+		if values := request.Metadata.Get(key); len(values) > 0 {
+			md.Set(key, values...)
+		}
+	}
+	return md
+}
+
+// callContext derives the context of the call to the backend. The deadline of the context, if it's
+// shorter than the timeout of the call (see fiber.ResolveTimeout), is propagated to the backend,
+// reduced by the deadline buffer
+func (d *Dispatcher) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	cancelDeadline := func() {}
+	if deadline, ok := ctx.Deadline(); ok && d.deadlineBuffer > 0 {
+		ctx, cancelDeadline = context.WithDeadline(ctx, deadline.Add(-d.deadlineBuffer))
+	}
+	ctx, cancel := context.WithTimeout(ctx, fiber.ResolveTimeout(ctx, d.timeout, d.maxTimeout))
+	return ctx, func() {
+		cancel()
+		cancelDeadline()
+	}
+}
+
+// errorResponse converts the error of the call to the backend into the error response
+func (d *Dispatcher) errorResponse(ctx context.Context, err error) fiber.Response {
+	if ctx.Err() == context.DeadlineExceeded && d.timeoutStatus != nil {
+		return fiber.NewErrorResponse(
+			fiberError.FiberError{
+				Code:    int(d.timeoutStatus.Code()),
+				Message: d.timeoutStatus.Message(),
+			})
+	}
+	// if ok is false, unknown codes.Unknown and Status msg is returned in Status
+	responseStatus, _ := status.FromError(err)
+	resp := fiber.NewErrorResponse(
+		fiberError.FiberError{
+			Code:    int(responseStatus.Code()),
+			Message: responseStatus.String(),
+		}).(*fiber.ErrorResponse)
+	if len(responseStatus.Proto().GetDetails()) > 0 {
+		// the details of the status are kept, so they can be sent back to the client (see ErrorStatus)
+		resp.WithCause(responseStatus.Err())
+	}
+	return resp
+}
+
+// Describe adds the endpoint of the backend, the protocol and the timeout of the calls to the info
+func (d *Dispatcher) Describe(info *fiber.ComponentInfo) {
+	info.Endpoint = d.endpoint
+	info.Protocol = protocol.GRPC
+	info.Timeout = d.timeout.String()
+}
+
+// Close closes the connection to the backend and stops the eviction of the idle connections.
+// The in-flight calls are cancelled
+func (d *Dispatcher) Close(context.Context) error {
+	return d.conn.close()
+}
+
+// EvictedConnections returns the number of the connections to the backend, that have been evicted, since they
+// have been idle (see DispatcherConfig.IdleTimeout), e.g. to be exported as the metrics of the backend
+func (d *Dispatcher) EvictedConnections() int64 {
+	return d.conn.evictedCount()
+}
+
+// callOptions translates the config into the default options of the calls to the backend
+func callOptions(config DispatcherConfig) []grpc.CallOption {
+	var options []grpc.CallOption
+	if config.MaxRequestSize > 0 {
+		options = append(options, grpc.MaxCallSendMsgSize(config.MaxRequestSize))
+	}
+	if config.MaxResponseSize > 0 {
+		options = append(options, grpc.MaxCallRecvMsgSize(config.MaxResponseSize))
+	}
+	if config.Compressor != "" {
+		options = append(options, grpc.UseCompressor(config.Compressor))
+	}
+	return options
+}
+
+// dialOptions translates the config into the options of the connection to the backend
+func dialOptions(config DispatcherConfig, transportCredentials credentials.TransportCredentials) []grpc.DialOption {
+	options := []grpc.DialOption{grpc.WithTransportCredentials(transportCredentials)}
+	if callOptions := callOptions(config); len(callOptions) > 0 {
+		options = append(options, grpc.WithDefaultCallOptions(callOptions...))
+	}
+	if config.Keepalive != nil {
+		options = append(options, grpc.WithKeepaliveParams(*config.Keepalive))
+	}
+	if config.ConnectParams != nil {
+		options = append(options, grpc.WithConnectParams(*config.ConnectParams))
+	}
+	if config.Authority != "" {
+		options = append(options, grpc.WithAuthority(config.Authority))
+	}
+	if config.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: config.ConnectTimeout}
+		options = append(options, grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			// the address of the unix socket target keeps its scheme, e.g. unix:///var/run/backend.sock
+			if socket, ok, err := fiber.UnixSocketPath(address); ok && err == nil {
+				return dialer.DialContext(ctx, "unix", socket)
+			}
+			return dialer.DialContext(ctx, "tcp", address)
+		}))
+	}
+	return options
+}
+
+// tlsConfig returns the TLS config of the connection to the backend. The grpc verifies the certificate of the
+// backend against the authority of the connection, so, if it's overridden, the host of the endpoint is set
+// as the server name, unless the config sets its own one
+func tlsConfig(config DispatcherConfig) *tls.Config {
+	if config.Authority == "" || config.TLSConfig.ServerName != "" {
+		return config.TLSConfig
+	}
+	tlsConfig := config.TLSConfig.Clone()
+	// the endpoint can be the target with the name of the resolver, e.g. dns:///localhost:50050
+	host := config.Endpoint[strings.LastIndex(config.Endpoint, "/")+1:]
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	tlsConfig.ServerName = host
+	return tlsConfig
+}
+
 // NewDispatcher is the constructor to create a dispatcher. It will create the clientconn and set defaults.
 // Endpoint, serviceMethod and response proto are required minimally to work.
 func NewDispatcher(config DispatcherConfig) (*Dispatcher, error) {
@@ -105,21 +444,58 @@ func NewDispatcher(config DispatcherConfig) (*Dispatcher, error) {
 		serviceMethodStringBuilder.WriteString("/")
 	}
 	serviceMethodStringBuilder.WriteString(config.ServiceMethod)
+	if _, _, err := fiber.UnixSocketPath(config.Endpoint); err != nil {
+		return nil, fiberError.ErrInvalidInput(protocol.GRPC, fmt.Errorf("grpc dispatcher: %v", err))
+	}
+	if config.Compressor != "" && encoding.GetCompressor(config.Compressor) == nil {
+		return nil, fiberError.ErrInvalidInput(
+			protocol.GRPC,
+			fmt.Errorf("grpc dispatcher: unknown compressor [%s]", config.Compressor))
+	}
 
-	conn, err := grpc.DialContext(context.Background(), config.Endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		// if ok is false, unknown codes.Unknown and Status msg is returned in Status
-		responseStatus, _ := status.FromError(err)
-		return nil, fiberError.ErrRequestFailed(
+	transportCredentials := insecure.NewCredentials()
+	if config.TLSConfig != nil {
+		transportCredentials = credentials.NewTLS(tlsConfig(config))
+	}
+
+	if config.IdleTimeout < 0 || config.IdleGracePeriod < 0 {
+		return nil, fiberError.ErrInvalidInput(
 			protocol.GRPC,
-			errors.New("grpc dispatcher: "+responseStatus.String()))
+			errors.New("grpc dispatcher: idle timeout and grace period can not be negative"))
+	}
+
+	options := dialOptions(config, transportCredentials)
+	conn, err := newConnection(func() (*grpc.ClientConn, error) {
+		conn, err := grpc.DialContext(context.Background(), config.Endpoint, options...)
+		if err != nil {
+			// if ok is false, unknown codes.Unknown and Status msg is returned in Status
+			responseStatus, _ := status.FromError(err)
+			return nil, fiberError.ErrRequestFailed(
+				protocol.GRPC,
+				errors.New("grpc dispatcher: "+responseStatus.String()))
+		}
+		return conn, nil
+	}, config.IdleTimeout, config.IdleGracePeriod)
+	if err != nil {
+		return nil, err
 	}
 
 	dispatcher := &Dispatcher{
-		timeout:       configuredTimeout,
-		serviceMethod: serviceMethodStringBuilder.String(),
-		endpoint:      config.Endpoint,
-		conn:          conn,
+		timeout:        configuredTimeout,
+		maxTimeout:     config.MaxTimeout,
+		serviceMethod:  serviceMethodStringBuilder.String(),
+		endpoint:       config.Endpoint,
+		conn:           conn,
+		timeoutStatus:  config.TimeoutStatus,
+		deadlineBuffer: config.DeadlineBuffer,
+		streaming:      config.Streaming,
+		maxRequestSize: config.MaxRequestSize,
+		keepalive:      config.Keepalive,
+		connectParams:  config.ConnectParams,
+	}
+	for _, key := range config.PropagatedMetadata {
+		// the keys of the metadata are case-insensitive
+		dispatcher.propagatedMetadata = append(dispatcher.propagatedMetadata, strings.ToLower(key))
 	}
 	return dispatcher, nil
 }
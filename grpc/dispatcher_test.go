@@ -1,23 +1,35 @@
 package grpc
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/gojek/fiber"
 	fiberError "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/extras"
 	"github.com/gojek/fiber/http"
 	testproto "github.com/gojek/fiber/internal/testdata/gen/testdata/proto"
+	fiberTestUtils "github.com/gojek/fiber/internal/testutils"
 	testutils "github.com/gojek/fiber/internal/testutils/grpc"
+	httpTestUtils "github.com/gojek/fiber/internal/testutils/http"
 	"github.com/gojek/fiber/protocol"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -118,6 +130,36 @@ func TestNewDispatcher(t *testing.T) {
 				endpoint:      fmt.Sprintf(":%d", port),
 			},
 		},
+		{
+			name: "ok response with connection options",
+			dispatcherConfig: DispatcherConfig{
+				ServiceMethod: serviceMethod,
+				Endpoint:      fmt.Sprintf(":%d", port),
+				Keepalive: &keepalive.ClientParameters{
+					Time:                time.Minute,
+					Timeout:             10 * time.Second,
+					PermitWithoutStream: true,
+				},
+				ConnectParams: &grpc.ConnectParams{
+					Backoff:           backoff.Config{BaseDelay: 100 * time.Millisecond, Multiplier: 2, MaxDelay: 5 * time.Second},
+					MinConnectTimeout: 2 * time.Second,
+				},
+			},
+			expected: &Dispatcher{
+				timeout:       TimeoutDefault,
+				serviceMethod: fmt.Sprintf("/%s", serviceMethod),
+				endpoint:      fmt.Sprintf(":%d", port),
+				keepalive: &keepalive.ClientParameters{
+					Time:                time.Minute,
+					Timeout:             10 * time.Second,
+					PermitWithoutStream: true,
+				},
+				connectParams: &grpc.ConnectParams{
+					Backoff:           backoff.Config{BaseDelay: 100 * time.Millisecond, Multiplier: 2, MaxDelay: 5 * time.Second},
+					MinConnectTimeout: 2 * time.Second,
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -180,7 +222,7 @@ func TestDispatcher_Do(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			response := dispatcher.Do(tt.input)
+			response := dispatcher.Do(context.Background(), tt.input)
 			errResponse, ok := response.(*fiber.ErrorResponse)
 			if ok {
 				assert.EqualValues(t, tt.expected, errResponse)
@@ -200,3 +242,975 @@ func TestDispatcher_Do(t *testing.T) {
 		})
 	}
 }
+
+func TestDispatcher_Close(t *testing.T) {
+	dispatcher, err := NewDispatcher(DispatcherConfig{
+		ServiceMethod: serviceMethod,
+		Endpoint:      fmt.Sprintf(":%d", port),
+	})
+	require.NoError(t, err)
+	require.True(t, dispatcher.Do(context.Background(), &Request{Message: []byte{}}).IsSuccess())
+
+	require.NoError(t, dispatcher.Close(context.Background()))
+	response := dispatcher.Do(context.Background(), &Request{Message: []byte{}})
+	assert.False(t, response.IsSuccess())
+	assert.Equal(t, int(codes.Canceled), response.StatusCode())
+}
+
+func TestDispatcher_PropagatedMetadata(t *testing.T) {
+	metadataPort := 50061
+	received := make(chan metadata.MD, 1)
+	testutils.RunTestUPIServer(
+		testutils.GrpcTestServer{
+			Port:             metadataPort,
+			MockResponse:     mockResponse,
+			MetadataObserver: func(md metadata.MD) { received <- md },
+		},
+	)
+
+	tests := []struct {
+		name               string
+		propagatedMetadata []string
+		expected           map[string][]string
+		dropped            []string
+	}{
+		{
+			name: "all metadata is propagated by default",
+			expected: map[string][]string{
+				"authorization": {"Bearer token"},
+				"x-tenant":      {"tenant-a", "tenant-b"},
+				"x-internal":    {"secret"},
+			},
+		},
+		{
+			name:               "only whitelisted metadata is propagated",
+			propagatedMetadata: []string{"Authorization", "x-tenant", "x-missing"},
+			expected: map[string][]string{
+				"authorization": {"Bearer token"},
+				"x-tenant":      {"tenant-a", "tenant-b"},
+			},
+			dropped: []string{"x-internal", "x-missing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher, err := NewDispatcher(DispatcherConfig{
+				ServiceMethod:      serviceMethod,
+				Endpoint:           fmt.Sprintf(":%d", metadataPort),
+				PropagatedMetadata: tt.propagatedMetadata,
+			})
+			require.NoError(t, err)
+			defer dispatcher.Close(context.Background())
+
+			md := metadata.Pairs(
+				"authorization", "Bearer token",
+				"x-tenant", "tenant-a",
+				"x-tenant", "tenant-b",
+				"x-internal", "secret",
+			)
+			req := &Request{Metadata: md, Message: []byte{}}
+			requestID := req.RequestID()
+			sent := md.Copy()
+			require.True(t, dispatcher.Do(context.Background(), req).IsSuccess())
+			// the metadata of the request is shared by its routes, so it's not modified by the dispatcher
+			assert.Equal(t, sent, req.Metadata)
+
+			incoming := <-received
+			for key, values := range tt.expected {
+				assert.Equal(t, values, incoming.Get(key), key)
+			}
+			for _, key := range tt.dropped {
+				assert.Empty(t, incoming.Get(key), key)
+			}
+			// the id of the request is sent, even if it's not whitelisted
+			assert.Equal(t, []string{requestID}, incoming.Get(fiber.RequestIDHeader))
+		})
+	}
+}
+
+func TestDispatcher_Authority(t *testing.T) {
+	authorityPort := 50064
+	received := make(chan metadata.MD, 1)
+	testutils.RunTestUPIServer(
+		testutils.GrpcTestServer{
+			Port:             authorityPort,
+			MockResponse:     mockResponse,
+			MetadataObserver: func(md metadata.MD) { received <- md },
+		},
+	)
+
+	dispatcher, err := NewDispatcher(DispatcherConfig{
+		ServiceMethod: serviceMethod,
+		Endpoint:      fmt.Sprintf("localhost:%d", authorityPort),
+		Authority:     "tenant-a.example.com",
+	})
+	require.NoError(t, err)
+	defer dispatcher.Close(context.Background())
+
+	require.True(t, dispatcher.Do(context.Background(), &Request{Message: []byte{}}).IsSuccess())
+	assert.Equal(t, []string{"tenant-a.example.com"}, (<-received).Get(":authority"))
+}
+
+func TestDispatcher_AuthorityTLSConfig(t *testing.T) {
+	tests := map[string]struct {
+		config             DispatcherConfig
+		expectedServerName string
+	}{
+		"authority is not overridden": {
+			config: DispatcherConfig{Endpoint: "backend.internal:443", TLSConfig: &tls.Config{}},
+		},
+		"certificate is verified against the host of the endpoint": {
+			config: DispatcherConfig{
+				Endpoint:  "dns:///backend.internal:443",
+				Authority: "tenant-a.example.com",
+				TLSConfig: &tls.Config{},
+			},
+			expectedServerName: "backend.internal",
+		},
+		"server name of the TLS config takes precedence": {
+			config: DispatcherConfig{
+				Endpoint:  "backend.internal:443",
+				Authority: "tenant-a.example.com",
+				TLSConfig: &tls.Config{ServerName: "proxy.example.com"},
+			},
+			expectedServerName: "proxy.example.com",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			serverName := tt.config.TLSConfig.ServerName
+			assert.Equal(t, tt.expectedServerName, tlsConfig(tt.config).ServerName)
+			// the TLS config of the caller is not modified
+			assert.Equal(t, serverName, tt.config.TLSConfig.ServerName)
+		})
+	}
+}
+
+func TestDispatcher_ConnectionOptions(t *testing.T) {
+	tests := map[string]DispatcherConfig{
+		"keepalive": {
+			Keepalive: &keepalive.ClientParameters{Time: 10 * time.Second, Timeout: time.Second, PermitWithoutStream: true},
+		},
+		"backoff": {
+			ConnectParams: &grpc.ConnectParams{Backoff: backoff.DefaultConfig, MinConnectTimeout: time.Second},
+		},
+		"keepalive and backoff": {
+			Keepalive:     &keepalive.ClientParameters{Time: time.Minute},
+			ConnectParams: &grpc.ConnectParams{Backoff: backoff.Config{BaseDelay: time.Second, MaxDelay: time.Minute}},
+		},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			config.ServiceMethod = serviceMethod
+			config.Endpoint = fmt.Sprintf(":%d", port)
+			// the transport credentials are always set, and each of the options adds one more
+			expectedOptions := 1
+			if config.Keepalive != nil {
+				expectedOptions++
+			}
+			if config.ConnectParams != nil {
+				expectedOptions++
+			}
+			assert.Len(t, dialOptions(config, insecure.NewCredentials()), expectedOptions)
+
+			// the connection, dialed with the options, serves the requests
+			dispatcher, err := NewDispatcher(config)
+			require.NoError(t, err)
+			defer dispatcher.Close(context.Background())
+
+			response := dispatcher.Do(context.Background(), &Request{Message: []byte{}})
+			require.Equal(t, int(codes.OK), response.StatusCode())
+		})
+	}
+}
+
+func TestDispatcher_Compressor(t *testing.T) {
+	t.Run("gzip", func(t *testing.T) {
+		config := DispatcherConfig{
+			ServiceMethod: serviceMethod,
+			Endpoint:      fmt.Sprintf(":%d", port),
+			Compressor:    "gzip",
+		}
+		assert.Contains(t, callOptions(config), grpc.UseCompressor("gzip"))
+
+		// the backend decompresses the request, since the compressor is registered
+		dispatcher, err := NewDispatcher(config)
+		require.NoError(t, err)
+		defer dispatcher.Close(context.Background())
+
+		response := dispatcher.Do(context.Background(), &Request{Message: []byte{}})
+		require.Equal(t, int(codes.OK), response.StatusCode())
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		assert.Empty(t, callOptions(DispatcherConfig{ServiceMethod: serviceMethod, Endpoint: ":8080"}))
+	})
+
+	t.Run("unknown compressor", func(t *testing.T) {
+		_, err := NewDispatcher(DispatcherConfig{
+			ServiceMethod: serviceMethod,
+			Endpoint:      fmt.Sprintf(":%d", port),
+			Compressor:    "snappy",
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "grpc dispatcher: unknown compressor [snappy]")
+	})
+}
+
+func TestDispatcher_ConnectTimeout(t *testing.T) {
+	delayedPort := 50063
+	testutils.RunTestUPIServer(
+		testutils.GrpcTestServer{
+			Port:         delayedPort,
+			MockResponse: mockResponse,
+			DelayTimer:   500 * time.Millisecond,
+		},
+	)
+
+	tests := map[string]struct {
+		endpoint     string
+		expectedCode codes.Code
+		expectedMsg  string
+	}{
+		"backend is unreachable": {
+			endpoint:     fiberTestUtils.UnresponsiveAddress(t),
+			expectedCode: codes.Unavailable,
+			expectedMsg:  "i/o timeout",
+		},
+		"backend is slow to respond": {
+			endpoint:     fmt.Sprintf(":%d", delayedPort),
+			expectedCode: codes.DeadlineExceeded,
+			expectedMsg:  "context deadline exceeded",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := DispatcherConfig{
+				ServiceMethod:  serviceMethod,
+				Endpoint:       tt.endpoint,
+				Timeout:        200 * time.Millisecond,
+				ConnectTimeout: 20 * time.Millisecond,
+			}
+			// the transport credentials and the dialer of the connections
+			assert.Len(t, dialOptions(config, insecure.NewCredentials()), 2)
+
+			dispatcher, err := NewDispatcher(config)
+			require.NoError(t, err)
+			defer dispatcher.Close(context.Background())
+
+			response := dispatcher.Do(context.Background(), &Request{Message: []byte{}})
+			assert.Equal(t, int(tt.expectedCode), response.StatusCode())
+			assert.Contains(t, string(response.Payload()), tt.expectedMsg)
+		})
+	}
+}
+
+func TestDispatcher_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "backend.sock")
+	testutils.RunTestUPIServer(testutils.GrpcTestServer{Socket: socket, MockResponse: mockResponse})
+
+	for name, connectTimeout := range map[string]time.Duration{
+		"default dialer":  0,
+		"connect timeout": time.Second,
+	} {
+		t.Run(name, func(t *testing.T) {
+			dispatcher, err := NewDispatcher(DispatcherConfig{
+				ServiceMethod:  serviceMethod,
+				Endpoint:       "unix://" + socket,
+				Timeout:        time.Second,
+				ConnectTimeout: connectTimeout,
+			})
+			require.NoError(t, err)
+			defer dispatcher.Close(context.Background())
+
+			response := dispatcher.Do(context.Background(), &Request{Message: []byte{}})
+			require.Equal(t, int(codes.OK), response.StatusCode(), string(response.Payload()))
+			responseProto := &testproto.PredictValuesResponse{}
+			require.NoError(t, proto.Unmarshal(response.Payload(), responseProto))
+			assert.True(t, proto.Equal(mockResponse, responseProto))
+		})
+	}
+
+	_, err := NewDispatcher(DispatcherConfig{ServiceMethod: serviceMethod, Endpoint: "unix://backend.sock"})
+	assert.EqualError(t, err,
+		"fiber: grpc dispatcher: malformed unix socket endpoint [unix://backend.sock], expected unix:///path/to.sock")
+}
+
+func TestDispatcher_MaxMessageSizes(t *testing.T) {
+	responseSize := proto.Size(mockResponse)
+	tests := []struct {
+		name            string
+		maxRequestSize  int
+		maxResponseSize int
+		request         []byte
+		expectedCode    codes.Code
+		expectedMsg     string
+	}{
+		{
+			name:            "under the limits",
+			maxRequestSize:  10,
+			maxResponseSize: responseSize,
+			request:         []byte{},
+			expectedCode:    codes.OK,
+		},
+		{
+			name:            "response over the limit",
+			maxResponseSize: responseSize - 1,
+			request:         []byte{},
+			expectedCode:    codes.ResourceExhausted,
+			expectedMsg:     "received message larger than max",
+		},
+		{
+			name:           "request over the limit",
+			maxRequestSize: 10,
+			request:        make([]byte, 11),
+			expectedCode:   codes.ResourceExhausted,
+			expectedMsg:    "fiber: request body exceeds the limit of 10 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher, err := NewDispatcher(DispatcherConfig{
+				ServiceMethod:   serviceMethod,
+				Endpoint:        fmt.Sprintf(":%d", port),
+				MaxRequestSize:  tt.maxRequestSize,
+				MaxResponseSize: tt.maxResponseSize,
+			})
+			require.NoError(t, err)
+			defer dispatcher.Close(context.Background())
+
+			response := dispatcher.Do(context.Background(), &Request{Message: tt.request})
+			require.Equal(t, int(tt.expectedCode), response.StatusCode())
+			if tt.expectedMsg != "" {
+				assert.Contains(t, string(response.Payload()), tt.expectedMsg)
+				return
+			}
+			responseProto := &testproto.PredictValuesResponse{}
+			require.NoError(t, proto.Unmarshal(response.Payload(), responseProto))
+			assert.True(t, proto.Equal(mockResponse, responseProto))
+		})
+	}
+}
+
+func TestDispatcher_Describe(t *testing.T) {
+	dispatcher, err := NewDispatcher(DispatcherConfig{
+		ServiceMethod: serviceMethod,
+		Endpoint:      fmt.Sprintf(":%d", port),
+		Timeout:       2 * time.Second,
+	})
+	require.NoError(t, err)
+	defer dispatcher.Close(context.Background())
+
+	var info fiber.ComponentInfo
+	dispatcher.Describe(&info)
+	assert.Equal(t, fiber.ComponentInfo{
+		Endpoint: fmt.Sprintf(":%d", port),
+		Protocol: protocol.GRPC,
+		Timeout:  "2s",
+	}, info)
+}
+
+func TestDispatcher_HealthProbe(t *testing.T) {
+	dispatcher, err := NewDispatcher(DispatcherConfig{
+		ServiceMethod: serviceMethod,
+		Endpoint:      fmt.Sprintf(":%d", port),
+	})
+	require.NoError(t, err)
+	defer dispatcher.Close(context.Background())
+
+	tests := []struct {
+		name        string
+		service     string
+		expectedErr codes.Code
+	}{
+		{
+			name:        "server is serving",
+			expectedErr: codes.OK,
+		},
+		{
+			name:        "unknown service",
+			service:     "testproto.UnknownService",
+			expectedErr: codes.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dispatcher.HealthProbe(tt.service).Probe(context.Background())
+			assert.Equal(t, tt.expectedErr, status.Code(err))
+		})
+	}
+}
+
+func TestDispatcher_DoTimeout(t *testing.T) {
+	delayedPort := 50056
+	testutils.RunTestUPIServer(
+		testutils.GrpcTestServer{
+			Port:         delayedPort,
+			MockResponse: mockResponse,
+			DelayTimer:   100 * time.Millisecond,
+		},
+	)
+
+	tests := []struct {
+		name          string
+		timeoutStatus *status.Status
+		expectedCode  int
+		expectedMsg   string
+	}{
+		{
+			name:         "default timeout status",
+			expectedCode: int(codes.DeadlineExceeded),
+			expectedMsg:  "rpc error: code = DeadlineExceeded desc = context deadline exceeded",
+		},
+		{
+			name:          "custom timeout status",
+			timeoutStatus: status.New(codes.Unavailable, "prediction service is busy"),
+			expectedCode:  int(codes.Unavailable),
+			expectedMsg:   "prediction service is busy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher, err := NewDispatcher(DispatcherConfig{
+				ServiceMethod: serviceMethod,
+				Endpoint:      fmt.Sprintf(":%d", delayedPort),
+				Timeout:       20 * time.Millisecond,
+				TimeoutStatus: tt.timeoutStatus,
+			})
+			require.NoError(t, err)
+
+			response := dispatcher.Do(context.Background(), &Request{Message: []byte{}})
+			require.False(t, response.IsSuccess())
+			assert.Equal(t, fiber.NewErrorResponse(fiberError.FiberError{
+				Code:    tt.expectedCode,
+				Message: tt.expectedMsg,
+			}), response)
+		})
+	}
+}
+
+func TestDispatcher_IdleTimeout(t *testing.T) {
+	delayedPort := 50065
+	testutils.RunTestUPIServer(testutils.GrpcTestServer{
+		Port:         delayedPort,
+		MockResponse: mockResponse,
+		DelayTimer:   200 * time.Millisecond,
+	})
+
+	newDispatcher := func(gracePeriod time.Duration) (*Dispatcher, *time.Time) {
+		// the reaper doesn't run within the test, so the idle connection is evicted by the fake clock
+		dispatcher, err := NewDispatcher(DispatcherConfig{
+			ServiceMethod:   serviceMethod,
+			Endpoint:        fmt.Sprintf(":%d", delayedPort),
+			Timeout:         time.Second,
+			IdleTimeout:     time.Hour,
+			IdleGracePeriod: gracePeriod,
+		})
+		require.NoError(t, err)
+		now := time.Now()
+		dispatcher.conn.now = func() time.Time { return now }
+		return dispatcher, &now
+	}
+	call := func(dispatcher *Dispatcher) <-chan fiber.Response {
+		done := make(chan fiber.Response, 1)
+		go func() {
+			done <- dispatcher.Do(context.Background(), &Request{Message: []byte{}})
+		}()
+		return done
+	}
+	inFlight := func(dispatcher *Dispatcher) func() bool {
+		return func() bool {
+			dispatcher.conn.lock.Lock()
+			defer dispatcher.conn.lock.Unlock()
+			return dispatcher.conn.current != nil && dispatcher.conn.current.calls == 1
+		}
+	}
+
+	t.Run("idle connection is evicted and re-dialed", func(t *testing.T) {
+		dispatcher, now := newDispatcher(0)
+		defer dispatcher.Close(context.Background())
+
+		assert.True(t, (<-call(dispatcher)).IsSuccess())
+		conn := dispatcher.conn.current
+
+		// the connection, used within the idle timeout, is kept
+		*now = now.Add(30 * time.Minute)
+		dispatcher.conn.evictIdle()
+		assert.Equal(t, int64(0), dispatcher.EvictedConnections())
+
+		*now = now.Add(time.Hour)
+		dispatcher.conn.evictIdle()
+		assert.Equal(t, int64(1), dispatcher.EvictedConnections())
+		assert.Equal(t, connectivity.Shutdown, conn.GetState())
+
+		// the next call dials the new connection
+		assert.True(t, (<-call(dispatcher)).IsSuccess())
+		assert.NotSame(t, conn, dispatcher.conn.current)
+		assert.Equal(t, int64(1), dispatcher.EvictedConnections())
+	})
+
+	t.Run("evicted connection waits for its calls", func(t *testing.T) {
+		dispatcher, now := newDispatcher(time.Minute)
+		defer dispatcher.Close(context.Background())
+
+		done := call(dispatcher)
+		require.Eventually(t, inFlight(dispatcher), time.Second, time.Millisecond)
+		conn := dispatcher.conn.current
+		*now = now.Add(2 * time.Hour)
+		dispatcher.conn.evictIdle()
+		assert.Equal(t, int64(1), dispatcher.EvictedConnections())
+		assert.NotEqual(t, connectivity.Shutdown, conn.GetState())
+
+		// the connection is closed, once its call is complete
+		assert.True(t, (<-done).IsSuccess())
+		require.Eventually(t, func() bool {
+			return conn.GetState() == connectivity.Shutdown
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("evicted connection is closed after the grace period", func(t *testing.T) {
+		dispatcher, now := newDispatcher(50 * time.Millisecond)
+		defer dispatcher.Close(context.Background())
+
+		done := call(dispatcher)
+		require.Eventually(t, inFlight(dispatcher), time.Second, time.Millisecond)
+		conn := dispatcher.conn.current
+		*now = now.Add(2 * time.Hour)
+		dispatcher.conn.evictIdle()
+
+		require.Eventually(t, func() bool {
+			return conn.GetState() == connectivity.Shutdown
+		}, time.Second, time.Millisecond)
+		// the call of the closed connection fails
+		assert.False(t, (<-done).IsSuccess())
+	})
+
+	t.Run("closed dispatcher doesn't re-dial", func(t *testing.T) {
+		dispatcher, now := newDispatcher(0)
+		*now = now.Add(2 * time.Hour)
+		dispatcher.conn.evictIdle()
+		require.NoError(t, dispatcher.Close(context.Background()))
+
+		resp := dispatcher.Do(context.Background(), &Request{Message: []byte{}})
+		assert.Equal(t, int(codes.Canceled), resp.StatusCode())
+		assert.Nil(t, dispatcher.conn.current)
+	})
+}
+
+func TestNewDispatcher_IdleTimeout(t *testing.T) {
+	_, err := NewDispatcher(DispatcherConfig{
+		ServiceMethod: serviceMethod,
+		Endpoint:      fmt.Sprintf(":%d", port),
+		IdleTimeout:   -time.Second,
+	})
+	assert.Equal(t, fiberError.ErrInvalidInput(protocol.GRPC,
+		errors.New("grpc dispatcher: idle timeout and grace period can not be negative")), err)
+
+	// the reaper evicts the idle connection in background, and stops, once the dispatcher is closed
+	dispatcher, err := NewDispatcher(DispatcherConfig{
+		ServiceMethod: serviceMethod,
+		Endpoint:      fmt.Sprintf(":%d", port),
+		IdleTimeout:   20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.True(t, dispatcher.Do(context.Background(), &Request{Message: []byte{}}).IsSuccess())
+	require.Eventually(t, func() bool {
+		return dispatcher.EvictedConnections() == 1
+	}, time.Second, time.Millisecond)
+	require.True(t, dispatcher.Do(context.Background(), &Request{Message: []byte{}}).IsSuccess())
+	require.NoError(t, dispatcher.Close(context.Background()))
+}
+
+func TestDispatcher_DoDeadlinePropagation(t *testing.T) {
+	delayedPort := 50057
+	testutils.RunTestUPIServer(
+		testutils.GrpcTestServer{
+			Port:         delayedPort,
+			MockResponse: mockResponse,
+			DelayTimer:   200 * time.Millisecond,
+		},
+	)
+
+	tests := []struct {
+		name           string
+		timeout        time.Duration
+		deadline       time.Duration
+		deadlineBuffer time.Duration
+		maxTimeout     time.Duration
+		requestTimeout time.Duration
+		maxElapsed     time.Duration
+		success        bool
+	}{
+		{
+			name:       "incoming deadline is shorter than timeout",
+			timeout:    time.Second,
+			deadline:   30 * time.Millisecond,
+			maxElapsed: 100 * time.Millisecond,
+		},
+		{
+			name:           "incoming deadline with buffer",
+			timeout:        time.Second,
+			deadline:       150 * time.Millisecond,
+			deadlineBuffer: 130 * time.Millisecond,
+			maxElapsed:     100 * time.Millisecond,
+		},
+		{
+			name:       "timeout is shorter than incoming deadline",
+			timeout:    30 * time.Millisecond,
+			deadline:   time.Second,
+			maxElapsed: 100 * time.Millisecond,
+		},
+		{
+			name:           "request timeout is shorter than timeout",
+			timeout:        time.Second,
+			deadline:       time.Second,
+			requestTimeout: 30 * time.Millisecond,
+			maxElapsed:     100 * time.Millisecond,
+		},
+		{
+			name:           "request timeout is capped by timeout",
+			timeout:        30 * time.Millisecond,
+			deadline:       time.Second,
+			requestTimeout: 500 * time.Millisecond,
+			maxElapsed:     100 * time.Millisecond,
+		},
+		{
+			name:           "request timeout is capped by max timeout",
+			timeout:        30 * time.Millisecond,
+			deadline:       time.Second,
+			maxTimeout:     50 * time.Millisecond,
+			requestTimeout: 500 * time.Millisecond,
+			maxElapsed:     100 * time.Millisecond,
+		},
+		{
+			name:           "incoming deadline is shorter than request timeout",
+			timeout:        30 * time.Millisecond,
+			deadline:       50 * time.Millisecond,
+			maxTimeout:     time.Second,
+			requestTimeout: 500 * time.Millisecond,
+			maxElapsed:     100 * time.Millisecond,
+		},
+		{
+			name:           "request timeout within max timeout",
+			timeout:        30 * time.Millisecond,
+			deadline:       time.Second,
+			maxTimeout:     time.Second,
+			requestTimeout: 500 * time.Millisecond,
+			maxElapsed:     500 * time.Millisecond,
+			success:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher, err := NewDispatcher(DispatcherConfig{
+				ServiceMethod:  serviceMethod,
+				Endpoint:       fmt.Sprintf(":%d", delayedPort),
+				Timeout:        tt.timeout,
+				MaxTimeout:     tt.maxTimeout,
+				DeadlineBuffer: tt.deadlineBuffer,
+			})
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.deadline)
+			defer cancel()
+			if tt.requestTimeout > 0 {
+				ctx = fiber.WithRequestTimeout(ctx, tt.requestTimeout)
+			}
+
+			start := time.Now()
+			response := dispatcher.Do(ctx, &Request{Message: []byte{}})
+			assert.Less(t, int64(time.Since(start)), int64(tt.maxElapsed))
+			if tt.success {
+				assert.True(t, response.IsSuccess())
+				return
+			}
+			require.False(t, response.IsSuccess())
+			assert.Equal(t, int(codes.DeadlineExceeded), response.StatusCode())
+		})
+	}
+
+	t.Run("router responds with request timeout", func(t *testing.T) {
+		dispatcher, err := NewDispatcher(DispatcherConfig{
+			ServiceMethod: serviceMethod,
+			Endpoint:      fmt.Sprintf(":%d", delayedPort),
+			Timeout:       time.Second,
+		})
+		require.NoError(t, err)
+		caller, err := fiber.NewCaller("route-a", dispatcher)
+		require.NoError(t, err)
+
+		router := fiber.NewEagerRouter("eager-router")
+		router.SetRoutes(map[string]fiber.Component{"route-a": fiber.NewProxy(nil, caller)})
+		router.SetStrategy(&extras.RandomRoutingStrategy{})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		defer cancel()
+
+		response, ok := <-router.Dispatch(ctx, &Request{Message: []byte{}}).Iter()
+		require.True(t, ok)
+		expected := fiber.NewErrorResponse(fiberError.ErrRequestTimeout(protocol.GRPC))
+		assert.Equal(t, expected.StatusCode(), response.StatusCode())
+		assert.Equal(t, expected.Payload(), response.Payload())
+		// route-a hasn't responded before the deadline of the router
+		assert.Equal(t, []fiberError.RouteError{
+			{RouteID: "route-a", Code: int(codes.Unavailable), Message: "fiber: no responses received"},
+		}, response.(*fiber.ErrorResponse).RouteErrors())
+	})
+}
+
+func TestDispatcher_DoStreaming(t *testing.T) {
+	servers := map[string]testutils.StreamingTestServer{
+		"ok": {
+			Port:   50058,
+			Frames: [][]byte{[]byte("frame-1"), []byte("frame-2"), []byte("frame-3")},
+		},
+		"broken": {
+			Port:   50059,
+			Frames: [][]byte{[]byte("frame-1")},
+			Err:    status.Error(codes.Internal, "stream broken"),
+		},
+		"unavailable": {
+			Port: 50060,
+			Err:  status.Error(codes.Unavailable, "backend unavailable"),
+		},
+	}
+	dispatchers := make(map[string]*Dispatcher)
+	for name, server := range servers {
+		testutils.RunTestStreamingServer(server)
+		dispatcher, err := NewDispatcher(DispatcherConfig{
+			ServiceMethod: "testproto.StreamingService/StreamValues",
+			Endpoint:      fmt.Sprintf(":%d", server.Port),
+			Timeout:       time.Second,
+			Streaming:     true,
+		})
+		require.NoError(t, err)
+		dispatchers[name] = dispatcher
+	}
+
+	type frame struct {
+		payload string
+		code    int
+		backend string
+	}
+
+	tests := []struct {
+		name     string
+		routes   []string
+		expected []frame
+	}{
+		{
+			name:   "frames of the stream",
+			routes: []string{"ok"},
+			expected: []frame{
+				{payload: "frame-1", backend: "ok"},
+				{payload: "frame-2", backend: "ok"},
+				{payload: "frame-3", backend: "ok"},
+			},
+		},
+		{
+			name:   "router is committed to the stream after the first frame",
+			routes: []string{"broken", "ok"},
+			expected: []frame{
+				{payload: "frame-1", backend: "broken"},
+				{code: int(codes.Internal), backend: "broken"},
+			},
+		},
+		{
+			name:   "router falls back from the failed stream",
+			routes: []string{"unavailable", "ok"},
+			expected: []frame{
+				{payload: "frame-1", backend: "ok"},
+				{payload: "frame-2", backend: "ok"},
+				{payload: "frame-3", backend: "ok"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := make(map[string]fiber.Component)
+			for _, name := range tt.routes {
+				caller, err := fiber.NewCaller(name, dispatchers[name])
+				require.NoError(t, err)
+				routes[name] = caller
+			}
+			router := fiber.NewLazyRouter("lazy-router")
+			router.SetRoutes(routes)
+			router.SetStrategy(fiberTestUtils.NewMockRoutingStrategy(routes, tt.routes, 0, nil))
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			var received []frame
+			for resp := range router.Dispatch(ctx, &Request{Message: []byte("request")}).Iter() {
+				received = append(received, frame{
+					payload: string(resp.Payload()),
+					code:    resp.StatusCode(),
+					backend: resp.BackendName(),
+				})
+				if !resp.IsSuccess() {
+					// the payload of the error response is not compared
+					received[len(received)-1].payload = ""
+				}
+			}
+			assert.Equal(t, tt.expected, received)
+		})
+	}
+
+	t.Run("eager router falls back from the streaming route", func(t *testing.T) {
+		caller, err := fiber.NewCaller("ok", dispatchers["ok"])
+		require.NoError(t, err)
+		routes := map[string]fiber.Component{
+			"ok": caller,
+			"unary": fiberTestUtils.NewMockComponent("unary", httpTestUtils.DelayedResponse{
+				Response: &Response{Message: []byte("unary"), Metadata: metadata.MD{}, Status: *status.New(codes.OK, "")},
+				Latency:  50 * time.Millisecond,
+			}),
+		}
+		router := fiber.NewEagerRouter("eager-router")
+		router.SetRoutes(routes)
+		router.SetStrategy(fiberTestUtils.NewMockRoutingStrategy(routes, []string{"ok", "unary"}, 0, nil))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		// the frames of the stream can't be selected as the single response of the router
+		resp, ok := <-router.Dispatch(ctx, &Request{Message: []byte("request")}).Iter()
+		require.True(t, ok)
+		assert.True(t, resp.IsSuccess())
+		assert.Equal(t, "unary", string(resp.Payload()))
+
+		router.SetStrategy(fiberTestUtils.NewMockRoutingStrategy(routes, []string{"ok"}, 0, nil))
+		resp, ok = <-router.Dispatch(ctx, &Request{Message: []byte("request")}).Iter()
+		require.True(t, ok)
+		assert.Equal(t, int(codes.Unavailable), resp.StatusCode())
+		routeErrors := resp.(*fiber.ErrorResponse).RouteErrors()
+		require.Len(t, routeErrors, 1)
+		assert.Contains(t, routeErrors[0].Message, "eager router doesn't support the streaming route: ok")
+	})
+
+	t.Run("dispatcher responds with the stream", func(t *testing.T) {
+		response := dispatchers["ok"].Do(context.Background(), &Request{Message: []byte("request")})
+		require.IsType(t, &StreamingResponse{}, response)
+
+		var payloads []string
+		for frame := range response.(fiber.StreamingResponse).Frames() {
+			require.True(t, frame.(fiber.StreamFrame).IsStreamFrame())
+			payloads = append(payloads, string(frame.Payload()))
+		}
+		assert.Equal(t, []string{"frame-1", "frame-2", "frame-3"}, payloads)
+	})
+}
+
+func TestDispatcher_DoStatusDetails(t *testing.T) {
+	badRequest := &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "prediction_rows", Description: "must not be empty"},
+		},
+	}
+	invalidArgument, err := status.New(codes.InvalidArgument, "invalid prediction rows").WithDetails(badRequest)
+	require.NoError(t, err)
+
+	invalidArgumentPort := 50062
+	testutils.RunTestUPIServer(
+		testutils.GrpcTestServer{
+			Port:      invalidArgumentPort,
+			MockError: invalidArgument.Err(),
+		},
+	)
+	dispatcher, err := NewDispatcher(DispatcherConfig{
+		ServiceMethod: serviceMethod,
+		Endpoint:      fmt.Sprintf(":%d", invalidArgumentPort),
+		Timeout:       time.Second,
+	})
+	require.NoError(t, err)
+	route, err := fiber.NewCaller("route-a", dispatcher)
+	require.NoError(t, err)
+
+	// the response of the fallback route carries the details of its status too
+	resourceInfo := &errdetails.ResourceInfo{ResourceType: "model", ResourceName: "linear"}
+	fallbackStatus, err := status.New(codes.NotFound, "model not found").WithDetails(resourceInfo)
+	require.NoError(t, err)
+	fallback := fiberTestUtils.NewMockComponent("route-b", httpTestUtils.DelayedResponse{
+		Response: &Response{Metadata: metadata.MD{}, Status: *fallbackStatus},
+	})
+
+	tests := []struct {
+		name         string
+		failureCodes []int
+		expectedCode codes.Code
+		expected     []proto.Message
+	}{
+		{
+			name:         "terminal status of the route",
+			expectedCode: codes.InvalidArgument,
+			expected:     []proto.Message{badRequest},
+		},
+		{
+			name:         "status of the fallback route",
+			failureCodes: []int{int(codes.InvalidArgument)},
+			expectedCode: codes.NotFound,
+			expected:     []proto.Message{resourceInfo},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := map[string]fiber.Component{"route-a": route, "route-b": fallback}
+			router := fiber.NewLazyRouter("router")
+			router.SetRoutes(routes)
+			router.SetStrategy(fiberTestUtils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+			if tt.failureCodes != nil {
+				router.SetFailureStatusCodes(tt.failureCodes...)
+			}
+
+			resp, ok := <-router.Dispatch(context.Background(), &Request{Message: []byte{}}).Iter()
+			require.True(t, ok)
+			require.Equal(t, int(tt.expectedCode), resp.StatusCode())
+
+			var st *status.Status
+			if errResp, ok := resp.(*fiber.ErrorResponse); ok {
+				st = ErrorStatus(errResp)
+			} else {
+				grpcResp, ok := resp.(*Response)
+				require.True(t, ok)
+				st = &grpcResp.Status
+			}
+			details := st.Details()
+			require.Len(t, details, len(tt.expected))
+			for idx, expected := range tt.expected {
+				detail, ok := details[idx].(proto.Message)
+				require.True(t, ok)
+				assert.True(t, proto.Equal(expected, detail), "actual status details don't match expected")
+			}
+		})
+	}
+
+	// the details of the failed routes follow their error info, when all the routes have failed
+	routes := map[string]fiber.Component{"route-a": route}
+	router := fiber.NewLazyRouter("router")
+	router.SetRoutes(routes)
+	router.SetStrategy(fiberTestUtils.NewMockRoutingStrategy(routes, []string{"route-a"}, 0, nil))
+	router.SetFailureStatusCodes(int(codes.InvalidArgument))
+
+	resp, ok := <-router.Dispatch(context.Background(), &Request{Message: []byte{}}).Iter()
+	require.True(t, ok)
+	errResp, ok := resp.(*fiber.ErrorResponse)
+	require.True(t, ok)
+	details := ErrorStatus(errResp).Details()
+	require.Len(t, details, 2)
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, "route-a", info.Domain)
+	detail, ok := details[1].(proto.Message)
+	require.True(t, ok)
+	assert.True(t, proto.Equal(badRequest, detail), "actual status details don't match expected")
+}
@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gojek/fiber"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CanonicalHash returns the hex-encoded sha256 hash of the deterministic encoding of the message of the given type.
+// The protobuf encoding isn't canonical, i.e. the same message can be encoded with its fields in a different order,
+// so the message is decoded and encoded again, before it's hashed, and the semantically-equal messages have the same
+// hash. The hashes are only comparable within the same version of the binary and of the message type
+func CanonicalHash(messageType protoreflect.MessageType, payload []byte) (string, error) {
+	message := messageType.New().Interface()
+	if err := proto.Unmarshal(payload, message); err != nil {
+		return "", fmt.Errorf("grpc hash: invalid message [%s]: %v", messageType.Descriptor().FullName(), err)
+	}
+	canonical, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		return "", fmt.Errorf("grpc hash: %v", err)
+	}
+	digest := sha256.Sum256(canonical)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// CanonicalCacheKey returns the fiber.CacheKeyFunc, that keys the grpc requests on the CanonicalHash of their
+// message of the given type, e.g. the input of the service method, and the values of the given metadata keys
+// (see CacheKey). The requests with the message, that can't be decoded, are dispatched without the cache
+func CanonicalCacheKey(messageType protoreflect.MessageType, metadataKeys ...string) fiber.CacheKeyFunc {
+	return func(req fiber.Request) (string, bool) {
+		grpcReq, ok := req.(*Request)
+		if !ok {
+			return "", false
+		}
+		hash, err := CanonicalHash(messageType, grpcReq.Payload())
+		if err != nil {
+			return "", false
+		}
+		return cacheKey(hash, grpcReq, metadataKeys), true
+	}
+}
+
+// CanonicalResponseKey returns the key function of the responses, that keys them on the CanonicalHash of their
+// message of the given type, e.g. to be used as the extras.QuorumKeyFunc, so the responses with the same message
+// agree, even if they are encoded differently. The responses with the message, that can't be decoded, are keyed
+// on the hash of their payload as it is
+func CanonicalResponseKey(messageType protoreflect.MessageType) func(resp fiber.Response) string {
+	return func(resp fiber.Response) string {
+		if hash, err := CanonicalHash(messageType, resp.Payload()); err == nil {
+			return hash
+		}
+		digest := sha256.Sum256(resp.Payload())
+		return hex.EncodeToString(digest[:])
+	}
+}
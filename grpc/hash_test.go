@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// appendString appends the string field to the encoded message
+func appendString(b []byte, num protowire.Number, value string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+// appendMessage appends the embedded message field to the encoded message
+func appendMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+func TestCanonicalCacheKey(t *testing.T) {
+	descriptors, err := LoadDescriptorSet(descriptorSetPath)
+	require.NoError(t, err)
+	requestType, err := descriptors.FindMessageType("testproto.PredictValuesRequest")
+	require.NoError(t, err)
+
+	// the same request with prediction_rows and metadata encoded in the different order
+	row := appendString(nil, 1, "row-1")
+	requestMetadata := appendString(nil, 2, "target")
+	rowsFirst := appendMessage(appendMessage(nil, 1, row), 2, requestMetadata)
+	metadataFirst := appendMessage(appendMessage(nil, 2, requestMetadata), 1, row)
+	require.NotEqual(t, rowsFirst, metadataFirst)
+
+	tests := []struct {
+		name     string
+		reqA     *Request
+		reqB     *Request
+		sameKeys bool
+	}{
+		{
+			name:     "same messages in different field order",
+			reqA:     &Request{Message: rowsFirst},
+			reqB:     &Request{Message: metadataFirst},
+			sameKeys: true,
+		},
+		{
+			name: "different messages",
+			reqA: &Request{Message: rowsFirst},
+			reqB: &Request{Message: appendMessage(nil, 1, row)},
+		},
+		{
+			name: "different selected metadata",
+			reqA: &Request{Message: rowsFirst, Metadata: metadata.Pairs("customer-id", "1")},
+			reqB: &Request{Message: metadataFirst, Metadata: metadata.Pairs("customer-id", "2")},
+		},
+	}
+
+	key := CanonicalCacheKey(requestType, "Customer-ID")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyA, okA := key(tt.reqA)
+			keyB, okB := key(tt.reqB)
+			assert.True(t, okA)
+			assert.True(t, okB)
+			assert.Equal(t, tt.sameKeys, keyA == keyB)
+		})
+	}
+
+	// the raw bytes of the messages differ, so their plain cache keys do too
+	plainA, _ := CacheKey()(&Request{Message: rowsFirst})
+	plainB, _ := CacheKey()(&Request{Message: metadataFirst})
+	assert.NotEqual(t, plainA, plainB)
+
+	_, ok := key(&Request{Message: []byte("invalid")})
+	assert.False(t, ok, "the request with the invalid message should not be cached")
+}
+
+func TestCanonicalResponseKey(t *testing.T) {
+	descriptors, err := LoadDescriptorSet(descriptorSetPath)
+	require.NoError(t, err)
+	responseType, err := descriptors.FindMessageType("testproto.PredictValuesResponse")
+	require.NoError(t, err)
+
+	prediction := appendString(nil, 1, "row-1")
+	responseMetadata := appendString(appendString(nil, 2, "target"), 1, "prediction-1")
+	reorderedMetadata := appendString(appendString(nil, 1, "prediction-1"), 2, "target")
+
+	key := CanonicalResponseKey(responseType)
+	predictionsFirst := key(&Response{Message: appendMessage(appendMessage(nil, 1, prediction), 2, responseMetadata)})
+	metadataFirst := key(&Response{Message: appendMessage(appendMessage(nil, 2, reorderedMetadata), 1, prediction)})
+	assert.Equal(t, predictionsFirst, metadataFirst)
+	assert.NotEqual(t, predictionsFirst, key(&Response{Message: appendMessage(nil, 1, prediction)}))
+
+	// the responses, that can't be decoded, are keyed on their payload
+	assert.Equal(t, key(&Response{Message: []byte("invalid")}), key(&Response{Message: []byte("invalid")}))
+	assert.NotEqual(t, key(&Response{Message: []byte("invalid")}), key(&Response{Message: []byte("other")}))
+}
@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gojek/fiber"
+	fiberError "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MergeMessages returns the function, that merges the messages of the grpc responses into a single message
+// of the given type with proto.Merge, in the order of the responses: the repeated fields are concatenated,
+// and the singular ones are overwritten by the later responses. It can be used as the extras.MergeFunc,
+// e.g. to concatenate the results of the sharded backends. The type can be the dynamic one (see DescriptorSet),
+// so the generated code of the message is not needed. The message, that can't be decoded, is either skipped
+// (fiber.DecodeErrorFail), or sent back as it is instead of the merged one (fiber.DecodeErrorPassThrough),
+// or ErrDecodeFailed is sent back (fiber.DecodeErrorRespond). ErrDecodeFailed is also sent back, if none of
+// the messages can be decoded
+func MergeMessages(
+	messageType protoreflect.MessageType,
+	policy fiber.DecodeErrorPolicy,
+) func(context.Context, fiber.Request, []fiber.Response) fiber.Response {
+	return func(ctx context.Context, _ fiber.Request, responses []fiber.Response) fiber.Response {
+		merged := messageType.New().Interface()
+		var decodeErr error
+		decoded := 0
+		for _, resp := range responses {
+			message := messageType.New().Interface()
+			if err := proto.Unmarshal(resp.Payload(), message); err != nil {
+				decodeErr = fmt.Errorf("grpc merge: invalid response message [%s]: %v", messageType.Descriptor().FullName(), err)
+				fiber.LogDecodeError(ctx, resp, decodeErr)
+				switch policy {
+				case fiber.DecodeErrorPassThrough:
+					return resp
+				case fiber.DecodeErrorRespond:
+					return fiber.NewErrorResponse(fiberError.ErrDecodeFailed(protocol.GRPC, decodeErr))
+				}
+				continue
+			}
+			proto.Merge(merged, message)
+			decoded++
+		}
+		if decoded == 0 && decodeErr != nil {
+			return fiber.NewErrorResponse(fiberError.ErrDecodeFailed(protocol.GRPC, decodeErr))
+		}
+		payload, err := proto.Marshal(merged)
+		if err != nil {
+			return fiber.NewErrorResponse(fiberError.ErrRequestFailed(protocol.GRPC, err))
+		}
+		return &Response{
+			Metadata: metadata.MD{},
+			Message:  payload,
+			Status:   *status.New(codes.OK, ""),
+		}
+	}
+}
@@ -34,9 +34,29 @@ func (r *Request) Header() map[string][]string {
 	return r.Metadata
 }
 
+// SetHeader sets the metadata of the request, initializing it, if the request was created without one
+func (r *Request) SetHeader(key string, values ...string) {
+	if r.Metadata == nil {
+		r.Metadata = metadata.MD{}
+	}
+	r.Metadata.Set(key, values...)
+}
+
+// Clone returns a copy of the request with its own metadata, so the routers can tag the copy, that is
+// dispatched by a route, without changing the caller's request. The message is shared, since it's not
+// modified by the dispatch
 func (r *Request) Clone() (fiber.Request, error) {
-	// for grpc, we'll just return itself (no cloning)
-	return r, nil
+	clone := *r
+	if r.Metadata != nil {
+		clone.Metadata = r.Metadata.Copy()
+	}
+	return &clone, nil
+}
+
+// RequestID returns the id of the request from its metadata, generating it, if the request doesn't
+// have one (see fiber.EnsureRequestID)
+func (r *Request) RequestID() string {
+	return fiber.EnsureRequestID(r)
 }
 
 // OperationName is naming used in tracing interceptors
@@ -33,6 +33,33 @@ func TestRequest_Clone(t *testing.T) {
 			assert.Equal(t, tt.req, clone)
 		})
 	}
+
+	t.Run("metadata is copied", func(t *testing.T) {
+		req := &Request{Metadata: metadata.New(map[string]string{"test": "1"})}
+		clone, err := req.Clone()
+		assert.NoError(t, err)
+
+		fiber.SetRequestHeader(clone, "test", "2")
+		assert.Equal(t, []string{"1"}, req.Metadata.Get("test"))
+		assert.Equal(t, []string{"2"}, clone.Header()["test"])
+	})
+}
+
+func TestRequest_RequestID(t *testing.T) {
+	t.Run("provided", func(t *testing.T) {
+		req := &Request{Metadata: metadata.Pairs("x-request-id", "request-1")}
+		assert.Equal(t, "request-1", req.RequestID())
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		req := &Request{}
+		id := req.RequestID()
+		assert.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", id)
+		// the id is set on the metadata of the request, so it's sent to the backend and stays the same
+		assert.Equal(t, []string{id}, req.Metadata.Get(fiber.RequestIDHeader))
+		assert.Equal(t, id, req.RequestID())
+		assert.NotEqual(t, id, (&Request{}).RequestID())
+	})
 }
 
 func TestRequest_Header(t *testing.T) {
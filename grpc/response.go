@@ -1,9 +1,11 @@
 package grpc
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/gojek/fiber"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -13,6 +15,9 @@ type Response struct {
 	Metadata metadata.MD
 	Message  []byte
 	Status   status.Status
+
+	// frame is true, if the response is a frame of a StreamingResponse
+	frame bool
 }
 
 func (r *Response) IsSuccess() bool {
@@ -27,11 +32,152 @@ func (r *Response) StatusCode() int {
 	return int(r.Status.Code())
 }
 
+// BackendName returns the breadcrumb of the backends of the response, joined with fiber.BackendNameSeparator
 func (r *Response) BackendName() string {
-	return strings.Join(r.Metadata.Get("backend"), ",")
+	return strings.Join(r.Metadata.Get("backend"), fiber.BackendNameSeparator)
 }
 
+// WithBackendName prepends the backend to the breadcrumb of the response, so the routers, that name
+// the responses after the routes they've been dispatched to, record the routes from the outermost one
 func (r *Response) WithBackendName(backendName string) fiber.Response {
-	r.Metadata.Set("backend", backendName)
+	r.initMetadata()
+	r.Metadata.Set("backend", append([]string{backendName}, r.Metadata.Get("backend")...)...)
+	return r
+}
+
+// AppendBackendName adds the backend to the end of the breadcrumb of the response, e.g. to record
+// the backend, that has served the innermost route
+func (r *Response) AppendBackendName(backendName string) fiber.Response {
+	return r.AppendMetadata("backend", backendName)
+}
+
+// MetadataValues returns the copy of the values of the metadata key, which is case-insensitive
+func (r *Response) MetadataValues(key string) []string {
+	values := r.Metadata.Get(key)
+	if len(values) == 0 {
+		return nil
+	}
+	return append([]string(nil), values...)
+}
+
+// MetadataFirst returns the first value of the metadata key, and false, if the response has no such key
+func (r *Response) MetadataFirst(key string) (string, bool) {
+	values := r.Metadata.Get(key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// AppendMetadata adds the values to the metadata key, keeping its existing values
+func (r *Response) AppendMetadata(key string, values ...string) *Response {
+	r.initMetadata()
+	r.Metadata.Append(key, values...)
+	return r
+}
+
+// initMetadata initializes the metadata, if the response was created without one
+func (r *Response) initMetadata() {
+	if r.Metadata == nil {
+		r.Metadata = metadata.MD{}
+	}
+}
+
+// Clone returns the copy of the response with its own metadata, so it can be modified independently.
+// The message is shared by the copies
+func (r *Response) Clone() fiber.Response {
+	return &Response{
+		Metadata: r.Metadata.Copy(),
+		Message:  r.Message,
+		Status:   r.Status,
+		frame:    r.frame,
+	}
+}
+
+// SetHeader sets the metadata key of the response, replacing its existing values
+func (r *Response) SetHeader(key string, values ...string) {
+	if r.Metadata == nil {
+		r.Metadata = metadata.MD{}
+	}
+	r.Metadata.Set(key, values...)
+}
+
+// IsStreamFrame returns true, if the response is a frame of a StreamingResponse
+func (r *Response) IsStreamFrame() bool {
+	return r.frame
+}
+
+// StreamingResponse is the response of a server-streaming method. Its frames are the messages
+// of the stream, and the failure of the stream, if any, is sent as the last frame
+type StreamingResponse struct {
+	Metadata metadata.MD
+	frames   <-chan fiber.Response
+}
+
+func (r *StreamingResponse) IsSuccess() bool {
+	return true
+}
+
+func (r *StreamingResponse) Payload() []byte {
+	return nil
+}
+
+func (r *StreamingResponse) StatusCode() int {
+	return int(codes.OK)
+}
+
+func (r *StreamingResponse) BackendName() string {
+	return strings.Join(r.Metadata.Get("backend"), fiber.BackendNameSeparator)
+}
+
+// WithBackendName prepends the backend to the breadcrumb of the response (see Response.WithBackendName)
+func (r *StreamingResponse) WithBackendName(backendName string) fiber.Response {
+	if r.Metadata == nil {
+		r.Metadata = metadata.MD{}
+	}
+	r.Metadata.Set("backend", append([]string{backendName}, r.Metadata.Get("backend")...)...)
 	return r
 }
+
+// Frames returns the channel of the messages of the stream
+func (r *StreamingResponse) Frames() <-chan fiber.Response {
+	return r.frames
+}
+
+// ErrorStatus converts the error response into the grpc status, so it can be returned by the grpc server.
+// The details of the status of the backend, that the response has been created from, are kept (see
+// fiber.ErrorResponse.Cause). The failures of the routes, if any (see fiber.ErrorResponse.RouteErrors), are added
+// into the details of the status, as one errdetails.ErrorInfo per route, with the route ID as its domain, followed
+// by the details of the status of the route's backend, if it had any
+func ErrorStatus(resp *fiber.ErrorResponse) *status.Status {
+	st := withCauseDetails(status.New(codes.Code(resp.StatusCode()), resp.Message()), resp.Cause())
+	for _, routeErr := range resp.RouteErrors() {
+		withDetails, err := st.WithDetails(&errdetails.ErrorInfo{
+			Reason: codes.Code(routeErr.Code).String(),
+			Domain: routeErr.RouteID,
+			Metadata: map[string]string{
+				"code":  strconv.Itoa(routeErr.Code),
+				"error": routeErr.Message,
+			},
+		})
+		if err != nil {
+			return st
+		}
+		st = withCauseDetails(withDetails, routeErr.Cause)
+	}
+	return st
+}
+
+// withCauseDetails appends the details of the grpc status of the cause, if it has any, to the details of the status
+func withCauseDetails(st *status.Status, cause error) *status.Status {
+	if cause == nil {
+		return st
+	}
+	causeStatus, ok := status.FromError(cause)
+	if !ok || len(causeStatus.Proto().GetDetails()) == 0 {
+		return st
+	}
+	withDetails := st.Proto()
+	withDetails.Details = append(withDetails.Details, causeStatus.Proto().GetDetails()...)
+	return status.FromProto(withDetails)
+}
@@ -1,11 +1,19 @@
 package grpc
 
 import (
+	"context"
 	"log"
 	"testing"
 
+	"github.com/gojek/fiber"
+	fiberError "github.com/gojek/fiber/errors"
 	testproto "github.com/gojek/fiber/internal/testdata/gen/testdata/proto"
+	fiberTestUtils "github.com/gojek/fiber/internal/testutils"
+	httpTestUtils "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -39,6 +47,92 @@ func TestResponse_Backend(t *testing.T) {
 	}
 }
 
+func TestResponse_SetHeader(t *testing.T) {
+	res := &Response{}
+	res.SetHeader("Model-Name", "model-a")
+	res.SetHeader("model-name", "model-b")
+	assert.Equal(t, []string{"model-b"}, res.Metadata.Get("model-name"))
+}
+
+func TestResponse_AppendBackendName(t *testing.T) {
+	res := &Response{}
+	res.AppendBackendName("inner")
+	res.AppendBackendName("outer")
+	assert.Equal(t, []string{"inner", "outer"}, res.MetadataValues("backend"))
+	assert.Equal(t, "inner,outer", res.BackendName())
+
+	// WithBackendName prepends the route to the backends, that have been appended
+	res.WithBackendName("route")
+	assert.Equal(t, "route,inner,outer", res.BackendName())
+	assert.Equal(t, "route", fiber.RouteID(res))
+}
+
+func TestResponse_Metadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		res         *Response
+		key         string
+		want        []string
+		wantFirst   string
+		wantFirstOk bool
+	}{
+		{
+			name: "multiple values",
+			res: &Response{
+				Metadata: metadata.Pairs("model-name", "model-a", "model-name", "model-b"),
+			},
+			key:         "model-name",
+			want:        []string{"model-a", "model-b"},
+			wantFirst:   "model-a",
+			wantFirstOk: true,
+		},
+		{
+			name: "case-insensitive key",
+			res: &Response{
+				Metadata: metadata.Pairs("model-name", "model-a"),
+			},
+			key:         "Model-Name",
+			want:        []string{"model-a"},
+			wantFirst:   "model-a",
+			wantFirstOk: true,
+		},
+		{
+			name: "missing key",
+			res: &Response{
+				Metadata: metadata.Pairs("model-name", "model-a"),
+			},
+			key: "model-version",
+		},
+		{
+			name: "nil metadata",
+			res:  &Response{},
+			key:  "model-name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.res.MetadataValues(tt.key))
+
+			first, ok := tt.res.MetadataFirst(tt.key)
+			assert.Equal(t, tt.wantFirstOk, ok)
+			assert.Equal(t, tt.wantFirst, first)
+		})
+	}
+}
+
+func TestResponse_AppendMetadata(t *testing.T) {
+	res := &Response{}
+	res.AppendMetadata("model-name", "model-a").AppendMetadata("Model-Name", "model-b", "model-c")
+	assert.Equal(t, []string{"model-a", "model-b", "model-c"}, res.MetadataValues("model-name"))
+
+	// the returned values are a copy, so they can't modify the metadata of the response
+	values := res.MetadataValues("model-name")
+	values[0] = "modified"
+	first, ok := res.MetadataFirst("model-name")
+	assert.True(t, ok)
+	assert.Equal(t, "model-a", first)
+}
+
 func TestResponse_Status(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -100,3 +194,50 @@ func TestResponse_Payload(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorStatus(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": fiberTestUtils.NewMockComponent("route-a", httpTestUtils.DelayedResponse{
+			Response: fiber.NewErrorResponse(fiberError.FiberError{
+				Code: int(codes.Unavailable), Message: "backend is unavailable"})}),
+		"route-b": fiberTestUtils.NewMockComponent("route-b", httpTestUtils.DelayedResponse{
+			Response: fiber.NewErrorResponse(fiberError.FiberError{
+				Code: int(codes.Internal), Message: "failed to predict"})}),
+	}
+	router := fiber.NewLazyRouter("lazy-router")
+	router.SetRoutes(routes)
+	router.SetStrategy(fiberTestUtils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+
+	resp, ok := <-router.Dispatch(context.Background(), &Request{Metadata: metadata.MD{}}).Iter()
+	require.True(t, ok)
+	errResp, ok := resp.(*fiber.ErrorResponse)
+	require.True(t, ok)
+
+	st := ErrorStatus(errResp)
+	assert.Equal(t, codes.Unavailable, st.Code())
+	assert.Equal(t, "fiber: no responses received", st.Message())
+
+	details := st.Details()
+	require.Len(t, details, 2)
+	for idx, expected := range []*errdetails.ErrorInfo{
+		{
+			Reason:   "Unavailable",
+			Domain:   "route-a",
+			Metadata: map[string]string{"code": "14", "error": "backend is unavailable"},
+		},
+		{
+			Reason:   "Internal",
+			Domain:   "route-b",
+			Metadata: map[string]string{"code": "13", "error": "failed to predict"},
+		},
+	} {
+		info, ok := details[idx].(*errdetails.ErrorInfo)
+		require.True(t, ok)
+		assert.True(t, proto.Equal(expected, info), "actual error info doesn't match expected")
+	}
+
+	// the status of the error response without route errors has no details
+	st = ErrorStatus(fiber.NewErrorResponse(fiberError.ErrRequestTimeout(protocol.GRPC)).(*fiber.ErrorResponse))
+	assert.Equal(t, codes.DeadlineExceeded, st.Code())
+	assert.Empty(t, st.Details())
+}
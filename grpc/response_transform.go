@@ -0,0 +1,44 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
+)
+
+// ResponseTransform rewrites the grpc response, e.g. to unwrap the field of the message, or to inject
+// the metadata. The Message and the Metadata of the response can be replaced or modified in place, however
+// the message should not be modified in place, since it can be shared by the copies of the response
+// (e.g. the cached ones). If the error is returned, it's sent back instead of the response
+type ResponseTransform func(ctx context.Context, req fiber.Request, resp *Response) error
+
+// ResponseTransformer is a fiber.ResponseInterceptor, that rewrites the grpc responses of the backends,
+// including the frames of the streams, with the ResponseTransform. The responses of other protocols, and
+// the error responses (e.g. the non-OK statuses of the backends), are passed as they are.
+// When it's added to the interceptors of a router, only the response, chosen by the router, is transformed
+type ResponseTransformer struct {
+	fiber.NoopBeforeDispatchInterceptor
+	fiber.NoopAfterDispatchInterceptor
+	fiber.NoopAfterCompletionInterceptor
+
+	transform ResponseTransform
+}
+
+// NewResponseTransformer creates the ResponseTransformer with the given transform
+func NewResponseTransformer(transform ResponseTransform) *ResponseTransformer {
+	return &ResponseTransformer{transform: transform}
+}
+
+// InterceptResponse transforms the grpc response
+func (t *ResponseTransformer) InterceptResponse(ctx context.Context, req fiber.Request, resp fiber.Response) fiber.Response {
+	grpcResp, ok := resp.(*Response)
+	if !ok {
+		return resp
+	}
+	if err := t.transform(ctx, req, grpcResp); err != nil {
+		return fiber.NewErrorResponse(errors.NewFiberError(protocol.GRPC, err))
+	}
+	return grpcResp
+}
@@ -0,0 +1,259 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gojek/fiber"
+	fiberError "github.com/gojek/fiber/errors"
+	fiberHTTP "github.com/gojek/fiber/http"
+	"github.com/gojek/fiber/protocol"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// transportHeaders are the headers of the http request, that describe its connection or its body,
+// and are not sent to the grpc backend as the metadata
+var transportHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Host":              true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Te":                true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// TranscodingDispatcher is a Dispatcher, that lets the http clients call the unary method of the grpc backend
+// with JSON: the JSON body of the http request is unmarshalled into the request message of the method, and the
+// response message is marshalled back to JSON (see protojson), so the http response is sent back. The headers
+// of the http request are sent as the metadata. The grpc requests are dispatched as they are.
+// The response message, that can't be decoded, is handled according to the fiber.DecodeErrorPolicy
+// (see WithDecodeErrorPolicy)
+type TranscodingDispatcher struct {
+	dispatcher   fiber.Dispatcher
+	requestType  protoreflect.MessageType
+	responseType protoreflect.MessageType
+	decodeError  fiber.DecodeErrorPolicy
+}
+
+// NewTranscodingDispatcher is a factory method, that creates a TranscodingDispatcher, that transcodes
+// the http requests into the messages of the given types, dispatched by the given grpc Dispatcher
+func NewTranscodingDispatcher(
+	dispatcher fiber.Dispatcher,
+	requestType protoreflect.MessageType,
+	responseType protoreflect.MessageType,
+) (*TranscodingDispatcher, error) {
+	if dispatcher == nil {
+		return nil, errors.New("grpc transcoding: dispatcher can not be nil")
+	}
+	if requestType == nil || responseType == nil {
+		return nil, errors.New("grpc transcoding: request and response message types are required")
+	}
+	return &TranscodingDispatcher{
+		dispatcher:   dispatcher,
+		requestType:  requestType,
+		responseType: responseType,
+		decodeError:  fiber.DecodeErrorFail,
+	}, nil
+}
+
+// WithDecodeErrorPolicy sets how the response message, that can't be decoded, is handled: it's either
+// the failure of the route (fiber.DecodeErrorFail, the default), or its raw bytes are sent back as the body
+// of the http response (fiber.DecodeErrorPassThrough). fiber.DecodeErrorRespond sends back ErrDecodeFailed,
+// same as fiber.DecodeErrorFail, since the dispatcher has no other response to send back
+func (d *TranscodingDispatcher) WithDecodeErrorPolicy(policy fiber.DecodeErrorPolicy) *TranscodingDispatcher {
+	d.decodeError = policy
+	return d
+}
+
+// NewTranscodingDispatcherByName creates a TranscodingDispatcher with the message types, found by their
+// full names (e.g. `testproto.PredictValuesRequest`) in protoregistry.GlobalTypes, so the generated
+// code of the messages has to be linked into the binary
+func NewTranscodingDispatcherByName(
+	dispatcher fiber.Dispatcher,
+	requestMessage string,
+	responseMessage string,
+) (*TranscodingDispatcher, error) {
+	requestType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(requestMessage))
+	if err != nil {
+		return nil, fmt.Errorf("grpc transcoding: request message [%s]: %v", requestMessage, err)
+	}
+	responseType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(responseMessage))
+	if err != nil {
+		return nil, fmt.Errorf("grpc transcoding: response message [%s]: %v", responseMessage, err)
+	}
+	return NewTranscodingDispatcher(dispatcher, requestType, responseType)
+}
+
+// NewTranscodingDispatcherForMethod creates a TranscodingDispatcher with the dynamic types of the request and
+// the response messages of the service method (e.g. `testproto.UniversalPredictionService/PredictValues`),
+// found in the DescriptorSet, so the generated code of the messages is not needed
+func NewTranscodingDispatcherForMethod(
+	dispatcher fiber.Dispatcher,
+	descriptors *DescriptorSet,
+	serviceMethod string,
+) (*TranscodingDispatcher, error) {
+	method, err := descriptors.FindMethod(serviceMethod)
+	if err != nil {
+		return nil, fmt.Errorf("grpc transcoding: %v", err)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, fmt.Errorf("grpc transcoding: streaming method [%s] is not supported", serviceMethod)
+	}
+	return NewTranscodingDispatcher(dispatcher,
+		dynamicpb.NewMessageType(method.Input()), dynamicpb.NewMessageType(method.Output()))
+}
+
+// Do transcodes the http request into the grpc one, dispatches it, and transcodes the response back.
+// The failures of the backend are sent back with the http status codes, that correspond to their
+// grpc status codes (see HTTPStatusFromCode)
+func (d *TranscodingDispatcher) Do(ctx context.Context, req fiber.Request) fiber.Response {
+	if req.Protocol() != protocol.HTTP {
+		return d.dispatcher.Do(ctx, req)
+	}
+
+	grpcRequest, err := d.transcodeRequest(req)
+	if err != nil {
+		return fiber.NewErrorResponse(fiberError.ErrInvalidInput(protocol.HTTP, err))
+	}
+	resp := d.dispatcher.Do(ctx, grpcRequest)
+	if _, streaming := resp.(fiber.StreamingResponse); streaming {
+		return fiber.NewErrorResponse(fiberError.ErrRequestFailed(
+			protocol.HTTP, errors.New("grpc transcoding: streaming methods are not supported")))
+	}
+	if !resp.IsSuccess() {
+		code := codes.Unknown
+		message := string(resp.Payload())
+		if errResp, ok := resp.(*fiber.ErrorResponse); ok {
+			code = codes.Code(errResp.StatusCode())
+			message = errResp.Message()
+		}
+		return fiber.NewErrorResponse(&fiberError.FiberError{Code: HTTPStatusFromCode(code), Message: message})
+	}
+	return d.transcodeResponse(ctx, resp)
+}
+
+// transcodeRequest unmarshals the JSON body of the http request into the request message
+func (d *TranscodingDispatcher) transcodeRequest(req fiber.Request) (*Request, error) {
+	message := d.requestType.New().Interface()
+	if payload := req.Payload(); len(bytes.TrimSpace(payload)) > 0 {
+		if err := protojson.Unmarshal(payload, message); err != nil {
+			return nil, fmt.Errorf("grpc transcoding: invalid JSON of [%s]: %v", d.requestType.Descriptor().FullName(), err)
+		}
+	}
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	md := metadata.MD{}
+	for key, values := range req.Header() {
+		if !transportHeaders[http.CanonicalHeaderKey(key)] {
+			md.Append(strings.ToLower(key), values...)
+		}
+	}
+	return NewRequest(md, payload, message), nil
+}
+
+// transcodeResponse marshals the response message into the JSON body of the http response
+func (d *TranscodingDispatcher) transcodeResponse(ctx context.Context, resp fiber.Response) fiber.Response {
+	message := d.responseType.New().Interface()
+	if err := proto.Unmarshal(resp.Payload(), message); err != nil {
+		err = fmt.Errorf("grpc transcoding: invalid response message [%s]: %v", d.responseType.Descriptor().FullName(), err)
+		fiber.LogDecodeError(ctx, resp, err)
+		if d.decodeError == fiber.DecodeErrorPassThrough {
+			return fiberHTTP.NewHTTPResponse(&http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/x-protobuf"}},
+				Body:       ioutil.NopCloser(bytes.NewReader(resp.Payload())),
+			})
+		}
+		return fiber.NewErrorResponse(fiberError.ErrDecodeFailed(protocol.HTTP, err))
+	}
+	body, err := protojson.Marshal(message)
+	if err != nil {
+		return fiber.NewErrorResponse(fiberError.ErrRequestFailed(protocol.HTTP, err))
+	}
+	return fiberHTTP.NewHTTPResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	})
+}
+
+// Describe adds the details of the underlying dispatcher to the info, and the http protocol,
+// that the dispatcher serves
+func (d *TranscodingDispatcher) Describe(info *fiber.ComponentInfo) {
+	if describer, ok := d.dispatcher.(fiber.Describer); ok {
+		describer.Describe(info)
+	}
+	info.Protocol = protocol.HTTP
+}
+
+// Saturated returns true, if the underlying dispatcher is saturated, e.g. it's rate-limited
+// (see fiber.SaturationReporter)
+func (d *TranscodingDispatcher) Saturated() bool {
+	reporter, ok := d.dispatcher.(fiber.SaturationReporter)
+	return ok && reporter.Saturated()
+}
+
+// Close closes the dispatcher, that the transcoded grpc requests are sent with (see fiber.Closer)
+func (d *TranscodingDispatcher) Close(ctx context.Context) error {
+	if closer, ok := d.dispatcher.(fiber.Closer); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+// WarmUp establishes the connection of the underlying dispatcher to its backend, if it's a fiber.Warmer
+func (d *TranscodingDispatcher) WarmUp(ctx context.Context) error {
+	if warmer, ok := d.dispatcher.(fiber.Warmer); ok {
+		return warmer.WarmUp(ctx)
+	}
+	return nil
+}
+
+// HTTPStatusFromCode returns the http status code, that corresponds to the grpc status code
+// (see https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto)
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		// the client has closed the request
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gojek/fiber"
+	fiberError "github.com/gojek/fiber/errors"
+	testproto "github.com/gojek/fiber/internal/testdata/gen/testdata/proto"
+	httpTestUtils "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+type dispatcherFunc func(ctx context.Context, req fiber.Request) fiber.Response
+
+func (f dispatcherFunc) Do(ctx context.Context, req fiber.Request) fiber.Response {
+	return f(ctx, req)
+}
+
+func TestTranscodingDispatcher_Do(t *testing.T) {
+	backendResponse := &testproto.PredictValuesResponse{
+		Metadata: &testproto.ResponseMetadata{PredictionId: "abc"},
+	}
+	message, err := proto.Marshal(backendResponse)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		body           string
+		backend        fiber.Response
+		decodeError    fiber.DecodeErrorPolicy
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "ok",
+			body:           `{"predictionRows": [{"rowId": "1"}]}`,
+			backend:        &Response{Message: message},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"metadata": {"predictionId": "abc"}}`,
+		},
+		{
+			name: "backend failed",
+			body: `{}`,
+			backend: fiber.NewErrorResponse(
+				fiberError.FiberError{Code: int(codes.Unavailable), Message: "backend is unavailable"}),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "invalid json",
+			body:           `[1, 2]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid response message",
+			body:           `{}`,
+			backend:        &Response{Message: []byte("invalid")},
+			expectedStatus: http.StatusBadGateway,
+		},
+		{
+			name:           "invalid response message passed through",
+			body:           `{}`,
+			backend:        &Response{Message: []byte("invalid")},
+			decodeError:    fiber.DecodeErrorPassThrough,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received *Request
+			backend := dispatcherFunc(func(_ context.Context, req fiber.Request) fiber.Response {
+				received = req.(*Request)
+				return tt.backend
+			})
+			dispatcher, err := NewTranscodingDispatcherByName(
+				backend, "testproto.PredictValuesRequest", "testproto.PredictValuesResponse")
+			require.NoError(t, err)
+			if tt.decodeError != "" {
+				dispatcher.WithDecodeErrorPolicy(tt.decodeError)
+			}
+
+			req := httpTestUtils.MockReq(http.MethodPost, "http://localhost:8080/predict", tt.body)
+			req.Header()["X-Tenant-Id"] = []string{"tenant"}
+			resp := dispatcher.Do(context.Background(), req)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode())
+			if tt.backend == nil {
+				assert.Nil(t, received)
+				return
+			}
+			assert.Equal(t, []string{"tenant"}, received.Metadata.Get("x-tenant-id"))
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, string(resp.Payload()))
+			}
+			if tt.decodeError == fiber.DecodeErrorPassThrough {
+				assert.Equal(t, tt.backend.Payload(), resp.Payload())
+			}
+		})
+	}
+}
+
+func TestNewTranscodingDispatcherByName_UnknownMessage(t *testing.T) {
+	_, err := NewTranscodingDispatcherByName(
+		dispatcherFunc(nil), "testproto.UnknownRequest", "testproto.PredictValuesResponse")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "grpc transcoding: request message [testproto.UnknownRequest]")
+}
@@ -0,0 +1,29 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gojek/fiber"
+	fiberError "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ValidateMessage returns the fiber.PreRoutingHook, that rejects the grpc requests, which message can't be decoded
+// into the message of the given type, with errors.ErrInvalidInput, before the routing strategy selects the routes.
+// The requests of the other protocols are passed as they are
+func ValidateMessage(messageType protoreflect.MessageType) fiber.PreRoutingHook {
+	return func(ctx context.Context, req fiber.Request) (context.Context, error) {
+		grpcReq, ok := req.(*Request)
+		if !ok {
+			return ctx, nil
+		}
+		if err := proto.Unmarshal(grpcReq.Payload(), messageType.New().Interface()); err != nil {
+			return ctx, fiberError.ErrInvalidInput(protocol.GRPC,
+				fmt.Errorf("invalid message [%s]: %v", messageType.Descriptor().FullName(), err))
+		}
+		return ctx, nil
+	}
+}
@@ -0,0 +1,72 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	fiberError "github.com/gojek/fiber/errors"
+	fiberHTTP "github.com/gojek/fiber/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestValidateMessage(t *testing.T) {
+	descriptors, err := LoadDescriptorSet(descriptorSetPath)
+	require.NoError(t, err)
+	requestType, err := descriptors.FindMessageType("testproto.PredictValuesRequest")
+	require.NoError(t, err)
+
+	valid := appendMessage(nil, 1, appendString(nil, 1, "row-1"))
+
+	tests := []struct {
+		name    string
+		message []byte
+		invalid bool
+	}{
+		{
+			name:    "valid message",
+			message: valid,
+		},
+		{
+			name: "empty message",
+		},
+		{
+			name:    "truncated message",
+			message: valid[:len(valid)-2],
+			invalid: true,
+		},
+		{
+			name:    "malformed message",
+			message: []byte{0xff, 0xff, 0xff},
+			invalid: true,
+		},
+	}
+
+	hook := ValidateMessage(requestType)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := hook(context.Background(), &Request{Message: tt.message})
+			if !tt.invalid {
+				assert.NoError(t, err)
+				return
+			}
+			var fiberErr *fiberError.FiberError
+			require.ErrorAs(t, err, &fiberErr)
+			assert.Equal(t, int(codes.InvalidArgument), fiberErr.Code)
+			assert.Contains(t, fiberErr.Message, "invalid message [testproto.PredictValuesRequest]")
+		})
+	}
+
+	t.Run("other protocol", func(t *testing.T) {
+		httpReq, err := http.NewRequest(http.MethodPost, "/", strings.NewReader("invalid"))
+		require.NoError(t, err)
+		req, err := fiberHTTP.NewHTTPRequest(httpReq)
+		require.NoError(t, err)
+
+		_, err = hook(context.Background(), req)
+		assert.NoError(t, err)
+	})
+}
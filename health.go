@@ -0,0 +1,236 @@
+package fiber
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Defaults of the HealthCheckPolicy
+const (
+	DefaultHealthCheckInterval = 10 * time.Second
+	DefaultHealthCheckTimeout  = time.Second
+	DefaultHealthyThreshold    = 2
+	DefaultUnhealthyThreshold  = 3
+)
+
+// HealthProbe checks the health of a backend, i.e. with an http request to its health endpoint,
+// or with the grpc health-check RPC. The backend is healthy, if no error is returned
+type HealthProbe interface {
+	Probe(ctx context.Context) error
+}
+
+// HealthProbeFunc is an adapter to use the function as a HealthProbe
+type HealthProbeFunc func(ctx context.Context) error
+
+// Probe calls the function
+func (f HealthProbeFunc) Probe(ctx context.Context) error {
+	return f(ctx)
+}
+
+// HealthStateObserver is notified about the changes of the health of the routes. The dispatch metrics, that implement
+// this interface, get the health of the routes, initialized from the config
+type HealthStateObserver interface {
+	RecordRouteHealth(routeID string, healthy bool)
+}
+
+// HealthReporter is implemented by the routes, that know if they are healthy (see HealthCheckedComponent).
+// Routers don't select the unhealthy routes, unless all of their routes are unhealthy
+type HealthReporter interface {
+	Healthy() bool
+}
+
+// HealthCheckPolicy defines how often the backend is probed, and how many probes in a row have
+// to fail or succeed to change its health. The zero values are replaced with the defaults
+type HealthCheckPolicy struct {
+	// Interval is the time between the probes
+	Interval time.Duration
+	// Timeout is the timeout of each probe
+	Timeout time.Duration
+	// HealthyThreshold is the number of consecutive successful probes, after which the unhealthy
+	// backend becomes healthy again
+	HealthyThreshold int
+	// UnhealthyThreshold is the number of consecutive failed probes, after which the backend
+	// becomes unhealthy
+	UnhealthyThreshold int
+}
+
+// HealthChecker probes the backend of a route in the background, once it's started, and tracks
+// its health according to the HealthCheckPolicy. The backend is considered healthy, until it
+// fails UnhealthyThreshold probes in a row
+type HealthChecker struct {
+	routeID  string
+	probe    HealthProbe
+	policy   HealthCheckPolicy
+	observer HealthStateObserver
+
+	lock      sync.Mutex
+	healthy   bool
+	successes int
+	failures  int
+
+	start sync.Once
+	stop  sync.Once
+	done  chan struct{}
+}
+
+// NewHealthChecker is a factory method, that creates a HealthChecker of the backend of the given route
+func NewHealthChecker(routeID string, probe HealthProbe, policy HealthCheckPolicy) (*HealthChecker, error) {
+	if probe == nil {
+		return nil, errors.New("health check: probe can not be nil")
+	}
+	if policy.Interval < 0 || policy.Timeout < 0 || policy.HealthyThreshold < 0 || policy.UnhealthyThreshold < 0 {
+		return nil, errors.New("health check: interval, timeout and thresholds can not be negative")
+	}
+
+	if policy.Interval == 0 {
+		policy.Interval = DefaultHealthCheckInterval
+	}
+	if policy.Timeout == 0 {
+		policy.Timeout = DefaultHealthCheckTimeout
+	}
+	if policy.HealthyThreshold == 0 {
+		policy.HealthyThreshold = DefaultHealthyThreshold
+	}
+	if policy.UnhealthyThreshold == 0 {
+		policy.UnhealthyThreshold = DefaultUnhealthyThreshold
+	}
+
+	return &HealthChecker{
+		routeID: routeID,
+		probe:   probe,
+		policy:  policy,
+		healthy: true,
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// WithObserver sets the observer, that is notified on each change of the health of the backend
+func (h *HealthChecker) WithObserver(observer HealthStateObserver) *HealthChecker {
+	h.observer = observer
+	if observer != nil {
+		observer.RecordRouteHealth(h.routeID, h.Healthy())
+	}
+	return h
+}
+
+// Start starts probing the backend every Interval, until the checker is closed
+func (h *HealthChecker) Start() *HealthChecker {
+	h.start.Do(func() {
+		go func() {
+			ticker := time.NewTicker(h.policy.Interval)
+			defer ticker.Stop()
+			for {
+				h.check()
+				select {
+				case <-ticker.C:
+				case <-h.done:
+					return
+				}
+			}
+		}()
+	})
+	return h
+}
+
+// Healthy returns true, if the backend is healthy
+func (h *HealthChecker) Healthy() bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.healthy
+}
+
+// Close stops probing the backend
+func (h *HealthChecker) Close(context.Context) error {
+	h.stop.Do(func() {
+		close(h.done)
+	})
+	return nil
+}
+
+// check probes the backend and updates its health
+func (h *HealthChecker) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), h.policy.Timeout)
+	err := h.probe.Probe(ctx)
+	cancel()
+
+	h.lock.Lock()
+	changed := false
+	if err == nil {
+		h.successes, h.failures = h.successes+1, 0
+		if !h.healthy && h.successes >= h.policy.HealthyThreshold {
+			h.healthy, changed = true, true
+		}
+	} else {
+		h.successes, h.failures = 0, h.failures+1
+		if h.healthy && h.failures >= h.policy.UnhealthyThreshold {
+			h.healthy, changed = false, true
+		}
+	}
+	healthy := h.healthy
+	h.lock.Unlock()
+
+	if changed && h.observer != nil {
+		h.observer.RecordRouteHealth(h.routeID, healthy)
+	}
+}
+
+// HealthCheckedComponent is a Component, that reports the health of its backend, tracked by
+// the HealthChecker, so the routers can skip it, while it's unhealthy
+type HealthCheckedComponent struct {
+	Component
+	checker *HealthChecker
+}
+
+// NewHealthCheckedComponent is a factory method, that creates a HealthCheckedComponent, which health is
+// tracked by the given checker. The checker has to be started separately
+func NewHealthCheckedComponent(component Component, checker *HealthChecker) *HealthCheckedComponent {
+	return &HealthCheckedComponent{
+		Component: component,
+		checker:   checker,
+	}
+}
+
+// Healthy returns true, if the backend of the component is healthy
+func (c *HealthCheckedComponent) Healthy() bool {
+	return c.checker.Healthy()
+}
+
+// Saturated returns true, if the component is saturated (see SaturationReporter)
+func (c *HealthCheckedComponent) Saturated() bool {
+	return saturatedIfReporter(c.Component)
+}
+
+// Describe adds the health of the backend and the details of the component to the info
+func (c *HealthCheckedComponent) Describe(info *ComponentInfo) {
+	healthy := c.Healthy()
+	info.Healthy = &healthy
+	describeIfDescriber(c.Component, info)
+}
+
+// Close stops the health checker, and closes the component, if it holds any resources (see Closer)
+func (c *HealthCheckedComponent) Close(ctx context.Context) error {
+	_ = c.checker.Close(ctx)
+	return closeIfCloser(ctx, c.Component)
+}
+
+// WarmUp warms up the component, if it's a Warmer
+func (c *HealthCheckedComponent) WarmUp(ctx context.Context) error {
+	return warmUpIfWarmer(ctx, c.Component)
+}
+
+// healthyRoutes returns the routes, that are not unhealthy, or all the routes, if none of them is healthy,
+// so the request is still dispatched
+func healthyRoutes(routes map[string]Component) map[string]Component {
+	healthy := make(map[string]Component, len(routes))
+	for id, route := range routes {
+		if reporter, ok := route.(HealthReporter); !ok || reporter.Healthy() {
+			healthy[id] = route
+		}
+	}
+	if len(healthy) == 0 || len(healthy) == len(routes) {
+		return routes
+	}
+	return healthy
+}
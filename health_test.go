@@ -0,0 +1,211 @@
+package fiber_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// switchableProbe fails, while the backend is down
+type switchableProbe struct {
+	down   int32
+	probes int32
+}
+
+func (p *switchableProbe) Probe(context.Context) error {
+	atomic.AddInt32(&p.probes, 1)
+	if atomic.LoadInt32(&p.down) == 1 {
+		return errors.New("backend is down")
+	}
+	return nil
+}
+
+func (p *switchableProbe) setDown(down bool) {
+	value := int32(0)
+	if down {
+		value = 1
+	}
+	atomic.StoreInt32(&p.down, value)
+}
+
+// orderedRoutingStrategy selects the routes, that are passed to it, in the given order
+type orderedRoutingStrategy struct {
+	fiber.BaseFiberType
+	order []string
+}
+
+func (s *orderedRoutingStrategy) SelectRoute(
+	_ context.Context,
+	_ fiber.Request,
+	routes map[string]fiber.Component,
+) (fiber.Component, []fiber.Component, error) {
+	var selected []fiber.Component
+	for _, id := range s.order {
+		if route, ok := routes[id]; ok {
+			selected = append(selected, route)
+		}
+	}
+	return selected[0], selected[1:], nil
+}
+
+// recordingHealthObserver records the changes of the health of the routes
+type recordingHealthObserver struct {
+	lock    sync.Mutex
+	records []bool
+}
+
+func (o *recordingHealthObserver) RecordRouteHealth(_ string, healthy bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.records = append(o.records, healthy)
+}
+
+func (o *recordingHealthObserver) recorded() []bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return append([]bool(nil), o.records...)
+}
+
+func TestNewHealthChecker(t *testing.T) {
+	suite := map[string]struct {
+		probe       fiber.HealthProbe
+		policy      fiber.HealthCheckPolicy
+		expectedErr string
+	}{
+		"ok: defaults": {
+			probe: &switchableProbe{},
+		},
+		"error: nil probe": {
+			expectedErr: "health check: probe can not be nil",
+		},
+		"error: negative interval": {
+			probe:       &switchableProbe{},
+			policy:      fiber.HealthCheckPolicy{Interval: -time.Second},
+			expectedErr: "health check: interval, timeout and thresholds can not be negative",
+		},
+		"error: negative threshold": {
+			probe:       &switchableProbe{},
+			policy:      fiber.HealthCheckPolicy{UnhealthyThreshold: -1},
+			expectedErr: "health check: interval, timeout and thresholds can not be negative",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			checker, err := fiber.NewHealthChecker("route-a", tt.probe, tt.policy)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+				assert.True(t, checker.Healthy())
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestHealthChecker_Thresholds(t *testing.T) {
+	probe := &switchableProbe{}
+	observer := &recordingHealthObserver{}
+	checker, err := fiber.NewHealthChecker("route-a", probe, fiber.HealthCheckPolicy{
+		Interval:           5 * time.Millisecond,
+		HealthyThreshold:   2,
+		UnhealthyThreshold: 3,
+	})
+	require.NoError(t, err)
+	checker.WithObserver(observer).Start()
+	defer checker.Close(context.Background())
+
+	// the backend becomes unhealthy after 3 failed probes in a row
+	probe.setDown(true)
+	probes := atomic.LoadInt32(&probe.probes)
+	require.Eventually(t, func() bool { return !checker.Healthy() }, time.Second, time.Millisecond)
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&probe.probes)-probes, int32(3))
+
+	// and healthy again after 2 successful ones
+	probe.setDown(false)
+	probes = atomic.LoadInt32(&probe.probes)
+	require.Eventually(t, checker.Healthy, time.Second, time.Millisecond)
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&probe.probes)-probes, int32(2))
+
+	assert.Equal(t, []bool{true, false, true}, observer.recorded())
+
+	// no probes are sent after the checker is closed
+	require.NoError(t, checker.Close(context.Background()))
+	time.Sleep(10 * time.Millisecond)
+	probes = atomic.LoadInt32(&probe.probes)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, probes, atomic.LoadInt32(&probe.probes))
+}
+
+func TestRouter_SkipsUnhealthyRoutes(t *testing.T) {
+	probes := map[string]*switchableProbe{"route-a": {}, "route-b": {}}
+	routes := make(map[string]fiber.Component)
+	for id, probe := range probes {
+		checker, err := fiber.NewHealthChecker(id, probe, fiber.HealthCheckPolicy{
+			Interval:           time.Millisecond,
+			HealthyThreshold:   1,
+			UnhealthyThreshold: 1,
+		})
+		require.NoError(t, err)
+		defer checker.Close(context.Background())
+		// the responses are not shared by the dispatches, since the eager router doesn't wait for all of them
+		routes[id] = fiber.NewHealthCheckedComponent(newSlowComponent(id, 0), checker.Start())
+	}
+
+	for name, router := range map[string]fiber.Router{
+		"lazy router":  fiber.NewLazyRouter("lazy-router"),
+		"eager router": fiber.NewEagerRouter("eager-router"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			router.SetRoutes(routes)
+			router.SetStrategy(&orderedRoutingStrategy{order: []string{"route-a", "route-b"}})
+			dispatch := func() string {
+				resp := <-router.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+				require.True(t, resp.IsSuccess())
+				return string(resp.Payload())
+			}
+
+			// the unhealthy primary route is skipped
+			probes["route-a"].setDown(true)
+			require.Eventually(t, func() bool { return !routes["route-a"].(fiber.HealthReporter).Healthy() },
+				time.Second, time.Millisecond)
+			assert.Equal(t, "route-b", dispatch())
+
+			// all the routes are unhealthy, so the request is still dispatched
+			probes["route-b"].setDown(true)
+			require.Eventually(t, func() bool { return !routes["route-b"].(fiber.HealthReporter).Healthy() },
+				time.Second, time.Millisecond)
+			assert.Equal(t, "route-a", dispatch())
+
+			// the recovered route is selected again
+			probes["route-a"].setDown(false)
+			probes["route-b"].setDown(false)
+			require.Eventually(t, func() bool { return routes["route-a"].(fiber.HealthReporter).Healthy() },
+				time.Second, time.Millisecond)
+			assert.Equal(t, "route-a", dispatch())
+		})
+	}
+}
+
+func TestHealthCheckedComponent_Close(t *testing.T) {
+	probe := &switchableProbe{}
+	checker, err := fiber.NewHealthChecker("route-a", probe, fiber.HealthCheckPolicy{Interval: time.Millisecond})
+	require.NoError(t, err)
+	route := newSlowComponent("route-a", 0)
+	component := fiber.NewHealthCheckedComponent(route, checker.Start())
+
+	require.NoError(t, component.Close(context.Background()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&route.closed))
+	time.Sleep(10 * time.Millisecond)
+	probes := atomic.LoadInt32(&probe.probes)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, probes, atomic.LoadInt32(&probe.probes))
+}
@@ -0,0 +1,248 @@
+package fiber
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gojek/fiber/errors"
+)
+
+const (
+	// DefaultMaxHedges is the maximum number of the hedged requests per request, if it's not configured
+	DefaultMaxHedges = 1
+
+	// hedgingWindow is the number of the latest latencies of the router, that the hedge delay is derived from
+	hedgingWindow = 100
+	// hedgingMinSamples is the number of the latencies, that the router observes, before it derives the hedge delay
+	hedgingMinSamples = 10
+)
+
+// HedgingPolicy defines, when the LazyRouter sends the hedged requests. Instead of waiting for the primary route
+// to fail, the router sends the duplicate of the request to the next route, if the primary one hasn't responded
+// within the hedge delay, and takes the response, that comes first. The routes, that are still in flight,
+// are cancelled, once the response is taken
+type HedgingPolicy struct {
+	// Delay is the time, that the router waits for the response of the route, before it sends the hedged request
+	// to the next route. If Percentile is set, it's the delay, until the router has observed enough latencies
+	Delay time.Duration
+	// Percentile, if set, derives the hedge delay from the latencies of the latest successful responses
+	// of the router, e.g. 95 hedges the requests, that are slower than p95. It must be in the (0, 100) range
+	Percentile float64
+	// MaxHedges is the maximum number of the hedged requests per request. Defaults to DefaultMaxHedges
+	MaxHedges int
+}
+
+// hedging is the HedgingPolicy of the LazyRouter with the window of the latencies of its successful responses
+type hedging struct {
+	policy HedgingPolicy
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+func newHedging(policy HedgingPolicy) (*hedging, error) {
+	if policy.Delay <= 0 {
+		return nil, fmt.Errorf("hedging: delay must be positive: [%s]", policy.Delay)
+	}
+	if policy.Percentile < 0 || policy.Percentile >= 100 {
+		return nil, fmt.Errorf("hedging: percentile must be in (0, 100) range: [%v]", policy.Percentile)
+	}
+	if policy.MaxHedges < 0 {
+		return nil, fmt.Errorf("hedging: max_hedges can not be negative: [%d]", policy.MaxHedges)
+	}
+	if policy.MaxHedges == 0 {
+		policy.MaxHedges = DefaultMaxHedges
+	}
+	return &hedging{policy: policy}, nil
+}
+
+// delay returns the hedge delay: the percentile of the observed latencies, if it's configured, and the router
+// has observed enough of them, or the configured delay otherwise
+func (h *hedging) delay() time.Duration {
+	if h.policy.Percentile == 0 {
+		return h.policy.Delay
+	}
+
+	h.mu.Lock()
+	if len(h.latencies) < hedgingMinSamples {
+		h.mu.Unlock()
+		return h.policy.Delay
+	}
+	latencies := make([]time.Duration, len(h.latencies))
+	copy(latencies, h.latencies)
+	h.mu.Unlock()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	idx := int(math.Ceil(h.policy.Percentile/100*float64(len(latencies)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return latencies[idx]
+}
+
+// observe records the latency of the successful response of the router
+func (h *hedging) observe(latency time.Duration) {
+	if h.policy.Percentile == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgingWindow {
+		h.latencies = append(h.latencies, latency)
+	} else {
+		h.latencies[h.next] = latency
+		h.next = (h.next + 1) % hedgingWindow
+	}
+}
+
+// hedgedAttempt is the outcome of the dispatch of the request by a route in the hedging mode
+type hedgedAttempt struct {
+	route     Component
+	responses []Response
+	last      Response
+	failed    bool
+	latency   time.Duration
+	// rest is the queue of the following frames of the stream, that the route has started to respond with
+	rest <-chan Response
+}
+
+// attempt dispatches the request by the route and reports its responses, once the route has responded
+// with all of them, has failed, or has sent the first successful frame of a stream
+func (r *LazyRouter) attempt(
+	ctx context.Context,
+	log *dispatchLogger,
+	route Component,
+	req Request,
+	results chan<- hedgedAttempt,
+) {
+	copyReq, _ := req.Clone()
+	log.tag(copyReq)
+	log.log(ctx, RouteAttemptStartedEvent, route.ID(), nil, 0)
+	start := time.Now()
+
+	result := hedgedAttempt{route: route}
+	responseCh := safeDispatch(ctx, log, route, copyReq).Iter()
+	for resp := range responseCh {
+		result.last = resp.WithBackendName(route.ID())
+		if r.failures.isFailure(req.Protocol(), resp) {
+			result.failed = true
+			break
+		}
+		result.responses = append(result.responses, result.last)
+		if isStreamFrame(resp) {
+			result.rest = responseCh
+			break
+		}
+	}
+	result.latency = time.Since(start)
+	results <- result
+}
+
+// dispatchHedged dispatches the request by the primary route and sends the hedged requests to the next routes,
+// while none of the routes in flight has responded within the hedge delay, up to the MaxHedges of the policy.
+// If a route fails, and there are no other routes in flight, the router falls back to the next route at once.
+// The first successful response is sent back, and the rest of the routes are cancelled
+func (r *LazyRouter) dispatchHedged(
+	ctx context.Context,
+	log *dispatchLogger,
+	req Request,
+	routes []Component,
+	out chan<- Response,
+) {
+	results := make(chan hedgedAttempt, len(routes))
+	cancels := make(map[string]context.CancelFunc, len(routes))
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	var (
+		next, inFlight, hedges int
+		timer                  *time.Timer
+	)
+	dispatch := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels[routes[next].ID()] = cancel
+		go r.attempt(attemptCtx, log, routes[next], req, results)
+		next, inFlight = next+1, inFlight+1
+
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.NewTimer(r.hedging.delay())
+	}
+	dispatch()
+	defer func() { timer.Stop() }()
+
+	routeErrors := make([]errors.RouteError, 0, len(routes))
+	for inFlight > 0 {
+		var hedge <-chan time.Time
+		if next < len(routes) && hedges < r.hedging.policy.MaxHedges {
+			hedge = timer.C
+		}
+
+		select {
+		case result := <-results:
+			inFlight--
+			log.log(ctx, RouteAttemptFinishedEvent, result.route.ID(), result.last, result.latency)
+			if ctx.Err() != nil {
+				// the route has given up, since the request has timed out
+				r.respondTimeout(ctx, log, req, out)
+				return
+			}
+			if !result.failed {
+				// the first successful response is taken, and the routes, that are still in flight, are cancelled
+				for id, cancel := range cancels {
+					if id != result.route.ID() {
+						cancel()
+					}
+				}
+				r.hedging.observe(result.latency)
+				if result.last != nil {
+					log.logResponse(ctx, result.last)
+				}
+				for _, resp := range result.responses {
+					out <- resp
+				}
+				if result.rest != nil {
+					// the router is committed to the streaming route, so its frames are sent back as they arrive
+					for resp := range result.rest {
+						out <- resp.WithBackendName(result.route.ID())
+					}
+				}
+				return
+			}
+			routeErrors = append(routeErrors, newRouteError(req.Protocol(), result.route.ID(), result.last))
+			if inFlight == 0 && next < len(routes) {
+				log.log(ctx, FallbackTriggeredEvent, routes[next].ID(), nil, 0)
+				dispatch()
+			}
+		case <-hedge:
+			hedges++
+			log.log(ctx, HedgeTriggeredEvent, routes[next].ID(), nil, 0)
+			dispatch()
+		case <-ctx.Done():
+			r.respondTimeout(ctx, log, req, out)
+			return
+		}
+	}
+
+	// all the routes have failed
+	resp := errAllRoutesFailed(req.Protocol(), routeErrors)
+	log.logResponse(ctx, resp)
+	out <- resp
+}
+
+// respondTimeout sends back the timeout response of the router, once the request has timed out
+// (see SetTimeoutResponse)
+func (r *LazyRouter) respondTimeout(ctx context.Context, log *dispatchLogger, req Request, out chan<- Response) {
+	resp := r.timeout.response(req.Protocol())
+	log.logResponse(ctx, resp)
+	out <- resp
+}
@@ -0,0 +1,207 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHedgedRouter(t *testing.T, policy fiber.HedgingPolicy, routes ...fiber.Component) *fiber.LazyRouter {
+	routesMap := make(map[string]fiber.Component, len(routes))
+	order := make([]string, 0, len(routes))
+	for _, route := range routes {
+		routesMap[route.ID()] = route
+		order = append(order, route.ID())
+	}
+	router := fiber.NewLazyRouter("hedged-router")
+	router.SetRoutes(routesMap)
+	router.SetStrategy(testutils.NewMockRoutingStrategy(routesMap, order, 0, nil))
+	require.NoError(t, router.SetHedgingPolicy(&policy))
+	return router
+}
+
+func TestLazyRouter_Hedging(t *testing.T) {
+	t.Run("hedge wins and the primary is cancelled", func(t *testing.T) {
+		primary := newCancellableComponent("route-a", time.Second)
+		logger := &recordingLogger{}
+		router := newHedgedRouter(t, fiber.HedgingPolicy{Delay: 20 * time.Millisecond}, primary,
+			testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+				Response: testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil),
+			}))
+		router.SetLogger(logger)
+
+		start := time.Now()
+		responses := testutils.Dispatch(context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+		require.Len(t, responses, 1)
+		assert.Equal(t, "B-OK", string(responses[0].Payload()))
+		assert.Equal(t, "route-b", responses[0].BackendName())
+		assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+
+		select {
+		case <-primary.cancelled:
+		case <-time.After(time.Second):
+			assert.Fail(t, "the primary route is not cancelled")
+		}
+
+		var hedged []string
+		for _, entry := range logger.Entries() {
+			if entry.Event == fiber.HedgeTriggeredEvent {
+				hedged = append(hedged, entry.RouteID)
+			}
+		}
+		assert.Equal(t, []string{"route-b"}, hedged)
+	})
+
+	t.Run("primary responds within the delay", func(t *testing.T) {
+		secondary := newCancellableComponent("route-b", 0)
+		router := newHedgedRouter(t, fiber.HedgingPolicy{Delay: 200 * time.Millisecond},
+			testutils.NewMockComponent("route-a", testUtilsHttp.DelayedResponse{
+				Latency:  10 * time.Millisecond,
+				Response: testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil),
+			}), secondary)
+
+		responses := testutils.Dispatch(context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+		require.Len(t, responses, 1)
+		assert.Equal(t, "A-OK", string(responses[0].Payload()))
+	})
+
+	t.Run("failed primary falls back without the delay", func(t *testing.T) {
+		router := newHedgedRouter(t, fiber.HedgingPolicy{Delay: time.Second},
+			testutils.NewMockComponent("route-a", testUtilsHttp.DelayedResponse{
+				Response: testUtilsHttp.MockResp(http.StatusServiceUnavailable, "", nil,
+					fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+			}),
+			testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+				Response: testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil),
+			}))
+
+		start := time.Now()
+		responses := testutils.Dispatch(context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+		require.Len(t, responses, 1)
+		assert.Equal(t, "B-OK", string(responses[0].Payload()))
+		assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+	})
+
+	t.Run("hedges are limited", func(t *testing.T) {
+		routeC := newCancellableComponent("route-c", 0)
+		router := newHedgedRouter(t, fiber.HedgingPolicy{Delay: 10 * time.Millisecond, MaxHedges: 1},
+			newCancellableComponent("route-a", time.Second),
+			newCancellableComponent("route-b", time.Second),
+			routeC)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		responses := testutils.Dispatch(ctx, router, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+		require.Len(t, responses, 1)
+		assert.Equal(t, http.StatusRequestTimeout, responses[0].StatusCode())
+
+		select {
+		case <-routeC.cancelled:
+			assert.Fail(t, "the request is hedged more than once")
+		default:
+		}
+	})
+
+	t.Run("all routes failed", func(t *testing.T) {
+		router := newHedgedRouter(t, fiber.HedgingPolicy{Delay: 10 * time.Millisecond},
+			testutils.NewMockComponent("route-a", testUtilsHttp.DelayedResponse{
+				Latency: 30 * time.Millisecond,
+				Response: testUtilsHttp.MockResp(http.StatusServiceUnavailable, "", nil,
+					fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+			}),
+			testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+				Response: testUtilsHttp.MockResp(http.StatusBadGateway, "", nil,
+					fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+			}))
+
+		responses := testutils.Dispatch(context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+		require.Len(t, responses, 1)
+		assert.Equal(t, http.StatusServiceUnavailable, responses[0].StatusCode())
+		errResp, ok := responses[0].(*fiber.ErrorResponse)
+		require.True(t, ok)
+		var routes []string
+		for _, routeErr := range errResp.RouteErrors() {
+			routes = append(routes, routeErr.RouteID)
+		}
+		assert.Equal(t, []string{"route-b", "route-a"}, routes)
+	})
+
+	t.Run("delay is derived from the observed latencies", func(t *testing.T) {
+		// the primary route responds fast to the first requests, and then slows down
+		var dispatches int32
+		routeA, err := fiber.NewCaller("route-a", fiber.DispatcherFunc(func(ctx context.Context, _ fiber.Request) fiber.Response {
+			latency := 5 * time.Millisecond
+			if atomic.AddInt32(&dispatches, 1) > 10 {
+				latency = time.Second
+			}
+			select {
+			case <-time.After(latency):
+			case <-ctx.Done():
+			}
+			return testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil)
+		}))
+		require.NoError(t, err)
+		router := newHedgedRouter(t, fiber.HedgingPolicy{Delay: time.Second, Percentile: 90},
+			routeA,
+			testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+				Response: testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil),
+			}))
+		for i := 0; i < 10; i++ {
+			responses := testutils.Dispatch(context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+			require.Len(t, responses, 1)
+			require.Equal(t, "A-OK", string(responses[0].Payload()))
+		}
+
+		// the request is hedged long before the configured delay
+		start := time.Now()
+		responses := testutils.Dispatch(context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+		require.Len(t, responses, 1)
+		assert.Equal(t, "B-OK", string(responses[0].Payload()))
+		assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+	})
+}
+
+func TestLazyRouter_SetHedgingPolicy(t *testing.T) {
+	suite := map[string]struct {
+		policy   *fiber.HedgingPolicy
+		expected string
+	}{
+		"disabled": {},
+		"valid": {
+			policy: &fiber.HedgingPolicy{Delay: time.Millisecond, Percentile: 95, MaxHedges: 2},
+		},
+		"no delay": {
+			policy:   &fiber.HedgingPolicy{Percentile: 95},
+			expected: "hedging: delay must be positive: [0s]",
+		},
+		"invalid percentile": {
+			policy:   &fiber.HedgingPolicy{Delay: time.Millisecond, Percentile: 100},
+			expected: "hedging: percentile must be in (0, 100) range: [100]",
+		},
+		"negative max hedges": {
+			policy:   &fiber.HedgingPolicy{Delay: time.Millisecond, MaxHedges: -1},
+			expected: "hedging: max_hedges can not be negative: [-1]",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			err := fiber.NewLazyRouter("router").SetHedgingPolicy(tt.policy)
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
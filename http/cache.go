@@ -0,0 +1,63 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gojek/fiber"
+)
+
+// CacheKey returns the fiber.CacheKeyFunc, that keys the http requests on their method, path and query,
+// the values of the given headers and the body. Only GET and HEAD requests are cached
+func CacheKey(headers ...string) fiber.CacheKeyFunc {
+	return func(req fiber.Request) (string, bool) {
+		httpReq, ok := req.(*Request)
+		if !ok || (httpReq.Method != http.MethodGet && httpReq.Method != http.MethodHead) {
+			return "", false
+		}
+
+		var key strings.Builder
+		key.WriteString(httpReq.Method)
+		key.WriteString(" ")
+		key.WriteString(httpReq.URL.RequestURI())
+		for _, header := range headers {
+			key.WriteString("\n")
+			key.WriteString(http.CanonicalHeaderKey(header))
+			key.WriteString(": ")
+			key.WriteString(strings.Join(httpReq.Request.Header.Values(header), ","))
+		}
+		if payload := httpReq.Payload(); len(payload) > 0 {
+			digest := sha256.Sum256(payload)
+			key.WriteString("\n")
+			key.WriteString(hex.EncodeToString(digest[:]))
+		}
+		return key.String(), true
+	}
+}
+
+// CacheableResponse returns the function, that checks, if the http response can be cached with the key of the request,
+// that includes the values of the given headers (see CacheKey). The response, which Vary header lists the headers,
+// the key doesn't include, or `*`, varies by the requests, that share the key, so it's not cached
+func CacheableResponse(headers ...string) func(resp fiber.Response) bool {
+	keyed := make(map[string]bool, len(headers))
+	for _, header := range headers {
+		keyed[http.CanonicalHeaderKey(header)] = true
+	}
+	return func(resp fiber.Response) bool {
+		httpResp, ok := resp.(*Response)
+		if !ok {
+			return true
+		}
+		for _, value := range httpResp.Header().Values("Vary") {
+			for _, header := range strings.Split(value, ",") {
+				header = strings.TrimSpace(header)
+				if header == "*" || (header != "" && !keyed[http.CanonicalHeaderKey(header)]) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+}
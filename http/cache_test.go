@@ -0,0 +1,105 @@
+package http_test
+
+import (
+	"net/http"
+	"testing"
+
+	fiberHTTP "github.com/gojek/fiber/http"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheKey(t *testing.T) {
+	withHeader := func(req *fiberHTTP.Request, key, value string) *fiberHTTP.Request {
+		req.Request.Header.Set(key, value)
+		return req
+	}
+
+	suite := map[string]struct {
+		headers  []string
+		reqA     *fiberHTTP.Request
+		reqB     *fiberHTTP.Request
+		sameKeys bool
+	}{
+		"same requests": {
+			reqA:     testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", ""),
+			reqB:     testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", ""),
+			sameKeys: true,
+		},
+		"different queries": {
+			reqA: testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=1", ""),
+			reqB: testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features?id=2", ""),
+		},
+		"different methods": {
+			reqA: testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features", ""),
+			reqB: testUtilsHttp.MockReq(http.MethodHead, "http://localhost/features", ""),
+		},
+		"different bodies": {
+			reqA: testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features", "a"),
+			reqB: testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features", "b"),
+		},
+		"different selected headers": {
+			headers: []string{"x-customer-id"},
+			reqA:    withHeader(testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features", ""), "X-Customer-ID", "1"),
+			reqB:    withHeader(testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features", ""), "X-Customer-ID", "2"),
+		},
+		"different other headers": {
+			headers:  []string{"x-customer-id"},
+			reqA:     withHeader(testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features", ""), "X-Request-ID", "1"),
+			reqB:     withHeader(testUtilsHttp.MockReq(http.MethodGet, "http://localhost/features", ""), "X-Request-ID", "2"),
+			sameKeys: true,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			key := fiberHTTP.CacheKey(tt.headers...)
+			keyA, okA := key(tt.reqA)
+			keyB, okB := key(tt.reqB)
+			assert.True(t, okA)
+			assert.True(t, okB)
+			assert.Equal(t, tt.sameKeys, keyA == keyB)
+		})
+	}
+
+	t.Run("non-idempotent request", func(t *testing.T) {
+		_, ok := fiberHTTP.CacheKey()(testUtilsHttp.MockReq(http.MethodPost, "http://localhost/features", ""))
+		assert.False(t, ok)
+	})
+}
+
+func TestCacheableResponse(t *testing.T) {
+	suite := map[string]struct {
+		headers  []string
+		vary     []string
+		expected bool
+	}{
+		"no vary": {
+			expected: true,
+		},
+		"vary by the key headers": {
+			headers:  []string{"accept-language", "X-Customer-ID"},
+			vary:     []string{"Accept-Language, x-customer-id"},
+			expected: true,
+		},
+		"vary by the other headers": {
+			headers: []string{"Accept-Language"},
+			vary:    []string{"Accept-Language", "Accept-Encoding"},
+		},
+		"vary by anything": {
+			headers: []string{"Accept-Language"},
+			vary:    []string{"*"},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			header := http.Header{}
+			for _, value := range tt.vary {
+				header.Add("Vary", value)
+			}
+			resp := testUtilsHttp.MockResp(http.StatusOK, "", header, nil)
+			assert.Equal(t, tt.expected, fiberHTTP.CacheableResponse(tt.headers...)(resp))
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// ContentDecoder wraps the encoded body of the response into the reader of its decoded body. The body
+// is decoded, while it's read, so the decoded body is never held in memory beyond the payload size limit
+type ContentDecoder func(body io.Reader) (io.Reader, error)
+
+// DefaultContentDecoders are the decoders of the gzip and deflate content encodings, that are used by
+// the Dispatcher with decompression enabled (see WithDecompression). Brotli is not a part of the standard
+// library, so the decoder of br (e.g. from github.com/andybalholm/brotli) is added with WithContentDecoder
+var DefaultContentDecoders = map[string]ContentDecoder{
+	"gzip":    decodeGzip,
+	"deflate": decodeDeflate,
+}
+
+func decodeGzip(body io.Reader) (io.Reader, error) {
+	return gzip.NewReader(body)
+}
+
+// decodeDeflate decodes the zlib-wrapped deflate stream (RFC 1950), as mandated by the http spec, or the raw
+// deflate stream (RFC 1951), that some servers send instead
+func decodeDeflate(body io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(body)
+	header, err := buffered.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	// the zlib header declares the deflate compression method, and is a multiple of 31
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// decode replaces the encoded body of the response with the decoded one, if its content encoding has
+// a decoder. The Content-Encoding and Content-Length headers are removed, since they describe the encoded
+// body. The responses with other content encodings (or several ones) are left as they are
+func (d *Dispatcher) decode(resp *http.Response) error {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	decoder, ok := d.decoders[encoding]
+	if !ok || resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	decoded, err := decoder(resp.Body)
+	if err == io.EOF {
+		// the body is empty, e.g. the response to the HEAD request
+		decoded, err = http.NoBody, nil
+	}
+	if err != nil {
+		return err
+	}
+	// the original body is closed by the dispatcher, once the response is read
+	resp.Body = ioutil.NopCloser(decoded)
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
@@ -1,27 +1,113 @@
 package http
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
 )
 
+// DefaultUserAgent is the value of the User-Agent header, that is sent with outgoing
+// requests, unless another user agent is configured on the Dispatcher
+var DefaultUserAgent = "fiber/" + fiber.Version
+
 // Client is the base interface for an http-client (to be able to mock actual implementation)
 type Client interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// Dispatcher is the fiber.Dispatcher, that sends the requests to the backend with the http client
 type Dispatcher struct {
 	httpClient Client
+	userAgent  string
+	// host, if set, is sent as the Host header instead of the host of the url (see WithHost)
+	host string
+	// backendNameHeader, if set, carries the breadcrumb of the backends of the responses (see WithBackendNameHeader)
+	backendNameHeader string
+	// timeout and maxTimeout, if set, are applied to each request by the dispatcher (see WithTimeout)
+	timeout    time.Duration
+	maxTimeout time.Duration
+	// timeoutResponse, if set, is returned instead of the client error when the request times out
+	timeoutResponse *TimeoutResponse
+	// propagatedHeaders, if set, are the headers of the request, that are sent to the backend
+	propagatedHeaders []string
+	// maxRequestSize and maxResponseSize, if set, are the limits of the request and response bodies in bytes
+	maxRequestSize  int64
+	maxResponseSize int64
+	// decoders, if set, decode the responses with the corresponding content encodings (see WithDecompression)
+	decoders map[string]ContentDecoder
 }
 
-func (d *Dispatcher) Do(req fiber.Request) fiber.Response {
+var _ fiber.Dispatcher = (*Dispatcher)(nil)
+
+// hopByHopHeaders are the headers, that are meaningful only for a single connection, and are never
+// forwarded to the backend (see RFC 7230, section 6.1)
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// TimeoutResponse defines the status code and the body to be sent back
+// to the client, when the request to the backend times out
+type TimeoutResponse = fiber.TimeoutResponse
+
+// Do sends the request to the backend. The request is cancelled, when the context is done, or
+// the timeout of the request, set with fiber.WithRequestTimeout, is exceeded
+func (d *Dispatcher) Do(ctx context.Context, req fiber.Request) fiber.Response {
 	if httpReq, ok := req.(*Request); ok {
-		resp, err := d.httpClient.Do(httpReq.Request)
+		if d.maxRequestSize > 0 && int64(len(httpReq.Payload())) > d.maxRequestSize {
+			return fiber.NewErrorResponse(fiberErrors.ErrRequestTooLarge(protocol.HTTP, d.maxRequestSize))
+		}
+		if _, ok := fiber.RequestTimeout(ctx); ok || d.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, fiber.ResolveTimeout(ctx, d.timeout, d.maxTimeout))
+			defer cancel()
+		}
+		requestID := httpReq.RequestID()
+		outgoing := httpReq.Request.WithContext(ctx)
+		outgoing.Header = d.requestHeader(httpReq.Request.Header)
+		// the id of the request is sent, even if its header isn't propagated
+		outgoing.Header.Set(fiber.RequestIDHeader, requestID)
+		if d.host != "" {
+			outgoing.Host = d.host
+		}
+		// User-Agent explicitly set on the request (i.e. by the interceptors) takes precedence
+		if outgoing.Header.Get("User-Agent") == "" && d.userAgent != "" {
+			outgoing.Header.Set("User-Agent", d.userAgent)
+		}
+		resp, err := d.httpClient.Do(outgoing)
 		if resp != nil && resp.Body != nil {
 			defer resp.Body.Close()
-			return NewHTTPResponse(resp)
+			response := d.response(resp)
+			if httpResp, ok := response.(*Response); ok && d.backendNameHeader != "" {
+				httpResp.WithBackendNameHeader(d.backendNameHeader)
+			}
+			return response
+		}
+		if isConnectTimeout(err) && ctx.Err() == nil {
+			// the connection is not established within the dial timeout of the transport, so the backend
+			// is unreachable, rather than slow to respond
+			return fiber.NewErrorResponse(fiberErrors.ErrConnectTimeout(protocol.HTTP))
+		}
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() && d.timeoutResponse != nil {
+			return fiber.NewErrorResponseWithPayload(d.timeoutResponse.StatusCode, d.timeoutResponse.Body)
 		}
 		return fiber.NewErrorResponse(err)
 	}
@@ -29,11 +115,228 @@ func (d *Dispatcher) Do(req fiber.Request) fiber.Response {
 	return fiber.NewErrorResponse(errors.New("fiber: http.Dispatcher supports only http.Request type of requests"))
 }
 
-func NewDispatcher(client Client) (fiber.Dispatcher, error) {
+// isConnectTimeout reports, if the error is the timeout of establishing the connection to the backend
+func isConnectTimeout(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout()
+}
+
+// response reads the response of the backend, decoding its body, if the decompression is enabled.
+// If the (decoded) body of the response exceeds the limit, it's not read any further, and
+// ErrResponseTooLarge is returned instead
+func (d *Dispatcher) response(resp *http.Response) fiber.Response {
+	if err := d.decode(resp); err != nil {
+		return fiber.NewErrorResponse(fmt.Errorf("unable to decode response body: %s", err.Error()))
+	}
+	if d.maxResponseSize <= 0 {
+		return NewHTTPResponse(resp)
+	}
+	tooLarge := fiber.NewErrorResponse(fiberErrors.ErrResponseTooLarge(protocol.HTTP, d.maxResponseSize))
+	if resp.ContentLength > d.maxResponseSize {
+		return tooLarge
+	}
+	// one byte more than the limit is read to find out, if the body exceeds it
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, d.maxResponseSize+1))
+	if err != nil {
+		return fiber.NewErrorResponse(fmt.Errorf("unable to read response body: %s", err.Error()))
+	}
+	if int64(len(body)) > d.maxResponseSize {
+		return tooLarge
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return NewHTTPResponse(resp)
+}
+
+// requestHeader returns the header of the request, that is sent to the backend: the propagated headers only,
+// if they are configured, without the hop-by-hop headers, including the ones listed in the Connection header
+func (d *Dispatcher) requestHeader(header http.Header) http.Header {
+	outgoing := header.Clone()
+	if d.propagatedHeaders != nil {
+		outgoing = make(http.Header, len(d.propagatedHeaders))
+		for _, key := range d.propagatedHeaders {
+			if values, ok := header[key]; ok {
+				outgoing[key] = append([]string(nil), values...)
+			}
+		}
+	} else if outgoing == nil {
+		outgoing = make(http.Header)
+	}
+
+	for _, connection := range header.Values("Connection") {
+		for _, key := range strings.Split(connection, ",") {
+			outgoing.Del(strings.TrimSpace(key))
+		}
+	}
+	for _, key := range hopByHopHeaders {
+		outgoing.Del(key)
+	}
+	return outgoing
+}
+
+// Describe adds the protocol and the timeout of the requests to the info. The timeout of the
+// http client (e.g. *http.Client) is used, if the dispatcher doesn't apply its own one
+func (d *Dispatcher) Describe(info *fiber.ComponentInfo) {
+	info.Protocol = protocol.HTTP
+	timeout := d.timeout
+	if client, ok := d.httpClient.(*http.Client); ok && timeout == 0 {
+		timeout = client.Timeout
+	}
+	if timeout > 0 {
+		info.Timeout = timeout.String()
+	}
+}
+
+// Close closes the idle connections of the http client, if it supports it (e.g. *http.Client).
+// The connections of the in-flight requests are closed, when the requests are complete. The *http.Client
+// without its own transport uses http.DefaultTransport, that is shared by the whole process, so its connections
+// are left open
+func (d *Dispatcher) Close(context.Context) error {
+	if client, ok := d.httpClient.(*http.Client); ok &&
+		(client.Transport == nil || client.Transport == http.DefaultTransport) {
+		return nil
+	}
+	if client, ok := d.httpClient.(interface{ CloseIdleConnections() }); ok {
+		client.CloseIdleConnections()
+	}
+	return nil
+}
+
+// WarmUp sends the HEAD request to the endpoint of the backend (see fiber.WarmUpEndpoint), so the http client
+// establishes the connection to it, that is kept for the following requests. Any response of the backend
+// means the connection is established. Nothing is done, if the endpoint is not known
+func (d *Dispatcher) WarmUp(ctx context.Context) error {
+	endpoint := fiber.WarmUpEndpoint(ctx)
+	if endpoint == "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if d.userAgent != "" {
+		req.Header.Set("User-Agent", d.userAgent)
+	}
+	if d.host != "" {
+		req.Host = d.host
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// HealthProbe returns the fiber.HealthProbe, that sends the GET request to the url with the http client
+// of the dispatcher. The backend is healthy, if it responds with a 2xx status code
+func (d *Dispatcher) HealthProbe(url string) fiber.HealthProbe {
+	return fiber.HealthProbeFunc(func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if d.userAgent != "" {
+			req.Header.Set("User-Agent", d.userAgent)
+		}
+		if d.host != "" {
+			req.Host = d.host
+		}
+		resp, err := d.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if !isSuccessStatus(resp.StatusCode) {
+			return fmt.Errorf("health probe: unexpected status code [%d]", resp.StatusCode)
+		}
+		return nil
+	})
+}
+
+// WithUserAgent sets the User-Agent header value to be sent with outgoing requests,
+// that don't have this header set already
+func (d *Dispatcher) WithUserAgent(userAgent string) *Dispatcher {
+	d.userAgent = userAgent
+	return d
+}
+
+// WithHost sets the Host header of the requests to the backend, e.g. to address the tenant of the multi-tenant
+// backend behind the shared proxy. The connection is still established to the host of the url, and its TLS
+// certificate is verified against it (or against the ServerName of the TLS config of the transport)
+func (d *Dispatcher) WithHost(host string) *Dispatcher {
+	d.host = host
+	return d
+}
+
+// WithBackendNameHeader sets the header of the responses of the backend, that carries the breadcrumb of their
+// backends, instead of DefaultBackendNameHeader (see Response.WithBackendNameHeader)
+func (d *Dispatcher) WithBackendNameHeader(header string) *Dispatcher {
+	d.backendNameHeader = header
+	return d
+}
+
+// WithTimeout sets the timeout of the requests to the backend, and the ceiling of the timeout
+// of the request, set with fiber.WithRequestTimeout. Without it, the timeout of the request
+// can only be shorter than the timeout of the http client
+func (d *Dispatcher) WithTimeout(timeout time.Duration, maxTimeout time.Duration) *Dispatcher {
+	d.timeout = timeout
+	d.maxTimeout = maxTimeout
+	return d
+}
+
+// WithPropagatedHeaders sets the headers of the incoming request, that are sent to the backend. The other
+// headers are dropped, including the ones set by the interceptors, unless they are listed too. By default,
+// all the headers are sent. The hop-by-hop headers (e.g. Connection, Keep-Alive) are never sent
+func (d *Dispatcher) WithPropagatedHeaders(headers ...string) *Dispatcher {
+	d.propagatedHeaders = nil
+	for _, key := range headers {
+		d.propagatedHeaders = append(d.propagatedHeaders, http.CanonicalHeaderKey(key))
+	}
+	return d
+}
+
+// WithMaxBodySizes sets the limits of the bodies of the requests to the backend, and of its responses,
+// in bytes. The larger requests are not sent, and ErrRequestTooLarge is returned, while the larger responses
+// are not read beyond the limit, and ErrResponseTooLarge is returned. Zero means no limit
+func (d *Dispatcher) WithMaxBodySizes(maxRequestSize int64, maxResponseSize int64) *Dispatcher {
+	d.maxRequestSize = maxRequestSize
+	d.maxResponseSize = maxResponseSize
+	return d
+}
+
+// WithDecompression enables the decompression of the responses, encoded with gzip or deflate (see
+// DefaultContentDecoders), so the decoded payloads are sent back. The Content-Encoding and Content-Length
+// headers of the decoded responses are removed, and the limit of the response body (see WithMaxBodySizes)
+// applies to the decoded body. The responses with other content encodings are sent back as they are
+func (d *Dispatcher) WithDecompression() *Dispatcher {
+	for encoding, decoder := range DefaultContentDecoders {
+		d.WithContentDecoder(encoding, decoder)
+	}
+	return d
+}
+
+// WithContentDecoder enables the decompression of the responses with the given content encoding (e.g. br)
+func (d *Dispatcher) WithContentDecoder(encoding string, decoder ContentDecoder) *Dispatcher {
+	if d.decoders == nil {
+		d.decoders = make(map[string]ContentDecoder)
+	}
+	d.decoders[strings.ToLower(encoding)] = decoder
+	return d
+}
+
+// WithTimeoutResponse sets the response to be returned, when the request to the backend times out
+func (d *Dispatcher) WithTimeoutResponse(timeoutResponse *TimeoutResponse) *Dispatcher {
+	d.timeoutResponse = timeoutResponse
+	return d
+}
+
+func NewDispatcher(client Client) (*Dispatcher, error) {
 	if client == nil {
 		return nil, errors.New("client can not be nil")
 	}
 	return &Dispatcher{
 		httpClient: client,
+		userAgent:  DefaultUserAgent,
 	}, nil
 }
@@ -2,17 +2,30 @@ package http_test
 
 import (
 	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
 	fiberHTTP "github.com/gojek/fiber/http"
+	"github.com/gojek/fiber/internal/testutils"
 	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
 	"github.com/gojek/fiber/protocol"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type unsupportedRequest struct {
@@ -31,6 +44,10 @@ func (r *unsupportedRequest) OperationName() string {
 	panic("not implemented")
 }
 
+func (r *unsupportedRequest) RequestID() string {
+	panic("not implemented")
+}
+
 func (r *unsupportedRequest) Transform(_ fiber.Backend) (fiber.Request, error) {
 	panic("not implemented")
 }
@@ -59,7 +76,10 @@ type dispatcherTestCase struct {
 func (tt dispatcherTestCase) mockClient() *MockHTTPClient {
 	mockClient := new(MockHTTPClient)
 	if httpReq, ok := tt.request.(*fiberHTTP.Request); ok {
-		mockClient.On("Do", httpReq.Request).Once().Return(tt.response, tt.error)
+		// the dispatcher sends a copy of the request with the dispatch context
+		mockClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+			return req.URL == httpReq.Request.URL && req.Method == httpReq.Request.Method
+		})).Once().Return(tt.response, tt.error)
 	}
 
 	return mockClient
@@ -109,10 +129,564 @@ func TestDispatcher_Do(t *testing.T) {
 
 			dispatcher, _ := fiberHTTP.NewDispatcher(mockClient)
 
-			resp := dispatcher.Do(tt.request)
+			resp := dispatcher.Do(context.Background(), tt.request)
 			assert.Equal(t, tt.expected, resp)
 			mockClient.AssertExpectations(t)
 		})
 	}
 
 }
+
+func TestDispatcher_UserAgent(t *testing.T) {
+	suite := map[string]struct {
+		userAgent     string
+		requestHeader http.Header
+		expected      string
+	}{
+		"default user agent": {
+			expected: fiberHTTP.DefaultUserAgent,
+		},
+		"configured user agent": {
+			userAgent: "route-a/1.0",
+			expected:  "route-a/1.0",
+		},
+		"user agent set on the request is not overridden": {
+			userAgent:     "route-a/1.0",
+			requestHeader: http.Header{"User-Agent": {"interceptor/2.0"}},
+			expected:      "interceptor/2.0",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			var received string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Get("User-Agent")
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			dispatcher, err := fiberHTTP.NewDispatcher(server.Client())
+			require.NoError(t, err)
+			if tt.userAgent != "" {
+				dispatcher.WithUserAgent(tt.userAgent)
+			}
+
+			req := testUtilsHttp.MockReq("GET", server.URL, "")
+			for key, values := range tt.requestHeader {
+				req.Request.Header[key] = values
+			}
+
+			resp := dispatcher.Do(context.Background(), req)
+			require.True(t, resp.IsSuccess())
+			assert.Equal(t, tt.expected, received)
+		})
+	}
+}
+
+func TestDispatcher_Host(t *testing.T) {
+	received := make(chan string, 2)
+	// the certificate of the server is issued to 127.0.0.1, so it's verified against the host of the url
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Host
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dispatcher, err := fiberHTTP.NewDispatcher(server.Client())
+	require.NoError(t, err)
+	dispatcher.WithHost("tenant-a.example.com")
+
+	resp := dispatcher.Do(context.Background(), testUtilsHttp.MockReq("GET", server.URL, ""))
+	require.True(t, resp.IsSuccess())
+	assert.Equal(t, "tenant-a.example.com", <-received)
+
+	// the health probe addresses the same host
+	require.NoError(t, dispatcher.HealthProbe(server.URL+"/healthz").Probe(context.Background()))
+	assert.Equal(t, "tenant-a.example.com", <-received)
+}
+
+func TestDispatcher_Close(t *testing.T) {
+	suite := map[string]struct {
+		transport      http.RoundTripper
+		expectedClosed bool
+	}{
+		"own transport": {
+			transport:      &http.Transport{},
+			expectedClosed: true,
+		},
+		"default transport": {
+			expectedClosed: false,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			closed := make(chan struct{}, 1)
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateClosed {
+					closed <- struct{}{}
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			dispatcher, err := fiberHTTP.NewDispatcher(&http.Client{Transport: tt.transport})
+			require.NoError(t, err)
+			require.True(t, dispatcher.Do(context.Background(), testUtilsHttp.MockReq("GET", server.URL, "")).IsSuccess())
+
+			// only the idle connections of the transport, that the dispatcher owns, are closed
+			require.NoError(t, dispatcher.Close(context.Background()))
+			select {
+			case <-closed:
+				assert.True(t, tt.expectedClosed, "the idle connection should be kept")
+			case <-time.After(100 * time.Millisecond):
+				assert.False(t, tt.expectedClosed, "the idle connection should be closed")
+			}
+		})
+	}
+}
+
+func TestDispatcher_PropagatedHeaders(t *testing.T) {
+	requestHeader := http.Header{
+		"Authorization":    {"Bearer token"},
+		"X-Request-Id":     {"abc"},
+		"X-Tenant":         {"tenant-a", "tenant-b"},
+		"X-Internal":       {"secret"},
+		"Connection":       {"keep-alive, X-Hop"},
+		"X-Hop":            {"hop"},
+		"Keep-Alive":       {"timeout=5"},
+		"Upgrade":          {"websocket"},
+		"Proxy-Connection": {"keep-alive"},
+	}
+
+	suite := map[string]struct {
+		propagatedHeaders []string
+		expected          http.Header
+		dropped           []string
+	}{
+		"all the headers are propagated by default": {
+			expected: http.Header{
+				"Authorization": {"Bearer token"},
+				"X-Request-Id":  {"abc"},
+				"X-Tenant":      {"tenant-a", "tenant-b"},
+				"X-Internal":    {"secret"},
+			},
+			dropped: []string{"X-Hop", "Keep-Alive", "Upgrade", "Proxy-Connection"},
+		},
+		"only the whitelisted headers are propagated": {
+			propagatedHeaders: []string{"authorization", "X-Request-ID", "X-Tenant", "X-Missing"},
+			expected: http.Header{
+				"Authorization": {"Bearer token"},
+				"X-Request-Id":  {"abc"},
+				"X-Tenant":      {"tenant-a", "tenant-b"},
+			},
+			dropped: []string{"X-Internal", "X-Missing"},
+		},
+		"hop-by-hop headers are stripped, even if they are whitelisted": {
+			propagatedHeaders: []string{"Authorization", "Keep-Alive", "Upgrade", "X-Hop"},
+			expected: http.Header{
+				"Authorization": {"Bearer token"},
+			},
+			dropped: []string{"Keep-Alive", "Upgrade", "X-Hop", "X-Internal"},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			var received http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Clone()
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			dispatcher, err := fiberHTTP.NewDispatcher(server.Client())
+			require.NoError(t, err)
+			dispatcher.WithPropagatedHeaders(tt.propagatedHeaders...)
+
+			req := testUtilsHttp.MockReq("GET", server.URL, "")
+			for key, values := range requestHeader {
+				req.Request.Header[key] = values
+			}
+
+			resp := dispatcher.Do(context.Background(), req)
+			require.True(t, resp.IsSuccess())
+			for key, values := range tt.expected {
+				assert.Equal(t, values, received.Values(key), key)
+			}
+			for _, key := range tt.dropped {
+				assert.Empty(t, received.Values(key), key)
+			}
+			// the user agent of the dispatcher and the id of the request are still sent
+			assert.Equal(t, fiberHTTP.DefaultUserAgent, received.Get("User-Agent"))
+			assert.Equal(t, "abc", received.Get(fiber.RequestIDHeader))
+			// and the incoming request is not modified
+			assert.Equal(t, []string{"secret"}, req.Request.Header.Values("X-Internal"))
+		})
+	}
+}
+
+func TestDispatcher_MaxBodySizes(t *testing.T) {
+	suite := map[string]struct {
+		maxRequestSize  int64
+		maxResponseSize int64
+		requestBody     string
+		responseBody    string
+		chunked         bool
+		expectedStatus  int
+		expectedBody    string
+	}{
+		"under the limits": {
+			maxRequestSize:  5,
+			maxResponseSize: 5,
+			requestBody:     "12345",
+			responseBody:    "12345",
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "12345",
+		},
+		"response over the limit": {
+			maxResponseSize: 5,
+			responseBody:    "123456",
+			expectedStatus:  http.StatusBadGateway,
+			expectedBody:    "fiber: response body exceeds the limit of 5 bytes",
+		},
+		"chunked response over the limit": {
+			maxResponseSize: 5,
+			responseBody:    "123456",
+			chunked:         true,
+			expectedStatus:  http.StatusBadGateway,
+			expectedBody:    "fiber: response body exceeds the limit of 5 bytes",
+		},
+		"request over the limit": {
+			maxRequestSize: 5,
+			requestBody:    "123456",
+			responseBody:   "OK",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   "fiber: request body exceeds the limit of 5 bytes",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if tt.chunked {
+					// the response is flushed before it's complete, so its length is unknown
+					w.(http.Flusher).Flush()
+				}
+				_, _ = w.Write([]byte(tt.responseBody))
+			}))
+			defer server.Close()
+
+			dispatcher, err := fiberHTTP.NewDispatcher(server.Client())
+			require.NoError(t, err)
+			dispatcher.WithMaxBodySizes(tt.maxRequestSize, tt.maxResponseSize)
+
+			resp := dispatcher.Do(context.Background(), testUtilsHttp.MockReq("POST", server.URL, tt.requestBody))
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode())
+			assert.Contains(t, string(resp.Payload()), tt.expectedBody)
+		})
+	}
+}
+
+func TestDispatcher_Decompression(t *testing.T) {
+	payload := strings.Repeat("decoded payload ", 16)
+	encode := func(newWriter func(io.Writer) io.WriteCloser) []byte {
+		var buf bytes.Buffer
+		writer := newWriter(&buf)
+		_, _ = writer.Write([]byte(payload))
+		_ = writer.Close()
+		return buf.Bytes()
+	}
+	gzipped := encode(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+
+	suite := map[string]struct {
+		disabled        bool
+		encoding        string
+		body            []byte
+		maxResponseSize int64
+		expectedStatus  int
+		expectedBody    string
+		expectedHeader  http.Header
+	}{
+		"gzip": {
+			encoding:       "gzip",
+			body:           gzipped,
+			expectedStatus: http.StatusOK,
+			expectedBody:   payload,
+		},
+		"deflate": {
+			encoding:       "deflate",
+			body:           encode(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }),
+			expectedStatus: http.StatusOK,
+			expectedBody:   payload,
+		},
+		"raw deflate": {
+			encoding: "deflate",
+			body: encode(func(w io.Writer) io.WriteCloser {
+				writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+				return writer
+			}),
+			expectedStatus: http.StatusOK,
+			expectedBody:   payload,
+		},
+		"decoded body under the limit": {
+			encoding:        "gzip",
+			body:            gzipped,
+			maxResponseSize: int64(len(payload)),
+			expectedStatus:  http.StatusOK,
+			expectedBody:    payload,
+		},
+		"decoded body over the limit": {
+			encoding:        "gzip",
+			body:            gzipped,
+			maxResponseSize: int64(len(gzipped)),
+			expectedStatus:  http.StatusBadGateway,
+			expectedBody:    fmt.Sprintf("fiber: response body exceeds the limit of %d bytes", len(gzipped)),
+		},
+		"corrupted body": {
+			encoding:       "gzip",
+			body:           []byte("not gzipped"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "unable to decode response body: gzip: invalid header",
+		},
+		"unsupported encoding": {
+			encoding:       "br",
+			body:           []byte("brotli"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "brotli",
+			expectedHeader: http.Header{"Content-Encoding": []string{"br"}},
+		},
+		"decompression disabled": {
+			disabled:       true,
+			encoding:       "gzip",
+			body:           gzipped,
+			expectedStatus: http.StatusOK,
+			expectedBody:   string(gzipped),
+			expectedHeader: http.Header{"Content-Encoding": []string{"gzip"}},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Encoding", tt.encoding)
+				_, _ = w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			dispatcher, err := fiberHTTP.NewDispatcher(server.Client())
+			require.NoError(t, err)
+			if !tt.disabled {
+				dispatcher.WithDecompression()
+			}
+			dispatcher.WithMaxBodySizes(0, tt.maxResponseSize)
+
+			// the client doesn't decode the response itself, since the request accepts the encodings explicitly
+			req := testUtilsHttp.MockReq("GET", server.URL, "")
+			req.Request.Header.Set("Accept-Encoding", "gzip, deflate, br")
+			resp := dispatcher.Do(context.Background(), req)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode())
+			assert.Contains(t, string(resp.Payload()), tt.expectedBody)
+			if httpResp, ok := resp.(*fiberHTTP.Response); ok {
+				assert.Equal(t, tt.expectedHeader.Get("Content-Encoding"), httpResp.Header().Get("Content-Encoding"))
+				if tt.expectedHeader == nil {
+					assert.Empty(t, httpResp.Header().Get("Content-Length"))
+				}
+			}
+		})
+	}
+}
+
+func TestDispatcher_TimeoutResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	suite := map[string]struct {
+		timeoutResponse *fiberHTTP.TimeoutResponse
+		expectedStatus  int
+		expectedBody    string
+	}{
+		"default timeout response": {
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"custom timeout response": {
+			timeoutResponse: &fiberHTTP.TimeoutResponse{
+				StatusCode: http.StatusGatewayTimeout,
+				Body:       []byte(`{"status":"error","reason":"upstream timeout"}`),
+			},
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   `{"status":"error","reason":"upstream timeout"}`,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			dispatcher, err := fiberHTTP.NewDispatcher(&http.Client{Timeout: 10 * time.Millisecond})
+			require.NoError(t, err)
+			dispatcher.WithTimeoutResponse(tt.timeoutResponse)
+
+			resp := dispatcher.Do(context.Background(), testUtilsHttp.MockReq("GET", server.URL, ""))
+			assert.False(t, resp.IsSuccess())
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode())
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, string(resp.Payload()))
+			}
+		})
+	}
+}
+
+func TestDispatcher_RequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	suite := map[string]struct {
+		clientTimeout  time.Duration
+		timeout        time.Duration
+		maxTimeout     time.Duration
+		requestTimeout time.Duration
+		success        bool
+	}{
+		"no request timeout": {
+			clientTimeout: time.Second,
+			success:       true,
+		},
+		"request timeout is shorter than client timeout": {
+			clientTimeout:  time.Second,
+			requestTimeout: 20 * time.Millisecond,
+		},
+		"request timeout is capped by client timeout": {
+			clientTimeout:  20 * time.Millisecond,
+			requestTimeout: time.Second,
+		},
+		"timeout of the dispatcher": {
+			clientTimeout: time.Second,
+			timeout:       20 * time.Millisecond,
+			maxTimeout:    time.Second,
+		},
+		"request timeout within max timeout": {
+			clientTimeout:  time.Second,
+			timeout:        20 * time.Millisecond,
+			maxTimeout:     time.Second,
+			requestTimeout: 500 * time.Millisecond,
+			success:        true,
+		},
+		"request timeout is capped by max timeout": {
+			clientTimeout:  time.Second,
+			timeout:        20 * time.Millisecond,
+			maxTimeout:     50 * time.Millisecond,
+			requestTimeout: 500 * time.Millisecond,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			dispatcher, err := fiberHTTP.NewDispatcher(&http.Client{Timeout: tt.clientTimeout})
+			require.NoError(t, err)
+			if tt.timeout > 0 {
+				dispatcher.WithTimeout(tt.timeout, tt.maxTimeout)
+			}
+			dispatcher.WithTimeoutResponse(&fiberHTTP.TimeoutResponse{StatusCode: http.StatusGatewayTimeout})
+
+			ctx := context.Background()
+			if tt.requestTimeout > 0 {
+				ctx = fiber.WithRequestTimeout(ctx, tt.requestTimeout)
+			}
+			resp := dispatcher.Do(ctx, testUtilsHttp.MockReq("GET", server.URL, ""))
+			if tt.success {
+				assert.True(t, resp.IsSuccess())
+			} else {
+				assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode())
+			}
+		})
+	}
+}
+
+func TestDispatcher_ConnectTimeout(t *testing.T) {
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slowServer.Close()
+
+	suite := map[string]struct {
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		"backend is unreachable": {
+			url:            "http://" + testutils.UnresponsiveAddress(t),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   fiberErrors.ErrConnectTimeout(protocol.HTTP).Message,
+		},
+		"backend is slow to respond": {
+			url:            slowServer.URL,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   "backend is slow",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			transport, err := fiberHTTP.NewTransport(fiberHTTP.TransportConfig{DialTimeout: 20 * time.Millisecond})
+			require.NoError(t, err)
+			dispatcher, err := fiberHTTP.NewDispatcher(&http.Client{Transport: transport})
+			require.NoError(t, err)
+			dispatcher.WithTimeout(100*time.Millisecond, 0).WithTimeoutResponse(&fiberHTTP.TimeoutResponse{
+				StatusCode: http.StatusGatewayTimeout,
+				Body:       []byte("backend is slow"),
+			})
+
+			resp := dispatcher.Do(context.Background(), testUtilsHttp.MockReq("GET", tt.url, ""))
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode())
+			assert.Contains(t, string(resp.Payload()), tt.expectedBody)
+		})
+	}
+}
+
+func TestDispatcher_HealthProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, fiberHTTP.DefaultUserAgent, r.UserAgent())
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	dispatcher, err := fiberHTTP.NewDispatcher(http.DefaultClient)
+	require.NoError(t, err)
+
+	suite := map[string]struct {
+		url         string
+		expectedErr string
+	}{
+		"healthy": {
+			url: server.URL + "/healthz",
+		},
+		"unhealthy": {
+			url:         server.URL + "/unhealthy",
+			expectedErr: "health probe: unexpected status code [503]",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			err := dispatcher.HealthProbe(tt.url).Probe(context.Background())
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
@@ -14,6 +14,12 @@ import (
 // the Request handler
 type Options struct {
 	Timeout time.Duration
+	// MaxBodySize is the maximum size of the body of the incoming request in bytes. Larger requests are
+	// rejected with the 413 status code, without being buffered. Zero means no limit
+	MaxBodySize int64
+	// RouteErrorDetails adds the failures of the routes into the body of the error response, that is
+	// sent back, when all the routes have failed (see fiber.ErrorResponse.PayloadWithRouteErrors)
+	RouteErrorDetails bool
 }
 
 // Handler is a structure used to capture a fiber component and a set of
@@ -47,7 +53,7 @@ func (h *Handler) ServeHTTP(writer http.ResponseWriter, httpReq *http.Request) {
 
 // DoRequest executes the given http request and returns the response / error
 func (h *Handler) DoRequest(httpReq *http.Request) (fiber.Response, *fiberErrors.FiberError) {
-	if req, err := NewHTTPRequest(httpReq); err == nil {
+	if req, err := NewHTTPRequestWithLimit(httpReq, h.options.MaxBodySize); err == nil {
 		ctx, cancel := context.WithTimeout(req.Context(), h.options.Timeout)
 		defer cancel()
 
@@ -60,6 +66,8 @@ func (h *Handler) DoRequest(httpReq *http.Request) (fiber.Response, *fiberErrors
 		case <-time.After(h.options.Timeout):
 			return nil, fiberErrors.ErrRequestTimeout(protocol.HTTP)
 		}
+	} else if err == ErrBodyTooLarge {
+		return nil, fiberErrors.ErrRequestTooLarge(protocol.HTTP, h.options.MaxBodySize)
 	} else {
 		return nil, fiberErrors.ErrReadRequestFailed(protocol.HTTP, err)
 	}
@@ -75,7 +83,12 @@ func (h *Handler) write(resp fiber.Response, writer http.ResponseWriter) (err er
 		}
 	}
 
+	payload := resp.Payload()
+	if errResp, ok := resp.(*fiber.ErrorResponse); ok && h.options.RouteErrorDetails {
+		payload = errResp.PayloadWithRouteErrors()
+	}
+
 	writer.WriteHeader(resp.StatusCode())
-	_, err = writer.Write(resp.Payload())
+	_, err = writer.Write(payload)
 	return err
 }
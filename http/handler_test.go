@@ -19,11 +19,12 @@ import (
 )
 
 type handlerTestCase struct {
-	name      string
-	request   *http.Request
-	responses []testUtilsHttp.DelayedResponse
-	expected  *http.Response
-	timeout   time.Duration
+	name        string
+	request     *http.Request
+	responses   []testUtilsHttp.DelayedResponse
+	expected    *http.Response
+	timeout     time.Duration
+	maxBodySize int64
 }
 
 func (tt *handlerTestCase) mockComponent() fiber.Component {
@@ -144,13 +145,31 @@ func TestHandler_ServeHTTP(t *testing.T) {
 			},
 			timeout: 20 * time.Millisecond,
 		},
+		{
+			name: "error: request body too large",
+			request: newHTTPRequest(
+				"POST",
+				"localhost:8080/handler",
+				ioutil.NopCloser(bytes.NewBuffer([]byte("request body")))),
+			expected: &http.Response{
+				StatusCode: http.StatusRequestEntityTooLarge,
+				Header:     http.Header{},
+				Body: makeBody([]byte(
+					`{
+  "code": 413,
+  "error": "fiber: request body exceeds the limit of 8 bytes"
+}`)),
+			},
+			timeout:     20 * time.Millisecond,
+			maxBodySize: 8,
+		},
 	}
 
 	for _, tt := range suite {
 		t.Run(tt.name, func(t *testing.T) {
 			component := tt.mockComponent()
 
-			handler := fiberHTTP.NewHandler(component, fiberHTTP.Options{Timeout: tt.timeout})
+			handler := fiberHTTP.NewHandler(component, fiberHTTP.Options{Timeout: tt.timeout, MaxBodySize: tt.maxBodySize})
 
 			recorder := httptest.NewRecorder()
 			handler.ServeHTTP(recorder, tt.request)
@@ -161,3 +180,50 @@ func TestHandler_ServeHTTP(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_RouteErrorDetails(t *testing.T) {
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a",
+			testUtilsHttp.DelayedResponse{Response: testUtilsHttp.MockResp(500, "internal error", nil, nil)}),
+		"route-b": testutils.NewMockComponent("route-b",
+			testUtilsHttp.DelayedResponse{Response: testUtilsHttp.MockResp(502, "bad gateway", nil, nil)}),
+	}
+	router := fiber.NewLazyRouter("lazy-router")
+	router.SetRoutes(routes)
+	router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+
+	suite := map[string]struct {
+		routeErrorDetails bool
+		expected          string
+	}{
+		"route error details": {
+			routeErrorDetails: true,
+			expected: `{
+  "code": 503,
+  "error": "fiber: no responses received",
+  "route_errors": [
+    {"route_id": "route-a", "code": 500, "error": "internal error"},
+    {"route_id": "route-b", "code": 502, "error": "bad gateway"}
+  ]
+}`,
+		},
+		"no route error details": {
+			expected: `{"code": 503, "error": "fiber: no responses received"}`,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			handler := fiberHTTP.NewHandler(router, fiberHTTP.Options{
+				Timeout:           100 * time.Millisecond,
+				RouteErrorDetails: tt.routeErrorDetails,
+			})
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/route-errors", nil))
+
+			assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+			assert.JSONEq(t, tt.expected, recorder.Body.String())
+		})
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -26,8 +27,27 @@ func (r *Request) Header() map[string][]string {
 	return r.Request.Header
 }
 
+// SetHeader sets the header of the request, initializing it, if the request was created without one
+func (r *Request) SetHeader(key string, values ...string) {
+	if r.Request.Header == nil {
+		r.Request.Header = http.Header{}
+	}
+	r.Request.Header[http.CanonicalHeaderKey(key)] = values
+}
+
+// ErrBodyTooLarge is returned by NewHTTPRequestWithLimit, when the body of the request exceeds the limit
+var ErrBodyTooLarge = errors.New("http request body is too large")
+
 // NewHTTPRequest initialize a new client request from incoming server request
 func NewHTTPRequest(req *http.Request) (*Request, error) {
+	return NewHTTPRequestWithLimit(req, 0)
+}
+
+// NewHTTPRequestWithLimit initialize a new client request from incoming server request. The body of
+// the request is read once and buffered, so each of its copies, dispatched by the routes, is sent with
+// the full body. ErrBodyTooLarge is returned, if the body is larger than maxBodySize bytes, so it's not
+// buffered beyond the limit. Zero maxBodySize means no limit
+func NewHTTPRequestWithLimit(req *http.Request, maxBodySize int64) (*Request, error) {
 	// RequestURI can't be set in client requests
 	req.RequestURI = ""
 
@@ -38,39 +58,59 @@ func NewHTTPRequest(req *http.Request) (*Request, error) {
 	} else {
 		defer req.Body.Close()
 
-		data, err := ioutil.ReadAll(req.Body)
+		if maxBodySize > 0 && req.ContentLength > maxBodySize {
+			return nil, ErrBodyTooLarge
+		}
+		body := io.Reader(req.Body)
+		if maxBodySize > 0 {
+			// one byte more than the limit is read to find out, if the body exceeds it
+			body = io.LimitReader(req.Body, maxBodySize+1)
+		}
+		data, err := ioutil.ReadAll(body)
 		if err != nil {
 			return nil, err
 		}
+		if maxBodySize > 0 && int64(len(data)) > maxBodySize {
+			return nil, ErrBodyTooLarge
+		}
 
 		payload = fiber.NewCachedPayload(data)
-		req.GetBody = func() (io.ReadCloser, error) {
-			r := bytes.NewReader(data)
-			return ioutil.NopCloser(r), nil
-		}
+		req.GetBody = bodyOf(data)
+		// the body is replaced, so the request can still be dispatched as is
+		req.Body, _ = req.GetBody()
+		req.ContentLength = int64(len(data))
 	}
 
 	return &Request{Request: req, CachedPayload: payload}, nil
 }
 
+// bodyOf returns the function, that returns a new reader of the data on each call
+func bodyOf(data []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
 // Copy creates a deep copy of this request
 func (r *Request) Clone() (fiber.Request, error) {
-	bodyReader := bytes.NewReader(r.Payload())
-
-	proxyRequest, err := http.NewRequest(r.Method, r.URL.String(), bodyReader)
+	proxyRequest, err := http.NewRequest(r.Method, r.URL.String(), bytes.NewReader(r.Payload()))
 	if err != nil {
 		return nil, err
 	}
 
-	proxyRequest.GetBody = func() (io.ReadCloser, error) {
-		return ioutil.NopCloser(bodyReader), nil
-	}
+	// the copies share the buffered body, but each reads it from the start
+	proxyRequest.GetBody = bodyOf(r.Payload())
 
-	proxyRequest.Header = r.Header()
+	proxyRequest.Header = http.Header(r.Header()).Clone()
 
 	return &Request{CachedPayload: r.CachedPayload, Request: proxyRequest}, nil
 }
 
+// RequestID returns the id of the request, generating it, if the request doesn't have one (see fiber.EnsureRequestID)
+func (r *Request) RequestID() string {
+	return fiber.EnsureRequestID(r)
+}
+
 func (r *Request) OperationName() string {
 	return fmt.Sprintf("%s %s", r.Method, r.URL.Path)
 }
@@ -1,10 +1,13 @@
 package http_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -134,6 +137,40 @@ func TestRequest_Clone(t *testing.T) {
 	})
 }
 
+func TestRequest_RequestID(t *testing.T) {
+	t.Run("provided", func(t *testing.T) {
+		httpReq := newHTTPRequest(http.MethodGet, "http://localhost:9999/api/mock", nil)
+		httpReq.Header.Set("X-Request-ID", "request-1")
+		req, err := fiberHTTP.NewHTTPRequest(httpReq)
+		require.NoError(t, err)
+
+		require.Equal(t, "request-1", req.RequestID())
+		clone, err := req.Clone()
+		require.NoError(t, err)
+		require.Equal(t, "request-1", fiber.RequestID(clone))
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		httpReq := newHTTPRequest(http.MethodGet, "http://localhost:9999/api/mock", nil)
+		httpReq.Header = nil
+		req, err := fiberHTTP.NewHTTPRequest(httpReq)
+		require.NoError(t, err)
+
+		id := req.RequestID()
+		require.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", id)
+		// the id is set on the request, so it's the same for the request and its copies
+		require.Equal(t, id, req.RequestID())
+		require.Equal(t, id, req.Request.Header.Get(fiber.RequestIDHeader))
+		clone, err := req.Clone()
+		require.NoError(t, err)
+		require.Equal(t, id, fiber.RequestID(clone))
+
+		other, err := fiberHTTP.NewHTTPRequest(newHTTPRequest(http.MethodGet, "http://localhost:9999/api/mock", nil))
+		require.NoError(t, err)
+		require.NotEqual(t, id, other.RequestID())
+	})
+}
+
 func TestRequest_OperationName(t *testing.T) {
 	reqPath := "/internal/api"
 	method := http.MethodPost
@@ -208,3 +245,131 @@ func TestRequest_Transform(t *testing.T) {
 		})
 	}
 }
+
+func TestNewHTTPRequestWithLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          io.Reader
+		maxBodySize   int64
+		expectedError error
+	}{
+		{
+			name:        "within limit",
+			body:        strings.NewReader("12345678"),
+			maxBodySize: 8,
+		},
+		{
+			name:          "content length exceeds limit",
+			body:          strings.NewReader("123456789"),
+			maxBodySize:   8,
+			expectedError: fiberHTTP.ErrBodyTooLarge,
+		},
+		{
+			name:          "unknown length body exceeds limit",
+			body:          makeBody([]byte("123456789")),
+			maxBodySize:   8,
+			expectedError: fiberHTTP.ErrBodyTooLarge,
+		},
+		{
+			name: "no limit",
+			body: makeBody(requestPayload),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpReq := newHTTPRequest(http.MethodPost, "http://localhost:9999/limit", tt.body)
+			req, err := fiberHTTP.NewHTTPRequestWithLimit(httpReq, tt.maxBodySize)
+			if tt.expectedError != nil {
+				require.Equal(t, tt.expectedError, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(len(req.Payload())), req.ContentLength)
+			require.Equal(t, req.Payload(), readBytes(req.Body), "the request should be dispatchable as is")
+		})
+	}
+}
+
+// bodyRecordingServer starts the backend, that records the bodies of the requests it receives,
+// and responds with the given status code
+func bodyRecordingServer(t *testing.T, statusCode int, bodies chan<- []byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies <- body
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newBackendProxy(t *testing.T, id string, url string) fiber.Component {
+	dispatcher, err := fiberHTTP.NewDispatcher(http.DefaultClient)
+	require.NoError(t, err)
+	caller, err := fiber.NewCaller(id, dispatcher)
+	require.NoError(t, err)
+	return fiber.NewProxy(fiber.NewBackend(id, url), caller)
+}
+
+func TestRequest_BufferedBody(t *testing.T) {
+	body := []byte(`{"prediction_table": {"name": "table", "rows": [{"row_id": "1"}, {"row_id": "2"}]}}`)
+
+	tests := []struct {
+		name      string
+		component func(t *testing.T, bodies chan<- []byte) fiber.Component
+		backends  int
+	}{
+		{
+			name: "fan out",
+			component: func(t *testing.T, bodies chan<- []byte) fiber.Component {
+				fanOut := fiber.NewFanOut("fan-out")
+				routes := make(map[string]fiber.Component)
+				for _, id := range []string{"route-a", "route-b", "route-c"} {
+					routes[id] = newBackendProxy(t, id, bodyRecordingServer(t, http.StatusOK, bodies).URL)
+				}
+				fanOut.SetRoutes(routes)
+				return fanOut
+			},
+			backends: 3,
+		},
+		{
+			name: "fallback",
+			component: func(t *testing.T, bodies chan<- []byte) fiber.Component {
+				routes := map[string]fiber.Component{
+					"route-a": newBackendProxy(t, "route-a",
+						bodyRecordingServer(t, http.StatusInternalServerError, bodies).URL),
+					"route-b": newBackendProxy(t, "route-b", bodyRecordingServer(t, http.StatusOK, bodies).URL),
+				}
+				router := fiber.NewLazyRouter("router")
+				router.SetRoutes(routes)
+				router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+				return router
+			},
+			backends: 2,
+		},
+		{
+			name: "proxy",
+			component: func(t *testing.T, bodies chan<- []byte) fiber.Component {
+				return newBackendProxy(t, "route-a", bodyRecordingServer(t, http.StatusOK, bodies).URL)
+			},
+			backends: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies := make(chan []byte, tt.backends)
+			component := tt.component(t, bodies)
+
+			req, err := fiberHTTP.NewHTTPRequest(newHTTPRequest(http.MethodPost, "", makeBody(body)))
+			require.NoError(t, err)
+			for range component.Dispatch(context.Background(), req).Iter() {
+			}
+
+			require.Len(t, bodies, tt.backends)
+			for idx := 0; idx < tt.backends; idx++ {
+				require.Equal(t, string(body), string(<-bodies))
+			}
+		})
+	}
+}
@@ -9,12 +9,15 @@ import (
 	"github.com/gojek/fiber/errors"
 )
 
-// HeaderBackendName is the default backend name
-var headerBackendName = "X-Fiber-Route-ID"
+// DefaultBackendNameHeader is the default header of the response, that carries the breadcrumb of its
+// backends (see Response.WithBackendName)
+const DefaultBackendNameHeader = "X-Fiber-Backend"
 
 type Response struct {
 	*fiber.CachedPayload
 	response *http.Response
+	// backendNameHeader, if set, is the header of the breadcrumb instead of DefaultBackendNameHeader
+	backendNameHeader string
 }
 
 // IsSuccess returns the success state of the request, which is true if the status
@@ -22,17 +25,39 @@ func (r *Response) IsSuccess() bool {
 	return isSuccessStatus(r.StatusCode())
 }
 
+// WithBackendName prepends the backend to the breadcrumb of the response (see fiber.BackendNameSeparator)
 func (r *Response) WithBackendName(backEnd string) fiber.Response {
-	r.Header().Set(headerBackendName, backEnd)
+	if breadcrumb := r.BackendName(); breadcrumb != "" {
+		backEnd += fiber.BackendNameSeparator + breadcrumb
+	}
+	r.Header().Set(r.BackendNameHeader(), backEnd)
 	return r
 }
 
-// BackendName returns the backend used to make the request
+// BackendName returns the backend used to make the request, or the breadcrumb of the routes,
+// if it's been dispatched by the nested routers
 func (r *Response) BackendName() string {
-	if r.Header() == nil {
-		r.response.Header = make(http.Header)
+	return r.Header().Get(r.BackendNameHeader())
+}
+
+// BackendNameHeader returns the header of the response, that carries the breadcrumb of its backends
+func (r *Response) BackendNameHeader() string {
+	if r.backendNameHeader == "" {
+		return DefaultBackendNameHeader
 	}
-	return r.Header().Get(headerBackendName)
+	return r.backendNameHeader
+}
+
+// WithBackendNameHeader replaces the header of the breadcrumb of the backends of the response, e.g. to keep
+// the header, that the clients expect. The breadcrumb, that the response already has, is moved to the new header
+func (r *Response) WithBackendNameHeader(header string) *Response {
+	breadcrumb := r.BackendName()
+	r.Header().Del(r.BackendNameHeader())
+	r.backendNameHeader = http.CanonicalHeaderKey(header)
+	if breadcrumb != "" {
+		r.Header().Set(r.BackendNameHeader(), breadcrumb)
+	}
+	return r
 }
 
 // StatusCode returns the response status code
@@ -40,14 +65,43 @@ func (r *Response) StatusCode() int {
 	return r.response.StatusCode
 }
 
-// Header returns the response header
-func (r *Response) Header() http.Header {
-	if r.response.Header == nil {
-		r.response.Header = make(http.Header)
+// Clone returns the copy of the response with its own header, so it can be modified independently.
+// The payload is shared by the copies
+func (r *Response) Clone() fiber.Response {
+	response := *r.response
+	response.Header = r.Header().Clone()
+	return &Response{
+		CachedPayload:     r.CachedPayload,
+		response:          &response,
+		backendNameHeader: r.backendNameHeader,
 	}
+}
+
+// WithStatusCode replaces the status code of the response
+func (r *Response) WithStatusCode(code int) *Response {
+	r.response.StatusCode = code
+	return r
+}
+
+// WithPayload replaces the body of the response. The Content-Length header of the original body
+// is removed, so it's not sent back with the new one
+func (r *Response) WithPayload(payload []byte) *Response {
+	r.CachedPayload = fiber.NewCachedPayload(payload)
+	r.Header().Del("Content-Length")
+	return r
+}
+
+// Header returns the response header. It's initialized, when the response is created, so the header
+// can be read concurrently
+func (r *Response) Header() http.Header {
 	return r.response.Header
 }
 
+// SetHeader sets the header of the response, replacing its existing values
+func (r *Response) SetHeader(key string, values ...string) {
+	r.Header()[http.CanonicalHeaderKey(key)] = values
+}
+
 // FromHTTP constructs a fiber http or error response from http response / error object
 func NewHTTPResponse(httpResponse *http.Response) fiber.Response {
 	if httpResponse == nil {
@@ -68,6 +122,9 @@ func NewHTTPResponse(httpResponse *http.Response) fiber.Response {
 		return fiber.NewErrorResponse(err)
 	}
 	// Return the success response
+	if httpResponse.Header == nil {
+		httpResponse.Header = make(http.Header)
+	}
 	return &Response{
 		response:      httpResponse,
 		CachedPayload: fiber.NewCachedPayload(body),
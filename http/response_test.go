@@ -1,8 +1,10 @@
 package http_test
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -88,6 +90,63 @@ func TestNewHTTPResponse(t *testing.T) {
 			require.Equal(t, string(tt.expected.payload), string(resp.Payload()))
 			require.Equal(t, tt.expected.status, resp.StatusCode())
 			require.Equal(t, tt.expected.status/100 == 2, resp.IsSuccess())
+			if httpResp, ok := resp.(*fiberHTTP.Response); ok {
+				// the header of the response without one is initialized, so it's not written on the first read
+				require.NotNil(t, httpResp.Header())
+			}
 		})
 	}
 }
+
+func TestResponse_BackendName(t *testing.T) {
+	newResponse := func() *fiberHTTP.Response {
+		resp := fiberHTTP.NewHTTPResponse(&http.Response{StatusCode: http.StatusOK, Body: makeBody([]byte("OK"))})
+		return resp.(*fiberHTTP.Response)
+	}
+
+	t.Run("default header", func(t *testing.T) {
+		resp := newResponse()
+		require.Equal(t, "", resp.BackendName())
+
+		resp.WithBackendName("backend-a")
+		resp.WithBackendName("route-a")
+		require.Equal(t, "route-a,backend-a", resp.BackendName())
+		require.Equal(t, "route-a,backend-a", resp.Header().Get(fiberHTTP.DefaultBackendNameHeader))
+
+		// the breadcrumb is kept by the copies of the response
+		require.Equal(t, "route-a,backend-a", resp.Clone().BackendName())
+	})
+
+	t.Run("custom header", func(t *testing.T) {
+		resp := newResponse()
+		resp.WithBackendName("backend-a")
+		resp.WithBackendNameHeader("x-fiber-route-id")
+		resp.WithBackendName("route-a")
+
+		require.Equal(t, "X-Fiber-Route-Id", resp.BackendNameHeader())
+		require.Equal(t, "route-a,backend-a", resp.BackendName())
+		require.Equal(t, "route-a,backend-a", resp.Header().Get("X-Fiber-Route-ID"))
+		require.Empty(t, resp.Header().Get(fiberHTTP.DefaultBackendNameHeader))
+		require.Equal(t, "route-a,backend-a", resp.Clone().(*fiberHTTP.Response).Header().Get("X-Fiber-Route-ID"))
+	})
+
+	t.Run("dispatcher", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the breadcrumb of the backend, e.g. another fiber router, is extended
+			w.Header().Set("X-Backend", "backend-a")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		dispatcher, err := fiberHTTP.NewDispatcher(server.Client())
+		require.NoError(t, err)
+		dispatcher.WithBackendNameHeader("X-Backend")
+
+		req, err := fiberHTTP.NewHTTPRequest(newHTTPRequest(http.MethodGet, server.URL, nil))
+		require.NoError(t, err)
+		resp := dispatcher.Do(context.Background(), req).WithBackendName("route-a")
+		require.True(t, resp.IsSuccess())
+		require.Equal(t, "route-a,backend-a", resp.BackendName())
+		require.Equal(t, "route-a,backend-a", resp.(*fiberHTTP.Response).Header().Get("X-Backend"))
+	})
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"context"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
+)
+
+// ResponseTransform rewrites the http response, e.g. to unwrap the envelope of the body, or to inject
+// the headers. The header can be modified in place, and the status code and the body are replaced
+// with WithStatusCode and WithPayload. If the error is returned, it's sent back instead of the response
+type ResponseTransform func(ctx context.Context, req fiber.Request, resp *Response) error
+
+// ResponseTransformer is a fiber.ResponseInterceptor, that rewrites the http responses of the backends
+// with the ResponseTransform. The responses of other protocols, and the error responses, created by fiber
+// (e.g. ErrServiceUnavailable, or the non-2xx responses of the backends), are passed as they are.
+// When it's added to the interceptors of a router, only the response, chosen by the router, is transformed
+type ResponseTransformer struct {
+	fiber.NoopBeforeDispatchInterceptor
+	fiber.NoopAfterDispatchInterceptor
+	fiber.NoopAfterCompletionInterceptor
+
+	transform ResponseTransform
+}
+
+// NewResponseTransformer creates the ResponseTransformer with the given transform
+func NewResponseTransformer(transform ResponseTransform) *ResponseTransformer {
+	return &ResponseTransformer{transform: transform}
+}
+
+// InterceptResponse transforms the http response
+func (t *ResponseTransformer) InterceptResponse(ctx context.Context, req fiber.Request, resp fiber.Response) fiber.Response {
+	httpResp, ok := resp.(*Response)
+	if !ok {
+		return resp
+	}
+	if err := t.transform(ctx, req, httpResp); err != nil {
+		return fiber.NewErrorResponse(errors.NewFiberError(protocol.HTTP, err))
+	}
+	return httpResp
+}
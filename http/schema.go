@@ -0,0 +1,390 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gojek/fiber"
+	fiberError "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
+)
+
+// JSONSchema validates the JSON documents, i.e. the bodies of the http requests, against the subset of
+// the JSON Schema: the `type`, `enum`, `required`, `properties`, `additionalProperties`, `items`, `minItems`,
+// `maxItems`, `minLength`, `maxLength`, `pattern`, `minimum`, `maximum`, `allOf`, `anyOf`, `oneOf` and `not`
+// keywords. The other keywords are ignored, except `$ref`, which is not supported
+type JSONSchema struct {
+	types                []string
+	enum                 []interface{}
+	required             []string
+	properties           map[string]*JSONSchema
+	additionalProperties *JSONSchema
+	noAdditional         bool
+	items                *JSONSchema
+	minItems, maxItems   *int
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+	allOf, anyOf, oneOf  []*JSONSchema
+	not                  *JSONSchema
+}
+
+type jsonSchemaDefinition struct {
+	Type                 json.RawMessage            `json:"type"`
+	Enum                 []interface{}              `json:"enum"`
+	Required             []string                   `json:"required"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	MinItems             *int                       `json:"minItems"`
+	MaxItems             *int                       `json:"maxItems"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+	Pattern              *string                    `json:"pattern"`
+	Minimum              *float64                   `json:"minimum"`
+	Maximum              *float64                   `json:"maximum"`
+	AllOf                []json.RawMessage          `json:"allOf"`
+	AnyOf                []json.RawMessage          `json:"anyOf"`
+	OneOf                []json.RawMessage          `json:"oneOf"`
+	Not                  json.RawMessage            `json:"not"`
+	Ref                  *string                    `json:"$ref"`
+}
+
+// schemaTypes are the types of the JSON values, that the schema can require
+var schemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true,
+}
+
+// NewJSONSchema parses the JSON schema
+func NewJSONSchema(schema []byte) (*JSONSchema, error) {
+	parsed, err := parseJSONSchema(schema, "$")
+	if err != nil {
+		return nil, fmt.Errorf("json schema: %v", err)
+	}
+	return parsed, nil
+}
+
+// LoadJSONSchema reads the JSON schema from the file and parses it
+func LoadJSONSchema(path string) (*JSONSchema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("json schema: %v", err)
+	}
+	return NewJSONSchema(data)
+}
+
+func parseJSONSchema(data json.RawMessage, path string) (*JSONSchema, error) {
+	// the boolean schemas accept any document, or none of them
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		return &JSONSchema{}, nil
+	case "false":
+		return &JSONSchema{not: &JSONSchema{}}, nil
+	}
+
+	var def jsonSchemaDefinition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if def.Ref != nil {
+		return nil, fmt.Errorf("%s: $ref is not supported", path)
+	}
+
+	schema := &JSONSchema{
+		enum:      def.Enum,
+		required:  def.Required,
+		minItems:  def.MinItems,
+		maxItems:  def.MaxItems,
+		minLength: def.MinLength,
+		maxLength: def.MaxLength,
+		minimum:   def.Minimum,
+		maximum:   def.Maximum,
+	}
+	if len(def.Type) > 0 {
+		if err := json.Unmarshal(def.Type, &schema.types); err != nil {
+			var single string
+			if err = json.Unmarshal(def.Type, &single); err != nil {
+				return nil, fmt.Errorf("%s.type: expected string or array of strings", path)
+			}
+			schema.types = []string{single}
+		}
+		for _, typ := range schema.types {
+			if !schemaTypes[typ] {
+				return nil, fmt.Errorf("%s.type: unknown type [%s]", path, typ)
+			}
+		}
+	}
+	if def.Pattern != nil {
+		pattern, err := regexp.Compile(*def.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s.pattern: %v", path, err)
+		}
+		schema.pattern = pattern
+	}
+
+	var err error
+	if len(def.Properties) > 0 {
+		schema.properties = make(map[string]*JSONSchema, len(def.Properties))
+		for name, property := range def.Properties {
+			if schema.properties[name], err = parseJSONSchema(property, path+".properties."+name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(def.AdditionalProperties) > 0 {
+		if string(bytes.TrimSpace(def.AdditionalProperties)) == "false" {
+			schema.noAdditional = true
+		} else if schema.additionalProperties, err = parseJSONSchema(
+			def.AdditionalProperties, path+".additionalProperties"); err != nil {
+			return nil, err
+		}
+	}
+	if len(def.Items) > 0 {
+		if schema.items, err = parseJSONSchema(def.Items, path+".items"); err != nil {
+			return nil, err
+		}
+	}
+	if len(def.Not) > 0 {
+		if schema.not, err = parseJSONSchema(def.Not, path+".not"); err != nil {
+			return nil, err
+		}
+	}
+	for keyword, defs := range map[string][]json.RawMessage{"allOf": def.AllOf, "anyOf": def.AnyOf, "oneOf": def.OneOf} {
+		schemas := make([]*JSONSchema, len(defs))
+		for idx, nested := range defs {
+			if schemas[idx], err = parseJSONSchema(nested, fmt.Sprintf("%s.%s[%d]", path, keyword, idx)); err != nil {
+				return nil, err
+			}
+		}
+		switch keyword {
+		case "allOf":
+			schema.allOf = schemas
+		case "anyOf":
+			schema.anyOf = schemas
+		case "oneOf":
+			schema.oneOf = schemas
+		}
+	}
+	return schema, nil
+}
+
+// Validate returns the error, describing the first violation of the schema by the JSON document,
+// with the path of the offending value, e.g. `$.instances[0].id: expected type string, got number`
+func (s *JSONSchema) Validate(document []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	if decoder.More() {
+		return errors.New("invalid JSON: unexpected data after the top-level value")
+	}
+	return s.validate(value, "$")
+}
+
+func (s *JSONSchema) validate(value interface{}, path string) error {
+	if len(s.types) > 0 {
+		actual := jsonType(value)
+		matches := false
+		for _, typ := range s.types {
+			if typ == actual || (typ == "number" && actual == "integer") {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			return fmt.Errorf("%s: expected type %s, got %s", path, joinTypes(s.types), actual)
+		}
+	}
+	if len(s.enum) > 0 && !s.inEnum(value) {
+		return fmt.Errorf("%s: value is not one of the allowed values", path)
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if err := s.validateObject(typed, path); err != nil {
+			return err
+		}
+	case []interface{}:
+		if err := s.validateArray(typed, path); err != nil {
+			return err
+		}
+	case string:
+		length := utf8.RuneCountInString(typed)
+		if s.minLength != nil && length < *s.minLength {
+			return fmt.Errorf("%s: length must be at least %d", path, *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			return fmt.Errorf("%s: length must be at most %d", path, *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(typed) {
+			return fmt.Errorf("%s: value doesn't match the pattern [%s]", path, s.pattern)
+		}
+	case json.Number:
+		number, _ := typed.Float64()
+		if s.minimum != nil && number < *s.minimum {
+			return fmt.Errorf("%s: value must be at least %v", path, *s.minimum)
+		}
+		if s.maximum != nil && number > *s.maximum {
+			return fmt.Errorf("%s: value must be at most %v", path, *s.maximum)
+		}
+	}
+
+	for _, nested := range s.allOf {
+		if err := nested.validate(value, path); err != nil {
+			return err
+		}
+	}
+	if len(s.anyOf) > 0 && s.matching(s.anyOf, value, path) == 0 {
+		return fmt.Errorf("%s: value doesn't match any of the schemas", path)
+	}
+	if len(s.oneOf) > 0 && s.matching(s.oneOf, value, path) != 1 {
+		return fmt.Errorf("%s: value must match exactly one of the schemas", path)
+	}
+	if s.not != nil && s.not.validate(value, path) == nil {
+		return fmt.Errorf("%s: value must not match the schema", path)
+	}
+	return nil
+}
+
+func (s *JSONSchema) validateObject(object map[string]interface{}, path string) error {
+	for _, name := range s.required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s: missing required property [%s]", path, name)
+		}
+	}
+
+	// the properties are validated in order, so the same violation is reported first
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := s.properties[name]
+		switch {
+		case ok:
+		case s.noAdditional:
+			return fmt.Errorf("%s: unexpected property [%s]", path, name)
+		case s.additionalProperties != nil:
+			property = s.additionalProperties
+		default:
+			continue
+		}
+		if err := property.validate(object[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *JSONSchema) validateArray(array []interface{}, path string) error {
+	if s.minItems != nil && len(array) < *s.minItems {
+		return fmt.Errorf("%s: must have at least %d item(s)", path, *s.minItems)
+	}
+	if s.maxItems != nil && len(array) > *s.maxItems {
+		return fmt.Errorf("%s: must have at most %d item(s)", path, *s.maxItems)
+	}
+	if s.items != nil {
+		for idx, item := range array {
+			if err := s.items.validate(item, path+"["+strconv.Itoa(idx)+"]"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matching returns the number of the schemas, that the value matches
+func (s *JSONSchema) matching(schemas []*JSONSchema, value interface{}, path string) int {
+	matched := 0
+	for _, nested := range schemas {
+		if nested.validate(value, path) == nil {
+			matched++
+		}
+	}
+	return matched
+}
+
+func (s *JSONSchema) inEnum(value interface{}) bool {
+	for _, allowed := range s.enum {
+		if equalJSON(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalJSON compares the decoded JSON values, with the numbers compared by their value
+func equalJSON(a, b interface{}) bool {
+	toFloat := func(value interface{}) (float64, bool) {
+		switch number := value.(type) {
+		case float64:
+			return number, true
+		case json.Number:
+			f, err := number.Float64()
+			return f, err == nil
+		}
+		return 0, false
+	}
+	if numberA, ok := toFloat(a); ok {
+		numberB, ok := toFloat(b)
+		return ok && numberA == numberB
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// jsonType returns the type of the decoded JSON value, as it's named by the schema
+func jsonType(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case json.Number:
+		if number, err := typed.Float64(); err == nil && number == math.Trunc(number) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return fmt.Sprintf("%v", types)
+}
+
+// ValidateJSON returns the fiber.PreRoutingHook, that rejects the http requests, which body doesn't match
+// the schema, with errors.ErrInvalidInput, before the routing strategy selects the routes. The requests
+// of the other protocols are passed as they are
+func ValidateJSON(schema *JSONSchema) fiber.PreRoutingHook {
+	return func(ctx context.Context, req fiber.Request) (context.Context, error) {
+		if _, ok := req.(*Request); !ok {
+			return ctx, nil
+		}
+		if err := schema.Validate(req.Payload()); err != nil {
+			return ctx, fiberError.ErrInvalidInput(protocol.HTTP, fmt.Errorf("request doesn't match the schema: %v", err))
+		}
+		return ctx, nil
+	}
+}
@@ -0,0 +1,128 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	fiberError "github.com/gojek/fiber/errors"
+	fiberHTTP "github.com/gojek/fiber/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const requestSchema = `{
+	"type": "object",
+	"required": ["id", "rows"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "pattern": "^[a-z0-9-]+$"},
+		"mode": {"enum": ["fast", "accurate"]},
+		"rows": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"required": ["value"],
+				"properties": {"value": {"type": "number", "minimum": 0, "maximum": 1}}
+			}
+		}
+	}
+}`
+
+func TestJSONSchema_Validate(t *testing.T) {
+	schema, err := fiberHTTP.NewJSONSchema([]byte(requestSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		document string
+		errMsg   string
+	}{
+		{
+			name:     "valid",
+			document: `{"id": "req-1", "mode": "fast", "rows": [{"value": 0.5}]}`,
+		},
+		{
+			name:     "invalid JSON",
+			document: `{"id": `,
+			errMsg:   "invalid JSON",
+		},
+		{
+			name:     "missing required property",
+			document: `{"id": "req-1"}`,
+			errMsg:   "missing required property [rows]",
+		},
+		{
+			name:     "wrong type",
+			document: `{"id": 1, "rows": [{"value": 0.5}]}`,
+			errMsg:   "$.id: expected type string, got integer",
+		},
+		{
+			name:     "pattern mismatch",
+			document: `{"id": "REQ 1", "rows": [{"value": 0.5}]}`,
+			errMsg:   "$.id",
+		},
+		{
+			name:     "not in enum",
+			document: `{"id": "req-1", "mode": "slow", "rows": [{"value": 0.5}]}`,
+			errMsg:   "$.mode",
+		},
+		{
+			name:     "too few items",
+			document: `{"id": "req-1", "rows": []}`,
+			errMsg:   "$.rows",
+		},
+		{
+			name:     "nested value out of range",
+			document: `{"id": "req-1", "rows": [{"value": 2}]}`,
+			errMsg:   "$.rows[0].value",
+		},
+		{
+			name:     "additional property",
+			document: `{"id": "req-1", "rows": [{"value": 0.5}], "debug": true}`,
+			errMsg:   "debug",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tt.document))
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestNewJSONSchema_Invalid(t *testing.T) {
+	_, err := fiberHTTP.NewJSONSchema([]byte(`{"properties": {"a": {"$ref": "#/definitions/a"}}}`))
+	assert.EqualError(t, err, "json schema: $.properties.a: $ref is not supported")
+}
+
+func TestValidateJSON(t *testing.T) {
+	schema, err := fiberHTTP.NewJSONSchema([]byte(requestSchema))
+	require.NoError(t, err)
+	hook := fiberHTTP.ValidateJSON(schema)
+
+	newRequest := func(body string) *fiberHTTP.Request {
+		httpReq, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		require.NoError(t, err)
+		req, err := fiberHTTP.NewHTTPRequest(httpReq)
+		require.NoError(t, err)
+		return req
+	}
+
+	_, err = hook(context.Background(), newRequest(`{"id": "req-1", "rows": [{"value": 0.5}]}`))
+	assert.NoError(t, err)
+
+	_, err = hook(context.Background(), newRequest(`{"id": "req-1", "rows": "row-1"}`))
+	var fiberErr *fiberError.FiberError
+	require.ErrorAs(t, err, &fiberErr)
+	assert.Equal(t, http.StatusBadRequest, fiberErr.Code)
+	assert.Equal(t, "fiber: request doesn't match the schema: $.rows: expected type array, got string", fiberErr.Message)
+}
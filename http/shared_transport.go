@@ -0,0 +1,92 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+)
+
+// DefaultTransportPool is the TransportPool of the routes, that share their transports (i.e. the routes,
+// initialized from the config with shared_transport)
+var DefaultTransportPool = NewTransportPool()
+
+// TransportPool shares the transports between the http clients of the routes with the same key, e.g. the routes to
+// the same host with the same TLS config, so their requests reuse the connections of a single pool instead of each
+// route establishing its own. The transport is created by the first route, that gets it, and its idle connections
+// are closed, once all the routes have released it
+type TransportPool struct {
+	lock       sync.Mutex
+	transports map[string]*pooledTransport
+}
+
+// pooledTransport is the transport of the TransportPool with the number of the routes, that use it
+type pooledTransport struct {
+	transport http.RoundTripper
+	refs      int
+}
+
+// NewTransportPool creates the empty TransportPool
+func NewTransportPool() *TransportPool {
+	return &TransportPool{transports: make(map[string]*pooledTransport)}
+}
+
+// Get returns the transport of the key, and creates it, if there is none. The returned SharedTransport
+// has to be released with CloseIdleConnections, when the route doesn't use it anymore
+func (p *TransportPool) Get(key string, create func() (http.RoundTripper, error)) (*SharedTransport, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pooled, ok := p.transports[key]
+	if !ok {
+		transport, err := create()
+		if err != nil {
+			return nil, err
+		}
+		pooled = &pooledTransport{transport: transport}
+		p.transports[key] = pooled
+	}
+	pooled.refs++
+	return &SharedTransport{RoundTripper: pooled.transport, pool: p, key: key}, nil
+}
+
+// Len returns the number of the transports, that are shared
+func (p *TransportPool) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.transports)
+}
+
+// release drops the reference of the route to the transport of the key, and closes its idle connections,
+// once no route uses it
+func (p *TransportPool) release(key string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pooled, ok := p.transports[key]
+	if !ok {
+		return
+	}
+	if pooled.refs--; pooled.refs > 0 {
+		return
+	}
+	delete(p.transports, key)
+	if closer, ok := pooled.transport.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// SharedTransport is the route's reference to the transport of the TransportPool
+type SharedTransport struct {
+	http.RoundTripper
+
+	pool     *TransportPool
+	key      string
+	released sync.Once
+}
+
+// CloseIdleConnections releases the route's reference to the transport, so http.Client.CloseIdleConnections
+// doesn't close the connections of the other routes. The idle connections are closed by the last route
+func (t *SharedTransport) CloseIdleConnections() {
+	t.released.Do(func() {
+		t.pool.release(t.key)
+	})
+}
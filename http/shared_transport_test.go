@@ -0,0 +1,67 @@
+package http_test
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	fiberHTTP "github.com/gojek/fiber/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closingTransport is the transport, that records, how many times its idle connections have been closed
+type closingTransport struct {
+	http.RoundTripper
+	closed int32
+}
+
+func (t *closingTransport) CloseIdleConnections() {
+	atomic.AddInt32(&t.closed, 1)
+}
+
+func TestTransportPool(t *testing.T) {
+	pool := fiberHTTP.NewTransportPool()
+	var created []*closingTransport
+	create := func() (http.RoundTripper, error) {
+		transport := &closingTransport{}
+		created = append(created, transport)
+		return transport, nil
+	}
+
+	routeA, err := pool.Get("backend-a", create)
+	require.NoError(t, err)
+	routeB, err := pool.Get("backend-a", create)
+	require.NoError(t, err)
+	routeC, err := pool.Get("backend-c", create)
+	require.NoError(t, err)
+
+	// the routes with the same key share the transport
+	require.Len(t, created, 2)
+	assert.Same(t, created[0], routeA.RoundTripper)
+	assert.Same(t, created[0], routeB.RoundTripper)
+	assert.Same(t, created[1], routeC.RoundTripper)
+	assert.Equal(t, 2, pool.Len())
+
+	// the idle connections are closed by the last route, that releases the transport, only once
+	routeA.CloseIdleConnections()
+	routeA.CloseIdleConnections()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&created[0].closed))
+	routeB.CloseIdleConnections()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&created[0].closed))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&created[1].closed))
+	assert.Equal(t, 1, pool.Len())
+
+	// the released transport is created again for the next route
+	routeD, err := pool.Get("backend-a", create)
+	require.NoError(t, err)
+	require.Len(t, created, 3)
+	assert.Same(t, created[2], routeD.RoundTripper)
+
+	_, err = pool.Get("backend-e", func() (http.RoundTripper, error) {
+		return nil, errors.New("invalid tls config")
+	})
+	assert.EqualError(t, err, "invalid tls config")
+	assert.Equal(t, 2, pool.Len())
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	// DefaultMaxIdleConnsPerHost is the default number of idle (keep-alive) connections to a backend,
+	// that are kept in the pool of the transport
+	DefaultMaxIdleConnsPerHost = 100
+	// DefaultDialTimeout is the default timeout of establishing a connection to a backend
+	DefaultDialTimeout = 30 * time.Second
+	// DefaultKeepAlive is the default interval of the keep-alive probes of the connections to a backend
+	DefaultKeepAlive = 30 * time.Second
+)
+
+// TransportConfig captures the settings of the connection pool to a backend.
+// Zero values are replaced with the defaults of http.DefaultTransport, except for
+// MaxIdleConnsPerHost, that defaults to DefaultMaxIdleConnsPerHost
+type TransportConfig struct {
+	// MaxIdleConns is the maximum number of idle connections across all hosts
+	MaxIdleConns int
+	// MaxIdleConnsPerHost is the maximum number of idle connections to keep per host
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the total number of connections per host. Zero means no limit
+	MaxConnsPerHost int
+	// IdleConnTimeout is the maximum amount of time an idle connection remains in the pool
+	IdleConnTimeout time.Duration
+	// KeepAlive is the interval of the keep-alive probes of the active connections
+	KeepAlive time.Duration
+	// DialTimeout is the timeout of establishing a connection to the backend, defaults to DefaultDialTimeout.
+	// It's separate from the timeout of the request, so a backend, that is slow to connect to, fails fast
+	DialTimeout time.Duration
+	// UnixSockets, if set, are the paths of the unix domain sockets, keyed by the hosts of the request urls
+	// (see UnixSocketHost), that the connections to these hosts are established over instead of tcp
+	UnixSockets map[string]string
+}
+
+// UnixSocketHost returns the host of the urls of the requests to the backend, that listens on the unix domain
+// socket with the given path. The transport connects to the socket, instead of the host, if the socket is
+// in its UnixSockets. The host is derived from the path, so the connections to each socket are pooled separately
+func UnixSocketHost(path string) string {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(path))
+	return fmt.Sprintf("unix-%08x", hash.Sum32())
+}
+
+// NewTransport creates the transport with the connection pool configured according to the given config.
+// The transport should be created once per backend and shared by all requests to it, so the connections
+// are reused instead of being established for every request
+func NewTransport(config TransportConfig) (*http.Transport, error) {
+	if config.MaxIdleConns < 0 || config.MaxIdleConnsPerHost < 0 || config.MaxConnsPerHost < 0 {
+		return nil, errors.New("http transport: number of connections can not be negative")
+	}
+	if config.IdleConnTimeout < 0 || config.KeepAlive < 0 || config.DialTimeout < 0 {
+		return nil, errors.New("http transport: timeouts can not be negative")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = newDialContext(config)
+
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+	return transport, nil
+}
+
+// NewHTTP2Transport creates the transport, that sends the requests to the backend over HTTP/2. Without the TLS
+// config, the requests are sent over the cleartext connections with prior knowledge (h2c), otherwise HTTP/2 is
+// negotiated with ALPN. The requests are multiplexed over a single connection to each host, so only the dialing
+// settings of the config apply: DialTimeout, KeepAlive and UnixSockets
+func NewHTTP2Transport(config TransportConfig, tlsConfig *tls.Config) (*http2.Transport, error) {
+	if config.IdleConnTimeout < 0 || config.KeepAlive < 0 || config.DialTimeout < 0 {
+		return nil, errors.New("http transport: timeouts can not be negative")
+	}
+
+	dialContext := newDialContext(config)
+	if tlsConfig == nil {
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, address string, _ *tls.Config) (net.Conn, error) {
+				return dialContext(context.Background(), network, address)
+			},
+		}, nil
+	}
+
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.NextProtos = []string{http2.NextProtoTLS}
+	return &http2.Transport{
+		TLSClientConfig: tlsConfig,
+		DialTLS: func(network, address string, cfg *tls.Config) (net.Conn, error) {
+			conn, err := dialContext(context.Background(), network, address)
+			if err != nil {
+				return nil, err
+			}
+			if cfg.ServerName == "" {
+				cfg = cfg.Clone()
+				cfg.ServerName, _, _ = net.SplitHostPort(address)
+			}
+			tlsConn := tls.Client(conn, cfg)
+			if err = tlsConn.Handshake(); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+			if protocol := tlsConn.ConnectionState().NegotiatedProtocol; protocol != http2.NextProtoTLS {
+				_ = conn.Close()
+				return nil, fmt.Errorf("http transport: backend [%s] doesn't support HTTP/2", address)
+			}
+			return tlsConn, nil
+		},
+	}, nil
+}
+
+// newDialContext returns the function, that establishes the connections to the backend according to the config
+func newDialContext(config TransportConfig) func(ctx context.Context, network, address string) (net.Conn, error) {
+	keepAlive := DefaultKeepAlive
+	if config.KeepAlive > 0 {
+		keepAlive = config.KeepAlive
+	}
+	dialTimeout := DefaultDialTimeout
+	if config.DialTimeout > 0 {
+		dialTimeout = config.DialTimeout
+	}
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: keepAlive,
+	}
+	if len(config.UnixSockets) == 0 {
+		return dialer.DialContext
+	}
+
+	sockets := config.UnixSockets
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			if socket, ok := sockets[host]; ok {
+				return dialer.DialContext(ctx, "unix", socket)
+			}
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+}
@@ -0,0 +1,243 @@
+package http_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	fiberHTTP "github.com/gojek/fiber/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestNewTransport(t *testing.T) {
+	suite := map[string]struct {
+		config                      fiberHTTP.TransportConfig
+		expectedMaxIdleConns        int
+		expectedMaxIdleConnsPerHost int
+		expectedMaxConnsPerHost     int
+		expectedIdleConnTimeout     time.Duration
+		expectedErr                 string
+	}{
+		"defaults": {
+			expectedMaxIdleConns:        http.DefaultTransport.(*http.Transport).MaxIdleConns,
+			expectedMaxIdleConnsPerHost: fiberHTTP.DefaultMaxIdleConnsPerHost,
+			expectedIdleConnTimeout:     http.DefaultTransport.(*http.Transport).IdleConnTimeout,
+		},
+		"configured pool": {
+			config: fiberHTTP.TransportConfig{
+				MaxIdleConns:        20,
+				MaxIdleConnsPerHost: 10,
+				MaxConnsPerHost:     15,
+				IdleConnTimeout:     time.Minute,
+				KeepAlive:           time.Second,
+			},
+			expectedMaxIdleConns:        20,
+			expectedMaxIdleConnsPerHost: 10,
+			expectedMaxConnsPerHost:     15,
+			expectedIdleConnTimeout:     time.Minute,
+		},
+		"negative number of connections": {
+			config:      fiberHTTP.TransportConfig{MaxConnsPerHost: -1},
+			expectedErr: "http transport: number of connections can not be negative",
+		},
+		"negative timeout": {
+			config:      fiberHTTP.TransportConfig{IdleConnTimeout: -time.Second},
+			expectedErr: "http transport: timeouts can not be negative",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			transport, err := fiberHTTP.NewTransport(tt.config)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedMaxIdleConns, transport.MaxIdleConns)
+			assert.Equal(t, tt.expectedMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+			assert.Equal(t, tt.expectedMaxConnsPerHost, transport.MaxConnsPerHost)
+			assert.Equal(t, tt.expectedIdleConnTimeout, transport.IdleConnTimeout)
+		})
+	}
+}
+
+// newCountingServer starts the test server, that counts the connections established to it
+func newCountingServer(t testing.TB) (*httptest.Server, *int64) {
+	var connections int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&connections, 1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &connections
+}
+
+func TestNewTransport_ConnectionsReused(t *testing.T) {
+	suite := map[string]struct {
+		config             fiberHTTP.TransportConfig
+		concurrency        int
+		maxConnections     int64
+		requestsPerRoutine int
+	}{
+		"sequential requests": {
+			concurrency:        1,
+			maxConnections:     1,
+			requestsPerRoutine: 20,
+		},
+		"concurrent requests": {
+			config:             fiberHTTP.TransportConfig{MaxIdleConnsPerHost: 4, MaxConnsPerHost: 4},
+			concurrency:        8,
+			maxConnections:     4,
+			requestsPerRoutine: 10,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			server, connections := newCountingServer(t)
+			transport, err := fiberHTTP.NewTransport(tt.config)
+			require.NoError(t, err)
+			defer transport.CloseIdleConnections()
+
+			dispatcher, err := fiberHTTP.NewDispatcher(&http.Client{Transport: transport})
+			require.NoError(t, err)
+
+			var wg sync.WaitGroup
+			for routine := 0; routine < tt.concurrency; routine++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for idx := 0; idx < tt.requestsPerRoutine; idx++ {
+						httpReq, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+						req, _ := fiberHTTP.NewHTTPRequest(httpReq)
+						resp := dispatcher.Do(context.Background(), req)
+						assert.True(t, resp.IsSuccess())
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.LessOrEqual(t, atomic.LoadInt64(connections), tt.maxConnections)
+		})
+	}
+}
+
+func TestNewTransport_UnixSockets(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "backend.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK " + r.URL.Path))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	host := fiberHTTP.UnixSocketHost(socket)
+	transport, err := fiberHTTP.NewTransport(fiberHTTP.TransportConfig{UnixSockets: map[string]string{host: socket}})
+	require.NoError(t, err)
+	defer transport.CloseIdleConnections()
+
+	dispatcher, err := fiberHTTP.NewDispatcher(&http.Client{Transport: transport})
+	require.NoError(t, err)
+	httpReq, _ := http.NewRequest(http.MethodGet, "http://"+host+"/predict", nil)
+	req, _ := fiberHTTP.NewHTTPRequest(httpReq)
+	resp := dispatcher.Do(context.Background(), req)
+	require.True(t, resp.IsSuccess(), string(resp.Payload()))
+	assert.Equal(t, "OK /predict", string(resp.Payload()))
+
+	// the connections to each socket are pooled separately
+	assert.NotEqual(t, host, fiberHTTP.UnixSocketHost(socket+".other"))
+}
+
+func TestNewHTTP2Transport(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})
+	dispatch := func(t *testing.T, transport http.RoundTripper, url string) {
+		dispatcher, err := fiberHTTP.NewDispatcher(&http.Client{Transport: transport})
+		require.NoError(t, err)
+		httpReq, _ := http.NewRequest(http.MethodGet, url, nil)
+		req, _ := fiberHTTP.NewHTTPRequest(httpReq)
+		resp := dispatcher.Do(context.Background(), req)
+		require.True(t, resp.IsSuccess(), string(resp.Payload()))
+		assert.Equal(t, "HTTP/2.0", string(resp.Payload()))
+	}
+
+	t.Run("h2c", func(t *testing.T) {
+		server := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+		defer server.Close()
+
+		transport, err := fiberHTTP.NewHTTP2Transport(fiberHTTP.TransportConfig{}, nil)
+		require.NoError(t, err)
+		defer transport.CloseIdleConnections()
+		dispatch(t, transport, server.URL)
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(handler)
+		server.EnableHTTP2 = true
+		server.StartTLS()
+		defer server.Close()
+
+		roots := x509.NewCertPool()
+		roots.AddCert(server.Certificate())
+		transport, err := fiberHTTP.NewHTTP2Transport(fiberHTTP.TransportConfig{}, &tls.Config{RootCAs: roots})
+		require.NoError(t, err)
+		defer transport.CloseIdleConnections()
+		dispatch(t, transport, server.URL)
+	})
+
+	_, err := fiberHTTP.NewHTTP2Transport(fiberHTTP.TransportConfig{DialTimeout: -time.Second}, nil)
+	assert.EqualError(t, err, "http transport: timeouts can not be negative")
+}
+
+func BenchmarkDispatcher_Do(b *testing.B) {
+	server, _ := newCountingServer(b)
+	dispatch := func(b *testing.B, client *http.Client) {
+		dispatcher, _ := fiberHTTP.NewDispatcher(client)
+		httpReq, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req, _ := fiberHTTP.NewHTTPRequest(httpReq)
+		if resp := dispatcher.Do(context.Background(), req); !resp.IsSuccess() {
+			b.Fatalf("unexpected response: %d", resp.StatusCode())
+		}
+	}
+
+	b.Run("transport per dispatch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			transport, _ := fiberHTTP.NewTransport(fiberHTTP.TransportConfig{})
+			dispatch(b, &http.Client{Transport: transport})
+			transport.CloseIdleConnections()
+		}
+	})
+
+	b.Run("pooled transport", func(b *testing.B) {
+		transport, _ := fiberHTTP.NewTransport(fiberHTTP.TransportConfig{})
+		defer transport.CloseIdleConnections()
+		client := &http.Client{Transport: transport}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			dispatch(b, client)
+		}
+	})
+}
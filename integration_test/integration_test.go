@@ -3,10 +3,14 @@ package integration_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
@@ -20,10 +24,13 @@ import (
 	"github.com/gojek/fiber/internal/testutils"
 	testGrpcUtils "github.com/gojek/fiber/internal/testutils/grpc"
 	"github.com/gojek/fiber/protocol"
+	"github.com/gojek/fiber/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -75,6 +82,10 @@ var (
 	}
 	grpcResponse2 = &testproto.PredictValuesResponse{}
 	grpcResponse3 = &testproto.PredictValuesResponse{}
+
+	// the backends, that fail with the retryable and terminal statuses
+	grpcPortUnavailable     = 50558
+	grpcPortInvalidArgument = 50559
 )
 
 func TestMain(m *testing.M) {
@@ -88,6 +99,9 @@ func TestMain(m *testing.M) {
 	runTestGrpcServer(grpcPort2, grpcResponse2, 0)
 	runTestGrpcServer(grpcPort3, grpcResponse3, 10)
 
+	runFailingGrpcServer(grpcPortUnavailable, status.Error(codes.Unavailable, "backend is unavailable"))
+	runFailingGrpcServer(grpcPortInvalidArgument, status.Error(codes.InvalidArgument, "invalid prediction rows"))
+
 	os.Exit(m.Run())
 }
 
@@ -117,6 +131,13 @@ func runTestGrpcServer(port int, response *testproto.PredictValuesResponse, dela
 	})
 }
 
+func runFailingGrpcServer(port int, err error) {
+	testGrpcUtils.RunTestUPIServer(testGrpcUtils.GrpcTestServer{
+		Port:      port,
+		MockError: err,
+	})
+}
+
 func TestE2EFromConfig(t *testing.T) {
 	bytePayload, _ := proto.Marshal(&testproto.PredictValuesRequest{
 		PredictionRows: []*testproto.PredictionRow{
@@ -260,6 +281,7 @@ func TestE2EFromConfig(t *testing.T) {
 	}
 
 	for _, tt := range tests {
+		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -281,7 +303,13 @@ func TestE2EFromConfig(t *testing.T) {
 			require.True(t, ok)
 
 			if tt.expectedFiberErr != nil {
-				assert.EqualValues(t, tt.expectedFiberErr, resp)
+				assert.Equal(t, tt.expectedFiberErr.StatusCode(), resp.StatusCode())
+				assert.Equal(t, tt.expectedFiberErr.Payload(), resp.Payload())
+				// the route has timed out, so its failure is captured in the response
+				errResp, ok := resp.(*fiber.ErrorResponse)
+				require.True(t, ok)
+				require.Len(t, errResp.RouteErrors(), 1)
+				assert.Equal(t, route3, errResp.RouteErrors()[0].RouteID)
 			} else {
 				require.Equal(t, resp.StatusCode(), tt.expectedResponse.StatusCode())
 				if tt.request.Protocol() == protocol.GRPC {
@@ -298,6 +326,499 @@ func TestE2EFromConfig(t *testing.T) {
 	}
 }
 
+func TestE2EGrpcStatusFallback(t *testing.T) {
+	bytePayload, _ := proto.Marshal(&testproto.PredictValuesRequest{
+		PredictionRows: []*testproto.PredictionRow{
+			{
+				RowId: "1",
+			},
+		},
+	})
+	route1 := "route1"
+	unavailableRoute := "unavailable_route"
+	invalidArgumentRoute := "invalid_argument_route"
+
+	tests := []struct {
+		name                 string
+		configPath           string
+		routesOrder          []string
+		expectedCode         codes.Code
+		expectedMessageProto *testproto.PredictValuesResponse
+	}{
+		{
+			name:                 "retryable status, route 1 fallback returned",
+			configPath:           "./fibergrpcstatus.yaml",
+			routesOrder:          []string{unavailableRoute, route1},
+			expectedCode:         codes.OK,
+			expectedMessageProto: grpcResponse1,
+		},
+		{
+			name:         "terminal status returned without fallback",
+			configPath:   "./fibergrpcstatus.yaml",
+			routesOrder:  []string{invalidArgumentRoute, route1},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "retryable status, terminal status of the fallback returned",
+			configPath:   "./fibergrpcstatus.yaml",
+			routesOrder:  []string{unavailableRoute, invalidArgumentRoute, route1},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:                 "overridden: terminal status, route 1 fallback returned",
+			configPath:           "./fibergrpcstatusoverride.yaml",
+			routesOrder:          []string{invalidArgumentRoute, route1},
+			expectedCode:         codes.OK,
+			expectedMessageProto: grpcResponse1,
+		},
+		{
+			name:                 "overridden: retryable status, route 1 fallback returned",
+			configPath:           "./fibergrpcstatusoverride.yaml",
+			routesOrder:          []string{unavailableRoute, route1},
+			expectedCode:         codes.OK,
+			expectedMessageProto: grpcResponse1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			component, err := config.InitComponentFromConfig(tt.configPath)
+			require.NoError(t, err)
+			router, ok := component.(*fiber.EagerRouter)
+			require.True(t, ok)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(router.GetRoutes(), tt.routesOrder, 0, nil))
+
+			resp, ok := <-router.Dispatch(context.Background(), &grpc.Request{Message: bytePayload}).Iter()
+			require.True(t, ok)
+			require.Equal(t, int(tt.expectedCode), resp.StatusCode())
+			if tt.expectedMessageProto != nil {
+				responseProto := &testproto.PredictValuesResponse{}
+				require.NoError(t, proto.Unmarshal(resp.Payload(), responseProto))
+				assert.True(t, proto.Equal(tt.expectedMessageProto, responseProto), "actual proto response don't match expected")
+			}
+		})
+	}
+}
+
+// responseTransformers wrap the http responses into the envelope, and unwrap the predictions of the
+// grpc responses, moving the model name into the metadata. Both fail, if the request asks for it
+func responseTransformers() []fiber.Interceptor {
+	return []fiber.Interceptor{
+		fiberhttp.NewResponseTransformer(func(_ context.Context, req fiber.Request, resp *fiberhttp.Response) error {
+			if len(req.Header()["Fail-Transform"]) > 0 {
+				return errors.New("failed to transform the response")
+			}
+			resp.Header().Set("X-Route", resp.BackendName())
+			resp.WithStatusCode(http.StatusNonAuthoritativeInfo).
+				WithPayload([]byte(fmt.Sprintf(`{"data": "%s"}`, resp.Payload())))
+			return nil
+		}),
+		grpc.NewResponseTransformer(func(_ context.Context, req fiber.Request, resp *grpc.Response) error {
+			if len(req.Header()["fail-transform"]) > 0 {
+				return errors.New("failed to transform the response")
+			}
+			msg := &testproto.PredictValuesResponse{}
+			if err := proto.Unmarshal(resp.Message, msg); err != nil {
+				return err
+			}
+			payload, err := proto.Marshal(&testproto.PredictValuesResponse{Predictions: msg.Predictions})
+			if err != nil {
+				return err
+			}
+			resp.Message = payload
+			resp.Metadata.Set("model-name", msg.GetMetadata().GetModelName())
+			return nil
+		}),
+	}
+}
+
+func TestE2EHTTPResponseTransform(t *testing.T) {
+	tests := []struct {
+		name             string
+		routesOrder      []string
+		header           http.Header
+		expectedStatus   int
+		expectedBody     string
+		expectedRoute    string
+		expectedFiberErr fiber.Response
+	}{
+		{
+			name:           "primary route transformed",
+			routesOrder:    []string{"route1", "route2"},
+			expectedStatus: http.StatusNonAuthoritativeInfo,
+			expectedBody:   `{"data": "response 1"}`,
+			expectedRoute:  "route1",
+		},
+		{
+			name:           "fallback transformed",
+			routesOrder:    []string{"route3", "route2"},
+			expectedStatus: http.StatusNonAuthoritativeInfo,
+			expectedBody:   `{"data": "response 2"}`,
+			expectedRoute:  "route2",
+		},
+		{
+			name:           "transform failed",
+			routesOrder:    []string{"route1"},
+			header:         http.Header{"Fail-Transform": []string{"true"}},
+			expectedStatus: http.StatusInternalServerError,
+			expectedFiberErr: fiber.NewErrorResponse(
+				fiberError.NewFiberError(protocol.HTTP, errors.New("failed to transform the response"))),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			component, err := config.InitComponentFromConfig("./fiberhttp.yaml")
+			require.NoError(t, err)
+			router, ok := component.(*fiber.EagerRouter)
+			require.True(t, ok)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(router.GetRoutes(), tt.routesOrder, 0, nil))
+			router.AddInterceptor(false, responseTransformers()...)
+
+			httpReq, err := http.NewRequest(http.MethodGet, "", ioutil.NopCloser(bytes.NewReader([]byte{})))
+			require.NoError(t, err)
+			httpReq.Header = tt.header
+			req, err := fiberhttp.NewHTTPRequest(httpReq)
+			require.NoError(t, err)
+
+			resp, ok := <-router.Dispatch(context.Background(), req).Iter()
+			require.True(t, ok)
+			require.Equal(t, tt.expectedStatus, resp.StatusCode())
+			if tt.expectedFiberErr != nil {
+				assert.EqualValues(t, tt.expectedFiberErr, resp)
+				return
+			}
+
+			httpResp, ok := resp.(*fiberhttp.Response)
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedBody, string(httpResp.Payload()))
+			assert.Equal(t, tt.expectedRoute, httpResp.Header().Get("X-Route"))
+			assert.Empty(t, httpResp.Header().Get("Content-Length"))
+		})
+	}
+}
+
+func TestE2EGrpcResponseTransform(t *testing.T) {
+	bytePayload, _ := proto.Marshal(&testproto.PredictValuesRequest{
+		PredictionRows: []*testproto.PredictionRow{
+			{
+				RowId: "1",
+			},
+		},
+	})
+
+	tests := []struct {
+		name              string
+		routesOrder       []string
+		metadata          metadata.MD
+		expectedCode      codes.Code
+		expectedMessage   *testproto.PredictValuesResponse
+		expectedModelName string
+	}{
+		{
+			name:              "primary route transformed",
+			routesOrder:       []string{"route1", "route2"},
+			expectedCode:      codes.OK,
+			expectedMessage:   &testproto.PredictValuesResponse{Predictions: grpcResponse1.Predictions},
+			expectedModelName: "linear",
+		},
+		{
+			name:              "fallback transformed",
+			routesOrder:       []string{"route3", "route1"},
+			expectedCode:      codes.OK,
+			expectedMessage:   &testproto.PredictValuesResponse{Predictions: grpcResponse1.Predictions},
+			expectedModelName: "linear",
+		},
+		{
+			name:         "transform failed",
+			routesOrder:  []string{"route1"},
+			metadata:     metadata.Pairs("fail-transform", "true"),
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			component, err := config.InitComponentFromConfig("./fibergrpc.yaml")
+			require.NoError(t, err)
+			router, ok := component.(*fiber.EagerRouter)
+			require.True(t, ok)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(router.GetRoutes(), tt.routesOrder, 0, nil))
+			router.AddInterceptor(false, responseTransformers()...)
+
+			resp, ok := <-router.Dispatch(
+				context.Background(),
+				&grpc.Request{Message: bytePayload, Metadata: tt.metadata},
+			).Iter()
+			require.True(t, ok)
+			require.Equal(t, int(tt.expectedCode), resp.StatusCode())
+			if tt.expectedMessage == nil {
+				return
+			}
+
+			grpcResp, ok := resp.(*grpc.Response)
+			require.True(t, ok)
+			responseProto := &testproto.PredictValuesResponse{}
+			require.NoError(t, proto.Unmarshal(grpcResp.Payload(), responseProto))
+			assert.True(t, proto.Equal(tt.expectedMessage, responseProto), "actual proto response don't match expected")
+			assert.Equal(t, []string{tt.expectedModelName}, grpcResp.Metadata.Get("model-name"))
+		})
+	}
+}
+
+func TestE2EGrpcTranscoding(t *testing.T) {
+	expectedBody, err := protojson.Marshal(grpcResponse1)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		routesOrder    []string
+		body           string
+		expectedStatus int
+		expectedBody   []byte
+		// expectedRouteCode is the status code of the failed route, that the error response carries
+		expectedRouteCode int
+	}{
+		{
+			name:           "json response transcoded from grpc",
+			routesOrder:    []string{"route1", "invalid_argument_route"},
+			body:           `{"predictionRows": [{"rowId": "1"}]}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   expectedBody,
+		},
+		{
+			name:              "grpc status transcoded into http status",
+			routesOrder:       []string{"invalid_argument_route"},
+			body:              `{"predictionRows": [{"rowId": "1"}]}`,
+			expectedStatus:    http.StatusBadRequest,
+			expectedRouteCode: http.StatusBadRequest,
+		},
+		{
+			name:              "invalid json",
+			routesOrder:       []string{"route1"},
+			body:              `{"unknownField": 1}`,
+			expectedStatus:    http.StatusBadRequest,
+			expectedRouteCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			component, err := config.InitComponentFromConfig("./fibergrpctranscoding.yaml")
+			require.NoError(t, err)
+			router, ok := component.(*fiber.EagerRouter)
+			require.True(t, ok)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(router.GetRoutes(), tt.routesOrder, 0, nil))
+			handler := fiberhttp.NewHandler(router, fiberhttp.Options{Timeout: 5 * time.Second, RouteErrorDetails: true})
+
+			httpReq := httptest.NewRequest(http.MethodPost, "/predict", bytes.NewReader([]byte(tt.body)))
+			httpReq.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httpReq)
+
+			require.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedBody != nil {
+				assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+				assert.JSONEq(t, string(tt.expectedBody), recorder.Body.String())
+			}
+			if tt.expectedRouteCode != 0 {
+				var body struct {
+					RouteErrors []fiberError.RouteError `json:"route_errors"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+				require.Len(t, body.RouteErrors, 1)
+				assert.Equal(t, tt.expectedRouteCode, body.RouteErrors[0].Code)
+			}
+		})
+	}
+}
+
+// completionRecorder records the last response of the route and the time, its dispatch has completed at
+type completionRecorder struct {
+	fiber.Component
+	completed chan routeCompletion
+}
+
+type routeCompletion struct {
+	at   time.Time
+	resp fiber.Response
+}
+
+func (r *completionRecorder) Dispatch(ctx context.Context, req fiber.Request) fiber.ResponseQueue {
+	in := r.Component.Dispatch(ctx, req).Iter()
+	out := make(chan fiber.Response, 1)
+	go func() {
+		defer close(out)
+		var last fiber.Response
+		for resp := range in {
+			last = resp
+			// the fan-out doesn't read the responses of the cancelled routes
+			select {
+			case out <- resp:
+			default:
+			}
+		}
+		r.completed <- routeCompletion{at: time.Now(), resp: last}
+	}()
+	return fiber.NewResponseQueue(out, 1)
+}
+
+func TestE2ECancelLosingRoutes(t *testing.T) {
+	bytePayload, _ := proto.Marshal(&testproto.PredictValuesRequest{
+		PredictionRows: []*testproto.PredictionRow{
+			{
+				RowId: "1",
+			},
+		},
+	})
+	httpReq, err := http.NewRequest(http.MethodGet, "", ioutil.NopCloser(bytes.NewReader([]byte{})))
+	require.NoError(t, err)
+	httpRequest, err := fiberhttp.NewHTTPRequest(httpReq)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		configPath string
+		request    fiber.Request
+	}{
+		{
+			name:       "http",
+			configPath: "./fiberhttp.yaml",
+			request:    httpRequest,
+		},
+		{
+			name:       "grpc",
+			configPath: "./fibergrpc.yaml",
+			request:    &grpc.Request{Message: bytePayload},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			component, err := config.InitComponentFromConfig(tt.configPath)
+			require.NoError(t, err)
+			router, ok := component.(*fiber.EagerRouter)
+			require.True(t, ok)
+
+			// route3 is delayed for 10 seconds, longer than its timeout of 2 seconds
+			routes := router.GetRoutes()
+			slowRoute := &completionRecorder{Component: routes["route3"], completed: make(chan routeCompletion, 1)}
+			routes["route3"] = slowRoute
+			router.SetRoutes(routes)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route1", "route2", "route3"}, 0, nil))
+
+			resp, ok := <-router.Dispatch(context.Background(), tt.request).Iter()
+			require.True(t, ok)
+			require.True(t, resp.IsSuccess())
+			assert.Equal(t, "route1", resp.BackendName())
+			won := time.Now()
+
+			select {
+			case completion := <-slowRoute.completed:
+				// the call is aborted, instead of waiting for the backend or the timeout
+				assert.Less(t, int64(completion.at.Sub(won)), int64(500*time.Millisecond))
+				require.NotNil(t, completion.resp)
+				assert.False(t, completion.resp.IsSuccess())
+				if tt.request.Protocol() == protocol.GRPC {
+					assert.Equal(t, int(codes.Canceled), completion.resp.StatusCode())
+				} else {
+					assert.Contains(t, string(completion.resp.Payload()), context.Canceled.Error())
+				}
+			case <-time.After(time.Second):
+				assert.Fail(t, "the call of the slow route hasn't been cancelled after the fast one has won")
+			}
+		})
+	}
+}
+
+// recordedDispatches are the dispatches, recorded by recordingMetrics, that are created from the config
+var recordedDispatches = make(chan int, 10)
+
+// recordingMetrics records the status codes of the dispatches
+type recordingMetrics struct {
+	fiber.BaseFiberType
+}
+
+func (m *recordingMetrics) RecordDispatch(_ string, _ protocol.Protocol, statusCode int, _ time.Duration) {
+	recordedDispatches <- statusCode
+}
+
+// countingInterceptor counts the completed dispatches of the component
+type countingInterceptor struct {
+	fiber.NoopBeforeDispatchInterceptor
+	fiber.NoopAfterDispatchInterceptor
+	completed chan fiber.Response
+}
+
+func (i *countingInterceptor) AfterCompletion(_ context.Context, _ fiber.Request, queue fiber.ResponseQueue) {
+	for resp := range queue.Iter() {
+		i.completed <- resp
+	}
+}
+
+func TestE2EPassthroughProxy(t *testing.T) {
+	require.NoError(t, types.InstallType("integration_test.recordingMetrics", &recordingMetrics{}))
+	newRequest := func() fiber.Request {
+		httpReq, err := http.NewRequest(http.MethodGet, "", ioutil.NopCloser(bytes.NewReader([]byte{})))
+		require.NoError(t, err)
+		httpRequest, err := fiberhttp.NewHTTPRequest(httpReq)
+		require.NoError(t, err)
+		return httpRequest
+	}
+
+	passthrough, err := config.InitComponentFromConfig("./fiberhttppassthrough.yaml")
+	require.NoError(t, err)
+	router, err := config.InitComponentFromConfig("./fiberhttpsingleroute.yaml")
+	require.NoError(t, err)
+
+	// the proxy dispatches the request directly, without the routing strategy and the fallbacks
+	_, isRouter := passthrough.(fiber.Router)
+	assert.False(t, isRouter)
+	assert.Empty(t, fiber.DescribeComponent(passthrough).Routes)
+
+	interceptor := &countingInterceptor{completed: make(chan fiber.Response, 1)}
+	passthrough.AddInterceptor(false, interceptor)
+
+	// the proxy responds the same way as the router with the single route
+	expected := testutils.Dispatch(context.Background(), router, newRequest())
+	responses := testutils.Dispatch(context.Background(), passthrough, newRequest())
+	require.Len(t, expected, 1)
+	require.Len(t, responses, 1)
+	assert.True(t, responses[0].IsSuccess())
+	assert.Equal(t, expected[0].StatusCode(), responses[0].StatusCode())
+	assert.Equal(t, expected[0].Payload(), responses[0].Payload())
+	assert.Equal(t, httpResponse1, responses[0].Payload())
+
+	// the dispatch is observed by the interceptors and the metrics of the proxy
+	select {
+	case resp := <-interceptor.completed:
+		assert.Equal(t, httpResponse1, resp.Payload())
+	case <-time.After(time.Second):
+		assert.Fail(t, "the dispatch of the proxy hasn't been intercepted")
+	}
+	select {
+	case status := <-recordedDispatches:
+		assert.Equal(t, http.StatusOK, status)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the dispatch of the proxy hasn't been recorded")
+	}
+}
+
 func makeBody(body []byte) io.ReadCloser {
 	return ioutil.NopCloser(bytes.NewReader(body))
 }
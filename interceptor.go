@@ -12,6 +12,14 @@ var (
 	CtxComponentIDKey CtxKey = "CTX_COMPONENT_ID"
 	// CtxComponentKindKey is used to denote the component's kind in the request context
 	CtxComponentKindKey CtxKey = "CTX_COMPONENT_KIND"
+	// CtxRoutingStrategyKey is used to denote the type name of the routing strategy of the router,
+	// that dispatches the request, in the request context
+	CtxRoutingStrategyKey CtxKey = "CTX_ROUTING_STRATEGY"
+	// CtxCorrelationIDKey is used to denote the correlation id of the request in the request context
+	CtxCorrelationIDKey CtxKey = "CTX_CORRELATION_ID"
+	// CtxTimeoutKey is used to denote the timeout of the request, that overrides the timeouts of the
+	// dispatchers, in the request context (see WithRequestTimeout)
+	CtxTimeoutKey CtxKey = "CTX_TIMEOUT"
 )
 
 // Interceptor is the interface for a structural interceptor
@@ -0,0 +1,92 @@
+package fiber
+
+import (
+	"context"
+	"net/textproto"
+	"strings"
+
+	"github.com/gojek/fiber/protocol"
+)
+
+// RequestInterceptor is implemented by the Interceptor-s, that transform the request before it's dispatched
+// by the component, e.g. to inject the auth tokens, or strip the sensitive headers. The requests are passed
+// through the interceptors of the component in the order they were added (see BaseComponent.AddInterceptor).
+// If the error is returned, the request is not dispatched, and the error is sent back as the response
+type RequestInterceptor interface {
+	InterceptRequest(ctx context.Context, req Request) (Request, error)
+}
+
+// RequestInterceptorFunc is an adapter to use the function as the Interceptor, that transforms the request
+// (see RequestInterceptor)
+func RequestInterceptorFunc(f func(ctx context.Context, req Request) (Request, error)) Interceptor {
+	return &requestInterceptorFunc{intercept: f}
+}
+
+type requestInterceptorFunc struct {
+	NoopBeforeDispatchInterceptor
+	NoopAfterDispatchInterceptor
+	NoopAfterCompletionInterceptor
+
+	intercept func(ctx context.Context, req Request) (Request, error)
+}
+
+// InterceptRequest calls the function
+func (i *requestInterceptorFunc) InterceptRequest(ctx context.Context, req Request) (Request, error) {
+	return i.intercept(ctx, req)
+}
+
+// ResponseInterceptor is implemented by the Interceptor-s, that transform each response of the component,
+// e.g. to redact its fields. The responses are passed through the interceptors of the component in the order
+// they were added (see BaseComponent.AddInterceptor)
+type ResponseInterceptor interface {
+	InterceptResponse(ctx context.Context, req Request, resp Response) Response
+}
+
+// ResponseInterceptorFunc is an adapter to use the function as the Interceptor, that transforms the responses
+// (see ResponseInterceptor)
+func ResponseInterceptorFunc(f func(ctx context.Context, req Request, resp Response) Response) Interceptor {
+	return &responseInterceptorFunc{intercept: f}
+}
+
+type responseInterceptorFunc struct {
+	NoopBeforeDispatchInterceptor
+	NoopAfterDispatchInterceptor
+	NoopAfterCompletionInterceptor
+
+	intercept func(ctx context.Context, req Request, resp Response) Response
+}
+
+// InterceptResponse calls the function
+func (i *responseInterceptorFunc) InterceptResponse(ctx context.Context, req Request, resp Response) Response {
+	return i.intercept(ctx, req, resp)
+}
+
+// SetRequestHeader sets the header of the http request, or the metadata of the grpc request, replacing
+// its existing values. The key is canonicalized according to the protocol of the request
+func SetRequestHeader(req Request, key string, values ...string) {
+	if setter, ok := req.(headerSetter); ok {
+		setter.SetHeader(key, values...)
+		return
+	}
+	req.Header()[headerKey(req.Protocol(), key)] = values
+}
+
+// headerSetter is implemented by the requests and the responses, which headers (or grpc metadata) can be set,
+// e.g. grpc.Request, that initializes its metadata on the first update
+type headerSetter interface {
+	SetHeader(key string, values ...string)
+}
+
+// DeleteRequestHeader deletes the header of the http request, or the metadata of the grpc request
+func DeleteRequestHeader(req Request, key string) {
+	delete(req.Header(), headerKey(req.Protocol(), key))
+}
+
+// headerKey returns the key, that the header is stored by in the headers of the request: the canonical
+// http header key, or the lower-cased grpc metadata key
+func headerKey(proto protocol.Protocol, key string) string {
+	if proto == protocol.GRPC {
+		return strings.ToLower(key)
+	}
+	return textproto.CanonicalMIMEHeaderKey(key)
+}
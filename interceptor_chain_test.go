@@ -0,0 +1,227 @@
+package fiber_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gojek/fiber"
+	fiberGRPC "github.com/gojek/fiber/grpc"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// headerRecordingDispatcher responds with the given response and records the headers of the request
+type headerRecordingDispatcher struct {
+	response fiber.Response
+
+	lock   sync.Mutex
+	header map[string][]string
+}
+
+func (d *headerRecordingDispatcher) Do(_ context.Context, req fiber.Request) fiber.Response {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.header = make(map[string][]string)
+	for key, values := range req.Header() {
+		d.header[key] = values
+	}
+	return d.response
+}
+
+// redactedResponse hides the secrets in the payload of the response
+type redactedResponse struct {
+	fiber.Response
+}
+
+func (r *redactedResponse) Payload() []byte {
+	return []byte(strings.ReplaceAll(string(r.Response.Payload()), "secret", "[redacted]"))
+}
+
+func TestInterceptors_Dispatch(t *testing.T) {
+	suite := map[string]struct {
+		request        fiber.Request
+		response       fiber.Response
+		expectedHeader map[string][]string
+	}{
+		"http headers": {
+			request: func() fiber.Request {
+				req := testUtilsHttp.MockReq("GET", "http://localhost:8080/chain", "")
+				req.Header()["X-Internal-Secret"] = []string{"password"}
+				return req
+			}(),
+			response: testUtilsHttp.MockResp(http.StatusOK, "user: secret", nil, nil),
+			expectedHeader: map[string][]string{
+				"Authorization": {"Bearer token"},
+			},
+		},
+		"grpc metadata": {
+			request: &fiberGRPC.Request{Metadata: metadata.Pairs("x-internal-secret", "password")},
+			response: &fiberGRPC.Response{
+				Metadata: metadata.MD{},
+				Message:  []byte("user: secret"),
+				Status:   *status.New(codes.OK, ""),
+			},
+			expectedHeader: map[string][]string{
+				"authorization": {"Bearer token"},
+			},
+		},
+		"grpc request without metadata": {
+			request: &fiberGRPC.Request{},
+			response: &fiberGRPC.Response{
+				Metadata: metadata.MD{},
+				Message:  []byte("user: secret"),
+				Status:   *status.New(codes.OK, ""),
+			},
+			expectedHeader: map[string][]string{
+				"authorization": {"Bearer token"},
+			},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			dispatcher := &headerRecordingDispatcher{response: tt.response}
+			caller, err := fiber.NewCaller("route-a", dispatcher)
+			require.NoError(t, err)
+			routes := map[string]fiber.Component{"route-a": caller}
+			router := fiber.NewLazyRouter("router")
+			router.SetRoutes(routes)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a"}, 0, nil))
+
+			var order []string
+			router.AddInterceptor(false,
+				fiber.RequestInterceptorFunc(func(_ context.Context, req fiber.Request) (fiber.Request, error) {
+					order = append(order, "auth")
+					fiber.SetRequestHeader(req, "Authorization", "Bearer token")
+					return req, nil
+				}),
+				fiber.RequestInterceptorFunc(func(_ context.Context, req fiber.Request) (fiber.Request, error) {
+					order = append(order, "strip")
+					fiber.DeleteRequestHeader(req, "X-Internal-Secret")
+					return req, nil
+				}),
+				fiber.ResponseInterceptorFunc(func(_ context.Context, _ fiber.Request, resp fiber.Response) fiber.Response {
+					return &redactedResponse{Response: resp}
+				}),
+			)
+
+			var responses []fiber.Response
+			for resp := range router.Dispatch(context.Background(), tt.request).Iter() {
+				responses = append(responses, resp)
+			}
+
+			require.Len(t, responses, 1)
+			assert.True(t, responses[0].IsSuccess())
+			assert.Equal(t, "user: [redacted]", string(responses[0].Payload()))
+			assert.Equal(t, "route-a", responses[0].BackendName())
+			assert.Equal(t, []string{"auth", "strip"}, order)
+
+			dispatcher.lock.Lock()
+			defer dispatcher.lock.Unlock()
+			// the router sets the id of the request, so the route sends the same one
+			for key, values := range dispatcher.header {
+				if strings.EqualFold(key, fiber.RequestIDHeader) {
+					assert.Equal(t, []string{fiber.RequestID(tt.request)}, values)
+					delete(dispatcher.header, key)
+				}
+			}
+			assert.Equal(t, tt.expectedHeader, dispatcher.header)
+		})
+	}
+}
+
+func TestInterceptors_RequestInterceptorError(t *testing.T) {
+	suite := map[string]struct {
+		request      fiber.Request
+		expectedCode int
+	}{
+		"http": {
+			request:      testUtilsHttp.MockReq("GET", "http://localhost:8080/chain", ""),
+			expectedCode: http.StatusInternalServerError,
+		},
+		"grpc": {
+			request:      &fiberGRPC.Request{},
+			expectedCode: int(codes.Internal),
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			dispatcher := &headerRecordingDispatcher{}
+			caller, err := fiber.NewCaller("route-a", dispatcher)
+			require.NoError(t, err)
+
+			intercepted := false
+			caller.AddInterceptor(false,
+				fiber.RequestInterceptorFunc(func(context.Context, fiber.Request) (fiber.Request, error) {
+					return nil, errors.New("missing token")
+				}),
+				fiber.RequestInterceptorFunc(func(_ context.Context, req fiber.Request) (fiber.Request, error) {
+					intercepted = true
+					return req, nil
+				}),
+			)
+
+			resp, ok := <-caller.Dispatch(context.Background(), tt.request).Iter()
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode())
+			assert.Contains(t, string(resp.Payload()), "missing token")
+			assert.False(t, intercepted)
+			assert.Nil(t, dispatcher.header)
+		})
+	}
+}
+
+func TestInterceptors_EagerRouter(t *testing.T) {
+	routes := make(map[string]fiber.Component)
+	for _, id := range []string{"route-a", "route-b"} {
+		caller, err := fiber.NewCaller(id, &headerRecordingDispatcher{
+			response: testUtilsHttp.MockResp(http.StatusOK, id, nil, nil),
+		})
+		require.NoError(t, err)
+		routes[id] = caller
+	}
+	router := fiber.NewEagerRouter("router")
+	router.SetRoutes(routes)
+	router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-b", "route-a"}, 0, nil))
+
+	var lock sync.Mutex
+	var requests, responses int
+	router.AddInterceptor(false,
+		fiber.RequestInterceptorFunc(func(_ context.Context, req fiber.Request) (fiber.Request, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			requests++
+			return req, nil
+		}),
+		fiber.ResponseInterceptorFunc(func(_ context.Context, _ fiber.Request, resp fiber.Response) fiber.Response {
+			lock.Lock()
+			defer lock.Unlock()
+			responses++
+			return resp
+		}),
+	)
+
+	var received []fiber.Response
+	req := testUtilsHttp.MockReq("GET", "http://localhost:8080/eager", "")
+	for resp := range router.Dispatch(context.Background(), req).Iter() {
+		received = append(received, resp)
+	}
+
+	// the router shares the interceptors with its combiner, but intercepts the request and its response once
+	require.Len(t, received, 1)
+	assert.Equal(t, "route-b", string(received[0].Payload()))
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 1, responses)
+}
@@ -0,0 +1,99 @@
+package testutils
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+)
+
+// MockBackend is the fiber.Dispatcher, that responds with the scripted sequence of the responses,
+// http or grpc ones, so the retries, the circuit breakers and the fallbacks can be tested without
+// the backends. The last response of the script is repeated, once the script is over
+type MockBackend struct {
+	mu     sync.Mutex
+	script []testUtilsHttp.DelayedResponse
+	calls  int
+
+	// failAfter, if set, is the number of the calls, after which the backend responds with the failure
+	failAfter int
+	failure   fiber.Response
+}
+
+// NewMockBackend creates the MockBackend, that responds with the given responses in order
+func NewMockBackend(responses ...fiber.Response) *MockBackend {
+	backend := &MockBackend{}
+	for _, resp := range responses {
+		backend.script = append(backend.script, testUtilsHttp.DelayedResponse{Response: resp})
+	}
+	return backend
+}
+
+// ThenRespond adds the response to the script, that is sent back after the latency
+func (b *MockBackend) ThenRespond(resp fiber.Response, latency time.Duration) *MockBackend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.script = append(b.script, testUtilsHttp.DelayedResponse{Response: resp, Latency: latency})
+	return b
+}
+
+// FailAfter makes the backend respond with the failure to all the calls after the first n ones
+func (b *MockBackend) FailAfter(n int, failure fiber.Response) *MockBackend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failAfter, b.failure = n, failure
+	return b
+}
+
+// Do responds with the next response of the script, after its latency. If the context is done
+// before that, the request timeout error is returned instead
+func (b *MockBackend) Do(ctx context.Context, req fiber.Request) fiber.Response {
+	b.mu.Lock()
+	call := b.calls
+	b.calls++
+	next := b.next(call)
+	b.mu.Unlock()
+
+	if next.Latency > 0 {
+		select {
+		case <-time.After(next.Latency):
+		case <-ctx.Done():
+			return fiber.NewErrorResponse(fiberErrors.ErrRequestTimeout(req.Protocol()))
+		}
+	}
+	// the responses are cloned, so the routers, that name them after the routes, don't share them
+	if cloneable, ok := next.Response.(interface{ Clone() fiber.Response }); ok {
+		return cloneable.Clone()
+	}
+	return next.Response
+}
+
+// next returns the scripted response of the call
+func (b *MockBackend) next(call int) testUtilsHttp.DelayedResponse {
+	if b.failure != nil && call >= b.failAfter {
+		return testUtilsHttp.DelayedResponse{Response: b.failure}
+	}
+	if len(b.script) == 0 {
+		return testUtilsHttp.DelayedResponse{Response: testUtilsHttp.MockResp(200, "", nil, nil)}
+	}
+	if call >= len(b.script) {
+		return b.script[len(b.script)-1]
+	}
+	return b.script[call]
+}
+
+// Calls returns the number of the calls of the backend
+func (b *MockBackend) Calls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls
+}
+
+// Component returns the route with the given ID, that dispatches the requests to the backend
+func (b *MockBackend) Component(id string) fiber.Component {
+	caller, _ := fiber.NewCaller(id, b)
+	return caller
+}
@@ -15,6 +15,8 @@ type MockComponent struct {
 	mock.Mock
 
 	responses []testUtilsHttp.DelayedResponse
+	// cloned, if set, makes the component send the copies of the canned responses
+	cloned bool
 }
 
 func NewMockComponent(id string, responses ...testUtilsHttp.DelayedResponse) *MockComponent {
@@ -24,13 +26,25 @@ func NewMockComponent(id string, responses ...testUtilsHttp.DelayedResponse) *Mo
 	}
 }
 
+// WithClonedResponses makes the component send the copy of each canned response, that can be cloned
+// (e.g. the http and grpc responses), on each dispatch, so the component can be dispatched concurrently
+// by the routers, that set their backend names on the responses
+func (m *MockComponent) WithClonedResponses() *MockComponent {
+	m.cloned = true
+	return m
+}
+
 func (m *MockComponent) Dispatch(context.Context, fiber.Request) fiber.ResponseQueue {
 	out := make(chan fiber.Response, len(m.responses))
 
 	go func() {
 		for _, r := range m.responses {
 			time.Sleep(r.Latency)
-			out <- r.Response
+			resp := r.Response
+			if cloneable, ok := resp.(interface{ Clone() fiber.Response }); ok && m.cloned {
+				resp = cloneable.Clone()
+			}
+			out <- resp
 		}
 		close(out)
 	}()
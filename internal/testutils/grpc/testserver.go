@@ -1,6 +1,7 @@
 package testutils
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log"
@@ -10,18 +11,35 @@ import (
 
 	testproto "github.com/gojek/fiber/internal/testdata/gen/testdata/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
 )
 
 type GrpcTestServer struct {
-	Port         int
+	Port int
+	// Socket, if set, is the path of the unix domain socket, that the server listens on instead of the Port
+	Socket       string
 	MockResponse *testproto.PredictValuesResponse
-	DelayTimer   time.Duration
+	// MockError, if set, is returned instead of the response
+	MockError  error
+	DelayTimer time.Duration
+	// MetadataObserver, if set, is called with the metadata of each incoming request
+	MetadataObserver func(md metadata.MD)
 }
 
-func (s *GrpcTestServer) PredictValues(_ context.Context, _ *testproto.PredictValuesRequest) (*testproto.PredictValuesResponse, error) {
+func (s *GrpcTestServer) PredictValues(ctx context.Context, _ *testproto.PredictValuesRequest) (*testproto.PredictValuesResponse, error) {
 	time.Sleep(s.DelayTimer)
 
+	if s.MetadataObserver != nil {
+		md, _ := metadata.FromIncomingContext(ctx)
+		s.MetadataObserver(md)
+	}
+
+	if s.MockError != nil {
+		return nil, s.MockError
+	}
 	if s.MockResponse != nil {
 		return s.MockResponse, nil
 	}
@@ -35,14 +53,56 @@ func (s *GrpcTestServer) PredictValues(_ context.Context, _ *testproto.PredictVa
 }
 
 func RunTestUPIServer(srv GrpcTestServer) {
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", srv.Port))
+	network, address := "tcp", fmt.Sprintf(":%d", srv.Port)
+	if srv.Socket != "" {
+		network, address = "unix", srv.Socket
+	}
+	listener, err := net.Listen(network, address)
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
 	s := grpc.NewServer()
 	testproto.RegisterUniversalPredictionServiceServer(s, &srv)
+	// the overall health of the server is SERVING
+	healthpb.RegisterHealthServer(s, health.NewServer())
 	reflection.Register(s)
-	log.Printf("Running Test Server at %v", srv.Port)
+	log.Printf("Running Test Server at %v", listener.Addr())
+	go func() {
+		if err := s.Serve(listener); err != nil {
+			log.Fatalf("failed to serve: %v", err)
+		}
+	}()
+}
+
+// StreamingTestServer is the server-streaming backend, that responds to any method with the
+// given frames, and finishes the stream with the given error
+type StreamingTestServer struct {
+	Port   int
+	Frames [][]byte
+	Err    error
+}
+
+func (s *StreamingTestServer) handleStream(_ interface{}, stream grpc.ServerStream) error {
+	if err := stream.RecvMsg(new(bytes.Buffer)); err != nil {
+		return err
+	}
+	for _, frame := range s.Frames {
+		if err := stream.SendMsg(frame); err != nil {
+			return err
+		}
+	}
+	return s.Err
+}
+
+// RunTestStreamingServer runs the StreamingTestServer. The messages are sent and received
+// as is, so the server has to be called with the content subtype of the fiber codec
+func RunTestStreamingServer(srv StreamingTestServer) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", srv.Port))
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	s := grpc.NewServer(grpc.UnknownServiceHandler(srv.handleStream))
+	log.Printf("Running Test Streaming Server at %v", srv.Port)
 	go func() {
 		if err := s.Serve(listener); err != nil {
 			log.Fatalf("failed to serve: %v", err)
@@ -0,0 +1,39 @@
+package testutils
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+)
+
+// UnresponsiveAddress returns the address of the listener, that never completes the handshakes of the new
+// connections, so the attempts to connect to it time out. The listener has the backlog of a single connection,
+// that is filled up, so the kernel drops the following connection requests (as on Linux). The listener is
+// closed, when the test is complete
+func UnresponsiveAddress(t testing.TB) string {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("unable to create the socket: %v", err)
+	}
+	t.Cleanup(func() { _ = syscall.Close(fd) })
+	if err = syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		t.Fatalf("unable to bind the socket: %v", err)
+	}
+	if err = syscall.Listen(fd, 0); err != nil {
+		t.Fatalf("unable to listen on the socket: %v", err)
+	}
+	sockaddr, err := syscall.Getsockname(fd)
+	if err != nil {
+		t.Fatalf("unable to get the address of the socket: %v", err)
+	}
+	address := fmt.Sprintf("127.0.0.1:%d", sockaddr.(*syscall.SockaddrInet4).Port)
+
+	// the connection, that is never accepted, fills up the backlog
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("unable to fill up the backlog of the listener: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return address
+}
@@ -0,0 +1,104 @@
+package testutils
+
+import (
+	"context"
+	"time"
+
+	"github.com/gojek/fiber"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+)
+
+// RouterBuilder builds the routers in memory, which routes are the MockComponents, that respond
+// with the canned responses, so the strategies and the combiners can be tested without the backends
+type RouterBuilder struct {
+	id     string
+	routes map[string]fiber.Component
+
+	strategy        fiber.RoutingStrategy
+	order           []string
+	strategyLatency time.Duration
+	strategyErr     error
+}
+
+// NewRouterBuilder creates the RouterBuilder of the router with the given ID
+func NewRouterBuilder(id string) *RouterBuilder {
+	return &RouterBuilder{
+		id:     id,
+		routes: make(map[string]fiber.Component),
+	}
+}
+
+// WithRoute adds the route, that responds with the given responses
+func (b *RouterBuilder) WithRoute(id string, responses ...fiber.Response) *RouterBuilder {
+	delayed := make([]testUtilsHttp.DelayedResponse, len(responses))
+	for idx, resp := range responses {
+		delayed[idx] = testUtilsHttp.DelayedResponse{Response: resp}
+	}
+	return b.WithDelayedRoute(id, delayed...)
+}
+
+// WithDelayedRoute adds the route, that responds with the copies of the given responses, each after its latency,
+// so the router can be dispatched many times
+func (b *RouterBuilder) WithDelayedRoute(id string, responses ...testUtilsHttp.DelayedResponse) *RouterBuilder {
+	return b.WithComponent(NewMockComponent(id, responses...).WithClonedResponses())
+}
+
+// WithComponent adds the given component as the route, e.g. the nested router
+func (b *RouterBuilder) WithComponent(component fiber.Component) *RouterBuilder {
+	b.routes[component.ID()] = component
+	return b
+}
+
+// WithStrategy sets the strategy of the router, e.g. the one under test
+func (b *RouterBuilder) WithStrategy(strategy fiber.RoutingStrategy) *RouterBuilder {
+	b.strategy = strategy
+	return b
+}
+
+// WithOrder sets the MockRoutingStrategy of the router, that selects the routes in the given order,
+// after the latency, and returns the error along with them (see NewMockRoutingStrategy)
+func (b *RouterBuilder) WithOrder(order []string, latency time.Duration, err error) *RouterBuilder {
+	b.strategy = nil
+	b.order, b.strategyLatency, b.strategyErr = order, latency, err
+	return b
+}
+
+// Routes returns the routes of the router, keyed by their IDs
+func (b *RouterBuilder) Routes() map[string]fiber.Component {
+	return b.routes
+}
+
+// Build creates the EagerRouter with the routes and the strategy
+func (b *RouterBuilder) Build() *fiber.EagerRouter {
+	router := fiber.NewEagerRouter(b.id)
+	b.configure(router)
+	return router
+}
+
+// BuildLazy creates the LazyRouter with the routes and the strategy
+func (b *RouterBuilder) BuildLazy() *fiber.LazyRouter {
+	router := fiber.NewLazyRouter(b.id)
+	b.configure(router)
+	return router
+}
+
+// configure sets the routes and the strategy of the router. Unless the strategy is set, the
+// MockRoutingStrategy selects the routes in the configured order
+func (b *RouterBuilder) configure(router fiber.Router) {
+	router.SetRoutes(b.routes)
+	strategy := b.strategy
+	if strategy == nil {
+		strategy = NewMockRoutingStrategy(b.routes, b.order, b.strategyLatency, b.strategyErr)
+	}
+	router.SetStrategy(strategy)
+}
+
+// Dispatch dispatches the request by the component and returns all of its responses,
+// once its response queue is closed
+func Dispatch(ctx context.Context, component fiber.Component, req fiber.Request) []fiber.Response {
+	responses := make([]fiber.Response, 0)
+	for resp := range component.Dispatch(ctx, req).Iter() {
+		responses = append(responses, resp)
+	}
+	return responses
+}
@@ -0,0 +1,146 @@
+package fiber
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxKeys is the number of the keys, which state is kept by the keyed components (e.g. the rate limits
+// of the keys of the RateLimitedDispatcher), if it's not configured
+const DefaultMaxKeys = 10000
+
+// RequestKeyFunc returns the key of the request, that the components keep their separate state by,
+// e.g. the tenant of the request. The requests with the same key share the state
+type RequestKeyFunc func(req Request) string
+
+// RequestKey returns the RequestKeyFunc, that keys the requests on the values of the given headers
+// (or grpc metadata keys), joined with `:` (e.g. `gold:mobile`). The missing headers are keyed as the empty
+// values. If operation is set, the operation name of the request, i.e. the method and the path of the http
+// request, is appended to the key too, so each endpoint is keyed separately
+func RequestKey(operation bool, headers ...string) RequestKeyFunc {
+	return func(req Request) string {
+		values := make([]string, 0, len(headers)+1)
+		for _, header := range headers {
+			value := ""
+			if header := req.Header()[headerKey(req.Protocol(), header)]; len(header) > 0 {
+				value = header[0]
+			}
+			values = append(values, value)
+		}
+		if operation {
+			values = append(values, req.OperationName())
+		}
+		return strings.Join(values, ":")
+	}
+}
+
+// keyedStates keeps the states of up to maxKeys keys, and evicts the state of the least recently used key,
+// once there are more of them, so the unbounded keys (e.g. the forged tenants) can't exhaust the memory
+type keyedStates struct {
+	lock    sync.Mutex
+	maxKeys int
+	// evicted, if set, is called with the state of the evicted key, while the lock is held
+	evicted func(state interface{})
+	// entries are ordered from the most to the least recently used one
+	entries *list.List
+	index   map[string]*list.Element
+}
+
+type keyedState struct {
+	key   string
+	state interface{}
+}
+
+func newKeyedStates(maxKeys int, evicted func(state interface{})) *keyedStates {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+	return &keyedStates{
+		maxKeys: maxKeys,
+		evicted: evicted,
+		entries: list.New(),
+		index:   make(map[string]*list.Element),
+	}
+}
+
+// get returns the state of the key, and creates it, if the key has no state yet. Nothing is kept,
+// if create returns nil, so it's created again for the next request of the key. Since create is called
+// while the lock is held, it has to be cheap (see lookup and insert otherwise)
+func (s *keyedStates) get(key string, create func() interface{}) interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if state, ok := s.find(key); ok {
+		return state
+	}
+	state := create()
+	if state == nil {
+		return nil
+	}
+	s.push(key, state)
+	return state
+}
+
+// lookup returns the state of the key, if it has one
+func (s *keyedStates) lookup(key string) (interface{}, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.find(key)
+}
+
+// insert keeps the state of the key, that's been created without the lock, and returns it, unless the state
+// of the key has been inserted by another request in the meantime. Then that state is returned instead,
+// together with false, and the given one is not kept
+func (s *keyedStates) insert(key string, state interface{}) (interface{}, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if existing, ok := s.find(key); ok {
+		return existing, false
+	}
+	s.push(key, state)
+	return state, true
+}
+
+// find returns the state of the key, and marks it as the most recently used one. The lock has to be held
+func (s *keyedStates) find(key string) (interface{}, bool) {
+	elem, ok := s.index[key]
+	if !ok {
+		return nil, false
+	}
+	s.entries.MoveToFront(elem)
+	return elem.Value.(*keyedState).state, true
+}
+
+// push keeps the state of the key, and evicts the least recently used one, if there are more than maxKeys
+// keys. The lock has to be held
+func (s *keyedStates) push(key string, state interface{}) {
+	s.index[key] = s.entries.PushFront(&keyedState{key: key, state: state})
+	if s.entries.Len() > s.maxKeys {
+		oldest := s.entries.Back()
+		s.entries.Remove(oldest)
+		delete(s.index, oldest.Value.(*keyedState).key)
+		if s.evicted != nil {
+			s.evicted(oldest.Value.(*keyedState).state)
+		}
+	}
+}
+
+// states returns the states of all the keys, from the most to the least recently used one
+func (s *keyedStates) states() []interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	states := make([]interface{}, 0, s.entries.Len())
+	for elem := s.entries.Front(); elem != nil; elem = elem.Next() {
+		states = append(states, elem.Value.(*keyedState).state)
+	}
+	return states
+}
+
+// len returns the number of the keys, which state is kept
+func (s *keyedStates) len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.entries.Len()
+}
@@ -2,6 +2,7 @@ package fiber
 
 import (
 	"context"
+	"time"
 
 	"github.com/gojek/fiber/errors"
 	"github.com/gojek/fiber/util"
@@ -11,11 +12,22 @@ import (
 // based on the routing strategy.
 // The reason why it's 'lazy' is because it tries to dispatch an incoming request by
 // a primary route first and switches to fallback options (one by one) only if
-// received response is not OK
+// received response is not OK (see SetFailureStatusCodes)
 type LazyRouter struct {
 	*BaseMultiRouteComponent
 
-	strategy *baseRoutingStrategy
+	strategy   *baseRoutingStrategy
+	preRouting PreRoutingHook
+	logger     Logger
+	access     AccessLogger
+	failures   failureStatuses
+	override   *RouteOverride
+	tags       *RouteTagFilter
+	hedging    *hedging
+	timeout    *TimeoutResponse
+	// maxAttempts, if positive, is the number of the routes, that the router tries at most (see SetMaxAttempts)
+	maxAttempts int
+	gate        dispatchGate
 }
 
 // NewLazyRouter initializes new LazyRouter
@@ -30,15 +42,106 @@ func NewLazyRouter(id string) *LazyRouter {
 
 // SetStrategy sets routing strategy for this router
 func (r *LazyRouter) SetStrategy(strategy RoutingStrategy) {
-	r.strategy = &baseRoutingStrategy{RoutingStrategy: strategy}
+	r.strategy = newBaseRoutingStrategy(strategy)
 }
 
-// Dispatch makes a synchronous call to a routing strategy to select the primary route and fallbacks.
+// SetPreRoutingHook sets the hook, that enriches each request, before the routing strategy selects the routes
+func (r *LazyRouter) SetPreRoutingHook(hook PreRoutingHook) {
+	r.preRouting = hook
+}
+
+// SetLogger sets the logger of the routing decisions of this router. The router doesn't log
+// anything, and doesn't set the correlation id on the requests, unless the logger or the access logger is set
+func (r *LazyRouter) SetLogger(logger Logger) {
+	r.logger = logger
+}
+
+// SetAccessLogger sets the sink of the access log records of this router, one per dispatch, that are
+// written, once the response of the router is chosen, including the error responses. The router sets
+// the correlation id on the requests, if either of the loggers is set
+func (r *LazyRouter) SetAccessLogger(logger AccessLogger) {
+	r.access = logger
+}
+
+// SetFailureStatusCodes sets the status codes of the responses, on which the router falls back
+// to the next route, even if they are successful. The router always falls back on any non-successful
+// response, except the grpc responses with DefaultGRPCTerminalStatusCodes, which are sent back as they are,
+// unless their codes are set too
+func (r *LazyRouter) SetFailureStatusCodes(codes ...int) {
+	r.failures = newFailureStatuses(codes)
+}
+
+// SetTimeoutResponse sets the response, that is sent back instead of ErrRequestTimeout, when the request
+// times out, while the router is waiting for its routes. ErrRequestTimeout is sent back, if it's nil (default)
+func (r *LazyRouter) SetTimeoutResponse(timeout *TimeoutResponse) {
+	r.timeout = timeout
+}
+
+// SetRouteOverride lets the requests pin the route of this router with the header, bypassing the routing
+// strategy. The override is disabled, if it's nil (default)
+func (r *LazyRouter) SetRouteOverride(override *RouteOverride) {
+	r.override = override
+}
+
+// SetRouteTagFilter restricts the routes, that the routing strategy selects from, to the ones, which tags
+// match the tags of the request (see RouteTagFilter). The routes are not filtered, if it's nil (default)
+func (r *LazyRouter) SetRouteTagFilter(filter *RouteTagFilter) {
+	r.tags = filter
+}
+
+// SetHedgingPolicy lets the router send the hedged requests to the fallback routes, if the route hasn't
+// responded within the hedge delay, instead of waiting for it to fail (see HedgingPolicy).
+// The hedging is disabled, if the policy is nil (default)
+func (r *LazyRouter) SetHedgingPolicy(policy *HedgingPolicy) error {
+	if policy == nil {
+		r.hedging = nil
+		return nil
+	}
+	hedging, err := newHedging(*policy)
+	if err != nil {
+		return err
+	}
+	r.hedging = hedging
+	return nil
+}
+
+// SetMaxAttempts limits the number of the routes, that the router tries, before it sends back the failures
+// of the routes, that have been tried. The routes are tried in the order of the routing strategy, so only
+// the first maxAttempts of them are dispatched. All the routes are tried, if it's zero (default)
+func (r *LazyRouter) SetMaxAttempts(maxAttempts int) {
+	r.maxAttempts = maxAttempts
+}
+
+// Dispatch makes a synchronous call to a routing strategy to select the primary route and fallbacks,
+// after the request is enriched by the PreRoutingHook, if it's set.
 // After receiving a response it asynchronously asks a primary route to dispatch the request.
 // If all responseQueue from a primary route are OK, it sends them back to output
 // Otherwise it repeats the same with all fallback options one by one until one of fallbacks
-// successfully dispatches a request or all fallbacks tried and failed to dispatch it
+// successfully dispatches a request or all fallbacks tried and failed to dispatch it, up to the max attempts
+// of the router, if they are set (see SetMaxAttempts). In the latter case,
+// ErrServiceUnavailable is sent back with the failures of all the routes (see ErrorResponse.RouteErrors),
+// or ErrRequestRejected, if all of them have rejected the request as invalid.
+// The routes, which tags don't match the request, are skipped (see SetRouteTagFilter), as are
+// the saturated routes (see SaturationReporter), and the request is shed with ErrComponentSaturated,
+// if all the routes are saturated.
+// If a route responds with a stream, the router commits to it on the first successful frame,
+// and sends this and the following frames back to output without buffering.
+// If the HedgingPolicy is set, the fallbacks are dispatched in advance, when the routes in flight are slow
+// to respond (see dispatchHedged)
 func (r *LazyRouter) Dispatch(ctx context.Context, req Request) ResponseQueue {
+	return r.intercept(ctx, req, r.dispatch)
+}
+
+func (r *LazyRouter) dispatch(ctx context.Context, req Request) ResponseQueue {
+	if !r.gate.enter() {
+		return NewResponseQueueFromResponses(NewErrorResponse(errors.ErrComponentClosed(req.Protocol())))
+	}
+
+	// the id is set on the request, before it's copied for the routes, so all of them send the same one
+	RequestID(req)
+	log, ctx := newDispatchLogger(r.strategy.withName(ctx), r.logger, r.access, r.ID(), req)
+	ctx = withDispatchLogger(ctx, log)
+	ctx, hookResp := preRoute(ctx, r.preRouting, req)
 	ctx = r.beforeDispatch(ctx, req)
 	out := make(chan Response, 1)
 
@@ -46,11 +149,37 @@ func (r *LazyRouter) Dispatch(ctx context.Context, req Request) ResponseQueue {
 	defer r.afterDispatch(ctx, req, queue)
 
 	go func() {
+		defer r.gate.leave()
 		defer r.afterCompletion(ctx, req, queue)
 		defer close(out)
 
+		if hookResp != nil {
+			log.logResponse(ctx, hookResp)
+			out <- hookResp
+			return
+		}
+
+		// the routes, which tags don't match the request, are neither selected, nor used as the fallbacks
+		candidates, err := r.tags.filter(req, r.routes)
+		if err != nil {
+			resp := NewErrorResponse(errors.NewFiberError(req.Protocol(), err))
+			log.logResponse(ctx, resp)
+			out <- resp
+			return
+		}
+
+		// the saturated routes are not selected, and the request is shed, if all the routes are saturated
+		available := unsaturatedRoutes(candidates)
+		if len(available) == 0 && len(candidates) > 0 {
+			resp := NewErrorResponse(errors.ErrComponentSaturated(req.Protocol()))
+			log.logResponse(ctx, resp)
+			out <- resp
+			return
+		}
+
 		var routes []Component
-		routesOrderCh, errCh := r.strategy.getRoutesOrder(ctx, req, r.routes)
+		selectionStart := time.Now()
+		routesOrderCh, errCh := r.strategy.getRoutesOrder(ctx, req, available, r.override)
 		for routesOrderCh != nil || errCh != nil {
 			select {
 			case orderedRoutes, ok := <-routesOrderCh:
@@ -61,48 +190,149 @@ func (r *LazyRouter) Dispatch(ctx context.Context, req Request) ResponseQueue {
 				}
 			case err, ok := <-errCh:
 				if ok {
-					out <- NewErrorResponse(errors.NewFiberError(req.Protocol(), err))
+					logStrategyPanic(ctx, log, err)
+					resp := NewErrorResponse(errors.NewFiberError(req.Protocol(), err))
+					log.logResponse(ctx, resp)
+					out <- resp
 					return
 				}
 				errCh = nil
 			case <-ctx.Done():
-				out <- NewErrorResponse(errors.ErrRouterStrategyTimeoutExceeded(req.Protocol()))
+				resp := NewErrorResponse(errors.ErrRouterStrategyTimeoutExceeded(req.Protocol()))
+				log.logResponse(ctx, resp)
+				out <- resp
 				return
 			}
 		}
 
+		if r.maxAttempts > 0 && len(routes) > r.maxAttempts {
+			routes = routes[:r.maxAttempts]
+		}
+
 		if len(routes) > 0 {
+			log.log(ctx, RouteSelectedEvent, routes[0].ID(), nil, time.Since(selectionStart))
+
+			if r.hedging != nil {
+				r.dispatchHedged(ctx, log, req, routes, out)
+				return
+			}
+
+			routeErrors := make([]errors.RouteError, 0, len(routes))
+
 			// iterate over an ordered slice of possible routes
-			for _, route := range routes {
+			for idx, route := range routes {
+				if idx > 0 {
+					log.log(ctx, FallbackTriggeredEvent, route.ID(), nil, 0)
+				}
+
 				copyReq, _ := req.Clone()
+				log.tag(copyReq)
+				log.log(ctx, RouteAttemptStartedEvent, route.ID(), nil, 0)
+				start := time.Now()
+
+				var last Response
 				responses := make([]Response, 0)
-				responseCh := route.Dispatch(ctx, copyReq).Iter()
-				ok := true
+				responseCh := safeDispatch(ctx, log, route, copyReq).Iter()
+				ok, committed := true, false
 				for ok {
 					select {
 					case resp, notClosed := <-responseCh:
 						if notClosed {
-							if ok = resp.IsSuccess(); ok {
-								responses = append(responses, resp.WithBackendName(route.ID()))
+							last = resp.WithBackendName(route.ID())
+							if committed {
+								// the router is committed to the streaming route, so its frames
+								// are sent back to output as they arrive, even the failed ones
+								out <- last
+							} else if ok = !r.failures.isFailure(req.Protocol(), resp); ok {
+								responses = append(responses, last)
+								if isStreamFrame(resp) {
+									// the first successful frame of a stream commits the router to this route
+									committed = true
+									log.logResponse(ctx, last)
+									for _, resp := range responses {
+										out <- resp
+									}
+									responses = responses[:0]
+								}
+							} else {
+								log.log(ctx, RouteAttemptFinishedEvent, route.ID(), resp, time.Since(start))
+								routeErrors = append(routeErrors, newRouteError(req.Protocol(), route.ID(), last))
 							}
 						} else {
 							// all responseQueue from selected route are ok, sending them back to output
 							// and breaking a cycle over other routes
+							log.log(ctx, RouteAttemptFinishedEvent, route.ID(), last, time.Since(start))
+							if !committed && last != nil {
+								log.logResponse(ctx, last)
+							}
 							for _, resp := range responses {
 								out <- resp
 							}
 							return
 						}
 					case <-ctx.Done():
-						out <- NewErrorResponse(errors.ErrRequestTimeout(req.Protocol()))
+						resp := r.timeout.response(req.Protocol())
+						log.log(ctx, RouteAttemptFinishedEvent, route.ID(), resp, time.Since(start))
+						log.logResponse(ctx, resp)
+						out <- resp
 						return
 					}
 				}
 			}
+
+			// all the routes have failed
+			resp := errAllRoutesFailed(req.Protocol(), routeErrors)
+			log.logResponse(ctx, resp)
+			out <- resp
 		} else {
-			out <- NewErrorResponse(errors.ErrRouterStrategyReturnedEmptyRoutes(req.Protocol()))
+			resp := NewErrorResponse(errors.ErrRouterStrategyReturnedEmptyRoutes(req.Protocol()))
+			log.logResponse(ctx, resp)
+			out <- resp
 		}
 	}()
 
 	return queue
 }
+
+// Explain runs the routing strategy for the request, after it's enriched by the PreRoutingHook, and returns
+// the routes in the order, in which the router would try them, without dispatching the request. The decision
+// includes the routes, that the router would skip, and the scores of the routes, if the strategy is a RouteScorer.
+// The error of the hook or the strategy is returned, if either of them fails
+func (r *LazyRouter) Explain(ctx context.Context, req Request) (RoutingDecision, error) {
+	ctx, err := runPreRoutingHook(ctx, r.preRouting, req)
+	if err != nil {
+		return RoutingDecision{RouterID: r.ID()}, err
+	}
+
+	var decision RoutingDecision
+	candidates, err := r.tags.filter(req, r.routes)
+	if err != nil {
+		return RoutingDecision{RouterID: r.ID()}, err
+	}
+	available := unsaturatedRoutes(candidates)
+	for id := range r.routes {
+		if _, ok := candidates[id]; !ok {
+			decision.skip(id, SkippedTags)
+		} else if _, ok := available[id]; !ok {
+			decision.skip(id, SkippedSaturated)
+		}
+	}
+	decision, err = r.strategy.explain(ctx, r.ID(), req, available, r.override, decision)
+	if err != nil {
+		return decision, err
+	}
+
+	if r.maxAttempts > 0 && len(decision.Routes) > r.maxAttempts {
+		for _, id := range decision.Routes[r.maxAttempts:] {
+			decision.skip(id, SkippedMaxAttempts)
+		}
+		decision.Routes = decision.Routes[:r.maxAttempts]
+	}
+	return decision, nil
+}
+
+// Close stops accepting new requests, that are responded with ErrComponentClosed, waits for the in-flight
+// dispatches to complete, until the context is done, and then closes the routes of the router
+func (r *LazyRouter) Close(ctx context.Context) error {
+	return r.gate.close(ctx, r.BaseMultiRouteComponent.Close)
+}
@@ -9,10 +9,12 @@ import (
 
 	"github.com/gojek/fiber"
 	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/grpc"
 	"github.com/gojek/fiber/internal/testutils"
 	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
 	"github.com/gojek/fiber/protocol"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type lazyRouterTestCase struct {
@@ -150,3 +152,96 @@ func TestLazyRouter_Dispatch(t *testing.T) {
 		})
 	}
 }
+
+func TestLazyRouter_NestedBackendName(t *testing.T) {
+	suite := []struct {
+		name     string
+		response func() fiber.Response
+		expected string
+	}{
+		{
+			name: "http",
+			response: func() fiber.Response {
+				return testUtilsHttp.MockResp(200, "OK", nil, nil).WithBackendName("backend-b")
+			},
+			expected: "inner-router,route-b,backend-b",
+		},
+		{
+			name: "grpc",
+			response: func() fiber.Response {
+				return (&grpc.Response{}).AppendBackendName("backend-b")
+			},
+			expected: "inner-router,route-b,backend-b",
+		},
+	}
+
+	for _, tt := range suite {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := testutils.NewRouterBuilder("inner-router").
+				WithRoute("route-b", tt.response()).
+				WithOrder([]string{"route-b"}, 0, nil).
+				BuildLazy()
+			outer := testutils.NewRouterBuilder("outer-router").
+				WithComponent(inner).
+				WithOrder([]string{"inner-router"}, 0, nil).
+				BuildLazy()
+
+			request := testUtilsHttp.MockReq("POST", "http://localhost:8080/lazy-router", "payload")
+			responses := testutils.Dispatch(context.Background(), outer, request)
+			require.Len(t, responses, 1)
+			assert.True(t, responses[0].IsSuccess())
+			assert.Equal(t, tt.expected, responses[0].BackendName())
+			assert.Equal(t, "inner-router", fiber.RouteID(responses[0]))
+		})
+	}
+}
+
+func TestLazyRouter_MockBackendFallback(t *testing.T) {
+	// the primary backend fails after the first call, so the second request falls back
+	primary := testutils.NewMockBackend(testUtilsHttp.MockResp(200, "A-OK", nil, nil)).
+		FailAfter(1, fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP)))
+	fallback := testutils.NewMockBackend().
+		ThenRespond(testUtilsHttp.MockResp(200, "B-OK", nil, nil), 10*time.Millisecond)
+	router := testutils.NewRouterBuilder("lazy-router").
+		WithComponent(primary.Component("route-a")).
+		WithComponent(fallback.Component("route-b")).
+		WithOrder([]string{"route-a", "route-b"}, 0, nil).
+		BuildLazy()
+
+	for _, expected := range []string{"A-OK", "B-OK"} {
+		responses := testutils.Dispatch(context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost:8080", ""))
+		require.Len(t, responses, 1)
+		assert.Equal(t, expected, string(responses[0].Payload()))
+	}
+	assert.Equal(t, 2, primary.Calls())
+	assert.Equal(t, 1, fallback.Calls())
+}
+
+func TestLazyRouter_MaxAttempts(t *testing.T) {
+	builder := testutils.NewRouterBuilder("lazy-router")
+	backends := make(map[string]*testutils.MockBackend)
+	for _, id := range []string{"route-a", "route-b", "route-c", "route-d", "route-e"} {
+		backends[id] = testutils.NewMockBackend(
+			fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP)))
+		builder.WithComponent(backends[id].Component(id))
+	}
+	router := builder.
+		WithOrder([]string{"route-d", "route-b", "route-a", "route-e", "route-c"}, 0, nil).
+		BuildLazy()
+	router.SetMaxAttempts(2)
+
+	responses := testutils.Dispatch(context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost:8080", ""))
+	require.Len(t, responses, 1)
+	errResp, ok := responses[0].(*fiber.ErrorResponse)
+	require.True(t, ok)
+	var routes []string
+	for _, routeErr := range errResp.RouteErrors() {
+		routes = append(routes, routeErr.RouteID)
+	}
+
+	// only the first two routes of the strategy are tried
+	assert.Equal(t, []string{"route-d", "route-b"}, routes)
+	for id, calls := range map[string]int{"route-a": 0, "route-b": 1, "route-c": 0, "route-d": 1, "route-e": 0} {
+		assert.Equal(t, calls, backends[id].Calls(), id)
+	}
+}
@@ -0,0 +1,176 @@
+package fiber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// LoadBalancingPolicy selects the endpoint of the route, that dispatches the request
+type LoadBalancingPolicy string
+
+const (
+	// RoundRobinLoadBalancing selects the endpoints in turn
+	RoundRobinLoadBalancing LoadBalancingPolicy = "round_robin"
+	// LeastConnectionsLoadBalancing selects the endpoint with the fewest requests in flight. The endpoints
+	// with the same number of the requests in flight are selected in turn
+	LeastConnectionsLoadBalancing LoadBalancingPolicy = "least_connections"
+)
+
+// LoadBalancingDispatcher is a Dispatcher, that spreads the requests across the dispatchers of the equivalent
+// endpoints of a single route (i.e. the replicas of the backend), according to the LoadBalancingPolicy.
+// The endpoint is selected on each call, so each attempt of the RetryingDispatcher, that wraps it, can be
+// dispatched to another endpoint. The request of the streaming response is in flight, until the stream is
+// returned, not until all of its frames are received
+type LoadBalancingDispatcher struct {
+	dispatchers []Dispatcher
+	policy      LoadBalancingPolicy
+
+	next     uint64
+	inFlight []int64
+}
+
+// NewLoadBalancingDispatcher is a factory method, that creates a LoadBalancingDispatcher across the given
+// dispatchers. RoundRobinLoadBalancing is used, if the policy is empty
+func NewLoadBalancingDispatcher(policy LoadBalancingPolicy, dispatchers ...Dispatcher) (*LoadBalancingDispatcher, error) {
+	if len(dispatchers) == 0 {
+		return nil, errors.New("load balancing: at least one dispatcher is required")
+	}
+	for _, dispatcher := range dispatchers {
+		if dispatcher == nil {
+			return nil, errors.New("load balancing: dispatcher can not be nil")
+		}
+	}
+	switch policy {
+	case "":
+		policy = RoundRobinLoadBalancing
+	case RoundRobinLoadBalancing, LeastConnectionsLoadBalancing:
+	default:
+		return nil, fmt.Errorf("load balancing: unknown policy [%s], expected %s or %s",
+			policy, RoundRobinLoadBalancing, LeastConnectionsLoadBalancing)
+	}
+
+	return &LoadBalancingDispatcher{
+		dispatchers: dispatchers,
+		policy:      policy,
+		inFlight:    make([]int64, len(dispatchers)),
+	}, nil
+}
+
+// Do dispatches the request by the dispatcher of the selected endpoint
+func (d *LoadBalancingDispatcher) Do(ctx context.Context, req Request) Response {
+	idx := d.selectEndpoint()
+	atomic.AddInt64(&d.inFlight[idx], 1)
+	defer atomic.AddInt64(&d.inFlight[idx], -1)
+	return d.dispatchers[idx].Do(ctx, req)
+}
+
+// selectEndpoint returns the index of the dispatcher of the endpoint, selected by the policy
+func (d *LoadBalancingDispatcher) selectEndpoint() int {
+	start := int((atomic.AddUint64(&d.next, 1) - 1) % uint64(len(d.dispatchers)))
+	if d.policy != LeastConnectionsLoadBalancing {
+		return start
+	}
+
+	// the search starts at the next endpoint in turn, so the ties are spread across the endpoints
+	selected, fewest := start, atomic.LoadInt64(&d.inFlight[start])
+	for offset := 1; offset < len(d.dispatchers); offset++ {
+		idx := (start + offset) % len(d.dispatchers)
+		if inFlight := atomic.LoadInt64(&d.inFlight[idx]); inFlight < fewest {
+			selected, fewest = idx, inFlight
+		}
+	}
+	return selected
+}
+
+// Describe adds the endpoints and the details of the first dispatcher to the info, since the dispatchers
+// of the endpoints are configured the same way
+func (d *LoadBalancingDispatcher) Describe(info *ComponentInfo) {
+	describeIfDescriber(d.dispatchers[0], info)
+	endpoints := make([]string, 0, len(d.dispatchers))
+	for _, dispatcher := range d.dispatchers {
+		var endpointInfo ComponentInfo
+		describeIfDescriber(dispatcher, &endpointInfo)
+		if endpointInfo.Endpoint != "" {
+			endpoints = append(endpoints, endpointInfo.Endpoint)
+		}
+	}
+	info.Endpoint = ""
+	info.Endpoints = endpoints
+}
+
+// Close releases the resources of the dispatchers of all the endpoints, and returns the first error, if any
+func (d *LoadBalancingDispatcher) Close(ctx context.Context) error {
+	var err error
+	for _, dispatcher := range d.dispatchers {
+		if closeErr := closeIfCloser(ctx, dispatcher); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// WarmUp establishes the connections of the dispatchers of all the endpoints concurrently, and returns
+// the first error, if any
+func (d *LoadBalancingDispatcher) WarmUp(ctx context.Context) error {
+	errs := make([]error, len(d.dispatchers))
+	var wg sync.WaitGroup
+	for idx, dispatcher := range d.dispatchers {
+		wg.Add(1)
+		go func(idx int, dispatcher Dispatcher) {
+			defer wg.Done()
+			errs[idx] = warmUpIfWarmer(ctx, dispatcher)
+		}(idx, dispatcher)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackendDispatcher is a Dispatcher, that sends the requests to its Backend, i.e. one of the endpoints
+// of the LoadBalancingDispatcher. The request is transformed on each call, so the same request can be
+// dispatched to the different backends (see Proxy, that transforms the request once per dispatch)
+type BackendDispatcher struct {
+	backend    Backend
+	dispatcher Dispatcher
+}
+
+// NewBackendDispatcher is a factory method, that creates a BackendDispatcher, that sends the requests
+// to the backend with the given dispatcher
+func NewBackendDispatcher(backend Backend, dispatcher Dispatcher) *BackendDispatcher {
+	return &BackendDispatcher{backend: backend, dispatcher: dispatcher}
+}
+
+// Do dispatches the copy of the request, transformed for the backend
+func (d *BackendDispatcher) Do(ctx context.Context, req Request) Response {
+	backendReq, err := req.Clone()
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	if backendReq, err = backendReq.Transform(d.backend); err != nil {
+		return NewErrorResponse(err)
+	}
+	return d.dispatcher.Do(ctx, backendReq)
+}
+
+// Describe adds the endpoint of the backend and the details of the dispatcher to the info
+func (d *BackendDispatcher) Describe(info *ComponentInfo) {
+	describeIfDescriber(d.dispatcher, info)
+	info.Endpoint = d.backend.URL("")
+}
+
+// Close closes the dispatcher of the endpoint (see Closer)
+func (d *BackendDispatcher) Close(ctx context.Context) error {
+	return closeIfCloser(ctx, d.dispatcher)
+}
+
+// WarmUp establishes the connection of the dispatcher to the backend, if it's a Warmer
+func (d *BackendDispatcher) WarmUp(ctx context.Context) error {
+	return warmUpIfWarmer(withWarmUpEndpoint(ctx, d.backend.URL("")), d.dispatcher)
+}
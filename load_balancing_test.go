@@ -0,0 +1,135 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	fiberHTTP "github.com/gojek/fiber/http"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLoadBalancingDispatcher(t *testing.T) {
+	_, err := fiber.NewLoadBalancingDispatcher(fiber.RoundRobinLoadBalancing)
+	assert.EqualError(t, err, "load balancing: at least one dispatcher is required")
+
+	_, err = fiber.NewLoadBalancingDispatcher(fiber.RoundRobinLoadBalancing, nil)
+	assert.EqualError(t, err, "load balancing: dispatcher can not be nil")
+
+	_, err = fiber.NewLoadBalancingDispatcher("random", testutils.NewMockBackend())
+	assert.EqualError(t, err, "load balancing: unknown policy [random], expected round_robin or least_connections")
+}
+
+func TestLoadBalancingDispatcher_Do(t *testing.T) {
+	for _, policy := range []fiber.LoadBalancingPolicy{"", fiber.LeastConnectionsLoadBalancing} {
+		t.Run(string(policy), func(t *testing.T) {
+			backends := []*testutils.MockBackend{
+				testutils.NewMockBackend(), testutils.NewMockBackend(), testutils.NewMockBackend(),
+			}
+			dispatcher, err := fiber.NewLoadBalancingDispatcher(policy, backends[0], backends[1], backends[2])
+			require.NoError(t, err)
+
+			// the sequential requests are spread evenly, since none of them is in flight, when the next one is sent
+			for i := 0; i < 9; i++ {
+				resp := dispatcher.Do(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", ""))
+				require.True(t, resp.IsSuccess())
+			}
+			for _, backend := range backends {
+				assert.Equal(t, 3, backend.Calls())
+			}
+		})
+	}
+}
+
+func TestLoadBalancingDispatcher_LeastConnections(t *testing.T) {
+	slow := testutils.NewMockBackend().
+		ThenRespond(testUtilsHttp.MockResp(http.StatusOK, "slow", nil, nil), 100*time.Millisecond)
+	fast := testutils.NewMockBackend()
+	dispatcher, err := fiber.NewLoadBalancingDispatcher(fiber.LeastConnectionsLoadBalancing, slow, fast)
+	require.NoError(t, err)
+
+	// the slow endpoint is busy with the first request, so the rest of them are sent to the fast one
+	done := make(chan fiber.Response)
+	go func() {
+		done <- dispatcher.Do(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", ""))
+	}()
+	require.Eventually(t, func() bool { return slow.Calls() == 1 }, time.Second, time.Millisecond)
+	for i := 0; i < 4; i++ {
+		dispatcher.Do(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", ""))
+	}
+	assert.Equal(t, "slow", string((<-done).Payload()))
+	assert.Equal(t, 1, slow.Calls())
+	assert.Equal(t, 4, fast.Calls())
+}
+
+func TestLoadBalancingDispatcher_Fallback(t *testing.T) {
+	unavailable := fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP))
+	failing := []*testutils.MockBackend{
+		testutils.NewMockBackend(unavailable), testutils.NewMockBackend(unavailable),
+	}
+	pool, err := fiber.NewLoadBalancingDispatcher(fiber.RoundRobinLoadBalancing, failing[0], failing[1])
+	require.NoError(t, err)
+
+	// each attempt of the retrying dispatcher is sent to the next endpoint
+	retrying, err := fiber.NewRetryingDispatcher(pool, fiber.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+	require.NoError(t, err)
+	route, err := fiber.NewCaller("route-a", retrying)
+	require.NoError(t, err)
+
+	// the router falls back to the next route, once all the attempts of the pool have failed
+	router := testutils.NewRouterBuilder("router").
+		WithComponent(route).
+		WithRoute("route-b", testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil)).
+		WithOrder([]string{"route-a", "route-b"}, 0, nil).
+		BuildLazy()
+	responses := testutils.Dispatch(
+		context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+	require.Len(t, responses, 1)
+	assert.Equal(t, "B-OK", string(responses[0].Payload()))
+	assert.Equal(t, 1, failing[0].Calls())
+	assert.Equal(t, 1, failing[1].Calls())
+}
+
+// urlRecordingDispatcher records the urls of the requests
+type urlRecordingDispatcher struct {
+	lock sync.Mutex
+	urls []string
+}
+
+func (d *urlRecordingDispatcher) Do(_ context.Context, req fiber.Request) fiber.Response {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.urls = append(d.urls, req.(*fiberHTTP.Request).URL.String())
+	return testUtilsHttp.MockResp(http.StatusOK, "", nil, nil)
+}
+
+func TestBackendDispatcher_Do(t *testing.T) {
+	recorder := &urlRecordingDispatcher{}
+	dispatcher, err := fiber.NewLoadBalancingDispatcher(fiber.RoundRobinLoadBalancing,
+		fiber.NewBackendDispatcher(fiber.NewBackend("route-a", "http://replica-1:8080"), recorder),
+		fiber.NewBackendDispatcher(fiber.NewBackend("route-a", "http://replica-2:8080"), recorder))
+	require.NoError(t, err)
+
+	// the copy of the request is transformed on each call, so the same request is sent to the both endpoints
+	req := testUtilsHttp.MockReq("GET", "http://localhost/predict?model=a", "")
+	for i := 0; i < 2; i++ {
+		require.True(t, dispatcher.Do(context.Background(), req).IsSuccess())
+	}
+	assert.Equal(t, []string{
+		"http://replica-1:8080/predict?model=a",
+		"http://replica-2:8080/predict?model=a",
+	}, recorder.urls)
+	assert.Equal(t, "http://localhost/predict?model=a", req.URL.String())
+
+	info := fiber.ComponentInfo{}
+	dispatcher.Describe(&info)
+	assert.Equal(t, []string{"http://replica-1:8080", "http://replica-2:8080"}, info.Endpoints)
+}
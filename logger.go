@@ -0,0 +1,249 @@
+package fiber
+
+import (
+	"context"
+	stdlog "log"
+	"sync/atomic"
+	"time"
+
+	"github.com/gojek/fiber/protocol"
+	"github.com/gojek/fiber/util"
+)
+
+// CorrelationIDHeader is the header (or the grpc metadata key) of the request, that carries its
+// correlation id. Routers with the Logger read the correlation id from it, or generate a new one,
+// and set it on the requests they dispatch by their routes
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// LogEvent is a routing decision, made by a router while it dispatches the request
+type LogEvent string
+
+const (
+	// RouteSelectedEvent is logged when the routing strategy selects the primary route
+	RouteSelectedEvent LogEvent = "route selected"
+	// RouteAttemptStartedEvent is logged when the request is dispatched by a route
+	RouteAttemptStartedEvent LogEvent = "route attempt started"
+	// RouteAttemptFinishedEvent is logged when the route has responded
+	RouteAttemptFinishedEvent LogEvent = "route attempt finished"
+	// FallbackTriggeredEvent is logged when the router switches to the next fallback route
+	FallbackTriggeredEvent LogEvent = "fallback triggered"
+	// HedgeTriggeredEvent is logged when the router sends the hedged request to the next route (see HedgingPolicy)
+	HedgeTriggeredEvent LogEvent = "hedge triggered"
+	// ResponseChosenEvent is logged when the router has chosen the response to send back
+	ResponseChosenEvent LogEvent = "response chosen"
+	// PanicRecoveredEvent is logged when a route, the routing strategy or an interceptor has panicked,
+	// and the panic has been recovered
+	PanicRecoveredEvent LogEvent = "panic recovered"
+	// DecodeFailedEvent is logged when the response of a route can't be decoded (see DecodeErrorPolicy)
+	DecodeFailedEvent LogEvent = "decode failed"
+)
+
+// LogEntry holds the structured fields of the routing decision
+type LogEntry struct {
+	Event         LogEvent
+	CorrelationID string
+	// RouterID is the id of the router, that made the decision
+	RouterID string
+	// RouteID is the id of the route, the decision is about. It's empty, when there is no such route,
+	// e.g. the error response is chosen, because the request has timed out
+	RouteID  string
+	Protocol protocol.Protocol
+	// Status is the status code of the route's response, or zero, if there is no response yet
+	Status int
+	// Latency is the time since the request was dispatched by the route, or by the router, for
+	// the ResponseChosenEvent, or the time, the routing strategy has taken to select the routes,
+	// for the RouteSelectedEvent
+	Latency time.Duration
+	// Error and Stack are the recovered panic and the stack trace of the goroutine, that has panicked,
+	// for the PanicRecoveredEvent, or the error of the DecodeFailedEvent
+	Error string
+	Stack string
+	// Size is the size of the payload, that can't be decoded, for the DecodeFailedEvent
+	Size int
+}
+
+// Logger is invoked by the routers at their key routing decisions. A router calls Log concurrently
+// for every incoming request, so the implementations must be safe for concurrent use
+type Logger interface {
+	Log(ctx context.Context, entry LogEntry)
+}
+
+// NoopLogger doesn't log anything
+type NoopLogger struct{}
+
+// Log is an empty method
+func (NoopLogger) Log(context.Context, LogEntry) {}
+
+// CorrelationID returns the correlation id of the request from the request context, or an empty
+// string if the request is not dispatched by a router with the Logger
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(CtxCorrelationIDKey).(string)
+	return id
+}
+
+// ctxDispatchLoggerKey is used to pass the dispatchLogger of the request from the EagerRouter
+// to its fan in
+type ctxDispatchLoggerKey struct{}
+
+// withDispatchLogger passes the dispatchLogger of the router to its routes and its fan in. The logger
+// of the parent router is not used for the routing decisions of the router without its own one
+func withDispatchLogger(ctx context.Context, log *dispatchLogger) context.Context {
+	if log != nil || ctx.Value(ctxDispatchLoggerKey{}) != nil {
+		return context.WithValue(ctx, ctxDispatchLoggerKey{}, log)
+	}
+	return ctx
+}
+
+// ctxDispatchLogger returns the dispatchLogger of the router, that has dispatched the request, if any
+func ctxDispatchLogger(ctx context.Context) *dispatchLogger {
+	log, _ := ctx.Value(ctxDispatchLoggerKey{}).(*dispatchLogger)
+	return log
+}
+
+// dispatchLogger logs the routing decisions of a router for a single request, and records its timing,
+// if it's enabled (see WithTiming). A nil dispatchLogger (the router has no Logger) does nothing
+type dispatchLogger struct {
+	logger        Logger
+	accessLogger  AccessLogger
+	timing        *timingRecorder
+	routerID      string
+	correlationID string
+	protocol      protocol.Protocol
+	start         time.Time
+	// attempts is the number of the routes, that have dispatched the request
+	attempts int32
+}
+
+// newDispatchLogger returns the dispatchLogger of the request and the request context with its
+// correlation id. The correlation id is taken from the request context of the parent router, or from
+// the request header, or generated, if neither of them has it. Either of the loggers can be nil
+func newDispatchLogger(
+	ctx context.Context,
+	logger Logger,
+	accessLogger AccessLogger,
+	routerID string,
+	req Request,
+) (*dispatchLogger, context.Context) {
+	timing := claimTiming(ctx, routerID)
+	if logger == nil && accessLogger == nil {
+		if timing == nil {
+			return nil, ctx
+		}
+		// the timing alone doesn't tag the requests with the correlation id
+		return &dispatchLogger{timing: timing, routerID: routerID, protocol: req.Protocol(), start: time.Now()}, ctx
+	}
+
+	id := CorrelationID(ctx)
+	if id == "" {
+		if values := req.Header()[headerKey(req.Protocol(), CorrelationIDHeader)]; len(values) > 0 && values[0] != "" {
+			id = values[0]
+		} else {
+			id = util.UID()
+		}
+		ctx = context.WithValue(ctx, CtxCorrelationIDKey, id)
+	}
+
+	return &dispatchLogger{
+		logger:        logger,
+		accessLogger:  accessLogger,
+		timing:        timing,
+		routerID:      routerID,
+		correlationID: id,
+		protocol:      req.Protocol(),
+		start:         time.Now(),
+	}, ctx
+}
+
+// tag sets the correlation id on the request, that is dispatched by a route
+func (l *dispatchLogger) tag(req Request) {
+	if l != nil && l.correlationID != "" {
+		SetRequestHeader(req, CorrelationIDHeader, l.correlationID)
+	}
+}
+
+// log logs the event about the route with the status of the given response, if any
+func (l *dispatchLogger) log(ctx context.Context, event LogEvent, routeID string, resp Response, latency time.Duration) {
+	if l == nil {
+		return
+	}
+	if event == RouteAttemptStartedEvent {
+		atomic.AddInt32(&l.attempts, 1)
+	}
+	if l.timing != nil {
+		l.timing.record(event, routeID, resp, latency)
+	}
+	if l.logger == nil {
+		return
+	}
+	entry := LogEntry{
+		Event:         event,
+		CorrelationID: l.correlationID,
+		RouterID:      l.routerID,
+		RouteID:       routeID,
+		Protocol:      l.protocol,
+		Latency:       latency,
+	}
+	if resp != nil {
+		entry.Status = resp.StatusCode()
+	}
+	l.logger.Log(ctx, entry)
+}
+
+// logResponse logs the response chosen by the router, and writes the access log record of the dispatch
+func (l *dispatchLogger) logResponse(ctx context.Context, resp Response) {
+	if l == nil {
+		return
+	}
+	latency := time.Since(l.start)
+	l.log(ctx, ResponseChosenEvent, resp.BackendName(), resp, latency)
+	if l.accessLogger != nil {
+		l.accessLogger.LogAccess(ctx, AccessLogEntry{
+			CorrelationID: l.correlationID,
+			RouterID:      l.routerID,
+			RouteID:       resp.BackendName(),
+			Protocol:      l.protocol,
+			Attempts:      int(atomic.LoadInt32(&l.attempts)),
+			Status:        resp.StatusCode(),
+			Latency:       latency,
+		})
+	}
+}
+
+// logPanic logs the recovered panic of the component, e.g. the route, with its stack trace. If the router
+// has no Logger, the panic is logged by the standard logger, so it's not silenced
+func (l *dispatchLogger) logPanic(ctx context.Context, componentID string, err *PanicError) {
+	if l == nil || l.logger == nil {
+		stdlog.Printf("fiber: [%s]: %v\n%s", componentID, err, err.Stack)
+		return
+	}
+	l.logger.Log(ctx, LogEntry{
+		Event:         PanicRecoveredEvent,
+		CorrelationID: l.correlationID,
+		RouterID:      l.routerID,
+		RouteID:       componentID,
+		Protocol:      l.protocol,
+		Error:         err.Error(),
+		Stack:         string(err.Stack),
+	})
+}
+
+// LogDecodeError logs the response of the route, that can't be decoded, with the error and the size of
+// its payload, by the Logger of the router, that has dispatched the request. If the router has no Logger,
+// the error is logged by the standard logger
+func LogDecodeError(ctx context.Context, resp Response, err error) {
+	l := ctxDispatchLogger(ctx)
+	if l == nil || l.logger == nil {
+		stdlog.Printf("fiber: [%s]: %v (%d bytes)", resp.BackendName(), err, len(resp.Payload()))
+		return
+	}
+	l.logger.Log(ctx, LogEntry{
+		Event:         DecodeFailedEvent,
+		CorrelationID: l.correlationID,
+		RouterID:      l.routerID,
+		RouteID:       resp.BackendName(),
+		Protocol:      l.protocol,
+		Status:        resp.StatusCode(),
+		Error:         err.Error(),
+		Size:          len(resp.Payload()),
+	})
+}
@@ -0,0 +1,215 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/grpc"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+// recordingLogger records the log entries without their latencies
+type recordingLogger struct {
+	lock    sync.Mutex
+	entries []fiber.LogEntry
+}
+
+func (l *recordingLogger) Log(_ context.Context, entry fiber.LogEntry) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	entry.Latency = 0
+	l.entries = append(l.entries, entry)
+}
+
+func (l *recordingLogger) Entries() []fiber.LogEntry {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]fiber.LogEntry(nil), l.entries...)
+}
+
+type loggedRouter interface {
+	fiber.Router
+	SetLogger(logger fiber.Logger)
+}
+
+func newLoggedRoutes(t *testing.T) (map[string]fiber.Component, *headerRecordingDispatcher, *headerRecordingDispatcher) {
+	dispatcherA := &headerRecordingDispatcher{
+		response: testUtilsHttp.MockResp(
+			http.StatusServiceUnavailable, "A-NOK", nil, fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+	}
+	dispatcherB := &headerRecordingDispatcher{response: testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil)}
+	callerA, err := fiber.NewCaller("route-a", dispatcherA)
+	require.NoError(t, err)
+	callerB, err := fiber.NewCaller("route-b", dispatcherB)
+	require.NoError(t, err)
+	return map[string]fiber.Component{"route-a": callerA, "route-b": callerB}, dispatcherA, dispatcherB
+}
+
+func logEntry(event fiber.LogEvent, correlationID string, routeID string, status int) fiber.LogEntry {
+	return fiber.LogEntry{
+		Event:         event,
+		CorrelationID: correlationID,
+		RouterID:      "router",
+		RouteID:       routeID,
+		Protocol:      protocol.HTTP,
+		Status:        status,
+	}
+}
+
+func TestRouter_Logger(t *testing.T) {
+	suite := map[string]struct {
+		router   func(routes map[string]fiber.Component) loggedRouter
+		expected func(correlationID string) []fiber.LogEntry
+		ordered  bool
+	}{
+		"lazy router": {
+			router: func(routes map[string]fiber.Component) loggedRouter {
+				router := fiber.NewLazyRouter("router")
+				router.SetRoutes(routes)
+				router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+				return router
+			},
+			expected: func(id string) []fiber.LogEntry {
+				return []fiber.LogEntry{
+					logEntry(fiber.RouteSelectedEvent, id, "route-a", 0),
+					logEntry(fiber.RouteAttemptStartedEvent, id, "route-a", 0),
+					logEntry(fiber.RouteAttemptFinishedEvent, id, "route-a", http.StatusServiceUnavailable),
+					logEntry(fiber.FallbackTriggeredEvent, id, "route-b", 0),
+					logEntry(fiber.RouteAttemptStartedEvent, id, "route-b", 0),
+					logEntry(fiber.RouteAttemptFinishedEvent, id, "route-b", http.StatusOK),
+					logEntry(fiber.ResponseChosenEvent, id, "route-b", http.StatusOK),
+				}
+			},
+			ordered: true,
+		},
+		"eager router": {
+			router: func(routes map[string]fiber.Component) loggedRouter {
+				router := fiber.NewEagerRouter("router")
+				router.SetRoutes(routes)
+				router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+				return router
+			},
+			expected: func(id string) []fiber.LogEntry {
+				// all the routes are dispatched at once, so the events are not ordered
+				return []fiber.LogEntry{
+					logEntry(fiber.RouteAttemptStartedEvent, id, "route-a", 0),
+					logEntry(fiber.RouteAttemptStartedEvent, id, "route-b", 0),
+					logEntry(fiber.RouteSelectedEvent, id, "route-a", 0),
+					logEntry(fiber.RouteAttemptFinishedEvent, id, "route-a", http.StatusServiceUnavailable),
+					logEntry(fiber.RouteAttemptFinishedEvent, id, "route-b", http.StatusOK),
+					logEntry(fiber.FallbackTriggeredEvent, id, "route-b", 0),
+					logEntry(fiber.ResponseChosenEvent, id, "route-b", http.StatusOK),
+				}
+			},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			for _, correlationID := range []string{"", "request-1"} {
+				routes, dispatcherA, dispatcherB := newLoggedRoutes(t)
+				logger := &recordingLogger{}
+				router := tt.router(routes)
+				router.SetLogger(logger)
+
+				req := testUtilsHttp.MockReq("GET", "http://localhost:8080/logger", "")
+				if correlationID != "" {
+					fiber.SetRequestHeader(req, fiber.CorrelationIDHeader, correlationID)
+				}
+
+				resp, ok := <-router.Dispatch(context.Background(), req).Iter()
+				require.True(t, ok)
+				assert.Equal(t, "B-OK", string(resp.Payload()))
+
+				entries := logger.Entries()
+				require.NotEmpty(t, entries)
+				if correlationID == "" {
+					// the correlation id is generated
+					correlationID = entries[0].CorrelationID
+					assert.NotEmpty(t, correlationID)
+					assert.Empty(t, http.Header(req.Header()).Get(fiber.CorrelationIDHeader))
+				}
+				if tt.ordered {
+					assert.Equal(t, tt.expected(correlationID), entries)
+				} else {
+					assert.ElementsMatch(t, tt.expected(correlationID), entries)
+				}
+
+				for _, dispatcher := range []*headerRecordingDispatcher{dispatcherA, dispatcherB} {
+					dispatcher.lock.Lock()
+					assert.Equal(t, []string{correlationID}, http.Header(dispatcher.header).Values(fiber.CorrelationIDHeader))
+					dispatcher.lock.Unlock()
+				}
+			}
+		})
+	}
+}
+
+func TestRouter_Logger_GRPCMetadata(t *testing.T) {
+	suite := map[string]func(routes map[string]fiber.Component) loggedRouter{
+		"lazy router": func(routes map[string]fiber.Component) loggedRouter {
+			router := fiber.NewLazyRouter("router")
+			router.SetRoutes(routes)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+			return router
+		},
+		"lazy router with hedging": func(routes map[string]fiber.Component) loggedRouter {
+			router := fiber.NewLazyRouter("router")
+			router.SetRoutes(routes)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+			require.NoError(t, router.SetHedgingPolicy(&fiber.HedgingPolicy{Delay: time.Millisecond}))
+			return router
+		},
+		"eager router": func(routes map[string]fiber.Component) loggedRouter {
+			router := fiber.NewEagerRouter("router")
+			router.SetRoutes(routes)
+			router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+			return router
+		},
+	}
+
+	for name, newRouter := range suite {
+		t.Run(name, func(t *testing.T) {
+			routes, _, dispatcherB := newLoggedRoutes(t)
+			router := newRouter(routes)
+			router.SetLogger(&recordingLogger{})
+
+			req := &grpc.Request{Metadata: metadata.Pairs("key", "value")}
+			_, ok := <-router.Dispatch(context.Background(), req).Iter()
+			require.True(t, ok)
+
+			// the correlation id is set on the copy of the metadata, that is sent by the routes
+			assert.Empty(t, req.Metadata.Get(fiber.CorrelationIDHeader))
+			assert.Equal(t, []string{"value"}, req.Metadata.Get("key"))
+			dispatcherB.lock.Lock()
+			assert.Len(t, dispatcherB.header[strings.ToLower(fiber.CorrelationIDHeader)], 1)
+			dispatcherB.lock.Unlock()
+		})
+	}
+}
+
+func TestRouter_WithoutLogger(t *testing.T) {
+	routes, dispatcherA, _ := newLoggedRoutes(t)
+	router := fiber.NewLazyRouter("router")
+	router.SetRoutes(routes)
+	router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+
+	resp, ok := <-router.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost:8080", "")).Iter()
+	require.True(t, ok)
+	assert.Equal(t, "B-OK", string(resp.Payload()))
+
+	dispatcherA.lock.Lock()
+	defer dispatcherA.lock.Unlock()
+	assert.Empty(t, http.Header(dispatcherA.header).Values(fiber.CorrelationIDHeader))
+}
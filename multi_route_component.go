@@ -1,5 +1,7 @@
 package fiber
 
+import "context"
+
 // MultiRouteComponent - is a network component with zero or more possible routes,
 // such as FanOut, Combiner, Router
 type MultiRouteComponent interface {
@@ -32,6 +34,28 @@ func (multiRoute *BaseMultiRouteComponent) GetRoutes() map[string]Component {
 	return multiRoute.routes
 }
 
+// Close closes all the routes of this multi-route component, that hold any resources (see Closer).
+// The first error, if any, is returned
+func (multiRoute *BaseMultiRouteComponent) Close(ctx context.Context) error {
+	var err error
+	for _, route := range multiRoute.routes {
+		if closeErr := closeIfCloser(ctx, route); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// WarmUp warms up all the routes of this multi-route component concurrently (see Warmer)
+func (multiRoute *BaseMultiRouteComponent) WarmUp(ctx context.Context) error {
+	return warmUpRoutes(ctx, multiRoute.routes)
+}
+
+// Describe adds the descriptions of the routes of this multi-route component to the info
+func (multiRoute *BaseMultiRouteComponent) Describe(info *ComponentInfo) {
+	info.Routes = describeRoutes(multiRoute.routes)
+}
+
 // AddInterceptor can be used to (optionally, recursively) add one or more interceptors to
 // the BaseMultiRouteComponent
 func (multiRoute *BaseMultiRouteComponent) AddInterceptor(recursive bool, interceptors ...Interceptor) {
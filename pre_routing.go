@@ -0,0 +1,41 @@
+package fiber
+
+import (
+	"context"
+
+	"github.com/gojek/fiber/errors"
+)
+
+// PreRoutingHook enriches the request, before the routing strategy of the router selects the routes, e.g. with
+// the values derived from its payload (such as the tier of the tenant). The returned context is passed to the strategy
+// and the routes, so the strategy can key off the values added to it. If the error is returned, the request is not
+// dispatched, and the error is sent back as the response (a *errors.FiberError keeps its status code)
+type PreRoutingHook func(ctx context.Context, req Request) (context.Context, error)
+
+// preRoute runs the hook, if it's set, and returns the enriched context, or the error response,
+// if the hook has failed
+func preRoute(ctx context.Context, hook PreRoutingHook, req Request) (context.Context, Response) {
+	enriched, err := runPreRoutingHook(ctx, hook, req)
+	if err != nil {
+		if fiberErr, ok := err.(*errors.FiberError); ok {
+			return ctx, NewErrorResponse(fiberErr)
+		}
+		return ctx, NewErrorResponse(errors.NewFiberError(req.Protocol(), err))
+	}
+	return enriched, nil
+}
+
+// runPreRoutingHook runs the hook, if it's set, and returns the enriched context, or the error of the hook
+func runPreRoutingHook(ctx context.Context, hook PreRoutingHook, req Request) (context.Context, error) {
+	if hook == nil {
+		return ctx, nil
+	}
+	enriched, err := hook(ctx, req)
+	if err != nil {
+		return ctx, err
+	}
+	if enriched == nil {
+		return ctx, nil
+	}
+	return enriched, nil
+}
@@ -0,0 +1,132 @@
+package fiber_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+type tierKey struct{}
+
+// tierRoutingStrategy routes the requests by the tier of the tenant, set in the context by the pre-routing hook
+type tierRoutingStrategy struct {
+	fiber.BaseFiberType
+}
+
+func (s *tierRoutingStrategy) SelectRoute(
+	ctx context.Context,
+	_ fiber.Request,
+	routes map[string]fiber.Component,
+) (fiber.Component, []fiber.Component, error) {
+	if tier, _ := ctx.Value(tierKey{}).(string); tier == "gold" {
+		return routes["route-gold"], []fiber.Component{routes["route-default"]}, nil
+	}
+	return routes["route-default"], []fiber.Component{routes["route-gold"]}, nil
+}
+
+// tierHook derives the tier of the tenant from the payload of the request
+func tierHook(ctx context.Context, req fiber.Request) (context.Context, error) {
+	switch string(req.Payload()) {
+	case "":
+		return nil, fiberErrors.ErrInvalidInput(req.Protocol(), errors.New("tenant is required"))
+	case "invalid":
+		return nil, errors.New("tenant can not be resolved")
+	case "tenant-a":
+		return context.WithValue(ctx, tierKey{}, "gold"), nil
+	default:
+		return ctx, nil
+	}
+}
+
+type preRoutingRouter interface {
+	fiber.Router
+	SetPreRoutingHook(hook fiber.PreRoutingHook)
+}
+
+func TestRouter_PreRoutingHook(t *testing.T) {
+	routers := map[string]func() preRoutingRouter{
+		"lazy router":  func() preRoutingRouter { return fiber.NewLazyRouter("lazy-router") },
+		"eager router": func() preRoutingRouter { return fiber.NewEagerRouter("eager-router") },
+	}
+
+	suite := map[string]struct {
+		request         fiber.Request
+		expectedStatus  int
+		expectedPayload string
+		expectedErrMsg  string
+	}{
+		"http: the strategy reads the value set by the hook": {
+			request:         testUtilsHttp.MockReq("POST", "http://localhost", "tenant-a"),
+			expectedStatus:  http.StatusOK,
+			expectedPayload: "route-gold",
+		},
+		"http: the request without the value": {
+			request:         testUtilsHttp.MockReq("POST", "http://localhost", "tenant-b"),
+			expectedStatus:  http.StatusOK,
+			expectedPayload: "route-default",
+		},
+		"http: the fiber error of the hook aborts the dispatch": {
+			request:        testUtilsHttp.MockReq("POST", "http://localhost", ""),
+			expectedStatus: http.StatusBadRequest,
+			expectedErrMsg: "fiber: tenant is required",
+		},
+		"grpc: the strategy reads the value set by the hook": {
+			request:         &grpcRequest{Request: testUtilsHttp.MockReq("POST", "http://localhost", "tenant-a")},
+			expectedStatus:  http.StatusOK,
+			expectedPayload: "route-gold",
+		},
+		"grpc: the fiber error of the hook aborts the dispatch": {
+			request:        &grpcRequest{Request: testUtilsHttp.MockReq("POST", "http://localhost", "")},
+			expectedStatus: int(codes.InvalidArgument),
+			expectedErrMsg: "fiber: tenant is required",
+		},
+		"grpc: the other errors of the hook abort the dispatch": {
+			request:        &grpcRequest{Request: testUtilsHttp.MockReq("POST", "http://localhost", "invalid")},
+			expectedStatus: int(codes.Internal),
+			expectedErrMsg: "fiber: request cannot be completed: tenant can not be resolved",
+		},
+	}
+
+	for routerName, newRouter := range routers {
+		for name, tt := range suite {
+			t.Run(routerName+"/"+name, func(t *testing.T) {
+				gold, def := newSlowComponent("route-gold", 0), newSlowComponent("route-default", 0)
+				router := newRouter()
+				router.SetRoutes(map[string]fiber.Component{gold.ID(): gold, def.ID(): def})
+				router.SetStrategy(&tierRoutingStrategy{})
+				router.SetPreRoutingHook(tierHook)
+
+				resp := <-router.Dispatch(context.Background(), tt.request).Iter()
+				require.NotNil(t, resp)
+				assert.Equal(t, tt.expectedStatus, resp.StatusCode())
+				if tt.expectedErrMsg != "" {
+					assert.Contains(t, string(resp.Payload()), tt.expectedErrMsg)
+					// the request is not dispatched by the routes
+					assert.Equal(t, int32(0), atomic.LoadInt32(&gold.completed)+atomic.LoadInt32(&def.completed))
+				} else {
+					assert.Equal(t, tt.expectedPayload, string(resp.Payload()))
+				}
+			})
+		}
+	}
+}
+
+func TestRouter_PreRoutingHookNotSet(t *testing.T) {
+	router := fiber.NewLazyRouter("lazy-router")
+	route := newSlowComponent("route-default", 0)
+	router.SetRoutes(map[string]fiber.Component{route.ID(): route, "route-gold": newSlowComponent("route-gold", 0)})
+	router.SetStrategy(&tierRoutingStrategy{})
+
+	resp := <-router.Dispatch(context.Background(), testUtilsHttp.MockReq("POST", "http://localhost", "")).Iter()
+	require.True(t, resp.IsSuccess())
+	assert.Equal(t, "route-default", string(resp.Payload()))
+}
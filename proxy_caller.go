@@ -8,8 +8,12 @@ type Proxy struct {
 	backend Backend
 }
 
-// Dispatch is used to dispatch the incoming request against the proxy backend
+// Dispatch is used to dispatch the incoming request against the proxy backend. The request is dispatched
+// as it is, if the proxy has no backend (e.g. the grpc backend, that is dialed by its dispatcher)
 func (p *Proxy) Dispatch(ctx context.Context, req Request) ResponseQueue {
+	if p.backend == nil {
+		return p.Component.Dispatch(ctx, req)
+	}
 	proxyReq, err := req.Transform(p.backend)
 
 	if err != nil {
@@ -26,3 +30,29 @@ func NewProxy(backend Backend, component Component) *Proxy {
 		backend:   backend,
 	}
 }
+
+// Saturated returns true, if the component of the proxy is saturated (see SaturationReporter)
+func (p *Proxy) Saturated() bool {
+	return saturatedIfReporter(p.Component)
+}
+
+// Describe adds the endpoint of the backend and the details of the component of the proxy to the info
+func (p *Proxy) Describe(info *ComponentInfo) {
+	if p.backend != nil {
+		info.Endpoint = p.backend.URL("")
+	}
+	describeIfDescriber(p.Component, info)
+}
+
+// Close closes the component, that the proxy forwards the requests to (see Closer), e.g. the Caller of the backend
+func (p *Proxy) Close(ctx context.Context) error {
+	return closeIfCloser(ctx, p.Component)
+}
+
+// WarmUp warms up the component of the proxy (see Warmer), with the endpoint of its backend, if it has one
+func (p *Proxy) WarmUp(ctx context.Context) error {
+	if p.backend != nil {
+		ctx = withWarmUpEndpoint(ctx, p.backend.URL(""))
+	}
+	return warmUpIfWarmer(ctx, p.Component)
+}
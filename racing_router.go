@@ -0,0 +1,80 @@
+package fiber
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/util"
+)
+
+// RacingRouter dispatches incoming request by all of its routes simultaneously and returns
+// the first successful response, regardless of the route, that it came from. Once the response
+// is selected, the dispatches of the routes, that haven't responded yet, are cancelled (see Combiner),
+// so they can free their resources.
+//
+// Unlike EagerRouter, RacingRouter doesn't prefer any of its routes, so it's useful for pure
+// redundancy, when all the routes serve the same responses
+type RacingRouter struct {
+	*Combiner
+}
+
+// NewRacingRouter initializes new RacingRouter
+func NewRacingRouter(id string) *RacingRouter {
+	if id == "" {
+		id = "racing-router_" + util.UID()
+	}
+	return &RacingRouter{
+		Combiner: NewCombiner(id).WithFanIn(&racingRouterFanIn{}),
+	}
+}
+
+// WithMaxConcurrency limits the number of the routes, that dispatch the request at the same time, so the rest
+// of the routes only join the race, once the in-flight ones have failed (see BaseFanOut.WithMaxConcurrency)
+func (r *RacingRouter) WithMaxConcurrency(limit int) *RacingRouter {
+	if fanOut, ok := r.FanOut.(*BaseFanOut); ok {
+		fanOut.WithMaxConcurrency(limit)
+	}
+	return r
+}
+
+// WithRandSource replaces the random source, that the routes are queued by, if the concurrency is limited,
+// with the given one (see BaseFanOut.WithRandSource)
+func (r *RacingRouter) WithRandSource(source rand.Source) *RacingRouter {
+	if fanOut, ok := r.FanOut.(*BaseFanOut); ok {
+		fanOut.WithRandSource(source)
+	}
+	return r
+}
+
+// racingRouterFanIn selects the first successful response of the routes. If none of the routes has
+// responded successfully, ErrServiceUnavailable is sent back with the failures of all the routes
+// (see ErrorResponse.RouteErrors), or ErrRequestTimeout, if the context of the request is done first
+type racingRouterFanIn struct {
+	BaseFanIn
+}
+
+func (fanIn *racingRouterFanIn) Aggregate(
+	ctx context.Context,
+	req Request,
+	queue ResponseQueue,
+) Response {
+	var routeErrors []errors.RouteError
+	responses := queue.Iter()
+	for {
+		select {
+		case resp, ok := <-responses:
+			if !ok {
+				return errAllRoutesFailed(req.Protocol(), routeErrors)
+			}
+			if resp.IsSuccess() {
+				return resp
+			}
+			routeErrors = append(routeErrors, newRouteError(req.Protocol(), RouteID(resp), resp))
+		case <-ctx.Done():
+			timeout := NewErrorResponse(errors.ErrRequestTimeout(req.Protocol())).(*ErrorResponse)
+			timeout.routeErrors = routeErrors
+			return timeout
+		}
+	}
+}
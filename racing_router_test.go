@@ -0,0 +1,130 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRacingRouter_Dispatch(t *testing.T) {
+	suite := map[string]struct {
+		routes              map[string]fiber.Component
+		expected            fiber.Response
+		expectedRouteErrors []fiberErrors.RouteError
+	}{
+		"fastest route wins": {
+			routes: map[string]fiber.Component{
+				"route-a": testutils.NewMockComponent("route-a", testUtilsHttp.DelayedResponse{
+					Latency:  50 * time.Millisecond,
+					Response: testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil),
+				}),
+				"route-b": testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+					Response: testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil),
+				}),
+			},
+			expected: testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil).WithBackendName("route-b"),
+		},
+		"fastest route failed, the fastest successful one wins": {
+			routes: map[string]fiber.Component{
+				"route-a": testutils.NewMockComponent("route-a", testUtilsHttp.DelayedResponse{
+					Latency:  50 * time.Millisecond,
+					Response: testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil),
+				}),
+				"route-b": testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+					Response: testUtilsHttp.MockResp(http.StatusInternalServerError, "", nil,
+						fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+				}),
+			},
+			expected: testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil).WithBackendName("route-a"),
+		},
+		"all routes failed": {
+			routes: map[string]fiber.Component{
+				"route-a": testutils.NewMockComponent("route-a", testUtilsHttp.DelayedResponse{
+					Latency: 50 * time.Millisecond,
+					Response: testUtilsHttp.MockResp(http.StatusBadGateway, "", nil,
+						&fiberErrors.FiberError{Code: http.StatusBadGateway, Message: "A-NOK"}),
+				}),
+				"route-b": testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+					Response: testUtilsHttp.MockResp(http.StatusInternalServerError, "", nil,
+						fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+				}),
+			},
+			expected: fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+			expectedRouteErrors: []fiberErrors.RouteError{
+				{RouteID: "route-b", Code: http.StatusServiceUnavailable, Message: "fiber: no responses received"},
+				{RouteID: "route-a", Code: http.StatusBadGateway, Message: "A-NOK"},
+			},
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			router := fiber.NewRacingRouter("racing-router")
+			router.SetRoutes(tt.routes)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			received := make([]fiber.Response, 0)
+			for resp := range router.Dispatch(ctx, testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter() {
+				received = append(received, resp)
+			}
+			if tt.expectedRouteErrors != nil {
+				require.Len(t, received, 1)
+				assert.Equal(t, tt.expected.StatusCode(), received[0].StatusCode())
+				assert.Equal(t, string(tt.expected.Payload()), string(received[0].Payload()))
+				assert.Equal(t, tt.expectedRouteErrors, received[0].(*fiber.ErrorResponse).RouteErrors())
+				return
+			}
+			assert.Equal(t, []fiber.Response{tt.expected}, received)
+		})
+	}
+}
+
+func TestRacingRouter_Timeout(t *testing.T) {
+	router := fiber.NewRacingRouter("racing-router")
+	router.SetRoutes(map[string]fiber.Component{
+		"route-a": newSlowComponent("route-a", 500*time.Millisecond),
+		"route-b": testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+			Response: testUtilsHttp.MockResp(http.StatusInternalServerError, "B-NOK", nil, nil),
+		}),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	responses := testutils.Dispatch(ctx, router, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+
+	// the router responds, once the request has timed out, without waiting for the slow route
+	require.Len(t, responses, 1)
+	assert.Equal(t, http.StatusRequestTimeout, responses[0].StatusCode())
+	assert.Equal(t, []fiberErrors.RouteError{
+		{RouteID: "route-b", Code: http.StatusInternalServerError, Message: "B-NOK"},
+	}, responses[0].(*fiber.ErrorResponse).RouteErrors())
+}
+
+func TestRacingRouter_CancelsLosers(t *testing.T) {
+	loser := newCancellableComponent("route-a", time.Minute)
+	router := fiber.NewRacingRouter("racing-router")
+	router.SetRoutes(map[string]fiber.Component{
+		"route-a": loser,
+		"route-b": newCancellableComponent("route-b", 0),
+	})
+
+	resp := <-router.Dispatch(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+	require.NotNil(t, resp)
+	assert.Equal(t, "route-b", string(resp.Payload()))
+
+	select {
+	case <-loser.cancelled:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the dispatch of the slower route hasn't been cancelled")
+	}
+}
@@ -0,0 +1,252 @@
+package fiber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
+)
+
+// RateLimitPolicy defines the rate of the requests, that the RateLimitedDispatcher sends to the backend
+type RateLimitPolicy struct {
+	// Rate is the number of the requests per second
+	Rate float64
+	// Burst is the number of the requests, that can be sent at once after a period of inactivity,
+	// defaults to 1
+	Burst int
+	// MaxWait, if set, is the longest time the request waits for its turn, if the rate is exceeded.
+	// Otherwise the request is responded with ErrRateLimited right away, so the router falls back
+	// to the next route
+	MaxWait time.Duration
+	// Key, if set, limits the rate of the requests of each key separately (e.g. of each tenant, see RequestKey),
+	// instead of the rate of all the requests together
+	Key RequestKeyFunc
+	// KeyLimits are the limits of the keys, that match their patterns. The first matching limit is used,
+	// and the keys, that match none of them, are limited by Rate and Burst
+	KeyLimits []KeyRateLimit
+	// MaxKeys is the number of the keys, which rates are tracked. The least recently used key is forgotten,
+	// once there are more of them. Defaults to DefaultMaxKeys
+	MaxKeys int
+}
+
+// KeyRateLimit is the rate limit of the keys, that match the pattern
+type KeyRateLimit struct {
+	// Pattern is matched against the whole key, with `*` matching any characters, e.g. `gold:*`
+	Pattern string
+	Rate    float64
+	// Burst defaults to 1
+	Burst int
+
+	regexp *regexp.Regexp
+}
+
+// RateLimitedDispatcher is a Dispatcher, that caps the rate of the requests to the backend with a token
+// bucket, so the quota of the backend is respected. The request, that exceeds the rate, either waits for
+// its turn up to MaxWait, or is responded with ErrRateLimited, if it would have to wait longer (or beyond
+// the deadline of its context). If the policy has the Key, each key of the requests has its own token bucket
+type RateLimitedDispatcher struct {
+	dispatcher Dispatcher
+	policy     RateLimitPolicy
+
+	// bucket limits all the requests, unless they are keyed
+	bucket *tokenBucket
+	// buckets are the token buckets of the keys of the requests
+	buckets *keyedStates
+}
+
+// NewRateLimitedDispatcher is a factory method, that creates a RateLimitedDispatcher around the given Dispatcher
+func NewRateLimitedDispatcher(dispatcher Dispatcher, policy RateLimitPolicy) (*RateLimitedDispatcher, error) {
+	if dispatcher == nil {
+		return nil, errors.New("rate limit: dispatcher can not be nil")
+	}
+	if !validRate(policy.Rate) {
+		return nil, errors.New("rate limit: rate must be positive")
+	}
+	if policy.Burst < 0 || policy.MaxWait < 0 {
+		return nil, errors.New("rate limit: burst and max_wait can not be negative")
+	}
+	if len(policy.KeyLimits) > 0 && policy.Key == nil {
+		return nil, errors.New("rate limit: key limits require the key of the requests")
+	}
+
+	limits := make([]KeyRateLimit, len(policy.KeyLimits))
+	for idx, limit := range policy.KeyLimits {
+		if limit.Pattern == "" || !validRate(limit.Rate) || limit.Burst < 0 {
+			return nil, fmt.Errorf("rate limit: invalid limit of the key pattern [%s]", limit.Pattern)
+		}
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(limit.Pattern), `\*`, ".*")
+		limit.regexp = regexp.MustCompile("^" + pattern + "$")
+		limits[idx] = limit
+	}
+	policy.KeyLimits = limits
+
+	if policy.Burst == 0 {
+		policy.Burst = 1
+	}
+	limited := &RateLimitedDispatcher{
+		dispatcher: dispatcher,
+		policy:     policy,
+	}
+	if policy.Key == nil {
+		limited.bucket = newTokenBucket(policy.Rate, policy.Burst)
+	} else {
+		limited.buckets = newKeyedStates(policy.MaxKeys, nil)
+	}
+	return limited, nil
+}
+
+// validRate returns true, if the rate is a positive number
+func validRate(rate float64) bool {
+	return rate > 0 && !math.IsInf(rate, 0) && !math.IsNaN(rate)
+}
+
+// tokenBucket returns the token bucket, that limits the rate of the request
+func (d *RateLimitedDispatcher) tokenBucket(req Request) *tokenBucket {
+	if d.policy.Key == nil {
+		return d.bucket
+	}
+	key := d.policy.Key(req)
+	return d.buckets.get(key, func() interface{} {
+		for _, limit := range d.policy.KeyLimits {
+			if limit.regexp.MatchString(key) {
+				burst := limit.Burst
+				if burst == 0 {
+					burst = 1
+				}
+				return newTokenBucket(limit.Rate, burst)
+			}
+		}
+		return newTokenBucket(d.policy.Rate, d.policy.Burst)
+	}).(*tokenBucket)
+}
+
+// Do dispatches the request, once it's within the rate, or responds with ErrRateLimited
+func (d *RateLimitedDispatcher) Do(ctx context.Context, req Request) Response {
+	bucket := d.tokenBucket(req)
+	wait, ok := bucket.reserve(ctx, d.policy.MaxWait)
+	if !ok {
+		return NewErrorResponse(fiberErrors.ErrRateLimited(req.Protocol()))
+	}
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			bucket.cancel()
+			return NewErrorResponse(fiberErrors.ErrRateLimited(req.Protocol()))
+		}
+	}
+	return d.dispatcher.Do(ctx, req)
+}
+
+// tokenBucket lets the requests through at the rate, and up to burst of them at once
+type tokenBucket struct {
+	rate  float64
+	burst int
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes the token of the request, and returns the time the request has to wait for it. The token
+// isn't taken, if the request would have to wait longer than maxWait or the deadline of its context
+func (b *tokenBucket) reserve(ctx context.Context, maxWait time.Duration) (time.Duration, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	b.tokens = b.available(now)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+
+	wait := b.wait(b.tokens)
+	if wait > maxWait {
+		return 0, false
+	}
+	if deadline, ok := ctx.Deadline(); ok && now.Add(wait).After(deadline) {
+		return 0, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+// available returns the number of the tokens, that are available at the given time. It must be called
+// with the lock held
+func (b *tokenBucket) available(now time.Time) float64 {
+	return math.Min(float64(b.burst), b.tokens+now.Sub(b.last).Seconds()*b.rate)
+}
+
+// wait returns the time, until the next token is available, if there are the given number of tokens now
+func (b *tokenBucket) wait(tokens float64) time.Duration {
+	return time.Duration((1 - tokens) / b.rate * float64(time.Second))
+}
+
+// saturated returns true, if the request would have to wait longer than maxWait for its token
+func (b *tokenBucket) saturated(maxWait time.Duration) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	tokens := b.available(time.Now())
+	return tokens < 1 && b.wait(tokens) > maxWait
+}
+
+// cancel returns the token of the request, that has been cancelled, while it was waiting for it
+func (b *tokenBucket) cancel() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.tokens++
+}
+
+// Saturated returns true, if the request would be responded with ErrRateLimited right away, since
+// it would have to wait longer than MaxWait for its turn, so the routers skip the route (see SaturationReporter).
+// The dispatcher, that limits the keys of the requests separately, is never saturated, since the keys
+// of the following requests are not known
+func (d *RateLimitedDispatcher) Saturated() bool {
+	if d.policy.Key != nil {
+		return false
+	}
+	return d.bucket.saturated(d.policy.MaxWait)
+}
+
+// isRateLimited returns true, if the request hasn't been dispatched to the backend, since it has
+// exceeded the rate limit (see RateLimitedDispatcher)
+func isRateLimited(proto protocol.Protocol, resp Response) bool {
+	errResp, ok := resp.(*ErrorResponse)
+	return ok && errResp.err != nil && *errResp.err == *fiberErrors.ErrRateLimited(proto)
+}
+
+// Close closes the dispatcher of the requests within the rate limit (see Closer). The limiters of the keys
+// hold no resources, so there is nothing to release
+func (d *RateLimitedDispatcher) Close(ctx context.Context) error {
+	return closeIfCloser(ctx, d.dispatcher)
+}
+
+// Describe adds the details of the dispatcher of the requests within the rate limit to the info
+func (d *RateLimitedDispatcher) Describe(info *ComponentInfo) {
+	describeIfDescriber(d.dispatcher, info)
+}
+
+// WarmUp establishes the connection of the underlying dispatcher to its backend, if it's a Warmer
+func (d *RateLimitedDispatcher) WarmUp(ctx context.Context) error {
+	return warmUpIfWarmer(ctx, d.dispatcher)
+}
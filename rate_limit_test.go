@@ -0,0 +1,288 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sequenceDispatcher responds with the given responses one by one, repeating the last one
+type sequenceDispatcher struct {
+	responses []fiber.Response
+	attempts  int
+}
+
+func (d *sequenceDispatcher) Do(context.Context, fiber.Request) fiber.Response {
+	resp := d.responses[len(d.responses)-1]
+	if d.attempts < len(d.responses) {
+		resp = d.responses[d.attempts]
+	}
+	d.attempts++
+	return resp
+}
+
+func TestNewRateLimitedDispatcher(t *testing.T) {
+	suite := map[string]struct {
+		dispatcher  fiber.Dispatcher
+		policy      fiber.RateLimitPolicy
+		expectedErr string
+	}{
+		"ok": {
+			dispatcher: &sequenceDispatcher{},
+			policy:     fiber.RateLimitPolicy{Rate: 10},
+		},
+		"error: nil dispatcher": {
+			policy:      fiber.RateLimitPolicy{Rate: 10},
+			expectedErr: "rate limit: dispatcher can not be nil",
+		},
+		"error: no rate": {
+			dispatcher:  &sequenceDispatcher{},
+			expectedErr: "rate limit: rate must be positive",
+		},
+		"error: negative burst": {
+			dispatcher:  &sequenceDispatcher{},
+			policy:      fiber.RateLimitPolicy{Rate: 10, Burst: -1},
+			expectedErr: "rate limit: burst and max_wait can not be negative",
+		},
+		"error: negative max wait": {
+			dispatcher:  &sequenceDispatcher{},
+			policy:      fiber.RateLimitPolicy{Rate: 10, MaxWait: -time.Second},
+			expectedErr: "rate limit: burst and max_wait can not be negative",
+		},
+		"error: key limits without the key": {
+			dispatcher:  &sequenceDispatcher{},
+			policy:      fiber.RateLimitPolicy{Rate: 10, KeyLimits: []fiber.KeyRateLimit{{Pattern: "gold", Rate: 100}}},
+			expectedErr: "rate limit: key limits require the key of the requests",
+		},
+		"error: invalid key limit": {
+			dispatcher: &sequenceDispatcher{},
+			policy: fiber.RateLimitPolicy{
+				Rate:      10,
+				Key:       fiber.RequestKey(false, "X-Tenant"),
+				KeyLimits: []fiber.KeyRateLimit{{Pattern: "gold"}},
+			},
+			expectedErr: "rate limit: invalid limit of the key pattern [gold]",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			_, err := fiber.NewRateLimitedDispatcher(tt.dispatcher, tt.policy)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRateLimitedDispatcher_Do(t *testing.T) {
+	ok := testUtilsHttp.MockResp(http.StatusOK, "OK", nil, nil)
+	rateLimited := fiber.NewErrorResponse(fiberErrors.ErrRateLimited(protocol.HTTP))
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080/predict", "")
+
+	t.Run("rejects the requests over the rate", func(t *testing.T) {
+		backend := &sequenceDispatcher{responses: []fiber.Response{ok}}
+		dispatcher, err := fiber.NewRateLimitedDispatcher(backend, fiber.RateLimitPolicy{Rate: 1, Burst: 2})
+		require.NoError(t, err)
+
+		assert.Equal(t, ok, dispatcher.Do(context.Background(), req))
+		assert.Equal(t, ok, dispatcher.Do(context.Background(), req))
+		assert.Equal(t, rateLimited, dispatcher.Do(context.Background(), req))
+		assert.Equal(t, 2, backend.attempts)
+	})
+
+	t.Run("waits within max wait", func(t *testing.T) {
+		backend := &sequenceDispatcher{responses: []fiber.Response{ok}}
+		dispatcher, err := fiber.NewRateLimitedDispatcher(backend,
+			fiber.RateLimitPolicy{Rate: 20, MaxWait: 200 * time.Millisecond})
+		require.NoError(t, err)
+
+		assert.Equal(t, ok, dispatcher.Do(context.Background(), req))
+		start := time.Now()
+		assert.Equal(t, ok, dispatcher.Do(context.Background(), req))
+		elapsed := time.Since(start)
+		assert.GreaterOrEqual(t, int64(elapsed), int64(40*time.Millisecond))
+		assert.Less(t, int64(elapsed), int64(200*time.Millisecond))
+		assert.Equal(t, 2, backend.attempts)
+	})
+
+	t.Run("doesn't wait longer than max wait", func(t *testing.T) {
+		backend := &sequenceDispatcher{responses: []fiber.Response{ok}}
+		dispatcher, err := fiber.NewRateLimitedDispatcher(backend,
+			fiber.RateLimitPolicy{Rate: 2, MaxWait: 100 * time.Millisecond})
+		require.NoError(t, err)
+
+		assert.Equal(t, ok, dispatcher.Do(context.Background(), req))
+		start := time.Now()
+		assert.Equal(t, rateLimited, dispatcher.Do(context.Background(), req))
+		assert.Less(t, int64(time.Since(start)), int64(50*time.Millisecond))
+		assert.Equal(t, 1, backend.attempts)
+	})
+
+	t.Run("doesn't wait beyond the deadline", func(t *testing.T) {
+		backend := &sequenceDispatcher{responses: []fiber.Response{ok}}
+		dispatcher, err := fiber.NewRateLimitedDispatcher(backend,
+			fiber.RateLimitPolicy{Rate: 10, MaxWait: time.Second})
+		require.NoError(t, err)
+
+		assert.Equal(t, ok, dispatcher.Do(context.Background(), req))
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, rateLimited, dispatcher.Do(ctx, req))
+		assert.Equal(t, 1, backend.attempts)
+	})
+}
+
+func tenantRequest(tenant string, path string) fiber.Request {
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080"+path, "")
+	req.Request.Header = http.Header{"X-Tenant": []string{tenant}}
+	return req
+}
+
+func TestRequestKey(t *testing.T) {
+	req := tenantRequest("gold", "/predict")
+
+	assert.Equal(t, "gold", fiber.RequestKey(false, "X-Tenant")(req))
+	assert.Equal(t, "gold::GET /predict", fiber.RequestKey(true, "x-tenant", "X-Missing")(req))
+}
+
+func TestRateLimitedDispatcher_DoKeyed(t *testing.T) {
+	ok := testUtilsHttp.MockResp(http.StatusOK, "OK", nil, nil)
+	rateLimited := fiber.NewErrorResponse(fiberErrors.ErrRateLimited(protocol.HTTP))
+
+	t.Run("limits each key separately", func(t *testing.T) {
+		backend := &sequenceDispatcher{responses: []fiber.Response{ok}}
+		dispatcher, err := fiber.NewRateLimitedDispatcher(backend, fiber.RateLimitPolicy{
+			Rate: 1,
+			Key:  fiber.RequestKey(true, "X-Tenant"),
+			KeyLimits: []fiber.KeyRateLimit{
+				{Pattern: "gold:*", Rate: 1, Burst: 3},
+				{Pattern: "*:GET /batch", Rate: 1, Burst: 2},
+			},
+		})
+		require.NoError(t, err)
+
+		// the unmatched keys are limited by the default rate
+		assert.Equal(t, ok, dispatcher.Do(context.Background(), tenantRequest("silver", "/predict")))
+		assert.Equal(t, rateLimited, dispatcher.Do(context.Background(), tenantRequest("silver", "/predict")))
+		// and each of them has its own token bucket
+		assert.Equal(t, ok, dispatcher.Do(context.Background(), tenantRequest("bronze", "/predict")))
+		// the first matching pattern limits the key
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, ok, dispatcher.Do(context.Background(), tenantRequest("gold", "/batch")))
+		}
+		assert.Equal(t, rateLimited, dispatcher.Do(context.Background(), tenantRequest("gold", "/batch")))
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, ok, dispatcher.Do(context.Background(), tenantRequest("silver", "/batch")))
+		}
+		assert.Equal(t, rateLimited, dispatcher.Do(context.Background(), tenantRequest("silver", "/batch")))
+		assert.Equal(t, 7, backend.attempts)
+		// the rate of the keyed requests is not known in advance
+		assert.False(t, dispatcher.Saturated())
+	})
+
+	t.Run("forgets the least recently used keys", func(t *testing.T) {
+		backend := &sequenceDispatcher{responses: []fiber.Response{ok}}
+		dispatcher, err := fiber.NewRateLimitedDispatcher(backend, fiber.RateLimitPolicy{
+			Rate:    1,
+			Key:     fiber.RequestKey(false, "X-Tenant"),
+			MaxKeys: 1,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, ok, dispatcher.Do(context.Background(), tenantRequest("gold", "/predict")))
+		assert.Equal(t, rateLimited, dispatcher.Do(context.Background(), tenantRequest("gold", "/predict")))
+		assert.Equal(t, ok, dispatcher.Do(context.Background(), tenantRequest("silver", "/predict")))
+		// the bucket of the evicted key is full again
+		assert.Equal(t, ok, dispatcher.Do(context.Background(), tenantRequest("gold", "/predict")))
+		assert.Equal(t, 3, backend.attempts)
+	})
+}
+
+func TestRateLimitedDispatcher_Saturated(t *testing.T) {
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080/predict", "")
+	backend := &sequenceDispatcher{responses: []fiber.Response{testUtilsHttp.MockResp(http.StatusOK, "OK", nil, nil)}}
+
+	t.Run("saturated, while the rate is exceeded", func(t *testing.T) {
+		dispatcher, err := fiber.NewRateLimitedDispatcher(backend, fiber.RateLimitPolicy{Rate: 1})
+		require.NoError(t, err)
+
+		assert.False(t, dispatcher.Saturated())
+		dispatcher.Do(context.Background(), req)
+		assert.True(t, dispatcher.Saturated())
+	})
+
+	t.Run("not saturated, while the request can wait for its turn", func(t *testing.T) {
+		dispatcher, err := fiber.NewRateLimitedDispatcher(backend, fiber.RateLimitPolicy{Rate: 1, MaxWait: 2 * time.Second})
+		require.NoError(t, err)
+
+		dispatcher.Do(context.Background(), req)
+		assert.False(t, dispatcher.Saturated())
+	})
+}
+
+func TestRateLimitedDispatcher_RetryAndCircuitBreaker(t *testing.T) {
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080/predict", "")
+	backend := &sequenceDispatcher{
+		responses: []fiber.Response{testUtilsHttp.MockResp(http.StatusServiceUnavailable, "NOK", nil, nil)},
+	}
+	limited, err := fiber.NewRateLimitedDispatcher(backend, fiber.RateLimitPolicy{Rate: 1, Burst: 2})
+	require.NoError(t, err)
+	retrying, err := fiber.NewRetryingDispatcher(limited, fiber.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond})
+	require.NoError(t, err)
+	breaker, err := fiber.NewCircuitBreakingDispatcher("route-a", retrying,
+		fiber.CircuitBreakerPolicy{FailureThreshold: 2, Cooldown: time.Minute})
+	require.NoError(t, err)
+
+	// each retry takes its own turn, so the request is rate-limited after the burst
+	resp := breaker.Do(context.Background(), req)
+	assert.Equal(t, fiber.NewErrorResponse(fiberErrors.ErrRateLimited(protocol.HTTP)), resp)
+	assert.Equal(t, 2, backend.attempts)
+	assert.True(t, breaker.Saturated())
+
+	// the rate-limited requests are not counted by the circuit breaker
+	breaker.Do(context.Background(), req)
+	assert.Equal(t, fiber.CircuitClosed, breaker.State())
+	assert.Equal(t, 2, backend.attempts)
+}
+
+func TestRateLimitedDispatcher_Fallback(t *testing.T) {
+	routeA := testutils.NewMockBackend(testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil))
+	limited, err := fiber.NewRateLimitedDispatcher(routeA, fiber.RateLimitPolicy{Rate: 1})
+	require.NoError(t, err)
+	caller, err := fiber.NewCaller("route-a", limited)
+	require.NoError(t, err)
+
+	router := fiber.NewLazyRouter("lazy-router")
+	router.SetRoutes(map[string]fiber.Component{
+		"route-a": caller,
+		"route-b": testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+			Response: testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil),
+		}),
+	})
+	router.SetStrategy(&orderedRoutingStrategy{order: []string{"route-a", "route-b"}})
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080/predict", "")
+
+	// the rate-limited route is skipped, while its rate is exceeded, and the router falls back to the next one
+	for _, expected := range []string{"A-OK", "B-OK"} {
+		responses := testutils.Dispatch(context.Background(), router, req)
+		require.Len(t, responses, 1)
+		assert.Equal(t, expected, string(responses[0].Payload()))
+	}
+	assert.Equal(t, 1, routeA.Calls())
+
+	decision, err := router.Explain(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.SkippedSaturated, decision.Skipped["route-a"])
+}
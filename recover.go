@@ -0,0 +1,84 @@
+package fiber
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
+)
+
+// PanicError is the panic of a route, a routing strategy or an interceptor, that has been recovered
+// by fiber, so it doesn't crash the process. Stack is the stack trace of the goroutine, that has panicked
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// newPanicError captures the stack trace of the recovered panic. It has to be called
+// by the deferred function, that has recovered it
+func newPanicError(value interface{}) *PanicError {
+	return &PanicError{Value: value, Stack: debug.Stack()}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("recovered panic: %v", e.Value)
+}
+
+// panicResponse creates the error response of the recovered panic
+func panicResponse(proto protocol.Protocol, err *PanicError) Response {
+	return NewErrorResponse(errors.ErrRequestFailed(proto, err))
+}
+
+// safeDispatch dispatches the request by the route. If the route panics, while the request is
+// dispatched, the panic is logged and the response queue with the error response is returned instead
+func safeDispatch(ctx context.Context, log *dispatchLogger, route Component, req Request) (queue ResponseQueue) {
+	defer func() {
+		if value := recover(); value != nil {
+			err := newPanicError(value)
+			log.logPanic(ctx, route.ID(), err)
+			queue = NewResponseQueueFromResponses(panicResponse(req.Protocol(), err))
+		}
+	}()
+	return route.Dispatch(ctx, req)
+}
+
+// safeAggregate aggregates the responses by the fan in. If the fan in panics, the panic is logged
+// and the error response is returned instead
+func safeAggregate(
+	ctx context.Context,
+	componentID string,
+	fanIn FanIn,
+	req Request,
+	responses ResponseQueue,
+) (resp Response) {
+	defer func() {
+		if value := recover(); value != nil {
+			err := newPanicError(value)
+			ctxDispatchLogger(ctx).logPanic(ctx, componentID, err)
+			resp = panicResponse(req.Protocol(), err)
+		}
+	}()
+	return fanIn.Aggregate(ctx, req, responses)
+}
+
+// logStrategyPanic logs the error of the routing strategy, if it's the recovered panic
+func logStrategyPanic(ctx context.Context, log *dispatchLogger, err error) {
+	if panicErr, ok := err.(*PanicError); ok {
+		log.logPanic(ctx, "", panicErr)
+	}
+}
+
+// safeGo runs the function in a new goroutine. If it panics, the panic is logged by the
+// dispatchLogger of the context, if any, and the goroutine exits, without crashing the process
+func safeGo(ctx context.Context, componentID string, fn func()) {
+	go func() {
+		defer func() {
+			if value := recover(); value != nil {
+				ctxDispatchLogger(ctx).logPanic(ctx, componentID, newPanicError(value))
+			}
+		}()
+		fn()
+	}()
+}
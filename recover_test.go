@@ -0,0 +1,196 @@
+package fiber_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panickingStrategy is the routing strategy, that panics on every request
+type panickingStrategy struct {
+	fiber.BaseFiberType
+}
+
+func (s *panickingStrategy) SelectRoute(
+	context.Context,
+	fiber.Request,
+	map[string]fiber.Component,
+) (fiber.Component, []fiber.Component, error) {
+	panic("strategy is broken")
+}
+
+// panickingDispatcher is the dispatcher, that panics on every request
+type panickingDispatcher struct{}
+
+func (panickingDispatcher) Do(context.Context, fiber.Request) fiber.Response {
+	panic("dispatcher is broken")
+}
+
+// panickingInterceptor is the interceptor, that panics in all of its hooks
+type panickingInterceptor struct{}
+
+func (panickingInterceptor) BeforeDispatch(context.Context, fiber.Request) context.Context {
+	panic("interceptor is broken")
+}
+
+func (panickingInterceptor) AfterDispatch(context.Context, fiber.Request, fiber.ResponseQueue) {
+	panic("interceptor is broken")
+}
+
+func (panickingInterceptor) AfterCompletion(context.Context, fiber.Request, fiber.ResponseQueue) {
+	panic("interceptor is broken")
+}
+
+// panics returns the recovered panics, that are logged by the logger
+func panics(logger *recordingLogger) []fiber.LogEntry {
+	entries := make([]fiber.LogEntry, 0)
+	for _, entry := range logger.Entries() {
+		if entry.Event == fiber.PanicRecoveredEvent {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func TestRouter_StrategyPanic(t *testing.T) {
+	tests := map[string]func(builder *testutils.RouterBuilder) loggedRouter{
+		"lazy router": func(builder *testutils.RouterBuilder) loggedRouter {
+			return builder.BuildLazy()
+		},
+		"eager router": func(builder *testutils.RouterBuilder) loggedRouter {
+			return builder.Build()
+		},
+	}
+
+	for name, build := range tests {
+		t.Run(name, func(t *testing.T) {
+			logger := &recordingLogger{}
+			router := build(testutils.NewRouterBuilder("router").
+				WithRoute("route-a", testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil)).
+				WithStrategy(&panickingStrategy{}))
+			router.SetLogger(logger)
+
+			responses := testutils.Dispatch(
+				context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost:8080", ""))
+			require.Len(t, responses, 1)
+			assert.Equal(t, http.StatusInternalServerError, responses[0].StatusCode())
+			assert.Contains(t, string(responses[0].Payload()), "recovered panic: strategy is broken")
+
+			logged := panics(logger)
+			require.Len(t, logged, 1)
+			assert.Equal(t, "router", logged[0].RouterID)
+			assert.Equal(t, "recovered panic: strategy is broken", logged[0].Error)
+			assert.NotEmpty(t, logged[0].Stack)
+		})
+	}
+}
+
+func TestRouter_RoutePanic(t *testing.T) {
+	broken, err := fiber.NewCaller("route-a", panickingDispatcher{})
+	require.NoError(t, err)
+
+	logger := &recordingLogger{}
+	router := testutils.NewRouterBuilder("router").
+		WithComponent(broken).
+		WithRoute("route-b", testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil)).
+		WithOrder([]string{"route-a", "route-b"}, 0, nil).
+		BuildLazy()
+	router.SetLogger(logger)
+
+	responses := testutils.Dispatch(
+		context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost:8080", ""))
+	require.Len(t, responses, 1)
+	assert.Equal(t, "B-OK", string(responses[0].Payload()))
+
+	logged := panics(logger)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "route-a", logged[0].RouteID)
+	assert.Equal(t, "recovered panic: dispatcher is broken", logged[0].Error)
+	assert.NotEmpty(t, logged[0].Stack)
+}
+
+func TestRouter_RoutePanicWithoutLogger(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	broken, err := fiber.NewCaller("route-a", panickingDispatcher{})
+	require.NoError(t, err)
+	router := testutils.NewRouterBuilder("router").
+		WithComponent(broken).
+		WithRoute("route-b", testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil)).
+		WithOrder([]string{"route-a", "route-b"}, 0, nil).
+		BuildLazy()
+
+	responses := testutils.Dispatch(
+		context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost:8080", ""))
+	require.Len(t, responses, 1)
+	assert.Equal(t, "B-OK", string(responses[0].Payload()))
+
+	// the panic is logged by the standard logger with its stack trace
+	assert.Contains(t, output.String(), "fiber: [route-a]: recovered panic: dispatcher is broken")
+	assert.Contains(t, output.String(), "runtime/debug.Stack")
+}
+
+// panickingFanIn is the fan in, that panics on every request
+type panickingFanIn struct {
+	fiber.BaseFanIn
+}
+
+func (panickingFanIn) Aggregate(context.Context, fiber.Request, fiber.ResponseQueue) fiber.Response {
+	panic("fan in is broken")
+}
+
+func TestCombiner_FanInPanic(t *testing.T) {
+	logger := &recordingLogger{}
+	router := testutils.NewRouterBuilder("router").
+		WithRoute("route-a", testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil)).
+		WithOrder([]string{"route-a"}, 0, nil).
+		BuildLazy()
+	router.SetLogger(logger)
+	combiner := fiber.NewCombiner("combiner").WithFanIn(panickingFanIn{})
+	combiner.SetRoutes(router.GetRoutes())
+	router.SetRoutes(map[string]fiber.Component{"combiner": combiner})
+	router.SetStrategy(testutils.NewMockRoutingStrategy(router.GetRoutes(), []string{"combiner"}, 0, nil))
+
+	responses := testutils.Dispatch(
+		context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost:8080", ""))
+	require.Len(t, responses, 1)
+	assert.Equal(t, http.StatusServiceUnavailable, responses[0].StatusCode())
+
+	// the panic of the fan in fails the route, and doesn't crash the process
+	logged := panics(logger)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "combiner", logged[0].RouteID)
+	assert.Equal(t, "recovered panic: fan in is broken", logged[0].Error)
+}
+
+func TestComponent_InterceptorPanic(t *testing.T) {
+	logger := &recordingLogger{}
+	router := testutils.NewRouterBuilder("router").
+		WithRoute("route-a", testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil)).
+		WithOrder([]string{"route-a"}, 0, nil).
+		BuildLazy()
+	router.SetLogger(logger)
+	router.AddInterceptor(true, panickingInterceptor{})
+
+	responses := testutils.Dispatch(
+		context.Background(), router, testUtilsHttp.MockReq("GET", "http://localhost:8080", ""))
+	require.Len(t, responses, 1)
+	assert.Equal(t, "A-OK", string(responses[0].Payload()))
+
+	// the hooks after the dispatch are called asynchronously
+	assert.Eventually(t, func() bool {
+		return len(panics(logger)) > 0
+	}, time.Second, 10*time.Millisecond)
+}
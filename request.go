@@ -1,6 +1,12 @@
 package fiber
 
-import "github.com/gojek/fiber/protocol"
+import (
+	"github.com/gojek/fiber/protocol"
+	"github.com/gojek/fiber/util"
+)
+
+// RequestIDHeader is the header (or the grpc metadata key) of the request, that carries its id
+const RequestIDHeader = "X-Request-ID"
 
 type Request interface {
 	Payload() []byte
@@ -11,3 +17,32 @@ type Request interface {
 
 	Transform(backend Backend) (Request, error)
 }
+
+// RequestIdentifier is implemented by the requests, that return their ids, e.g. the http and grpc requests.
+// It's optional, so the custom requests don't have to implement it (see RequestID)
+type RequestIdentifier interface {
+	// RequestID returns the id of the request from its RequestIDHeader, or generates one and sets it
+	// on the request (see EnsureRequestID), so the id is the same for the whole dispatch of the request
+	RequestID() string
+}
+
+// RequestID returns the id of the request, if it's a RequestIdentifier, or the one ensured by EnsureRequestID
+func RequestID(req Request) string {
+	if identifier, ok := req.(RequestIdentifier); ok {
+		return identifier.RequestID()
+	}
+	return EnsureRequestID(req)
+}
+
+// EnsureRequestID returns the id of the request from its RequestIDHeader. If the request doesn't have
+// one, a new UUID is generated and set on the request, so the following calls, and the copies of the
+// request, return the same id, and it's sent to the backends. The id is set on the request before it's
+// copied for the routes, so it shouldn't be called concurrently with the routes dispatching the request
+func EnsureRequestID(req Request) string {
+	if values := req.Header()[headerKey(req.Protocol(), RequestIDHeader)]; len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+	id := util.UUID()
+	SetRequestHeader(req, RequestIDHeader, id)
+	return id
+}
@@ -0,0 +1,30 @@
+package fiber_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gojek/fiber"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+)
+
+// customRequest is the request, that doesn't implement fiber.RequestIdentifier
+type customRequest struct {
+	fiber.Request
+}
+
+func TestRequestID(t *testing.T) {
+	identified := testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080", "")
+	identified.Request.Header.Set(fiber.RequestIDHeader, "request-1")
+	assert.Equal(t, "request-1", fiber.RequestID(identified))
+
+	// the id of the custom request is generated and set on it, same as for the http and grpc ones
+	custom := customRequest{Request: testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080", "")}
+	_, ok := fiber.Request(custom).(fiber.RequestIdentifier)
+	assert.False(t, ok)
+	id := fiber.RequestID(custom)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, id, fiber.RequestID(custom))
+	assert.Equal(t, id, http.Header(custom.Header()).Get(fiber.RequestIDHeader))
+}
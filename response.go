@@ -1,6 +1,9 @@
 package fiber
 
 import (
+	"encoding/json"
+	"strings"
+
 	"github.com/gojek/fiber/errors"
 	"github.com/gojek/fiber/protocol"
 )
@@ -13,10 +16,59 @@ type Response interface {
 	WithBackendName(string) Response
 }
 
+// BackendNameSeparator joins the entries of the breadcrumb, that the BackendName of the response is, when it's
+// been dispatched by the nested routers: each router prepends the ID of the route, it has dispatched the request to,
+// with WithBackendName, so the breadcrumb lists the routes from the outermost router to the innermost one
+const BackendNameSeparator = ","
+
+// RouteID returns the first entry of the breadcrumb of the response (see BackendNameSeparator), i.e. the ID
+// of the route, that the outermost router has dispatched the request to
+func RouteID(resp Response) string {
+	name := resp.BackendName()
+	if idx := strings.Index(name, BackendNameSeparator); idx >= 0 {
+		return name[:idx]
+	}
+	return name
+}
+
+// prependBackendName returns the breadcrumb with the backend name as its first entry
+func prependBackendName(backendName, breadcrumb string) string {
+	if breadcrumb == "" {
+		return backendName
+	}
+	return backendName + BackendNameSeparator + breadcrumb
+}
+
+// StreamingResponse is a Response, that is received from the backend as a stream of frames
+// (e.g. from a grpc server-streaming method). The Caller sends the frames to its response
+// queue one by one, as they arrive
+type StreamingResponse interface {
+	Response
+	// Frames returns the channel of the frames, that is closed when the stream is finished
+	Frames() <-chan Response
+}
+
+// StreamFrame is a Response, that can be a frame of a StreamingResponse. Routers commit to the route,
+// that has sent the first successful frame, and pass its following frames through as they arrive
+type StreamFrame interface {
+	Response
+	IsStreamFrame() bool
+}
+
+// isStreamFrame checks if the response is a frame of a StreamingResponse
+func isStreamFrame(resp Response) bool {
+	frame, ok := resp.(StreamFrame)
+	return ok && frame.IsStreamFrame()
+}
+
 type ErrorResponse struct {
 	*CachedPayload
-	code    int
-	backend string
+	code        int
+	backend     string
+	routeErrors []errors.RouteError
+	cause       error
+	// err is the FiberError, that the response has been created from
+	err *errors.FiberError
 }
 
 func (resp *ErrorResponse) IsSuccess() bool {
@@ -27,8 +79,9 @@ func (resp *ErrorResponse) BackendName() string {
 	return resp.backend
 }
 
+// WithBackendName prepends the backend to the breadcrumb of the response (see BackendNameSeparator)
 func (resp *ErrorResponse) WithBackendName(backendName string) Response {
-	resp.backend = backendName
+	resp.backend = prependBackendName(backendName, resp.backend)
 	return resp
 }
 
@@ -36,6 +89,69 @@ func (resp *ErrorResponse) StatusCode() int {
 	return resp.code
 }
 
+// Message returns the error message of the response, if its payload is the JSON encoded FiberError,
+// or the payload as it is otherwise
+func (resp *ErrorResponse) Message() string {
+	var fiberErr errors.FiberError
+	if err := json.Unmarshal(resp.Payload(), &fiberErr); err == nil && fiberErr.Message != "" {
+		return fiberErr.Message
+	}
+	return string(resp.Payload())
+}
+
+// Err returns the FiberError, that the response has been created from, so the failure can be classified
+// by its Kind, e.g. errors.Is(resp.Err(), errors.ErrTimeout). It's nil, if the response has been created
+// from the payload (see NewErrorResponseWithPayload)
+func (resp *ErrorResponse) Err() error {
+	if resp.err == nil {
+		return nil
+	}
+	return resp.err
+}
+
+// Cause returns the original error of the backend, that the response has been created from, e.g. the grpc
+// status with its details, or nil, if it's not known
+func (resp *ErrorResponse) Cause() error {
+	return resp.cause
+}
+
+// WithCause sets the original error of the backend, so the details of the error, that can't be carried
+// in the payload of the response, are not lost, when the response is sent back by the routers
+func (resp *ErrorResponse) WithCause(cause error) *ErrorResponse {
+	resp.cause = cause
+	return resp
+}
+
+// RouteErrors returns the failures of the routes, that the router has tried, before it has given up
+// on the request, in the order the routes were tried. It's empty, if the response is not the result
+// of all the routes failing
+func (resp *ErrorResponse) RouteErrors() []errors.RouteError {
+	return resp.routeErrors
+}
+
+// PayloadWithRouteErrors returns the payload of the response with the route errors added into it
+// as the "route_errors" field. The payload is returned as it is, if there are no route errors,
+// or if it's not a JSON object
+func (resp *ErrorResponse) PayloadWithRouteErrors() []byte {
+	if len(resp.routeErrors) == 0 {
+		return resp.Payload()
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(resp.Payload(), &fields); err != nil || fields == nil {
+		return resp.Payload()
+	}
+	routeErrors, err := json.Marshal(resp.routeErrors)
+	if err != nil {
+		return resp.Payload()
+	}
+	fields["route_errors"] = routeErrors
+	payload, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return resp.Payload()
+	}
+	return payload
+}
+
 func NewErrorResponse(err error) Response {
 	var fiberErr *errors.FiberError
 	if castedError, ok := err.(*errors.FiberError); ok {
@@ -47,5 +163,15 @@ func NewErrorResponse(err error) Response {
 	return &ErrorResponse{
 		CachedPayload: NewCachedPayload(payload),
 		code:          fiberErr.Code,
+		err:           fiberErr,
+	}
+}
+
+// NewErrorResponseWithPayload creates an ErrorResponse with the given status code,
+// that carries the given payload as is
+func NewErrorResponseWithPayload(code int, payload []byte) Response {
+	return &ErrorResponse{
+		CachedPayload: NewCachedPayload(payload),
+		code:          code,
 	}
 }
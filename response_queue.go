@@ -1,27 +1,137 @@
 package fiber
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
+)
 
 type ResponseQueue interface {
 	Iter() <-chan Response
 }
 
+// Outcome is the response, received from the ResponseQueue (see Outcomes), with the ID of the route,
+// that it has come from, and its error, if the response is not successful
+type Outcome struct {
+	// RouteID is the ID of the route of the response (see RouteID), or empty, if it's not known
+	RouteID  string
+	Response Response
+	// Err is nil, if the response is successful. Otherwise, it's the FiberError of the error response,
+	// that can be classified by its Kind, or the errors.RouteError with the status code and the payload
+	// of the response, if it carries no FiberError (e.g. the one of NewErrorResponseWithPayload)
+	Err error
+}
+
+// Outcomes returns the channel of the Outcome-s of the responses of the queue, in the order they are
+// received from Iter, so the consumers of the multiple responses (e.g. of the FanOut) can tell the successful
+// responses from the failed ones, and the routes they have come from, without inspecting the responses.
+// The channel is closed, once the queue is
+func Outcomes(queue ResponseQueue) <-chan Outcome {
+	out := make(chan Outcome)
+	go func() {
+		defer close(out)
+		for resp := range queue.Iter() {
+			out <- newOutcome(resp)
+		}
+	}()
+	return out
+}
+
+// newOutcome creates the Outcome of the response
+func newOutcome(resp Response) Outcome {
+	outcome := Outcome{RouteID: RouteID(resp), Response: resp}
+	if resp.IsSuccess() {
+		return outcome
+	}
+	if errResp, ok := resp.(*ErrorResponse); ok && errResp.err != nil {
+		outcome.Err = errResp.err
+	} else {
+		outcome.Err = newRouteError("", outcome.RouteID, resp)
+	}
+	return outcome
+}
+
+// QueueOverflowPolicy defines, what the bounded response queue does with the responses,
+// that arrive when it's full (see NewBoundedResponseQueue)
+type QueueOverflowPolicy int
+
+const (
+	// BlockOnOverflow makes the queue stop receiving the responses, until its subscribers have
+	// received the buffered ones, so the producers of the responses are blocked
+	BlockOnOverflow QueueOverflowPolicy = iota
+	// DropOnOverflow makes the queue drop the responses. The first dropped response is replaced
+	// with the error response, so the subscribers know that the responses are missing
+	DropOnOverflow
+)
+
 type responseQueue struct {
-	lock          sync.RWMutex
-	items         []Response
+	lock  sync.Mutex
+	cond  *sync.Cond
+	items []Response
+	// offset is the number of the responses, released by the bounded queue before the first of the items
+	offset        int
 	buffer        int
-	subscriptions []chan Response
+	subscriptions []*subscription
+	closed        bool
+
+	// capacity, if set, is the number of the responses, that the queue holds at most
+	capacity int
+	overflow QueueOverflowPolicy
+	protocol protocol.Protocol
+	dropped  bool
+}
 
-	done chan struct{}
+// subscription is the position of the next response, that is sent to the channel returned by Iter
+type subscription struct {
+	next int
 }
 
 func (r *responseQueue) append(resp Response) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
+	for r.capacity > 0 && len(r.items) >= r.capacity {
+		if r.overflow == DropOnOverflow {
+			if !r.dropped {
+				r.dropped = true
+				r.items = append(r.items, NewErrorResponse(errors.ErrResponsesDropped(r.protocol)))
+				r.cond.Broadcast()
+			}
+			return
+		}
+		r.cond.Wait()
+	}
 	r.items = append(r.items, resp)
-	for _, subscription := range r.subscriptions {
-		subscription <- resp
+	r.cond.Broadcast()
+}
+
+func (r *responseQueue) close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.closed = true
+	r.cond.Broadcast()
+}
+
+// release drops the responses, that all the subscribers of the bounded queue have received
+func (r *responseQueue) release() {
+	if r.capacity <= 0 || len(r.subscriptions) == 0 {
+		return
+	}
+	next := r.subscriptions[0].next
+	for _, s := range r.subscriptions[1:] {
+		if s.next < next {
+			next = s.next
+		}
+	}
+	if n := next - r.offset; n > 0 {
+		for idx := 0; idx < n; idx++ {
+			r.items[idx] = nil
+		}
+		r.items = r.items[n:]
+		r.offset = next
+		r.cond.Broadcast()
 	}
 }
 
@@ -29,45 +139,82 @@ func (r *responseQueue) Iter() <-chan Response {
 	out := make(chan Response, r.buffer)
 
 	go func() {
+		defer close(out)
+
 		r.lock.Lock()
-		r.subscriptions = append(r.subscriptions, out)
-		for _, resp := range r.items {
+		s := &subscription{next: r.offset}
+		r.subscriptions = append(r.subscriptions, s)
+		for {
+			for !r.closed && s.next == r.offset+len(r.items) {
+				r.cond.Wait()
+			}
+			if s.next == r.offset+len(r.items) {
+				r.lock.Unlock()
+				return
+			}
+			resp := r.items[s.next-r.offset]
+			s.next++
+			r.release()
+			r.lock.Unlock()
+
 			out <- resp
+			r.lock.Lock()
 		}
-		r.lock.Unlock()
-
-		<-r.done
-		close(out)
 	}()
 	return out
 }
 
-// NewResponseQueue takes an input channel and creates a Queue with all responseQueue from it
-func NewResponseQueue(in <-chan Response, bufferSize int) ResponseQueue {
-	queue := &responseQueue{
-		buffer: bufferSize,
-		done:   make(chan struct{}),
-	}
+func newResponseQueue(bufferSize int) *responseQueue {
+	queue := &responseQueue{buffer: bufferSize}
+	queue.cond = sync.NewCond(&queue.lock)
+	return queue
+}
 
-	go func(q *responseQueue) {
-		defer close(q.done)
+// receive appends the responses from the input channel to the queue, until the channel is closed
+func (r *responseQueue) receive(in <-chan Response) {
+	go func() {
+		defer r.close()
 
 		for resp := range in {
-			q.append(resp)
+			r.append(resp)
 		}
-	}(queue)
+	}()
+}
+
+// NewResponseQueue takes an input channel and creates a Queue with all responseQueue from it
+func NewResponseQueue(in <-chan Response, bufferSize int) ResponseQueue {
+	queue := newResponseQueue(bufferSize)
+	queue.receive(in)
+	return queue
+}
+
+// NewBoundedResponseQueue takes an input channel and creates a Queue, that holds at most capacity responses
+// from it, so a slow subscriber doesn't make it buffer the responses without a limit. The responses are
+// released, once all the subscribers of the queue have received them, so the subscribers, that start to iterate
+// the queue later, only receive the responses, that it still holds. When the queue is full, the next response
+// is either waited for, or dropped (see QueueOverflowPolicy), and the error response of the dropped ones is
+// of the given protocol. With BlockOnOverflow, the subscribers have to iterate the queue to the end, otherwise
+// its producers are blocked. Zero capacity makes the queue unbounded, as the one of NewResponseQueue
+func NewBoundedResponseQueue(
+	in <-chan Response,
+	bufferSize int,
+	capacity int,
+	overflow QueueOverflowPolicy,
+	protocol protocol.Protocol,
+) ResponseQueue {
+	queue := newResponseQueue(bufferSize)
+	queue.capacity = capacity
+	queue.overflow = overflow
+	queue.protocol = protocol
+	queue.receive(in)
 	return queue
 }
 
 // NewResponseQueueFromResponses takes list of responses and constructs
 // an instance of ResponseQueue from them
 func NewResponseQueueFromResponses(responses ...Response) ResponseQueue {
-	queue := &responseQueue{
-		items:  responses,
-		buffer: len(responses),
-		done:   make(chan struct{}),
-	}
-
-	close(queue.done)
+	queue := newResponseQueue(len(responses))
+	queue.items = responses
+	queue.closed = true
 	return queue
 }
@@ -1,12 +1,21 @@
 package fiber_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/internal/testutils"
 	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func chanToArray(ch <-chan fiber.Response) []fiber.Response {
@@ -80,3 +89,110 @@ func TestResponseQueue_Iter(t *testing.T) {
 	time.Sleep(400 * time.Millisecond)
 	assert.Equal(t, responses, chanToArray(q.Iter()))
 }
+
+func TestNewBoundedResponseQueue(t *testing.T) {
+	responses := make([]fiber.Response, 10)
+	for idx := range responses {
+		responses[idx] = testUtilsHttp.MockResp(http.StatusOK, fmt.Sprintf("response-%d", idx), nil, nil)
+	}
+	produce := func(in chan<- fiber.Response, produced *int32) {
+		for _, resp := range responses {
+			in <- resp
+			atomic.AddInt32(produced, 1)
+		}
+		close(in)
+	}
+
+	t.Run("block", func(t *testing.T) {
+		var produced int32
+		in := make(chan fiber.Response)
+		go produce(in, &produced)
+
+		q := fiber.NewBoundedResponseQueue(in, 0, 2, fiber.BlockOnOverflow, protocol.HTTP)
+		iter := q.Iter()
+
+		// the consumer is stalled: the queue holds 2 responses, one more is being sent to the consumer,
+		// and one more is waiting for the room in the queue, so the producer is blocked on the next one
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, int32(4), atomic.LoadInt32(&produced))
+
+		var received []fiber.Response
+		for resp := range iter {
+			received = append(received, resp)
+			time.Sleep(time.Millisecond)
+			assert.LessOrEqual(t, atomic.LoadInt32(&produced), int32(len(received)+4))
+		}
+		assert.Equal(t, responses, received)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		var produced int32
+		in := make(chan fiber.Response)
+		go produce(in, &produced)
+
+		q := fiber.NewBoundedResponseQueue(in, 0, 2, fiber.DropOnOverflow, protocol.HTTP)
+		require.Eventually(t, func() bool {
+			return atomic.LoadInt32(&produced) == int32(len(responses))
+		}, time.Second, 10*time.Millisecond)
+
+		received := chanToArray(q.Iter())
+		require.Len(t, received, 3)
+		assert.Equal(t, responses[:2], received[:2])
+		assert.Equal(t, fiber.NewErrorResponse(fiberErrors.ErrResponsesDropped(protocol.HTTP)), received[2])
+	})
+
+	t.Run("unbounded", func(t *testing.T) {
+		q := fiber.NewBoundedResponseQueue(makeChan(responses...), 0, 0, fiber.BlockOnOverflow, protocol.HTTP)
+
+		assert.Equal(t, responses, chanToArray(q.Iter()))
+		assert.Equal(t, responses, chanToArray(q.Iter()))
+	})
+}
+
+func TestOutcomes(t *testing.T) {
+	fanOut := fiber.NewFanOut("fan-out")
+	fanOut.SetRoutes(map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a", testUtilsHttp.DelayedResponse{
+			Response: testUtilsHttp.MockResp(http.StatusOK, "OK", nil, nil),
+		}),
+		"route-b": testutils.NewMockComponent("route-b", testUtilsHttp.DelayedResponse{
+			Response: fiber.NewErrorResponse(fiberErrors.ErrRequestTimeout(protocol.HTTP)),
+		}),
+		"route-c": testutils.NewMockComponent("route-c", testUtilsHttp.DelayedResponse{
+			Response: testUtilsHttp.MockResp(http.StatusInternalServerError, "backend failure", nil, nil),
+		}),
+		"route-d": testutils.NewMockComponent("route-d", testUtilsHttp.DelayedResponse{
+			Response: fiber.NewErrorResponseWithPayload(http.StatusBadGateway, []byte("bad gateway")),
+		}),
+	})
+
+	queue := fanOut.Dispatch(context.Background(), testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080", ""))
+	outcomes := make(map[string]fiber.Outcome)
+	for outcome := range fiber.Outcomes(queue) {
+		outcomes[outcome.RouteID] = outcome
+	}
+	require.Len(t, outcomes, 4)
+
+	assert.NoError(t, outcomes["route-a"].Err)
+	assert.Equal(t, "OK", string(outcomes["route-a"].Response.Payload()))
+
+	// the error response carries its fiber error, that can be classified by its kind
+	assert.True(t, errors.Is(outcomes["route-b"].Err, fiberErrors.ErrTimeout))
+
+	var fiberErr *fiberErrors.FiberError
+	require.True(t, errors.As(outcomes["route-c"].Err, &fiberErr))
+	assert.Equal(t, http.StatusInternalServerError, fiberErr.Code)
+	assert.Equal(t, "backend failure", fiberErr.Message)
+
+	// the error response without the fiber error is described by the route error
+	var routeErr fiberErrors.RouteError
+	require.True(t, errors.As(outcomes["route-d"].Err, &routeErr))
+	assert.Equal(t, fiberErrors.RouteError{
+		RouteID: "route-d",
+		Code:    http.StatusBadGateway,
+		Message: "bad gateway",
+	}, routeErr)
+
+	// the responses of the queue are still received from Iter
+	assert.Len(t, chanToArray(queue.Iter()), 4)
+}
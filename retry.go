@@ -0,0 +1,190 @@
+package fiber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gojek/fiber/protocol"
+	"github.com/gojek/fiber/util"
+	"google.golang.org/grpc/codes"
+)
+
+// Defaults of the RetryPolicy
+const (
+	DefaultRetryMaxAttempts    = 3
+	DefaultRetryInitialBackoff = 50 * time.Millisecond
+	DefaultRetryMultiplier     = 2.0
+	DefaultRetryMaxBackoff     = time.Second
+)
+
+var (
+	// DefaultHTTPRetryableStatusCodes are the http status codes, that are retried by default
+	DefaultHTTPRetryableStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable}
+	// DefaultGRPCRetryableStatusCodes are the grpc status codes, that are retried by default. Only the codes,
+	// which guarantee that the request hasn't been processed by the backend, are retried, so the retries
+	// are safe for non-idempotent methods
+	DefaultGRPCRetryableStatusCodes = []int{int(codes.Unavailable)}
+)
+
+// RetryPolicy defines how the failed requests to a backend are retried. The zero values are replaced
+// with the defaults
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+	// Multiplier is the factor, by which the backoff is increased after each retry
+	Multiplier float64
+	// MaxBackoff is the upper limit of the backoff
+	MaxBackoff time.Duration
+	// Jitter is the fraction of the backoff, by which it's randomly reduced, in the [0, 1] range.
+	// Zero means no jitter
+	Jitter float64
+	// RetryableStatusCodes are the status codes of the responses, that are retried. By default,
+	// DefaultHTTPRetryableStatusCodes or DefaultGRPCRetryableStatusCodes are used, depending on the protocol
+	RetryableStatusCodes []int
+}
+
+// RetryingDispatcher is a Dispatcher, that retries the requests, that have failed with a retryable status
+// code, according to the RetryPolicy, before giving up on the backend. The retries stop, when the context
+// of the request is done, or the next backoff would exceed its deadline
+type RetryingDispatcher struct {
+	dispatcher Dispatcher
+	policy     RetryPolicy
+	retryable  map[int]bool
+	rand       *util.ShardedRand
+}
+
+// NewRetryingDispatcher is a factory method, that creates a RetryingDispatcher, that retries the requests
+// dispatched by the given Dispatcher according to the policy
+func NewRetryingDispatcher(dispatcher Dispatcher, policy RetryPolicy) (*RetryingDispatcher, error) {
+	if dispatcher == nil {
+		return nil, errors.New("retry policy: dispatcher can not be nil")
+	}
+	if policy.MaxAttempts < 0 || policy.InitialBackoff < 0 || policy.MaxBackoff < 0 {
+		return nil, errors.New("retry policy: max_attempts and backoffs can not be negative")
+	}
+	if policy.Multiplier != 0 && policy.Multiplier < 1 {
+		return nil, fmt.Errorf("retry policy: multiplier can not be less than 1: [%v]", policy.Multiplier)
+	}
+	if policy.Jitter < 0 || policy.Jitter > 1 {
+		return nil, fmt.Errorf("retry policy: jitter must be in [0, 1] range: [%v]", policy.Jitter)
+	}
+
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = DefaultRetryMaxAttempts
+	}
+	if policy.InitialBackoff == 0 {
+		policy.InitialBackoff = DefaultRetryInitialBackoff
+	}
+	if policy.Multiplier == 0 {
+		policy.Multiplier = DefaultRetryMultiplier
+	}
+	if policy.MaxBackoff == 0 {
+		policy.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	if policy.MaxBackoff < policy.InitialBackoff {
+		policy.MaxBackoff = policy.InitialBackoff
+	}
+
+	var retryable map[int]bool
+	if len(policy.RetryableStatusCodes) > 0 {
+		retryable = make(map[int]bool, len(policy.RetryableStatusCodes))
+		for _, code := range policy.RetryableStatusCodes {
+			retryable[code] = true
+		}
+	}
+
+	return &RetryingDispatcher{
+		dispatcher: dispatcher,
+		policy:     policy,
+		retryable:  retryable,
+		rand:       util.NewShardedRand(time.Now().UnixNano()),
+	}, nil
+}
+
+// Do dispatches the request, and retries it, while the response has a retryable status code
+// and the attempts are not exhausted. The response of the last attempt is returned
+func (d *RetryingDispatcher) Do(ctx context.Context, req Request) Response {
+	backoff := d.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			// the request is cloned, since its body has been consumed by the previous attempt
+			if cloned, err := req.Clone(); err == nil {
+				attemptReq = cloned
+			}
+		}
+
+		resp := d.dispatcher.Do(ctx, attemptReq)
+		if attempt >= d.policy.MaxAttempts || resp.IsSuccess() || !d.isRetryable(req.Protocol(), resp.StatusCode()) {
+			return resp
+		}
+
+		delay := d.withJitter(backoff)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			// the retry wouldn't complete before the deadline
+			return resp
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return resp
+		}
+
+		backoff = time.Duration(float64(backoff) * d.policy.Multiplier)
+		if backoff > d.policy.MaxBackoff {
+			backoff = d.policy.MaxBackoff
+		}
+	}
+}
+
+// Saturated returns true, if the underlying dispatcher is saturated, e.g. it's rate-limited
+// (see SaturationReporter)
+func (d *RetryingDispatcher) Saturated() bool {
+	return saturatedIfReporter(d.dispatcher)
+}
+
+// Describe adds the details of the dispatcher, that sends each attempt of the request, to the info
+func (d *RetryingDispatcher) Describe(info *ComponentInfo) {
+	describeIfDescriber(d.dispatcher, info)
+}
+
+// Close closes the dispatcher, that sends each attempt of the request (see Closer)
+func (d *RetryingDispatcher) Close(ctx context.Context) error {
+	return closeIfCloser(ctx, d.dispatcher)
+}
+
+// WarmUp establishes the connection of the underlying dispatcher to its backend, if it's a Warmer
+func (d *RetryingDispatcher) WarmUp(ctx context.Context) error {
+	return warmUpIfWarmer(ctx, d.dispatcher)
+}
+
+func (d *RetryingDispatcher) isRetryable(proto protocol.Protocol, statusCode int) bool {
+	if d.retryable != nil {
+		return d.retryable[statusCode]
+	}
+	defaults := DefaultHTTPRetryableStatusCodes
+	if proto == protocol.GRPC {
+		defaults = DefaultGRPCRetryableStatusCodes
+	}
+	for _, code := range defaults {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// withJitter randomly reduces the backoff by up to the jitter fraction of it
+func (d *RetryingDispatcher) withJitter(backoff time.Duration) time.Duration {
+	if d.policy.Jitter == 0 {
+		return backoff
+	}
+	return time.Duration(float64(backoff) * (1 - d.policy.Jitter*d.rand.Float64()))
+}
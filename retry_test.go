@@ -0,0 +1,242 @@
+package fiber_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/grpc"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// grpcRequest is the request of the grpc protocol, used to check the default grpc retry policy
+type grpcRequest struct {
+	fiber.Request
+}
+
+func (r *grpcRequest) Protocol() protocol.Protocol {
+	return protocol.GRPC
+}
+
+func (r *grpcRequest) Clone() (fiber.Request, error) {
+	return r, nil
+}
+
+func TestNewRetryingDispatcher(t *testing.T) {
+	suite := map[string]struct {
+		dispatcher  fiber.Dispatcher
+		policy      fiber.RetryPolicy
+		expectedErr string
+	}{
+		"ok: defaults": {
+			dispatcher: &sequenceDispatcher{},
+		},
+		"error: nil dispatcher": {
+			expectedErr: "retry policy: dispatcher can not be nil",
+		},
+		"error: negative max attempts": {
+			dispatcher:  &sequenceDispatcher{},
+			policy:      fiber.RetryPolicy{MaxAttempts: -1},
+			expectedErr: "retry policy: max_attempts and backoffs can not be negative",
+		},
+		"error: multiplier less than 1": {
+			dispatcher:  &sequenceDispatcher{},
+			policy:      fiber.RetryPolicy{Multiplier: 0.5},
+			expectedErr: "retry policy: multiplier can not be less than 1: [0.5]",
+		},
+		"error: jitter out of range": {
+			dispatcher:  &sequenceDispatcher{},
+			policy:      fiber.RetryPolicy{Jitter: 1.5},
+			expectedErr: "retry policy: jitter must be in [0, 1] range: [1.5]",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			_, err := fiber.NewRetryingDispatcher(tt.dispatcher, tt.policy)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRetryingDispatcher_Do(t *testing.T) {
+	unavailable := testUtilsHttp.MockResp(
+		http.StatusServiceUnavailable, "", nil, fiberErrors.ErrServiceUnavailable(protocol.HTTP))
+	internal := testUtilsHttp.MockResp(
+		http.StatusInternalServerError, "", nil, fiberErrors.ErrRequestFailed(protocol.HTTP, errors.New("failed")))
+	ok := testUtilsHttp.MockResp(http.StatusOK, "OK", nil, nil)
+	grpcUnavailable := fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.GRPC))
+	grpcInternal := fiber.NewErrorResponse(fiberErrors.ErrRequestFailed(protocol.GRPC, errors.New("failed")))
+
+	httpReq := testUtilsHttp.MockReq("GET", "http://localhost:8080/retry", "")
+
+	suite := map[string]struct {
+		policy           fiber.RetryPolicy
+		request          fiber.Request
+		responses        []fiber.Response
+		timeout          time.Duration
+		expected         fiber.Response
+		expectedAttempts int
+		maxElapsed       time.Duration
+	}{
+		"succeeded after retry": {
+			policy:           fiber.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			request:          httpReq,
+			responses:        []fiber.Response{unavailable, ok},
+			expected:         ok,
+			expectedAttempts: 2,
+		},
+		"attempts exhausted": {
+			policy:           fiber.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			request:          httpReq,
+			responses:        []fiber.Response{unavailable},
+			expected:         unavailable,
+			expectedAttempts: 3,
+		},
+		"not retryable status code": {
+			policy:           fiber.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			request:          httpReq,
+			responses:        []fiber.Response{internal, ok},
+			expected:         internal,
+			expectedAttempts: 1,
+		},
+		"configured retryable status code": {
+			policy: fiber.RetryPolicy{
+				MaxAttempts:          3,
+				InitialBackoff:       time.Millisecond,
+				RetryableStatusCodes: []int{http.StatusInternalServerError},
+			},
+			request:          httpReq,
+			responses:        []fiber.Response{internal, ok},
+			expected:         ok,
+			expectedAttempts: 2,
+		},
+		"grpc unavailable is retried by default": {
+			policy:           fiber.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			request:          &grpcRequest{Request: httpReq},
+			responses:        []fiber.Response{grpcUnavailable, ok},
+			expected:         ok,
+			expectedAttempts: 2,
+		},
+		"grpc internal is not retried by default": {
+			policy:           fiber.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			request:          &grpcRequest{Request: httpReq},
+			responses:        []fiber.Response{grpcInternal, ok},
+			expected:         grpcInternal,
+			expectedAttempts: 1,
+		},
+		"backoff exceeds the deadline": {
+			policy:           fiber.RetryPolicy{MaxAttempts: 3, InitialBackoff: 200 * time.Millisecond},
+			request:          httpReq,
+			responses:        []fiber.Response{unavailable, ok},
+			timeout:          50 * time.Millisecond,
+			expected:         unavailable,
+			expectedAttempts: 1,
+			maxElapsed:       30 * time.Millisecond,
+		},
+		"deadline exceeded during backoffs": {
+			policy: fiber.RetryPolicy{
+				MaxAttempts:    10,
+				InitialBackoff: 20 * time.Millisecond,
+				Multiplier:     1,
+			},
+			request:          httpReq,
+			responses:        []fiber.Response{unavailable},
+			timeout:          50 * time.Millisecond,
+			expected:         unavailable,
+			expectedAttempts: 3,
+			maxElapsed:       50 * time.Millisecond,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			dispatcher := &sequenceDispatcher{responses: tt.responses}
+			retrying, err := fiber.NewRetryingDispatcher(dispatcher, tt.policy)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			resp := retrying.Do(ctx, tt.request)
+			if tt.maxElapsed > 0 {
+				assert.Less(t, int64(time.Since(start)), int64(tt.maxElapsed))
+			}
+			assert.Equal(t, tt.expected, resp)
+			assert.Equal(t, tt.expectedAttempts, dispatcher.attempts)
+		})
+	}
+}
+
+func TestRetryingDispatcher_Backoff(t *testing.T) {
+	unavailable := fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP))
+	dispatcher := &sequenceDispatcher{responses: []fiber.Response{unavailable}}
+	retrying, err := fiber.NewRetryingDispatcher(dispatcher, fiber.RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 10 * time.Millisecond,
+		Multiplier:     2,
+		MaxBackoff:     25 * time.Millisecond,
+		Jitter:         0.5,
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	retrying.Do(context.Background(), testUtilsHttp.MockReq("GET", "http://localhost:8080/retry", ""))
+	elapsed := time.Since(start)
+
+	// backoffs are 10ms, 20ms and 25ms (capped), each reduced by up to a half with the jitter
+	assert.Equal(t, 4, dispatcher.attempts)
+	assert.GreaterOrEqual(t, int64(elapsed), int64(27500*time.Microsecond))
+	assert.Less(t, int64(elapsed), int64(55*time.Millisecond+50*time.Millisecond))
+}
+
+func TestRetryingDispatcher_MockBackend(t *testing.T) {
+	suite := map[string]struct {
+		request fiber.Request
+		backend *testutils.MockBackend
+	}{
+		"http": {
+			request: testUtilsHttp.MockReq("GET", "http://localhost:8080/retry", ""),
+			backend: testutils.NewMockBackend(
+				fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+				testUtilsHttp.MockResp(http.StatusOK, "OK", nil, nil),
+			),
+		},
+		"grpc": {
+			request: &grpc.Request{},
+			backend: testutils.NewMockBackend(
+				fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.GRPC)),
+				&grpc.Response{Message: []byte("OK")},
+			),
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			dispatcher, err := fiber.NewRetryingDispatcher(tt.backend, fiber.RetryPolicy{InitialBackoff: time.Millisecond})
+			require.NoError(t, err)
+
+			resp := dispatcher.Do(context.Background(), tt.request)
+			assert.True(t, resp.IsSuccess())
+			assert.Equal(t, "OK", string(resp.Payload()))
+			assert.Equal(t, 2, tt.backend.Calls())
+		})
+	}
+}
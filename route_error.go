@@ -0,0 +1,54 @@
+package fiber
+
+import (
+	"net/http"
+
+	"github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
+)
+
+// newRouteError captures the failed response of the route. The route, that hasn't responded at all,
+// is captured with ErrServiceUnavailable
+func newRouteError(proto protocol.Protocol, routeID string, resp Response) errors.RouteError {
+	if resp == nil {
+		err := errors.ErrServiceUnavailable(proto)
+		return errors.RouteError{RouteID: routeID, Code: err.Code, Message: err.Message}
+	}
+	routeErr := errors.RouteError{RouteID: routeID, Code: resp.StatusCode(), Message: string(resp.Payload())}
+	if errResp, ok := resp.(*ErrorResponse); ok {
+		routeErr.Message = errResp.Message()
+		routeErr.Cause = errResp.Cause()
+	}
+	return routeErr
+}
+
+// errAllRoutesFailed creates the ErrServiceUnavailable response, that carries the errors of the routes,
+// that have failed. If all of them have rejected the request as invalid, ErrRequestRejected with the status
+// of the last rejection is created instead, since the request would fail the same on any other route
+func errAllRoutesFailed(proto protocol.Protocol, routeErrors []errors.RouteError) Response {
+	err := errors.ErrServiceUnavailable(proto)
+	if len(routeErrors) > 0 {
+		rejected := true
+		for _, routeErr := range routeErrors {
+			rejected = rejected && isRejection(proto, routeErr.Code)
+		}
+		if rejected {
+			err = errors.ErrRequestRejected(proto, routeErrors[len(routeErrors)-1].Code)
+		}
+	}
+	resp := NewErrorResponse(err).(*ErrorResponse)
+	resp.routeErrors = routeErrors
+	return resp
+}
+
+// isRejection returns true, if the status code means, that the backend has rejected the request as invalid,
+// rather than failed to serve it: the 4xx status codes of http, except the timeout and the throttling ones,
+// and DefaultGRPCTerminalStatusCodes of grpc
+func isRejection(proto protocol.Protocol, code int) bool {
+	if proto == protocol.GRPC {
+		_, ok := defaultGRPCTerminalStatuses[code]
+		return ok
+	}
+	return code >= http.StatusBadRequest && code < http.StatusInternalServerError &&
+		code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
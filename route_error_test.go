@@ -0,0 +1,287 @@
+package fiber_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	fiberGRPC "github.com/gojek/fiber/grpc"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRouter_RouteErrors(t *testing.T) {
+	routers := map[string]func() fiber.Router{
+		"lazy router":  func() fiber.Router { return fiber.NewLazyRouter("lazy-router") },
+		"eager router": func() fiber.Router { return fiber.NewEagerRouter("eager-router") },
+	}
+
+	suite := map[string]struct {
+		request        fiber.Request
+		responses      map[string]fiber.Response
+		expectedStatus int
+		expected       []fiberErrors.RouteError
+	}{
+		"http": {
+			request: testUtilsHttp.MockReq("POST", "http://localhost:8080/route-errors", "payload"),
+			responses: map[string]fiber.Response{
+				"route-a": testUtilsHttp.MockResp(500, "internal error", nil, nil),
+				"route-b": testUtilsHttp.MockResp(502, "bad gateway", nil, nil),
+				"route-c": fiber.NewErrorResponse(fiberErrors.ErrRequestTimeout(protocol.HTTP)),
+			},
+			expectedStatus: 503,
+			expected: []fiberErrors.RouteError{
+				{RouteID: "route-a", Code: 500, Message: "internal error"},
+				{RouteID: "route-b", Code: 502, Message: "bad gateway"},
+				{RouteID: "route-c", Code: 408, Message: "fiber: failed to receive a response within configured timeout"},
+			},
+		},
+		"grpc": {
+			request: &fiberGRPC.Request{Metadata: metadata.MD{}},
+			responses: map[string]fiber.Response{
+				"route-a": fiber.NewErrorResponse(fiberErrors.FiberError{
+					Code: int(codes.Unavailable), Message: "backend is unavailable"}),
+				"route-b": fiber.NewErrorResponse(fiberErrors.FiberError{
+					Code: int(codes.ResourceExhausted), Message: "too many requests"}),
+				"route-c": fiber.NewErrorResponse(fiberErrors.FiberError{
+					Code: int(codes.Internal), Message: "failed to predict"}),
+			},
+			expectedStatus: int(codes.Unavailable),
+			expected: []fiberErrors.RouteError{
+				{RouteID: "route-a", Code: int(codes.Unavailable), Message: "backend is unavailable"},
+				{RouteID: "route-b", Code: int(codes.ResourceExhausted), Message: "too many requests"},
+				{RouteID: "route-c", Code: int(codes.Internal), Message: "failed to predict"},
+			},
+		},
+	}
+
+	for routerName, newRouter := range routers {
+		for name, tt := range suite {
+			t.Run(routerName+": "+name, func(t *testing.T) {
+				routes := make(map[string]fiber.Component)
+				for routeID, resp := range tt.responses {
+					routes[routeID] = testutils.NewMockComponent(routeID, testUtilsHttp.DelayedResponse{Response: resp})
+				}
+				router := newRouter()
+				router.SetRoutes(routes)
+				router.SetStrategy(testutils.NewMockRoutingStrategy(
+					routes, []string{"route-a", "route-b", "route-c"}, 0, nil))
+
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+
+				resp, ok := <-router.Dispatch(ctx, tt.request).Iter()
+				require.True(t, ok)
+				require.Equal(t, tt.expectedStatus, resp.StatusCode())
+
+				errResp, ok := resp.(*fiber.ErrorResponse)
+				require.True(t, ok)
+				assert.Equal(t, "fiber: no responses received", errResp.Message())
+				assert.Equal(t, tt.expected, errResp.RouteErrors())
+			})
+		}
+	}
+}
+
+func TestRouter_ErrorKinds(t *testing.T) {
+	routers := map[string]func(builder *testutils.RouterBuilder) fiber.Router{
+		"lazy router":  func(builder *testutils.RouterBuilder) fiber.Router { return builder.BuildLazy() },
+		"eager router": func(builder *testutils.RouterBuilder) fiber.Router { return builder.Build() },
+	}
+	delayed := func(status int) testUtilsHttp.DelayedResponse {
+		return testUtilsHttp.DelayedResponse{Response: testUtilsHttp.MockResp(status, "", nil, nil), Latency: time.Second}
+	}
+
+	suite := map[string]struct {
+		responses       map[string]testUtilsHttp.DelayedResponse
+		order           []string
+		strategyLatency time.Duration
+		expectedKind    fiberErrors.Kind
+		expectedStatus  int
+	}{
+		"all routes failed": {
+			responses: map[string]testUtilsHttp.DelayedResponse{
+				"route-a": {Response: testUtilsHttp.MockResp(http.StatusInternalServerError, "", nil, nil)},
+				"route-b": {Response: testUtilsHttp.MockResp(http.StatusBadGateway, "", nil, nil)},
+			},
+			expectedKind:   fiberErrors.ErrAllRoutesFailed,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		"all routes rejected": {
+			responses: map[string]testUtilsHttp.DelayedResponse{
+				"route-a": {Response: testUtilsHttp.MockResp(http.StatusBadRequest, "", nil, nil)},
+				"route-b": {Response: testUtilsHttp.MockResp(http.StatusNotFound, "", nil, nil)},
+			},
+			expectedKind:   fiberErrors.ErrBackendRejected,
+			expectedStatus: http.StatusNotFound,
+		},
+		"some routes rejected": {
+			responses: map[string]testUtilsHttp.DelayedResponse{
+				"route-a": {Response: testUtilsHttp.MockResp(http.StatusBadRequest, "", nil, nil)},
+				"route-b": {Response: testUtilsHttp.MockResp(http.StatusTooManyRequests, "", nil, nil)},
+			},
+			expectedKind:   fiberErrors.ErrAllRoutesFailed,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		"routes timed out": {
+			responses: map[string]testUtilsHttp.DelayedResponse{
+				"route-a": delayed(http.StatusOK),
+				"route-b": delayed(http.StatusOK),
+			},
+			expectedKind:   fiberErrors.ErrTimeout,
+			expectedStatus: http.StatusRequestTimeout,
+		},
+		"routing strategy timed out": {
+			responses: map[string]testUtilsHttp.DelayedResponse{
+				"route-a": delayed(http.StatusOK),
+				"route-b": delayed(http.StatusOK),
+			},
+			strategyLatency: time.Second,
+			expectedKind:    fiberErrors.ErrTimeout,
+			expectedStatus:  http.StatusRequestTimeout,
+		},
+		"no routes": {
+			responses: map[string]testUtilsHttp.DelayedResponse{
+				"route-a": {Response: testUtilsHttp.MockResp(http.StatusOK, "", nil, nil)},
+				"route-b": {Response: testUtilsHttp.MockResp(http.StatusOK, "", nil, nil)},
+			},
+			order:          []string{},
+			expectedKind:   fiberErrors.ErrNoRoutes,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for routerName, newRouter := range routers {
+		for name, tt := range suite {
+			t.Run(routerName+"/"+name, func(t *testing.T) {
+				builder := testutils.NewRouterBuilder("router")
+				for _, id := range []string{"route-a", "route-b"} {
+					builder.WithDelayedRoute(id, tt.responses[id])
+				}
+				order := tt.order
+				if order == nil {
+					order = []string{"route-a", "route-b"}
+				}
+				builder.WithOrder(order, tt.strategyLatency, nil)
+
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				responses := testutils.Dispatch(ctx, newRouter(builder), testUtilsHttp.MockReq("GET", "http://localhost", ""))
+				require.Len(t, responses, 1)
+				errResp, ok := responses[0].(*fiber.ErrorResponse)
+				require.True(t, ok)
+				assert.Equal(t, tt.expectedStatus, errResp.StatusCode())
+				assert.True(t, errors.Is(errResp.Err(), tt.expectedKind), "%v is not %s", errResp.Err(), tt.expectedKind)
+
+				var fiberErr *fiberErrors.FiberError
+				require.True(t, errors.As(errResp.Err(), &fiberErr))
+				assert.Equal(t, protocol.HTTP, fiberErr.Protocol)
+			})
+		}
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	suite := map[string]struct {
+		err          error
+		expectedKind fiberErrors.Kind
+	}{
+		"closed component": {
+			err:          fiberErrors.ErrComponentClosed(protocol.GRPC),
+			expectedKind: fiberErrors.ErrUnavailable,
+		},
+		"saturated component": {
+			err:          fiberErrors.ErrComponentSaturated(protocol.HTTP),
+			expectedKind: fiberErrors.ErrUnavailable,
+		},
+		"open circuit": {
+			err:          fiberErrors.ErrCircuitOpen(protocol.HTTP),
+			expectedKind: fiberErrors.ErrUnavailable,
+		},
+		"unreachable backend": {
+			err:          fiberErrors.ErrConnectTimeout(protocol.HTTP),
+			expectedKind: fiberErrors.ErrUnavailable,
+		},
+		"invalid input": {
+			err:          fiberErrors.ErrInvalidInput(protocol.HTTP, errors.New("missing key")),
+			expectedKind: fiberErrors.ErrInvalidRequest,
+		},
+		"request too large": {
+			err:          fiberErrors.ErrRequestTooLarge(protocol.HTTP, 10),
+			expectedKind: fiberErrors.ErrLimitExceeded,
+		},
+		"wrapped fiber error": {
+			err:          fmt.Errorf("dispatch: %w", *fiberErrors.ErrRequestTimeout(protocol.GRPC)),
+			expectedKind: fiberErrors.ErrTimeout,
+		},
+		"other error": {
+			err:          errors.New("connection reset"),
+			expectedKind: fiberErrors.ErrInternal,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			errResp := fiber.NewErrorResponse(tt.err).(*fiber.ErrorResponse)
+			assert.True(t, errors.Is(errResp.Err(), tt.expectedKind), "%v is not %s", errResp.Err(), tt.expectedKind)
+			for _, kind := range []fiberErrors.Kind{fiberErrors.ErrAllRoutesFailed, fiberErrors.ErrBackendRejected} {
+				assert.False(t, errors.Is(errResp.Err(), kind))
+			}
+		})
+	}
+
+	// the response, created from the payload, carries no error
+	errResp := fiber.NewErrorResponseWithPayload(http.StatusBadGateway, []byte("bad gateway")).(*fiber.ErrorResponse)
+	assert.Nil(t, errResp.Err())
+}
+
+func TestErrorResponse_PayloadWithRouteErrors(t *testing.T) {
+	router := fiber.NewEagerRouter("eager-router")
+	routes := map[string]fiber.Component{
+		"route-a": testutils.NewMockComponent("route-a",
+			testUtilsHttp.DelayedResponse{Response: testUtilsHttp.MockResp(500, "internal error", nil, nil)}),
+		"route-b": testutils.NewMockComponent("route-b",
+			testUtilsHttp.DelayedResponse{
+				Latency:  time.Second,
+				Response: testUtilsHttp.MockResp(200, "B-OK", nil, nil)}),
+	}
+	router.SetRoutes(routes)
+	router.SetStrategy(testutils.NewMockRoutingStrategy(routes, []string{"route-a", "route-b"}, 0, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	resp, ok := <-router.Dispatch(
+		ctx,
+		testUtilsHttp.MockReq("POST", "http://localhost:8080/route-errors", "payload"),
+	).Iter()
+	require.True(t, ok)
+	errResp, ok := resp.(*fiber.ErrorResponse)
+	require.True(t, ok)
+
+	// route-b hasn't responded within the timeout
+	assert.JSONEq(t, `{
+	  "code": 503,
+	  "error": "fiber: no responses received",
+	  "route_errors": [
+	    {"route_id": "route-a", "code": 500, "error": "internal error"},
+	    {"route_id": "route-b", "code": 503, "error": "fiber: no responses received"}
+	  ]
+	}`, string(errResp.PayloadWithRouteErrors()))
+	assert.JSONEq(t, `{"code": 503, "error": "fiber: no responses received"}`, string(errResp.Payload()))
+
+	// the responses without route errors are rendered as they are
+	plain := fiber.NewErrorResponseWithPayload(500, []byte("not a json")).(*fiber.ErrorResponse)
+	assert.Equal(t, "not a json", string(plain.PayloadWithRouteErrors()))
+	assert.Equal(t, "not a json", plain.Message())
+}
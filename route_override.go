@@ -0,0 +1,63 @@
+package fiber
+
+import (
+	"fmt"
+
+	"github.com/gojek/fiber/errors"
+)
+
+// RouteOverrideHeader is the header (or the grpc metadata key) of the request, that names the route to
+// dispatch the request by, if the router has the RouteOverride
+const RouteOverrideHeader = "X-Fiber-Route"
+
+// RouteOverride lets the request pin the route of the router, bypassing its routing strategy (e.g. for the
+// canary testing), by naming the route in the header. The request without the header is routed as usual
+type RouteOverride struct {
+	// Header is the header (or the grpc metadata key) with the id of the route. Defaults to RouteOverrideHeader
+	Header string
+	// Fallback, if set, keeps the routes selected by the routing strategy as the fallbacks of the pinned route.
+	// Otherwise, the request is dispatched by the pinned route only
+	Fallback bool
+	// RejectUnknown, if set, fails the request, that names an unknown route, with ErrInvalidInput.
+	// Otherwise, the header is ignored, and the request is routed by the routing strategy
+	RejectUnknown bool
+}
+
+// route returns the route, named by the header of the request, or nil, if the header is not set, or the
+// unknown route is ignored
+func (o *RouteOverride) route(req Request, routes map[string]Component) (Component, error) {
+	if o == nil {
+		return nil, nil
+	}
+	header := o.Header
+	if header == "" {
+		header = RouteOverrideHeader
+	}
+	values := req.Header()[headerKey(req.Protocol(), header)]
+	if len(values) == 0 || values[0] == "" {
+		return nil, nil
+	}
+	if route, ok := routes[values[0]]; ok {
+		return route, nil
+	}
+	if o.RejectUnknown {
+		return nil, *errors.ErrInvalidInput(req.Protocol(),
+			fmt.Errorf("route [%s] of the %s header doesn't exist", values[0], header))
+	}
+	return nil, nil
+}
+
+// pin returns the pinned route, followed by the routes selected by the routing strategy, if the fallback
+// is enabled
+func (o *RouteOverride) pin(route Component, selected []Component) []Component {
+	routes := []Component{route}
+	if !o.Fallback {
+		return routes
+	}
+	for _, fallback := range selected {
+		if fallback.ID() != route.ID() {
+			routes = append(routes, fallback)
+		}
+	}
+	return routes
+}
@@ -0,0 +1,132 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gojek/fiber"
+	fiberGRPC "github.com/gojek/fiber/grpc"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// overridableRouter is the router with the RouteOverride
+type overridableRouter interface {
+	fiber.Router
+	SetRouteOverride(override *fiber.RouteOverride)
+}
+
+func TestRouter_RouteOverride(t *testing.T) {
+	httpReq := func(route string) fiber.Request {
+		req := testUtilsHttp.MockReq("GET", "http://localhost", "")
+		if route != "" {
+			req.Request.Header.Set(fiber.RouteOverrideHeader, route)
+		}
+		return req
+	}
+
+	suite := map[string]struct {
+		override       *fiber.RouteOverride
+		request        fiber.Request
+		expectedStatus int
+		expectedBody   string
+		expectedRoutes []string
+	}{
+		"http header": {
+			override:       &fiber.RouteOverride{},
+			request:        httpReq("route-c"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "C-OK",
+		},
+		"grpc metadata": {
+			override:       &fiber.RouteOverride{},
+			request:        &fiberGRPC.Request{Metadata: metadata.Pairs("x-fiber-route", "route-c")},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "C-OK",
+		},
+		"custom header": {
+			override:       &fiber.RouteOverride{Header: "X-Canary"},
+			request:        &fiberGRPC.Request{Metadata: metadata.Pairs("x-canary", "route-c")},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "C-OK",
+		},
+		"override disabled": {
+			request:        httpReq("route-c"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "A-OK",
+		},
+		"no header": {
+			override:       &fiber.RouteOverride{},
+			request:        httpReq(""),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "A-OK",
+		},
+		"unknown route is ignored": {
+			override:       &fiber.RouteOverride{},
+			request:        httpReq("route-x"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "A-OK",
+		},
+		"unknown route is rejected": {
+			override:       &fiber.RouteOverride{RejectUnknown: true},
+			request:        httpReq("route-x"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "fiber: route [route-x] of the X-Fiber-Route header doesn't exist",
+		},
+		"unknown route is rejected by grpc": {
+			override:       &fiber.RouteOverride{RejectUnknown: true},
+			request:        &fiberGRPC.Request{Metadata: metadata.Pairs("x-fiber-route", "route-x")},
+			expectedStatus: int(codes.InvalidArgument),
+			expectedBody:   "fiber: route [route-x] of the X-Fiber-Route header doesn't exist",
+		},
+		"pinned route without fallback": {
+			override:       &fiber.RouteOverride{},
+			request:        httpReq("route-b"),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedRoutes: []string{"route-b"},
+		},
+		"pinned route with fallback": {
+			override:       &fiber.RouteOverride{Fallback: true},
+			request:        httpReq("route-b"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "A-OK",
+		},
+	}
+
+	for routerName, newRouter := range map[string]func() overridableRouter{
+		"lazy router":  func() overridableRouter { return fiber.NewLazyRouter("lazy-router") },
+		"eager router": func() overridableRouter { return fiber.NewEagerRouter("eager-router") },
+	} {
+		for name, tt := range suite {
+			t.Run(routerName+"/"+name, func(t *testing.T) {
+				builder := testutils.NewRouterBuilder("router").
+					WithRoute("route-a", testUtilsHttp.MockResp(http.StatusOK, "A-OK", nil, nil)).
+					WithRoute("route-b", testUtilsHttp.MockResp(http.StatusInternalServerError, "B-FAIL", nil, nil)).
+					WithRoute("route-c", testUtilsHttp.MockResp(http.StatusOK, "C-OK", nil, nil))
+				router := newRouter()
+				router.SetRoutes(builder.Routes())
+				router.SetStrategy(&orderedRoutingStrategy{order: []string{"route-a", "route-b", "route-c"}})
+				router.SetRouteOverride(tt.override)
+
+				responses := testutils.Dispatch(context.Background(), router, tt.request)
+				require.Len(t, responses, 1)
+				assert.Equal(t, tt.expectedStatus, responses[0].StatusCode())
+				assert.Contains(t, string(responses[0].Payload()), tt.expectedBody)
+				if tt.expectedRoutes != nil {
+					errResp, ok := responses[0].(*fiber.ErrorResponse)
+					require.True(t, ok)
+					var routes []string
+					for _, routeErr := range errResp.RouteErrors() {
+						routes = append(routes, routeErr.RouteID)
+					}
+					assert.Equal(t, tt.expectedRoutes, routes)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package fiber
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gojek/fiber/errors"
+)
+
+// RouteTagFilter restricts the routes, that the routing strategy of the router selects the primary route and
+// the fallbacks from, to the ones, which tags match the tags of the request (e.g. to keep the requests within
+// their region). The tags of the request are read from its headers (or the grpc metadata), and the request
+// without the header of a tag isn't restricted by this tag
+type RouteTagFilter struct {
+	// Headers are the headers (or the grpc metadata keys) with the values of the tags of the request,
+	// keyed by the tag, e.g. {"region": "X-Region"}
+	Headers map[string]string
+	// Tags are the tags of the routes, keyed by the route ID. The route doesn't match the request,
+	// unless it has all the tags of the request with the same values
+	Tags map[string]map[string]string
+}
+
+// requestTags returns the tags of the request, that are set by its headers
+func (f *RouteTagFilter) requestTags(req Request) map[string]string {
+	tags := make(map[string]string, len(f.Headers))
+	for tag, header := range f.Headers {
+		if values := req.Header()[headerKey(req.Protocol(), header)]; len(values) > 0 && values[0] != "" {
+			tags[tag] = values[0]
+		}
+	}
+	return tags
+}
+
+// filter returns the routes, which tags match the tags of the request, or ErrInvalidInput, if none of them does.
+// All the routes are returned, if the request has no tags, or the filter is not set
+func (f *RouteTagFilter) filter(req Request, routes map[string]Component) (map[string]Component, error) {
+	if f == nil {
+		return routes, nil
+	}
+	required := f.requestTags(req)
+	if len(required) == 0 {
+		return routes, nil
+	}
+
+	matching := make(map[string]Component, len(routes))
+	for id, route := range routes {
+		if f.matches(id, required) {
+			matching[id] = route
+		}
+	}
+	if len(matching) == 0 && len(routes) > 0 {
+		return nil, *errors.ErrInvalidInput(req.Protocol(),
+			fmt.Errorf("no route matches the tags [%s]", formatTags(required)))
+	}
+	return matching, nil
+}
+
+// matches returns true, if the route has all the required tags with the same values
+func (f *RouteTagFilter) matches(routeID string, required map[string]string) bool {
+	tags := f.Tags[routeID]
+	for tag, value := range required {
+		if tags[tag] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// formatTags formats the tags as the sorted `tag=value` pairs, e.g. `region=us, tier=gold`
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for tag, value := range tags {
+		pairs = append(pairs, tag+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
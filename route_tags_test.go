@@ -0,0 +1,101 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyRouter_RouteTagFilter(t *testing.T) {
+	tags := map[string]map[string]string{
+		"route-us-a": {"region": "us", "tier": "gold"},
+		"route-us-b": {"region": "us"},
+		"route-eu-a": {"region": "eu", "tier": "gold"},
+		"route-none": nil,
+	}
+
+	// all the routes fail, so the routes, that the router has tried, are the ones of its route errors
+	builder := testutils.NewRouterBuilder("lazy-router").WithStrategy(new(extras.RandomRoutingStrategy).WithSeed(42))
+	backends := make(map[string]*testutils.MockBackend)
+	for id := range tags {
+		backends[id] = testutils.NewMockBackend(
+			fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP)))
+		builder.WithComponent(backends[id].Component(id))
+	}
+	router := builder.BuildLazy()
+	router.SetRouteTagFilter(&fiber.RouteTagFilter{
+		Headers: map[string]string{"region": "X-Region", "tier": "X-Tier"},
+		Tags:    tags,
+	})
+
+	suite := map[string]struct {
+		headers  map[string]string
+		expected []string
+		errMsg   string
+	}{
+		"routes of the region": {
+			headers:  map[string]string{"X-Region": "us"},
+			expected: []string{"route-us-a", "route-us-b"},
+		},
+		"routes of all the tags": {
+			headers:  map[string]string{"X-Region": "us", "X-Tier": "gold"},
+			expected: []string{"route-us-a"},
+		},
+		"no tags": {
+			expected: []string{"route-eu-a", "route-none", "route-us-a", "route-us-b"},
+		},
+		"no matching routes": {
+			headers: map[string]string{"X-Region": "ap", "X-Tier": "gold"},
+			errMsg:  "fiber: no route matches the tags [region=ap, tier=gold]",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080", "")
+			for header, value := range tt.headers {
+				req.Request.Header.Set(header, value)
+			}
+
+			for i := 0; i < 5; i++ {
+				decision, err := router.Explain(context.Background(), req)
+				if tt.errMsg != "" {
+					assert.EqualError(t, err, tt.errMsg)
+				} else {
+					require.NoError(t, err)
+					assert.ElementsMatch(t, tt.expected, decision.Routes)
+					assert.Len(t, decision.Skipped, len(tags)-len(tt.expected))
+					for id, reason := range decision.Skipped {
+						assert.Equal(t, fiber.SkippedTags, reason, id)
+					}
+				}
+
+				responses := testutils.Dispatch(context.Background(), router, req)
+				require.Len(t, responses, 1)
+				if tt.errMsg != "" {
+					assert.Equal(t, http.StatusBadRequest, responses[0].StatusCode())
+					assert.Contains(t, string(responses[0].Payload()), tt.errMsg)
+					continue
+				}
+				errResp, ok := responses[0].(*fiber.ErrorResponse)
+				require.True(t, ok)
+				tried := make([]string, 0, len(tt.expected))
+				for _, routeErr := range errResp.RouteErrors() {
+					tried = append(tried, routeErr.RouteID)
+				}
+				sort.Strings(tried)
+				assert.Equal(t, tt.expected, tried)
+			}
+		})
+	}
+}
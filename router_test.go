@@ -0,0 +1,116 @@
+package fiber_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/extras"
+	fiberHTTP "github.com/gojek/fiber/http"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_ConcurrentDispatch(t *testing.T) {
+	const (
+		goroutines = 16
+		requests   = 20
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	// keep enough idle connections for all the goroutines, so the test doesn't exhaust local ports
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = goroutines * 3
+	dispatcher, err := fiberHTTP.NewDispatcher(&http.Client{Transport: transport})
+	require.NoError(t, err)
+
+	routes := func() map[string]fiber.Component {
+		routes := make(map[string]fiber.Component)
+		for _, id := range []string{"route-a", "route-b", "route-c"} {
+			caller, err := fiber.NewCaller(id, dispatcher)
+			require.NoError(t, err)
+			routes[id] = fiber.NewProxy(fiber.NewBackend(id, server.URL+"/"+id), caller)
+		}
+		return routes
+	}
+
+	strategies := map[string]func() fiber.RoutingStrategy{
+		"random": func() fiber.RoutingStrategy {
+			return &extras.RandomRoutingStrategy{}
+		},
+		"weighted": func() fiber.RoutingStrategy {
+			strategy, _ := extras.NewWeightedRandomRoutingStrategy(map[string]float64{"route-a": 2})
+			return strategy
+		},
+		"latency": func() fiber.RoutingStrategy {
+			strategy, _ := extras.NewLatencyAwareRoutingStrategy(extras.DefaultLatencyDecayFactor, 5)
+			return strategy
+		},
+		"consistent hash": func() fiber.RoutingStrategy {
+			return extras.NewConsistentHashRoutingStrategy("X-Route", extras.DefaultVirtualNodes)
+		},
+		"external": func() fiber.RoutingStrategy {
+			decisionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprintf(w, `{"route_id": "route-%s"}`, r.URL.Query().Get("key"))
+			}))
+			t.Cleanup(decisionServer.Close)
+			return extras.NewExternalRoutingStrategy(decisionServer.URL, "X-Route", time.Minute, time.Second)
+		},
+	}
+
+	routers := map[string]func() fiber.Router{
+		"lazy": func() fiber.Router {
+			return fiber.NewLazyRouter("lazy-router")
+		},
+		"eager": func() fiber.Router {
+			return fiber.NewEagerRouter("eager-router")
+		},
+	}
+
+	for routerName, newRouter := range routers {
+		for strategyName, newStrategy := range strategies {
+			t.Run(routerName+" | "+strategyName, func(t *testing.T) {
+				router := newRouter()
+				strategy := newStrategy()
+				router.SetRoutes(routes())
+				router.SetStrategy(strategy)
+				if observer, ok := strategy.(interface{ Interceptor() fiber.Interceptor }); ok {
+					for _, route := range router.GetRoutes() {
+						route.AddInterceptor(false, observer.Interceptor())
+					}
+				}
+
+				var wg sync.WaitGroup
+				wg.Add(goroutines)
+				for g := 0; g < goroutines; g++ {
+					go func(g int) {
+						defer wg.Done()
+						for i := 0; i < requests; i++ {
+							req := testUtilsHttp.MockReq("GET", "http://localhost:8080/router", "")
+							req.Header()["X-Route"] = []string{[]string{"a", "b", "c"}[(g+i)%3]}
+
+							ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+							resp, ok := <-router.Dispatch(ctx, req).Iter()
+							cancel()
+
+							if assert.True(t, ok) {
+								assert.True(t, resp.IsSuccess(), string(resp.Payload()))
+							}
+						}
+					}(g)
+				}
+				wg.Wait()
+			})
+		}
+	}
+}
@@ -1,11 +1,18 @@
 package fiber
 
-import "context"
+import (
+	"context"
+	"fmt"
+)
 
 // RoutingStrategy picks up primary route and zero or more fallbacks
-// from the map of router routes
+// from the map of router routes. A router calls SelectRoute concurrently for
+// every incoming request, so the implementations must be safe for concurrent use.
+// The strategies can route on the content of the request, i.e. its payload, the http
+// headers or the grpc metadata (see Request.Header), and on the values of its context
 type RoutingStrategy interface {
 	Type
+	// ctx - context of the request, with the values set by the PreRoutingHook, if any
 	// req - Incoming request (so the route can be selected based on the request)
 	// routes - map of all possible routes
 	SelectRoute(ctx context.Context,
@@ -17,18 +24,55 @@ type RoutingStrategy interface {
 type baseRoutingStrategy struct {
 	RoutingStrategy
 	BaseFiberType
+
+	// name is the type name of the routing strategy, i.e. "*extras.RandomRoutingStrategy"
+	name string
+}
+
+func newBaseRoutingStrategy(strategy RoutingStrategy) *baseRoutingStrategy {
+	return &baseRoutingStrategy{RoutingStrategy: strategy, name: fmt.Sprintf("%T", strategy)}
+}
+
+// withName adds the name of the routing strategy into the request context
+func (s *baseRoutingStrategy) withName(ctx context.Context) context.Context {
+	return context.WithValue(ctx, CtxRoutingStrategyKey, s.name)
 }
 
+// getRoutesOrder selects the ordered routes of the request, i.e. the primary route and its fallbacks, unless
+// the request pins its route with the override header (see RouteOverride)
 func (s *baseRoutingStrategy) getRoutesOrder(
 	ctx context.Context,
 	req Request,
 	routes map[string]Component,
+	override *RouteOverride,
 ) (<-chan []Component, <-chan error) {
 	out := make(chan []Component)
 	errCh := make(chan error, 1)
 
 	go func() {
-		route, fallbacks, err := s.SelectRoute(ctx, req, routes)
+		// Close both channels
+		defer close(errCh)
+		defer close(out)
+		defer func() {
+			// the panic of the routing strategy is sent back as its error
+			if value := recover(); value != nil {
+				errCh <- newPanicError(value)
+			}
+		}()
+
+		// the pinned route is selected, even if it's unhealthy
+		pinned, err := override.route(req, routes)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		if pinned != nil && !override.Fallback {
+			out <- override.pin(pinned, nil)
+			return
+		}
+
+		// the unhealthy routes are not selected, unless all the routes are unhealthy
+		route, fallbacks, err := s.SelectRoute(ctx, req, healthyRoutes(routes))
 
 		if err != nil {
 			errCh <- err
@@ -38,11 +82,11 @@ func (s *baseRoutingStrategy) getRoutesOrder(
 			if route != nil {
 				routes = append([]Component{route}, routes...)
 			}
+			if pinned != nil {
+				routes = override.pin(pinned, routes)
+			}
 			out <- routes
 		}
-		// Close both channels
-		close(out)
-		close(errCh)
 	}()
 
 	return out, errCh
@@ -0,0 +1,93 @@
+package fiber_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gojek/fiber"
+	fiberGRPC "github.com/gojek/fiber/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+type regionKey struct{}
+
+// metadataRoutingStrategy routes the grpc requests by the value of the `x-tenant-tier` metadata and
+// the requests without it, by the region, set in their context
+type metadataRoutingStrategy struct {
+	fiber.BaseFiberType
+}
+
+func (s *metadataRoutingStrategy) SelectRoute(
+	ctx context.Context,
+	req fiber.Request,
+	routes map[string]fiber.Component,
+) (fiber.Component, []fiber.Component, error) {
+	if tier := req.Header()["x-tenant-tier"]; len(tier) > 0 {
+		if route, ok := routes["route-"+tier[0]]; ok {
+			return route, []fiber.Component{routes["route-default"]}, nil
+		}
+	}
+	if region, _ := ctx.Value(regionKey{}).(string); region != "" {
+		return routes["route-"+region], []fiber.Component{routes["route-default"]}, nil
+	}
+	return routes["route-default"], nil, nil
+}
+
+func TestRoutingStrategy_RequestAndContext(t *testing.T) {
+	routers := map[string]func() fiber.Router{
+		"lazy router":  func() fiber.Router { return fiber.NewLazyRouter("lazy-router") },
+		"eager router": func() fiber.Router { return fiber.NewEagerRouter("eager-router") },
+	}
+
+	suite := map[string]struct {
+		ctx      context.Context
+		metadata metadata.MD
+		expected string
+	}{
+		"routed by the metadata": {
+			ctx:      context.Background(),
+			metadata: metadata.Pairs("X-Tenant-Tier", "gold"),
+			expected: "route-gold",
+		},
+		"metadata takes precedence over the context": {
+			ctx:      context.WithValue(context.Background(), regionKey{}, "eu"),
+			metadata: metadata.Pairs("x-tenant-tier", "gold"),
+			expected: "route-gold",
+		},
+		"unknown metadata value": {
+			ctx:      context.Background(),
+			metadata: metadata.Pairs("x-tenant-tier", "silver"),
+			expected: "route-default",
+		},
+		"routed by the context": {
+			ctx:      context.WithValue(context.Background(), regionKey{}, "eu"),
+			expected: "route-eu",
+		},
+		"neither metadata nor context": {
+			ctx:      context.Background(),
+			expected: "route-default",
+		},
+	}
+
+	for routerName, newRouter := range routers {
+		for name, tt := range suite {
+			t.Run(routerName+"/"+name, func(t *testing.T) {
+				routes := make(map[string]fiber.Component)
+				for _, id := range []string{"route-gold", "route-eu", "route-default"} {
+					routes[id] = newSlowComponent(id, 0)
+				}
+				router := newRouter()
+				router.SetRoutes(routes)
+				router.SetStrategy(&metadataRoutingStrategy{})
+
+				req := &fiberGRPC.Request{Metadata: tt.metadata, Message: []byte("payload")}
+				resp := <-router.Dispatch(tt.ctx, req).Iter()
+				require.NotNil(t, resp)
+				require.True(t, resp.IsSuccess())
+				assert.Equal(t, tt.expected, string(resp.Payload()))
+			})
+		}
+	}
+}
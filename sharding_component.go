@@ -0,0 +1,253 @@
+package fiber
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/util"
+)
+
+// ShardingComponentKind represents the ShardingComponent type
+const ShardingComponentKind ComponentKind = "ShardingComponent"
+
+// BatchCodec is used by the ShardingComponent to split batch requests into items and to
+// assemble the items back into requests and responses. Its implementation is specific to
+// the payload format of the batch requests
+type BatchCodec interface {
+	// SplitRequest splits the incoming batch request into individual items
+	SplitRequest(req Request) ([][]byte, error)
+	// JoinRequest creates a sub-request with the given items from the incoming request
+	JoinRequest(req Request, items [][]byte) (Request, error)
+	// SplitResponse splits the response to a sub-request into items, in the order
+	// of the items of this sub-request
+	SplitResponse(resp Response) ([][]byte, error)
+	// JoinResponses creates a single response from the items, ordered the same way as
+	// the items of the incoming request. Items of the failed shards are nil
+	JoinResponses(req Request, items [][]byte) (Response, error)
+}
+
+// KeyExtractor returns the sharding key of the batch item
+type KeyExtractor func(item []byte) (string, error)
+
+// ShardRange defines the range of keys [From, To), that is owned by the route with a given ID.
+// Empty From or To means, that the range is unbounded from the corresponding side
+type ShardRange struct {
+	From    string
+	To      string
+	RouteID string
+}
+
+func (r ShardRange) contains(key string) bool {
+	return (r.From == "" || key >= r.From) && (r.To == "" || key < r.To)
+}
+
+// ShardFailurePolicy defines how the ShardingComponent handles a failure of one of the shards
+type ShardFailurePolicy string
+
+const (
+	// ShardFailurePolicyFail fails the whole batch, if any of the shards has failed. The shards, that are still
+	// in flight, are cancelled
+	ShardFailurePolicyFail ShardFailurePolicy = "FAIL"
+	// ShardFailurePolicyPartial assembles the response from the shards, that responded
+	// successfully. Items of the failed shards are passed as nil into the BatchCodec
+	ShardFailurePolicyPartial ShardFailurePolicy = "PARTIAL"
+)
+
+// ShardingComponent is a MultiRouteComponent, that splits an incoming batch request into
+// sub-requests by the key of each batch item, dispatches each sub-request by the route, that
+// owns the key range of its items, and then reassembles responses from all the routes into
+// a single response, preserving the order of items of the incoming request
+type ShardingComponent struct {
+	*BaseMultiRouteComponent
+
+	codec     BatchCodec
+	extractor KeyExtractor
+	shards    []ShardRange
+	policy    ShardFailurePolicy
+}
+
+// NewShardingComponent is a factory for the ShardingComponent type. Keys that fall into
+// more than one shard range are owned by the first matching shard
+func NewShardingComponent(
+	id string,
+	codec BatchCodec,
+	extractor KeyExtractor,
+	shards []ShardRange,
+) *ShardingComponent {
+	if id == "" {
+		id = "sharding_" + util.UID()
+	}
+	component := &ShardingComponent{
+		BaseMultiRouteComponent: NewMultiRouteComponent(id),
+		codec:                   codec,
+		extractor:               extractor,
+		shards:                  shards,
+		policy:                  ShardFailurePolicyFail,
+	}
+	component.kind = ShardingComponentKind
+	return component
+}
+
+// WithFailurePolicy sets the policy to handle failures of individual shards
+func (c *ShardingComponent) WithFailurePolicy(policy ShardFailurePolicy) *ShardingComponent {
+	c.policy = policy
+	return c
+}
+
+// Dispatch splits the incoming request, dispatches the sub-requests by the routes owning
+// their shards in parallel and sends the reassembled response into the output channel
+func (c *ShardingComponent) Dispatch(ctx context.Context, req Request) ResponseQueue {
+	return c.intercept(ctx, req, c.dispatch)
+}
+
+func (c *ShardingComponent) dispatch(ctx context.Context, req Request) ResponseQueue {
+	ctx = c.beforeDispatch(ctx, req)
+	out := make(chan Response, 1)
+
+	queue := NewResponseQueue(out, 1)
+	defer c.afterDispatch(ctx, req, queue)
+
+	go func() {
+		defer c.afterCompletion(ctx, req, queue)
+
+		out <- c.dispatchShards(ctx, req)
+		close(out)
+	}()
+
+	return queue
+}
+
+func (c *ShardingComponent) shardOf(key string) (string, bool) {
+	for _, shard := range c.shards {
+		if shard.contains(key) {
+			return shard.RouteID, true
+		}
+	}
+	return "", false
+}
+
+type shardResult struct {
+	routeID string
+	items   [][]byte
+	resp    Response
+}
+
+func (c *ShardingComponent) dispatchShards(ctx context.Context, req Request) Response {
+	items, err := c.codec.SplitRequest(req)
+	if err != nil {
+		return NewErrorResponse(errors.ErrInvalidInput(req.Protocol(), err))
+	}
+
+	// indices of the items of the incoming request, grouped by the route owning them
+	indices := make(map[string][]int)
+	for idx, item := range items {
+		key, err := c.extractor(item)
+		if err != nil {
+			return NewErrorResponse(errors.ErrInvalidInput(req.Protocol(), err))
+		}
+		routeID, ok := c.shardOf(key)
+		if !ok {
+			return NewErrorResponse(errors.ErrInvalidInput(
+				req.Protocol(), fmt.Errorf("no shard owns the key: %s", key)))
+		}
+		indices[routeID] = append(indices[routeID], idx)
+	}
+
+	// build sub-requests for every shard before dispatching any of them
+	shardRequests := make(map[string]Request, len(indices))
+	for routeID, shardIndices := range indices {
+		if _, ok := c.routes[routeID]; !ok {
+			return NewErrorResponse(errors.ErrRequestFailed(
+				req.Protocol(), fmt.Errorf("route not found: %s", routeID)))
+		}
+
+		shardItems := make([][]byte, len(shardIndices))
+		for i, idx := range shardIndices {
+			shardItems[i] = items[idx]
+		}
+		shardReq, err := c.codec.JoinRequest(req, shardItems)
+		if err != nil {
+			return NewErrorResponse(errors.ErrRequestFailed(req.Protocol(), err))
+		}
+		shardRequests[routeID] = shardReq
+	}
+
+	// with the fail-fast policy, the shards in flight are cancelled on the first failure
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan shardResult, len(shardRequests))
+	var wg sync.WaitGroup
+	wg.Add(len(shardRequests))
+	for routeID, shardReq := range shardRequests {
+		go func(routeID string, shardReq Request) {
+			defer wg.Done()
+			results <- c.dispatchShard(ctx, routeID, c.routes[routeID], shardReq, len(indices[routeID]))
+		}(routeID, shardReq)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	assembled := make([][]byte, len(items))
+	var (
+		failed   Response
+		failures int
+	)
+	for result := range results {
+		if result.resp != nil {
+			if c.policy != ShardFailurePolicyPartial {
+				return result.resp
+			}
+			failed = result.resp
+			failures++
+			continue
+		}
+		for i, idx := range indices[result.routeID] {
+			assembled[idx] = result.items[i]
+		}
+	}
+	if failures > 0 && failures == len(shardRequests) {
+		// nothing to assemble, when all the shards have failed
+		return failed
+	}
+
+	resp, err := c.codec.JoinResponses(req, assembled)
+	if err != nil {
+		return NewErrorResponse(errors.ErrRequestFailed(req.Protocol(), err))
+	}
+	return resp
+}
+
+// dispatchShard dispatches the sub-request by the given route and splits the response into
+// items. If the shard has failed, the error response is returned in the result
+func (c *ShardingComponent) dispatchShard(
+	ctx context.Context,
+	routeID string,
+	route Component,
+	req Request,
+	expected int,
+) shardResult {
+	select {
+	case resp, ok := <-safeDispatch(ctx, ctxDispatchLogger(ctx), route, req).Iter():
+		if !ok {
+			return shardResult{routeID: routeID, resp: NewErrorResponse(errors.ErrServiceUnavailable(req.Protocol()))}
+		}
+		if !resp.IsSuccess() {
+			return shardResult{routeID: routeID, resp: resp.WithBackendName(routeID)}
+		}
+		items, err := c.codec.SplitResponse(resp)
+		if err == nil && len(items) != expected {
+			err = fmt.Errorf("shard %s responded with %d items, expected %d", routeID, len(items), expected)
+		}
+		if err != nil {
+			return shardResult{routeID: routeID, resp: NewErrorResponse(errors.ErrRequestFailed(req.Protocol(), err))}
+		}
+		return shardResult{routeID: routeID, items: items}
+	case <-ctx.Done():
+		return shardResult{routeID: routeID, resp: NewErrorResponse(errors.ErrRequestTimeout(req.Protocol()))}
+	}
+}
@@ -0,0 +1,249 @@
+package fiber_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+// csvCodec treats the payload as a comma-separated list of items
+type csvCodec struct{}
+
+func (c *csvCodec) SplitRequest(req fiber.Request) ([][]byte, error) {
+	return c.split(req.Payload()), nil
+}
+
+func (c *csvCodec) JoinRequest(req fiber.Request, items [][]byte) (fiber.Request, error) {
+	return testUtilsHttp.MockReq("POST", "http://localhost:8080/shard", c.join(items)), nil
+}
+
+func (c *csvCodec) SplitResponse(resp fiber.Response) ([][]byte, error) {
+	return c.split(resp.Payload()), nil
+}
+
+func (c *csvCodec) JoinResponses(_ fiber.Request, items [][]byte) (fiber.Response, error) {
+	for idx := range items {
+		if items[idx] == nil {
+			items[idx] = []byte("-")
+		}
+	}
+	return testUtilsHttp.MockResp(200, c.join(items), nil, nil), nil
+}
+
+func (c *csvCodec) split(payload []byte) [][]byte {
+	items := make([][]byte, 0)
+	for _, item := range strings.Split(string(payload), ",") {
+		items = append(items, []byte(item))
+	}
+	return items
+}
+
+func (c *csvCodec) join(items [][]byte) string {
+	values := make([]string, len(items))
+	for idx, item := range items {
+		values[idx] = string(item)
+	}
+	return strings.Join(values, ",")
+}
+
+// shardComponent responds with the items of the request, prefixed with the shard id
+type shardComponent struct {
+	*fiber.BaseComponent
+	latency time.Duration
+	err     error
+}
+
+func (s *shardComponent) Dispatch(_ context.Context, req fiber.Request) fiber.ResponseQueue {
+	time.Sleep(s.latency)
+	if s.err != nil {
+		return fiber.NewResponseQueueFromResponses(fiber.NewErrorResponse(s.err))
+	}
+	items := strings.Split(string(req.Payload()), ",")
+	for idx := range items {
+		items[idx] = fmt.Sprintf("%s:%s", s.ID(), items[idx])
+	}
+	return fiber.NewResponseQueueFromResponses(
+		testUtilsHttp.MockResp(200, strings.Join(items, ","), nil, nil))
+}
+
+// cancellableComponent responds after the latency, unless the context of the dispatch is cancelled
+// earlier, in which case it records the cancellation and responds with no response
+type cancellableComponent struct {
+	*fiber.BaseComponent
+	latency   time.Duration
+	cancelled chan struct{}
+}
+
+func newCancellableComponent(id string, latency time.Duration) *cancellableComponent {
+	return &cancellableComponent{
+		BaseComponent: fiber.NewBaseComponent(id, fiber.CallerKind),
+		latency:       latency,
+		cancelled:     make(chan struct{}),
+	}
+}
+
+func (c *cancellableComponent) Dispatch(ctx context.Context, _ fiber.Request) fiber.ResponseQueue {
+	out := make(chan fiber.Response, 1)
+	go func() {
+		defer close(out)
+		select {
+		case <-time.After(c.latency):
+			out <- testUtilsHttp.MockResp(http.StatusOK, c.ID(), nil, nil)
+		case <-ctx.Done():
+			close(c.cancelled)
+		}
+	}()
+	return fiber.NewResponseQueue(out, 1)
+}
+
+func TestShardingComponent_Dispatch(t *testing.T) {
+	shards := []fiber.ShardRange{
+		{To: "h", RouteID: "shard-a"},
+		{From: "h", To: "p", RouteID: "shard-b"},
+		{From: "p", RouteID: "shard-c"},
+	}
+
+	suite := []struct {
+		name     string
+		payload  string
+		policy   fiber.ShardFailurePolicy
+		failed   map[string]bool
+		expected fiber.Response
+	}{
+		{
+			name:     "items order preserved",
+			payload:  "zeta,alpha,kilo,bravo,quebec,hotel",
+			expected: testUtilsHttp.MockResp(200, "shard-c:zeta,shard-a:alpha,shard-b:kilo,shard-a:bravo,shard-c:quebec,shard-b:hotel", nil, nil),
+		},
+		{
+			name:     "single shard",
+			payload:  "bravo,alpha",
+			expected: testUtilsHttp.MockResp(200, "shard-a:bravo,shard-a:alpha", nil, nil),
+		},
+		{
+			name:     "shard failure fails the whole batch",
+			payload:  "zeta,alpha,kilo",
+			failed:   map[string]bool{"shard-b": true},
+			expected: fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+		},
+		{
+			name:     "shard failure with partial policy",
+			payload:  "zeta,alpha,kilo",
+			policy:   fiber.ShardFailurePolicyPartial,
+			failed:   map[string]bool{"shard-b": true},
+			expected: testUtilsHttp.MockResp(200, "shard-c:zeta,shard-a:alpha,-", nil, nil),
+		},
+		{
+			name:     "all shards failed with partial policy",
+			payload:  "kilo",
+			policy:   fiber.ShardFailurePolicyPartial,
+			failed:   map[string]bool{"shard-b": true},
+			expected: fiber.NewErrorResponse(fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+		},
+	}
+
+	for _, tt := range suite {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := make(map[string]fiber.Component)
+			for idx, shard := range shards {
+				route := &shardComponent{
+					BaseComponent: fiber.NewBaseComponent(shard.RouteID, fiber.CallerKind),
+					// responses from the shards arrive in the reverse order
+					latency: time.Duration(len(shards)-idx) * 10 * time.Millisecond,
+				}
+				if tt.failed[shard.RouteID] {
+					route.err = fiberErrors.ErrServiceUnavailable(protocol.HTTP)
+				}
+				routes[shard.RouteID] = route
+			}
+
+			component := fiber.NewShardingComponent(
+				"sharding", &csvCodec{}, func(item []byte) (string, error) { return string(item), nil }, shards)
+			if tt.policy != "" {
+				component.WithFailurePolicy(tt.policy)
+			}
+			component.SetRoutes(routes)
+
+			req := testUtilsHttp.MockReq("POST", "http://localhost:8080/sharding", tt.payload)
+			resp, ok := <-component.Dispatch(context.Background(), req).Iter()
+
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected.StatusCode(), resp.StatusCode())
+			assert.Equal(t, string(tt.expected.Payload()), string(resp.Payload()))
+		})
+	}
+}
+
+func TestShardingComponent_DispatchCancelsShards(t *testing.T) {
+	slow := newCancellableComponent("shard-a", time.Minute)
+	component := fiber.NewShardingComponent(
+		"sharding",
+		&csvCodec{},
+		func(item []byte) (string, error) { return string(item), nil },
+		[]fiber.ShardRange{{To: "h", RouteID: "shard-a"}, {From: "h", RouteID: "shard-b"}})
+	component.SetRoutes(map[string]fiber.Component{
+		"shard-a": slow,
+		"shard-b": &shardComponent{
+			BaseComponent: fiber.NewBaseComponent("shard-b", fiber.CallerKind),
+			err:           fiberErrors.ErrServiceUnavailable(protocol.HTTP),
+		},
+	})
+
+	req := testUtilsHttp.MockReq("POST", "http://localhost:8080/sharding", "alpha,kilo")
+	resp, ok := <-component.Dispatch(context.Background(), req).Iter()
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+
+	// the shard in flight is cancelled, once the batch has failed
+	select {
+	case <-slow.cancelled:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the dispatch of the slow shard hasn't been cancelled")
+	}
+}
+
+func TestShardingComponent_DispatchInvalidKey(t *testing.T) {
+	component := fiber.NewShardingComponent(
+		"sharding",
+		&csvCodec{},
+		func(item []byte) (string, error) {
+			if len(item) == 0 {
+				return "", errors.New("empty key")
+			}
+			return string(item), nil
+		},
+		[]fiber.ShardRange{{To: "m", RouteID: "shard-a"}})
+
+	suite := map[string]struct {
+		payload  string
+		expected fiber.Response
+	}{
+		"key extraction failed": {
+			payload:  "alpha,",
+			expected: fiber.NewErrorResponse(fiberErrors.ErrInvalidInput(protocol.HTTP, errors.New("empty key"))),
+		},
+		"no shard owns the key": {
+			payload: "zeta",
+			expected: fiber.NewErrorResponse(
+				fiberErrors.ErrInvalidInput(protocol.HTTP, errors.New("no shard owns the key: zeta"))),
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			req := testUtilsHttp.MockReq("POST", "http://localhost:8080/sharding", tt.payload)
+			resp := <-component.Dispatch(context.Background(), req).Iter()
+			assert.Equal(t, tt.expected, resp)
+		})
+	}
+}
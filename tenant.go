@@ -0,0 +1,225 @@
+package fiber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdlog "log"
+	"sync"
+
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
+)
+
+// TenantPolicy defines the tenants of the requests, that the TenantIsolatedDispatcher isolates
+type TenantPolicy struct {
+	// Key returns the tenant of the request (see RequestKey). The requests with the empty key have no tenant
+	Key RequestKeyFunc
+	// MaxTenants is the number of the tenants, which dispatchers are kept. The dispatcher of the least recently
+	// used tenant is closed, once there are more of them. Defaults to DefaultMaxKeys
+	MaxTenants int
+}
+
+// TenantDispatcherFactory creates the dispatcher of the requests of the tenant. It's called with the empty
+// tenant for the requests without one
+type TenantDispatcherFactory func(tenant string) (Dispatcher, error)
+
+// TenantDispatchObserver is notified about the dispatches of the requests of the tenants. The dispatch metrics,
+// that implement this interface (e.g. extras.PrometheusMetrics), get the outcomes of the tenants of the routes,
+// initialized from the config. The dispatches can be counted by the statsd client too
+// (see interceptor.MetricsInterceptor)
+type TenantDispatchObserver interface {
+	RecordTenantDispatch(routeID string, tenant string, proto protocol.Protocol, statusCode int)
+}
+
+// TenantIsolatedDispatcher is a Dispatcher, that dispatches the requests of each tenant with its own dispatcher,
+// created by the factory, so the tenants don't share the connections to the backend, the rate limits or the states
+// of the circuit breakers, and one tenant's overload doesn't affect the others. The requests without the tenant
+// share the default dispatcher, that is created with the TenantIsolatedDispatcher. The dispatcher of the evicted
+// tenant is closed, once its in-flight requests are complete
+type TenantIsolatedDispatcher struct {
+	routeID  string
+	policy   TenantPolicy
+	factory  TenantDispatcherFactory
+	observer TenantDispatchObserver
+
+	defaultDispatcher Dispatcher
+	tenants           *keyedStates
+}
+
+// tenantDispatcher is the dispatcher of a tenant, that tracks its in-flight requests, so it's closed after them
+type tenantDispatcher struct {
+	tenant     string
+	dispatcher Dispatcher
+
+	lock     sync.Mutex
+	inFlight int
+	evicted  bool
+}
+
+// NewTenantIsolatedDispatcher is a factory method, that creates a TenantIsolatedDispatcher of the given route,
+// and the default dispatcher of the requests without the tenant
+func NewTenantIsolatedDispatcher(
+	routeID string,
+	policy TenantPolicy,
+	factory TenantDispatcherFactory,
+) (*TenantIsolatedDispatcher, error) {
+	if factory == nil {
+		return nil, errors.New("tenant isolation: factory can not be nil")
+	}
+	if policy.Key == nil {
+		return nil, errors.New("tenant isolation: key of the tenants can not be nil")
+	}
+	if policy.MaxTenants < 0 {
+		return nil, errors.New("tenant isolation: max_tenants can not be negative")
+	}
+	defaultDispatcher, err := factory("")
+	if err != nil {
+		return nil, err
+	}
+
+	return &TenantIsolatedDispatcher{
+		routeID:           routeID,
+		policy:            policy,
+		factory:           factory,
+		defaultDispatcher: defaultDispatcher,
+		tenants: newKeyedStates(policy.MaxTenants, func(state interface{}) {
+			state.(*tenantDispatcher).evict(routeID)
+		}),
+	}, nil
+}
+
+// WithObserver sets the observer, that is notified on each dispatch of the request of a tenant
+func (d *TenantIsolatedDispatcher) WithObserver(observer TenantDispatchObserver) *TenantIsolatedDispatcher {
+	d.observer = observer
+	return d
+}
+
+// Tenants returns the number of the tenants, which dispatchers are kept
+func (d *TenantIsolatedDispatcher) Tenants() int {
+	return d.tenants.len()
+}
+
+// Do dispatches the request with the dispatcher of its tenant, that is created on the first request of the tenant
+func (d *TenantIsolatedDispatcher) Do(ctx context.Context, req Request) Response {
+	tenant := d.policy.Key(req)
+	if tenant == "" {
+		return d.defaultDispatcher.Do(ctx, req)
+	}
+
+	var resp Response
+	for resp == nil {
+		dispatcher, err := d.tenantDispatcher(tenant)
+		if err != nil {
+			resp = NewErrorResponse(fiberErrors.ErrRequestFailed(req.Protocol(),
+				fmt.Errorf("tenant [%s]: %v", tenant, err)))
+		} else {
+			// the dispatcher, that has been evicted since it's been got, is replaced with the new one
+			resp = dispatcher.do(ctx, req, d.routeID)
+		}
+	}
+	if d.observer != nil {
+		d.observer.RecordTenantDispatch(d.routeID, tenant, req.Protocol(), resp.StatusCode())
+	}
+	return resp
+}
+
+// tenantDispatcher returns the dispatcher of the tenant, and creates it, if the tenant has none yet.
+// The factory is called without holding the lock of the tenants, so a slow one (e.g. dialing the backend)
+// doesn't block the requests of the other tenants. If the dispatcher of the tenant has been created
+// by another request in the meantime, that one is used, and the new one is closed
+func (d *TenantIsolatedDispatcher) tenantDispatcher(tenant string) (*tenantDispatcher, error) {
+	if state, ok := d.tenants.lookup(tenant); ok {
+		return state.(*tenantDispatcher), nil
+	}
+	dispatcher, err := d.factory(tenant)
+	if err != nil {
+		return nil, err
+	}
+	created := &tenantDispatcher{tenant: tenant, dispatcher: dispatcher}
+	state, inserted := d.tenants.insert(tenant, created)
+	if !inserted {
+		go created.close(d.routeID)
+	}
+	return state.(*tenantDispatcher), nil
+}
+
+// do dispatches the request, unless the dispatcher has been evicted, and returns nil then
+func (t *tenantDispatcher) do(ctx context.Context, req Request, routeID string) Response {
+	if !t.enter() {
+		return nil
+	}
+	// the request is complete, even if the dispatcher panics, so the evicted one is still closed
+	defer t.leave(routeID)
+	return t.dispatcher.Do(ctx, req)
+}
+
+// enter registers the in-flight request, unless the dispatcher has been evicted. If it returns true,
+// leave has to be called, when the request is complete
+func (t *tenantDispatcher) enter() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.evicted {
+		return false
+	}
+	t.inFlight++
+	return true
+}
+
+// leave marks the request, registered by enter, as complete, and closes the evicted dispatcher after its last request
+func (t *tenantDispatcher) leave(routeID string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.inFlight--
+	if t.evicted && t.inFlight == 0 {
+		go t.close(routeID)
+	}
+}
+
+// evict stops accepting new requests, and closes the dispatcher, unless it has the in-flight ones
+func (t *tenantDispatcher) evict(routeID string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.evicted = true
+	if t.inFlight == 0 {
+		go t.close(routeID)
+	}
+}
+
+// close releases the resources of the evicted dispatcher. There is no caller to return the error to,
+// so it's logged by the standard logger
+func (t *tenantDispatcher) close(routeID string) {
+	if err := closeIfCloser(context.Background(), t.dispatcher); err != nil {
+		stdlog.Printf("fiber: [%s]: failed to close the dispatcher of the tenant [%s]: %v", routeID, t.tenant, err)
+	}
+}
+
+// Saturated returns false, since the dispatchers of the following requests are not known, until their tenants are
+// (see SaturationReporter)
+func (d *TenantIsolatedDispatcher) Saturated() bool {
+	return false
+}
+
+// Describe adds the details of the default dispatcher to the info, since the dispatchers of the tenants
+// only differ from it by their state
+func (d *TenantIsolatedDispatcher) Describe(info *ComponentInfo) {
+	describeIfDescriber(d.defaultDispatcher, info)
+}
+
+// Close releases the resources of the default dispatcher and the dispatchers of all the tenants,
+// and returns the first error, if any
+func (d *TenantIsolatedDispatcher) Close(ctx context.Context) error {
+	err := closeIfCloser(ctx, d.defaultDispatcher)
+	for _, state := range d.tenants.states() {
+		if closeErr := closeIfCloser(ctx, state.(*tenantDispatcher).dispatcher); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// WarmUp establishes the connection of the default dispatcher to its backend, if it's a Warmer. The dispatchers
+// of the tenants are connected on their first requests
+func (d *TenantIsolatedDispatcher) WarmUp(ctx context.Context) error {
+	return warmUpIfWarmer(ctx, d.defaultDispatcher)
+}
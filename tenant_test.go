@@ -0,0 +1,248 @@
+package fiber_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tenantDispatcher is the dispatcher of a tenant, that responds with the status of the tenant,
+// or panics, and records, if it has been closed
+type tenantDispatcher struct {
+	status  int
+	panics  bool
+	release chan struct{}
+	closed  int32
+}
+
+func (d *tenantDispatcher) Do(context.Context, fiber.Request) fiber.Response {
+	if d.release != nil {
+		<-d.release
+	}
+	if d.panics {
+		panic("tenant dispatcher failed")
+	}
+	return testUtilsHttp.MockResp(d.status, "", nil, nil)
+}
+
+func (d *tenantDispatcher) Close(context.Context) error {
+	atomic.AddInt32(&d.closed, 1)
+	return nil
+}
+
+// tenantObserver records the statuses of the dispatches of the tenants
+type tenantObserver struct {
+	lock     sync.Mutex
+	statuses map[string][]int
+}
+
+func (o *tenantObserver) RecordTenantDispatch(_ string, tenant string, _ protocol.Protocol, statusCode int) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.statuses[tenant] = append(o.statuses[tenant], statusCode)
+}
+
+func TestNewTenantIsolatedDispatcher(t *testing.T) {
+	factory := func(string) (fiber.Dispatcher, error) { return &tenantDispatcher{}, nil }
+
+	suite := map[string]struct {
+		policy      fiber.TenantPolicy
+		factory     fiber.TenantDispatcherFactory
+		expectedErr string
+	}{
+		"ok": {
+			policy:  fiber.TenantPolicy{Key: fiber.RequestKey(false, "X-Tenant")},
+			factory: factory,
+		},
+		"error: nil factory": {
+			policy:      fiber.TenantPolicy{Key: fiber.RequestKey(false, "X-Tenant")},
+			expectedErr: "tenant isolation: factory can not be nil",
+		},
+		"error: nil key": {
+			factory:     factory,
+			expectedErr: "tenant isolation: key of the tenants can not be nil",
+		},
+		"error: negative max tenants": {
+			policy:      fiber.TenantPolicy{Key: fiber.RequestKey(false, "X-Tenant"), MaxTenants: -1},
+			factory:     factory,
+			expectedErr: "tenant isolation: max_tenants can not be negative",
+		},
+		"error: default dispatcher": {
+			policy: fiber.TenantPolicy{Key: fiber.RequestKey(false, "X-Tenant")},
+			factory: func(string) (fiber.Dispatcher, error) {
+				return nil, errors.New("invalid backend")
+			},
+			expectedErr: "invalid backend",
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			_, err := fiber.NewTenantIsolatedDispatcher("route", tt.policy, tt.factory)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestTenantIsolatedDispatcher_Do(t *testing.T) {
+	statuses := map[string]int{"": http.StatusOK, "gold": http.StatusAccepted, "silver": http.StatusCreated}
+	var created []string
+	dispatcher, err := fiber.NewTenantIsolatedDispatcher("route",
+		fiber.TenantPolicy{Key: fiber.RequestKey(false, "X-Tenant")},
+		func(tenant string) (fiber.Dispatcher, error) {
+			created = append(created, tenant)
+			if tenant == "bronze" {
+				return nil, errors.New("invalid backend")
+			}
+			return &tenantDispatcher{status: statuses[tenant]}, nil
+		})
+	require.NoError(t, err)
+	observer := &tenantObserver{statuses: make(map[string][]int)}
+	dispatcher.WithObserver(observer)
+
+	req := testUtilsHttp.MockReq(http.MethodGet, "http://localhost:8080", "")
+	assert.Equal(t, http.StatusOK, dispatcher.Do(context.Background(), req).StatusCode())
+	for _, tenant := range []string{"gold", "silver", "gold"} {
+		resp := dispatcher.Do(context.Background(), tenantRequest(tenant, "/"))
+		assert.Equal(t, statuses[tenant], resp.StatusCode())
+	}
+
+	// the dispatcher, that fails to be created, isn't kept
+	for i := 0; i < 2; i++ {
+		resp := dispatcher.Do(context.Background(), tenantRequest("bronze", "/"))
+		assert.Equal(t, fiber.NewErrorResponse(fiberErrors.ErrRequestFailed(protocol.HTTP,
+			errors.New("tenant [bronze]: invalid backend"))), resp)
+	}
+
+	assert.Equal(t, []string{"", "gold", "silver", "bronze", "bronze"}, created)
+	assert.Equal(t, 2, dispatcher.Tenants())
+	assert.Equal(t, map[string][]int{
+		"gold":   {http.StatusAccepted, http.StatusAccepted},
+		"silver": {http.StatusCreated},
+		"bronze": {http.StatusInternalServerError, http.StatusInternalServerError},
+	}, observer.statuses)
+}
+
+func TestTenantIsolatedDispatcher_Evict(t *testing.T) {
+	dispatchers := map[string]*tenantDispatcher{
+		"":       {status: http.StatusOK},
+		"gold":   {status: http.StatusOK, release: make(chan struct{})},
+		"silver": {status: http.StatusOK},
+	}
+	dispatcher, err := fiber.NewTenantIsolatedDispatcher("route",
+		fiber.TenantPolicy{Key: fiber.RequestKey(false, "X-Tenant"), MaxTenants: 1},
+		func(tenant string) (fiber.Dispatcher, error) {
+			return dispatchers[tenant], nil
+		})
+	require.NoError(t, err)
+
+	done := make(chan fiber.Response)
+	go func() {
+		done <- dispatcher.Do(context.Background(), tenantRequest("gold", "/"))
+	}()
+	require.Eventually(t, func() bool { return dispatcher.Tenants() == 1 }, time.Second, time.Millisecond)
+
+	// the evicted dispatcher is closed, once its in-flight request is complete
+	assert.Equal(t, http.StatusOK, dispatcher.Do(context.Background(), tenantRequest("silver", "/")).StatusCode())
+	assert.Equal(t, 1, dispatcher.Tenants())
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&dispatchers["gold"].closed))
+
+	close(dispatchers["gold"].release)
+	assert.Equal(t, http.StatusOK, (<-done).StatusCode())
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&dispatchers["gold"].closed) == 1
+	}, time.Second, time.Millisecond)
+
+	// the default dispatcher and the dispatchers of the kept tenants are closed with the dispatcher
+	require.NoError(t, dispatcher.Close(context.Background()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dispatchers[""].closed))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dispatchers["silver"].closed))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dispatchers["gold"].closed))
+}
+
+func TestTenantIsolatedDispatcher_Panic(t *testing.T) {
+	dispatchers := map[string]*tenantDispatcher{
+		"":       {status: http.StatusOK},
+		"gold":   {panics: true},
+		"silver": {status: http.StatusOK},
+	}
+	dispatcher, err := fiber.NewTenantIsolatedDispatcher("route",
+		fiber.TenantPolicy{Key: fiber.RequestKey(false, "X-Tenant"), MaxTenants: 1},
+		func(tenant string) (fiber.Dispatcher, error) {
+			return dispatchers[tenant], nil
+		})
+	require.NoError(t, err)
+
+	assert.Panics(t, func() {
+		dispatcher.Do(context.Background(), tenantRequest("gold", "/"))
+	})
+
+	// the request, that has panicked, is complete, so the evicted dispatcher is closed
+	assert.Equal(t, http.StatusOK, dispatcher.Do(context.Background(), tenantRequest("silver", "/")).StatusCode())
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&dispatchers["gold"].closed) == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestTenantIsolatedDispatcher_SlowFactory(t *testing.T) {
+	release := make(chan struct{})
+	var creating int32
+	var lock sync.Mutex
+	var created []*tenantDispatcher
+	dispatcher, err := fiber.NewTenantIsolatedDispatcher("route",
+		fiber.TenantPolicy{Key: fiber.RequestKey(false, "X-Tenant")},
+		func(tenant string) (fiber.Dispatcher, error) {
+			if tenant == "gold" {
+				atomic.AddInt32(&creating, 1)
+				<-release
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			created = append(created, &tenantDispatcher{status: http.StatusOK})
+			return created[len(created)-1], nil
+		})
+	require.NoError(t, err)
+
+	done := make(chan fiber.Response, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- dispatcher.Do(context.Background(), tenantRequest("gold", "/"))
+		}()
+	}
+
+	// the other tenants aren't blocked, while the dispatcher of the tenant is created
+	assert.Equal(t, http.StatusOK, dispatcher.Do(context.Background(), tenantRequest("silver", "/")).StatusCode())
+	assert.Equal(t, 1, dispatcher.Tenants())
+
+	// only one of the dispatchers, created concurrently for the tenant, is kept, and the other one is closed
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&creating) == 2 }, time.Second, time.Millisecond)
+	close(release)
+	assert.Equal(t, http.StatusOK, (<-done).StatusCode())
+	assert.Equal(t, http.StatusOK, (<-done).StatusCode())
+	assert.Equal(t, 2, dispatcher.Tenants())
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		closed := 0
+		for _, created := range created {
+			closed += int(atomic.LoadInt32(&created.closed))
+		}
+		return len(created) == 4 && closed == 1
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,69 @@
+package fiber
+
+import (
+	"context"
+	"time"
+
+	"github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/protocol"
+)
+
+// TimeoutResponse defines the status code and the body of the response, that is sent back instead of
+// ErrRequestTimeout, when the request times out. For http, the body is sent as is with the status code,
+// for grpc, they are used as the status code and the status message
+type TimeoutResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+// response returns the response of the request, that has timed out: the TimeoutResponse, if it's set, or
+// ErrRequestTimeout otherwise. Either way, the failure is classified as errors.ErrTimeout (see ErrorResponse.Err)
+func (t *TimeoutResponse) response(proto protocol.Protocol) *ErrorResponse {
+	err := errors.ErrRequestTimeout(proto)
+	if t == nil {
+		return NewErrorResponse(err).(*ErrorResponse)
+	}
+	err.Code = t.StatusCode
+	if proto == protocol.GRPC {
+		err.Message = string(t.Body)
+		return NewErrorResponse(err).(*ErrorResponse)
+	}
+	return &ErrorResponse{
+		CachedPayload: NewCachedPayload(t.Body),
+		code:          t.StatusCode,
+		err:           err,
+	}
+}
+
+// WithRequestTimeout returns the request context, that overrides the timeouts of the dispatchers,
+// the request is dispatched by, with the given one. The override can't exceed the maximum timeout of
+// a dispatcher, or, if it's not configured, its timeout, so it can only make the timeout shorter.
+// The deadline of the request context still takes precedence, if it's earlier
+func WithRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, CtxTimeoutKey, timeout)
+}
+
+// RequestTimeout returns the timeout of the request, set with WithRequestTimeout
+func RequestTimeout(ctx context.Context) (time.Duration, bool) {
+	timeout, ok := ctx.Value(CtxTimeoutKey).(time.Duration)
+	return timeout, ok && timeout > 0
+}
+
+// ResolveTimeout returns the timeout of the dispatcher for the request: the timeout of the request,
+// if it's set in the request context, capped by maxTimeout, or by timeout, if maxTimeout is zero.
+// Otherwise, timeout is returned as is
+func ResolveTimeout(ctx context.Context, timeout time.Duration, maxTimeout time.Duration) time.Duration {
+	override, ok := RequestTimeout(ctx)
+	if !ok {
+		return timeout
+	}
+
+	ceiling := maxTimeout
+	if ceiling <= 0 {
+		ceiling = timeout
+	}
+	if ceiling > 0 && override > ceiling {
+		return ceiling
+	}
+	return override
+}
@@ -0,0 +1,129 @@
+package fiber_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveTimeout(t *testing.T) {
+	suite := map[string]struct {
+		requestTimeout time.Duration
+		timeout        time.Duration
+		maxTimeout     time.Duration
+		expected       time.Duration
+	}{
+		"no request timeout": {
+			timeout:    time.Second,
+			maxTimeout: 2 * time.Second,
+			expected:   time.Second,
+		},
+		"request timeout is shorter than timeout": {
+			requestTimeout: 100 * time.Millisecond,
+			timeout:        time.Second,
+			expected:       100 * time.Millisecond,
+		},
+		"request timeout is capped by timeout": {
+			requestTimeout: 5 * time.Second,
+			timeout:        time.Second,
+			expected:       time.Second,
+		},
+		"request timeout within max timeout": {
+			requestTimeout: 1500 * time.Millisecond,
+			timeout:        time.Second,
+			maxTimeout:     2 * time.Second,
+			expected:       1500 * time.Millisecond,
+		},
+		"request timeout is capped by max timeout": {
+			requestTimeout: 5 * time.Second,
+			timeout:        time.Second,
+			maxTimeout:     2 * time.Second,
+			expected:       2 * time.Second,
+		},
+		"no timeouts configured": {
+			requestTimeout: 5 * time.Second,
+			expected:       5 * time.Second,
+		},
+		"non-positive request timeout": {
+			requestTimeout: -time.Second,
+			timeout:        time.Second,
+			expected:       time.Second,
+		},
+	}
+
+	for name, tt := range suite {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.requestTimeout != 0 {
+				ctx = fiber.WithRequestTimeout(ctx, tt.requestTimeout)
+			}
+			assert.Equal(t, tt.expected, fiber.ResolveTimeout(ctx, tt.timeout, tt.maxTimeout))
+		})
+	}
+}
+
+type timeoutRouter interface {
+	fiber.Router
+	SetTimeoutResponse(timeout *fiber.TimeoutResponse)
+}
+
+func TestRouter_TimeoutResponse(t *testing.T) {
+	routers := map[string]func() timeoutRouter{
+		"lazy router": func() timeoutRouter { return fiber.NewLazyRouter("router") },
+		"lazy router with hedging": func() timeoutRouter {
+			router := fiber.NewLazyRouter("router")
+			require.NoError(t, router.SetHedgingPolicy(&fiber.HedgingPolicy{Delay: 5 * time.Millisecond}))
+			return router
+		},
+		"eager router": func() timeoutRouter { return fiber.NewEagerRouter("router") },
+	}
+	suite := map[string]struct {
+		timeout         *fiber.TimeoutResponse
+		expectedStatus  int
+		expectedPayload string
+	}{
+		"default": {
+			expectedStatus:  http.StatusRequestTimeout,
+			expectedPayload: `{"code":408,"error":"fiber: failed to receive a response within configured timeout"}`,
+		},
+		"custom": {
+			timeout:         &fiber.TimeoutResponse{StatusCode: http.StatusGatewayTimeout, Body: []byte(`{"error":"timeout"}`)},
+			expectedStatus:  http.StatusGatewayTimeout,
+			expectedPayload: `{"error":"timeout"}`,
+		},
+	}
+
+	for routerName, newRouter := range routers {
+		for name, tt := range suite {
+			t.Run(routerName+": "+name, func(t *testing.T) {
+				routes := map[string]fiber.Component{
+					"route-a": newSlowComponent("route-a", 200*time.Millisecond),
+					"route-b": newSlowComponent("route-b", 200*time.Millisecond),
+				}
+				router := newRouter()
+				router.SetRoutes(routes)
+				router.SetStrategy(&orderedRoutingStrategy{order: []string{"route-a", "route-b"}})
+				router.SetTimeoutResponse(tt.timeout)
+
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				defer cancel()
+				resp, ok := <-router.Dispatch(ctx, testUtilsHttp.MockReq("GET", "http://localhost", "")).Iter()
+				require.True(t, ok)
+				assert.Equal(t, tt.expectedStatus, resp.StatusCode())
+				assert.JSONEq(t, tt.expectedPayload, string(resp.Payload()))
+				// the custom response is still classified as the timeout
+				errResp, ok := resp.(*fiber.ErrorResponse)
+				require.True(t, ok)
+				assert.True(t, errors.Is(errResp.Err(), fiberErrors.ErrTimeout))
+			})
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package fiber
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DispatchTiming is the breakdown of the time, that the router has spent on the dispatch of the request
+// (see WithTiming)
+type DispatchTiming struct {
+	// RouterID is the id of the router, that has recorded the timing
+	RouterID string
+	// Strategy is the time, that the routing strategy has taken to select the routes
+	Strategy time.Duration
+	// Attempts are the attempts of the routes, in the order they have finished
+	Attempts []AttemptTiming
+	// Total is the time since the request was dispatched by the router, until its response was chosen
+	Total time.Duration
+}
+
+// AttemptTiming is the time, that a route has taken to respond to the request
+type AttemptTiming struct {
+	RouteID string
+	// Status is the status code of the response of the route
+	Status  int
+	Latency time.Duration
+}
+
+// ctxTimingKey is used to pass the timingRecorder of the request to the router, that dispatches it
+type ctxTimingKey struct{}
+
+// timingRecorder collects the DispatchTiming of the request. It's claimed by the outermost router, so
+// the nested routers are only recorded as the attempts of its routes
+type timingRecorder struct {
+	claimed int32
+
+	lock   sync.Mutex
+	timing DispatchTiming
+}
+
+// WithTiming returns the request context, that lets the router, that dispatches the request, record
+// the breakdown of the time of the dispatch, that is returned by Timing, once the response is received.
+// The timing is opt-in, so the routers don't record anything for the other requests
+func WithTiming(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxTimingKey{}, &timingRecorder{})
+}
+
+// Timing returns the DispatchTiming of the request, dispatched with the context, returned by WithTiming.
+// The attempts of the routes, that finish after the response is chosen (e.g. the routes of the EagerRouter),
+// are only included, if they have finished by the time it's called. It returns false, if the timing isn't
+// enabled for the request, or the request hasn't been dispatched by a router
+func Timing(ctx context.Context) (DispatchTiming, bool) {
+	recorder, ok := ctx.Value(ctxTimingKey{}).(*timingRecorder)
+	if !ok || atomic.LoadInt32(&recorder.claimed) == 0 {
+		return DispatchTiming{}, false
+	}
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	timing := recorder.timing
+	timing.Attempts = append([]AttemptTiming(nil), recorder.timing.Attempts...)
+	return timing, true
+}
+
+// claimTiming returns the timingRecorder of the request, if the timing is enabled, and it hasn't been
+// claimed by another router yet
+func claimTiming(ctx context.Context, routerID string) *timingRecorder {
+	recorder, ok := ctx.Value(ctxTimingKey{}).(*timingRecorder)
+	if !ok || !atomic.CompareAndSwapInt32(&recorder.claimed, 0, 1) {
+		return nil
+	}
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.timing.RouterID = routerID
+	return recorder
+}
+
+// record updates the timing of the request with the routing decision of the router
+func (r *timingRecorder) record(event LogEvent, routeID string, resp Response, latency time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch event {
+	case RouteSelectedEvent:
+		r.timing.Strategy = latency
+	case RouteAttemptFinishedEvent:
+		attempt := AttemptTiming{RouteID: routeID, Latency: latency}
+		if resp != nil {
+			attempt.Status = resp.StatusCode()
+		}
+		r.timing.Attempts = append(r.timing.Attempts, attempt)
+	case ResponseChosenEvent:
+		r.timing.Total = latency
+	}
+}
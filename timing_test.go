@@ -0,0 +1,81 @@
+package fiber_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojek/fiber"
+	fiberErrors "github.com/gojek/fiber/errors"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/gojek/fiber/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTiming(t *testing.T) {
+	newRouter := func() *fiber.LazyRouter {
+		return testutils.NewRouterBuilder("lazy-router").
+			WithDelayedRoute("route-a", testUtilsHttp.DelayedResponse{
+				Latency: 20 * time.Millisecond,
+				Response: testUtilsHttp.MockResp(http.StatusServiceUnavailable, "", nil,
+					fiberErrors.ErrServiceUnavailable(protocol.HTTP)),
+			}).
+			WithDelayedRoute("route-b", testUtilsHttp.DelayedResponse{
+				Latency:  10 * time.Millisecond,
+				Response: testUtilsHttp.MockResp(http.StatusOK, "B-OK", nil, nil),
+			}).
+			WithOrder([]string{"route-a", "route-b"}, 15*time.Millisecond, nil).
+			BuildLazy()
+	}
+
+	t.Run("fallback", func(t *testing.T) {
+		ctx := fiber.WithTiming(context.Background())
+		responses := testutils.Dispatch(ctx, newRouter(), testUtilsHttp.MockReq("GET", "http://localhost", ""))
+		require.Len(t, responses, 1)
+		assert.Equal(t, "B-OK", string(responses[0].Payload()))
+
+		timing, ok := fiber.Timing(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "lazy-router", timing.RouterID)
+		assert.GreaterOrEqual(t, int64(timing.Strategy), int64(15*time.Millisecond))
+
+		require.Len(t, timing.Attempts, 2)
+		assert.Equal(t, "route-a", timing.Attempts[0].RouteID)
+		assert.Equal(t, http.StatusServiceUnavailable, timing.Attempts[0].Status)
+		assert.GreaterOrEqual(t, int64(timing.Attempts[0].Latency), int64(20*time.Millisecond))
+		assert.Equal(t, "route-b", timing.Attempts[1].RouteID)
+		assert.Equal(t, http.StatusOK, timing.Attempts[1].Status)
+		assert.GreaterOrEqual(t, int64(timing.Attempts[1].Latency), int64(10*time.Millisecond))
+
+		assert.GreaterOrEqual(t, int64(timing.Total),
+			int64(timing.Strategy+timing.Attempts[0].Latency+timing.Attempts[1].Latency))
+	})
+
+	t.Run("nested router is an attempt of the outermost one", func(t *testing.T) {
+		router := fiber.NewLazyRouter("outer-router")
+		router.SetRoutes(map[string]fiber.Component{"lazy-router": newRouter()})
+		router.SetStrategy(&orderedRoutingStrategy{order: []string{"lazy-router"}})
+
+		ctx := fiber.WithTiming(context.Background())
+		responses := testutils.Dispatch(ctx, router, testUtilsHttp.MockReq("GET", "http://localhost", ""))
+		require.Len(t, responses, 1)
+
+		timing, ok := fiber.Timing(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "outer-router", timing.RouterID)
+		require.Len(t, timing.Attempts, 1)
+		assert.Equal(t, "lazy-router", timing.Attempts[0].RouteID)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctx := context.Background()
+		responses := testutils.Dispatch(ctx, newRouter(), testUtilsHttp.MockReq("GET", "http://localhost", ""))
+		require.Len(t, responses, 1)
+
+		_, ok := fiber.Timing(ctx)
+		assert.False(t, ok)
+	})
+}
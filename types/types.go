@@ -1,11 +1,17 @@
 package types
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/gojek/fiber"
 	"github.com/gojek/fiber/extras"
+	"github.com/gojek/fiber/extras/interceptor"
 )
 
 // Category is an alias for a string
@@ -16,32 +22,185 @@ const (
 	RoutingStrategy = "ROUTING_STRATEGY"
 	// FanIn is the type name use to define a FAN_IN
 	FanIn = "FAN_IN"
+	// Metrics is the type name use to define a METRICS
+	Metrics = "METRICS"
 )
 
 var categories = map[Category]reflect.Type{
 	RoutingStrategy: reflect.TypeOf((*fiber.RoutingStrategy)(nil)).Elem(),
 	FanIn:           reflect.TypeOf((*fiber.FanIn)(nil)).Elem(),
+	Metrics:         reflect.TypeOf((*interceptor.DispatchMetrics)(nil)).Elem(),
 }
 
 var types = map[Category]map[string]reflect.Type{
 	RoutingStrategy: {
-		"fiber.RandomRoutingStrategy": reflect.TypeOf(&extras.RandomRoutingStrategy{}).Elem(),
+		"fiber.RandomRoutingStrategy":          reflect.TypeOf(&extras.RandomRoutingStrategy{}).Elem(),
+		"fiber.ExternalRoutingStrategy":        reflect.TypeOf(&extras.ExternalRoutingStrategy{}).Elem(),
+		"fiber.HeaderRoutingStrategy":          reflect.TypeOf(&extras.HeaderRoutingStrategy{}).Elem(),
+		"fiber.ConsistentHashRoutingStrategy":  reflect.TypeOf(&extras.ConsistentHashRoutingStrategy{}).Elem(),
+		"fiber.LatencyAwareRoutingStrategy":    reflect.TypeOf(&extras.LatencyAwareRoutingStrategy{}).Elem(),
+		"fiber.CapacityAwareRoutingStrategy":   reflect.TypeOf(&extras.CapacityAwareRoutingStrategy{}).Elem(),
+		"fiber.WeightedRandomRoutingStrategy":  reflect.TypeOf(&extras.WeightedRandomRoutingStrategy{}).Elem(),
+		"fiber.WeightedShuffleRoutingStrategy": reflect.TypeOf(&extras.WeightedShuffleRoutingStrategy{}).Elem(),
+		"fiber.StickyRoutingStrategy":          reflect.TypeOf(&extras.StickyRoutingStrategy{}).Elem(),
+		"fiber.RoundRobinRoutingStrategy":      reflect.TypeOf(&extras.RoundRobinRoutingStrategy{}).Elem(),
 	},
 	FanIn: {
 		"fiber.FastestResponseFanIn": reflect.TypeOf(&extras.FastestResponseFanIn{}).Elem(),
 	},
+	Metrics: {
+		"fiber.NoopMetrics":       reflect.TypeOf(&interceptor.NoopDispatchMetrics{}).Elem(),
+		"fiber.PrometheusMetrics": reflect.TypeOf(&extras.PrometheusMetrics{}).Elem(),
+	},
 }
 
+// RoutingStrategyFactory creates the RoutingStrategy from the properties of its configuration
+type RoutingStrategyFactory func(properties json.RawMessage) (fiber.RoutingStrategy, error)
+
+// strategyFactories are the factories of the routing strategies, registered with RegisterRoutingStrategy
+var strategyFactories = map[string]RoutingStrategyFactory{}
+
+// typesLock guards the types and the factories, that can be registered at any time
+var typesLock sync.RWMutex
+
 func typeByName(category Category, typez string) (interface{}, error) {
+	typesLock.RLock()
+	defer typesLock.RUnlock()
 	if strategyType, exist := types[category][typez]; exist {
 		return reflect.New(strategyType).Interface(), nil
 	}
 	return nil, fmt.Errorf("unknown %s type: %s", category, typez)
 }
 
-// InstallType updates the type map with new sub-types
+// RegisterRoutingStrategy registers the factory of the routing strategy with the type name, so the strategy
+// can be used in the configuration of the routers, i.e. `strategy.type`. The factory is called with
+// the `strategy.properties` of each router, that uses the strategy. The type name can't be the one of
+// the built-in strategies or the strategies, installed with InstallType
+func RegisterRoutingStrategy(name string, factory RoutingStrategyFactory) error {
+	if name == "" {
+		return errors.New("routing strategy type name can not be empty")
+	}
+	if factory == nil {
+		return fmt.Errorf("factory of routing strategy type %s can not be nil", name)
+	}
+
+	typesLock.Lock()
+	defer typesLock.Unlock()
+	if _, exist := strategyFactories[name]; exist {
+		return fmt.Errorf("routing strategy type %s is already registered", name)
+	}
+	if _, exist := types[RoutingStrategy][name]; exist {
+		return fmt.Errorf("routing strategy type %s is already registered", name)
+	}
+	strategyFactories[name] = factory
+	return nil
+}
+
+// RoutingStrategyTypes returns the sorted type names of the registered routing strategies, including
+// the ones installed with InstallType
+func RoutingStrategyTypes() []string {
+	typesLock.RLock()
+	defer typesLock.RUnlock()
+	names := make([]string, 0, len(types[RoutingStrategy])+len(strategyFactories))
+	for name := range types[RoutingStrategy] {
+		names = append(names, name)
+	}
+	for name := range strategyFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateRoutingStrategy returns the error, listing the registered type names, if no routing strategy
+// is registered with the type name
+func ValidateRoutingStrategy(name string) error {
+	typesLock.RLock()
+	_, installed := types[RoutingStrategy][name]
+	_, registered := strategyFactories[name]
+	typesLock.RUnlock()
+	if installed || registered {
+		return nil
+	}
+	return unknownStrategyError(name)
+}
+
+// NewRoutingStrategy creates the routing strategy of the type name from its properties: with the factory,
+// registered with RegisterRoutingStrategy, or, for the types installed with InstallType, by initializing
+// the new instance of the type with the properties
+func NewRoutingStrategy(name string, properties json.RawMessage) (fiber.RoutingStrategy, error) {
+	typesLock.RLock()
+	factory, registered := strategyFactories[name]
+	typesLock.RUnlock()
+	if registered {
+		return factory(properties)
+	}
+
+	strategy, err := StrategyByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err = strategy.Initialize(properties); err != nil {
+		return nil, err
+	}
+	// the fallback of the external strategy can be any registered strategy, so it's created here
+	if external, ok := strategy.(*extras.ExternalRoutingStrategy); ok && external.FallbackType() != nil {
+		fallback, err := NewRoutingStrategy(external.FallbackType().Type, external.FallbackType().Properties)
+		if err != nil {
+			return nil, fmt.Errorf("external routing strategy: fallback: %v", err)
+		}
+		external.WithFallback(fallback)
+	}
+	return strategy, nil
+}
+
+// acceptancePredicates are the predicates of the responses of the eager routers, registered
+// with RegisterAcceptancePredicate
+var acceptancePredicates = map[string]fiber.AcceptancePredicate{}
+
+// RegisterAcceptancePredicate registers the predicate of the responses with the name, so it can be used
+// in the configuration of the eager routers, i.e. `acceptance.predicate` (see fiber.EagerRouter.SetAcceptancePredicate)
+func RegisterAcceptancePredicate(name string, predicate fiber.AcceptancePredicate) error {
+	if name == "" {
+		return errors.New("acceptance predicate name can not be empty")
+	}
+	if predicate == nil {
+		return fmt.Errorf("acceptance predicate %s can not be nil", name)
+	}
+
+	typesLock.Lock()
+	defer typesLock.Unlock()
+	if _, exist := acceptancePredicates[name]; exist {
+		return fmt.Errorf("acceptance predicate %s is already registered", name)
+	}
+	acceptancePredicates[name] = predicate
+	return nil
+}
+
+// AcceptancePredicateByName returns the acceptance predicate, registered with the name
+func AcceptancePredicateByName(name string) (fiber.AcceptancePredicate, error) {
+	typesLock.RLock()
+	defer typesLock.RUnlock()
+	if predicate, exist := acceptancePredicates[name]; exist {
+		return predicate, nil
+	}
+	return nil, fmt.Errorf("unknown acceptance predicate: %s", name)
+}
+
+func unknownStrategyError(name string) error {
+	return fmt.Errorf("unknown %s type: %s (registered types: %s)",
+		RoutingStrategy, name, strings.Join(RoutingStrategyTypes(), ", "))
+}
+
+// InstallType updates the type map with new sub-types. The routing strategy can't be installed with the type name,
+// that is registered with RegisterRoutingStrategy
 func InstallType(key string, objectOfType interface{}) error {
+	typesLock.Lock()
+	defer typesLock.Unlock()
 	newType := reflect.TypeOf(objectOfType)
+	if _, exist := strategyFactories[key]; exist && newType.Implements(categories[RoutingStrategy]) {
+		return fmt.Errorf("routing strategy type %s is already registered", key)
+	}
 	i := 0
 	for k, category := range categories {
 		if newType.Implements(category) {
@@ -56,10 +215,17 @@ func InstallType(key string, objectOfType interface{}) error {
 }
 
 // StrategyByName identifies a routing strategy type that matches the type name specified
-// and returns an instance of that type
+// and returns an instance of that type. The strategies, registered with RegisterRoutingStrategy,
+// are created with their properties by NewRoutingStrategy instead
 func StrategyByName(name string) (fiber.RoutingStrategy, error) {
 	if strategy, err := typeByName(RoutingStrategy, name); err != nil {
-		return nil, err
+		typesLock.RLock()
+		_, registered := strategyFactories[name]
+		typesLock.RUnlock()
+		if registered {
+			return nil, fmt.Errorf("routing strategy type %s is created from its properties, see NewRoutingStrategy", name)
+		}
+		return nil, unknownStrategyError(name)
 	} else if typed, ok := strategy.(fiber.RoutingStrategy); ok {
 		return typed, nil
 	}
@@ -78,3 +244,16 @@ func FanInByName(name string) (fiber.FanIn, error) {
 	}
 	return nil, fmt.Errorf("incompatible fan-in type: %s", name)
 }
+
+// MetricsByName identifies a metrics type that matches the type name specified
+// and returns an instance of that type
+func MetricsByName(name string) (interceptor.DispatchMetrics, error) {
+	metrics, err := typeByName(Metrics, name)
+	if err != nil {
+		return nil, err
+	}
+	if typed, ok := metrics.(interceptor.DispatchMetrics); ok {
+		return typed, nil
+	}
+	return nil, fmt.Errorf("incompatible metrics type: %s", name)
+}
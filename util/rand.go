@@ -0,0 +1,92 @@
+package util
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// randShards is the number of independently locked random sources of the ShardedRand
+const randShards = 16
+
+// ShardedRand is a pseudo-random numbers generator, that is safe for concurrent use.
+// It spreads the calls across several independently locked random sources, so
+// the concurrent callers don't contend on a single lock
+type ShardedRand struct {
+	next   uint32
+	shards []randShard
+}
+
+type randShard struct {
+	lock sync.Mutex
+	rand *rand.Rand
+}
+
+// NewShardedRand creates a new ShardedRand. Its sources are seeded with the values
+// derived from the given seed, so the sequence of generated values is deterministic,
+// if the ShardedRand is used by a single goroutine
+func NewShardedRand(seed int64) *ShardedRand {
+	r := &ShardedRand{shards: make([]randShard, randShards)}
+	for idx := range r.shards {
+		r.shards[idx].rand = rand.New(rand.NewSource(seed + int64(idx)))
+	}
+	return r
+}
+
+// NewShardedRandFromSource creates a new ShardedRand with a single shard, that draws the values from
+// the given source, e.g. to make the randomized strategies reproducible in the tests. The source is only
+// used under the lock of the shard, so it doesn't need to be safe for concurrent use
+func NewShardedRandFromSource(source rand.Source) *ShardedRand {
+	return &ShardedRand{shards: []randShard{{rand: rand.New(source)}}}
+}
+
+// shard returns the next shard of the ShardedRand
+func (r *ShardedRand) shard() *randShard {
+	return &r.shards[(atomic.AddUint32(&r.next, 1)-1)%uint32(len(r.shards))]
+}
+
+// Float64 returns a pseudo-random number in [0.0,1.0)
+func (r *ShardedRand) Float64() float64 {
+	shard := r.shard()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+	return shard.rand.Float64()
+}
+
+// Shuffle pseudo-randomizes the order of n elements, swapped with the given function
+func (r *ShardedRand) Shuffle(n int, swap func(i, j int)) {
+	shard := r.shard()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+	shard.rand.Shuffle(n, swap)
+}
+
+// WeightedShuffle reorders the ids in place by a weighted random draw without replacement
+// (Efraimidis-Spirakis), so the ids with bigger weights are more likely to come first.
+// Ids with non-positive weights are placed last, in their original order
+func (r *ShardedRand) WeightedShuffle(ids []string, weight func(id string) float64) {
+	keys := make([]float64, len(ids))
+	for idx, id := range ids {
+		if w := weight(id); w > 0 {
+			keys[idx] = -math.Log(1-r.Float64()) / w
+		} else {
+			keys[idx] = math.Inf(1)
+		}
+	}
+	sort.Stable(weightedKeys{ids: ids, keys: keys})
+}
+
+// weightedKeys sorts the ids by their sampling keys
+type weightedKeys struct {
+	ids  []string
+	keys []float64
+}
+
+func (s weightedKeys) Len() int           { return len(s.ids) }
+func (s weightedKeys) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+func (s weightedKeys) Swap(i, j int) {
+	s.ids[i], s.ids[j] = s.ids[j], s.ids[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
@@ -0,0 +1,25 @@
+package util_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/gojek/fiber/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedRand_Float64(t *testing.T) {
+	r1, r2 := util.NewShardedRand(42), util.NewShardedRand(42)
+	for i := 0; i < 100; i++ {
+		value := r1.Float64()
+		assert.True(t, value >= 0 && value < 1)
+		assert.Equal(t, value, r2.Float64(), "sequences with the same seed should be equal")
+	}
+}
+
+func TestNewShardedRandFromSource(t *testing.T) {
+	r, expected := util.NewShardedRandFromSource(rand.NewSource(42)), rand.New(rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, expected.Float64(), r.Float64(), "values should be drawn from the source")
+	}
+}
@@ -1,31 +1,57 @@
 package util
 
 import (
+	cryptoRand "crypto/rand"
+	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 )
 
+// randString generates random strings; rand.Rand is not safe for concurrent use, so each
+// goroutine borrows its own source from the pool, instead of contending on a shared lock
 type randString struct {
-	rand     *rand.Rand
+	pool     sync.Pool
 	alphabet string
 }
 
 // Generates a pseudo-random string of a given length from the provided alphabet
 func (n *randString) String(length int) string {
+	r := n.pool.Get().(*rand.Rand)
+	defer n.pool.Put(r)
+
 	bytes := make([]byte, length)
 	for idx := range bytes {
-		bytes[idx] = n.alphabet[n.rand.Intn(len(n.alphabet))]
+		bytes[idx] = n.alphabet[r.Intn(len(n.alphabet))]
 	}
 
 	return string(bytes)
 }
 
-var alphanumeric = randString{
-	rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+var alphanumeric = &randString{
+	pool: sync.Pool{
+		New: func() interface{} {
+			return rand.New(rand.NewSource(time.Now().UnixNano() + rand.Int63()))
+		},
+	},
 	alphabet: "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
 }
 
-// UID generates pseudo-random 6 char long String uid
+// UID generates pseudo-random 6 char long String uid. It is safe for concurrent use
 func UID() string {
 	return alphanumeric.String(6)
 }
+
+// UUID generates a random (version 4) UUID, e.g. to identify a request. It is safe for concurrent use
+func UUID() string {
+	var uuid [16]byte
+	if _, err := cryptoRand.Read(uuid[:]); err != nil {
+		// the pseudo-random source is used, if the system one is not available
+		r := alphanumeric.pool.Get().(*rand.Rand)
+		_, _ = r.Read(uuid[:])
+		alphanumeric.pool.Put(r)
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
@@ -0,0 +1,31 @@
+package util_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/gojek/fiber/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUID_Concurrent(t *testing.T) {
+	const goroutines = 32
+
+	uids := make(chan string, goroutines*100)
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				uids <- util.UID()
+			}
+		}()
+	}
+	wg.Wait()
+	close(uids)
+
+	for uid := range uids {
+		assert.Len(t, uid, 6)
+	}
+}
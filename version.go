@@ -0,0 +1,4 @@
+package fiber
+
+// Version is the current version of the fiber library
+const Version = "0.2.0"
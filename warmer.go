@@ -0,0 +1,110 @@
+package fiber
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Warmer is implemented by the components and the dispatchers, that establish the connections to their backends
+// lazily (i.e. grpc connections or http transports), so the connections can be established in advance, before
+// the traffic arrives (see WarmUp)
+type Warmer interface {
+	WarmUp(ctx context.Context) error
+}
+
+// WarmUpError is the failure of the route to establish the connection to its backend
+type WarmUpError struct {
+	RouteID string
+	Err     error
+}
+
+func (err WarmUpError) Error() string {
+	return fmt.Sprintf("route [%s]: %v", err.RouteID, err.Err)
+}
+
+// Unwrap returns the original error of the warm-up
+func (err WarmUpError) Unwrap() error {
+	return err.Err
+}
+
+// WarmUpErrors are the failures of all the routes of the component, that have failed to warm up, sorted by their IDs
+type WarmUpErrors []WarmUpError
+
+func (errs WarmUpErrors) Error() string {
+	problems := make([]string, len(errs))
+	for idx, err := range errs {
+		problems[idx] = err.Error()
+	}
+	return fmt.Sprintf("warm-up failed: %s", strings.Join(problems, "; "))
+}
+
+// WarmUp establishes the connections to the backends of the component, and, recursively, of its routes, if they
+// are Warmers, until the context is done. The routes are warmed up concurrently. The routes, that have failed
+// to warm up, are returned as WarmUpErrors. The component is still usable in this case, and the connections
+// to the failed backends are established on the first requests, same as without the warm-up
+func WarmUp(ctx context.Context, component Component) error {
+	return warmUpIfWarmer(ctx, component)
+}
+
+// warmUpIfWarmer warms up the component or the dispatcher, if it's a Warmer
+func warmUpIfWarmer(ctx context.Context, warmable interface{}) error {
+	if warmer, ok := warmable.(Warmer); ok {
+		return warmer.WarmUp(ctx)
+	}
+	return nil
+}
+
+// warmUpRoutes warms up the routes of a multi-route component concurrently, and collects their failures
+func warmUpRoutes(ctx context.Context, routes map[string]Component) error {
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+		errs WarmUpErrors
+	)
+	for id, route := range routes {
+		wg.Add(1)
+		go func(id string, route Component) {
+			defer wg.Done()
+			if err := warmUpIfWarmer(ctx, route); err != nil {
+				lock.Lock()
+				defer lock.Unlock()
+				errs = appendWarmUpError(errs, id, err)
+			}
+		}(id, route)
+	}
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].RouteID < errs[j].RouteID })
+	return errs
+}
+
+// appendWarmUpError appends the failure of the route, or the failures of its nested routes, if it's
+// a multi-route component
+func appendWarmUpError(errs WarmUpErrors, routeID string, err error) WarmUpErrors {
+	if nested, ok := err.(WarmUpErrors); ok {
+		return append(errs, nested...)
+	}
+	return append(errs, WarmUpError{RouteID: routeID, Err: err})
+}
+
+// ctxWarmUpEndpointKey is used to pass the endpoint of the backend to the dispatchers, that are warmed up
+type ctxWarmUpEndpointKey struct{}
+
+// withWarmUpEndpoint passes the endpoint of the backend to the dispatcher, that sends the requests to the endpoints
+// of the requests (e.g. the http dispatcher), so it knows, where to establish the connection to
+func withWarmUpEndpoint(ctx context.Context, endpoint string) context.Context {
+	return context.WithValue(ctx, ctxWarmUpEndpointKey{}, endpoint)
+}
+
+// WarmUpEndpoint returns the endpoint of the backend, that the dispatcher is warmed up for (see Proxy and
+// BackendDispatcher), or an empty string, if the backend is not known to the caller of WarmUp
+func WarmUpEndpoint(ctx context.Context) string {
+	endpoint, _ := ctx.Value(ctxWarmUpEndpointKey{}).(string)
+	return endpoint
+}
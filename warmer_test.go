@@ -0,0 +1,88 @@
+package fiber_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/gojek/fiber"
+	"github.com/gojek/fiber/internal/testutils"
+	testUtilsHttp "github.com/gojek/fiber/internal/testutils/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// warmingDispatcher records the endpoints, it has been warmed up for, and fails to warm up for the given one
+type warmingDispatcher struct {
+	failing string
+
+	lock      sync.Mutex
+	endpoints []string
+}
+
+func (d *warmingDispatcher) Do(context.Context, fiber.Request) fiber.Response {
+	return testUtilsHttp.MockResp(http.StatusOK, "OK", nil, nil)
+}
+
+func (d *warmingDispatcher) WarmUp(ctx context.Context) error {
+	endpoint := fiber.WarmUpEndpoint(ctx)
+	d.lock.Lock()
+	d.endpoints = append(d.endpoints, endpoint)
+	d.lock.Unlock()
+	if endpoint == d.failing {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (d *warmingDispatcher) warmedUp() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	endpoints := append([]string(nil), d.endpoints...)
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+func TestWarmUp(t *testing.T) {
+	dispatcher := &warmingDispatcher{failing: "http://route-b-2"}
+	newProxy := func(id string, backend fiber.Backend, dispatcher fiber.Dispatcher) fiber.Component {
+		caller, err := fiber.NewCaller(id, dispatcher)
+		require.NoError(t, err)
+		return fiber.NewProxy(backend, caller)
+	}
+	balanced, err := fiber.NewLoadBalancingDispatcher(fiber.RoundRobinLoadBalancing,
+		fiber.NewBackendDispatcher(fiber.NewBackend("route-b", "http://route-b-1"), dispatcher),
+		fiber.NewBackendDispatcher(fiber.NewBackend("route-b", "http://route-b-2"), dispatcher))
+	require.NoError(t, err)
+
+	retrying, err := fiber.NewRetryingDispatcher(dispatcher, fiber.RetryPolicy{MaxAttempts: 2})
+	require.NoError(t, err)
+
+	nested := fiber.NewEagerRouter("nested-router")
+	nested.SetRoutes(map[string]fiber.Component{
+		"route-b": newProxy("route-b", nil, balanced),
+		"route-c": newProxy("route-c", fiber.NewBackend("route-c", "http://route-c"), retrying),
+	})
+	router := fiber.NewLazyRouter("router")
+	router.SetRoutes(map[string]fiber.Component{
+		"route-a":       newProxy("route-a", fiber.NewBackend("route-a", "http://route-a"), dispatcher),
+		"nested-router": nested,
+		// the components, that aren't Warmers, are skipped
+		"route-d": testutils.NewMockComponent("route-d"),
+	})
+
+	err = fiber.WarmUp(context.Background(), router)
+	var errs fiber.WarmUpErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 1)
+	assert.Equal(t, "route-b", errs[0].RouteID)
+	assert.EqualError(t, errs[0], "route [route-b]: connection refused")
+	assert.EqualError(t, err, "warm-up failed: route [route-b]: connection refused")
+
+	// every backend of the router is warmed up with its endpoint
+	assert.Equal(t, []string{"http://route-a", "http://route-b-1", "http://route-b-2", "http://route-c"},
+		dispatcher.warmedUp())
+}